@@ -0,0 +1,129 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const crdFile = "../../config/crd/bases/metal3.io_provisionings.yaml"
+
+// schemaProps holds the parts of the generated OpenAPI schema checked here
+type schemaProps struct {
+	Pattern    string                 `json:"pattern"`
+	Properties map[string]schemaProps `json:"properties"`
+	Items      *schemaProps           `json:"items"`
+}
+
+// specSchema returns the generated OpenAPI schema of ProvisioningSpec
+func specSchema(t *testing.T) schemaProps {
+	data, err := ioutil.ReadFile(crdFile)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// Skip the empty document before the leading separator
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("---"))
+
+	crd := struct {
+		Spec struct {
+			Versions []struct {
+				Schema struct {
+					OpenAPIV3Schema schemaProps `json:"openAPIV3Schema"`
+				} `json:"schema"`
+			} `json:"versions"`
+		} `json:"spec"`
+	}{}
+	assert.NoError(t, utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096).Decode(&crd))
+	if !assert.NotEmpty(t, crd.Spec.Versions) {
+		t.FailNow()
+	}
+	return crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"]
+}
+
+func TestSpecFieldPatterns(t *testing.T) {
+	spec := specSchema(t)
+	relay := spec.Properties["dhcpRelayRanges"].Items.Properties
+
+	tCases := []struct {
+		name    string
+		schema  schemaProps
+		valid   []string
+		invalid []string
+	}{
+		{
+			name:    "ProvisioningIP",
+			schema:  spec.Properties["provisioningIP"],
+			valid:   []string{"", "172.30.20.3", "fd2e:6f44:5dd8:b856::3", "172.30.20.3/24", "fd2e:6f44:5dd8:b856::3/64"},
+			invalid: []string{"172.30.20", "172.30.20.3/", "172.30.20.3/240", "provisioning-host"},
+		},
+		{
+			name:    "ProvisioningNetworkCIDR",
+			schema:  spec.Properties["provisioningNetworkCIDR"],
+			valid:   []string{"", "172.30.20.0/24", "fd2e:6f44:5dd8:b856::/64"},
+			invalid: []string{"172.30.20.0", "172.30.20.0/", "fd2e:6f44:5dd8:b856::"},
+		},
+		{
+			name:    "ProvisioningDHCPRange",
+			schema:  spec.Properties["provisioningDHCPRange"],
+			valid:   []string{"", "172.30.20.11,172.30.20.101", "172.30.20.11, 172.30.20.101", "fd2e:6f44:5dd8:b856::10,fd2e:6f44:5dd8:b856::ff"},
+			invalid: []string{"172.30.20.11", "172.30.20.11-172.30.20.101", "172.30.20.11,fd2e:6f44:5dd8:b856::ff"},
+		},
+		{
+			name:    "ProvisioningOSDownloadURL",
+			schema:  spec.Properties["provisioningOSDownloadURL"],
+			valid:   []string{"", "http://mirror.example.com/rhcos.qcow2.gz", "https://mirror.example.com/rhcos.qcow2.gz"},
+			invalid: []string{"ftp://mirror.example.com/rhcos.qcow2.gz", "mirror.example.com/rhcos.qcow2.gz"},
+		},
+		{
+			name:    "DHCPRelayRange.NetworkCIDR",
+			schema:  relay["networkCIDR"],
+			valid:   []string{"192.168.10.0/24"},
+			invalid: []string{"", "192.168.10.0"},
+		},
+		{
+			name:    "DHCPRelayRange.DHCPRange",
+			schema:  relay["dhcpRange"],
+			valid:   []string{"192.168.10.10,192.168.10.100"},
+			invalid: []string{"", "192.168.10.10"},
+		},
+		{
+			name:    "DHCPRelayRange.Router",
+			schema:  relay["router"],
+			valid:   []string{"", "192.168.10.1"},
+			invalid: []string{"router.example.com"},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !assert.NotEmpty(t, tc.schema.Pattern) {
+				return
+			}
+			pattern := regexp.MustCompile(tc.schema.Pattern)
+			for _, value := range tc.valid {
+				assert.True(t, pattern.MatchString(value), "expected %q to be accepted", value)
+			}
+			for _, value := range tc.invalid {
+				assert.False(t, pattern.MatchString(value), "expected %q to be rejected", value)
+			}
+		})
+	}
+}
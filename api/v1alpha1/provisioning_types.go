@@ -16,13 +16,15 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	operatorv1 "github.com/openshift/api/operator/v1"
 )
 
 // ProvisioningNetwork is the boot mode of the system
-// +kubebuilder:validation:Enum=Managed;Unmanaged;Disabled
+// +kubebuilder:validation:Enum=Managed;Unmanaged;Disabled;Auto
 type ProvisioningNetwork string
 
 // ProvisioningNetwork modes
@@ -30,9 +32,29 @@ const (
 	ProvisioningNetworkManaged   ProvisioningNetwork = "Managed"
 	ProvisioningNetworkUnmanaged ProvisioningNetwork = "Unmanaged"
 	ProvisioningNetworkDisabled  ProvisioningNetwork = "Disabled"
+	ProvisioningNetworkAuto      ProvisioningNetwork = "Auto"
 )
 
-// ProvisioningSpec defines the desired state of Provisioning
+// ProvisioningNetworkSource is how the provisioning interface of the Auto
+// mode was found.
+type ProvisioningNetworkSource string
+
+const (
+	// ProvisioningNetworkSourceSpec is the ProvisioningInterface of the
+	// spec.
+	ProvisioningNetworkSourceSpec ProvisioningNetworkSource = "Spec"
+	// ProvisioningNetworkSourceNodeLabel is the interface the nodes are
+	// labelled with.
+	ProvisioningNetworkSourceNodeLabel ProvisioningNetworkSource = "NodeLabel"
+	// ProvisioningNetworkSourceLinkLocal is the only connected NIC of a
+	// node with no address but link-local ones.
+	ProvisioningNetworkSourceLinkLocal ProvisioningNetworkSource = "LinkLocal"
+)
+
+// ProvisioningSpec defines the desired state of Provisioning. The format of
+// addresses and URLs is checked by the CRD schema, so that malformed values
+// are rejected even when the admission webhook is not reachable; checks
+// involving several fields are left to the webhook and the operator.
 type ProvisioningSpec struct {
 	// ProvisioningInterface is the name of the network interface
 	// on a baremetal server to the provisioning network. It can
@@ -42,12 +64,17 @@ type ProvisioningSpec struct {
 	// ProvisioningIP is the IP address assigned to the
 	// provisioningInterface of the baremetal server. This IP
 	// address should be within the provisioning subnet, and
-	// outside of the DHCP range.
+	// outside of the DHCP range. It can be given in CIDR notation,
+	// such as 172.30.20.3/24, in which case the admission webhook
+	// stores the address alone and its network as the
+	// ProvisioningNetworkCIDR, which must match when set.
+	// +kubebuilder:validation:Pattern=`^$|^([0-9]{1,3}\.){3}[0-9]{1,3}(/[0-9]{1,2})?$|^[0-9a-fA-F:.]*:[0-9a-fA-F:.]*(/[0-9]{1,3})?$`
 	ProvisioningIP string `json:"provisioningIP,omitempty"`
 
 	// ProvisioningNetworkCIDR is the network on which the
 	// baremetal nodes are provisioned. The provisioningIP and the
 	// IPs in the dhcpRange all come from within this network.
+	// +kubebuilder:validation:Pattern=`^$|^([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]{1,2}$|^[0-9a-fA-F:.]*:[0-9a-fA-F:.]*/[0-9]{1,3}$`
 	ProvisioningNetworkCIDR string `json:"provisioningNetworkCIDR,omitempty"`
 
 	// ProvisioningDHCPExternal indicates whether the DHCP server
@@ -67,21 +94,87 @@ type ProvisioningSpec struct {
 	// ProvisioningDHCPExternal is set to False, indicating an
 	// internal DHCP server and the value of ProvisioningDHCPRange
 	// is not set, then the DHCP range is taken to be the default
-	// range which goes from .10 to .100 of the
-	// ProvisioningNetworkCIDR. This is the only value in all of
+	// range which goes from .10 to the penultimate address of the
+	// ProvisioningNetworkCIDR, excluding the ProvisioningIP. This is the only value in all of
 	// the Provisioning configuration that can be changed after
 	// the installer has created the CR. This value needs to be
 	// two comma sererated IP addresses within the
 	// ProvisioningNetworkCIDR where the 1st address represents
 	// the start of the range and the 2nd address represents the
 	// last usable address in the  range.
+	// +kubebuilder:validation:Pattern=`^$|^ *([0-9]{1,3}\.){3}[0-9]{1,3} *, *([0-9]{1,3}\.){3}[0-9]{1,3} *$|^ *[0-9a-fA-F:.]*:[0-9a-fA-F:.]* *, *[0-9a-fA-F:.]*:[0-9a-fA-F:.]* *$`
 	ProvisioningDHCPRange string `json:"provisioningDHCPRange,omitempty"`
 
+	// StrictDHCPRangeValidation disables the computation of a default
+	// ProvisioningDHCPRange when it is not set in Managed mode. When set,
+	// an empty ProvisioningDHCPRange is rejected as invalid.
+	StrictDHCPRangeValidation bool `json:"strictDHCPRangeValidation,omitempty"`
+
+	// DHCPLeasesVolumeClaim is the name of a PersistentVolumeClaim in the
+	// openshift-machine-api namespace used to persist the DHCP lease
+	// database across restarts of the dnsmasq pods. The claim is mounted
+	// on every control plane node, so it must support the ReadWriteMany
+	// access mode. When not set, the leases are persisted on each control
+	// plane node.
+	DHCPLeasesVolumeClaim string `json:"dhcpLeasesVolumeClaim,omitempty"`
+
+	// DHCPRelayRanges are additional DHCP ranges served to hosts on routed
+	// subnets, such as remote worker sites, whose DHCP requests are
+	// forwarded to the provisioning network by a DHCP relay. Only used
+	// when the ProvisioningNetwork is Managed.
+	DHCPRelayRanges []DHCPRelayRange `json:"dhcpRelayRanges,omitempty"`
+
+	// DHCPCircuitIDMappings assign fixed addresses or boot files to the
+	// IPv4 hosts whose DHCP requests carry a given circuit ID in the
+	// relay agent information option (option 82), as set by the switch
+	// or relay the host is connected to. Only used when the
+	// ProvisioningNetwork is Managed.
+	DHCPCircuitIDMappings []DHCPCircuitIDMapping `json:"dhcpCircuitIDMappings,omitempty"`
+
+	// TFTP configures the TFTP server, for hosts whose NIC firmware needs
+	// specific iPXE builds or transfer options. Only used when the
+	// ProvisioningNetwork is Managed.
+	TFTP *TFTPConfig `json:"tftp,omitempty"`
+
+	// SecureBoot serves the UEFI hosts booting from the network the
+	// signed shim and GRUB binaries of the metal3 image instead of iPXE,
+	// which UEFI Secure Boot refuses to run. BIOS hosts keep booting
+	// iPXE. The hosts must use the pxe boot interface of Ironic. When
+	// the ProvisioningNetwork is Unmanaged, the external DHCP server must
+	// hand out the shim boot files. Not supported when the
+	// ProvisioningNetwork is Disabled, as virtual media boots need no
+	// network boot chain. Tech preview, only honored when the cluster
+	// enables the TechPreviewNoUpgrade feature set.
+	SecureBoot bool `json:"secureBoot,omitempty"`
+
+	// UEFIBootLoader is the boot loader served to the UEFI hosts booting
+	// from the network. iPXE, the default, is chainloaded from the iPXE
+	// builds of the metal3 image. GRUB serves the GRUB network boot
+	// images of each architecture instead, for UEFI firmware that cannot
+	// chainload iPXE, and Ironic generates the GRUB configuration of each
+	// host for its architecture. The metal3 image does not hold these
+	// images, so the TFTP.FilesConfigMap must provide grubx64.efi and
+	// grubaa64.efi. BIOS hosts keep booting iPXE. The pxe boot interface
+	// of Ironic is made the default, which the hosts must use. Only
+	// supported when the ProvisioningNetwork is Managed, and not with
+	// SecureBoot, which already boots GRUB through the signed shim.
+	// +kubebuilder:validation:Enum=iPXE;GRUB
+	UEFIBootLoader UEFIBootLoader `json:"uefiBootLoader,omitempty"`
+
 	// ProvisioningOSDownloadURL is the location from which the OS
 	// Image used to boot baremetal host machines can be downloaded
 	// by the metal3 cluster.
+	// +kubebuilder:validation:Pattern=`^$|^https?://`
 	ProvisioningOSDownloadURL string `json:"provisioningOSDownloadURL,omitempty"`
 
+	// ProvisioningOSImage is the structured form of the
+	// ProvisioningOSDownloadURL, with the checksum apart from the URL so
+	// that images whose checksum cannot be passed in the sha256 query
+	// parameter can be used. The ProvisioningOSDownloadURL is derived
+	// from it when not set, and must name the same image otherwise.
+	// +optional
+	ProvisioningOSImage *ProvisioningOSImage `json:"provisioningOSImage,omitempty"`
+
 	// ProvisioningNetwork provides a way to indicate the state of the
 	// underlying network configuration for the provisioning network.
 	// This field can have one of the following values -
@@ -95,16 +188,1567 @@ type ProvisioningSpec struct {
 	// installation. If using metal3 for power management, BMCs must be
 	// accessible from the machine networks. User should provide two IPs on
 	// the external network that would be used for provisioning services.
+	// `Auto`- when the provisioning network is Managed, with the
+	// ProvisioningInterface found by the operator when not set: the one
+	// the nodes are labelled with in baremetal.openshift.io/provisioning-interface,
+	// or else the only connected NIC of a node with no address but
+	// link-local ones. Without a ProvisioningNetworkCIDR, the
+	// 172.22.0.0/24 network is used with 172.22.0.3 as ProvisioningIP.
+	// The network found is recorded in the status. The Auto mode is tech
+	// preview, only honored when the cluster enables the
+	// TechPreviewNoUpgrade feature set.
 	ProvisioningNetwork ProvisioningNetwork `json:"provisioningNetwork,omitempty"`
+
+	// AutoUpdateOSImage indicates that the provisioning OS image should
+	// follow the RHCOS stream metadata published in the coreos-bootimages
+	// ConfigMap, rolling forward on cluster upgrades. When set, the
+	// ProvisioningOSDownloadURL is only used until stream metadata is
+	// available.
+	AutoUpdateOSImage bool `json:"autoUpdateOSImage,omitempty"`
+
+	// ConvertOSImageToRaw indicates that the cached provisioning OS
+	// image should be converted to the raw format once it has been
+	// downloaded. The raw image is served next to the qcow2 image along
+	// with its sha256 checksum, so that hosts whose drivers require raw
+	// images do not need to convert the image while deploying.
+	ConvertOSImageToRaw bool `json:"convertOSImageToRaw,omitempty"`
+
+	// ImageServerHTTPS enables an HTTPS listener on the image server in
+	// addition to the plain HTTP one. Both sets of URLs are published in
+	// the status so that consumers can pick the scheme supported by each
+	// host, e.g. BMCs that cannot use HTTPS for virtual media.
+	ImageServerHTTPS bool `json:"imageServerHTTPS,omitempty"`
+
+	// LivePXEArtifacts are the RHCOS live PXE kernel, initramfs and
+	// rootfs cached by the metal3 pod, and published with the other boot
+	// artifacts under stable URLs for the installation flows booting the
+	// live system without going through Ironic.
+	// +optional
+	LivePXEArtifacts *LivePXEArtifacts `json:"livePXEArtifacts,omitempty"`
+
+	// IronicAPIExposure exposes the Ironic API outside of the node
+	// network through a TLS Service guarded by a sidecar requiring client
+	// certificates. The Ironic API stays only reachable on the
+	// provisioning network when not set.
+	IronicAPIExposure *IronicAPIExposure `json:"ironicAPIExposure,omitempty"`
+
+	// CertificateExpiryWarningDays is how many days before they expire
+	// the serving certificates of the operands are reported by the
+	// CertificatesValid condition of the status. The certificates issued
+	// by the operator itself are renewed at that point. Defaults to 30.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=365
+	CertificateExpiryWarningDays int32 `json:"certificateExpiryWarningDays,omitempty"`
+
+	// ControlPlaneOnly restricts the metal3 pods to the control plane
+	// nodes. Defaults to true. When set to false, the pods run on the
+	// nodes matching NodeSelector instead, which must all be connected to
+	// the provisioning network.
+	ControlPlaneOnly *bool `json:"controlPlaneOnly,omitempty"`
+
+	// NodeSelector selects the nodes the metal3 pods run on when
+	// ControlPlaneOnly is false.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// HighAvailability runs several replicas of the metal3 pod, each on a
+	// distinct node and spread across zones when the nodes are labelled
+	// with one. Only one of them is active, the operator electing the pod
+	// running the metal3 services and holding the ProvisioningIP while
+	// the others stand by with the images downloaded. Another pod is only
+	// elected once the active one is gone, which on an unreachable node
+	// requires the node to be fenced and the pod force deleted, so that
+	// two pods never provision the hosts at once. A single metal3 pod
+	// runs when not set. Tech preview, only honored when the cluster
+	// enables the TechPreviewNoUpgrade feature set.
+	HighAvailability *HighAvailability `json:"highAvailability,omitempty"`
+
+	// ProvisioningIPPool are addresses of the ProvisioningNetworkCIDR
+	// allocated to the nodes running the metal3 pods when
+	// HighAvailability is set, one per node, and configured on their
+	// provisioning interface besides the ProvisioningIP. The Ironic
+	// conductor of the active metal3 pod is reached at the address of its
+	// node. The pool must have at least as many addresses as
+	// HighAvailability.Replicas, and cannot contain the ProvisioningIP or
+	// addresses of the ProvisioningDHCPRange. Tech preview, only honored
+	// when the cluster enables the TechPreviewNoUpgrade feature set.
+	ProvisioningIPPool []string `json:"provisioningIPPool,omitempty"`
+
+	// ProvisioningVIP configures how the ProvisioningIP moves with the
+	// active metal3 pod when HighAvailability is set. The node of the new
+	// active pod announces the move with gratuitous ARP, so that the
+	// deployKernelUrl and the Ironic endpoints stay reachable as the
+	// active metal3 pod changes nodes. Tech preview, only honored when
+	// the cluster enables the TechPreviewNoUpgrade feature set.
+	ProvisioningVIP *ProvisioningVIP `json:"provisioningVIP,omitempty"`
+
+	// ConductorGroups run additional Ironic conductors, each serving the
+	// Ironic nodes whose conductor_group is the name of the group, so
+	// that the hosts of very large clusters are spread across conductors.
+	// The hosts of a group are labelled with
+	// baremetal.openshift.io/conductor-group set to its name, and the
+	// operator sets the conductor_group of their Ironic nodes accordingly
+	// once the baremetal-operator registered them. The conductors share
+	// the database of the metal3 pod. Tech preview, only honored when the
+	// cluster enables the TechPreviewNoUpgrade feature set.
+	ConductorGroups []ConductorGroup `json:"conductorGroups,omitempty"`
+
+	// OperandMetadata holds labels and annotations added to the
+	// Deployments, DaemonSets, Services and Secrets generated by the
+	// operator, such as cost center or ownership metadata. Keys set by
+	// the operator itself cannot be used.
+	OperandMetadata *OperandMetadata `json:"operandMetadata,omitempty"`
+
+	// EnableProvisioningDomains allows additional Provisioning instances,
+	// each deploying a separate metal3 stack for its own provisioning
+	// network. Only honored on the provisioning-configuration instance.
+	// Tech preview, only honored when the cluster enables the
+	// TechPreviewNoUpgrade feature set.
+	EnableProvisioningDomains bool `json:"enableProvisioningDomains,omitempty"`
+
+	// HostSelector selects the BareMetalHosts managed by the metal3 stack
+	// of this instance. It is required on all instances once additional
+	// Provisioning instances exist, and the selectors of the instances
+	// must be disjoint, through a label they require different values of,
+	// or one requires while the other excludes it. An additional instance
+	// whose selector may overlap with the selector of an older instance
+	// is ignored.
+	HostSelector *metav1.LabelSelector `json:"hostSelector,omitempty"`
+
+	// ProvisioningLimit is the number of hosts the baremetal-operator
+	// provisions at once, which also bounds the MaxParallelDeploys of the
+	// capacity. Defaults to 20, the limit of the baremetal-operator.
+	// +kubebuilder:validation:Minimum=1
+	ProvisioningLimit int32 `json:"provisioningLimit,omitempty"`
+
+	// EnableOnAnyPlatform deploys metal3 on clusters that were not
+	// installed with the BareMetal platform, like user provisioned
+	// clusters on platform None, so that they can manage BareMetalHosts.
+	// Only the Unmanaged and Disabled provisioning networks are supported
+	// on such clusters. Only honored on the provisioning-configuration
+	// instance.
+	EnableOnAnyPlatform bool `json:"enableOnAnyPlatform,omitempty"`
+
+	// ImageCache configures the eviction of the cached provisioning OS
+	// images. When set, the images are cached on the nodes running the
+	// metal3 pod, so that they are not downloaded again when the pod
+	// restarts, and the images replaced by an upgrade are evicted.
+	ImageCache *ImageCache `json:"imageCache,omitempty"`
+
+	// PreStagedImagePVC is the name of a PersistentVolumeClaim of the
+	// openshift-machine-api namespace already holding the provisioning OS
+	// image, for sites that cannot download it. The volume holds the
+	// uncompressed image, named after the ProvisioningOSDownloadURL
+	// without its compression suffix, which is then served as it is:
+	// the image is never downloaded. It is mounted read-only, and must
+	// be ReadOnlyMany when HighAvailability is set. Cannot be combined
+	// with ImageCache, OSImageSignatureRef, ConvertOSImageToRaw nor
+	// AutoUpdateOSImage.
+	PreStagedImagePVC string `json:"preStagedImagePVC,omitempty"`
+
+	// OSImageSignatureRef enables the verification of the signature of
+	// the provisioning OS image once it has been downloaded. An image
+	// failing the verification is removed from the cache and never
+	// served, and the operator reports OSImageVerificationFailed.
+	OSImageSignatureRef *OSImageSignatureRef `json:"osImageSignatureRef,omitempty"`
+
+	// ImagePullSecretRef names the credentials the provisioning OS image
+	// and the IPA artifacts are downloaded with, for registries and HTTP
+	// servers requiring authentication. The operator passes the
+	// dockerconfigjson of the Secret to the downloaders as it is, and to
+	// curl as a netrc file, so the credentials must not hold whitespace
+	// or quotes. The downloaders download again when the Secret is
+	// rotated.
+	ImagePullSecretRef *ImagePullSecretRef `json:"imagePullSecretRef,omitempty"`
+
+	// IronicAPIAudit deploys an auditing proxy in front of the Ironic
+	// API, recording every call with the authenticated user, the request
+	// and its outcome. The baremetal-operator and the IronicAPIExposure
+	// reach Ironic through the proxy. Requires a ProvisioningIP.
+	IronicAPIAudit *IronicAPIAudit `json:"ironicAPIAudit,omitempty"`
+
+	// HardwareMetrics has Ironic poll the power and temperature sensors
+	// of the hosts through their BMC, and serves them as Prometheus
+	// metrics on the hw-metrics port of the metal3-hardware-metrics
+	// Service, scraped by the cluster monitoring.
+	HardwareMetrics *HardwareMetrics `json:"hardwareMetrics,omitempty"`
+
+	// OrphanCleanup has the operator periodically delete the Ironic
+	// nodes no BareMetalHost manages anymore, and release the DHCP leases
+	// of their ports, so that deleted hosts do not accumulate in the
+	// Ironic database. Only nodes that are not deployed nor busy are
+	// deleted. Orphans are kept when not set.
+	OrphanCleanup *OrphanCleanup `json:"orphanCleanup,omitempty"`
+
+	// InspectorRules are introspection rules loaded into Ironic
+	// Inspector, for instance to set root device hints or capabilities
+	// from the inspection data. They replace all the rules of Ironic
+	// Inspector, and are loaded again whenever the ConfigMap changes.
+	// Requires a ProvisioningIP.
+	InspectorRules *InspectorRules `json:"inspectorRules,omitempty"`
+
+	// ImageServerMounts are ConfigMaps and PersistentVolumeClaims of the
+	// openshift-machine-api namespace served by the image server, such as
+	// vendor firmware bundles or custom ignition files. Their paths must
+	// not overlap with each other nor with the files of the image server.
+	ImageServerMounts []ImageServerMount `json:"imageServerMounts,omitempty"`
+
+	// Maintenance scales the metal3 pods down, and stops the DHCP and
+	// TFTP servers, for instance during maintenance windows of the
+	// provisioning network. The DHCP leases and the operator state are
+	// kept, while the Ironic database is not, the hosts being registered
+	// again with Ironic once the pods run again. Hosts being provisioned
+	// or cleaned delay the scale down, as their operations would be
+	// abandoned, and hosts cannot be provisioned while it is set.
+	Maintenance bool `json:"maintenance,omitempty"`
+
+	// Enabled deploys the metal3 stack. Defaults to true. When set to
+	// false, the metal3 pods, the dnsmasq servers, the conductor groups
+	// and provisioning domains, and the Services and ConfigMaps serving
+	// them are deleted, while the Provisioning instance, the generated
+	// passwords and the operand revision history are kept, for clusters
+	// done with bare metal provisioning. Unlike the Disabled provisioning
+	// network, nothing runs, so the BareMetalHosts are not reconciled
+	// anymore. Hosts being provisioned or cleaned delay the teardown.
+	// Setting it back to true deploys the stack again.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// EnableIgnitionOverrides serves per-host ignition and network
+	// configurations at stable URLs, so that hosts can be booted with a
+	// customized first-boot configuration. They are read from the
+	// ConfigMaps of the openshift-machine-api namespace labelled with
+	// baremetal.openshift.io/ignition-override-host, whose value is the
+	// name of the host, from their ignition and network keys. They are
+	// only served on the ProvisioningIP, which is required, and the
+	// overrides of all hosts together must fit in a single ConfigMap of 1
+	// MiB, the hosts that do not fit being ignored. Tech preview, only
+	// honored when the cluster enables the TechPreviewNoUpgrade feature
+	// set.
+	EnableIgnitionOverrides bool `json:"enableIgnitionOverrides,omitempty"`
+
+	// Profile selects the metal3 services that are run. Minimal only runs
+	// the baremetal-operator, Ironic, the image server and the manager of
+	// the ProvisioningIP, with Ironic
+	// keeping its database in SQLite, and limits their memory, for edge
+	// clusters where the footprint matters more than the features. As it
+	// runs neither Ironic Inspector nor a DHCP server, it requires a
+	// ProvisioningNetwork of Unmanaged or Disabled, and hosts must be
+	// registered with inspection disabled.
+	// +optional
+	Profile ProvisioningProfile `json:"profile,omitempty"`
+
+	// OperandLogLevel is the verbosity of Ironic, Ironic Inspector and
+	// dnsmasq. Debug enables their debug logs, and Trace those of the
+	// libraries they use as well. Changing it restarts the metal3 pods.
+	// Defaults to Normal.
+	// +optional
+	OperandLogLevel OperandLogLevel `json:"operandLogLevel,omitempty"`
+
+	// OperandLogFormat is the format of the logs of Ironic, Ironic
+	// Inspector and dnsmasq. With JSON, each line is a record with the
+	// time, level, component, logger and message fields, and the pods are
+	// annotated with baremetal.openshift.io/log-format=json for the log
+	// collectors. Changing it restarts the metal3 pods. Defaults to Text.
+	// +optional
+	OperandLogFormat OperandLogFormat `json:"operandLogFormat,omitempty"`
+
+	// VendorExtensions enable the Ironic drivers of hardware vendors, for
+	// fleets whose BMCs are managed best through them. When set, Ironic
+	// only enables the generic IPMI and Redfish drivers and those of the
+	// vendors listed, instead of every driver of the image.
+	// +optional
+	VendorExtensions *VendorExtensions `json:"vendorExtensions,omitempty"`
+
+	// FirmwareUpdates serves firmware bundles from the image server, for
+	// the firmware update steps of the hosts managed through Redfish.
+	// Requires a ProvisioningIP.
+	// +optional
+	FirmwareUpdates *FirmwareUpdates `json:"firmwareUpdates,omitempty"`
+
+	// DefaultRAIDConfig are RAID layouts registered with Ironic as deploy
+	// templates named CUSTOM_OCP_RAID_<name>. A BareMetalHost is deployed
+	// with a layout when it is labelled baremetal.openshift.io/raid-
+	// template=<name>, the operator then adding the trait of the template
+	// to its Ironic node and to the traits requested by its deployment
+	// while the node is manageable or available. Common layouts are thus
+	// defined once for the cluster rather than in each BareMetalHost.
+	// Hosts with a RAID configuration of their own should not request
+	// them.
+	// +optional
+	DefaultRAIDConfig []RAIDTemplate `json:"defaultRAIDConfig,omitempty"`
+
+	// BIOSProfiles are BIOS settings registered with Ironic as deploy
+	// templates named CUSTOM_OCP_BIOS_<name>. A BareMetalHost is deployed
+	// with a profile when it is labelled baremetal.openshift.io/bios-
+	// profile=<name>, the operator then adding the trait of the template
+	// to its Ironic node and to the traits requested by its deployment
+	// while the node is manageable or available. A class of hosts thus
+	// gets its settings from a profile rather than from settings repeated
+	// in each BareMetalHost.
+	// +optional
+	BIOSProfiles []BIOSProfile `json:"biosProfiles,omitempty"`
+
+	// PodSecurityMode selects how closely the metal3 pods follow the Pod
+	// Security Standards. With Restricted, every container runs
+	// unprivileged, without privilege escalation, with the RuntimeDefault
+	// seccomp profile and only the capabilities it needs: the network
+	// ones for the containers configuring the host network and DHCP, and
+	// the ones switching users for the services dropping root. The pods
+	// still use the host network, so the namespace keeps enforcing the
+	// privileged level and is labelled to audit the restricted one.
+	// Defaults to Privileged.
+	// +optional
+	PodSecurityMode PodSecurityMode `json:"podSecurityMode,omitempty"`
+
+	// BMCProxy routes the HTTP traffic of the Ironic conductors, such as
+	// the Redfish and vendor API calls to the BMCs, through a proxy. It
+	// is distinct from the cluster-wide proxy, which the metal3 pods do
+	// not use. IPMI is not proxied.
+	// +optional
+	BMCProxy *BMCProxy `json:"bmcProxy,omitempty"`
+
+	// BMCNetwork reaches the BMCs through a dedicated out-of-band network
+	// rather than the default route of the nodes. The metal3 pods and the
+	// conductors of the groups route the subnets of the BMCs through its
+	// interface on their node for as long as they run.
+	// +optional
+	BMCNetwork *BMCNetwork `json:"bmcNetwork,omitempty"`
+
+	// ProvisioningInterfaceConfig selects how the ProvisioningInterface of
+	// the control plane nodes is configured. With Pod, the metal3 pods
+	// bring it up when they start. With MachineConfig, the operator
+	// renders a MachineConfig with a NetworkManager profile keeping it up
+	// across reboots and restarts of the pods and of NetworkManager, with
+	// no DHCP client and only link-local IPv6 addresses. The
+	// ProvisioningIP still follows the metal3 pod in both cases. Changing
+	// to or from MachineConfig, or the ProvisioningInterface with it,
+	// reboots the control plane nodes one at a time. Defaults to Pod.
+	// +optional
+	ProvisioningInterfaceConfig ProvisioningInterfaceConfig `json:"provisioningInterfaceConfig,omitempty"`
+
+	// SmokeTest runs a Job exercising the Ironic and Ironic Inspector
+	// APIs and the image server of the metal3 pod each time a new
+	// configuration has rolled out, and reports its outcome in the
+	// status. Tech preview, only honored when the cluster enables the
+	// TechPreviewNoUpgrade feature set.
+	// +optional
+	SmokeTest bool `json:"smokeTest,omitempty"`
+
+	// UpdateStrategy selects how the metal3 pods are replaced when their
+	// Deployment changes. Defaults to stopping the running pods before
+	// starting new ones, as the host network ports and the
+	// ProvisioningIP of the pods would otherwise conflict.
+	// +optional
+	UpdateStrategy *Metal3UpdateStrategy `json:"updateStrategy,omitempty"`
+
+	// TerminationGracePeriodSeconds is how long the metal3 pods are given
+	// to stop, e.g. for Ironic to finish the operations in progress,
+	// before they are killed. Defaults to 30.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// IronicRPCTransport selects how the Ironic API calls the Ironic
+	// conductors. JSONRPC calls them over HTTP on port 8089, which
+	// ConductorGroups require as the conductors run in other pods. Local
+	// runs the API and the conductor of the metal3 pod in a single
+	// process, which saves the memory of a second process when the metal3
+	// pod runs the only conductor. Defaults to JSONRPC.
+	// +optional
+	IronicRPCTransport IronicRPCTransport `json:"ironicRPCTransport,omitempty"`
+
+	// IronicDBRecovery quarantines the Ironic database of the metal3 pods
+	// that keep failing on a corrupted one, starts them on a new database
+	// and registers the BareMetalHosts again. The quarantined databases
+	// are kept on the nodes under /var/lib/metal3/ironic-db-quarantine.
+	// Tech preview, only honored when the cluster enables the
+	// TechPreviewNoUpgrade feature set.
+	// +optional
+	IronicDBRecovery bool `json:"ironicDBRecovery,omitempty"`
+}
+
+// IronicRPCTransport is how the Ironic API calls the Ironic conductors.
+// +kubebuilder:validation:Enum=JSONRPC;Local
+type IronicRPCTransport string
+
+const (
+	// IronicRPCTransportJSONRPC calls the conductors over JSON-RPC.
+	IronicRPCTransportJSONRPC IronicRPCTransport = "JSONRPC"
+	// IronicRPCTransportLocal runs the API and the conductor in the same
+	// process.
+	IronicRPCTransportLocal IronicRPCTransport = "Local"
+)
+
+// Metal3UpdateStrategyType is how the metal3 pods are replaced.
+// +kubebuilder:validation:Enum=Recreate;RollingUpdate
+type Metal3UpdateStrategyType string
+
+const (
+	// Metal3UpdateRecreate stops all the metal3 pods before starting
+	// the new ones.
+	Metal3UpdateRecreate Metal3UpdateStrategyType = "Recreate"
+	// Metal3UpdateRollingUpdate replaces the metal3 pods a few at a
+	// time, within MaxSurge and MaxUnavailable.
+	Metal3UpdateRollingUpdate Metal3UpdateStrategyType = "RollingUpdate"
+)
+
+// Metal3UpdateStrategy configures the update strategy of the metal3
+// Deployment.
+type Metal3UpdateStrategy struct {
+	// Type is Recreate or RollingUpdate. Defaults to Recreate.
+	// +optional
+	Type Metal3UpdateStrategyType `json:"type,omitempty"`
+
+	// MaxSurge is the number or percentage of metal3 pods started above
+	// the desired replicas during a RollingUpdate. Surging requires
+	// HighAvailability, which keeps the metal3 pods on distinct nodes, as
+	// the new pods would otherwise share the host network ports of the
+	// old ones on the same node. Defaults to 1.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// MaxUnavailable is the number or percentage of the desired metal3
+	// pods that can be unavailable during a RollingUpdate. Defaults to
+	// 0.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// ProvisioningInterfaceConfig selects how the provisioning interface of
+// the nodes is configured.
+// +kubebuilder:validation:Enum=Pod;MachineConfig
+type ProvisioningInterfaceConfig string
+
+const (
+	// ProvisioningInterfaceConfigPod configures the interface from the
+	// metal3 pods.
+	ProvisioningInterfaceConfigPod ProvisioningInterfaceConfig = "Pod"
+	// ProvisioningInterfaceConfigMachineConfig configures the interface
+	// through a MachineConfig of the control plane nodes.
+	ProvisioningInterfaceConfigMachineConfig ProvisioningInterfaceConfig = "MachineConfig"
+)
+
+// BMCProxy is the proxy the Ironic conductors reach the BMCs through.
+type BMCProxy struct {
+	// URL is the URL of the proxy, with the http, https, socks5 or
+	// socks5h scheme and without credentials.
+	// +kubebuilder:validation:Pattern=`^(https?|socks5h?)://`
+	URL string `json:"url"`
+
+	// CredentialsSecretName is the name of a Secret in the metal3
+	// namespace holding the username and password keys to authenticate
+	// with the proxy. Both must be URL-safe.
+	// +optional
+	CredentialsSecretName string `json:"credentialsSecretName,omitempty"`
+
+	// NoProxy are hosts, domains and CIDRs reached without the proxy,
+	// besides the ProvisioningIP, the ProvisioningNetworkCIDR, the
+	// cluster services and localhost, which always are.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+}
+
+// BMCNetwork is the out-of-band network the BMCs are reached through.
+type BMCNetwork struct {
+	// Interface is the NIC of the nodes connected to the out-of-band
+	// network. It cannot be the ProvisioningInterface.
+	Interface string `json:"interface"`
+
+	// Subnets are the networks of the BMCs, in CIDR notation, routed
+	// through the Interface. They cannot overlap the
+	// ProvisioningNetworkCIDR.
+	// +kubebuilder:validation:MinItems=1
+	Subnets []string `json:"subnets"`
+
+	// Gateway is the router of the out-of-band network the Subnets are
+	// reached through. The Subnets are considered directly connected to
+	// the Interface when not set.
+	// +optional
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// OperandLogFormat is the format of the logs of the metal3 services.
+// +kubebuilder:validation:Enum=Text;JSON
+type OperandLogFormat string
+
+const (
+	// OperandLogFormatText keeps the logs of the services unchanged.
+	OperandLogFormatText OperandLogFormat = "Text"
+	// OperandLogFormatJSON writes the logs as JSON records.
+	OperandLogFormatJSON OperandLogFormat = "JSON"
+)
+
+// PodSecurityMode selects the security context of the metal3 containers.
+// +kubebuilder:validation:Enum=Privileged;Restricted
+type PodSecurityMode string
+
+const (
+	// PodSecurityModePrivileged runs the metal3 containers privileged.
+	PodSecurityModePrivileged PodSecurityMode = "Privileged"
+	// PodSecurityModeRestricted follows the restricted Pod Security
+	// profile wherever the metal3 containers allow it.
+	PodSecurityModeRestricted PodSecurityMode = "Restricted"
+)
+
+// VendorExtensions lists the hardware vendors whose Ironic drivers are
+// enabled.
+type VendorExtensions struct {
+	// IDRAC enables the idrac driver of Dell servers.
+	// +optional
+	IDRAC *IDRACExtension `json:"idrac,omitempty"`
+
+	// ILO enables the ilo drivers of HPE servers.
+	// +optional
+	ILO *ILOExtension `json:"ilo,omitempty"`
+
+	// IRMC enables the irmc driver of Fujitsu servers.
+	// +optional
+	IRMC *IRMCExtension `json:"irmc,omitempty"`
+
+	// XClarity enables the xclarity driver of Lenovo servers.
+	// +optional
+	XClarity *XClarityExtension `json:"xclarity,omitempty"`
+}
+
+// IDRACProtocol is the protocol the idrac driver talks to the BMC with.
+// +kubebuilder:validation:Enum=Redfish;WSMAN
+type IDRACProtocol string
+
+const (
+	// IDRACProtocolRedfish uses the Redfish API of iDRAC 9 and later.
+	IDRACProtocolRedfish IDRACProtocol = "Redfish"
+	// IDRACProtocolWSMAN uses the WS-Management API of older iDRACs.
+	IDRACProtocolWSMAN IDRACProtocol = "WSMAN"
+)
+
+// IDRACExtension configures the idrac driver.
+type IDRACExtension struct {
+	// Protocol is the protocol of the management, power, BIOS, RAID and
+	// inspection interfaces. Defaults to Redfish.
+	// +optional
+	Protocol IDRACProtocol `json:"protocol,omitempty"`
+}
+
+// ILOGeneration is the generation of the HPE iLO BMCs.
+// +kubebuilder:validation:Enum=ILO4;ILO5
+type ILOGeneration string
+
+const (
+	// ILOGeneration4 enables the ilo hardware type.
+	ILOGeneration4 ILOGeneration = "ILO4"
+	// ILOGeneration5 enables the ilo5 hardware type as well.
+	ILOGeneration5 ILOGeneration = "ILO5"
+)
+
+// ILOExtension configures the ilo drivers.
+type ILOExtension struct {
+	// Generation is the newest generation of the iLOs of the fleet.
+	// Defaults to ILO5.
+	// +optional
+	Generation ILOGeneration `json:"generation,omitempty"`
+
+	// EnableRAID enables the ilo5 RAID interface, which configures the
+	// RAID controllers out of band. Requires the ILO5 Generation.
+	// +optional
+	EnableRAID bool `json:"enableRAID,omitempty"`
+
+	// UseWebServerForImages serves the virtual media images from the
+	// image server rather than from Swift, which the cluster does not
+	// run.
+	// +optional
+	UseWebServerForImages bool `json:"useWebServerForImages,omitempty"`
+}
+
+// IRMCSNMPVersion is the SNMP version of the iRMC BMCs.
+// +kubebuilder:validation:Enum=v1;v2c;v3
+type IRMCSNMPVersion string
+
+const (
+	// IRMCSNMPv1 is SNMP v1, authenticated by a community.
+	IRMCSNMPv1 IRMCSNMPVersion = "v1"
+	// IRMCSNMPv2c is SNMP v2c, authenticated by a community.
+	IRMCSNMPv2c IRMCSNMPVersion = "v2c"
+	// IRMCSNMPv3 is SNMP v3, authenticated by a security name.
+	IRMCSNMPv3 IRMCSNMPVersion = "v3"
+)
+
+// IRMCExtension configures the irmc driver, which reads the power state
+// and the inventory of the hosts through SNMP.
+type IRMCExtension struct {
+	// SNMPVersion is the SNMP version of the BMCs. Defaults to v2c.
+	// +optional
+	SNMPVersion IRMCSNMPVersion `json:"snmpVersion,omitempty"`
+
+	// SNMPPort is the SNMP port of the BMCs. Defaults to 161.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	SNMPPort int32 `json:"snmpPort,omitempty"`
+
+	// SNMPCommunity is the SNMP community of the v1 and v2c versions.
+	// Defaults to public.
+	// +optional
+	SNMPCommunity string `json:"snmpCommunity,omitempty"`
+
+	// SNMPSecurity is the SNMP security name of the v3 version, which
+	// is then required.
+	// +optional
+	SNMPSecurity string `json:"snmpSecurity,omitempty"`
+}
+
+// FirmwareStaging is where the BMCs download the firmware bundles from.
+// +kubebuilder:validation:Enum=ImageServer;Direct
+type FirmwareStaging string
+
+const (
+	// FirmwareStagingImageServer has Ironic download the bundles of the
+	// firmware update steps and serve them from the image server, so that
+	// the BMCs only need to reach the provisioning network.
+	FirmwareStagingImageServer FirmwareStaging = "ImageServer"
+	// FirmwareStagingDirect passes the URLs of the firmware update steps
+	// to the BMCs as they are.
+	FirmwareStagingDirect FirmwareStaging = "Direct"
+)
+
+// FirmwareUpdates configures the firmware bundles served to the BMCs.
+type FirmwareUpdates struct {
+	// PersistentVolumeClaimName is the name of a PersistentVolumeClaim in
+	// the openshift-machine-api namespace holding the firmware bundles,
+	// served read-only under the firmware-bundles path of the image
+	// server. It is mounted on every node running a metal3 pod, so it
+	// must support the ReadOnlyMany or ReadWriteMany access mode.
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName"`
+
+	// Staging is where the BMCs download the bundles from. Defaults to
+	// ImageServer.
+	// +optional
+	Staging FirmwareStaging `json:"staging,omitempty"`
+}
+
+// XClarityExtension configures the xclarity driver. The address and the
+// credentials of the XClarity Controller are set on each host.
+type XClarityExtension struct {
+}
+
+// OperandLogLevel is the verbosity of the metal3 services.
+// +kubebuilder:validation:Enum=Normal;Debug;Trace
+type OperandLogLevel string
+
+const (
+	// OperandLogLevelNormal logs the informational messages.
+	OperandLogLevelNormal OperandLogLevel = "Normal"
+	// OperandLogLevelDebug adds the debug messages of the services.
+	OperandLogLevelDebug OperandLogLevel = "Debug"
+	// OperandLogLevelTrace adds the debug messages of the libraries the
+	// services use, such as their database and HTTP clients.
+	OperandLogLevelTrace OperandLogLevel = "Trace"
+)
+
+// ProvisioningProfile selects the metal3 services that are run.
+// +kubebuilder:validation:Enum=Default;Minimal
+type ProvisioningProfile string
+
+const (
+	// ProvisioningProfileDefault runs all the metal3 services.
+	ProvisioningProfileDefault ProvisioningProfile = "Default"
+	// ProvisioningProfileMinimal only runs the baremetal-operator, Ironic,
+	// the image server and the manager of the ProvisioningIP.
+	ProvisioningProfileMinimal ProvisioningProfile = "Minimal"
+)
+
+// BIOSProfile is a set of BIOS settings applied by an Ironic deploy
+// template.
+type BIOSProfile struct {
+	// Name is the suffix of the trait of the deploy template.
+	// +kubebuilder:validation:Pattern=`^[A-Z0-9_]+$`
+	// +kubebuilder:validation:MaxLength=200
+	Name string `json:"name"`
+
+	// ConfigMapName is the name of the ConfigMap of the
+	// openshift-machine-api namespace holding the settings, keyed by
+	// the names the hardware interface of the hosts gives them.
+	ConfigMapName string `json:"configMapName"`
+}
+
+// RAIDTemplate is a RAID layout applied by an Ironic deploy template.
+type RAIDTemplate struct {
+	// Name is the suffix of the trait of the deploy template.
+	// +kubebuilder:validation:Pattern=`^[A-Z0-9_]+$`
+	// +kubebuilder:validation:MaxLength=200
+	Name string `json:"name"`
+
+	// LogicalDisks are the volumes created, replacing the existing ones.
+	// +kubebuilder:validation:MinItems=1
+	LogicalDisks []RAIDLogicalDisk `json:"logicalDisks"`
+}
+
+// RAIDLogicalDisk is a volume of a RAID layout.
+type RAIDLogicalDisk struct {
+	// SizeGB is the size of the volume in GiB. The volume takes all the
+	// space left when not set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SizeGB *int32 `json:"sizeGB,omitempty"`
+
+	// RAIDLevel is the RAID level of the volume.
+	// +kubebuilder:validation:Enum="0";"1";"2";"5";"6";"1+0";"5+0";"6+0"
+	RAIDLevel string `json:"raidLevel"`
+
+	// IsRootVolume makes the volume the root device of the host. At most
+	// one volume of a layout can be the root volume.
+	// +optional
+	IsRootVolume bool `json:"isRootVolume,omitempty"`
+
+	// NumberOfPhysicalDisks is the number of disks the volume spans.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	NumberOfPhysicalDisks int32 `json:"numberOfPhysicalDisks,omitempty"`
+
+	// Controller is the RAID controller the volume is created on, as
+	// named by the hardware interface of the hosts.
+	// +optional
+	Controller string `json:"controller,omitempty"`
+
+	// PhysicalDisks are the disks the volume spans, as named by the
+	// hardware interface of the hosts. Requires a Controller.
+	// +optional
+	PhysicalDisks []string `json:"physicalDisks,omitempty"`
+}
+
+// ImageServerMount is a volume served by the image server. Exactly one of
+// ConfigMapName and PersistentVolumeClaimName must be set.
+type ImageServerMount struct {
+	// Path is the directory the content is served from, relative to the
+	// root of the image server.
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`
+	Path string `json:"path"`
+
+	// ConfigMapName is the name of a ConfigMap whose keys are served as
+	// files.
+	ConfigMapName string `json:"configMapName,omitempty"`
+
+	// PersistentVolumeClaimName is the name of a PersistentVolumeClaim
+	// whose content is served. It is mounted read-only, and must be
+	// ReadOnlyMany when HighAvailability is set.
+	PersistentVolumeClaimName string `json:"persistentVolumeClaimName,omitempty"`
+}
+
+// IronicAPIAudit configures where the Ironic API audit records are kept.
+// Records are written to /var/log/metal3/ironic-api-audit.log on the node
+// running the metal3 pod.
+type IronicAPIAudit struct {
+	// MaxFileSizeMB is the size in megabytes at which the audit log is
+	// rotated. Defaults to 100.
+	// +kubebuilder:validation:Minimum=1
+	MaxFileSizeMB int32 `json:"maxFileSizeMB,omitempty"`
+
+	// MaxFiles is the number of rotated audit logs kept. Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	MaxFiles int32 `json:"maxFiles,omitempty"`
+
+	// SyslogEndpoint is the host:port of a syslog server the audit
+	// records are also forwarded to over TCP.
+	SyslogEndpoint string `json:"syslogEndpoint,omitempty"`
+}
+
+// InspectorRules references the introspection rules of Ironic Inspector.
+type InspectorRules struct {
+	// ConfigMapName is the name of the ConfigMap of the
+	// openshift-machine-api namespace holding the rules.
+	ConfigMapName string `json:"configMapName"`
+
+	// Key is the key of the ConfigMap holding the rules, as a JSON list
+	// in the format of the Ironic Inspector rules API. Defaults to
+	// rules.json.
+	Key string `json:"key,omitempty"`
+}
+
+// HardwareMetrics configures the polling of the sensors of the hosts.
+type HardwareMetrics struct {
+	// IntervalSeconds is how often the sensors of each host are read.
+	// BMCs are slow to answer, so it defaults to 600.
+	// +kubebuilder:validation:Minimum=60
+	IntervalSeconds int32 `json:"intervalSeconds,omitempty"`
+}
+
+// OrphanCleanup configures the removal of the orphaned Ironic nodes.
+type OrphanCleanup struct {
+	// IntervalMinutes is how often orphaned Ironic nodes are looked for.
+	// Defaults to 60.
+	// +kubebuilder:validation:Minimum=5
+	IntervalMinutes int32 `json:"intervalMinutes,omitempty"`
+}
+
+// OSImageChecksumType is the algorithm of the checksum of the provisioning
+// OS image.
+// +kubebuilder:validation:Enum=sha256;sha512
+type OSImageChecksumType string
+
+const (
+	// OSImageChecksumSHA256 is a SHA-256 checksum
+	OSImageChecksumSHA256 OSImageChecksumType = "sha256"
+	// OSImageChecksumSHA512 is a SHA-512 checksum
+	OSImageChecksumSHA512 OSImageChecksumType = "sha512"
+)
+
+// OSImageFormat is the disk format of the provisioning OS image, once
+// decompressed.
+// +kubebuilder:validation:Enum=qcow2;raw
+type OSImageFormat string
+
+const (
+	// OSImageFormatQCOW2 is a qcow2 disk image
+	OSImageFormatQCOW2 OSImageFormat = "qcow2"
+	// OSImageFormatRaw is a raw disk image
+	OSImageFormatRaw OSImageFormat = "raw"
+)
+
+// ProvisioningOSImage locates the provisioning OS image and the checksum
+// it is verified against.
+type ProvisioningOSImage struct {
+	// URL is the location of the image, without checksum.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// Checksum is the hexadecimal checksum of the uncompressed image.
+	// +kubebuilder:validation:Pattern=`^[0-9a-fA-F]*$`
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+
+	// ChecksumType is the algorithm of the Checksum. Defaults to sha256.
+	// The sha512 checksums are verified by the operator, which then
+	// downloads the image itself.
+	// +optional
+	ChecksumType OSImageChecksumType `json:"checksumType,omitempty"`
+
+	// Format is the disk format of the uncompressed image. When set, the
+	// downloaded image is checked to be of this format before it is
+	// served.
+	// +optional
+	Format OSImageFormat `json:"format,omitempty"`
+
+	// Mirrors are other locations of the same image, tried in order when
+	// the download from the URL fails. They must serve a file of the same
+	// name, and require a Checksum so that the image is verified wherever
+	// it comes from.
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	Mirrors []string `json:"mirrors,omitempty"`
+
+	// Delta is a patch producing the image from an earlier image kept in
+	// the ImageCache. It is downloaded instead of the whole image when
+	// that image is cached, saving bandwidth on upgrades. The whole image
+	// is downloaded when the earlier image is not cached, or the patched
+	// image does not match the Checksum.
+	// +optional
+	Delta *OSImageDelta `json:"delta,omitempty"`
+}
+
+// OSImageDelta is a zstd patch between two provisioning OS images, as
+// made by zstd --patch-from.
+type OSImageDelta struct {
+	// URL is the location of the patch.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// BaseURL is the URL the image the patch applies to was downloaded
+	// from, which names it in the ImageCache.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	BaseURL string `json:"baseURL"`
+}
+
+// UEFIBootLoader is the boot loader served to the UEFI hosts booting from
+// the network.
+type UEFIBootLoader string
+
+const (
+	// UEFIBootLoaderIPXE chainloads iPXE
+	UEFIBootLoaderIPXE UEFIBootLoader = "iPXE"
+	// UEFIBootLoaderGRUB boots the GRUB network boot images
+	UEFIBootLoaderGRUB UEFIBootLoader = "GRUB"
+)
+
+// OSImageSignatureType is the kind of signature of the provisioning OS
+// image.
+type OSImageSignatureType string
+
+const (
+	// OSImageSignatureGPG is a detached GPG signature
+	OSImageSignatureGPG OSImageSignatureType = "GPG"
+	// OSImageSignatureSigstore is a signature made by cosign sign-blob
+	// with a key pair. Keyless signatures are not supported.
+	OSImageSignatureSigstore OSImageSignatureType = "Sigstore"
+)
+
+// OSImageSignatureRef locates the signature of the provisioning OS image
+// and the key it must be signed with.
+type OSImageSignatureRef struct {
+	// Type is the kind of signature.
+	// +kubebuilder:validation:Enum=GPG;Sigstore
+	Type OSImageSignatureType `json:"type"`
+
+	// SignatureURL is the URL the detached signature is downloaded from.
+	// The signature covers the decompressed image, as cached and served
+	// by the image server.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	SignatureURL string `json:"signatureURL"`
+
+	// KeyConfigMap is the name of a ConfigMap in the
+	// openshift-machine-api namespace holding, in its key.pub key, the
+	// public key the image must be signed with: an armored GPG public key
+	// or a PEM encoded sigstore public key.
+	KeyConfigMap string `json:"keyConfigMap"`
+}
+
+// ImagePullSecretRef locates the credentials of the downloads of the
+// provisioning OS image and the IPA artifacts.
+type ImagePullSecretRef struct {
+	// Name is the name of a Secret in the openshift-machine-api namespace
+	// holding, in its .dockerconfigjson key, the credentials of each host
+	// in the format of the pull secrets.
+	Name string `json:"name"`
+}
+
+// ImageCache is the eviction policy of the provisioning OS image cache.
+// The image currently in use is never evicted.
+type ImageCache struct {
+	// Size is the disk space the cached images may use. The least
+	// recently used images are evicted when it is exceeded.
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Retention is how long an image is kept after it was last used.
+	Retention *metav1.Duration `json:"retention,omitempty"`
+}
+
+// DHCPRelayRange is a DHCP range for the hosts of a subnet that is not
+// directly attached to the provisioning network.
+type DHCPRelayRange struct {
+	// NetworkCIDR is the routed subnet the hosts are connected to. The
+	// DHCP relay of the subnet must use an address of this network as
+	// its gateway address.
+	// +kubebuilder:validation:Pattern=`^([0-9]{1,3}\.){3}[0-9]{1,3}/[0-9]{1,2}$|^[0-9a-fA-F:.]*:[0-9a-fA-F:.]*/[0-9]{1,3}$`
+	NetworkCIDR string `json:"networkCIDR"`
+
+	// DHCPRange is the range of addresses leased to the hosts of the
+	// subnet, as two comma separated IP addresses within NetworkCIDR.
+	// +kubebuilder:validation:Pattern=`^ *([0-9]{1,3}\.){3}[0-9]{1,3} *, *([0-9]{1,3}\.){3}[0-9]{1,3} *$|^ *[0-9a-fA-F:.]*:[0-9a-fA-F:.]* *, *[0-9a-fA-F:.]*:[0-9a-fA-F:.]* *$`
+	DHCPRange string `json:"dhcpRange"`
+
+	// Router is the default gateway advertised to the hosts of the
+	// subnet. Required for IPv4 subnets; IPv6 hosts learn their routers
+	// from router advertisements.
+	// +kubebuilder:validation:Pattern=`^$|^([0-9]{1,3}\.){3}[0-9]{1,3}$|^[0-9a-fA-F:.]*:[0-9a-fA-F:.]*$`
+	Router string `json:"router,omitempty"`
+}
+
+// TFTPConfig configures the files and transfers of the TFTP server.
+type TFTPConfig struct {
+	// FilesConfigMap is the name of a ConfigMap in the
+	// openshift-machine-api namespace whose keys, such as undionly.kpxe
+	// or snponly.efi, are served instead of the iPXE builds of the metal3
+	// image. It must hold every boot file requested by the hosts, and
+	// changes to it are served without restarting the TFTP server.
+	FilesConfigMap string `json:"filesConfigMap,omitempty"`
+
+	// MaxBlockSize caps the size of the blocks negotiated with the hosts,
+	// for NIC firmware failing to receive large blocks.
+	// +kubebuilder:validation:Minimum=512
+	// +kubebuilder:validation:Maximum=65464
+	MaxBlockSize int32 `json:"maxBlockSize,omitempty"`
+
+	// DisableBlockSizeNegotiation ignores the block size requested by the
+	// hosts, which then receive 512 bytes blocks, for NIC firmware
+	// negotiating a block size it cannot handle.
+	DisableBlockSizeNegotiation bool `json:"disableBlockSizeNegotiation,omitempty"`
+}
+
+// DHCPCircuitIDMapping configures the DHCP leases of the hosts identified
+// by a relay agent circuit ID. At least one of IPAddress and BootFile must
+// be set.
+type DHCPCircuitIDMapping struct {
+	// CircuitID is the circuit ID set by the relay agent, either as text
+	// or as colon separated hexadecimal bytes.
+	// +kubebuilder:validation:Pattern=`^[^,\s"]+$`
+	CircuitID string `json:"circuitID"`
+
+	// IPAddress is the address leased to the host. It must belong to the
+	// provisioning network or to one of the DHCPRelayRanges networks,
+	// outside of their DHCP ranges.
+	// +kubebuilder:validation:Pattern=`^$|^([0-9]{1,3}\.){3}[0-9]{1,3}$`
+	IPAddress string `json:"ipAddress,omitempty"`
+
+	// BootFile replaces the boot file served to the host, such as the
+	// URL of a custom iPXE script.
+	BootFile string `json:"bootFile,omitempty"`
+}
+
+// IronicAPIExposure configures the authenticated access to the Ironic API.
+type IronicAPIExposure struct {
+	// ClientCAConfigMap is the name of a ConfigMap in the
+	// openshift-machine-api namespace holding, in its ca-bundle.crt key,
+	// the certificate authorities that client certificates must be
+	// signed by.
+	ClientCAConfigMap string `json:"clientCAConfigMap"`
+
+	// CreateRoute additionally exposes the Service through a Route with
+	// passthrough TLS termination, for clients outside of the cluster.
+	CreateRoute bool `json:"createRoute,omitempty"`
+}
+
+// LivePXEArtifacts are the download locations of the RHCOS live PXE
+// artifacts. As with the ProvisioningOSDownloadURL, a sha256 query
+// parameter has the download verified against the checksum it holds.
+type LivePXEArtifacts struct {
+	// KernelURL is the location of the live kernel.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	KernelURL string `json:"kernelURL"`
+
+	// InitramfsURL is the location of the live initramfs.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	InitramfsURL string `json:"initramfsURL"`
+
+	// RootfsURL is the location of the live rootfs, fetched by the
+	// initramfs once booted.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	RootfsURL string `json:"rootfsURL"`
+}
+
+// BootArtifactURLs are the URLs the boot artifacts are served from.
+type BootArtifactURLs struct {
+	// DeployKernel is the URL of the deploy ramdisk kernel.
+	DeployKernel string `json:"deployKernel,omitempty"`
+
+	// DeployRamdisk is the URL of the deploy ramdisk initramfs.
+	DeployRamdisk string `json:"deployRamdisk,omitempty"`
+
+	// OSImage is the URL of the cached provisioning OS image, in the raw
+	// format when ConvertOSImageToRaw is set.
+	OSImage string `json:"osImage,omitempty"`
+
+	// OSImageChecksum is the URL of the SHA256 checksum of the raw OS
+	// image, when ConvertOSImageToRaw is set.
+	OSImageChecksum string `json:"osImageChecksum,omitempty"`
+
+	// Firmware is the URL of the directory the firmware bundles are
+	// served from, when FirmwareUpdates is set.
+	Firmware string `json:"firmware,omitempty"`
+
+	// LiveKernel, LiveInitramfs and LiveRootfs are the URLs of the cached
+	// RHCOS live PXE artifacts, when LivePXEArtifacts is set. They stay
+	// the same when the artifacts are updated.
+	LiveKernel    string `json:"liveKernel,omitempty"`
+	LiveInitramfs string `json:"liveInitramfs,omitempty"`
+	LiveRootfs    string `json:"liveRootfs,omitempty"`
+}
+
+// ImageServerStatus describes the URLs published by the image server.
+type ImageServerStatus struct {
+	// HTTP are the plain HTTP URLs of the boot artifacts.
+	HTTP BootArtifactURLs `json:"http,omitempty"`
+
+	// HTTPS are the HTTPS URLs of the boot artifacts. They are only set
+	// when the HTTPS listener is enabled.
+	HTTPS *BootArtifactURLs `json:"https,omitempty"`
+}
+
+// OSImageStatus describes the provisioning OS image served by the
+// metal3 image cache.
+type OSImageStatus struct {
+	// URL is the location the current OS image was downloaded from.
+	URL string `json:"url,omitempty"`
+
+	// Version is the RHCOS release of the current OS image, when known.
+	Version string `json:"version,omitempty"`
+
+	// PreviousURL is the location of the OS image that was replaced by
+	// the last image update.
+	PreviousURL string `json:"previousURL,omitempty"`
+
+	// PreviousVersion is the RHCOS release of the OS image that was
+	// replaced by the last image update.
+	PreviousVersion string `json:"previousVersion,omitempty"`
+
+	// Download is the progress of the download of the current OS image
+	// by the metal3 pod. It is only reported while the image server runs
+	// alongside the download, which is not the case when the image is
+	// verified or converted before the metal3 services start.
+	// +optional
+	Download *OSImageDownloadStatus `json:"download,omitempty"`
+}
+
+// OSImageDownloadState is the state of the download of the provisioning
+// OS image.
+type OSImageDownloadState string
+
+const (
+	// OSImageDownloading means the image is being downloaded
+	OSImageDownloading OSImageDownloadState = "Downloading"
+	// OSImageDownloadComplete means the image is cached and served
+	OSImageDownloadComplete OSImageDownloadState = "Complete"
+)
+
+// OSImageDownloadStatus is the progress of the download of the
+// provisioning OS image.
+type OSImageDownloadStatus struct {
+	// State is whether the download is still running.
+	State OSImageDownloadState `json:"state"`
+
+	// BytesDownloaded is the size of the image downloaded so far.
+	BytesDownloaded int64 `json:"bytesDownloaded"`
+
+	// BytesTotal is the size of the image, when the server reports it.
+	// +optional
+	BytesTotal int64 `json:"bytesTotal,omitempty"`
+
+	// PercentComplete is the share of the image downloaded so far, when
+	// its size is known.
+	// +optional
+	PercentComplete *int32 `json:"percentComplete,omitempty"`
+
+	// LastUpdateTime is when the progress was last read from the metal3
+	// pod.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
+// HighAvailability configures the replicas of the metal3 pod
+type HighAvailability struct {
+	// Replicas is the number of metal3 pods. When fewer nodes can run
+	// them, only one pod runs on each.
+	// +kubebuilder:validation:Minimum=2
+	Replicas int32 `json:"replicas"`
+}
+
+// ProvisioningVIP configures the failover of the ProvisioningIP.
+type ProvisioningVIP struct {
+	// GratuitousARPCount is the number of gratuitous ARP requests sent
+	// by the node taking over the ProvisioningIP. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=20
+	// +optional
+	GratuitousARPCount int32 `json:"gratuitousARPCount,omitempty"`
+}
+
+// ConductorGroup is a group of Ironic conductors.
+type ConductorGroup struct {
+	// Name is the conductor_group of the Ironic nodes the group serves.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// Replicas is the number of conductors of the group, each on a
+	// distinct node. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
+	// NodeSelector selects the nodes the conductors of the group run
+	// on. They run on the nodes of the metal3 pods when not set.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// OperandMetadata is the metadata added to the generated resources
+type OperandMetadata struct {
+	// Labels are added to the labels of the generated resources.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the annotations of the generated
+	// resources.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // ProvisioningStatus defines the observed state of Provisioning
 type ProvisioningStatus struct {
 	operatorv1.OperatorStatus `json:",inline"`
+
+	// OSImage describes the provisioning OS image currently in use.
+	OSImage OSImageStatus `json:"osImage,omitempty"`
+
+	// DetectedNetwork is the provisioning network found by the Auto
+	// ProvisioningNetwork mode.
+	DetectedNetwork *DetectedProvisioningNetwork `json:"detectedNetwork,omitempty"`
+
+	// DHCPRange is the range of IP addresses served by the metal3 DHCP
+	// server. It differs from the ProvisioningDHCPRange when a default
+	// range was computed.
+	DHCPRange string `json:"dhcpRange,omitempty"`
+
+	// IronicEndpoint is the URL of the Ironic API served by the metal3
+	// pods.
+	IronicEndpoint string `json:"ironicEndpoint,omitempty"`
+
+	// ImageServer describes the URLs boot artifacts are served from.
+	ImageServer ImageServerStatus `json:"imageServer,omitempty"`
+
+	// IgnitionOverridesURL is the URL the ignition overrides are served
+	// under, at <URL>/<host>/ignition and <URL>/<host>/network, when
+	// EnableIgnitionOverrides is set.
+	IgnitionOverridesURL string `json:"ignitionOverridesURL,omitempty"`
+
+	// RolloutHash identifies the metal3 resources rendered for the spec
+	// of the ObservedGeneration. The metal3 Deployment and DaemonSet
+	// match it when their baremetal.openshift.io/rollout-hash annotation
+	// has the same value.
+	RolloutHash string `json:"rolloutHash,omitempty"`
+
+	// LastSuccessfulConfiguration is the last configuration that produced
+	// healthy metal3 pods, to compare with the spec when it fails to be
+	// applied.
+	LastSuccessfulConfiguration *SuccessfulConfiguration `json:"lastSuccessfulConfiguration,omitempty"`
+
+	// LastAction is the outcome of the last action requested with the
+	// baremetal.openshift.io/action annotation.
+	LastAction *ActionStatus `json:"lastAction,omitempty"`
+
+	// ConductorGroups describe the conductors running for the
+	// ConductorGroups of the spec.
+	ConductorGroups []ConductorGroupStatus `json:"conductorGroups,omitempty"`
+
+	// TopologyProfile is how the metal3 pods are deployed for the
+	// control plane topology of the cluster.
+	TopologyProfile TopologyProfile `json:"topologyProfile,omitempty"`
+
+	// NetworkMigration describes the latest change of the
+	// ProvisioningIP or ProvisioningNetworkCIDR.
+	NetworkMigration *NetworkMigration `json:"networkMigration,omitempty"`
+
+	// ProvisioningIPs are the addresses of the ProvisioningIPPool
+	// allocated to the nodes that can run the metal3 pods.
+	ProvisioningIPs []NodeProvisioningIP `json:"provisioningIPs,omitempty"`
+
+	// ProvisioningVIPNode is the node of the active metal3 pod, which
+	// holds the ProvisioningIP, when HighAvailability is set.
+	ProvisioningVIPNode string `json:"provisioningVIPNode,omitempty"`
+
+	// LastOrphanCleanup is the outcome of the last search for orphaned
+	// Ironic nodes.
+	LastOrphanCleanup *OrphanCleanupStatus `json:"lastOrphanCleanup,omitempty"`
+
+	// OperandLogLevel is the log level of the metal3 pods once they
+	// became healthy with it.
+	OperandLogLevel OperandLogLevel `json:"operandLogLevel,omitempty"`
+
+	// RAIDTemplates are the traits of the deploy templates registered in
+	// Ironic for the DefaultRAIDConfig.
+	RAIDTemplates []string `json:"raidTemplates,omitempty"`
+
+	// BIOSTemplates are the traits of the deploy templates registered in
+	// Ironic for the BIOSProfiles.
+	BIOSTemplates []string `json:"biosTemplates,omitempty"`
+
+	// Capacity describes how many hosts the metal3 services can serve
+	// at once, to plan a scale-out.
+	Capacity *ProvisioningCapacity `json:"capacity,omitempty"`
+
+	// SmokeTest is the outcome of the smoke test of the last
+	// configuration rolled out, when SmokeTest is set.
+	SmokeTest *SmokeTestStatus `json:"smokeTest,omitempty"`
+
+	// ResourceFootprint is the CPU and memory usage of the metal3
+	// containers measured after the current configuration rolled out,
+	// with the resources recommended for them. Only measured when the
+	// cluster serves the resource metrics API.
+	ResourceFootprint *ResourceFootprint `json:"resourceFootprint,omitempty"`
+}
+
+// ResourceFootprint is the peak usage of the metal3 containers sampled
+// over a measurement window.
+type ResourceFootprint struct {
+	// RolloutHash is the rollout hash of the operands measured.
+	RolloutHash string `json:"rolloutHash"`
+
+	// StartTime is when the first sample was taken.
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime is when the measurement window ended. The
+	// recommendations are only published once it is set.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Samples is how many times the usage was sampled.
+	Samples int32 `json:"samples"`
+
+	// Containers are the footprints of the metal3 containers.
+	Containers []ContainerFootprint `json:"containers,omitempty"`
+}
+
+// ContainerFootprint is the usage of a metal3 container, the highest of
+// its replicas.
+type ContainerFootprint struct {
+	// Name is the name of the container.
+	Name string `json:"name"`
+
+	// PeakCPU is the highest CPU usage sampled.
+	PeakCPU resource.Quantity `json:"peakCPU"`
+
+	// PeakMemory is the highest memory usage sampled.
+	PeakMemory resource.Quantity `json:"peakMemory"`
+
+	// RecommendedCPU is the CPU request recommended for the container,
+	// with headroom over the peak usage.
+	// +optional
+	RecommendedCPU *resource.Quantity `json:"recommendedCPU,omitempty"`
+
+	// RecommendedMemory is the memory limit recommended for the
+	// container, with headroom over the peak usage.
+	// +optional
+	RecommendedMemory *resource.Quantity `json:"recommendedMemory,omitempty"`
+}
+
+// SmokeTestResult is the outcome of a smoke test.
+type SmokeTestResult string
+
+const (
+	// SmokeTestRunning means the smoke test Job has not completed yet
+	SmokeTestRunning SmokeTestResult = "Running"
+	// SmokeTestPassed means every check of the smoke test passed
+	SmokeTestPassed SmokeTestResult = "Passed"
+	// SmokeTestFailed means a check of the smoke test failed, or the Job
+	// did not report its checks
+	SmokeTestFailed SmokeTestResult = "Failed"
+)
+
+// SmokeTestCheck is the outcome of a check of the smoke test.
+type SmokeTestCheck struct {
+	// Name is what the check exercised.
+	Name string `json:"name"`
+
+	// Passed is whether the check passed.
+	Passed bool `json:"passed"`
+
+	// Message describes why the check failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SmokeTestStatus is the outcome of the smoke test of a configuration.
+type SmokeTestStatus struct {
+	// RolloutHash is the rollout hash of the operands tested.
+	RolloutHash string `json:"rolloutHash"`
+
+	// Result is the outcome of the smoke test.
+	Result SmokeTestResult `json:"result"`
+
+	// Checks are the outcomes of the checks run.
+	// +optional
+	Checks []SmokeTestCheck `json:"checks,omitempty"`
+
+	// StartTime is when the smoke test Job was created.
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime is when the outcome of the smoke test was recorded.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// ProvisioningCapacity describes how many hosts the metal3 services can
+// serve at once.
+type ProvisioningCapacity struct {
+	// DHCPAddresses is the number of addresses the metal3 DHCP server
+	// hands out, including the relayed ranges. Not set when it serves
+	// no DHCP.
+	// +optional
+	DHCPAddresses *int64 `json:"dhcpAddresses,omitempty"`
+
+	// DHCPLeases is the number of addresses leased by the metal3 DHCP
+	// server when last checked. Not set when it could not be read.
+	// +optional
+	DHCPLeases *int64 `json:"dhcpLeases,omitempty"`
+
+	// MaxParallelDeploys is the number of hosts that can be provisioned
+	// at once, limited by the baremetal-operator and by the memory the
+	// Ironic conductor is allowed to use.
+	MaxParallelDeploys int32 `json:"maxParallelDeploys"`
+}
+
+// OrphanCleanupStatus is the outcome of a search for orphaned Ironic
+// nodes.
+type OrphanCleanupStatus struct {
+	// Time is when the search ran.
+	Time metav1.Time `json:"time"`
+
+	// RemovedNodes are the UUIDs of the Ironic nodes deleted.
+	RemovedNodes []string `json:"removedNodes,omitempty"`
+
+	// SkippedNodes are the UUIDs of the orphaned Ironic nodes kept as
+	// they are deployed or busy.
+	SkippedNodes []string `json:"skippedNodes,omitempty"`
+}
+
+// NodeProvisioningIP is the address of the ProvisioningIPPool allocated
+// to a node.
+type NodeProvisioningIP struct {
+	// Node is the name of the node.
+	Node string `json:"node"`
+
+	// IP is the address configured on the provisioning interface of the
+	// node.
+	IP string `json:"ip"`
+}
+
+// NetworkMigrationPhase is the progress of a change of the provisioning
+// network.
+// +kubebuilder:validation:Enum=Draining;Migrating;Complete
+type NetworkMigrationPhase string
+
+const (
+	// NetworkMigrationDraining means the metal3 pods keep running on the
+	// previous network until no host is being provisioned or cleaned, or
+	// for an hour at most. The other hosts are paused meanwhile, so that
+	// none starts provisioning.
+	NetworkMigrationDraining NetworkMigrationPhase = "Draining"
+	// NetworkMigrationMigrating means the static IPs, the DHCP server and
+	// the Ironic endpoints are being moved to the new network.
+	NetworkMigrationMigrating NetworkMigrationPhase = "Migrating"
+	// NetworkMigrationComplete means the metal3 pods run healthy on the
+	// new network.
+	NetworkMigrationComplete NetworkMigrationPhase = "Complete"
+)
+
+// NetworkMigration describes a change of the provisioning network. The
+// metal3 pods are only moved to the new network once no host is being
+// provisioned or cleaned, or once the wait timed out, and the change is
+// complete once they are healthy on it.
+type NetworkMigration struct {
+	// FromIP and FromCIDR are the ProvisioningIP and
+	// ProvisioningNetworkCIDR the metal3 pods were healthy with.
+	FromIP   string `json:"fromIP,omitempty"`
+	FromCIDR string `json:"fromCIDR,omitempty"`
+
+	// ToIP and ToCIDR are the ProvisioningIP and ProvisioningNetworkCIDR
+	// of the spec.
+	ToIP   string `json:"toIP,omitempty"`
+	ToCIDR string `json:"toCIDR,omitempty"`
+
+	// Phase is the progress of the change.
+	Phase NetworkMigrationPhase `json:"phase"`
+
+	// StartTime is when the change was noticed.
+	StartTime metav1.Time `json:"startTime"`
+
+	// CompletionTime is when the metal3 pods became healthy on the new
+	// network.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// TopologyProfile is how the metal3 pods are deployed for the control
+// plane topology of the cluster.
+// +kubebuilder:validation:Enum=HighlyAvailable;SingleNode
+type TopologyProfile string
+
+const (
+	// TopologyProfileHighlyAvailable deploys the metal3 pods for a
+	// control plane of several nodes.
+	TopologyProfileHighlyAvailable TopologyProfile = "HighlyAvailable"
+	// TopologyProfileSingleNode deploys the metal3 pods for a single
+	// node OpenShift cluster: Ironic keeps its database in SQLite
+	// instead of running MariaDB, and the pods are not evicted from the
+	// node when it becomes not ready or unreachable, as there is no
+	// other node to run them.
+	TopologyProfileSingleNode TopologyProfile = "SingleNode"
+)
+
+// ConductorGroupStatus describes the conductors of a group.
+type ConductorGroupStatus struct {
+	// Name is the name of the group.
+	Name string `json:"name"`
+
+	// ReadyReplicas is the number of conductors of the group that are
+	// ready.
+	ReadyReplicas int32 `json:"readyReplicas"`
+
+	// Endpoints are the JSON-RPC endpoints of the ready conductors of
+	// the group, as host:port.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Hosts is the number of BareMetalHosts labelled for the group.
+	Hosts int `json:"hosts"`
+}
+
+// ActionResult is the outcome of a requested action.
+type ActionResult string
+
+const (
+	// ActionSucceeded means the action was carried out.
+	ActionSucceeded ActionResult = "Succeeded"
+	// ActionFailed means the action was not carried out.
+	ActionFailed ActionResult = "Failed"
+)
+
+// ActionStatus describes an action requested on the Provisioning
+// resource. The actions are requested by setting the
+// baremetal.openshift.io/action annotation to their name, which is
+// removed once the action has run:
+// RefreshImages rolls out the metal3 pods, which empty the ImageCache of
+// their node, so that they download the deploy ramdisk and the
+// provisioning OS image again. The refreshes are counted by the
+// baremetal.openshift.io/image-refresh-generation annotation.
+// RotateCredentials replaces the Ironic and Inspector passwords and
+// restarts the metal3 pods to use them.
+// Revalidate runs the checks of the provisioning interface on the nodes
+// again.
+// CollectSupportBundle writes the Provisioning resource, its validation,
+// the metal3 workloads, the status and recent logs of the metal3 and
+// operator pods, with credentials removed, as a tarball in the
+// support-bundle.tar.gz key of the metal3-support-bundle ConfigMap of
+// the openshift-machine-api namespace.
+type ActionStatus struct {
+	// Name is the name of the action.
+	Name string `json:"name"`
+
+	// Result is the outcome of the action.
+	// +kubebuilder:validation:Enum=Succeeded;Failed
+	Result ActionResult `json:"result"`
+
+	// Message describes what the action did, or why it failed.
+	Message string `json:"message,omitempty"`
+
+	// Time is when the action ran.
+	Time metav1.Time `json:"time"`
+}
+
+// SuccessfulConfiguration is a configuration that produced healthy metal3
+// pods.
+type SuccessfulConfiguration struct {
+	// Spec is the spec of the Provisioning resource.
+	Spec ProvisioningSpec `json:"spec"`
+
+	// Generation is the generation of the Provisioning resource.
+	Generation int64 `json:"generation"`
+
+	// RolloutHash identifies the metal3 resources rendered for the spec.
+	RolloutHash string `json:"rolloutHash"`
+
+	// Time is when the metal3 pods of the spec were found healthy.
+	Time metav1.Time `json:"time"`
+}
+
+// DetectedProvisioningNetwork is the provisioning network used by the
+// Auto ProvisioningNetwork mode.
+type DetectedProvisioningNetwork struct {
+	// Interface is the provisioning interface of the nodes.
+	Interface string `json:"interface"`
+
+	// Source is how the Interface was found.
+	Source ProvisioningNetworkSource `json:"source"`
+
+	// Node is the node the Interface was found on, with the LinkLocal
+	// Source.
+	// +optional
+	Node string `json:"node,omitempty"`
+
+	// ProvisioningIP is the address of the metal3 pod on the network.
+	ProvisioningIP string `json:"provisioningIP"`
+
+	// ProvisioningNetworkCIDR is the network the hosts are provisioned on.
+	ProvisioningNetworkCIDR string `json:"provisioningNetworkCIDR"`
 }
 
-// +kubebuilder:resource:path=provisionings,scope=Cluster
+// +kubebuilder:resource:path=provisionings,scope=Cluster,shortName=prov;metal3prov,categories=metal3;baremetal
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".spec.provisioningNetwork",description="Provisioning network mode"
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the metal3 pods are ready"
+// +kubebuilder:printcolumn:name="Ironic",type="string",JSONPath=".status.ironicEndpoint",description="URL of the Ironic API"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Provisioning contains configuration used by the Provisioning
 // service (Ironic) to provision baremetal hosts.
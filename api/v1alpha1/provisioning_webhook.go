@@ -0,0 +1,53 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// ProvisioningSingletonName is the only name accepted for the Provisioning
+// resource, which is a singleton
+const ProvisioningSingletonName = "provisioning-configuration"
+
+var _ webhook.Validator = &Provisioning{}
+
+// ValidateCreate rejects any Provisioning not named
+// ProvisioningSingletonName, unless it defines a provisioning domain.
+// Whether provisioning domains are enabled is left to the controller, as
+// it depends on the singleton.
+func (prov *Provisioning) ValidateCreate() error {
+	if prov.Name != ProvisioningSingletonName && prov.Spec.HostSelector == nil {
+		return fmt.Errorf("Provisioning is a singleton: only the name %q is accepted, not %q, "+
+			"unless spec.hostSelector is set to define a provisioning domain",
+			ProvisioningSingletonName, prov.Name)
+	}
+	return nil
+}
+
+// ValidateUpdate accepts all updates, so that instances created before the
+// webhook was installed can still be updated and deleted
+func (prov *Provisioning) ValidateUpdate(old runtime.Object) error {
+	return nil
+}
+
+// ValidateDelete accepts all deletions
+func (prov *Provisioning) ValidateDelete() error {
+	return nil
+}
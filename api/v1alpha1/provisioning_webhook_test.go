@@ -0,0 +1,46 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateCreate(t *testing.T) {
+	singleton := &Provisioning{ObjectMeta: metav1.ObjectMeta{Name: ProvisioningSingletonName}}
+	assert.NoError(t, singleton.ValidateCreate())
+
+	extra := &Provisioning{ObjectMeta: metav1.ObjectMeta{Name: "my-provisioning"}}
+	err := extra.ValidateCreate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), ProvisioningSingletonName)
+	}
+
+	domain := &Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: "rack-1"},
+		Spec: ProvisioningSpec{
+			HostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "1"}},
+		},
+	}
+	assert.NoError(t, domain.ValidateCreate())
+
+	// Instances that already exist must remain editable
+	assert.NoError(t, extra.ValidateUpdate(singleton))
+	assert.NoError(t, extra.ValidateDelete())
+}
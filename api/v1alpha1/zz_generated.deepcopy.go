@@ -20,15 +20,649 @@ limitations under the License.
 package v1alpha1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActionStatus) DeepCopyInto(out *ActionStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActionStatus.
+func (in *ActionStatus) DeepCopy() *ActionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ActionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BIOSProfile) DeepCopyInto(out *BIOSProfile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BIOSProfile.
+func (in *BIOSProfile) DeepCopy() *BIOSProfile {
+	if in == nil {
+		return nil
+	}
+	out := new(BIOSProfile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCNetwork) DeepCopyInto(out *BMCNetwork) {
+	*out = *in
+	if in.Subnets != nil {
+		in, out := &in.Subnets, &out.Subnets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCNetwork.
+func (in *BMCNetwork) DeepCopy() *BMCNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BMCProxy) DeepCopyInto(out *BMCProxy) {
+	*out = *in
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BMCProxy.
+func (in *BMCProxy) DeepCopy() *BMCProxy {
+	if in == nil {
+		return nil
+	}
+	out := new(BMCProxy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootArtifactURLs) DeepCopyInto(out *BootArtifactURLs) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootArtifactURLs.
+func (in *BootArtifactURLs) DeepCopy() *BootArtifactURLs {
+	if in == nil {
+		return nil
+	}
+	out := new(BootArtifactURLs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConductorGroup) DeepCopyInto(out *ConductorGroup) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConductorGroup.
+func (in *ConductorGroup) DeepCopy() *ConductorGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(ConductorGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConductorGroupStatus) DeepCopyInto(out *ConductorGroupStatus) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConductorGroupStatus.
+func (in *ConductorGroupStatus) DeepCopy() *ConductorGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ConductorGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerFootprint) DeepCopyInto(out *ContainerFootprint) {
+	*out = *in
+	out.PeakCPU = in.PeakCPU.DeepCopy()
+	out.PeakMemory = in.PeakMemory.DeepCopy()
+	if in.RecommendedCPU != nil {
+		in, out := &in.RecommendedCPU, &out.RecommendedCPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.RecommendedMemory != nil {
+		in, out := &in.RecommendedMemory, &out.RecommendedMemory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerFootprint.
+func (in *ContainerFootprint) DeepCopy() *ContainerFootprint {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerFootprint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DHCPCircuitIDMapping) DeepCopyInto(out *DHCPCircuitIDMapping) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DHCPCircuitIDMapping.
+func (in *DHCPCircuitIDMapping) DeepCopy() *DHCPCircuitIDMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(DHCPCircuitIDMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DHCPRelayRange) DeepCopyInto(out *DHCPRelayRange) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DHCPRelayRange.
+func (in *DHCPRelayRange) DeepCopy() *DHCPRelayRange {
+	if in == nil {
+		return nil
+	}
+	out := new(DHCPRelayRange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DetectedProvisioningNetwork) DeepCopyInto(out *DetectedProvisioningNetwork) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DetectedProvisioningNetwork.
+func (in *DetectedProvisioningNetwork) DeepCopy() *DetectedProvisioningNetwork {
+	if in == nil {
+		return nil
+	}
+	out := new(DetectedProvisioningNetwork)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareUpdates) DeepCopyInto(out *FirmwareUpdates) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareUpdates.
+func (in *FirmwareUpdates) DeepCopy() *FirmwareUpdates {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareUpdates)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HardwareMetrics) DeepCopyInto(out *HardwareMetrics) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HardwareMetrics.
+func (in *HardwareMetrics) DeepCopy() *HardwareMetrics {
+	if in == nil {
+		return nil
+	}
+	out := new(HardwareMetrics)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HighAvailability) DeepCopyInto(out *HighAvailability) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HighAvailability.
+func (in *HighAvailability) DeepCopy() *HighAvailability {
+	if in == nil {
+		return nil
+	}
+	out := new(HighAvailability)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IDRACExtension) DeepCopyInto(out *IDRACExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IDRACExtension.
+func (in *IDRACExtension) DeepCopy() *IDRACExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(IDRACExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ILOExtension) DeepCopyInto(out *ILOExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ILOExtension.
+func (in *ILOExtension) DeepCopy() *ILOExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(ILOExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IRMCExtension) DeepCopyInto(out *IRMCExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IRMCExtension.
+func (in *IRMCExtension) DeepCopy() *IRMCExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(IRMCExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageCache) DeepCopyInto(out *ImageCache) {
+	*out = *in
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageCache.
+func (in *ImageCache) DeepCopy() *ImageCache {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagePullSecretRef) DeepCopyInto(out *ImagePullSecretRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagePullSecretRef.
+func (in *ImagePullSecretRef) DeepCopy() *ImagePullSecretRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagePullSecretRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageServerMount) DeepCopyInto(out *ImageServerMount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageServerMount.
+func (in *ImageServerMount) DeepCopy() *ImageServerMount {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageServerMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageServerStatus) DeepCopyInto(out *ImageServerStatus) {
+	*out = *in
+	out.HTTP = in.HTTP
+	if in.HTTPS != nil {
+		in, out := &in.HTTPS, &out.HTTPS
+		*out = new(BootArtifactURLs)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageServerStatus.
+func (in *ImageServerStatus) DeepCopy() *ImageServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InspectorRules) DeepCopyInto(out *InspectorRules) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InspectorRules.
+func (in *InspectorRules) DeepCopy() *InspectorRules {
+	if in == nil {
+		return nil
+	}
+	out := new(InspectorRules)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IronicAPIAudit) DeepCopyInto(out *IronicAPIAudit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IronicAPIAudit.
+func (in *IronicAPIAudit) DeepCopy() *IronicAPIAudit {
+	if in == nil {
+		return nil
+	}
+	out := new(IronicAPIAudit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IronicAPIExposure) DeepCopyInto(out *IronicAPIExposure) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IronicAPIExposure.
+func (in *IronicAPIExposure) DeepCopy() *IronicAPIExposure {
+	if in == nil {
+		return nil
+	}
+	out := new(IronicAPIExposure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LivePXEArtifacts) DeepCopyInto(out *LivePXEArtifacts) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LivePXEArtifacts.
+func (in *LivePXEArtifacts) DeepCopy() *LivePXEArtifacts {
+	if in == nil {
+		return nil
+	}
+	out := new(LivePXEArtifacts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Metal3UpdateStrategy) DeepCopyInto(out *Metal3UpdateStrategy) {
+	*out = *in
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Metal3UpdateStrategy.
+func (in *Metal3UpdateStrategy) DeepCopy() *Metal3UpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(Metal3UpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkMigration) DeepCopyInto(out *NetworkMigration) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkMigration.
+func (in *NetworkMigration) DeepCopy() *NetworkMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeProvisioningIP) DeepCopyInto(out *NodeProvisioningIP) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeProvisioningIP.
+func (in *NodeProvisioningIP) DeepCopy() *NodeProvisioningIP {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeProvisioningIP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSImageDelta) DeepCopyInto(out *OSImageDelta) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSImageDelta.
+func (in *OSImageDelta) DeepCopy() *OSImageDelta {
+	if in == nil {
+		return nil
+	}
+	out := new(OSImageDelta)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSImageDownloadStatus) DeepCopyInto(out *OSImageDownloadStatus) {
+	*out = *in
+	if in.PercentComplete != nil {
+		in, out := &in.PercentComplete, &out.PercentComplete
+		*out = new(int32)
+		**out = **in
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSImageDownloadStatus.
+func (in *OSImageDownloadStatus) DeepCopy() *OSImageDownloadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OSImageDownloadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanCleanup) DeepCopyInto(out *OrphanCleanup) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanCleanup.
+func (in *OrphanCleanup) DeepCopy() *OrphanCleanup {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanCleanup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OrphanCleanupStatus) DeepCopyInto(out *OrphanCleanupStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.RemovedNodes != nil {
+		in, out := &in.RemovedNodes, &out.RemovedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkippedNodes != nil {
+		in, out := &in.SkippedNodes, &out.SkippedNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OrphanCleanupStatus.
+func (in *OrphanCleanupStatus) DeepCopy() *OrphanCleanupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OrphanCleanupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSImageSignatureRef) DeepCopyInto(out *OSImageSignatureRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSImageSignatureRef.
+func (in *OSImageSignatureRef) DeepCopy() *OSImageSignatureRef {
+	if in == nil {
+		return nil
+	}
+	out := new(OSImageSignatureRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSImageStatus) DeepCopyInto(out *OSImageStatus) {
+	*out = *in
+	if in.Download != nil {
+		in, out := &in.Download, &out.Download
+		*out = new(OSImageDownloadStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSImageStatus.
+func (in *OSImageStatus) DeepCopy() *OSImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OSImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperandMetadata) DeepCopyInto(out *OperandMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperandMetadata.
+func (in *OperandMetadata) DeepCopy() *OperandMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(OperandMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Provisioning) DeepCopyInto(out *Provisioning) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -50,6 +684,31 @@ func (in *Provisioning) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningCapacity) DeepCopyInto(out *ProvisioningCapacity) {
+	*out = *in
+	if in.DHCPAddresses != nil {
+		in, out := &in.DHCPAddresses, &out.DHCPAddresses
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DHCPLeases != nil {
+		in, out := &in.DHCPLeases, &out.DHCPLeases
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningCapacity.
+func (in *ProvisioningCapacity) DeepCopy() *ProvisioningCapacity {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningCapacity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisioningList) DeepCopyInto(out *ProvisioningList) {
 	*out = *in
@@ -82,9 +741,195 @@ func (in *ProvisioningList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningOSImage) DeepCopyInto(out *ProvisioningOSImage) {
+	*out = *in
+	if in.Mirrors != nil {
+		in, out := &in.Mirrors, &out.Mirrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Delta != nil {
+		in, out := &in.Delta, &out.Delta
+		*out = new(OSImageDelta)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningOSImage.
+func (in *ProvisioningOSImage) DeepCopy() *ProvisioningOSImage {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningOSImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisioningSpec) DeepCopyInto(out *ProvisioningSpec) {
 	*out = *in
+	if in.DHCPRelayRanges != nil {
+		in, out := &in.DHCPRelayRanges, &out.DHCPRelayRanges
+		*out = make([]DHCPRelayRange, len(*in))
+		copy(*out, *in)
+	}
+	if in.DHCPCircuitIDMappings != nil {
+		in, out := &in.DHCPCircuitIDMappings, &out.DHCPCircuitIDMappings
+		*out = make([]DHCPCircuitIDMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.TFTP != nil {
+		in, out := &in.TFTP, &out.TFTP
+		*out = new(TFTPConfig)
+		**out = **in
+	}
+	if in.ProvisioningOSImage != nil {
+		in, out := &in.ProvisioningOSImage, &out.ProvisioningOSImage
+		*out = new(ProvisioningOSImage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LivePXEArtifacts != nil {
+		in, out := &in.LivePXEArtifacts, &out.LivePXEArtifacts
+		*out = new(LivePXEArtifacts)
+		**out = **in
+	}
+	if in.IronicAPIExposure != nil {
+		in, out := &in.IronicAPIExposure, &out.IronicAPIExposure
+		*out = new(IronicAPIExposure)
+		**out = **in
+	}
+	if in.ControlPlaneOnly != nil {
+		in, out := &in.ControlPlaneOnly, &out.ControlPlaneOnly
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HighAvailability != nil {
+		in, out := &in.HighAvailability, &out.HighAvailability
+		*out = new(HighAvailability)
+		**out = **in
+	}
+	if in.ProvisioningIPPool != nil {
+		in, out := &in.ProvisioningIPPool, &out.ProvisioningIPPool
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ProvisioningVIP != nil {
+		in, out := &in.ProvisioningVIP, &out.ProvisioningVIP
+		*out = new(ProvisioningVIP)
+		**out = **in
+	}
+	if in.ConductorGroups != nil {
+		in, out := &in.ConductorGroups, &out.ConductorGroups
+		*out = make([]ConductorGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.OperandMetadata != nil {
+		in, out := &in.OperandMetadata, &out.OperandMetadata
+		*out = new(OperandMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostSelector != nil {
+		in, out := &in.HostSelector, &out.HostSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImageCache != nil {
+		in, out := &in.ImageCache, &out.ImageCache
+		*out = new(ImageCache)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OSImageSignatureRef != nil {
+		in, out := &in.OSImageSignatureRef, &out.OSImageSignatureRef
+		*out = new(OSImageSignatureRef)
+		**out = **in
+	}
+	if in.ImagePullSecretRef != nil {
+		in, out := &in.ImagePullSecretRef, &out.ImagePullSecretRef
+		*out = new(ImagePullSecretRef)
+		**out = **in
+	}
+	if in.IronicAPIAudit != nil {
+		in, out := &in.IronicAPIAudit, &out.IronicAPIAudit
+		*out = new(IronicAPIAudit)
+		**out = **in
+	}
+	if in.HardwareMetrics != nil {
+		in, out := &in.HardwareMetrics, &out.HardwareMetrics
+		*out = new(HardwareMetrics)
+		**out = **in
+	}
+	if in.OrphanCleanup != nil {
+		in, out := &in.OrphanCleanup, &out.OrphanCleanup
+		*out = new(OrphanCleanup)
+		**out = **in
+	}
+	if in.InspectorRules != nil {
+		in, out := &in.InspectorRules, &out.InspectorRules
+		*out = new(InspectorRules)
+		**out = **in
+	}
+	if in.ImageServerMounts != nil {
+		in, out := &in.ImageServerMounts, &out.ImageServerMounts
+		*out = make([]ImageServerMount, len(*in))
+		copy(*out, *in)
+	}
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.VendorExtensions != nil {
+		in, out := &in.VendorExtensions, &out.VendorExtensions
+		*out = new(VendorExtensions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FirmwareUpdates != nil {
+		in, out := &in.FirmwareUpdates, &out.FirmwareUpdates
+		*out = new(FirmwareUpdates)
+		**out = **in
+	}
+	if in.DefaultRAIDConfig != nil {
+		in, out := &in.DefaultRAIDConfig, &out.DefaultRAIDConfig
+		*out = make([]RAIDTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BIOSProfiles != nil {
+		in, out := &in.BIOSProfiles, &out.BIOSProfiles
+		*out = make([]BIOSProfile, len(*in))
+		copy(*out, *in)
+	}
+	if in.BMCProxy != nil {
+		in, out := &in.BMCProxy, &out.BMCProxy
+		*out = new(BMCProxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BMCNetwork != nil {
+		in, out := &in.BMCNetwork, &out.BMCNetwork
+		*out = new(BMCNetwork)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(Metal3UpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningSpec.
@@ -101,6 +946,70 @@ func (in *ProvisioningSpec) DeepCopy() *ProvisioningSpec {
 func (in *ProvisioningStatus) DeepCopyInto(out *ProvisioningStatus) {
 	*out = *in
 	in.OperatorStatus.DeepCopyInto(&out.OperatorStatus)
+	in.OSImage.DeepCopyInto(&out.OSImage)
+	if in.DetectedNetwork != nil {
+		in, out := &in.DetectedNetwork, &out.DetectedNetwork
+		*out = new(DetectedProvisioningNetwork)
+		**out = **in
+	}
+	in.ImageServer.DeepCopyInto(&out.ImageServer)
+	if in.LastSuccessfulConfiguration != nil {
+		in, out := &in.LastSuccessfulConfiguration, &out.LastSuccessfulConfiguration
+		*out = new(SuccessfulConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastAction != nil {
+		in, out := &in.LastAction, &out.LastAction
+		*out = new(ActionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ConductorGroups != nil {
+		in, out := &in.ConductorGroups, &out.ConductorGroups
+		*out = make([]ConductorGroupStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NetworkMigration != nil {
+		in, out := &in.NetworkMigration, &out.NetworkMigration
+		*out = new(NetworkMigration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProvisioningIPs != nil {
+		in, out := &in.ProvisioningIPs, &out.ProvisioningIPs
+		*out = make([]NodeProvisioningIP, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastOrphanCleanup != nil {
+		in, out := &in.LastOrphanCleanup, &out.LastOrphanCleanup
+		*out = new(OrphanCleanupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RAIDTemplates != nil {
+		in, out := &in.RAIDTemplates, &out.RAIDTemplates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.BIOSTemplates != nil {
+		in, out := &in.BIOSTemplates, &out.BIOSTemplates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(ProvisioningCapacity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SmokeTest != nil {
+		in, out := &in.SmokeTest, &out.SmokeTest
+		*out = new(SmokeTestStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceFootprint != nil {
+		in, out := &in.ResourceFootprint, &out.ResourceFootprint
+		*out = new(ResourceFootprint)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningStatus.
@@ -112,3 +1021,214 @@ func (in *ProvisioningStatus) DeepCopy() *ProvisioningStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningVIP) DeepCopyInto(out *ProvisioningVIP) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningVIP.
+func (in *ProvisioningVIP) DeepCopy() *ProvisioningVIP {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningVIP)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RAIDLogicalDisk) DeepCopyInto(out *RAIDLogicalDisk) {
+	*out = *in
+	if in.SizeGB != nil {
+		in, out := &in.SizeGB, &out.SizeGB
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PhysicalDisks != nil {
+		in, out := &in.PhysicalDisks, &out.PhysicalDisks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RAIDLogicalDisk.
+func (in *RAIDLogicalDisk) DeepCopy() *RAIDLogicalDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(RAIDLogicalDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RAIDTemplate) DeepCopyInto(out *RAIDTemplate) {
+	*out = *in
+	if in.LogicalDisks != nil {
+		in, out := &in.LogicalDisks, &out.LogicalDisks
+		*out = make([]RAIDLogicalDisk, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RAIDTemplate.
+func (in *RAIDTemplate) DeepCopy() *RAIDTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(RAIDTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceFootprint) DeepCopyInto(out *ResourceFootprint) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]ContainerFootprint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFootprint.
+func (in *ResourceFootprint) DeepCopy() *ResourceFootprint {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceFootprint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmokeTestCheck) DeepCopyInto(out *SmokeTestCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmokeTestCheck.
+func (in *SmokeTestCheck) DeepCopy() *SmokeTestCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(SmokeTestCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SmokeTestStatus) DeepCopyInto(out *SmokeTestStatus) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]SmokeTestCheck, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SmokeTestStatus.
+func (in *SmokeTestStatus) DeepCopy() *SmokeTestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SmokeTestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SuccessfulConfiguration) DeepCopyInto(out *SuccessfulConfiguration) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SuccessfulConfiguration.
+func (in *SuccessfulConfiguration) DeepCopy() *SuccessfulConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(SuccessfulConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TFTPConfig) DeepCopyInto(out *TFTPConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TFTPConfig.
+func (in *TFTPConfig) DeepCopy() *TFTPConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(TFTPConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VendorExtensions) DeepCopyInto(out *VendorExtensions) {
+	*out = *in
+	if in.IDRAC != nil {
+		in, out := &in.IDRAC, &out.IDRAC
+		*out = new(IDRACExtension)
+		**out = **in
+	}
+	if in.ILO != nil {
+		in, out := &in.ILO, &out.ILO
+		*out = new(ILOExtension)
+		**out = **in
+	}
+	if in.IRMC != nil {
+		in, out := &in.IRMC, &out.IRMC
+		*out = new(IRMCExtension)
+		**out = **in
+	}
+	if in.XClarity != nil {
+		in, out := &in.XClarity, &out.XClarity
+		*out = new(XClarityExtension)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VendorExtensions.
+func (in *VendorExtensions) DeepCopy() *VendorExtensions {
+	if in == nil {
+		return nil
+	}
+	out := new(VendorExtensions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *XClarityExtension) DeepCopyInto(out *XClarityExtension) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new XClarityExtension.
+func (in *XClarityExtension) DeepCopy() *XClarityExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(XClarityExtension)
+	in.DeepCopyInto(out)
+	return out
+}
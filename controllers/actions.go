@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// runRequestedAction runs the action requested with the ActionAnnotation
+// of the Provisioning CR, records its outcome in the status and removes
+// the annotation. On API errors the annotation is kept, so that the
+// action is retried by the next reconcile.
+func (r *ProvisioningReconciler) runRequestedAction(prov *metal3iov1alpha1.Provisioning, now time.Time) error {
+	action := provisioning.GetRequestedAction(prov)
+	if action == "" {
+		return nil
+	}
+	// Read before the status update replaces the metadata of the CR
+	refreshGeneration := provisioning.NextImageRefreshGeneration(prov)
+
+	outcome := &metal3iov1alpha1.ActionStatus{
+		Name:   action,
+		Result: metal3iov1alpha1.ActionSucceeded,
+		Time:   metav1.NewTime(now),
+	}
+	if err := provisioning.ValidateAction(action); err != nil {
+		outcome.Result = metal3iov1alpha1.ActionFailed
+		outcome.Message = err.Error()
+	} else {
+		message, err := r.runAction(prov, action, now)
+		if err != nil {
+			return err
+		}
+		outcome.Message = message
+	}
+	r.Log.Info("ran requested action", "action", action, "result", outcome.Result, "message", outcome.Message)
+
+	newStatus := prov.Status.DeepCopy()
+	newStatus.LastAction = outcome
+	if err := r.updateProvisioningStatus(prov, newStatus); err != nil {
+		return err
+	}
+	if r.EventRecorder != nil {
+		eventType := corev1.EventTypeNormal
+		if outcome.Result == metal3iov1alpha1.ActionFailed {
+			eventType = corev1.EventTypeWarning
+		}
+		r.EventRecorder.Event(prov, eventType, "Action"+string(outcome.Result), fmt.Sprintf("%s: %s", action, outcome.Message))
+	}
+
+	delete(prov.Annotations, provisioning.ActionAnnotation)
+	if action == provisioning.ActionRefreshImages {
+		// The metal3 pods are rolled out once the new generation is
+		// recorded, in the same update as the removal of the annotation,
+		// so that a failed removal does not refresh the images twice
+		metav1.SetMetaDataAnnotation(&prov.ObjectMeta, provisioning.ImageRefreshGenerationAnnotation, refreshGeneration)
+	}
+	return r.Client.Update(context.Background(), prov)
+}
+
+// runAction carries out a supported action and describes what it did
+func (r *ProvisioningReconciler) runAction(prov *metal3iov1alpha1.Provisioning, action string, now time.Time) (string, error) {
+	switch action {
+	case provisioning.ActionRefreshImages:
+		return fmt.Sprintf("rolling out the metal3 pods with image refresh generation %s", provisioning.NextImageRefreshGeneration(prov)), nil
+	case provisioning.ActionRotateCredentials:
+		secrets := r.KubeClient.CoreV1()
+		if err := provisioning.DeleteIronicPasswordSecrets(secrets, ComponentNamespace); err != nil {
+			return "", err
+		}
+		if err := provisioning.CreateIronicPasswordSecret(secrets, ComponentNamespace); err != nil {
+			return "", err
+		}
+		if err := provisioning.CreateInspectorPasswordSecret(secrets, ComponentNamespace); err != nil {
+			return "", err
+		}
+		restarted, err := provisioning.RestartMetal3Pods(r.KubeClient.CoreV1(), ComponentNamespace)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("replaced the Ironic and Inspector passwords, restarted %d metal3 pods", restarted), nil
+	case provisioning.ActionRevalidate:
+		restarted, err := r.restartChecks(prov)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("restarted %d node checks", restarted), nil
+	case provisioning.ActionCollectSupportBundle:
+		return r.collectSupportBundle(prov, now)
+	}
+	return "", provisioning.ValidateAction(action)
+}
+
+// restartChecks deletes the check pods of the Provisioning CR, so that
+// the checks run again on every node
+func (r *ProvisioningReconciler) restartChecks(prov *metal3iov1alpha1.Provisioning) (int, error) {
+	ctx := context.Background()
+	checkPods := &corev1.PodList{}
+	if err := r.Client.List(ctx, checkPods, client.InNamespace(ComponentNamespace),
+		client.MatchingFields{provisioningOwnerField: prov.Name}); err != nil {
+		return 0, err
+	}
+	restarted := 0
+	for _, pod := range checkPods.Items {
+		err := r.KubeClient.CoreV1().Pods(ComponentNamespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return restarted, err
+		}
+		restarted++
+	}
+	return restarted, nil
+}
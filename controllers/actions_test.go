@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestRunRequestedAction(t *testing.T) {
+	now := time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)
+	checkPod := newInterfaceCheckPod("master-0", "eth0", corev1.PodSucceeded, time.Now())
+
+	tCases := []struct {
+		name            string
+		action          string
+		expectedResult  metal3iov1alpha1.ActionResult
+		expectedMessage string
+		expectedPods    int
+	}{
+		{
+			name:            "RefreshImages",
+			action:          provisioning.ActionRefreshImages,
+			expectedResult:  metal3iov1alpha1.ActionSucceeded,
+			expectedMessage: "rolling out the metal3 pods with image refresh generation 1",
+			expectedPods:    2,
+		},
+		{
+			name:            "RotateCredentials",
+			action:          provisioning.ActionRotateCredentials,
+			expectedResult:  metal3iov1alpha1.ActionSucceeded,
+			expectedMessage: "replaced the Ironic and Inspector passwords, restarted 1 metal3 pods",
+			expectedPods:    1,
+		},
+		{
+			name:            "Revalidate",
+			action:          provisioning.ActionRevalidate,
+			expectedResult:  metal3iov1alpha1.ActionSucceeded,
+			expectedMessage: "restarted 1 node checks",
+			expectedPods:    1,
+		},
+		{
+			name:            "Unknown",
+			action:          "Reboot",
+			expectedResult:  metal3iov1alpha1.ActionFailed,
+			expectedMessage: `unknown action "Reboot", must be one of [RefreshImages RotateCredentials Revalidate CollectSupportBundle]`,
+			expectedPods:    2,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        BaremetalProvisioningCR,
+					Annotations: map[string]string{provisioning.ActionAnnotation: tc.action},
+				},
+			}
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(newMetal3Pod(), checkPod)
+			// The check pods are read from the cache
+			reconciler.Client = fakeclient.NewFakeClientWithScheme(setUpSchemeForReconciler(), []runtime.Object{prov, checkPod}...)
+			assert.NoError(t, provisioning.CreateIronicPasswordSecret(reconciler.KubeClient.CoreV1(), ComponentNamespace))
+			ironic, err := reconciler.KubeClient.CoreV1().Secrets(ComponentNamespace).Get(context.Background(), "metal3-ironic-password", metav1.GetOptions{})
+			assert.NoError(t, err)
+
+			assert.NoError(t, reconciler.runRequestedAction(prov, now))
+
+			updated := &metal3iov1alpha1.Provisioning{}
+			assert.NoError(t, reconciler.Client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, updated))
+			assert.NotContains(t, updated.Annotations, provisioning.ActionAnnotation)
+			if tc.action == provisioning.ActionRefreshImages {
+				assert.Equal(t, "1", provisioning.GetImageRefreshGeneration(updated))
+			} else {
+				assert.NotContains(t, updated.Annotations, provisioning.ImageRefreshGenerationAnnotation)
+			}
+			if assert.NotNil(t, updated.Status.LastAction) {
+				assert.Equal(t, tc.action, updated.Status.LastAction.Name)
+				assert.Equal(t, tc.expectedResult, updated.Status.LastAction.Result)
+				assert.Equal(t, tc.expectedMessage, updated.Status.LastAction.Message)
+				assert.True(t, now.Equal(updated.Status.LastAction.Time.Time))
+			}
+
+			pods, err := reconciler.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, pods.Items, tc.expectedPods)
+
+			rotated, err := reconciler.KubeClient.CoreV1().Secrets(ComponentNamespace).Get(context.Background(), "metal3-ironic-password", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.action == provisioning.ActionRotateCredentials,
+				ironic.StringData["password"] != rotated.StringData["password"], "password rotated")
+
+			// Nothing runs again once the annotation is removed
+			assert.NoError(t, reconciler.runRequestedAction(updated, now.Add(time.Minute)))
+			assert.True(t, now.Equal(updated.Status.LastAction.Time.Time))
+		})
+	}
+}
+
+func TestRefreshImagesRetry(t *testing.T) {
+	now := time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)
+	// The outcome of the refresh was recorded, but the annotation could
+	// not be removed
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: BaremetalProvisioningCR,
+			Annotations: map[string]string{
+				provisioning.ActionAnnotation:                 provisioning.ActionRefreshImages,
+				provisioning.ImageRefreshGenerationAnnotation: "3",
+			},
+		},
+		Status: metal3iov1alpha1.ProvisioningStatus{
+			LastAction: &metal3iov1alpha1.ActionStatus{
+				Name:    provisioning.ActionRefreshImages,
+				Result:  metal3iov1alpha1.ActionSucceeded,
+				Message: "rolling out the metal3 pods with image refresh generation 4",
+				Time:    metav1.NewTime(now),
+			},
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset()
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(setUpSchemeForReconciler(), prov)
+
+	assert.NoError(t, reconciler.runRequestedAction(prov, now.Add(time.Minute)))
+
+	// The generation the first attempt rolled out is not skipped
+	updated := &metal3iov1alpha1.Provisioning{}
+	assert.NoError(t, reconciler.Client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, updated))
+	assert.NotContains(t, updated.Annotations, provisioning.ActionAnnotation)
+	assert.Equal(t, "4", provisioning.GetImageRefreshGeneration(updated))
+	assert.Equal(t, "rolling out the metal3 pods with image refresh generation 4", updated.Status.LastAction.Message)
+}
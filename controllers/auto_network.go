@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// networkDetectionRequeueAfter is how often the provisioning network is
+// looked for again when it could not be detected, as the labels of the
+// nodes are not watched
+const networkDetectionRequeueAfter = time.Minute
+
+// resolveAutoProvisioningNetwork returns a copy of the Provisioning with
+// the Managed configuration its Auto network resolves to, along with the
+// network found, or nil while the interface is being detected. The
+// interface of the spec is used first, then the one the nodes are
+// labelled with, then the one found by a detection pod. A detected
+// interface is kept from the status, as it has more than link-local
+// addresses once the metal3 pods configured it.
+func (r *ProvisioningReconciler) resolveAutoProvisioningNetwork(prov *metal3iov1alpha1.Provisioning, images *provisioning.Images) (*metal3iov1alpha1.Provisioning, *metal3iov1alpha1.DetectedProvisioningNetwork, error) {
+	iface, source, nodeName := prov.Spec.ProvisioningInterface, metal3iov1alpha1.ProvisioningNetworkSourceSpec, ""
+	if iface == "" {
+		nodes, err := r.listProvisioningNodes(&prov.Spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		labelled, err := provisioning.GetLabelledProvisioningInterface(nodes)
+		if err != nil {
+			return nil, nil, provisioning.NewInvalidSpecError(err)
+		}
+		detected := prov.Status.DetectedNetwork
+		switch {
+		case labelled != "":
+			iface, source = labelled, metal3iov1alpha1.ProvisioningNetworkSourceNodeLabel
+		case detected != nil && detected.Source == metal3iov1alpha1.ProvisioningNetworkSourceLinkLocal:
+			iface, source, nodeName = detected.Interface, detected.Source, detected.Node
+		default:
+			iface, nodeName, err = r.detectProvisioningInterface(prov, images, nodes)
+			if err != nil || iface == "" {
+				return nil, nil, err
+			}
+			source = metal3iov1alpha1.ProvisioningNetworkSourceLinkLocal
+		}
+	}
+	resolved := prov.DeepCopy()
+	resolved.Spec = *provisioning.ResolveAutoProvisioningNetwork(&prov.Spec, iface)
+	return resolved, provisioning.GetDetectedNetwork(&resolved.Spec, source, nodeName), nil
+}
+
+// detectProvisioningInterface runs a detection pod on the first of the
+// nodes the metal3 pods may run on, and returns the interface it found
+// along with the node, or an empty interface while it runs. A failed pod
+// is deleted, so that the detection runs again on the next attempt.
+func (r *ProvisioningReconciler) detectProvisioningInterface(prov *metal3iov1alpha1.Provisioning, images *provisioning.Images, nodes []corev1.Node) (string, string, error) {
+	if len(nodes) == 0 {
+		return "", "", nil
+	}
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	nodeName := names[0]
+
+	ctx := context.Background()
+	pods := r.KubeClient.CoreV1().Pods(ComponentNamespace)
+	pod, err := pods.Get(ctx, provisioning.NetworkDetectionPodName(nodeName), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		pod = provisioning.NewNetworkDetectionPod(ComponentNamespace, nodeName, images)
+		if err := controllerutil.SetControllerReference(prov, pod, r.Scheme); err != nil {
+			return "", "", err
+		}
+		_, err = pods.Create(ctx, pod, metav1.CreateOptions{})
+		return "", nodeName, err
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	iface, failure := provisioning.GetNetworkDetectionResult(pod)
+	if failure != "" {
+		if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return "", "", err
+		}
+		return "", "", provisioning.NewInvalidSpecError(fmt.Errorf(
+			"could not detect the provisioning interface on node %s: %s; set the ProvisioningInterface or label the nodes with %s",
+			nodeName, failure, provisioning.ProvisioningInterfaceLabel))
+	}
+	return iface, nodeName, nil
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newAutoProvisioning() *metal3iov1alpha1.Provisioning {
+	return &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkAuto,
+			ProvisioningOSDownloadURL: "http://example.com/rhcos.qcow2.gz?sha256=abc",
+		},
+	}
+}
+
+func newNetworkDetectionPod(nodeName string, phase corev1.PodPhase, message string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      provisioning.NetworkDetectionPodName(nodeName),
+			Namespace: ComponentNamespace,
+		},
+		Status: corev1.PodStatus{
+			Phase: phase,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}},
+			}},
+		},
+	}
+}
+
+func TestResolveAutoProvisioningNetwork(t *testing.T) {
+	labelled := newMasterNode("master-1")
+	labelled.Labels[provisioning.ProvisioningInterfaceLabel] = "ens4"
+
+	t.Run("SpecInterface", func(t *testing.T) {
+		prov := newAutoProvisioning()
+		prov.Spec.ProvisioningInterface = "eth1"
+		reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+		reconciler.KubeClient = fakekube.NewSimpleClientset()
+
+		resolved, detected, err := reconciler.resolveAutoProvisioningNetwork(prov, &provisioning.Images{})
+		assert.NoError(t, err)
+		assert.Equal(t, metal3iov1alpha1.ProvisioningNetworkManaged, resolved.Spec.ProvisioningNetwork)
+		assert.Equal(t, &metal3iov1alpha1.DetectedProvisioningNetwork{
+			Interface:               "eth1",
+			Source:                  metal3iov1alpha1.ProvisioningNetworkSourceSpec,
+			ProvisioningIP:          "172.22.0.3",
+			ProvisioningNetworkCIDR: "172.22.0.0/24",
+		}, detected)
+		// The Provisioning read from the cluster is left untouched
+		assert.Equal(t, metal3iov1alpha1.ProvisioningNetworkAuto, prov.Spec.ProvisioningNetwork)
+	})
+
+	t.Run("NodeLabel", func(t *testing.T) {
+		prov := newAutoProvisioning()
+		reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+		reconciler.KubeClient = fakekube.NewSimpleClientset(newMasterNode("master-0"), labelled)
+
+		resolved, detected, err := reconciler.resolveAutoProvisioningNetwork(prov, &provisioning.Images{})
+		assert.NoError(t, err)
+		assert.Equal(t, "ens4", resolved.Spec.ProvisioningInterface)
+		assert.Equal(t, metal3iov1alpha1.ProvisioningNetworkSourceNodeLabel, detected.Source)
+	})
+
+	t.Run("ConflictingLabels", func(t *testing.T) {
+		other := newMasterNode("master-2")
+		other.Labels[provisioning.ProvisioningInterfaceLabel] = "ens5"
+		prov := newAutoProvisioning()
+		reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+		reconciler.KubeClient = fakekube.NewSimpleClientset(labelled, other)
+
+		_, _, err := reconciler.resolveAutoProvisioningNetwork(prov, &provisioning.Images{})
+		assert.True(t, errors.Is(err, provisioning.ErrInvalidSpec))
+	})
+
+	t.Run("LinkLocal", func(t *testing.T) {
+		prov := newAutoProvisioning()
+		reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+		reconciler.KubeClient = fakekube.NewSimpleClientset(newMasterNode("master-1"), newMasterNode("master-0"))
+
+		// The pod detecting the interface is created on the first node
+		resolved, detected, err := reconciler.resolveAutoProvisioningNetwork(prov, &provisioning.Images{})
+		assert.NoError(t, err)
+		assert.Nil(t, resolved)
+		assert.Nil(t, detected)
+		pods := reconciler.KubeClient.CoreV1().Pods(ComponentNamespace)
+		pod, err := pods.Get(context.Background(), provisioning.NetworkDetectionPodName("master-0"), metav1.GetOptions{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, "master-0", pod.Spec.NodeName)
+
+		pod.Status = newNetworkDetectionPod("master-0", corev1.PodSucceeded, "ens3").Status
+		_, err = pods.UpdateStatus(context.Background(), pod, metav1.UpdateOptions{})
+		assert.NoError(t, err)
+		resolved, detected, err = reconciler.resolveAutoProvisioningNetwork(prov, &provisioning.Images{})
+		assert.NoError(t, err)
+		assert.Equal(t, "ens3", resolved.Spec.ProvisioningInterface)
+		assert.Equal(t, &metal3iov1alpha1.DetectedProvisioningNetwork{
+			Interface:               "ens3",
+			Source:                  metal3iov1alpha1.ProvisioningNetworkSourceLinkLocal,
+			Node:                    "master-0",
+			ProvisioningIP:          "172.22.0.3",
+			ProvisioningNetworkCIDR: "172.22.0.0/24",
+		}, detected)
+
+		// Once configured the interface is not detected again
+		assert.NoError(t, pods.Delete(context.Background(), pod.Name, metav1.DeleteOptions{}))
+		prov.Status.DetectedNetwork = detected
+		resolved, _, err = reconciler.resolveAutoProvisioningNetwork(prov, &provisioning.Images{})
+		assert.NoError(t, err)
+		assert.Equal(t, "ens3", resolved.Spec.ProvisioningInterface)
+		_, err = pods.Get(context.Background(), pod.Name, metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+
+	t.Run("DetectionFailed", func(t *testing.T) {
+		prov := newAutoProvisioning()
+		reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+		reconciler.KubeClient = fakekube.NewSimpleClientset(newMasterNode("master-0"),
+			newNetworkDetectionPod("master-0", corev1.PodFailed, "several NICs with only link-local addresses: ens3 ens4"))
+
+		_, _, err := reconciler.resolveAutoProvisioningNetwork(prov, &provisioning.Images{})
+		assert.True(t, errors.Is(err, provisioning.ErrInvalidSpec))
+		assert.Contains(t, err.Error(), "could not detect the provisioning interface on node master-0: several NICs with only link-local addresses: ens3 ens4")
+		// The detection runs again on the next attempt
+		_, err = reconciler.KubeClient.CoreV1().Pods(ComponentNamespace).Get(context.Background(),
+			provisioning.NetworkDetectionPodName("master-0"), metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err))
+	})
+}
@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// configMapNamespaces are the namespaces other than ComponentNamespace
+// the operator reads ConfigMaps from
+var configMapNamespaces = []string{provisioning.CoreOSBootImagesNamespace}
+
+var configMapGVK = corev1.SchemeGroupVersion.WithKind("ConfigMap")
+
+// NewCache returns the cache of the manager. Instead of watching every
+// namespace, it only caches the objects of ComponentNamespace and the
+// cluster-scoped objects, along with the ConfigMaps of
+// configMapNamespaces. The multi-namespace cache of controller-runtime
+// cannot be used, as it does not serve cluster-scoped objects.
+func NewCache(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+	opts.Namespace = ComponentNamespace
+	c, err := cache.New(config, opts)
+	if err != nil {
+		return nil, err
+	}
+	operatorCache := &operatorCache{Cache: c, configMaps: map[string]cache.Cache{}}
+	for _, namespace := range configMapNamespaces {
+		opts.Namespace = namespace
+		if operatorCache.configMaps[namespace], err = cache.New(config, opts); err != nil {
+			return nil, err
+		}
+	}
+	return operatorCache, nil
+}
+
+// operatorCache serves the ConfigMaps of configMapNamespaces from their
+// own cache, and any other object from the cache of ComponentNamespace
+type operatorCache struct {
+	cache.Cache
+	configMaps map[string]cache.Cache
+}
+
+func isConfigMap(obj runtime.Object) bool {
+	switch obj.(type) {
+	case *corev1.ConfigMap, *corev1.ConfigMapList:
+		return true
+	}
+	return false
+}
+
+func (c *operatorCache) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	if namespaceCache, ok := c.configMaps[key.Namespace]; ok && isConfigMap(obj) {
+		return namespaceCache.Get(ctx, key, obj)
+	}
+	return c.Cache.Get(ctx, key, obj)
+}
+
+// List only returns the objects of ComponentNamespace when listing
+// namespaced objects across all namespaces
+func (c *operatorCache) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if namespaceCache, ok := c.configMaps[listOpts.Namespace]; ok && isConfigMap(list) {
+		return namespaceCache.List(ctx, list, opts...)
+	}
+	return c.Cache.List(ctx, list, opts...)
+}
+
+func (c *operatorCache) GetInformer(ctx context.Context, obj runtime.Object) (cache.Informer, error) {
+	if !isConfigMap(obj) {
+		return c.Cache.GetInformer(ctx, obj)
+	}
+	return c.getConfigMapInformer(func(namespaceCache cache.Cache) (cache.Informer, error) {
+		return namespaceCache.GetInformer(ctx, obj)
+	})
+}
+
+func (c *operatorCache) GetInformerForKind(ctx context.Context, gvk schema.GroupVersionKind) (cache.Informer, error) {
+	if gvk != configMapGVK {
+		return c.Cache.GetInformerForKind(ctx, gvk)
+	}
+	return c.getConfigMapInformer(func(namespaceCache cache.Cache) (cache.Informer, error) {
+		return namespaceCache.GetInformerForKind(ctx, gvk)
+	})
+}
+
+// getConfigMapInformer returns an informer for the ConfigMaps of every
+// cached namespace
+func (c *operatorCache) getConfigMapInformer(getInformer func(cache.Cache) (cache.Informer, error)) (cache.Informer, error) {
+	informer, err := getInformer(c.Cache)
+	if err != nil {
+		return nil, err
+	}
+	informers := multiInformer{informer}
+	for _, namespaceCache := range c.configMaps {
+		informer, err := getInformer(namespaceCache)
+		if err != nil {
+			return nil, err
+		}
+		informers = append(informers, informer)
+	}
+	return informers, nil
+}
+
+func (c *operatorCache) IndexField(ctx context.Context, obj runtime.Object, field string, extractValue client.IndexerFunc) error {
+	if isConfigMap(obj) {
+		for _, namespaceCache := range c.configMaps {
+			if err := namespaceCache.IndexField(ctx, obj, field, extractValue); err != nil {
+				return err
+			}
+		}
+	}
+	return c.Cache.IndexField(ctx, obj, field, extractValue)
+}
+
+// Start runs the caches until stopCh is closed
+func (c *operatorCache) Start(stopCh <-chan struct{}) error {
+	for namespace, namespaceCache := range c.configMaps {
+		go func(namespace string, namespaceCache cache.Cache) {
+			if err := namespaceCache.Start(stopCh); err != nil {
+				ctrl.Log.WithName("cache").Error(err, "ConfigMap cache failed to start", "namespace", namespace)
+			}
+		}(namespace, namespaceCache)
+	}
+	return c.Cache.Start(stopCh)
+}
+
+func (c *operatorCache) WaitForCacheSync(stop <-chan struct{}) bool {
+	for _, namespaceCache := range c.configMaps {
+		if !namespaceCache.WaitForCacheSync(stop) {
+			return false
+		}
+	}
+	return c.Cache.WaitForCacheSync(stop)
+}
+
+// multiInformer dispatches the events of several informers of a kind
+type multiInformer []cache.Informer
+
+func (m multiInformer) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	for _, informer := range m {
+		informer.AddEventHandler(handler)
+	}
+}
+
+func (m multiInformer) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) {
+	for _, informer := range m {
+		informer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	}
+}
+
+func (m multiInformer) AddIndexers(indexers toolscache.Indexers) error {
+	for _, informer := range m {
+		if err := informer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiInformer) HasSynced() bool {
+	for _, informer := range m {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
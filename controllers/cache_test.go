@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// recordingCache records the reads it serves
+type recordingCache struct {
+	cache.Cache
+	reads int
+}
+
+func (c *recordingCache) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	c.reads++
+	return nil
+}
+
+func (c *recordingCache) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	c.reads++
+	return nil
+}
+
+type fakeInformer struct {
+	cache.Informer
+	synced bool
+}
+
+func (i fakeInformer) HasSynced() bool {
+	return i.synced
+}
+
+func TestOperatorCacheRouting(t *testing.T) {
+	tests := []struct {
+		name                string
+		read                func(c client.Reader) error
+		expectedBootImages  bool
+		expectedDefaultRead bool
+	}{
+		{
+			name: "BootImagesConfigMap",
+			read: func(c client.Reader) error {
+				return c.Get(context.Background(), client.ObjectKey{
+					Namespace: provisioning.CoreOSBootImagesNamespace,
+					Name:      provisioning.CoreOSBootImagesConfigMap,
+				}, &corev1.ConfigMap{})
+			},
+			expectedBootImages: true,
+		},
+		{
+			name: "BootImagesConfigMapList",
+			read: func(c client.Reader) error {
+				return c.List(context.Background(), &corev1.ConfigMapList{}, client.InNamespace(provisioning.CoreOSBootImagesNamespace))
+			},
+			expectedBootImages: true,
+		},
+		{
+			name: "ComponentNamespaceConfigMap",
+			read: func(c client.Reader) error {
+				return c.Get(context.Background(), client.ObjectKey{Namespace: ComponentNamespace, Name: "metal3-service-ca"}, &corev1.ConfigMap{})
+			},
+			expectedDefaultRead: true,
+		},
+		{
+			name: "OtherKindInConfigMapNamespace",
+			read: func(c client.Reader) error {
+				return c.Get(context.Background(), client.ObjectKey{Namespace: provisioning.CoreOSBootImagesNamespace, Name: "mcd"}, &appsv1.DaemonSet{})
+			},
+			expectedDefaultRead: true,
+		},
+		{
+			name: "ClusterScoped",
+			read: func(c client.Reader) error {
+				return c.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, &metal3iov1alpha1.Provisioning{})
+			},
+			expectedDefaultRead: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defaultCache := &recordingCache{}
+			bootImagesCache := &recordingCache{}
+			c := &operatorCache{
+				Cache:      defaultCache,
+				configMaps: map[string]cache.Cache{provisioning.CoreOSBootImagesNamespace: bootImagesCache},
+			}
+			assert.NoError(t, tc.read(c))
+			assert.Equal(t, tc.expectedBootImages, bootImagesCache.reads == 1)
+			assert.Equal(t, tc.expectedDefaultRead, defaultCache.reads == 1)
+		})
+	}
+}
+
+func TestMultiInformerHasSynced(t *testing.T) {
+	assert.True(t, multiInformer{fakeInformer{synced: true}, fakeInformer{synced: true}}.HasSynced())
+	assert.False(t, multiInformer{fakeInformer{synced: true}, fakeInformer{synced: false}}.HasSynced())
+}
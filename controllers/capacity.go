@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// fetchOperandMetrics returns the metrics served at the given URL
+func (r *ProvisioningReconciler) fetchOperandMetrics(url string) ([]byte, error) {
+	if r.operandMetricsFetch != nil {
+		return r.operandMetricsFetch(url)
+	}
+	client := &http.Client{Timeout: operandHealthProbeTimeout}
+	resp, err := client.Get(url) // #nosec
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// countDHCPLeases returns the number of leases of the metal3 DHCP server,
+// or nil when no running dnsmasq pod reported it. Each dnsmasq pod keeps
+// the lease database of its node, so their counts are summed.
+func (r *ProvisioningReconciler) countDHCPLeases(spec *metal3iov1alpha1.ProvisioningSpec) *int64 {
+	if !provisioning.IsDnsmasqRequired(spec) {
+		return nil
+	}
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: "k8s-app=" + provisioning.DnsmasqAppName})
+	if err != nil {
+		r.Log.Info("failed to list the dnsmasq pods", "error", err.Error())
+		return nil
+	}
+	var leases *int64
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		metrics, err := r.fetchOperandMetrics(provisioning.DHCPMetricsURL(pod.Status.PodIP))
+		if err != nil {
+			r.Log.Info("failed to read the DHCP lease metrics", "pod", pod.Name, "error", err.Error())
+			continue
+		}
+		count, err := provisioning.ParseDHCPLeaseCount(metrics)
+		if err != nil {
+			r.Log.Info("failed to read the DHCP lease metrics", "pod", pod.Name, "error", err.Error())
+			continue
+		}
+		if leases == nil {
+			leases = new(int64)
+		}
+		*leases += count
+	}
+	return leases
+}
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newDnsmasqPod(name string, podIP string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{"k8s-app": provisioning.DnsmasqAppName},
+		},
+		Status: corev1.PodStatus{Phase: phase, PodIP: podIP},
+	}
+}
+
+func TestCountDHCPLeases(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:   "eth0",
+			ProvisioningIP:          "172.30.20.3",
+			ProvisioningNetworkCIDR: "172.30.20.0/24",
+			ProvisioningDHCPRange:   "172.30.20.11, 172.30.20.101",
+			ProvisioningNetwork:     metal3iov1alpha1.ProvisioningNetworkManaged,
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		newDnsmasqPod("dnsmasq-a", "192.168.111.20", corev1.PodRunning),
+		newDnsmasqPod("dnsmasq-b", "192.168.111.21", corev1.PodRunning),
+		newDnsmasqPod("dnsmasq-c", "192.168.111.22", corev1.PodRunning),
+		newDnsmasqPod("dnsmasq-d", "192.168.111.23", corev1.PodPending),
+	)
+	metrics := map[string]string{
+		"http://192.168.111.20:60002/metrics": "metal3_dhcp_leases 7\n",
+		"http://192.168.111.21:60002/metrics": "metal3_dhcp_leases 12\n",
+		"http://192.168.111.22:60002/metrics": "metal3_dhcp_range_capacity 91\n",
+	}
+	fetched := []string{}
+	reconciler.operandMetricsFetch = func(url string) ([]byte, error) {
+		fetched = append(fetched, url)
+		if body, ok := metrics[url]; ok {
+			return []byte(body), nil
+		}
+		return nil, fmt.Errorf("%s is unreachable", url)
+	}
+
+	// Each node leases addresses from its own database
+	leases := reconciler.countDHCPLeases(&prov.Spec)
+	if assert.NotNil(t, leases) {
+		assert.Equal(t, int64(19), *leases)
+	}
+	assert.Len(t, fetched, 3)
+
+	// The leases are unknown when no pod reports them
+	delete(metrics, "http://192.168.111.20:60002/metrics")
+	delete(metrics, "http://192.168.111.21:60002/metrics")
+	assert.Nil(t, reconciler.countDHCPLeases(&prov.Spec))
+
+	// Nothing is read when dnsmasq does not run
+	fetched = nil
+	prov.Spec.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkDisabled
+	assert.Nil(t, reconciler.countDHCPLeases(&prov.Spec))
+	assert.Empty(t, fetched)
+}
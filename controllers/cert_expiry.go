@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// certificatesValidCondition reports whether the serving certificates
+	// of the operands expire within the warning period
+	certificatesValidCondition = "CertificatesValid"
+	reasonCertificatesValid    = "CertificatesValid"
+	reasonCertificatesExpiring = "CertificatesExpiring"
+
+	// certificateExpiryRecheck is how often the certificates are checked
+	// again when none of them is about to expire
+	certificateExpiryRecheck = 24 * time.Hour
+)
+
+var operandCertificateExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "metal3_certificate_expiry_timestamp_seconds",
+	Help: "Expiry time of the serving certificates of the metal3 operands, in seconds since the epoch.",
+}, []string{"secret"})
+
+func init() {
+	metrics.Registry.MustRegister(operandCertificateExpiry)
+}
+
+// checkCertificateExpiry exports the expiry of the operand certificates
+// and records in the status those expiring within the warning period. It
+// returns when they should be checked again, as no event announces that
+// a certificate is about to expire.
+func (r *ProvisioningReconciler) checkCertificateExpiry(status *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec, now time.Time) (time.Duration, error) {
+	certs, err := provisioning.GetOperandCertificates(r.KubeClient.CoreV1(), ComponentNamespace)
+	if err != nil {
+		return 0, err
+	}
+	operandCertificateExpiry.Reset()
+	if len(certs) == 0 {
+		removeProvisioningCondition(status, certificatesValidCondition)
+		return 0, nil
+	}
+
+	warning := provisioning.GetCertificateExpiryWarning(spec)
+	recheck := certificateExpiryRecheck
+	expiring := []string{}
+	for _, cert := range certs {
+		operandCertificateExpiry.WithLabelValues(cert.SecretName).Set(float64(cert.NotAfter.Unix()))
+		untilWarning := cert.NotAfter.Add(-warning).Sub(now)
+		if untilWarning > 0 {
+			if untilWarning < recheck {
+				recheck = untilWarning
+			}
+			continue
+		}
+		verb := "expires"
+		if cert.NotAfter.Before(now) {
+			verb = "expired"
+		}
+		expiring = append(expiring, fmt.Sprintf("%s %s on %s", cert.SecretName, verb, cert.NotAfter.Format(time.RFC3339)))
+	}
+	if len(expiring) > 0 {
+		r.Log.Info("operand certificates are about to expire", "certificates", expiring)
+		setProvisioningCondition(status, certificatesValidCondition, operatorv1.ConditionFalse, reasonCertificatesExpiring,
+			strings.Join(expiring, ", "))
+		return recheck, nil
+	}
+	setProvisioningCondition(status, certificatesValidCondition, operatorv1.ConditionTrue, reasonCertificatesValid, "")
+	return recheck, nil
+}
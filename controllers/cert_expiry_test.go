@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestCheckCertificateExpiry(t *testing.T) {
+	// The test certificates expire within the hour
+	cert, _ := newTestCA(t)
+
+	tCases := []struct {
+		name            string
+		objects         []runtime.Object
+		now             time.Time
+		expectedStatus  operatorv1.ConditionStatus
+		expectedRecheck bool
+	}{
+		{
+			name: "NoCertificate",
+		},
+		{
+			name:           "Expiring",
+			objects:        []runtime.Object{newIronicAPITlsSecret(cert)},
+			now:            time.Now(),
+			expectedStatus: operatorv1.ConditionFalse,
+		},
+		{
+			name:           "Expired",
+			objects:        []runtime.Object{newIronicAPITlsSecret(cert), newImageServerTlsSecret(cert)},
+			now:            time.Now().Add(2 * time.Hour),
+			expectedStatus: operatorv1.ConditionFalse,
+		},
+		{
+			name:            "Valid",
+			objects:         []runtime.Object{newIronicAPITlsSecret(cert)},
+			now:             time.Now().Add(-30*24*time.Hour - 12*time.Hour),
+			expectedStatus:  operatorv1.ConditionTrue,
+			expectedRecheck: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+			}
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(tc.objects...)
+
+			status := &metal3iov1alpha1.ProvisioningStatus{}
+			recheck, err := reconciler.checkCertificateExpiry(status, &prov.Spec, tc.now)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedRecheck, recheck > 0 && recheck < certificateExpiryRecheck, "%v", recheck)
+
+			var cond *operatorv1.OperatorCondition
+			for i := range status.Conditions {
+				if status.Conditions[i].Type == certificatesValidCondition {
+					cond = &status.Conditions[i]
+				}
+			}
+			if tc.expectedStatus == "" {
+				assert.Nil(t, cond)
+				return
+			}
+			if assert.NotNil(t, cond) {
+				assert.Equal(t, tc.expectedStatus, cond.Status)
+				if tc.expectedStatus == operatorv1.ConditionFalse {
+					assert.Contains(t, cond.Message, "metal3-ironic-api-tls")
+				}
+			}
+
+			metric := &dto.Metric{}
+			assert.NoError(t, operandCertificateExpiry.WithLabelValues("metal3-ironic-api-tls").Write(metric))
+			assert.NotZero(t, metric.GetGauge().GetValue())
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups=flowcontrol.apiserver.k8s.io,resources=flowschemas,verbs=get;create;update
+
+// longClientThrottle is the delay by the client-side rate limiter past
+// which a request is logged
+const longClientThrottle = time.Second
+
+var clientRateLimiterDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "rest_client_rate_limiter_duration_seconds",
+	Help:    "Time the requests of the operator to the API server waited for the client-side rate limiter, in seconds.",
+	Buckets: []float64{0.005, 0.025, 0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+}, []string{"verb", "url"})
+
+// rateLimiterLatency records how long the client-side rate limiter
+// delayed the requests, by verb and URL template
+type rateLimiterLatency struct{}
+
+func (rateLimiterLatency) Observe(verb string, u url.URL, latency time.Duration) {
+	clientRateLimiterDuration.WithLabelValues(verb, u.Path).Observe(latency.Seconds())
+	if latency > longClientThrottle {
+		ctrl.Log.WithName("client").Info("request delayed by client-side throttling",
+			"verb", verb, "url", u.Path, "delay", latency.String())
+	}
+}
+
+func init() {
+	metrics.Registry.MustRegister(clientRateLimiterDuration)
+	// controller-runtime already registered its client metrics, and
+	// client-go takes a single registration
+	clientmetrics.RateLimiterLatency = rateLimiterLatency{}
+}
+
+// applyOperatorFlowSchema gives the requests of the operator and of the
+// metal3 services a priority level of their own. The flow schema is
+// applied by the operator rather than shipped as a manifest, as its
+// service account lives in ComponentNamespace.
+func (r *ProvisioningReconciler) applyOperatorFlowSchema() error {
+	return provisioning.ApplyOperatorFlowSchema(r.KubeClient.FlowcontrolV1alpha1(), provisioning.NewOperatorFlowSchema(ComponentNamespace))
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+)
+
+func TestRateLimiterLatency(t *testing.T) {
+	assert.Equal(t, rateLimiterLatency{}, clientmetrics.RateLimiterLatency)
+
+	u := url.URL{Scheme: "https", Host: "172.30.0.1:443", Path: "/api/v1/namespaces/{namespace}/secrets/{name}"}
+	clientmetrics.RateLimiterLatency.Observe("GET", u, 10*time.Millisecond)
+	clientmetrics.RateLimiterLatency.Observe("GET", u, 2*time.Second)
+
+	metric := &dto.Metric{}
+	observer := clientRateLimiterDuration.WithLabelValues("GET", "/api/v1/namespaces/{namespace}/secrets/{name}")
+	assert.NoError(t, observer.(prometheus.Metric).Write(metric))
+	assert.Equal(t, uint64(2), metric.GetHistogram().GetSampleCount())
+	assert.InDelta(t, 2.01, metric.GetHistogram().GetSampleSum(), 0.0001)
+}
@@ -40,6 +40,54 @@ const (
 	// ReasonDeploymentCrashLooping indicates that the deployment is crashlooping
 	ReasonDeploymentCrashLooping StatusReason = "DeploymentCrashLooping"
 
+	// ReasonImagePullFailure indicates that a metal3 container image cannot be pulled
+	ReasonImagePullFailure StatusReason = "ImagePullFailure"
+
+	// ReasonPortConflict indicates that a metal3 container cannot bind one of its ports
+	ReasonPortConflict StatusReason = "PortConflict"
+
+	// ReasonIronicConflict indicates that another Ironic already runs on the
+	// provisioning nodes or network, or another DHCP server answers on the
+	// provisioning network
+	ReasonIronicConflict StatusReason = "IronicConflict"
+
+	// ReasonProvisioningInterfaceError indicates that a metal3 container cannot use the provisioning interface
+	ReasonProvisioningInterfaceError StatusReason = "ProvisioningInterfaceError"
+
+	// ReasonProvisioningIPMismatch indicates that the ProvisioningIP is not configured on the provisioning interface of a node
+	ReasonProvisioningIPMismatch StatusReason = "ProvisioningIPMismatch"
+
+	// ReasonNoProvisioningNodes indicates that no node matches the placement of the metal3 pods
+	ReasonNoProvisioningNodes StatusReason = "NoProvisioningNodes"
+
+	// ReasonUnsupportedConfiguration indicates that the Provisioning configuration is not supported on the platform
+	ReasonUnsupportedConfiguration StatusReason = "UnsupportedConfiguration"
+
+	// ReasonOSImageVerificationFailed indicates that the signature of the provisioning OS image could not be verified
+	ReasonOSImageVerificationFailed StatusReason = "OSImageVerificationFailed"
+
+	// ReasonOperandRolloutFailed indicates that a new revision of the metal3 deployment did not become healthy
+	ReasonOperandRolloutFailed StatusReason = "OperandRolloutFailed"
+
+	// ReasonLegacyTakeoverInProgress indicates that the metal3 resources of the
+	// machine-api-operator are being taken over
+	ReasonLegacyTakeoverInProgress StatusReason = "LegacyTakeoverInProgress"
+
+	// ReasonOperandRolloutInProgress indicates that a new revision of the metal3
+	// deployment is being verified
+	ReasonOperandRolloutInProgress StatusReason = "OperandRolloutInProgress"
+
+	// ReasonHostsProvisioning indicates that BareMetalHosts are being deployed
+	// or cleaned
+	ReasonHostsProvisioning StatusReason = "HostsProvisioning"
+
+	// ReasonMaintenance indicates that the metal3 pods are scaled down on request
+	ReasonMaintenance StatusReason = "Maintenance"
+
+	// ReasonProvisioningDisabled indicates that the metal3 stack is torn down
+	// on request
+	ReasonProvisioningDisabled StatusReason = "ProvisioningDisabled"
+
 	// ReasonUnsupported is an unsupported StatusReason
 	ReasonUnsupported StatusReason = "UnsupportedPlatform"
 )
@@ -57,7 +105,8 @@ func defaultStatusConditions() []osconfigv1.ClusterOperatorStatusCondition {
 }
 
 // relatedObjects returns the current list of ObjectReference's for the
-// ClusterOperator objects's status.
+// ClusterOperator objects's status. They are collected by must-gather, so
+// they include the namespace holding the metal3 pods and their logs.
 func relatedObjects() []osconfigv1.ObjectReference {
 	return []osconfigv1.ObjectReference{
 		{
@@ -65,10 +114,25 @@ func relatedObjects() []osconfigv1.ObjectReference {
 			Resource: "namespaces",
 			Name:     ComponentNamespace,
 		},
+		{
+			Group:    "metal3.io",
+			Resource: "provisionings",
+			Name:     BaremetalProvisioningCR,
+		},
+		{
+			Group:    "apiextensions.k8s.io",
+			Resource: "customresourcedefinitions",
+			Name:     "baremetalhosts.metal3.io",
+		},
 		{
 			Group:     "metal3.io",
 			Resource:  "baremetalhosts",
-			Name:      "baremetalhosts.metal3.io",
+			Namespace: ComponentNamespace,
+		},
+		{
+			Group:     "apps",
+			Resource:  "deployments",
+			Name:      "metal3",
 			Namespace: ComponentNamespace,
 		},
 	}
@@ -149,6 +213,7 @@ func (r *ProvisioningReconciler) syncStatus(co *osconfigv1.ClusterOperator, cond
 	for _, c := range conds {
 		v1helpers.SetStatusCondition(&co.Status.Conditions, c)
 	}
+	co.Status.RelatedObjects = relatedObjects()
 
 	_, err := r.OSClient.ConfigV1().ClusterOperators().UpdateStatus(context.Background(), co, metav1.UpdateOptions{})
 	return err
@@ -162,6 +227,7 @@ func (r *ProvisioningReconciler) updateCOStatus(newReason StatusReason, msg, pro
 		return err
 	}
 	conds := defaultStatusConditions()
+	v1helpers.SetStatusCondition(&conds, r.upgradeableCondition())
 	switch newReason {
 	case ReasonUnsupported:
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(OperatorDisabled, osconfigv1.ConditionTrue, string(newReason), msg))
@@ -169,14 +235,18 @@ func (r *ProvisioningReconciler) updateCOStatus(newReason StatusReason, msg, pro
 	case ReasonSyncing:
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionTrue, string(newReason), msg))
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorProgressing, osconfigv1.ConditionTrue, string(newReason), progressMsg))
+	case ReasonMaintenance, ReasonProvisioningDisabled:
+		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionTrue, string(newReason), msg))
+		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorProgressing, osconfigv1.ConditionFalse, string(newReason), progressMsg))
 	case ReasonComplete:
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionTrue, string(newReason), msg))
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorProgressing, osconfigv1.ConditionFalse, string(newReason), progressMsg))
-	case ReasonInvalidConfiguration, ReasonDeployTimedOut:
+	case ReasonInvalidConfiguration, ReasonUnsupportedConfiguration, ReasonDeployTimedOut, ReasonOperandRolloutFailed:
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorDegraded, osconfigv1.ConditionTrue, string(newReason), msg))
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionTrue, string(ReasonEmpty), ""))
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorProgressing, osconfigv1.ConditionTrue, string(newReason), progressMsg))
-	case ReasonDeploymentCrashLooping:
+	case ReasonDeploymentCrashLooping, ReasonImagePullFailure, ReasonPortConflict, ReasonIronicConflict, ReasonProvisioningInterfaceError,
+		ReasonProvisioningIPMismatch, ReasonNoProvisioningNodes, ReasonOSImageVerificationFailed:
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorDegraded, osconfigv1.ConditionTrue, string(newReason), msg))
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionFalse, string(newReason), msg))
 		v1helpers.SetStatusCondition(&conds, setStatusCondition(osconfigv1.OperatorProgressing, osconfigv1.ConditionFalse, string(newReason), progressMsg))
@@ -266,3 +266,31 @@ func TestUpdateCOStatusDegraded(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateCOStatusOperandFailure(t *testing.T) {
+	for _, reason := range []StatusReason{ReasonImagePullFailure, ReasonPortConflict, ReasonProvisioningInterfaceError, ReasonNoProvisioningNodes} {
+		t.Run(string(reason), func(t *testing.T) {
+			expectedConditions := []osconfigv1.ClusterOperatorStatusCondition{
+				setStatusCondition(osconfigv1.OperatorDegraded, osconfigv1.ConditionTrue, string(reason), "metal3 container is failing"),
+				setStatusCondition(osconfigv1.OperatorProgressing, osconfigv1.ConditionFalse, string(reason), ""),
+				setStatusCondition(osconfigv1.OperatorAvailable, osconfigv1.ConditionFalse, string(reason), "metal3 container is failing"),
+				setStatusCondition(osconfigv1.OperatorUpgradeable, osconfigv1.ConditionTrue, "", ""),
+				setStatusCondition(OperatorDisabled, osconfigv1.ConditionFalse, "", ""),
+			}
+
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &osconfigv1.Infrastructure{})
+			co, _ := reconciler.createClusterOperator()
+			reconciler.OSClient = fakeconfigclientset.NewSimpleClientset(co)
+
+			if err := reconciler.updateCOStatus(reason, "metal3 container is failing", ""); err != nil {
+				t.Error(err)
+			}
+			gotCO, _ := reconciler.OSClient.ConfigV1().ClusterOperators().Get(context.Background(), clusterOperatorName, metav1.GetOptions{})
+
+			diff := getStatusConditionsDiff(expectedConditions, gotCO.Status.Conditions)
+			if diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
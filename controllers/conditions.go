@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// setProvisioningCondition sets a condition in the status of a
+// Provisioning instance, keeping its transition time when its status
+// does not change
+func setProvisioningCondition(status *metal3iov1alpha1.ProvisioningStatus, condType string, condStatus operatorv1.ConditionStatus, reason string, message string) {
+	cond := operatorv1.OperatorCondition{
+		Type:               condType,
+		Status:             condStatus,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+	for i, existing := range status.Conditions {
+		if existing.Type != condType {
+			continue
+		}
+		if existing.Status == cond.Status {
+			cond.LastTransitionTime = existing.LastTransitionTime
+		}
+		status.Conditions[i] = cond
+		return
+	}
+	status.Conditions = append(status.Conditions, cond)
+}
+
+// removeProvisioningCondition removes a condition from the status of a
+// Provisioning instance
+func removeProvisioningCondition(status *metal3iov1alpha1.ProvisioningStatus, condType string) {
+	conditions := []operatorv1.OperatorCondition{}
+	for _, cond := range status.Conditions {
+		if cond.Type != condType {
+			conditions = append(conditions, cond)
+		}
+	}
+	if len(conditions) != len(status.Conditions) {
+		status.Conditions = conditions
+	}
+}
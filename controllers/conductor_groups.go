@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// conductorGroupsRetry is how soon the conductor groups of the Ironic
+	// nodes are set again when no metal3 pod was ready to answer or a
+	// node could not be moved
+	conductorGroupsRetry = time.Minute
+	// conductorGroupsRecheck is how often the conductor groups of the
+	// Ironic nodes are checked, for the nodes registered since
+	conductorGroupsRecheck = 2 * time.Minute
+)
+
+// syncConductorGroups deploys the conductors of the configured groups
+// and removes those of the groups that are not configured anymore. It
+// returns the state of each group.
+func (r *ProvisioningReconciler) syncConductorGroups(prov *metal3iov1alpha1.Provisioning, images *provisioning.Images, spec *metal3iov1alpha1.ProvisioningSpec) ([]metal3iov1alpha1.ConductorGroupStatus, error) {
+	if err := provisioning.DeleteStaleConductorGroups(r.KubeClient.AppsV1(), ComponentNamespace, spec.ConductorGroups); err != nil {
+		return nil, err
+	}
+	if len(spec.ConductorGroups) == 0 {
+		return nil, provisioning.DeleteMetal3DatabaseService(r.KubeClient.CoreV1(), ComponentNamespace)
+	}
+
+	service := provisioning.NewMetal3DatabaseService(ComponentNamespace, spec)
+	if err := controllerutil.SetControllerReference(prov, service, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := provisioning.ApplyMetal3DatabaseService(r.KubeClient.CoreV1(), service); err != nil {
+		return nil, err
+	}
+
+	hosts, err := r.countConductorGroupHosts()
+	if err != nil {
+		return nil, err
+	}
+	// The conductors download the IPA artifacts with the credentials too
+	imagePullSecretHash, err := r.syncImagePullSecret(spec)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []metal3iov1alpha1.ConductorGroupStatus{}
+	for i := range spec.ConductorGroups {
+		group := &spec.ConductorGroups[i]
+		deployment := provisioning.NewConductorGroupDeployment(ComponentNamespace, images, spec, group)
+		if imagePullSecretHash != "" {
+			provisioning.SetImagePullSecretHash(&deployment.Spec.Template, imagePullSecretHash)
+		}
+		if refreshGeneration := provisioning.GetImageRefreshGeneration(prov); refreshGeneration != "" {
+			provisioning.SetImageRefreshGeneration(&deployment.Spec.Template, refreshGeneration)
+		}
+		if err := controllerutil.SetControllerReference(prov, deployment, r.Scheme); err != nil {
+			return nil, err
+		}
+		if _, err := provisioning.ApplyMetal3Deployment(r.KubeClient.AppsV1(), deployment); err != nil {
+			return nil, err
+		}
+
+		pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(), metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels).String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		endpoints := provisioning.GetConductorEndpoints(pods.Items)
+		statuses = append(statuses, metal3iov1alpha1.ConductorGroupStatus{
+			Name:          group.Name,
+			ReadyReplicas: int32(len(endpoints)),
+			Endpoints:     endpoints,
+			Hosts:         hosts[group.Name],
+		})
+	}
+	return statuses, nil
+}
+
+// countConductorGroupHosts returns the number of BareMetalHosts labelled
+// for each conductor group
+func (r *ProvisioningReconciler) countConductorGroupHosts() (map[string]int, error) {
+	hosts := newBareMetalHostList()
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(ComponentNamespace),
+		client.HasLabels{provisioning.ConductorGroupLabel}); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, host := range hosts.Items {
+		counts[host.GetLabels()[provisioning.ConductorGroupLabel]]++
+	}
+	return counts, nil
+}
+
+// syncNodeConductorGroups sets the conductor_group of the Ironic nodes of
+// the BareMetalHosts from their ConductorGroupLabel, as the
+// baremetal-operator registers every node without one. It runs while
+// groups are configured, and once more after they were all removed so
+// that the nodes return to the conductor of the metal3 pod. It returns
+// when the nodes are to be checked again, for those registered since.
+func (r *ProvisioningReconciler) syncNodeConductorGroups(prov *metal3iov1alpha1.Provisioning, spec *metal3iov1alpha1.ProvisioningSpec) (time.Duration, error) {
+	if len(spec.ConductorGroups) == 0 && len(prov.Status.ConductorGroups) == 0 {
+		return 0, nil
+	}
+	podIP, err := r.readyMetal3PodIP()
+	if err != nil || podIP == "" {
+		return conductorGroupsRetry, err
+	}
+	ironic, err := r.newIronicAPIClient(podIP, spec)
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := ironic.ListNodes()
+	if err != nil {
+		return 0, err
+	}
+	hosts := newBareMetalHostList()
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(ComponentNamespace)); err != nil {
+		return 0, err
+	}
+	hostGroups := map[string]string{}
+	for i := range hosts.Items {
+		if id := hostIronicID(&hosts.Items[i]); id != "" {
+			hostGroups[id] = hosts.Items[i].GetLabels()[provisioning.ConductorGroupLabel]
+		}
+	}
+
+	var failed error
+	for uuid, group := range provisioning.DiffNodeConductorGroups(nodes, hostGroups, spec.ConductorGroups) {
+		// A node locked by a running operation is moved on the next check
+		if err := ironic.SetNodeConductorGroup(uuid, group); err != nil {
+			failed = err
+			continue
+		}
+		r.Log.Info("moved Ironic node to conductor group", "node", uuid, "group", group)
+	}
+	if failed != nil {
+		return 0, failed
+	}
+	if len(spec.ConductorGroups) == 0 {
+		return 0, nil
+	}
+	return conductorGroupsRecheck, nil
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newConductorGroupHost(name, group string) *unstructured.Unstructured {
+	host := newTestBareMetalHost(ComponentNamespace, name, "provisioned")
+	if group != "" {
+		host.SetLabels(map[string]string{provisioning.ConductorGroupLabel: group})
+	}
+	return host
+}
+
+func TestSyncConductorGroups(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ConductorGroups: []metal3iov1alpha1.ConductorGroup{{Name: "rack-a"}, {Name: "rack-b"}},
+		},
+	}
+	readyConductor := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "metal3-conductor-rack-a-abcde",
+			Namespace: ComponentNamespace,
+			Labels: map[string]string{
+				"k8s-app":                        "metal3",
+				"controller":                     "metal3-conductor-rack-a",
+				provisioning.ConductorGroupLabel: "rack-a",
+			},
+		},
+		Status: corev1.PodStatus{
+			PodIP:      "192.168.111.21",
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	stale := provisioning.NewConductorGroupDeployment(ComponentNamespace, &provisioning.Images{}, &prov.Spec,
+		&metal3iov1alpha1.ConductorGroup{Name: "rack-c"})
+
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, []runtime.Object{
+		prov,
+		newConductorGroupHost("worker-0", "rack-a"),
+		newConductorGroupHost("worker-1", "rack-a"),
+		newConductorGroupHost("worker-2", "rack-b"),
+		newConductorGroupHost("worker-3", ""),
+	}...)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(readyConductor, stale)
+
+	statuses, err := reconciler.syncConductorGroups(prov, &provisioning.Images{}, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, []metal3iov1alpha1.ConductorGroupStatus{
+		{Name: "rack-a", ReadyReplicas: 1, Endpoints: []string{"192.168.111.21:8089"}, Hosts: 2},
+		{Name: "rack-b", ReadyReplicas: 0, Endpoints: []string{}, Hosts: 1},
+	}, statuses)
+
+	deployments := reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace)
+	for _, name := range []string{"metal3-conductor-rack-a", "metal3-conductor-rack-b"} {
+		_, err := deployments.Get(context.Background(), name, metav1.GetOptions{})
+		assert.NoError(t, err, name)
+	}
+	_, err = deployments.Get(context.Background(), "metal3-conductor-rack-c", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	_, err = reconciler.KubeClient.CoreV1().Services(ComponentNamespace).Get(context.Background(), "metal3-database", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	// Without groups, the database is not exposed anymore
+	prov.Spec.ConductorGroups = nil
+	statuses, err = reconciler.syncConductorGroups(prov, &provisioning.Images{}, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Empty(t, statuses)
+	_, err = reconciler.KubeClient.CoreV1().Services(ComponentNamespace).Get(context.Background(), "metal3-database", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestSyncNodeConductorGroups(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ConductorGroups: []metal3iov1alpha1.ConductorGroup{{Name: "rack-a"}},
+		},
+	}
+	withID := func(host *unstructured.Unstructured, id string) *unstructured.Unstructured {
+		_ = unstructured.SetNestedField(host.Object, id, "status", "provisioning", "ID")
+		return host
+	}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov,
+		withID(newConductorGroupHost("worker-0", "rack-a"), "uuid-0"),
+		withID(newConductorGroupHost("worker-1", "rack-a"), "uuid-1"),
+		withID(newConductorGroupHost("worker-2", ""), "uuid-2"),
+		withID(newConductorGroupHost("worker-3", "rack-b"), "uuid-3"),
+		newConductorGroupHost("worker-4", "rack-a"))
+	pod := newReadyMetal3Pod("metal3-a", "master-0")
+	pod.Status.PodIP = "192.168.111.20"
+	reconciler.KubeClient = fakekube.NewSimpleClientset(pod)
+	ironic := &fakeIronicClient{
+		nodes: []provisioning.IronicNode{
+			{UUID: "uuid-0"},
+			{UUID: "uuid-1", ConductorGroup: "rack-a"},
+			{UUID: "uuid-2", ConductorGroup: "rack-a"},
+			{UUID: "uuid-3"},
+			{UUID: "uuid-9"},
+		},
+	}
+	reconciler.ironicClient = func(podIP string) (ironicAPIClient, error) {
+		return ironic, nil
+	}
+
+	recheck, err := reconciler.syncNodeConductorGroups(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, conductorGroupsRecheck, recheck)
+	assert.ElementsMatch(t, []string{"conductor_group uuid-0=rack-a", "conductor_group uuid-2="}, ironic.calls)
+
+	// A locked node is moved on the next attempt
+	ironic.calls = nil
+	ironic.failing = map[string]bool{"uuid-0": true}
+	_, err = reconciler.syncNodeConductorGroups(prov, &prov.Spec)
+	assert.EqualError(t, err, "node uuid-0 is locked")
+
+	// The nodes return to the metal3 pod once the groups are removed
+	ironic.calls = nil
+	ironic.failing = nil
+	ironic.nodes = []provisioning.IronicNode{{UUID: "uuid-0", ConductorGroup: "rack-a"}}
+	prov.Status.ConductorGroups = []metal3iov1alpha1.ConductorGroupStatus{{Name: "rack-a"}}
+	prov.Spec.ConductorGroups = nil
+	recheck, err = reconciler.syncNodeConductorGroups(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Equal(t, []string{"conductor_group uuid-0="}, ironic.calls)
+
+	prov.Status.ConductorGroups = nil
+	ironic.calls = nil
+	_, err = reconciler.syncNodeConductorGroups(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Empty(t, ironic.calls)
+}
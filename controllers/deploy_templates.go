@@ -0,0 +1,211 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// deployTemplatesRetry is how soon the deploy templates are
+	// registered again when no metal3 pod was ready to answer
+	deployTemplatesRetry = time.Minute
+	// deployTemplatesRecheck is how often the deploy templates are
+	// compared with those of Ironic, whose database does not outlive the
+	// metal3 pod
+	deployTemplatesRecheck = 10 * time.Minute
+)
+
+// templateNames returns the names of the given deploy templates
+func templateNames(templates []provisioning.IronicDeployTemplate) []string {
+	var names []string
+	for _, template := range templates {
+		names = append(names, template.Name)
+	}
+	return names
+}
+
+// newBIOSDeployTemplates reads the ConfigMaps of the BIOSProfiles and
+// returns their deploy templates
+func (r *ProvisioningReconciler) newBIOSDeployTemplates(spec *metal3iov1alpha1.ProvisioningSpec) ([]provisioning.IronicDeployTemplate, error) {
+	templates := []provisioning.IronicDeployTemplate{}
+	for _, profile := range spec.BIOSProfiles {
+		configMap := &corev1.ConfigMap{}
+		err := r.Client.Get(context.Background(), client.ObjectKey{Namespace: ComponentNamespace, Name: profile.ConfigMapName}, configMap)
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Errorf("BIOSProfiles ConfigMap %s not found", profile.ConfigMapName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		template, err := provisioning.NewBIOSDeployTemplate(profile, configMap)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+// syncDeployTemplates registers the DefaultRAIDConfig and the
+// BIOSProfiles as Ironic deploy templates, and deletes the templates of
+// those that were removed. The registered traits are recorded in the
+// status, and it returns when the templates are to be checked again.
+func (r *ProvisioningReconciler) syncDeployTemplates(status *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec) (time.Duration, error) {
+	if len(spec.DefaultRAIDConfig) == 0 && len(spec.BIOSProfiles) == 0 &&
+		len(status.RAIDTemplates) == 0 && len(status.BIOSTemplates) == 0 {
+		return 0, nil
+	}
+	raid := provisioning.NewRAIDDeployTemplates(spec)
+	bios, err := r.newBIOSDeployTemplates(spec)
+	if err != nil {
+		return 0, err
+	}
+	podIP, err := r.readyMetal3PodIP()
+	if err != nil || podIP == "" {
+		return deployTemplatesRetry, err
+	}
+	ironic, err := r.newIronicAPIClient(podIP, spec)
+	if err != nil {
+		return 0, err
+	}
+	existing, err := ironic.ListDeployTemplates()
+	if err != nil {
+		return 0, err
+	}
+
+	desired := append(append([]provisioning.IronicDeployTemplate{}, raid...), bios...)
+	create, update, remove := provisioning.DiffDeployTemplates(desired, existing)
+	for _, template := range create {
+		if err := ironic.CreateDeployTemplate(template); err != nil {
+			return 0, err
+		}
+		r.Log.Info("registered deploy template", "template", template.Name)
+	}
+	for _, template := range update {
+		if err := ironic.UpdateDeployTemplate(template); err != nil {
+			return 0, err
+		}
+		r.Log.Info("updated deploy template", "template", template.Name)
+	}
+	for _, name := range remove {
+		if err := ironic.DeleteDeployTemplate(name); err != nil {
+			return 0, err
+		}
+		r.Log.Info("deleted deploy template", "template", name)
+	}
+
+	status.RAIDTemplates = templateNames(raid)
+	status.BIOSTemplates = templateNames(bios)
+	if len(desired) == 0 {
+		return 0, nil
+	}
+	return deployTemplatesRecheck, nil
+}
+
+// syncNodeDeployTraits sets the traits of the Ironic nodes of the
+// BareMetalHosts from their RAIDTemplateLabel and BIOSProfileLabel, so
+// that the deployment of a node runs the deploy templates its host
+// requests. Each trait is both added to the node and requested through
+// its instance_info, as Ironic only runs the templates matching the
+// traits requested by a deployment. It runs while templates are
+// configured, and once more after they were all removed so that their
+// traits are removed from the nodes. It returns when the nodes are to be
+// checked again.
+func (r *ProvisioningReconciler) syncNodeDeployTraits(prov *metal3iov1alpha1.Provisioning, spec *metal3iov1alpha1.ProvisioningSpec) (time.Duration, error) {
+	if len(spec.DefaultRAIDConfig) == 0 && len(spec.BIOSProfiles) == 0 &&
+		len(prov.Status.RAIDTemplates) == 0 && len(prov.Status.BIOSTemplates) == 0 {
+		return 0, nil
+	}
+	podIP, err := r.readyMetal3PodIP()
+	if err != nil || podIP == "" {
+		return deployTemplatesRetry, err
+	}
+	ironic, err := r.newIronicAPIClient(podIP, spec)
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := ironic.ListNodes()
+	if err != nil {
+		return 0, err
+	}
+	hosts := newBareMetalHostList()
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(ComponentNamespace)); err != nil {
+		return 0, err
+	}
+	hostTraits := map[string][]string{}
+	for i := range hosts.Items {
+		if id := hostIronicID(&hosts.Items[i]); id != "" {
+			hostTraits[id] = provisioning.HostDeployTraits(hosts.Items[i].GetLabels(), spec)
+		}
+	}
+
+	var failed error
+	for uuid, change := range provisioning.DiffNodeTraits(nodes, hostTraits) {
+		// A node locked by a running operation is updated on the next check
+		if err := r.applyNodeTraitsChange(ironic, uuid, change); err != nil {
+			failed = err
+			continue
+		}
+		r.Log.Info("updated the deploy traits of Ironic node", "node", uuid, "traits", change.InstanceTraits)
+	}
+	if failed != nil {
+		return 0, failed
+	}
+	if len(spec.DefaultRAIDConfig) == 0 && len(spec.BIOSProfiles) == 0 {
+		return 0, nil
+	}
+	return deployTemplatesRecheck, nil
+}
+
+func (r *ProvisioningReconciler) applyNodeTraitsChange(ironic ironicAPIClient, uuid string, change provisioning.NodeTraitsChange) error {
+	for _, trait := range change.Add {
+		if err := ironic.AddNodeTrait(uuid, trait); err != nil {
+			return err
+		}
+	}
+	// The traits are requested once the node carries them, as Ironic
+	// refuses to deploy a node with traits it does not have
+	if change.SetInstanceTraits {
+		if err := ironic.SetNodeInstanceTraits(uuid, change.InstanceTraits); err != nil {
+			return err
+		}
+	}
+	for _, trait := range change.Remove {
+		if err := ironic.RemoveNodeTrait(uuid, trait); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// biosProfilesToProvisioning maps changes to the ConfigMaps referenced by
+// the BIOSProfiles to a reconcile of the Provisioning singleton, so that
+// their deploy templates are updated.
+func (r *ProvisioningReconciler) biosProfilesToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() != ComponentNamespace {
+		return nil
+	}
+	prov := &metal3iov1alpha1.Provisioning{}
+	if err := r.Client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, prov); err != nil {
+		return nil
+	}
+	for _, profile := range prov.Spec.BIOSProfiles {
+		if profile.ConfigMapName == obj.Meta.GetName() {
+			return []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestSyncDeployTemplates(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			DefaultRAIDConfig: []metal3iov1alpha1.RAIDTemplate{
+				{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "1", IsRootVolume: true}}},
+				{Name: "STRIPE", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "0"}}},
+			},
+			BIOSProfiles: []metal3iov1alpha1.BIOSProfile{
+				{Name: "STORAGE", ConfigMapName: "bios-storage"},
+			},
+		},
+	}
+	biosSettings := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bios-storage", Namespace: ComponentNamespace},
+		Data:       map[string]string{"ProcVirtualization": "Disabled"},
+	}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov, biosSettings)
+	pod := newReadyMetal3Pod("metal3-a", "master-0")
+	pod.Status.PodIP = "192.168.111.20"
+	reconciler.KubeClient = fakekube.NewSimpleClientset(pod)
+	ironic := &fakeIronicClient{
+		templates: []provisioning.IronicDeployTemplate{
+			{Name: "CUSTOM_OCP_RAID_MIRROR"},
+			{Name: "CUSTOM_OCP_RAID_LEGACY"},
+			{Name: "CUSTOM_CPU_PERFORMANCE"},
+		},
+	}
+	reconciler.ironicClient = func(podIP string) (ironicAPIClient, error) {
+		assert.Equal(t, "192.168.111.20", podIP)
+		return ironic, nil
+	}
+
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	recheck, err := reconciler.syncDeployTemplates(status, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, deployTemplatesRecheck, recheck)
+	assert.Equal(t, []string{
+		"create CUSTOM_OCP_RAID_STRIPE", "create CUSTOM_OCP_BIOS_STORAGE",
+		"update CUSTOM_OCP_RAID_MIRROR", "delete CUSTOM_OCP_RAID_LEGACY",
+	}, ironic.calls)
+	assert.Equal(t, []string{"CUSTOM_OCP_RAID_MIRROR", "CUSTOM_OCP_RAID_STRIPE"}, status.RAIDTemplates)
+	assert.Equal(t, []string{"CUSTOM_OCP_BIOS_STORAGE"}, status.BIOSTemplates)
+
+	// The templates are deleted once the layouts and profiles are removed
+	bios, _ := provisioning.NewBIOSDeployTemplate(prov.Spec.BIOSProfiles[0], biosSettings)
+	ironic.calls = nil
+	ironic.templates = append(provisioning.NewRAIDDeployTemplates(&prov.Spec), bios)
+	prov.Spec.DefaultRAIDConfig = nil
+	prov.Spec.BIOSProfiles = nil
+	recheck, err = reconciler.syncDeployTemplates(status, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Equal(t, []string{
+		"delete CUSTOM_OCP_BIOS_STORAGE", "delete CUSTOM_OCP_RAID_MIRROR", "delete CUSTOM_OCP_RAID_STRIPE",
+	}, ironic.calls)
+	assert.Empty(t, status.RAIDTemplates)
+	assert.Empty(t, status.BIOSTemplates)
+
+	// Nothing happens once they are gone
+	ironic.calls = nil
+	recheck, err = reconciler.syncDeployTemplates(status, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Empty(t, ironic.calls)
+}
+
+func TestSyncDeployTemplatesFailure(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			DefaultRAIDConfig: []metal3iov1alpha1.RAIDTemplate{
+				{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "1"}}},
+			},
+		},
+	}
+	spec := &prov.Spec
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newReadyMetal3Pod("metal3-a", "master-0"))
+
+	// No pod is ready to answer yet
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	recheck, err := reconciler.syncDeployTemplates(status, spec)
+	assert.NoError(t, err)
+	assert.Equal(t, deployTemplatesRetry, recheck)
+
+	pod := newReadyMetal3Pod("metal3-b", "master-1")
+	pod.Status.PodIP = "192.168.111.21"
+	reconciler.KubeClient = fakekube.NewSimpleClientset(pod)
+	ironic := &fakeIronicClient{failing: map[string]bool{"CUSTOM_OCP_RAID_MIRROR": true}}
+	reconciler.ironicClient = func(podIP string) (ironicAPIClient, error) {
+		return ironic, nil
+	}
+	_, err = reconciler.syncDeployTemplates(status, spec)
+	assert.EqualError(t, err, "template CUSTOM_OCP_RAID_MIRROR is invalid")
+	assert.Empty(t, status.RAIDTemplates)
+
+	// A missing BIOS profile ConfigMap stops the sync before Ironic is
+	// called
+	spec.BIOSProfiles = []metal3iov1alpha1.BIOSProfile{{Name: "STORAGE", ConfigMapName: "bios-storage"}}
+	ironic.failing = nil
+	_, err = reconciler.syncDeployTemplates(status, spec)
+	assert.EqualError(t, err, "BIOSProfiles ConfigMap bios-storage not found")
+	assert.Empty(t, ironic.calls)
+}
+
+func TestSyncNodeDeployTraits(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			DefaultRAIDConfig: []metal3iov1alpha1.RAIDTemplate{
+				{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "1", IsRootVolume: true}}},
+			},
+			BIOSProfiles: []metal3iov1alpha1.BIOSProfile{
+				{Name: "STORAGE", ConfigMapName: "bios-storage"},
+			},
+		},
+	}
+	newHost := func(name, id string, labels map[string]string) *unstructured.Unstructured {
+		host := newTestBareMetalHost(ComponentNamespace, name, "ready")
+		_ = unstructured.SetNestedField(host.Object, id, "status", "provisioning", "ID")
+		host.SetLabels(labels)
+		return host
+	}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov,
+		newHost("worker-0", "uuid-0", map[string]string{provisioning.RAIDTemplateLabel: "MIRROR"}),
+		newHost("worker-1", "uuid-1", nil),
+		newHost("worker-2", "uuid-2", map[string]string{provisioning.BIOSProfileLabel: "STORAGE"}))
+	pod := newReadyMetal3Pod("metal3-a", "master-0")
+	pod.Status.PodIP = "192.168.111.20"
+	reconciler.KubeClient = fakekube.NewSimpleClientset(pod)
+	ironic := &fakeIronicClient{
+		nodes: []provisioning.IronicNode{
+			{UUID: "uuid-0", ProvisionState: "available"},
+			{UUID: "uuid-1", ProvisionState: "available", Traits: []string{"CUSTOM_OCP_RAID_MIRROR", "CUSTOM_GPU"}},
+			{UUID: "uuid-2", ProvisionState: "manageable"},
+		},
+	}
+	reconciler.ironicClient = func(podIP string) (ironicAPIClient, error) {
+		return ironic, nil
+	}
+
+	recheck, err := reconciler.syncNodeDeployTraits(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, deployTemplatesRecheck, recheck)
+	assert.ElementsMatch(t, []string{
+		"add trait uuid-0=CUSTOM_OCP_RAID_MIRROR", "instance traits uuid-0=CUSTOM_OCP_RAID_MIRROR",
+		"remove trait uuid-1=CUSTOM_OCP_RAID_MIRROR",
+		"add trait uuid-2=CUSTOM_OCP_BIOS_STORAGE", "instance traits uuid-2=CUSTOM_OCP_BIOS_STORAGE",
+	}, ironic.calls)
+
+	// A locked node is updated on the next attempt
+	ironic.calls = nil
+	ironic.failing = map[string]bool{"uuid-0": true}
+	_, err = reconciler.syncNodeDeployTraits(prov, &prov.Spec)
+	assert.EqualError(t, err, "node uuid-0 is locked")
+
+	// The traits are removed from the nodes once the layouts are removed
+	ironic.calls = nil
+	ironic.failing = nil
+	ironic.nodes = []provisioning.IronicNode{{
+		UUID: "uuid-0", ProvisionState: "manageable", Traits: []string{"CUSTOM_OCP_RAID_MIRROR"},
+		InstanceInfo: provisioning.IronicInstanceInfo{Traits: []string{"CUSTOM_OCP_RAID_MIRROR"}},
+	}}
+	prov.Status.RAIDTemplates = []string{"CUSTOM_OCP_RAID_MIRROR"}
+	prov.Spec.DefaultRAIDConfig = nil
+	prov.Spec.BIOSProfiles = nil
+	recheck, err = reconciler.syncNodeDeployTraits(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Equal(t, []string{"instance traits uuid-0=", "remove trait uuid-0=CUSTOM_OCP_RAID_MIRROR"}, ironic.calls)
+
+	prov.Status.RAIDTemplates = nil
+	ironic.calls = nil
+	_, err = reconciler.syncNodeDeployTraits(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Empty(t, ironic.calls)
+}
+
+func TestBIOSProfilesToProvisioning(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			BIOSProfiles: []metal3iov1alpha1.BIOSProfile{
+				{Name: "STORAGE", ConfigMapName: "bios-storage"},
+				{Name: "COMPUTE", ConfigMapName: "bios-compute"},
+			},
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	mapObject := func(namespace, name string) handler.MapObject {
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		return handler.MapObject{Meta: configMap, Object: configMap}
+	}
+
+	assert.Len(t, reconciler.biosProfilesToProvisioning(mapObject(ComponentNamespace, "bios-compute")), 1)
+	assert.Empty(t, reconciler.biosProfilesToProvisioning(mapObject(ComponentNamespace, "other")))
+	assert.Empty(t, reconciler.biosProfilesToProvisioning(mapObject("default", "bios-compute")))
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups=config.openshift.io,resources=featuregates,verbs=get;list;watch
+
+// getFeatureSet returns the feature set enabled on the cluster
+func (r *ProvisioningReconciler) getFeatureSet() (osconfigv1.FeatureSet, error) {
+	featureGate := &osconfigv1.FeatureGate{}
+	err := r.Client.Get(context.Background(), client.ObjectKey{Name: provisioning.ClusterFeatureGateName}, featureGate)
+	if apierrors.IsNotFound(err) {
+		return provisioning.GetFeatureSet(nil), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return provisioning.GetFeatureSet(featureGate), nil
+}
+
+// featureGateToProvisioning maps changes to the cluster FeatureGate to a
+// reconcile of the Provisioning singleton, so that a Provisioning waiting
+// for tech preview features is applied once they are enabled.
+func featureGateToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetName() != provisioning.ClusterFeatureGateName {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+	}
+}
@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestGetFeatureSet(t *testing.T) {
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &osconfigv1.Infrastructure{})
+	featureSet, err := reconciler.getFeatureSet()
+	assert.NoError(t, err)
+	assert.Equal(t, osconfigv1.Default, featureSet)
+
+	reconciler = newFakeProvisioningReconciler(setUpSchemeForReconciler(), &osconfigv1.FeatureGate{
+		ObjectMeta: metav1.ObjectMeta{Name: provisioning.ClusterFeatureGateName},
+		Spec: osconfigv1.FeatureGateSpec{
+			FeatureGateSelection: osconfigv1.FeatureGateSelection{FeatureSet: osconfigv1.TechPreviewNoUpgrade},
+		},
+	})
+	featureSet, err = reconciler.getFeatureSet()
+	assert.NoError(t, err)
+	assert.Equal(t, osconfigv1.TechPreviewNoUpgrade, featureSet)
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// HealthChecks verifies the dependencies the operator needs to make
+// progress, for the readiness probe of its Deployment
+type HealthChecks struct {
+	KubeClient kubernetes.Interface
+	// WebhookCertDir is the directory holding the serving certificate of
+	// the webhook server, empty when the webhook is not served
+	WebhookCertDir string
+}
+
+// CheckAPIServer fails when the API server cannot be reached
+func (h *HealthChecks) CheckAPIServer(_ *http.Request) error {
+	if _, err := h.KubeClient.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("unable to reach the API server: %v", err)
+	}
+	return nil
+}
+
+// CheckOperandNamespace fails when the resources of the namespace the
+// operands are deployed in cannot be read
+func (h *HealthChecks) CheckOperandNamespace(req *http.Request) error {
+	_, err := h.KubeClient.CoreV1().ConfigMaps(ComponentNamespace).List(req.Context(), metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return fmt.Errorf("unable to read namespace %s: %v", ComponentNamespace, err)
+	}
+	return nil
+}
+
+// CheckWebhookCert fails when the serving certificate of the webhook
+// server is missing, not valid yet or expired
+func (h *HealthChecks) CheckWebhookCert(_ *http.Request) error {
+	return checkCertificateFile(filepath.Join(h.WebhookCertDir, "tls.crt"), time.Now())
+}
+
+func checkCertificateFile(path string, now time.Time) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read webhook certificate: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("no PEM certificate found in %s", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid webhook certificate: %v", err)
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("webhook certificate is not valid before %s", cert.NotBefore.Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("webhook certificate expired on %s", cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// SetupWithManager registers the health checks with the manager. The
+// liveness probe only checks that the manager serves requests, so that
+// an API server outage does not restart the operator.
+func (h *HealthChecks) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("api-server", h.CheckAPIServer); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("operand-namespace", h.CheckOperandNamespace); err != nil {
+		return err
+	}
+	if h.WebhookCertDir != "" {
+		return mgr.AddReadyzCheck("webhook-cert", h.CheckWebhookCert)
+	}
+	return nil
+}
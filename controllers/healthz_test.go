@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// unreachableDiscovery fails to get the server version, which the fake
+// discovery client cannot be made to do with reactors
+type unreachableDiscovery struct {
+	discovery.DiscoveryInterface
+}
+
+func (unreachableDiscovery) ServerVersion() (*version.Info, error) {
+	return nil, fmt.Errorf("connection refused")
+}
+
+type unreachableClientset struct {
+	*fakekube.Clientset
+}
+
+func (c unreachableClientset) Discovery() discovery.DiscoveryInterface {
+	return unreachableDiscovery{c.Clientset.Discovery()}
+}
+
+func TestHealthChecks(t *testing.T) {
+	tests := []struct {
+		name           string
+		apiUnreachable bool
+		failVerb       string
+		failResource   string
+		expectedAPI    bool
+		expectedNSRead bool
+	}{
+		{
+			name:           "Healthy",
+			expectedAPI:    true,
+			expectedNSRead: true,
+		},
+		{
+			name:           "APIServerUnreachable",
+			apiUnreachable: true,
+			expectedAPI:    false,
+			expectedNSRead: true,
+		},
+		{
+			name:           "OperandNamespaceUnreadable",
+			failVerb:       "list",
+			failResource:   "configmaps",
+			expectedAPI:    true,
+			expectedNSRead: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakekube.NewSimpleClientset()
+			if tc.failVerb != "" {
+				kubeClient.PrependReactor(tc.failVerb, tc.failResource, func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, fmt.Errorf("connection refused")
+				})
+			}
+			checks := &HealthChecks{KubeClient: kubeClient}
+			if tc.apiUnreachable {
+				checks.KubeClient = unreachableClientset{kubeClient}
+			}
+			req, err := http.NewRequest("GET", "/readyz", nil)
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expectedAPI, checks.CheckAPIServer(req) == nil)
+			assert.Equal(t, tc.expectedNSRead, checks.CheckOperandNamespace(req) == nil)
+		})
+	}
+}
+
+func TestCheckCertificateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhook-cert")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cert, _ := newTestCA(t)
+	certFile := filepath.Join(dir, "tls.crt")
+	assert.NoError(t, ioutil.WriteFile(certFile, cert, 0600))
+	invalidFile := filepath.Join(dir, "invalid.crt")
+	assert.NoError(t, ioutil.WriteFile(invalidFile, []byte("not a certificate"), 0600))
+
+	tests := []struct {
+		name        string
+		path        string
+		now         time.Time
+		expectedErr bool
+	}{
+		{
+			name: "Valid",
+			path: certFile,
+			now:  time.Now(),
+		},
+		{
+			name:        "Expired",
+			path:        certFile,
+			now:         time.Now().Add(2 * time.Hour),
+			expectedErr: true,
+		},
+		{
+			name:        "NotYetValid",
+			path:        certFile,
+			now:         time.Now().Add(-2 * time.Hour),
+			expectedErr: true,
+		},
+		{
+			name:        "Missing",
+			path:        filepath.Join(dir, "missing.crt"),
+			now:         time.Now(),
+			expectedErr: true,
+		},
+		{
+			name:        "NotPEM",
+			path:        invalidFile,
+			now:         time.Now(),
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkCertificateFile(tc.path, tc.now)
+			assert.Equal(t, tc.expectedErr, err != nil, "%v", err)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// highAvailabilityCondition reports whether the replicas of the
+	// metal3 pod are placed as requested by the HighAvailability mode
+	highAvailabilityCondition = "HighAvailability"
+	reasonReplicasSpread      = "ReplicasSpread"
+	reasonInsufficientNodes   = "InsufficientNodes"
+	reasonInsufficientZones   = "InsufficientZones"
+)
+
+// highAvailability is the placement of the metal3 pods on the nodes: the
+// address each node got from the ProvisioningIPPool, and the node of the
+// active pod holding the ProvisioningIP
+type highAvailability struct {
+	ipAllocation provisioningIPAllocation
+	vipNode      string
+}
+
+// reconcileHighAvailability allocates the addresses of the
+// ProvisioningIPPool to the nodes and elects the active metal3 pod
+func (r *ProvisioningReconciler) reconcileHighAvailability(prov *metal3iov1alpha1.Provisioning, spec *metal3iov1alpha1.ProvisioningSpec, nodes []corev1.Node) (highAvailability, error) {
+	ipAllocation, err := r.syncProvisioningIPs(prov, spec, nodes)
+	if err != nil {
+		return highAvailability{}, errors.Wrap(err, "failed to allocate provisioning IPs")
+	}
+	vipNode, err := r.syncProvisioningVIP(prov, spec)
+	if err != nil {
+		return highAvailability{}, errors.Wrap(err, "failed to elect the provisioning VIP holder")
+	}
+	return highAvailability{ipAllocation: ipAllocation, vipNode: vipNode}, nil
+}
+
+// setHighAvailability records the placement of the metal3 pods in the
+// status
+func setHighAvailability(status *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec, nodes []corev1.Node, ha highAvailability) {
+	setHighAvailabilityCondition(status, spec, nodes)
+	setProvisioningIPs(status, spec, ha.ipAllocation)
+	setProvisioningVIP(status, spec, ha.vipNode)
+}
+
+// getPlaceableReplicas returns the number of metal3 pods to run. The
+// pods cannot share a node, so no more pods than nodes are run, which
+// would otherwise stay pending and fail every rollout.
+func getPlaceableReplicas(spec *metal3iov1alpha1.ProvisioningSpec, nodes []corev1.Node) int32 {
+	replicas := provisioning.GetMetal3Replicas(spec)
+	if int(replicas) > len(nodes) {
+		return int32(len(nodes))
+	}
+	return replicas
+}
+
+// setHighAvailabilityCondition records in the status whether the metal3
+// pods can run on distinct nodes and zones
+func setHighAvailabilityCondition(status *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec, nodes []corev1.Node) {
+	if spec.HighAvailability == nil {
+		removeProvisioningCondition(status, highAvailabilityCondition)
+		return
+	}
+	replicas := spec.HighAvailability.Replicas
+	if int(replicas) > len(nodes) {
+		setProvisioningCondition(status, highAvailabilityCondition, operatorv1.ConditionFalse, reasonInsufficientNodes,
+			fmt.Sprintf("%d metal3 replicas requested but only %d nodes can run them, running one on each", replicas, len(nodes)))
+		return
+	}
+	if zones, labelled := provisioning.GetNodeZones(nodes); labelled && zones < int(replicas) {
+		setProvisioningCondition(status, highAvailabilityCondition, operatorv1.ConditionFalse, reasonInsufficientZones,
+			fmt.Sprintf("%d metal3 replicas cannot be spread across the %d zones of the nodes", replicas, zones))
+		return
+	}
+	setProvisioningCondition(status, highAvailabilityCondition, operatorv1.ConditionTrue, reasonReplicasSpread, "")
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newZonedNodes(zones ...string) []corev1.Node {
+	nodes := []corev1.Node{}
+	for i, zone := range zones {
+		node := *newMasterNode(fmt.Sprintf("master-%d", i))
+		if zone != "" {
+			node.Labels[corev1.LabelZoneFailureDomainStable] = zone
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func TestHighAvailabilityPlacement(t *testing.T) {
+	tests := []struct {
+		name             string
+		replicas         int32
+		nodes            []corev1.Node
+		expectedReplicas int32
+		expectedStatus   operatorv1.ConditionStatus
+		expectedReason   string
+	}{
+		{
+			name:             "Disabled",
+			nodes:            newZonedNodes("", "", ""),
+			expectedReplicas: 1,
+		},
+		{
+			name:             "SpreadWithoutZones",
+			replicas:         3,
+			nodes:            newZonedNodes("", "", ""),
+			expectedReplicas: 3,
+			expectedStatus:   operatorv1.ConditionTrue,
+			expectedReason:   reasonReplicasSpread,
+		},
+		{
+			name:             "SpreadAcrossZones",
+			replicas:         3,
+			nodes:            newZonedNodes("a", "b", "c"),
+			expectedReplicas: 3,
+			expectedStatus:   operatorv1.ConditionTrue,
+			expectedReason:   reasonReplicasSpread,
+		},
+		{
+			name:             "InsufficientNodes",
+			replicas:         3,
+			nodes:            newZonedNodes("", ""),
+			expectedReplicas: 2,
+			expectedStatus:   operatorv1.ConditionFalse,
+			expectedReason:   reasonInsufficientNodes,
+		},
+		{
+			name:             "InsufficientZones",
+			replicas:         3,
+			nodes:            newZonedNodes("a", "a", "b"),
+			expectedReplicas: 3,
+			expectedStatus:   operatorv1.ConditionFalse,
+			expectedReason:   reasonInsufficientZones,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &metal3iov1alpha1.ProvisioningSpec{}
+			if tc.replicas > 0 {
+				spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: tc.replicas}
+			}
+			assert.Equal(t, tc.expectedReplicas, getPlaceableReplicas(spec, tc.nodes))
+
+			status := &metal3iov1alpha1.ProvisioningStatus{}
+			setProvisioningCondition(status, highAvailabilityCondition, operatorv1.ConditionUnknown, "", "")
+			setHighAvailabilityCondition(status, spec, tc.nodes)
+			if tc.expectedStatus == "" {
+				assert.Empty(t, status.Conditions)
+				return
+			}
+			if assert.Len(t, status.Conditions, 1) {
+				assert.Equal(t, highAvailabilityCondition, status.Conditions[0].Type)
+				assert.Equal(t, tc.expectedStatus, status.Conditions[0].Status)
+				assert.Equal(t, tc.expectedReason, status.Conditions[0].Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// maxListedHosts is how many hosts are named in the Upgradeable condition
+const maxListedHosts = 5
+
+// The BareMetalHost API is not vendored, so hosts are read as
+// unstructured objects.
+var bareMetalHostGVK = schema.GroupVersionKind{Group: "metal3.io", Version: "v1alpha1", Kind: "BareMetalHost"}
+
+// disruptedHostStates are the provisioning states of the BareMetalHosts
+// that fail when Ironic restarts, as a host being deployed or cleaned is
+// left half written
+var disruptedHostStates = map[string]bool{
+	"provisioning":   true,
+	"deprovisioning": true,
+	"preparing":      true,
+}
+
+func newBareMetalHost() *unstructured.Unstructured {
+	host := &unstructured.Unstructured{}
+	host.SetGroupVersionKind(bareMetalHostGVK)
+	return host
+}
+
+func newBareMetalHostList() *unstructured.UnstructuredList {
+	hosts := &unstructured.UnstructuredList{}
+	hosts.SetGroupVersionKind(bareMetalHostGVK.GroupVersion().WithKind(bareMetalHostGVK.Kind + "List"))
+	return hosts
+}
+
+func hostProvisioningState(host *unstructured.Unstructured) string {
+	state, _, _ := unstructured.NestedString(host.Object, "status", "provisioning", "state")
+	return state
+}
+
+// hostIronicID returns the UUID of the Ironic node of the host, once the
+// baremetal-operator registered it
+func hostIronicID(host *unstructured.Unstructured) string {
+	id, _, _ := unstructured.NestedString(host.Object, "status", "provisioning", "ID")
+	return id
+}
+
+// listBusyHosts returns the names of the BareMetalHosts being deployed or
+// cleaned
+func (r *ProvisioningReconciler) listBusyHosts() ([]string, error) {
+	hosts := newBareMetalHostList()
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(ComponentNamespace)); err != nil {
+		return nil, err
+	}
+	busy := []string{}
+	for i := range hosts.Items {
+		if disruptedHostStates[hostProvisioningState(&hosts.Items[i])] {
+			busy = append(busy, hosts.Items[i].GetName())
+		}
+	}
+	sort.Strings(busy)
+	return busy, nil
+}
+
+// busyHostsMessage explains why cluster upgrades are blocked by the given
+// hosts, or returns an empty string when there are none
+func busyHostsMessage(hosts []string) string {
+	if len(hosts) == 0 {
+		return ""
+	}
+	names := strings.Join(hosts, ", ")
+	if len(hosts) > maxListedHosts {
+		names = fmt.Sprintf("%s and %d more", strings.Join(hosts[:maxListedHosts], ", "), len(hosts)-maxListedHosts)
+	}
+	return fmt.Sprintf("%d hosts are being provisioned or cleaned: %s", len(hosts), names)
+}
+
+// bareMetalHostToProvisioning maps changes to the BareMetalHosts to a
+// reconcile of the Provisioning singleton.
+func bareMetalHostToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() != ComponentNamespace {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+	}
+}
+
+// hostStateChanged filters the BareMetalHost updates down to those
+// changing their provisioning state, their labels or their Ironic node,
+// as hosts update their status far more often. The labels select the
+// conductor group and the deploy templates of the node of a host, which
+// are set while it is not deployed.
+var hostStateChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldHost, oldOK := e.ObjectOld.(*unstructured.Unstructured)
+		newHost, newOK := e.ObjectNew.(*unstructured.Unstructured)
+		if !oldOK || !newOK {
+			return true
+		}
+		return hostProvisioningState(oldHost) != hostProvisioningState(newHost) ||
+			hostIronicID(oldHost) != hostIronicID(newHost) ||
+			!reflect.DeepEqual(oldHost.GetLabels(), newHost.GetLabels())
+	},
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newTestBareMetalHost(namespace, name, state string) *unstructured.Unstructured {
+	host := newBareMetalHost()
+	host.SetNamespace(namespace)
+	host.SetName(name)
+	if state != "" {
+		_ = unstructured.SetNestedField(host.Object, state, "status", "provisioning", "state")
+	}
+	return host
+}
+
+func TestListBusyHosts(t *testing.T) {
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, &osconfigv1.Infrastructure{})
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme,
+		newTestBareMetalHost(ComponentNamespace, "worker-2", "provisioning"),
+		newTestBareMetalHost(ComponentNamespace, "worker-1", "deprovisioning"),
+		newTestBareMetalHost(ComponentNamespace, "worker-0", "provisioned"),
+		newTestBareMetalHost(ComponentNamespace, "worker-3", ""),
+		newTestBareMetalHost("other", "worker-4", "preparing"),
+	)
+
+	hosts, err := reconciler.listBusyHosts()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"worker-1", "worker-2"}, hosts)
+	assert.Equal(t, "2 hosts are being provisioned or cleaned: worker-1, worker-2", busyHostsMessage(hosts))
+}
+
+func TestBusyHostsMessage(t *testing.T) {
+	assert.Equal(t, "", busyHostsMessage(nil))
+
+	hosts := []string{}
+	for i := 0; i < 7; i++ {
+		hosts = append(hosts, fmt.Sprintf("worker-%d", i))
+	}
+	assert.Equal(t, "7 hosts are being provisioned or cleaned: worker-0, worker-1, worker-2, worker-3, worker-4 and 2 more",
+		busyHostsMessage(hosts))
+}
+
+func TestHostStateChanged(t *testing.T) {
+	tCases := []struct {
+		name     string
+		oldState string
+		newState string
+		newID    string
+		newLabel string
+		expected bool
+	}{
+		{name: "StartsProvisioning", oldState: "ready", newState: "provisioning", expected: true},
+		{name: "Provisioned", oldState: "provisioning", newState: "provisioned", expected: true},
+		{name: "StartsCleaning", oldState: "provisioning", newState: "deprovisioning", expected: true},
+		{name: "Available", oldState: "deprovisioning", newState: "ready", expected: true},
+		{name: "Registered", oldState: "registering", newState: "registering", newID: "uuid-0", expected: true},
+		{name: "Labelled", oldState: "ready", newState: "ready", newLabel: "MIRROR", expected: true},
+		{name: "StatusUpdate", oldState: "provisioned", newState: "provisioned"},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			oldHost := newTestBareMetalHost(ComponentNamespace, "worker-0", tc.oldState)
+			newHost := newTestBareMetalHost(ComponentNamespace, "worker-0", tc.newState)
+			if tc.newID != "" {
+				_ = unstructured.SetNestedField(newHost.Object, tc.newID, "status", "provisioning", "ID")
+			}
+			if tc.newLabel != "" {
+				newHost.SetLabels(map[string]string{provisioning.RAIDTemplateLabel: tc.newLabel})
+			}
+			assert.Equal(t, tc.expected, hostStateChanged.Update(event.UpdateEvent{ObjectOld: oldHost, ObjectNew: newHost}))
+		})
+	}
+}
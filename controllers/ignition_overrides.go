@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=delete
+
+// ensureIgnitionOverrides gathers the ignition overrides of the labelled
+// ConfigMaps into the ConfigMap served by the ignition server, and
+// removes it once the overrides are not served anymore.
+func (r *ProvisioningReconciler) ensureIgnitionOverrides(prov *metal3iov1alpha1.Provisioning) error {
+	if !prov.Spec.EnableIgnitionOverrides {
+		return provisioning.DeleteIgnitionOverridesConfigMap(r.KubeClient.CoreV1(), ComponentNamespace)
+	}
+
+	sources := &corev1.ConfigMapList{}
+	if err := r.Client.List(context.Background(), sources, client.InNamespace(ComponentNamespace),
+		client.HasLabels{provisioning.IgnitionOverrideHostLabel}); err != nil {
+		return err
+	}
+	configMap, ignored := provisioning.NewIgnitionOverridesConfigMap(ComponentNamespace, sources.Items)
+	for _, name := range ignored {
+		r.Log.Info("ignoring ignition overrides: invalid or duplicate host, or no room left in the gathered overrides",
+			"configmap", name, "label", provisioning.IgnitionOverrideHostLabel)
+	}
+	if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+		return err
+	}
+	return provisioning.ApplyIgnitionOverridesConfigMap(r.KubeClient.CoreV1(), configMap)
+}
+
+// ignitionOverrideToProvisioning maps changes to the ConfigMaps holding
+// ignition overrides to a reconcile of the Provisioning singleton.
+func ignitionOverrideToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() != ComponentNamespace {
+		return nil
+	}
+	if _, ok := obj.Meta.GetLabels()[provisioning.IgnitionOverrideHostLabel]; !ok {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+	}
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newIgnitionOverrideSource(name, host string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{provisioning.IgnitionOverrideHostLabel: host},
+		},
+		Data: map[string]string{"ignition": `{"ignition":{"version":"3.2.0"}}`},
+	}
+}
+
+func TestEnsureIgnitionOverrides(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningIP:          "172.30.20.3",
+			EnableIgnitionOverrides: true,
+		},
+	}
+	unlabelled := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: ComponentNamespace},
+		Data:       map[string]string{"ignition": "{}"},
+	}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov,
+		newIgnitionOverrideSource("worker-0-overrides", "worker-0"), unlabelled)
+	kubeClient := fakekube.NewSimpleClientset()
+	reconciler.KubeClient = kubeClient
+
+	assert.NoError(t, reconciler.ensureIgnitionOverrides(prov))
+	configMap, err := kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(),
+		provisioning.IgnitionOverridesConfigMap, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"worker-0.ignition": `{"ignition":{"version":"3.2.0"}}`}, configMap.Data)
+		assert.Len(t, configMap.OwnerReferences, 1)
+	}
+
+	prov.Spec.EnableIgnitionOverrides = false
+	assert.NoError(t, reconciler.ensureIgnitionOverrides(prov))
+	_, err = kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(),
+		provisioning.IgnitionOverridesConfigMap, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	assert.NoError(t, reconciler.ensureIgnitionOverrides(prov))
+}
+
+func TestIgnitionOverrideToProvisioning(t *testing.T) {
+	source := newIgnitionOverrideSource("worker-0-overrides", "worker-0")
+	assert.Len(t, ignitionOverrideToProvisioning(handler.MapObject{Meta: source, Object: source}), 1)
+
+	unlabelled := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: ComponentNamespace}}
+	assert.Empty(t, ignitionOverrideToProvisioning(handler.MapObject{Meta: unlabelled, Object: unlabelled}))
+
+	elsewhere := newIgnitionOverrideSource("worker-0-overrides", "worker-0")
+	elsewhere.Namespace = "default"
+	assert.Empty(t, ignitionOverrideToProvisioning(handler.MapObject{Meta: elsewhere, Object: elsewhere}))
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// imagePrePullTimeout bounds how long the metal3 rollout waits for
+	// the new images to be pulled, so that a node unable to pull them
+	// does not block the upgrade
+	imagePrePullTimeout = 10 * time.Minute
+	// imagePrePullRequeueAfter is how often the timeout is checked, as
+	// the pre-pull DaemonSet is watched for progress
+	imagePrePullRequeueAfter = 30 * time.Second
+)
+
+// prePullImages pulls the images of the desired metal3 Deployment that the
+// deployed one does not use yet, on every node the metal3 pods may run on,
+// before the Deployment is rolled out to them. The Recreate rollout of the
+// metal3 Deployment then does not wait for image pulls while Ironic is
+// down. It returns true once the Deployment can be updated.
+func (r *ProvisioningReconciler) prePullImages(prov *metal3iov1alpha1.Provisioning, images *provisioning.Images, spec *metal3iov1alpha1.ProvisioningSpec, deployment *appsv1.Deployment) (bool, error) {
+	client := r.KubeClient.AppsV1()
+	existing, err := client.Deployments(deployment.Namespace).Get(context.Background(), deployment.Name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, err
+	}
+
+	newImages := []string{}
+	if err == nil {
+		newImages = provisioning.GetNewImages(&deployment.Spec.Template, &existing.Spec.Template)
+	}
+	if len(newImages) == 0 {
+		// Nothing is running yet, or the images are already rolled out
+		return true, provisioning.DeleteImagePrePullDaemonSet(client, deployment.Namespace)
+	}
+
+	daemonSet := provisioning.NewImagePrePullDaemonSet(deployment.Namespace, images, spec, newImages)
+	if err := controllerutil.SetControllerReference(prov, daemonSet, r.Scheme); err != nil {
+		return false, err
+	}
+	if _, err := provisioning.ApplyImagePrePullDaemonSet(client, daemonSet); err != nil {
+		return false, err
+	}
+	current, err := provisioning.GetImagePrePullDaemonSet(client, deployment.Namespace)
+	if err != nil || current == nil {
+		return false, err
+	}
+
+	if provisioning.IsImagePrePullComplete(current) {
+		r.Log.Info("new metal3 images pulled", "images", newImages)
+		return true, nil
+	}
+	if !current.CreationTimestamp.IsZero() && time.Since(current.CreationTimestamp.Time) > imagePrePullTimeout {
+		r.Log.Info("timed out pulling new metal3 images, rolling out anyway", "images", newImages)
+		return true, nil
+	}
+	r.Log.V(1).Info("waiting for new metal3 images to be pulled", "images", newImages)
+	return false, nil
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newMetal3Deployment(image string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "metal3", Namespace: ComponentNamespace},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"k8s-app": "metal3"}},
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "metal3-ironic-conductor", Image: image}},
+				},
+			},
+		},
+	}
+}
+
+func newImagePrePullDaemonSet(created time.Time, ready int32) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "metal3-image-prepull",
+			Namespace:         ComponentNamespace,
+			CreationTimestamp: metav1.NewTime(created),
+			Generation:        1,
+		},
+		Status: appsv1.DaemonSetStatus{
+			ObservedGeneration:     1,
+			DesiredNumberScheduled: 3,
+			UpdatedNumberScheduled: 3,
+			NumberReady:            ready,
+		},
+	}
+}
+
+func TestPrePullImages(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+	}
+
+	tCases := []struct {
+		name              string
+		objects           []runtime.Object
+		expectedPulled    bool
+		expectedDaemonSet bool
+	}{
+		{
+			name:           "FirstDeployment",
+			expectedPulled: true,
+		},
+		{
+			name: "ImagesUnchanged",
+			objects: []runtime.Object{newMetal3Deployment("ironic:2"),
+				newImagePrePullDaemonSet(time.Now(), 3)},
+			expectedPulled: true,
+		},
+		{
+			name:              "PrePullStarted",
+			objects:           []runtime.Object{newMetal3Deployment("ironic:1")},
+			expectedDaemonSet: true,
+		},
+		{
+			name: "PrePullInProgress",
+			objects: []runtime.Object{newMetal3Deployment("ironic:1"),
+				newImagePrePullDaemonSet(time.Now(), 1)},
+			expectedDaemonSet: true,
+		},
+		{
+			name: "PrePullComplete",
+			objects: []runtime.Object{newMetal3Deployment("ironic:1"),
+				newImagePrePullDaemonSet(time.Now(), 3)},
+			expectedPulled:    true,
+			expectedDaemonSet: true,
+		},
+		{
+			name: "PrePullTimedOut",
+			objects: []runtime.Object{newMetal3Deployment("ironic:1"),
+				newImagePrePullDaemonSet(time.Now().Add(-imagePrePullTimeout-time.Minute), 1)},
+			expectedPulled:    true,
+			expectedDaemonSet: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(tc.objects...)
+
+			pulled, err := reconciler.prePullImages(prov, &provisioning.Images{}, &prov.Spec, newMetal3Deployment("ironic:2"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedPulled, pulled)
+
+			_, err = reconciler.KubeClient.AppsV1().DaemonSets(ComponentNamespace).Get(context.Background(),
+				"metal3-image-prepull", metav1.GetOptions{})
+			assert.Equal(t, tc.expectedDaemonSet, err == nil)
+		})
+	}
+}
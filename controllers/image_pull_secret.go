@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// syncImagePullSecret reads the Secret referenced by the
+// ImagePullSecretRef, writes its credentials in the formats of the
+// downloaders, and returns a hash of them, recorded on the pods running
+// the downloaders so that they download again with the rotated
+// credentials. It returns an empty hash when no Secret is referenced. A
+// missing Secret would keep the metal3 pod from starting, so it is
+// reported as an invalid configuration, like invalid credentials. The
+// errors returned for them match ErrInvalidSpec.
+func (r *ProvisioningReconciler) syncImagePullSecret(spec *metal3iov1alpha1.ProvisioningSpec) (string, error) {
+	ref := spec.ImagePullSecretRef
+	if ref == nil {
+		return "", nil
+	}
+	secret := &corev1.Secret{}
+	err := r.Client.Get(context.Background(), client.ObjectKey{Namespace: ComponentNamespace, Name: ref.Name}, secret)
+	if apierrors.IsNotFound(err) {
+		return "", provisioning.NewInvalidSpecError(errors.Errorf("ImagePullSecretRef Secret %s not found", ref.Name))
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := provisioning.ValidateImagePullSecret(secret); err != nil {
+		return "", provisioning.NewInvalidSpecError(err)
+	}
+	credentials, err := provisioning.NewImagePullCredentialsSecret(ComponentNamespace, ref, secret)
+	if err != nil {
+		return "", provisioning.NewInvalidSpecError(err)
+	}
+	if err := provisioning.ApplyImagePullCredentialsSecret(r.KubeClient.CoreV1(), credentials); err != nil {
+		return "", err
+	}
+	return provisioning.GetImagePullSecretHash(secret), nil
+}
+
+// imagePullSecretToProvisioning maps changes to a Secret referenced by an
+// ImagePullSecretRef to a reconcile of the Provisioning resources
+// referencing it, so that rotated credentials are rolled out.
+func (r *ProvisioningReconciler) imagePullSecretToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() != ComponentNamespace {
+		return nil
+	}
+	instances := &metal3iov1alpha1.ProvisioningList{}
+	if err := r.Client.List(context.Background(), instances); err != nil {
+		r.Log.Error(err, "unable to list Provisioning CRs")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, instance := range instances.Items {
+		if ref := instance.Spec.ImagePullSecretRef; ref != nil && ref.Name == obj.Meta.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: instance.Name},
+			})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newTestImagePullSecret(dockerConfig string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mirror-pull-secret", Namespace: ComponentNamespace},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(dockerConfig)},
+	}
+}
+
+func TestSyncImagePullSecret(t *testing.T) {
+	spec := &metal3iov1alpha1.ProvisioningSpec{
+		ImagePullSecretRef: &metal3iov1alpha1.ImagePullSecretRef{Name: "mirror-pull-secret"},
+	}
+	tCases := []struct {
+		name          string
+		secret        *corev1.Secret
+		expectedError string
+	}{
+		{
+			name:   "Valid",
+			secret: newTestImagePullSecret(`{"auths": {"mirror.example.com": {"auth": "dXNlcjpwYXNz"}}}`),
+		},
+		{
+			name:          "Missing",
+			expectedError: "ImagePullSecretRef Secret mirror-pull-secret not found",
+		},
+		{
+			name:          "UnquotableCredentials",
+			secret:        newTestImagePullSecret(`{"auths": {"mirror.example.com": {"username": "user", "password": "two words"}}}`),
+			expectedError: "ImagePullSecretRef Secret mirror-pull-secret: invalid credentials for mirror.example.com: the login is empty, or the credentials hold whitespace or quotes",
+		},
+		{
+			name:          "NoCredentials",
+			secret:        newTestImagePullSecret(`{"auths": {}}`),
+			expectedError: "ImagePullSecretRef Secret mirror-pull-secret holds no credentials",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if tc.secret != nil {
+				objects = append(objects, tc.secret)
+			}
+			scheme := setUpSchemeForReconciler()
+			reconciler := newFakeProvisioningReconciler(scheme, &metal3iov1alpha1.Provisioning{})
+			reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, objects...)
+			reconciler.KubeClient = fakekube.NewSimpleClientset()
+
+			hash, err := reconciler.syncImagePullSecret(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, provisioning.GetImagePullSecretHash(tc.secret), hash)
+				// The credentials are written for curl
+				credentials, err := reconciler.KubeClient.CoreV1().Secrets(ComponentNamespace).Get(context.Background(),
+					"metal3-image-pull-mirror-pull-secret", metav1.GetOptions{})
+				if assert.NoError(t, err) {
+					assert.Equal(t, "machine mirror.example.com login user password pass\n", string(credentials.Data["netrc"]))
+				}
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+			assert.True(t, errors.Is(err, provisioning.ErrInvalidSpec))
+		})
+	}
+
+	hash, err := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &metal3iov1alpha1.Provisioning{}).
+		syncImagePullSecret(&metal3iov1alpha1.ProvisioningSpec{})
+	assert.NoError(t, err)
+	assert.Empty(t, hash)
+}
+
+func TestImagePullSecretToProvisioning(t *testing.T) {
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, &metal3iov1alpha1.Provisioning{})
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme,
+		&metal3iov1alpha1.Provisioning{
+			ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+			Spec: metal3iov1alpha1.ProvisioningSpec{
+				ImagePullSecretRef: &metal3iov1alpha1.ImagePullSecretRef{Name: "mirror-pull-secret"},
+			},
+		},
+		&metal3iov1alpha1.Provisioning{
+			ObjectMeta: metav1.ObjectMeta{Name: "rack-1"},
+			Spec: metal3iov1alpha1.ProvisioningSpec{
+				ImagePullSecretRef: &metal3iov1alpha1.ImagePullSecretRef{Name: "rack-1-pull-secret"},
+			},
+		},
+	)
+	mapObject := func(namespace, name string) handler.MapObject {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		return handler.MapObject{Meta: secret, Object: secret}
+	}
+
+	requests := reconciler.imagePullSecretToProvisioning(mapObject(ComponentNamespace, "rack-1-pull-secret"))
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "rack-1", requests[0].Name)
+	}
+	assert.Len(t, reconciler.imagePullSecretToProvisioning(mapObject(ComponentNamespace, "mirror-pull-secret")), 1)
+	assert.Empty(t, reconciler.imagePullSecretToProvisioning(mapObject(ComponentNamespace, "metal3-ironic-password")))
+	assert.Empty(t, reconciler.imagePullSecretToProvisioning(mapObject("default", "mirror-pull-secret")))
+}
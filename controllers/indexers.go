@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// provisioningOwnerField indexes the cached objects by the name of the
+// Provisioning resource controlling them, so that listing the objects of
+// a Provisioning resource does not go through every object of the kind
+const provisioningOwnerField = ".metadata.controller.provisioning"
+
+// controllingProvisioning returns the name of the Provisioning resource
+// controlling obj, if any
+func controllingProvisioning(obj runtime.Object) []string {
+	accessor, ok := obj.(metav1.Object)
+	if !ok {
+		return nil
+	}
+	owner := metav1.GetControllerOf(accessor)
+	if owner == nil || owner.Kind != "Provisioning" || owner.APIVersion != metal3iov1alpha1.GroupVersion.String() {
+		return nil
+	}
+	return []string{owner.Name}
+}
+
+// setupFieldIndexers adds the field indexes used by the reconciler to the
+// cache of the manager
+func setupFieldIndexers(mgr ctrl.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(context.Background(), &corev1.Pod{}, provisioningOwnerField, controllingProvisioning)
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newOwnedPod(name string, owner *metav1.OwnerReference) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ComponentNamespace}}
+	if owner != nil {
+		pod.OwnerReferences = []metav1.OwnerReference{*owner}
+	}
+	return pod
+}
+
+func provisioningOwner(name string) *metav1.OwnerReference {
+	return metav1.NewControllerRef(&metal3iov1alpha1.Provisioning{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		metal3iov1alpha1.GroupVersion.WithKind("Provisioning"))
+}
+
+func TestControllingProvisioning(t *testing.T) {
+	replicaSet := &metav1.OwnerReference{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "metal3", Controller: pointer.BoolPtr(true)}
+	notController := provisioningOwner(BaremetalProvisioningCR)
+	notController.Controller = nil
+
+	tests := []struct {
+		name     string
+		obj      runtime.Object
+		expected []string
+	}{
+		{
+			name:     "Provisioning",
+			obj:      newOwnedPod("check", provisioningOwner(BaremetalProvisioningCR)),
+			expected: []string{BaremetalProvisioningCR},
+		},
+		{
+			name: "OtherController",
+			obj:  newOwnedPod("metal3", replicaSet),
+		},
+		{
+			name: "NotController",
+			obj:  newOwnedPod("check", notController),
+		},
+		{
+			name: "NoOwner",
+			obj:  newOwnedPod("check", nil),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, controllingProvisioning(tc.obj))
+		})
+	}
+}
+
+// BenchmarkListOwnedPods compares listing the pods of a Provisioning
+// resource through the provisioningOwnerField index of the cache with
+// filtering every cached pod, in a namespace holding thousands of pods
+func BenchmarkListOwnedPods(b *testing.B) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		provisioningOwnerField: func(obj interface{}) ([]string, error) {
+			return controllingProvisioning(obj.(runtime.Object)), nil
+		},
+	})
+	for i := 0; i < 5000; i++ {
+		_ = indexer.Add(newOwnedPod(fmt.Sprintf("pod-%d", i), nil))
+	}
+	for i := 0; i < 3; i++ {
+		_ = indexer.Add(newOwnedPod(fmt.Sprintf("check-%d", i), provisioningOwner(BaremetalProvisioningCR)))
+	}
+
+	b.Run("Indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			pods, _ := indexer.ByIndex(provisioningOwnerField, BaremetalProvisioningCR)
+			if len(pods) != 3 {
+				b.Fatalf("expected 3 pods, got %d", len(pods))
+			}
+		}
+	})
+	b.Run("Unindexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			owned := 0
+			for _, obj := range indexer.List() {
+				names := controllingProvisioning(obj.(runtime.Object))
+				if len(names) == 1 && names[0] == BaremetalProvisioningCR {
+					owned++
+				}
+			}
+			if owned != 3 {
+				b.Fatalf("expected 3 pods, got %d", owned)
+			}
+		}
+	})
+}
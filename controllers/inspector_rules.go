@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// checkInspectorRules reads the ConfigMap referenced by the InspectorRules
+// and checks that it holds rules. A missing ConfigMap would keep the
+// metal3 pod from starting, so it is reported as an invalid
+// configuration. The errors returned for invalid rules match
+// ErrInvalidSpec.
+func (r *ProvisioningReconciler) checkInspectorRules(prov *metal3iov1alpha1.Provisioning) error {
+	rules := prov.Spec.InspectorRules
+	if rules == nil {
+		return nil
+	}
+	configMap := &corev1.ConfigMap{}
+	err := r.Client.Get(context.Background(), client.ObjectKey{Namespace: ComponentNamespace, Name: rules.ConfigMapName}, configMap)
+	if apierrors.IsNotFound(err) {
+		return provisioning.NewInvalidSpecError(errors.Errorf("InspectorRules ConfigMap %s not found", rules.ConfigMapName))
+	}
+	if err != nil {
+		return err
+	}
+	return provisioning.NewInvalidSpecError(provisioning.ValidateInspectorRules(configMap, rules))
+}
+
+// inspectorRulesToProvisioning maps changes to the ConfigMap referenced by
+// the InspectorRules to a reconcile of the Provisioning singleton, so that
+// fixed rules are noticed. The rules themselves are reloaded by the
+// metal3 pod.
+func (r *ProvisioningReconciler) inspectorRulesToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() != ComponentNamespace {
+		return nil
+	}
+	prov := &metal3iov1alpha1.Provisioning{}
+	if err := r.Client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, prov); err != nil {
+		return nil
+	}
+	if prov.Spec.InspectorRules == nil || prov.Spec.InspectorRules.ConfigMapName != obj.Meta.GetName() {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestCheckInspectorRules(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			InspectorRules: &metal3iov1alpha1.InspectorRules{ConfigMapName: "inspector-rules"},
+		},
+	}
+	tCases := []struct {
+		name          string
+		configMap     *corev1.ConfigMap
+		expectedError string
+	}{
+		{
+			name: "Valid",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "inspector-rules", Namespace: ComponentNamespace},
+				Data:       map[string]string{"rules.json": `[{"actions": [{"action": "set-capability", "name": "boot_mode", "value": "uefi"}]}]`},
+			},
+		},
+		{
+			name:          "Missing",
+			expectedError: "InspectorRules ConfigMap inspector-rules not found",
+		},
+		{
+			name: "Invalid",
+			configMap: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "inspector-rules", Namespace: ComponentNamespace},
+				Data:       map[string]string{"rules.json": `[{}]`},
+			},
+			expectedError: "rule 0 of ConfigMap inspector-rules has no actions",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []runtime.Object{prov}
+			if tc.configMap != nil {
+				objects = append(objects, tc.configMap)
+			}
+			scheme := setUpSchemeForReconciler()
+			reconciler := newFakeProvisioningReconciler(scheme, prov)
+			reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, objects...)
+
+			err := reconciler.checkInspectorRules(prov)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+			assert.True(t, errors.Is(err, provisioning.ErrInvalidSpec))
+		})
+	}
+}
+
+func TestInspectorRulesToProvisioning(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			InspectorRules: &metal3iov1alpha1.InspectorRules{ConfigMapName: "inspector-rules"},
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	mapObject := func(namespace, name string) handler.MapObject {
+		configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		return handler.MapObject{Meta: configMap, Object: configMap}
+	}
+
+	assert.Len(t, reconciler.inspectorRulesToProvisioning(mapObject(ComponentNamespace, "inspector-rules")), 1)
+	assert.Empty(t, reconciler.inspectorRulesToProvisioning(mapObject(ComponentNamespace, "other")))
+	assert.Empty(t, reconciler.inspectorRulesToProvisioning(mapObject("default", "inspector-rules")))
+}
@@ -0,0 +1,491 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	osclientset "github.com/openshift/client-go/config/clientset/versioned"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	integrationTimeout  = time.Minute
+	integrationInterval = 250 * time.Millisecond
+	integrationPodIP    = "192.168.111.20"
+)
+
+// integrationCRDs are the CRDs installed in the test API server: the
+// Provisioning CRD, those of the OpenShift APIs the operator reads or
+// writes, and the BareMetalHost CRD it watches
+var integrationCRDs = []string{
+	filepath.Join("..", "config", "crd", "bases"),
+	filepath.Join("..", "manifests", "0000_31_cluster-baremetal-operator_03_baremetalhost.crd.yaml"),
+	filepath.Join("..", "vendor", "github.com", "openshift", "api", "config", "v1", "0000_00_cluster-version-operator_01_clusteroperator.crd.yaml"),
+	filepath.Join("..", "vendor", "github.com", "openshift", "api", "config", "v1", "0000_10_config-operator_01_featuregate.crd.yaml"),
+	filepath.Join("..", "vendor", "github.com", "openshift", "api", "config", "v1", "0000_10_config-operator_01_infrastructure.crd.yaml"),
+}
+
+// envtestAssetsAvailable returns whether the etcd and kube-apiserver
+// binaries run by envtest can be found, as installed by
+// hack/fetch_ext_bins.sh
+func envtestAssetsAvailable() bool {
+	if os.Getenv("TEST_ASSET_KUBE_APISERVER") != "" && os.Getenv("TEST_ASSET_ETCD") != "" {
+		return true
+	}
+	dir := os.Getenv("KUBEBUILDER_ASSETS")
+	if dir == "" {
+		dir = "/usr/local/kubebuilder/bin"
+	}
+	for _, binary := range []string{"etcd", "kube-apiserver"} {
+		if _, err := os.Stat(filepath.Join(dir, binary)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// integrationEnvironment runs the controller against a real API server.
+// No kubelet nor controller manager runs there, so the pods the operator
+// creates and the metal3 Deployment are completed by simulateCluster.
+type integrationEnvironment struct {
+	client     client.Client
+	kubeClient kubernetes.Interface
+	osClient   osclientset.Interface
+	stop       func()
+}
+
+func startIntegrationEnvironment(t *testing.T) *integrationEnvironment {
+	if !envtestAssetsAvailable() {
+		t.Skip("envtest binaries not found, run hack/ci-test.sh or set KUBEBUILDER_ASSETS")
+	}
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     integrationCRDs,
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatalf("failed to start the test API server: %v", err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := metal3iov1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := osconfigv1.Install(scheme); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme,
+		MetricsBindAddress: "0",
+		NewCache:           NewCache,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The tests do not read through the cache, so that they see the
+	// writes of the controller right away
+	directClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env := &integrationEnvironment{
+		client:     directClient,
+		kubeClient: kubernetes.NewForConfigOrDie(cfg),
+		osClient:   osclientset.NewForConfigOrDie(cfg),
+	}
+
+	reconciler := &ProvisioningReconciler{
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("Provisioning"),
+		Scheme:     mgr.GetScheme(),
+		OSClient:   env.osClient,
+		KubeClient: env.kubeClient,
+		// The simulated metal3 pod serves no API
+		operandHealthProbe: func(url string) error { return nil },
+	}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := mgr.Start(stop); err != nil {
+			t.Errorf("manager stopped: %v", err)
+		}
+	}()
+	env.stop = func() {
+		close(stop)
+		<-done
+		if err := testEnv.Stop(); err != nil {
+			t.Errorf("failed to stop the test API server: %v", err)
+		}
+	}
+	return env
+}
+
+// createCluster creates the objects the installer and the other operators
+// provide: the operator namespace, the Infrastructure of a baremetal
+// cluster and a control plane node
+func (env *integrationEnvironment) createCluster(t *testing.T) {
+	ctx := context.Background()
+	if err := env.client.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ComponentNamespace}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	infra := &osconfigv1.Infrastructure{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}}
+	if err := env.client.Create(ctx, infra); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	infra.Status.Platform = osconfigv1.BareMetalPlatformType
+	infra.Status.PlatformStatus = &osconfigv1.PlatformStatus{Type: osconfigv1.BareMetalPlatformType}
+	if err := env.client.Status().Update(ctx, infra); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := env.client.Create(ctx, newMasterNode("master-0")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// applyProvisioning creates the Provisioning singleton, or replaces its
+// spec, and returns the generation to wait for
+func (env *integrationEnvironment) applyProvisioning(t *testing.T, spec metal3iov1alpha1.ProvisioningSpec) int64 {
+	ctx := context.Background()
+	prov := &metal3iov1alpha1.Provisioning{}
+	err := env.client.Get(ctx, client.ObjectKey{Name: BaremetalProvisioningCR}, prov)
+	if apierrors.IsNotFound(err) {
+		prov = &metal3iov1alpha1.Provisioning{
+			ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+			Spec:       spec,
+		}
+		if err := env.client.Create(ctx, prov); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return prov.Generation
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prov.Spec = spec
+	if err := env.client.Update(ctx, prov); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return prov.Generation
+}
+
+// simulateCluster completes the check pods created by the operator, and
+// rolls out the metal3 Deployment with a running pod, as the kubelet and
+// the deployment controller would
+func (env *integrationEnvironment) simulateCluster() error {
+	ctx := context.Background()
+	pods := env.kubeClient.CoreV1().Pods(ComponentNamespace)
+	podList, err := pods.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		owner := metav1.GetControllerOf(pod)
+		if owner == nil || owner.Kind != "Provisioning" || pod.Status.Phase == corev1.PodSucceeded {
+			continue
+		}
+		pod.Status.Phase = corev1.PodSucceeded
+		if _, err := pods.UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil && !apierrors.IsConflict(err) {
+			return err
+		}
+	}
+
+	deployments := env.kubeClient.AppsV1().Deployments(ComponentNamespace)
+	deployment, err := deployments.Get(ctx, "metal3", metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if provisioning.IsDeploymentRolledOut(deployment) {
+		return nil
+	}
+
+	// Replace the metal3 pods by one running the current template
+	podName := fmt.Sprintf("metal3-%.10s", deployment.Annotations[provisioning.RolloutHashAnnotation])
+	selector := labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels)
+	metal3Pods, err := pods.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return err
+	}
+	running := false
+	for _, pod := range metal3Pods.Items {
+		if pod.Name == podName {
+			running = true
+			continue
+		}
+		if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	if !running {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      podName,
+				Namespace: ComponentNamespace,
+				Labels:    deployment.Spec.Template.Labels,
+			},
+			Spec: *deployment.Spec.Template.Spec.DeepCopy(),
+		}
+		pod.Spec.NodeName = "master-0"
+		created, err := pods.Create(ctx, pod, metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		created.Status = corev1.PodStatus{Phase: corev1.PodRunning, PodIP: integrationPodIP}
+		if _, err := pods.UpdateStatus(ctx, created, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	deployment.Status = appsv1.DeploymentStatus{
+		ObservedGeneration: deployment.Generation,
+		Replicas:           replicas,
+		UpdatedReplicas:    replicas,
+		ReadyReplicas:      replicas,
+		AvailableReplicas:  replicas,
+	}
+	if _, err := deployments.UpdateStatus(ctx, deployment, metav1.UpdateOptions{}); err != nil && !apierrors.IsConflict(err) {
+		return err
+	}
+	return nil
+}
+
+// waitForHealthyGeneration waits until the operands of the given
+// generation of the Provisioning CR are rolled out and healthy
+func (env *integrationEnvironment) waitForHealthyGeneration(t *testing.T, generation int64) *metal3iov1alpha1.Provisioning {
+	prov := &metal3iov1alpha1.Provisioning{}
+	err := wait.PollImmediate(integrationInterval, integrationTimeout, func() (bool, error) {
+		if err := env.simulateCluster(); err != nil {
+			return false, err
+		}
+		if err := env.client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, prov); err != nil {
+			return false, err
+		}
+		last := prov.Status.LastSuccessfulConfiguration
+		return prov.Status.ObservedGeneration == generation && last != nil && last.Generation == generation, nil
+	})
+	if err != nil {
+		t.Fatalf("generation %d of the Provisioning CR did not become healthy: %v, status: %+v", generation, err, prov.Status)
+	}
+	return prov
+}
+
+func (env *integrationEnvironment) exists(t *testing.T, obj runtime.Object, name string) bool {
+	err := env.client.Get(context.Background(), client.ObjectKey{Namespace: ComponentNamespace, Name: name}, obj)
+	if apierrors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return true
+}
+
+func findProvisioningCondition(status *metal3iov1alpha1.ProvisioningStatus, condType string) *operatorv1.OperatorCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestIntegrationReconcile(t *testing.T) {
+	env := startIntegrationEnvironment(t)
+	defer env.stop()
+
+	savedImagesFile := ContainerImagesFile
+	ContainerImagesFile = filepath.Join("..", "provisioning", "sample_images.json")
+	defer func() { ContainerImagesFile = savedImagesFile }()
+
+	env.createCluster(t)
+
+	osDownloadURL := "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234"
+	testCases := []struct {
+		name              string
+		spec              metal3iov1alpha1.ProvisioningSpec
+		expectedDHCPRange string
+		expectedDnsmasq   bool
+		expectedService   bool
+		expectedSecrets   []string
+		expectedCerts     bool
+		// expectedImageServer is the address the image server URLs are
+		// published at, bracketed for IPv6
+		expectedImageServer string
+	}{
+		{
+			name: "Managed",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+				ProvisioningOSDownloadURL: osDownloadURL,
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkManaged,
+				ImageServerHTTPS:          true,
+			},
+			expectedDHCPRange: "172.30.20.11, 172.30.20.101",
+			expectedDnsmasq:   true,
+			expectedSecrets:   []string{"metal3-image-server-tls"},
+			expectedCerts:     true,
+		},
+		{
+			name: "Unmanaged",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningOSDownloadURL: osDownloadURL,
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+				IronicAPIExposure:         &metal3iov1alpha1.IronicAPIExposure{ClientCAConfigMap: "ironic-client-ca"},
+			},
+			expectedService: true,
+		},
+		{
+			name: "Disabled",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningOSDownloadURL: osDownloadURL,
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkDisabled,
+			},
+		},
+		{
+			name: "ManagedIPv6",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "fd2e:6f44:5dd8:b856::3",
+				ProvisioningNetworkCIDR:   "fd2e:6f44:5dd8:b856::/64",
+				ProvisioningDHCPRange:     "fd2e:6f44:5dd8:b856::10,fd2e:6f44:5dd8:b856::ff",
+				ProvisioningOSDownloadURL: osDownloadURL,
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkManaged,
+				ImageServerHTTPS:          true,
+			},
+			expectedDHCPRange:   "fd2e:6f44:5dd8:b856::10,fd2e:6f44:5dd8:b856::ff",
+			expectedDnsmasq:     true,
+			expectedSecrets:     []string{"metal3-image-server-tls"},
+			expectedCerts:       true,
+			expectedImageServer: "[fd2e:6f44:5dd8:b856::3]:6180",
+		},
+		{
+			name: "DisabledIPv6",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:            "fd2e:6f44:5dd8:b856::3",
+				ProvisioningNetworkCIDR:   "fd2e:6f44:5dd8:b856::/64",
+				ProvisioningOSDownloadURL: osDownloadURL,
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkDisabled,
+			},
+			expectedImageServer: "[fd2e:6f44:5dd8:b856::3]:6180",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			generation := env.applyProvisioning(t, tc.spec)
+			prov := env.waitForHealthyGeneration(t, generation)
+
+			deployment := &appsv1.Deployment{}
+			if !env.exists(t, deployment, "metal3") {
+				t.Fatal("metal3 deployment not found")
+			}
+			assert.Equal(t, prov.Status.RolloutHash, deployment.Annotations[provisioning.RolloutHashAnnotation])
+			owner := metav1.GetControllerOf(deployment)
+			if assert.NotNil(t, owner, "metal3 deployment has no owner") {
+				assert.Equal(t, prov.UID, owner.UID)
+			}
+
+			assert.Equal(t, tc.expectedDnsmasq, env.exists(t, &appsv1.DaemonSet{}, "metal3-dnsmasq"), "dnsmasq daemonset")
+			assert.Equal(t, tc.expectedService, env.exists(t, &corev1.Service{}, provisioning.IronicAPIProxyName), "ironic API service")
+
+			secrets := append([]string{"metal3-mariadb-password", "metal3-ironic-password", "metal3-ironic-inspector-password"},
+				tc.expectedSecrets...)
+			for _, name := range secrets {
+				assert.True(t, env.exists(t, &corev1.Secret{}, name), "secret %s not found", name)
+			}
+
+			assert.Equal(t, tc.expectedDHCPRange, prov.Status.DHCPRange)
+			assert.Equal(t, tc.spec, prov.Status.LastSuccessfulConfiguration.Spec)
+			assert.NotEmpty(t, prov.Status.ImageServer.HTTP.DeployKernel, "image server URLs not published")
+			if tc.expectedImageServer != "" {
+				assert.Contains(t, prov.Status.ImageServer.HTTP.DeployKernel, "http://"+tc.expectedImageServer+"/")
+			}
+			certs := findProvisioningCondition(&prov.Status, certificatesValidCondition)
+			if tc.expectedCerts && assert.NotNil(t, certs, "certificates not checked") {
+				assert.Equal(t, operatorv1.ConditionTrue, certs.Status)
+			}
+		})
+	}
+
+	t.Run("InvalidConfiguration", func(t *testing.T) {
+		env.applyProvisioning(t, metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:     "eth0",
+			ProvisioningIP:            "172.30.21.3",
+			ProvisioningNetworkCIDR:   "172.30.20.0/24",
+			ProvisioningOSDownloadURL: osDownloadURL,
+			ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+		})
+
+		var degraded *osconfigv1.ClusterOperatorStatusCondition
+		err := wait.PollImmediate(integrationInterval, integrationTimeout, func() (bool, error) {
+			co, err := env.osClient.ConfigV1().ClusterOperators().Get(context.Background(), clusterOperatorName, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			for i := range co.Status.Conditions {
+				if co.Status.Conditions[i].Type == osconfigv1.OperatorDegraded {
+					degraded = &co.Status.Conditions[i]
+				}
+			}
+			return degraded != nil && degraded.Status == osconfigv1.ConditionTrue, nil
+		})
+		if err != nil {
+			t.Fatalf("ClusterOperator not degraded: %v, last condition: %+v", err, degraded)
+		}
+		assert.Equal(t, string(ReasonInvalidConfiguration), degraded.Reason)
+
+		// The operands of the last valid configuration keep running
+		assert.True(t, env.exists(t, &appsv1.Deployment{}, "metal3"), "metal3 deployment removed")
+	})
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// noProvisioningNodesRequeueAfter is how often nodes are listed again
+	// when none of them can run the metal3 pods
+	noProvisioningNodesRequeueAfter = time.Minute
+	// interfaceCheckRetry is how long after it failed the check runs
+	// again on a node, as the interface may be configured at any time
+	interfaceCheckRetry = time.Minute
+)
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;delete
+
+// interfaceCheckResult is the outcome of the provisioning interface
+// pre-flight check on the nodes the metal3 pods may run on
+type interfaceCheckResult struct {
+	// missing are the nodes on which the interface was not found
+	missing []string
+	// pending is true while the check has not completed on every node
+	pending bool
+	// recheck is how soon the failed checks run again
+	recheck time.Duration
+}
+
+func (res interfaceCheckResult) message(iface string) string {
+	return fmt.Sprintf("provisioning interface %q not found on nodes: %s", iface, strings.Join(res.missing, ", "))
+}
+
+// listProvisioningNodes returns the nodes the metal3 pods may run on
+func (r *ProvisioningReconciler) listProvisioningNodes(spec *metal3iov1alpha1.ProvisioningSpec) ([]corev1.Node, error) {
+	selector := labels.SelectorFromSet(provisioning.GetMetal3NodeSelector(spec))
+	nodes, err := r.KubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	return nodes.Items, nil
+}
+
+// checkProvisioningInterface verifies that the ProvisioningInterface
+// exists on every node the metal3 pods may run on, by running a short lived pod on
+// each of them. Successful checks are only run again when the interface
+// changes, so that the check does not repeat on every reconcile. Failed
+// checks are removed once older than interfaceCheckRetry, so that an
+// interface configured on the node afterwards is noticed.
+func (r *ProvisioningReconciler) checkProvisioningInterface(prov *metal3iov1alpha1.Provisioning, images *provisioning.Images, now time.Time) (interfaceCheckResult, error) {
+	result := interfaceCheckResult{}
+	if prov.Spec.ProvisioningInterface == "" {
+		// Not used when the provisioning network is Disabled
+		return result, nil
+	}
+
+	ctx := context.Background()
+	nodes, err := r.listProvisioningNodes(&prov.Spec)
+	if err != nil {
+		return result, err
+	}
+
+	// The check pods are read from the cache, rather than with one request
+	// per node
+	checkPods := &corev1.PodList{}
+	if err := r.Client.List(ctx, checkPods, client.InNamespace(ComponentNamespace),
+		client.MatchingFields{provisioningOwnerField: prov.Name}); err != nil {
+		return result, err
+	}
+	existing := map[string]*corev1.Pod{}
+	for i := range checkPods.Items {
+		existing[checkPods.Items[i].Name] = &checkPods.Items[i]
+	}
+
+	pods := r.KubeClient.CoreV1().Pods(ComponentNamespace)
+	for _, node := range nodes {
+		pod, found := existing[provisioning.InterfaceCheckPodName(node.Name)]
+		if found && pod.Annotations[provisioning.InterfaceCheckInterfaceAnnotation] != prov.Spec.ProvisioningInterface {
+			// Stale result for a previous interface, check again
+			if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return result, err
+			}
+			result.pending = true
+			continue
+		}
+		if !found {
+			pod = provisioning.NewInterfaceCheckPod(ComponentNamespace, node.Name, images, &prov.Spec)
+			if err := controllerutil.SetControllerReference(prov, pod, r.Scheme); err != nil {
+				return result, err
+			}
+			// The cache may not have seen a pod created by a previous reconcile yet
+			if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+				return result, err
+			}
+			result.pending = true
+			continue
+		}
+
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+		case corev1.PodFailed:
+			if remaining := checkFinishedAt(pod).Add(interfaceCheckRetry).Sub(now); remaining > 0 {
+				result.missing = append(result.missing, node.Name)
+				result.recheck = soonestRequeue(result.recheck, remaining)
+				continue
+			}
+			if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return result, err
+			}
+			result.pending = true
+		default:
+			result.pending = true
+		}
+	}
+	sort.Strings(result.missing)
+	return result, nil
+}
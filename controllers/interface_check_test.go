@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newMasterNode(name string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"node-role.kubernetes.io/master": ""},
+		},
+	}
+}
+
+func newInterfaceCheckPod(nodeName string, iface string, phase corev1.PodPhase, finishedAt time.Time) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              provisioning.InterfaceCheckPodName(nodeName),
+			Namespace:         ComponentNamespace,
+			Annotations:       map[string]string{provisioning.InterfaceCheckInterfaceAnnotation: iface},
+			CreationTimestamp: metav1.NewTime(finishedAt),
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestCheckProvisioningInterface(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec:       metal3iov1alpha1.ProvisioningSpec{ProvisioningInterface: "eth1"},
+	}
+	worker := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}}
+	now := time.Now()
+
+	tCases := []struct {
+		name            string
+		objects         []runtime.Object
+		expectedMissing []string
+		expectedPending bool
+		expectedRecheck bool
+		expectedPods    int
+	}{
+		{
+			name:            "ChecksCreated",
+			objects:         []runtime.Object{newMasterNode("master-0"), newMasterNode("master-1"), worker},
+			expectedPending: true,
+			expectedPods:    2,
+		},
+		{
+			name: "InterfaceFound",
+			objects: []runtime.Object{newMasterNode("master-0"), newMasterNode("master-1"),
+				newInterfaceCheckPod("master-0", "eth1", corev1.PodSucceeded, now),
+				newInterfaceCheckPod("master-1", "eth1", corev1.PodSucceeded, now)},
+			expectedPods: 2,
+		},
+		{
+			name: "InterfaceMissing",
+			objects: []runtime.Object{newMasterNode("master-0"), newMasterNode("master-1"), newMasterNode("master-2"),
+				newInterfaceCheckPod("master-0", "eth1", corev1.PodSucceeded, now),
+				newInterfaceCheckPod("master-1", "eth1", corev1.PodFailed, now),
+				newInterfaceCheckPod("master-2", "eth1", corev1.PodRunning, now)},
+			expectedMissing: []string{"master-1"},
+			expectedPending: true,
+			expectedRecheck: true,
+			expectedPods:    3,
+		},
+		{
+			name: "FailedCheckRetried",
+			objects: []runtime.Object{newMasterNode("master-0"),
+				newInterfaceCheckPod("master-0", "eth1", corev1.PodFailed, now.Add(-interfaceCheckRetry))},
+			expectedPending: true,
+			expectedPods:    0,
+		},
+		{
+			name: "StaleCheck",
+			objects: []runtime.Object{newMasterNode("master-0"),
+				newInterfaceCheckPod("master-0", "eth0", corev1.PodFailed, now)},
+			expectedPending: true,
+			expectedPods:    0,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(tc.objects...)
+			// The check pods are read from the cache
+			reconciler.Client = fakeclient.NewFakeClientWithScheme(setUpSchemeForReconciler(), append([]runtime.Object{prov}, tc.objects...)...)
+
+			result, err := reconciler.checkProvisioningInterface(prov, &provisioning.Images{}, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedMissing, result.missing)
+			assert.Equal(t, tc.expectedPending, result.pending)
+			assert.Equal(t, tc.expectedRecheck, result.recheck > 0 && result.recheck <= interfaceCheckRetry, "%v", result.recheck)
+
+			pods, err := reconciler.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, pods.Items, tc.expectedPods)
+		})
+	}
+}
+
+func TestCheckProvisioningInterfaceDisabled(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec:       metal3iov1alpha1.ProvisioningSpec{ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkDisabled},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newMasterNode("master-0"))
+
+	result, err := reconciler.checkProvisioningInterface(prov, &provisioning.Images{}, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, interfaceCheckResult{}, result)
+}
+
+func TestCheckProvisioningInterfaceOnWorkers(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface: "eth1",
+			ControlPlaneOnly:      pointer.BoolPtr(false),
+			NodeSelector:          map[string]string{"metal3.io/provisioning": "true"},
+		},
+	}
+	worker := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "worker-0",
+		Labels: map[string]string{"metal3.io/provisioning": "true"},
+	}}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newMasterNode("master-0"), worker,
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker-1"}})
+
+	nodes, err := reconciler.listProvisioningNodes(&prov.Spec)
+	assert.NoError(t, err)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "worker-0", nodes[0].Name)
+	}
+
+	result, err := reconciler.checkProvisioningInterface(prov, &provisioning.Images{}, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, result.pending)
+	_, err = reconciler.KubeClient.CoreV1().Pods(ComponentNamespace).Get(context.Background(),
+		provisioning.InterfaceCheckPodName("worker-0"), metav1.GetOptions{})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// provisioningIPPoolCondition reports whether every node that can
+	// run the metal3 pods has an address of the ProvisioningIPPool
+	provisioningIPPoolCondition = "ProvisioningIPPool"
+	reasonIPsAllocated          = "IPsAllocated"
+	reasonPoolExhausted         = "PoolExhausted"
+)
+
+// provisioningIPAllocation is the outcome of allocating the addresses of
+// the ProvisioningIPPool to the nodes
+type provisioningIPAllocation struct {
+	allocations []metal3iov1alpha1.NodeProvisioningIP
+	unallocated []string
+}
+
+// syncProvisioningIPs allocates an address of the ProvisioningIPPool to
+// each node that can run the metal3 pods and publishes the allocations
+// to the static IP containers, or removes them when no pool is
+// configured. Every node gets an address, not only the ones running a
+// metal3 pod, so that pods moving to another node find theirs ready.
+func (r *ProvisioningReconciler) syncProvisioningIPs(prov *metal3iov1alpha1.Provisioning, spec *metal3iov1alpha1.ProvisioningSpec, nodes []corev1.Node) (provisioningIPAllocation, error) {
+	if !provisioning.HasProvisioningIPPool(spec) {
+		return provisioningIPAllocation{}, provisioning.DeleteProvisioningIPsConfigMap(r.KubeClient.CoreV1(), ComponentNamespace)
+	}
+
+	nodeNames := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	allocations, unallocated := provisioning.AllocateProvisioningIPs(spec.ProvisioningIPPool, nodeNames, prov.Status.ProvisioningIPs)
+	configMap := provisioning.NewProvisioningIPsConfigMap(ComponentNamespace, spec, allocations)
+	if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+		return provisioningIPAllocation{}, err
+	}
+	if err := provisioning.ApplyProvisioningIPsConfigMap(r.KubeClient.CoreV1(), configMap); err != nil {
+		return provisioningIPAllocation{}, err
+	}
+	return provisioningIPAllocation{allocations: allocations, unallocated: unallocated}, nil
+}
+
+// setProvisioningIPs records the allocated addresses in the status, and
+// whether the pool was large enough for all the nodes
+func setProvisioningIPs(status *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec, allocation provisioningIPAllocation) {
+	status.ProvisioningIPs = allocation.allocations
+	if !provisioning.HasProvisioningIPPool(spec) {
+		removeProvisioningCondition(status, provisioningIPPoolCondition)
+		return
+	}
+	if len(allocation.unallocated) > 0 {
+		setProvisioningCondition(status, provisioningIPPoolCondition, operatorv1.ConditionFalse, reasonPoolExhausted,
+			fmt.Sprintf("the %d addresses of the ProvisioningIPPool are all allocated, no address for nodes: %s",
+				len(spec.ProvisioningIPPool), strings.Join(allocation.unallocated, ", ")))
+		return
+	}
+	setProvisioningCondition(status, provisioningIPPoolCondition, operatorv1.ConditionTrue, reasonIPsAllocated, "")
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestSyncProvisioningIPs(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningNetworkCIDR: "172.30.20.0/24",
+			HighAvailability:        &metal3iov1alpha1.HighAvailability{Replicas: 2},
+			ProvisioningIPPool:      []string{"172.30.20.4", "172.30.20.5"},
+		},
+		Status: metal3iov1alpha1.ProvisioningStatus{
+			ProvisioningIPs: []metal3iov1alpha1.NodeProvisioningIP{{Node: "master-1", IP: "172.30.20.4"}},
+		},
+	}
+	nodes := newZonedNodes("", "", "")
+
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	kubeClient := fakekube.NewSimpleClientset()
+	reconciler.KubeClient = kubeClient
+
+	allocation, err := reconciler.syncProvisioningIPs(prov, &prov.Spec, nodes)
+	assert.NoError(t, err)
+	assert.Equal(t, []metal3iov1alpha1.NodeProvisioningIP{
+		{Node: "master-0", IP: "172.30.20.5"},
+		{Node: "master-1", IP: "172.30.20.4"},
+	}, allocation.allocations)
+	assert.Equal(t, []string{"master-2"}, allocation.unallocated)
+
+	configMap, err := kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(), provisioning.ProvisioningIPsConfigMapName, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"master-0": "172.30.20.5/24", "master-1": "172.30.20.4/24"}, configMap.Data)
+	}
+
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	setProvisioningIPs(status, &prov.Spec, allocation)
+	assert.Equal(t, allocation.allocations, status.ProvisioningIPs)
+	if assert.Len(t, status.Conditions, 1) {
+		assert.Equal(t, provisioningIPPoolCondition, status.Conditions[0].Type)
+		assert.Equal(t, operatorv1.ConditionFalse, status.Conditions[0].Status)
+		assert.Equal(t, reasonPoolExhausted, status.Conditions[0].Reason)
+	}
+
+	// Removing the pool removes the allocations
+	prov.Spec.ProvisioningIPPool = nil
+	allocation, err = reconciler.syncProvisioningIPs(prov, &prov.Spec, nodes)
+	assert.NoError(t, err)
+	_, err = kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(), provisioning.ProvisioningIPsConfigMapName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	setProvisioningIPs(status, &prov.Spec, allocation)
+	assert.Empty(t, status.ProvisioningIPs)
+	assert.Empty(t, status.Conditions)
+}
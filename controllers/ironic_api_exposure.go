@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=route.openshift.io,resources=routes/custom-host,verbs=create
+
+var routeGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}
+
+// ensureIronicAPIExposure creates the Service, and optionally the Route,
+// exposing the Ironic API, and removes them once they are not requested
+// anymore.
+func (r *ProvisioningReconciler) ensureIronicAPIExposure(prov *metal3iov1alpha1.Provisioning) error {
+	exposure := prov.Spec.IronicAPIExposure
+	if exposure == nil {
+		if err := r.deleteIronicAPIRoute(); err != nil {
+			return err
+		}
+		return r.deleteIronicAPIProxyService()
+	}
+
+	service := provisioning.NewIronicAPIProxyService(ComponentNamespace, &prov.Spec)
+	if err := controllerutil.SetControllerReference(prov, service, r.Scheme); err != nil {
+		return err
+	}
+	if err := provisioning.ApplyIronicAPIProxyService(r.KubeClient.CoreV1(), service); err != nil {
+		return err
+	}
+
+	if !exposure.CreateRoute {
+		return r.deleteIronicAPIRoute()
+	}
+	route := provisioning.NewIronicAPIRoute(ComponentNamespace)
+	if err := controllerutil.SetControllerReference(prov, route, r.Scheme); err != nil {
+		return err
+	}
+	// The Route spec is fixed, so it only ever needs to be created
+	err := r.Client.Create(context.Background(), route)
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *ProvisioningReconciler) deleteIronicAPIRoute() error {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(routeGVK)
+	err := r.Client.Get(context.Background(), client.ObjectKey{
+		Namespace: ComponentNamespace,
+		Name:      provisioning.IronicAPIProxyName,
+	}, route)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		// Nothing to remove, including when the Route API is not served
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = r.Client.Delete(context.Background(), route)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *ProvisioningReconciler) deleteIronicAPIProxyService() error {
+	err := r.KubeClient.CoreV1().Services(ComponentNamespace).Delete(context.Background(),
+		provisioning.IronicAPIProxyName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
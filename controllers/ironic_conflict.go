@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// ironicConflictRequeueAfter is how often the check for another
+	// Ironic runs again once one was found, as it may be stopped at any
+	// time
+	ironicConflictRequeueAfter = time.Minute
+	// ironicConflictRecheck is how often the check runs again once it
+	// passed, as a DHCP server may be started on the provisioning network
+	// at any time
+	ironicConflictRecheck = 10 * time.Minute
+)
+
+// ironicConflictResult is the outcome of the check for another Ironic on
+// the nodes the metal3 pods may run on, or another DHCP server on the
+// provisioning network
+type ironicConflictResult struct {
+	// conflicts are the messages of the failed checks, by node
+	conflicts map[string]string
+	// pending is true while the check has not completed on every node
+	pending bool
+	// deployed is true when metal3 is already deployed, and only the
+	// DHCP servers are checked
+	deployed bool
+	// recheck is how soon the check has to run again
+	recheck time.Duration
+}
+
+func (res ironicConflictResult) message() string {
+	nodes := make([]string, 0, len(res.conflicts))
+	for node := range res.conflicts {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	messages := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		messages = append(messages, fmt.Sprintf("%s: %s", node, res.conflicts[node]))
+	}
+	action := "deploy"
+	if res.deployed {
+		action = "update"
+	}
+	return fmt.Sprintf("refusing to %s metal3 alongside another Ironic or DHCP server; %s", action, strings.Join(messages, "; "))
+}
+
+// checkIronicConflicts verifies that no other Ironic runs on the nodes
+// the metal3 pods may run on nor at the ProvisioningIP, and that no other
+// DHCP server answers on the provisioning network, as they would answer
+// the same DHCP and PXE requests. Before the metal3 Deployment is created,
+// nodes already running metal3 pods are not checked, as their ports are
+// bound by the operands. Once it is created, only the DHCP servers are
+// checked. Completed checks are removed once they are older than the
+// time they are rechecked after, so that they run again.
+func (r *ProvisioningReconciler) checkIronicConflicts(prov *metal3iov1alpha1.Provisioning, images *provisioning.Images, nodes []corev1.Node, deploymentName string, now time.Time) (ironicConflictResult, error) {
+	result := ironicConflictResult{conflicts: map[string]string{}}
+	ctx := context.Background()
+
+	_, err := r.KubeClient.AppsV1().Deployments(ComponentNamespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return result, err
+	}
+	result.deployed = err == nil
+
+	operandNodes := map[string]bool{}
+	if !result.deployed {
+		operands, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(ctx, metav1.ListOptions{LabelSelector: metal3PodSelector})
+		if err != nil {
+			return result, err
+		}
+		for _, pod := range operands.Items {
+			operandNodes[pod.Spec.NodeName] = true
+		}
+	}
+	if result.deployed && !provisioning.ProbesDHCPServers(&prov.Spec) {
+		// Conflicts on the ports are found from the failures of the
+		// metal3 pods
+		return result, nil
+	}
+
+	checkPods := &corev1.PodList{}
+	if err := r.Client.List(ctx, checkPods, client.InNamespace(ComponentNamespace),
+		client.MatchingFields{provisioningOwnerField: prov.Name}); err != nil {
+		return result, err
+	}
+	existing := map[string]*corev1.Pod{}
+	for i := range checkPods.Items {
+		existing[checkPods.Items[i].Name] = &checkPods.Items[i]
+	}
+
+	check := provisioning.GetIronicConflictCheck(&prov.Spec, result.deployed)
+	pods := r.KubeClient.CoreV1().Pods(ComponentNamespace)
+	for _, node := range nodes {
+		if operandNodes[node.Name] {
+			continue
+		}
+		pod, found := existing[provisioning.IronicConflictCheckPodName(node.Name)]
+		if found && pod.Annotations[provisioning.IronicConflictCheckAnnotation] != check {
+			// Stale result for a previous configuration, check again
+			if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				return result, err
+			}
+			result.pending = true
+			continue
+		}
+		if !found {
+			pod = provisioning.NewIronicConflictCheckPod(ComponentNamespace, node.Name, images, &prov.Spec, result.deployed)
+			if err := controllerutil.SetControllerReference(prov, pod, r.Scheme); err != nil {
+				return result, err
+			}
+			// The cache may not have seen a pod created by a previous reconcile yet
+			if _, err := pods.Create(ctx, pod, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+				return result, err
+			}
+			result.pending = true
+			continue
+		}
+
+		var recheckAfter time.Duration
+		switch pod.Status.Phase {
+		case corev1.PodSucceeded:
+			recheckAfter = ironicConflictRecheck
+		case corev1.PodFailed:
+			recheckAfter = ironicConflictRequeueAfter
+		default:
+			result.pending = true
+			continue
+		}
+		remaining := checkFinishedAt(pod).Add(recheckAfter).Sub(now)
+		if remaining > 0 {
+			if pod.Status.Phase == corev1.PodFailed {
+				result.conflicts[node.Name] = terminationMessage(pod)
+			}
+			result.recheck = soonestRequeue(result.recheck, remaining)
+			continue
+		}
+		if err := pods.Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return result, err
+		}
+		result.pending = true
+	}
+	return result, nil
+}
+
+// checkFinishedAt returns when the check pod completed, or when it was
+// created if unknown
+func checkFinishedAt(pod *corev1.Pod) time.Time {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && !terminated.FinishedAt.IsZero() {
+			return terminated.FinishedAt.Time
+		}
+	}
+	return pod.CreationTimestamp.Time
+}
+
+// terminationMessage returns the termination message of the first
+// terminated container of the pod
+func terminationMessage(pod *corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.Message != "" {
+			return strings.TrimSpace(terminated.Message)
+		}
+	}
+	return "another Ironic or DHCP server is running"
+}
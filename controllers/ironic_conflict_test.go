@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newIronicConflictCheckPod(nodeName string, spec *metal3iov1alpha1.ProvisioningSpec, deployed bool, phase corev1.PodPhase, message string, finishedAt time.Time) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        provisioning.IronicConflictCheckPodName(nodeName),
+			Namespace:   ComponentNamespace,
+			Annotations: map[string]string{provisioning.IronicConflictCheckAnnotation: provisioning.GetIronicConflictCheck(spec, deployed)},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	if phase == corev1.PodSucceeded || phase == corev1.PodFailed {
+		terminated := &corev1.ContainerStateTerminated{Message: message, FinishedAt: metav1.NewTime(finishedAt)}
+		if phase == corev1.PodFailed {
+			terminated.ExitCode = 1
+		}
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: terminated}}}
+	}
+	return pod
+}
+
+func TestCheckIronicConflicts(t *testing.T) {
+	now := time.Date(2020, 11, 2, 10, 0, 0, 0, time.UTC)
+	recent := now.Add(-30 * time.Second)
+	old := now.Add(-time.Hour)
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:   "eth1",
+			ProvisioningIP:          "172.30.20.3",
+			ProvisioningNetworkCIDR: "172.30.20.0/24",
+			ProvisioningNetwork:     metal3iov1alpha1.ProvisioningNetworkManaged,
+		},
+	}
+	otherSpec := prov.Spec.DeepCopy()
+	otherSpec.ProvisioningIP = "172.30.20.4"
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "metal3", Namespace: ComponentNamespace}}
+	operand := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "metal3-dnsmasq-abcde", Namespace: ComponentNamespace, Labels: map[string]string{"k8s-app": "metal3-dnsmasq"}},
+		Spec:       corev1.PodSpec{NodeName: "master-1"},
+	}
+
+	tCases := []struct {
+		name              string
+		network           metal3iov1alpha1.ProvisioningNetwork
+		objects           []runtime.Object
+		expectedConflicts map[string]string
+		expectedPending   bool
+		expectedDeployed  bool
+		expectedRecheck   time.Duration
+		expectedPods      int
+	}{
+		{
+			name:            "ChecksCreated",
+			objects:         []runtime.Object{},
+			expectedPending: true,
+			expectedPods:    2,
+		},
+		{
+			name: "NoConflict",
+			objects: []runtime.Object{
+				newIronicConflictCheckPod("master-0", &prov.Spec, false, corev1.PodSucceeded, "", recent),
+				newIronicConflictCheckPod("master-1", &prov.Spec, false, corev1.PodSucceeded, "", recent),
+			},
+			expectedRecheck: ironicConflictRecheck - 30*time.Second,
+			expectedPods:    2,
+		},
+		{
+			name: "ConflictFound",
+			objects: []runtime.Object{
+				newIronicConflictCheckPod("master-0", &prov.Spec, false, corev1.PodFailed, "another Ironic or DHCP server is running: tcp/6385 dhcp/172.30.20.1\n", recent),
+				newIronicConflictCheckPod("master-1", &prov.Spec, false, corev1.PodSucceeded, "", recent),
+			},
+			expectedConflicts: map[string]string{"master-0": "another Ironic or DHCP server is running: tcp/6385 dhcp/172.30.20.1"},
+			expectedRecheck:   ironicConflictRequeueAfter - 30*time.Second,
+			expectedPods:      2,
+		},
+		{
+			name: "Rechecked",
+			objects: []runtime.Object{
+				newIronicConflictCheckPod("master-0", &prov.Spec, false, corev1.PodFailed, "another Ironic or DHCP server is running: tcp/6385", old),
+				newIronicConflictCheckPod("master-1", &prov.Spec, false, corev1.PodSucceeded, "", old),
+			},
+			expectedPending: true,
+			expectedPods:    0,
+		},
+		{
+			name: "StaleCheck",
+			objects: []runtime.Object{
+				newIronicConflictCheckPod("master-0", otherSpec, false, corev1.PodFailed, "another Ironic or DHCP server is running: tcp/6385", recent),
+				newIronicConflictCheckPod("master-1", &prov.Spec, false, corev1.PodSucceeded, "", recent),
+			},
+			expectedPending: true,
+			expectedRecheck: ironicConflictRecheck - 30*time.Second,
+			expectedPods:    1,
+		},
+		{
+			name:            "NodeRunningOperands",
+			objects:         []runtime.Object{operand, newIronicConflictCheckPod("master-0", &prov.Spec, false, corev1.PodSucceeded, "", recent)},
+			expectedRecheck: ironicConflictRecheck - 30*time.Second,
+			expectedPods:    2,
+		},
+		{
+			name:             "AlreadyDeployed",
+			objects:          []runtime.Object{deployment, operand},
+			expectedPending:  true,
+			expectedDeployed: true,
+			expectedPods:     3,
+		},
+		{
+			name: "DHCPServerFoundOnceDeployed",
+			objects: []runtime.Object{
+				deployment,
+				newIronicConflictCheckPod("master-0", &prov.Spec, true, corev1.PodFailed, "another Ironic or DHCP server is running: dhcp/172.30.20.1", recent),
+				newIronicConflictCheckPod("master-1", &prov.Spec, true, corev1.PodSucceeded, "", recent),
+			},
+			expectedConflicts: map[string]string{"master-0": "another Ironic or DHCP server is running: dhcp/172.30.20.1"},
+			expectedDeployed:  true,
+			expectedRecheck:   ironicConflictRequeueAfter - 30*time.Second,
+			expectedPods:      2,
+		},
+		{
+			name:             "AlreadyDeployedWithoutDHCP",
+			network:          metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			objects:          []runtime.Object{deployment},
+			expectedDeployed: true,
+			expectedPods:     0,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := prov.DeepCopy()
+			if tc.network != "" {
+				prov.Spec.ProvisioningNetwork = tc.network
+			}
+			nodes := []runtime.Object{newMasterNode("master-0"), newMasterNode("master-1")}
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(append(nodes, tc.objects...)...)
+			// The check pods are read from the cache
+			reconciler.Client = fakeclient.NewFakeClientWithScheme(setUpSchemeForReconciler(), append([]runtime.Object{prov}, tc.objects...)...)
+
+			provisioningNodes, err := reconciler.listProvisioningNodes(&prov.Spec)
+			assert.NoError(t, err)
+			result, err := reconciler.checkIronicConflicts(prov, &provisioning.Images{}, provisioningNodes, "metal3", now)
+			assert.NoError(t, err)
+			if tc.expectedConflicts == nil {
+				assert.Empty(t, result.conflicts)
+			} else {
+				assert.Equal(t, tc.expectedConflicts, result.conflicts)
+				assert.Contains(t, result.message(), "master-0: another Ironic or DHCP server is running")
+			}
+			assert.Equal(t, tc.expectedPending, result.pending)
+			assert.Equal(t, tc.expectedDeployed, result.deployed)
+			assert.Equal(t, tc.expectedRecheck, result.recheck)
+
+			pods, err := reconciler.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Len(t, pods.Items, tc.expectedPods)
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=pods,verbs=update
+
+const (
+	// ironicDBRecoveredCondition records the last recovery of the Ironic
+	// database from corruption
+	ironicDBRecoveredCondition = "IronicDatabaseRecovered"
+	reasonDBRecoveryInProgress = "RecoveryInProgress"
+	reasonDBReinitialized      = "DatabaseReinitialized"
+
+	// ironicDBCorruptionRestarts is how many times a container must have
+	// failed on a corrupted database before it is recovered, so that a
+	// database that repairs itself on restart is left alone
+	ironicDBCorruptionRestarts = 3
+
+	// ironicDBRecoveryRecheck is how often a recovering pod is looked at,
+	// as the request reaches its containers with the next volume sync
+	ironicDBRecoveryRecheck = 30 * time.Second
+
+	// ironicDBRecoveryIDFormat names the recoveries after the time they
+	// were requested
+	ironicDBRecoveryIDFormat = "20060102T150405Z"
+)
+
+// findIronicDBCorruption returns the container of the pod that keeps
+// failing on a corrupted Ironic database, or nil when there is none
+func findIronicDBCorruption(pod *corev1.Pod) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		status := &pod.Status.ContainerStatuses[i]
+		terminated := status.LastTerminationState.Terminated
+		if !provisioning.IsIronicDBContainer(status.Name) || status.RestartCount < ironicDBCorruptionRestarts ||
+			status.State.Running != nil || terminated == nil {
+			continue
+		}
+		if provisioning.IsIronicDBCorruption(terminated.Message) {
+			return status
+		}
+	}
+	return nil
+}
+
+// ironicDBContainerReady returns true once the containers of the pod that
+// may hold the Ironic database run again
+func ironicDBContainerReady(pod *corev1.Pod) bool {
+	found := false
+	for _, status := range pod.Status.ContainerStatuses {
+		if !provisioning.IsIronicDBContainer(status.Name) {
+			continue
+		}
+		if !status.Ready {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// recoverIronicDB quarantines the database of the metal3 pods that keep
+// failing on a corrupted one. Their containers move the database aside
+// and start on a new one once the pod is annotated, after which the
+// BareMetalHosts are annotated too, so that the baremetal-operator
+// registers them in the new database. The condition reports the last
+// recovery. A pod is recovered once, the next one starts with a new
+// database anyway. Nothing is recovered unless IronicDBRecovery is set.
+func (r *ProvisioningReconciler) recoverIronicDB(prov *metal3iov1alpha1.Provisioning, status *metal3iov1alpha1.ProvisioningStatus, now time.Time) (time.Duration, error) {
+	if !prov.Spec.IronicDBRecovery {
+		removeProvisioningCondition(status, ironicDBRecoveredCondition)
+		return 0, nil
+	}
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: metal3DeploymentPodSelector})
+	if err != nil {
+		return 0, err
+	}
+	recheck := time.Duration(0)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		id := pod.Annotations[provisioning.IronicDBRecoveryAnnotation]
+		if id == "" {
+			corrupted := findIronicDBCorruption(pod)
+			if corrupted == nil {
+				continue
+			}
+			if id, err = r.requestIronicDBRecovery(prov, pod, corrupted, now); err != nil {
+				return 0, err
+			}
+			setProvisioningCondition(status, ironicDBRecoveredCondition, operatorv1.ConditionFalse, reasonDBRecoveryInProgress,
+				fmt.Sprintf("the Ironic database of pod %s on node %s is corrupted, and is being quarantined as %s",
+					pod.Name, pod.Spec.NodeName, id))
+			recheck = ironicDBRecoveryRecheck
+			continue
+		}
+
+		if !ironicDBContainerReady(pod) {
+			recheck = ironicDBRecoveryRecheck
+			continue
+		}
+		reregistered, err := r.reregisterHosts(id)
+		if err != nil {
+			return 0, err
+		}
+		message := fmt.Sprintf("the corrupted Ironic database of pod %s was quarantined in %s/%s/%s on node %s and reinitialized",
+			pod.Name, provisioning.IronicDBQuarantineHostPath, pod.Labels["controller"], id, pod.Spec.NodeName)
+		if reregistered > 0 {
+			r.Log.Info("re-registering hosts in the new Ironic database", "pod", pod.Name, "recovery", id, "hosts", reregistered)
+			if r.EventRecorder != nil {
+				r.EventRecorder.Eventf(prov, corev1.EventTypeNormal, "IronicDatabaseRecovered",
+					"%s, %d hosts are being registered again", message, reregistered)
+			}
+		}
+		setProvisioningCondition(status, ironicDBRecoveredCondition, operatorv1.ConditionTrue, reasonDBReinitialized, message)
+	}
+	return recheck, nil
+}
+
+// requestIronicDBRecovery annotates the pod, so that the container
+// holding the database quarantines it when it next starts, and returns
+// the name of the recovery
+func (r *ProvisioningReconciler) requestIronicDBRecovery(prov *metal3iov1alpha1.Provisioning, pod *corev1.Pod, corrupted *corev1.ContainerStatus, now time.Time) (string, error) {
+	id := now.UTC().Format(ironicDBRecoveryIDFormat)
+	updated := pod.DeepCopy()
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	updated.Annotations[provisioning.IronicDBRecoveryAnnotation] = id
+	if _, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).Update(context.Background(), updated, metav1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+	r.Log.Info("quarantining the corrupted Ironic database", "pod", pod.Name, "container", corrupted.Name,
+		"restarts", corrupted.RestartCount, "recovery", id)
+	if r.EventRecorder != nil {
+		r.EventRecorder.Eventf(prov, corev1.EventTypeWarning, "IronicDatabaseCorrupted",
+			"container %s of pod %s keeps failing on a corrupted database, quarantining it as %s", corrupted.Name, pod.Name, id)
+	}
+	return id, nil
+}
+
+// reregisterHosts annotates the BareMetalHosts with the name of the
+// recovery, which has the baremetal-operator reconcile them and register
+// the nodes missing from the new database. It returns the number of
+// hosts annotated, which is zero once they all were.
+func (r *ProvisioningReconciler) reregisterHosts(id string) (int, error) {
+	hosts := newBareMetalHostList()
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(ComponentNamespace)); err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := range hosts.Items {
+		host := &hosts.Items[i]
+		annotations := host.GetAnnotations()
+		if annotations[provisioning.IronicDBRecoveryAnnotation] == id {
+			continue
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[provisioning.IronicDBRecoveryAnnotation] = id
+		host.SetAnnotations(annotations)
+		if err := r.Client.Update(context.Background(), host); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const innodbCorruption = "[ERROR] InnoDB: Database page corruption on disk or a failed file read of tablespace ironic/nodes"
+
+func newCorruptedMetal3Pod(restarts int32, message string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "metal3-abcde",
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{"k8s-app": "metal3", "controller": "metal3"},
+		},
+		Spec: corev1.PodSpec{NodeName: "master-0"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:         "metal3-mariadb",
+					RestartCount: restarts,
+					State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+					},
+					LastTerminationState: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: message},
+					},
+				},
+				{Name: "metal3-ironic-conductor", Ready: true, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+			},
+		},
+	}
+}
+
+func findCondition(status *metal3iov1alpha1.ProvisioningStatus, condType string) *operatorv1.OperatorCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestFindIronicDBCorruption(t *testing.T) {
+	tests := []struct {
+		name     string
+		pod      *corev1.Pod
+		expected string
+	}{
+		{
+			name:     "Corrupted",
+			pod:      newCorruptedMetal3Pod(3, innodbCorruption),
+			expected: "metal3-mariadb",
+		},
+		{
+			name: "TooFewRestarts",
+			pod:  newCorruptedMetal3Pod(2, innodbCorruption),
+		},
+		{
+			name: "OtherError",
+			pod:  newCorruptedMetal3Pod(5, "[ERROR] Can't start server: Bind on TCP/IP port: Address already in use"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			corrupted := findIronicDBCorruption(tc.pod)
+			if tc.expected == "" {
+				assert.Nil(t, corrupted)
+			} else if assert.NotNil(t, corrupted) {
+				assert.Equal(t, tc.expected, corrupted.Name)
+			}
+		})
+	}
+
+	// A container of another service logging the same error is not
+	// holding the database
+	pod := newCorruptedMetal3Pod(3, innodbCorruption)
+	pod.Status.ContainerStatuses[0].Name = "metal3-ironic-api"
+	assert.Nil(t, findIronicDBCorruption(pod))
+}
+
+func TestRecoverIronicDB(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec:       metal3iov1alpha1.ProvisioningSpec{IronicDBRecovery: true},
+	}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme,
+		newTestBareMetalHost(ComponentNamespace, "worker-0", "provisioned"),
+		newTestBareMetalHost(ComponentNamespace, "worker-1", "ready"),
+	)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newCorruptedMetal3Pod(4, innodbCorruption))
+	recorder := record.NewFakeRecorder(10)
+	reconciler.EventRecorder = recorder
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	pods := reconciler.KubeClient.CoreV1().Pods(ComponentNamespace)
+
+	// The pod is annotated, which its mariadb container reads on restart
+	recheck, err := reconciler.recoverIronicDB(prov, status, now)
+	assert.NoError(t, err)
+	assert.Equal(t, ironicDBRecoveryRecheck, recheck)
+	pod, err := pods.Get(context.Background(), "metal3-abcde", metav1.GetOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "20210601T120000Z", pod.Annotations[provisioning.IronicDBRecoveryAnnotation])
+	if cond := findCondition(status, ironicDBRecoveredCondition); assert.NotNil(t, cond) {
+		assert.Equal(t, operatorv1.ConditionFalse, cond.Status)
+		assert.Equal(t, reasonDBRecoveryInProgress, cond.Reason)
+	}
+	assert.Contains(t, <-recorder.Events, "IronicDatabaseCorrupted")
+
+	// Nothing more happens until the database runs again
+	recheck, err = reconciler.recoverIronicDB(prov, status, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, ironicDBRecoveryRecheck, recheck)
+	pod, _ = pods.Get(context.Background(), "metal3-abcde", metav1.GetOptions{})
+	assert.Equal(t, "20210601T120000Z", pod.Annotations[provisioning.IronicDBRecoveryAnnotation])
+
+	pod.Status.ContainerStatuses[0].Ready = true
+	pod.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	_, err = pods.UpdateStatus(context.Background(), pod, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	// The hosts are registered again once
+	recheck, err = reconciler.recoverIronicDB(prov, status, now.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	hosts := newBareMetalHostList()
+	assert.NoError(t, reconciler.Client.List(context.Background(), hosts))
+	for _, host := range hosts.Items {
+		assert.Equal(t, "20210601T120000Z", host.GetAnnotations()[provisioning.IronicDBRecoveryAnnotation], host.GetName())
+	}
+	if cond := findCondition(status, ironicDBRecoveredCondition); assert.NotNil(t, cond) {
+		assert.Equal(t, operatorv1.ConditionTrue, cond.Status)
+		assert.Equal(t, reasonDBReinitialized, cond.Reason)
+		assert.Equal(t, "the corrupted Ironic database of pod metal3-abcde was quarantined in "+
+			"/var/lib/metal3/ironic-db-quarantine/metal3/20210601T120000Z on node master-0 and reinitialized", cond.Message)
+	}
+	assert.Contains(t, <-recorder.Events, "2 hosts are being registered again")
+
+	count, err := reconciler.reregisterHosts("20210601T120000Z")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestRecoverIronicDBHealthy(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec:       metal3iov1alpha1.ProvisioningSpec{IronicDBRecovery: true},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	healthy := newCorruptedMetal3Pod(0, "")
+	healthy.Status.ContainerStatuses[0].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	reconciler.KubeClient = fakekube.NewSimpleClientset(healthy)
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+
+	recheck, err := reconciler.recoverIronicDB(prov, status, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Nil(t, findCondition(status, ironicDBRecoveredCondition))
+}
+
+func TestRecoverIronicDBDisabled(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newCorruptedMetal3Pod(4, innodbCorruption))
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	setProvisioningCondition(status, ironicDBRecoveredCondition, operatorv1.ConditionTrue, reasonDBReinitialized, "recovered")
+
+	recheck, err := reconciler.recoverIronicDB(prov, status, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Nil(t, findCondition(status, ironicDBRecoveredCondition))
+	pod, err := reconciler.KubeClient.CoreV1().Pods(ComponentNamespace).Get(context.Background(), "metal3-abcde", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, pod.Annotations[provisioning.IronicDBRecoveryAnnotation])
+}
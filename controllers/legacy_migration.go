@@ -0,0 +1,19 @@
+package controllers
+
+import (
+	"time"
+
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// legacyMigrationRequeueAfter is how often the takeover of the metal3
+// resources of the machine-api-operator is checked, as they are not owned
+// by the Provisioning resource and thus not watched
+const legacyMigrationRequeueAfter = 15 * time.Second
+
+// migrateLegacyMetal3 takes over the metal3 resources left by the
+// machine-api-operator. It returns false while the migration is in
+// progress.
+func (r *ProvisioningReconciler) migrateLegacyMetal3() (bool, error) {
+	return provisioning.MigrateLegacyMetal3(r.KubeClient.AppsV1(), r.KubeClient.CoreV1(), ComponentNamespace, time.Now())
+}
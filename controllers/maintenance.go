@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// maintenanceActiveCondition reports that the metal3 pods are scaled
+	// down by the Maintenance mode
+	maintenanceActiveCondition = "MaintenanceActive"
+	reasonMaintenanceRequested = "MaintenanceRequested"
+
+	maintenanceMessage = "metal3 is scaled down for maintenance"
+)
+
+// enterMaintenance scales the metal3 pods down and records it in the
+// status. The DHCP leases are stored outside of the dnsmasq pods, so the
+// DaemonSet is deleted, as are the conductors of the groups. The Ironic
+// database of the metal3 pod is lost, which only the idle hosts survive,
+// being registered again: the deployment or cleaning of the others would
+// be abandoned, so nothing is scaled down while some are busy and their
+// names are returned instead. Once in maintenance, the hosts cannot start
+// any operation anymore.
+func (r *ProvisioningReconciler) enterMaintenance(prov *metal3iov1alpha1.Provisioning) ([]string, error) {
+	if !isMaintenanceActive(&prov.Status) {
+		busyHosts, err := r.listBusyHosts()
+		if err != nil {
+			return nil, err
+		}
+		if len(busyHosts) > 0 {
+			return busyHosts, nil
+		}
+	}
+	if err := provisioning.ScaleDownMetal3Deployment(r.KubeClient.AppsV1(), ComponentNamespace); err != nil {
+		return nil, err
+	}
+	if err := provisioning.DeleteDnsmasqDaemonSet(r.KubeClient.AppsV1(), ComponentNamespace); err != nil {
+		return nil, err
+	}
+	// The conductors of the groups cannot run without the database of
+	// the metal3 pod
+	if err := provisioning.DeleteStaleConductorGroups(r.KubeClient.AppsV1(), ComponentNamespace, nil); err != nil {
+		return nil, err
+	}
+	newStatus := prov.Status.DeepCopy()
+	newStatus.ObservedGeneration = prov.Generation
+	setMaintenanceCondition(newStatus, true)
+	setProvisioningCondition(newStatus, provisioningReadyCondition, operatorv1.ConditionFalse, reasonScaledDown, maintenanceMessage)
+	return nil, r.updateProvisioningStatus(prov, newStatus)
+}
+
+// isMaintenanceActive returns whether the status records that the metal3
+// pods are scaled down for maintenance
+func isMaintenanceActive(status *metal3iov1alpha1.ProvisioningStatus) bool {
+	for _, cond := range status.Conditions {
+		if cond.Type == maintenanceActiveCondition {
+			return cond.Status == operatorv1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// setMaintenanceCondition records in the status whether the metal3 pods
+// are scaled down for maintenance
+func setMaintenanceCondition(status *metal3iov1alpha1.ProvisioningStatus, active bool) {
+	if !active {
+		removeProvisioningCondition(status, maintenanceActiveCondition)
+		return
+	}
+	setProvisioningCondition(status, maintenanceActiveCondition, operatorv1.ConditionTrue, reasonMaintenanceRequested, maintenanceMessage)
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestEnterMaintenance(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR, Generation: 4},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:   "eth0",
+			ProvisioningIP:          "172.30.20.3",
+			ProvisioningNetworkCIDR: "172.30.20.0/24",
+			ProvisioningDHCPRange:   "172.30.20.11, 172.30.20.101",
+			ProvisioningNetwork:     metal3iov1alpha1.ProvisioningNetworkManaged,
+			Maintenance:             true,
+		},
+	}
+	images := &provisioning.Images{}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		provisioning.NewMetal3Deployment(ComponentNamespace, images, &prov.Spec),
+		provisioning.NewDnsmasqDaemonSet(ComponentNamespace, images, &prov.Spec))
+
+	busyHosts, err := reconciler.enterMaintenance(prov)
+	assert.NoError(t, err)
+	assert.Empty(t, busyHosts)
+
+	deployment, err := reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).Get(context.Background(), "metal3", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), *deployment.Spec.Replicas)
+	_, err = reconciler.KubeClient.AppsV1().DaemonSets(ComponentNamespace).Get(context.Background(), "metal3-dnsmasq", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	updated := &metal3iov1alpha1.Provisioning{}
+	assert.NoError(t, reconciler.Client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, updated))
+	assert.Equal(t, int64(4), updated.Status.ObservedGeneration)
+	if assert.Len(t, updated.Status.Conditions, 2) {
+		assert.Equal(t, maintenanceActiveCondition, updated.Status.Conditions[0].Type)
+		assert.Equal(t, operatorv1.ConditionTrue, updated.Status.Conditions[0].Status)
+		assert.Equal(t, provisioningReadyCondition, updated.Status.Conditions[1].Type)
+		assert.Equal(t, operatorv1.ConditionFalse, updated.Status.Conditions[1].Status)
+		assert.Equal(t, reasonScaledDown, updated.Status.Conditions[1].Reason)
+	}
+
+	assert.True(t, isMaintenanceActive(&updated.Status))
+
+	setMaintenanceCondition(&updated.Status, false)
+	assert.Len(t, updated.Status.Conditions, 1)
+	assert.False(t, isMaintenanceActive(&updated.Status))
+}
+
+func TestEnterMaintenanceBusyHosts(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkDisabled,
+			Maintenance:         true,
+		},
+	}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov,
+		newTestBareMetalHost(ComponentNamespace, "worker-0", "provisioning"))
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		provisioning.NewMetal3Deployment(ComponentNamespace, &provisioning.Images{}, &prov.Spec))
+
+	// The deployment of the host would be abandoned
+	busyHosts, err := reconciler.enterMaintenance(prov)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"worker-0"}, busyHosts)
+	deployment, err := reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).Get(context.Background(), "metal3", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), *deployment.Spec.Replicas)
+
+	// Once in maintenance, the hosts stuck in their operations do not
+	// bring the pods back
+	setMaintenanceCondition(&prov.Status, true)
+	busyHosts, err = reconciler.enterMaintenance(prov)
+	assert.NoError(t, err)
+	assert.Empty(t, busyHosts)
+	deployment, err = reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).Get(context.Background(), "metal3", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), *deployment.Spec.Replicas)
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;create
+
+// ensureComponentNamespace creates ComponentNamespace when it is missing
+// and CreateNamespace is set. An existing namespace is left as it is, as
+// it belongs to whoever created it.
+func (r *ProvisioningReconciler) ensureComponentNamespace() error {
+	if !r.CreateNamespace {
+		return nil
+	}
+	namespaces := r.KubeClient.CoreV1().Namespaces()
+	_, err := namespaces.Get(context.Background(), ComponentNamespace, metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	_, err = namespaces.Create(context.Background(), provisioning.NewOperandNamespace(ComponentNamespace), metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	if err == nil {
+		r.Log.Info("created namespace", "namespace", ComponentNamespace)
+	}
+	return err
+}
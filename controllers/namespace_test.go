@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestEnsureComponentNamespace(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR}}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	kubeClient := fakekube.NewSimpleClientset()
+	reconciler.KubeClient = kubeClient
+
+	// Nothing is created unless requested
+	assert.NoError(t, reconciler.ensureComponentNamespace())
+	_, err := kubeClient.CoreV1().Namespaces().Get(context.Background(), ComponentNamespace, metav1.GetOptions{})
+	assert.Error(t, err)
+
+	reconciler.CreateNamespace = true
+	assert.NoError(t, reconciler.ensureComponentNamespace())
+	namespace, err := kubeClient.CoreV1().Namespaces().Get(context.Background(), ComponentNamespace, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "privileged", namespace.Labels["pod-security.kubernetes.io/enforce"])
+		assert.Equal(t, "true", namespace.Labels["openshift.io/cluster-monitoring"])
+	}
+
+	// An existing namespace is left as it is
+	existing := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ComponentNamespace, Labels: map[string]string{"owner": "installer"}}}
+	kubeClient = fakekube.NewSimpleClientset(existing)
+	reconciler.KubeClient = kubeClient
+	assert.NoError(t, reconciler.ensureComponentNamespace())
+	namespace, err = kubeClient.CoreV1().Namespaces().Get(context.Background(), ComponentNamespace, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"owner": "installer"}, namespace.Labels)
+	}
+}
@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// networkMigrationDrainTimeout is how long the move waits for the
+	// hosts being provisioned or cleaned, so that a host stuck deploying
+	// does not postpone it forever
+	networkMigrationDrainTimeout = time.Hour
+	// hostPausedAnnotation has the baremetal-operator leave a
+	// BareMetalHost alone
+	hostPausedAnnotation = "baremetalhost.metal3.io/paused"
+	// networkMigrationPauseOwner is the value of the pause annotation set
+	// on the idle hosts while draining, so that only those pauses are
+	// lifted once the pods moved
+	networkMigrationPauseOwner = "baremetal.openshift.io/network-migration"
+)
+
+// updateNetworkMigration returns the state of the move of the metal3 pods
+// to the provisioning network of the spec, from the network of the last
+// configuration they were healthy with. Moving the pods restarts Ironic,
+// which fails the hosts being provisioned or cleaned, so the move waits
+// for them unless it has already started or the drain timed out.
+func updateNetworkMigration(current *metal3iov1alpha1.NetworkMigration, last *metal3iov1alpha1.SuccessfulConfiguration, spec *metal3iov1alpha1.ProvisioningSpec, busy bool, now time.Time) *metal3iov1alpha1.NetworkMigration {
+	if last == nil {
+		// Nothing ran yet, there is nothing to move
+		return current
+	}
+	toIP, toCIDR := spec.ProvisioningIP, spec.ProvisioningNetworkCIDR
+	inProgress := current != nil && current.Phase != metal3iov1alpha1.NetworkMigrationComplete
+	if last.Spec.ProvisioningIP == toIP && last.Spec.ProvisioningNetworkCIDR == toCIDR {
+		if !inProgress {
+			return current
+		}
+		if current.ToIP != toIP || current.ToCIDR != toCIDR {
+			// The change was reverted before it was applied
+			return nil
+		}
+		completed := current.DeepCopy()
+		completed.Phase = metal3iov1alpha1.NetworkMigrationComplete
+		completionTime := metav1.NewTime(now)
+		completed.CompletionTime = &completionTime
+		return completed
+	}
+
+	migration := &metal3iov1alpha1.NetworkMigration{
+		FromIP:    last.Spec.ProvisioningIP,
+		FromCIDR:  last.Spec.ProvisioningNetworkCIDR,
+		ToIP:      toIP,
+		ToCIDR:    toCIDR,
+		Phase:     metal3iov1alpha1.NetworkMigrationDraining,
+		StartTime: metav1.NewTime(now),
+	}
+	if inProgress && current.ToIP == toIP && current.ToCIDR == toCIDR {
+		migration = current.DeepCopy()
+	}
+	// Pods already moved are not moved back when hosts start provisioning
+	drained := !busy || !now.Before(migration.StartTime.Add(networkMigrationDrainTimeout))
+	if drained || migration.Phase == metal3iov1alpha1.NetworkMigrationMigrating {
+		migration.Phase = metal3iov1alpha1.NetworkMigrationMigrating
+	}
+	return migration
+}
+
+// reconcileNetworkMigration returns the state of the move of the metal3
+// pods to the provisioning network of the spec. While draining, spec is
+// switched back to the previous network and the idle hosts are paused.
+func (r *ProvisioningReconciler) reconcileNetworkMigration(prov *metal3iov1alpha1.Provisioning, spec *metal3iov1alpha1.ProvisioningSpec, busy bool) (*metal3iov1alpha1.NetworkMigration, error) {
+	migration := updateNetworkMigration(prov.Status.NetworkMigration, prov.Status.LastSuccessfulConfiguration, &prov.Spec, busy, time.Now())
+	draining := isNetworkMigrationDraining(migration)
+	if draining {
+		// The start of the drain is recorded right away, so that it times
+		// out even when the reconciles end early
+		newStatus := prov.Status.DeepCopy()
+		newStatus.NetworkMigration = migration
+		if err := r.updateProvisioningStatus(prov, newStatus); err != nil {
+			return nil, errors.Wrap(err, "failed to update Provisioning status")
+		}
+		usePreviousNetwork(spec, &prov.Status.LastSuccessfulConfiguration.Spec)
+	}
+	if err := r.syncHostPauses(draining); err != nil {
+		return nil, errors.Wrap(err, "failed to pause the idle hosts")
+	}
+	return migration, nil
+}
+
+// isNetworkMigrationDraining returns true while the metal3 pods are kept
+// on the previous provisioning network
+func isNetworkMigrationDraining(migration *metal3iov1alpha1.NetworkMigration) bool {
+	return migration != nil && migration.Phase == metal3iov1alpha1.NetworkMigrationDraining
+}
+
+// networkMigrationRecheck returns when the drain times out, or zero when
+// the move is not draining
+func networkMigrationRecheck(migration *metal3iov1alpha1.NetworkMigration, now time.Time) time.Duration {
+	if !isNetworkMigrationDraining(migration) {
+		return 0
+	}
+	remaining := migration.StartTime.Add(networkMigrationDrainTimeout).Sub(now)
+	if remaining <= 0 {
+		return time.Second
+	}
+	return remaining
+}
+
+// usePreviousNetwork renders the metal3 pods on the provisioning network
+// of the last configuration they were healthy with, so that everything
+// but the network is still applied while draining
+func usePreviousNetwork(spec *metal3iov1alpha1.ProvisioningSpec, last *metal3iov1alpha1.ProvisioningSpec) {
+	spec.ProvisioningIP = last.ProvisioningIP
+	spec.ProvisioningNetworkCIDR = last.ProvisioningNetworkCIDR
+	spec.ProvisioningDHCPRange = last.ProvisioningDHCPRange
+}
+
+// syncHostPauses pauses the BareMetalHosts that are not being provisioned
+// or cleaned while draining, so that none starts an operation the move
+// would fail, and lifts those pauses once the move is no longer draining.
+// Pauses set by others are left alone.
+func (r *ProvisioningReconciler) syncHostPauses(draining bool) error {
+	hosts := newBareMetalHostList()
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(ComponentNamespace)); err != nil {
+		return err
+	}
+	for i := range hosts.Items {
+		host := &hosts.Items[i]
+		annotations := host.GetAnnotations()
+		_, paused := annotations[hostPausedAnnotation]
+		switch {
+		case draining && !paused && !disruptedHostStates[hostProvisioningState(host)]:
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[hostPausedAnnotation] = networkMigrationPauseOwner
+		case !draining && annotations[hostPausedAnnotation] == networkMigrationPauseOwner:
+			delete(annotations, hostPausedAnnotation)
+		default:
+			continue
+		}
+		host.SetAnnotations(annotations)
+		if err := r.Client.Update(context.Background(), host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// networkMigrationMessage describes why the move to a new provisioning
+// network is waiting
+func networkMigrationMessage(migration *metal3iov1alpha1.NetworkMigration, busyHosts []string) string {
+	return fmt.Sprintf("moving the provisioning network from %s to %s once no host is being provisioned or cleaned: %s",
+		describeNetwork(migration.FromIP, migration.FromCIDR), describeNetwork(migration.ToIP, migration.ToCIDR), busyHostsMessage(busyHosts))
+}
+
+func describeNetwork(ip, cidr string) string {
+	if ip == "" {
+		return cidr
+	}
+	return fmt.Sprintf("%s (%s)", cidr, ip)
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestUpdateNetworkMigration(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	started := metav1.NewTime(now.Add(-time.Hour))
+	oldNetwork := metal3iov1alpha1.ProvisioningSpec{ProvisioningIP: "172.22.0.3", ProvisioningNetworkCIDR: "172.22.0.0/24"}
+	newNetwork := metal3iov1alpha1.ProvisioningSpec{ProvisioningIP: "172.23.0.3", ProvisioningNetworkCIDR: "172.23.0.0/24"}
+	migration := func(phase metal3iov1alpha1.NetworkMigrationPhase) *metal3iov1alpha1.NetworkMigration {
+		return &metal3iov1alpha1.NetworkMigration{
+			FromIP:    "172.22.0.3",
+			FromCIDR:  "172.22.0.0/24",
+			ToIP:      "172.23.0.3",
+			ToCIDR:    "172.23.0.0/24",
+			Phase:     phase,
+			StartTime: started,
+		}
+	}
+	completionTime := metav1.NewTime(now)
+	completed := migration(metal3iov1alpha1.NetworkMigrationComplete)
+	completed.CompletionTime = &completionTime
+	newlyDraining := migration(metal3iov1alpha1.NetworkMigrationDraining)
+	newlyDraining.StartTime = metav1.NewTime(now)
+	timedOut := migration(metal3iov1alpha1.NetworkMigrationDraining)
+	timedOut.StartTime = metav1.NewTime(now.Add(-networkMigrationDrainTimeout))
+	timedOutMigrating := timedOut.DeepCopy()
+	timedOutMigrating.Phase = metal3iov1alpha1.NetworkMigrationMigrating
+
+	tCases := []struct {
+		name     string
+		current  *metal3iov1alpha1.NetworkMigration
+		last     *metal3iov1alpha1.ProvisioningSpec
+		spec     metal3iov1alpha1.ProvisioningSpec
+		busy     bool
+		expected *metal3iov1alpha1.NetworkMigration
+	}{
+		{
+			name: "FirstDeployment",
+			spec: newNetwork,
+			busy: true,
+		},
+		{
+			name: "Unchanged",
+			last: &oldNetwork,
+			spec: oldNetwork,
+		},
+		{
+			name:     "Draining",
+			last:     &oldNetwork,
+			spec:     newNetwork,
+			busy:     true,
+			expected: newlyDraining,
+		},
+		{
+			name:     "Drained",
+			current:  migration(metal3iov1alpha1.NetworkMigrationDraining),
+			last:     &oldNetwork,
+			spec:     newNetwork,
+			expected: migration(metal3iov1alpha1.NetworkMigrationMigrating),
+		},
+		{
+			name:     "StillDraining",
+			current:  newlyDraining,
+			last:     &oldNetwork,
+			spec:     newNetwork,
+			busy:     true,
+			expected: newlyDraining,
+		},
+		{
+			name:     "DrainTimedOut",
+			current:  timedOut,
+			last:     &oldNetwork,
+			spec:     newNetwork,
+			busy:     true,
+			expected: timedOutMigrating,
+		},
+		{
+			name:     "MigratingWhileBusy",
+			current:  migration(metal3iov1alpha1.NetworkMigrationMigrating),
+			last:     &oldNetwork,
+			spec:     newNetwork,
+			busy:     true,
+			expected: migration(metal3iov1alpha1.NetworkMigrationMigrating),
+		},
+		{
+			name:     "Healthy",
+			current:  migration(metal3iov1alpha1.NetworkMigrationMigrating),
+			last:     &newNetwork,
+			spec:     newNetwork,
+			expected: completed,
+		},
+		{
+			name:     "AlreadyComplete",
+			current:  completed,
+			last:     &newNetwork,
+			spec:     newNetwork,
+			expected: completed,
+		},
+		{
+			name:    "Reverted",
+			current: migration(metal3iov1alpha1.NetworkMigrationDraining),
+			last:    &oldNetwork,
+			spec:    oldNetwork,
+		},
+		{
+			name:    "NewTarget",
+			current: completed,
+			last:    &newNetwork,
+			spec:    oldNetwork,
+			busy:    true,
+			expected: &metal3iov1alpha1.NetworkMigration{
+				FromIP:    "172.23.0.3",
+				FromCIDR:  "172.23.0.0/24",
+				ToIP:      "172.22.0.3",
+				ToCIDR:    "172.22.0.0/24",
+				Phase:     metal3iov1alpha1.NetworkMigrationDraining,
+				StartTime: metav1.NewTime(now),
+			},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var last *metal3iov1alpha1.SuccessfulConfiguration
+			if tc.last != nil {
+				last = &metal3iov1alpha1.SuccessfulConfiguration{Spec: *tc.last}
+			}
+			assert.Equal(t, tc.expected, updateNetworkMigration(tc.current, last, &tc.spec, tc.busy, now))
+		})
+	}
+}
+
+func TestNetworkMigrationMessage(t *testing.T) {
+	migration := &metal3iov1alpha1.NetworkMigration{
+		FromIP:   "172.22.0.3",
+		FromCIDR: "172.22.0.0/24",
+		ToCIDR:   "172.23.0.0/24",
+	}
+	assert.Equal(t, "moving the provisioning network from 172.22.0.0/24 (172.22.0.3) to 172.23.0.0/24 once no host is being provisioned or cleaned: 1 hosts are being provisioned or cleaned: worker-0",
+		networkMigrationMessage(migration, []string{"worker-0"}))
+}
+
+func TestNetworkMigrationRecheck(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	migration := &metal3iov1alpha1.NetworkMigration{
+		Phase:     metal3iov1alpha1.NetworkMigrationDraining,
+		StartTime: metav1.NewTime(now.Add(-10 * time.Minute)),
+	}
+	assert.Equal(t, networkMigrationDrainTimeout-10*time.Minute, networkMigrationRecheck(migration, now))
+	assert.Equal(t, time.Second, networkMigrationRecheck(migration, now.Add(networkMigrationDrainTimeout)))
+
+	migration.Phase = metal3iov1alpha1.NetworkMigrationMigrating
+	assert.Equal(t, time.Duration(0), networkMigrationRecheck(migration, now))
+	assert.Equal(t, time.Duration(0), networkMigrationRecheck(nil, now))
+}
+
+func TestUsePreviousNetwork(t *testing.T) {
+	spec := &metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningInterface:   "eth1",
+		ProvisioningIP:          "172.23.0.3",
+		ProvisioningNetworkCIDR: "172.23.0.0/24",
+		ProvisioningDHCPRange:   "172.23.0.10,172.23.0.100",
+	}
+	usePreviousNetwork(spec, &metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningInterface:   "eth0",
+		ProvisioningIP:          "172.22.0.3",
+		ProvisioningNetworkCIDR: "172.22.0.0/24",
+	})
+	assert.Equal(t, &metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningInterface:   "eth1",
+		ProvisioningIP:          "172.22.0.3",
+		ProvisioningNetworkCIDR: "172.22.0.0/24",
+	}, spec)
+}
+
+func TestSyncHostPauses(t *testing.T) {
+	pausedByOthers := newTestBareMetalHost(ComponentNamespace, "worker-2", "available")
+	pausedByOthers.SetAnnotations(map[string]string{hostPausedAnnotation: ""})
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, &osconfigv1.Infrastructure{})
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme,
+		newTestBareMetalHost(ComponentNamespace, "worker-0", "provisioning"),
+		newTestBareMetalHost(ComponentNamespace, "worker-1", "available"),
+		pausedByOthers,
+	)
+	pauses := func() map[string]string {
+		result := map[string]string{}
+		for _, name := range []string{"worker-0", "worker-1", "worker-2"} {
+			host := newBareMetalHost()
+			if assert.NoError(t, reconciler.Client.Get(context.Background(), types.NamespacedName{Namespace: ComponentNamespace, Name: name}, host)) {
+				if value, ok := host.GetAnnotations()[hostPausedAnnotation]; ok {
+					result[name] = value
+				}
+			}
+		}
+		return result
+	}
+
+	// The host being provisioned is left to finish
+	assert.NoError(t, reconciler.syncHostPauses(true))
+	assert.Equal(t, map[string]string{"worker-1": networkMigrationPauseOwner, "worker-2": ""}, pauses())
+
+	assert.NoError(t, reconciler.syncHostPauses(false))
+	assert.Equal(t, map[string]string{"worker-2": ""}, pauses())
+}
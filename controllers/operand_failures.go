@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	metal3PodSelector = "k8s-app in (metal3, metal3-dnsmasq)"
+
+	operandFailureBaseDelay = 10 * time.Second
+	operandFailureMaxDelay  = 10 * time.Minute
+	// operandFailureJitter spreads retries by up to this fraction of the delay
+	operandFailureJitter = 0.5
+
+	// staticIPMismatchGrace leaves the static IP manager time to configure
+	// the ProvisioningIP again before it is reported missing
+	staticIPMismatchGrace = 2 * time.Minute
+)
+
+// operandFailure describes why a metal3 container is failing
+type operandFailure struct {
+	reason    StatusReason
+	container string
+	message   string
+}
+
+func (f operandFailure) String() string {
+	return fmt.Sprintf("metal3 container %s is failing: %s", f.container, f.message)
+}
+
+// interfaceErrorPattern matches the errors of ip and of the kernel about a
+// missing network interface, such as `Cannot find device "eth3"` or
+// `Device "eth3" does not exist.`
+var interfaceErrorPattern = regexp.MustCompile(`(?i)cannot find device|no such device|\bdevice "[^"]*" does not exist`)
+
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+	"InvalidImageName": true,
+}
+
+// classifyContainerFailure returns the failure of a container, or nil when
+// the container is not failing
+func classifyContainerFailure(status corev1.ContainerStatus) *operandFailure {
+	waiting := status.State.Waiting
+	if waiting == nil {
+		return nil
+	}
+	if imagePullReasons[waiting.Reason] {
+		return &operandFailure{
+			reason:    ReasonImagePullFailure,
+			container: status.Name,
+			message:   fmt.Sprintf("unable to pull image %s: %s", status.Image, waiting.Message),
+		}
+	}
+	if waiting.Reason != "CrashLoopBackOff" {
+		return nil
+	}
+
+	message := ""
+	if terminated := status.LastTerminationState.Terminated; terminated != nil {
+		message = strings.TrimSpace(terminated.Message)
+	}
+	failure := &operandFailure{
+		reason:    ReasonDeploymentCrashLooping,
+		container: status.Name,
+		message:   "crash looping",
+	}
+	if message != "" {
+		// Only keep the last line, which usually holds the actual error
+		lines := strings.Split(message, "\n")
+		failure.message = lines[len(lines)-1]
+	}
+
+	switch {
+	case status.Name == provisioning.OSImageVerifierContainerName:
+		failure.reason = ReasonOSImageVerificationFailed
+	case strings.Contains(strings.ToLower(message), "address already in use"):
+		failure.reason = ReasonPortConflict
+	case interfaceErrorPattern.MatchString(message):
+		failure.reason = ReasonProvisioningInterfaceError
+	}
+	return failure
+}
+
+// classifyStaticIPMismatch returns a failure when the static IP manager of
+// a pod has been running for longer than the grace period without finding
+// the ProvisioningIP on the provisioning interface, or nil otherwise
+func classifyStaticIPMismatch(pod *corev1.Pod, status corev1.ContainerStatus, now time.Time) *operandFailure {
+	running := status.State.Running
+	if status.Name != provisioning.StaticIPManagerContainerName || status.Ready || running == nil ||
+		now.Sub(running.StartedAt.Time) < staticIPMismatchGrace {
+		return nil
+	}
+	env := map[string]string{}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == status.Name {
+			for _, e := range container.Env {
+				env[e.Name] = e.Value
+			}
+		}
+	}
+	return &operandFailure{
+		reason:    ReasonProvisioningIPMismatch,
+		container: status.Name,
+		message: fmt.Sprintf("provisioning IP %s is not configured on interface %s of node %s",
+			env["PROVISIONING_IP"], env["PROVISIONING_INTERFACE"], pod.Spec.NodeName),
+	}
+}
+
+// checkMetal3Pods returns the first failure found in the metal3 pods, or
+// nil when none of their containers are failing
+func (r *ProvisioningReconciler) checkMetal3Pods() (*operandFailure, error) {
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: metal3PodSelector})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		statuses := append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if failure := classifyContainerFailure(status); failure != nil {
+				return failure, nil
+			}
+			if failure := classifyStaticIPMismatch(pod, status, time.Now()); failure != nil {
+				return failure, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// operandFailureBackoff returns how long to wait before checking failing
+// operands again. The delay doubles with each consecutive failure, up to
+// operandFailureMaxDelay, and is jittered so that retries do not hit the
+// API server in lockstep.
+func operandFailureBackoff(failures int) time.Duration {
+	delay := operandFailureBaseDelay
+	for i := 1; i < failures && delay < operandFailureMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > operandFailureMaxDelay {
+		delay = operandFailureMaxDelay
+	}
+	return wait.Jitter(delay, operandFailureJitter)
+}
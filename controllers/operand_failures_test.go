@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	configv1 "github.com/openshift/api/config/v1"
+)
+
+func crashLoopingStatus(name string, message string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name: name,
+		State: corev1.ContainerState{
+			Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+		},
+		LastTerminationState: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: message},
+		},
+	}
+}
+
+func TestClassifyContainerFailure(t *testing.T) {
+	tCases := []struct {
+		name           string
+		status         corev1.ContainerStatus
+		expectedReason StatusReason
+		expectedNil    bool
+	}{
+		{
+			name: "Running",
+			status: corev1.ContainerStatus{
+				Name:  "metal3-httpd",
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			},
+			expectedNil: true,
+		},
+		{
+			name: "ContainerCreating",
+			status: corev1.ContainerStatus{
+				Name:  "metal3-httpd",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}},
+			},
+			expectedNil: true,
+		},
+		{
+			name: "ImagePull",
+			status: corev1.ContainerStatus{
+				Name:  "metal3-httpd",
+				Image: "quay.io/metal3-io/ironic",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			},
+			expectedReason: ReasonImagePullFailure,
+		},
+		{
+			name:           "PortConflict",
+			status:         crashLoopingStatus("metal3-httpd", "(98)Address already in use: AH00072: make_sock: could not bind to address [::]:6180"),
+			expectedReason: ReasonPortConflict,
+		},
+		{
+			name:           "BadInterface",
+			status:         crashLoopingStatus("metal3-static-ip-set", "+ ip addr add 172.22.0.3/24 dev eth3\nCannot find device \"eth3\""),
+			expectedReason: ReasonProvisioningInterfaceError,
+		},
+		{
+			name:           "MissingInterface",
+			status:         crashLoopingStatus("metal3-static-ip-set", "+ ip link set eth3 up\nDevice \"eth3\" does not exist."),
+			expectedReason: ReasonProvisioningInterfaceError,
+		},
+		{
+			name:           "MissingFile",
+			status:         crashLoopingStatus("metal3-ironic-conductor", "ERROR: the directory /shared/html/images does not exist"),
+			expectedReason: ReasonDeploymentCrashLooping,
+		},
+		{
+			name:           "OSImageVerification",
+			status:         crashLoopingStatus("metal3-os-image-verifier", "gpg: BAD signature\nOS image signature verification failed: bad GPG signature for rhcos.qcow2"),
+			expectedReason: ReasonOSImageVerificationFailed,
+		},
+		{
+			name:           "Unknown",
+			status:         crashLoopingStatus("metal3-mariadb", "segmentation fault"),
+			expectedReason: ReasonDeploymentCrashLooping,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			failure := classifyContainerFailure(tc.status)
+			if tc.expectedNil {
+				assert.Nil(t, failure)
+				return
+			}
+			if assert.NotNil(t, failure) {
+				assert.Equal(t, tc.expectedReason, failure.reason)
+				assert.Equal(t, tc.status.Name, failure.container)
+			}
+		})
+	}
+}
+
+func TestClassifyStaticIPMismatch(t *testing.T) {
+	now := time.Now()
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: "master-0",
+			Containers: []corev1.Container{
+				{
+					Name: "metal3-static-ip-manager",
+					Env: []corev1.EnvVar{
+						{Name: "PROVISIONING_IP", Value: "172.22.0.3/24"},
+						{Name: "PROVISIONING_INTERFACE", Value: "eth3"},
+					},
+				},
+			},
+		},
+	}
+	runningSince := func(name string, ready bool, since time.Duration) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name:  name,
+			Ready: ready,
+			State: corev1.ContainerState{
+				Running: &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(now.Add(-since))},
+			},
+		}
+	}
+
+	tCases := []struct {
+		name        string
+		status      corev1.ContainerStatus
+		expectedNil bool
+	}{
+		{
+			name:        "Configured",
+			status:      runningSince("metal3-static-ip-manager", true, time.Hour),
+			expectedNil: true,
+		},
+		{
+			name:        "Starting",
+			status:      runningSince("metal3-static-ip-manager", false, time.Minute),
+			expectedNil: true,
+		},
+		{
+			name:        "OtherContainer",
+			status:      runningSince("metal3-httpd", false, time.Hour),
+			expectedNil: true,
+		},
+		{
+			name:   "Missing",
+			status: runningSince("metal3-static-ip-manager", false, time.Hour),
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			failure := classifyStaticIPMismatch(pod, tc.status, now)
+			if tc.expectedNil {
+				assert.Nil(t, failure)
+				return
+			}
+			if assert.NotNil(t, failure) {
+				assert.Equal(t, ReasonProvisioningIPMismatch, failure.reason)
+				assert.Equal(t, "metal3 container metal3-static-ip-manager is failing: provisioning IP 172.22.0.3/24 is not configured on interface eth3 of node master-0",
+					failure.String())
+			}
+		})
+	}
+}
+
+func TestCheckMetal3Pods(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "metal3-abcde",
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{"k8s-app": "metal3"},
+		},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				crashLoopingStatus("metal3-static-ip-set", "Cannot find device \"eth3\""),
+			},
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &configv1.Infrastructure{})
+
+	reconciler.KubeClient = fakekube.NewSimpleClientset()
+	failure, err := reconciler.checkMetal3Pods()
+	assert.NoError(t, err)
+	assert.Nil(t, failure)
+
+	reconciler.KubeClient = fakekube.NewSimpleClientset(pod)
+	failure, err = reconciler.checkMetal3Pods()
+	assert.NoError(t, err)
+	if assert.NotNil(t, failure) {
+		assert.Equal(t, ReasonProvisioningInterfaceError, failure.reason)
+	}
+}
+
+func TestOperandFailureBackoff(t *testing.T) {
+	tCases := []struct {
+		failures int
+		minDelay time.Duration
+	}{
+		{failures: 1, minDelay: operandFailureBaseDelay},
+		{failures: 2, minDelay: 2 * operandFailureBaseDelay},
+		{failures: 4, minDelay: 8 * operandFailureBaseDelay},
+		{failures: 100, minDelay: operandFailureMaxDelay},
+	}
+	for _, tc := range tCases {
+		delay := operandFailureBackoff(tc.failures)
+		assert.True(t, delay >= tc.minDelay, "failures %d: delay %s below %s", tc.failures, delay, tc.minDelay)
+		maxDelay := time.Duration(float64(tc.minDelay) * (1 + operandFailureJitter))
+		assert.True(t, delay <= maxDelay, "failures %d: delay %s above %s", tc.failures, delay, maxDelay)
+	}
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// operandRolloutTimeout is how long a new revision of the metal3
+	// Deployment has to become healthy before it is rolled back
+	operandRolloutTimeout = 10 * time.Minute
+	// operandRolloutRequeueAfter is how often a new revision is checked
+	// while it is being verified
+	operandRolloutRequeueAfter = 15 * time.Second
+	operandHealthProbeTimeout  = 5 * time.Second
+)
+
+// rolloutResult is the outcome of rolling out the metal3 Deployment
+type rolloutResult struct {
+	// prePulling is true while the new images are being pulled, before
+	// the Deployment is updated
+	prePulling bool
+	// verifying is true while the new revision is not healthy yet
+	verifying bool
+	// failure is set when the desired revision failed and was rolled back
+	failure string
+	// healthy is true once the desired revision was found healthy
+	healthy bool
+}
+
+// probeOperandHealth returns an error when the endpoint does not answer
+// successfully
+func (r *ProvisioningReconciler) probeOperandHealth(url string) error {
+	if r.operandHealthProbe != nil {
+		return r.operandHealthProbe(url)
+	}
+	client := &http.Client{Timeout: operandHealthProbeTimeout}
+	resp, err := client.Get(url) // #nosec
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// checkMetal3Health returns why the given revision of the metal3
+// Deployment is not healthy yet, or an empty string once its pods run and
+// the Ironic and Inspector APIs answer. With HighAvailability only the
+// active pod runs them, the standby ones are not probed.
+func (r *ProvisioningReconciler) checkMetal3Health(deployment *appsv1.Deployment, rolloutHash string) (string, error) {
+	ctx := context.Background()
+	existing, err := r.KubeClient.AppsV1().Deployments(deployment.Namespace).Get(ctx, deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if existing.Annotations[provisioning.RolloutHashAnnotation] != rolloutHash || !provisioning.IsDeploymentRolledOut(existing) {
+		return "deployment is not rolled out", nil
+	}
+
+	active, err := provisioning.GetActiveMetal3Node(r.KubeClient.CoreV1(), deployment.Namespace)
+	if err != nil {
+		return "", err
+	}
+	selector := labels.SelectorFromSet(deployment.Spec.Selector.MatchLabels)
+	pods, err := r.KubeClient.CoreV1().Pods(deployment.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+	probed := false
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if active != "" && pod.Spec.NodeName != active {
+			continue
+		}
+		for _, url := range provisioning.GetOperandHealthURLs(pod.Status.PodIP) {
+			if err := r.probeOperandHealth(url); err != nil {
+				return fmt.Sprintf("health check failed: %v", err), nil
+			}
+		}
+		probed = true
+	}
+	if !probed {
+		return "no running metal3 pod", nil
+	}
+	return "", nil
+}
+
+// applyMetal3Revision applies the given revision of the metal3 Deployment
+func (r *ProvisioningReconciler) applyMetal3Revision(prov *metal3iov1alpha1.Provisioning, deployment *appsv1.Deployment) error {
+	if err := controllerutil.SetControllerReference(prov, deployment, r.Scheme); err != nil {
+		return err
+	}
+	_, err := provisioning.ApplyMetal3Deployment(r.KubeClient.AppsV1(), deployment)
+	return err
+}
+
+func (r *ProvisioningReconciler) saveRolloutState(prov *metal3iov1alpha1.Provisioning, state *provisioning.RolloutState) error {
+	configMap, err := provisioning.NewRolloutStateConfigMap(ComponentNamespace, state)
+	if err != nil {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+		return err
+	}
+	return provisioning.ApplyRolloutStateConfigMap(r.KubeClient.CoreV1(), configMap)
+}
+
+// rolloutMetal3Deployment rolls the metal3 Deployment out to the desired
+// revision, then verifies that the Ironic and Inspector APIs of the new
+// revision become healthy. A revision still unhealthy after
+// operandRolloutTimeout is rolled back to the last healthy one, and is not
+// applied again until the rendered Deployment changes.
+func (r *ProvisioningReconciler) rolloutMetal3Deployment(prov *metal3iov1alpha1.Provisioning, images *provisioning.Images,
+	spec *metal3iov1alpha1.ProvisioningSpec, deployment *appsv1.Deployment, rolloutHash string) (rolloutResult, error) {
+	result := rolloutResult{}
+	state, err := provisioning.GetRolloutState(r.KubeClient.CoreV1(), ComponentNamespace)
+	if err != nil {
+		return result, err
+	}
+
+	if rolloutHash == state.FailedHash {
+		result.failure = fmt.Sprintf("metal3 revision %s failed to become healthy and was rolled back", rolloutHash)
+		if state.HealthyDeployment != nil {
+			return result, r.applyMetal3Revision(prov, state.HealthyDeployment)
+		}
+		return result, nil
+	}
+
+	pulled, err := r.prePullImages(prov, images, spec, deployment)
+	if err != nil {
+		return result, err
+	}
+	if !pulled {
+		result.prePulling = true
+		return result, nil
+	}
+	if err := r.applyMetal3Revision(prov, deployment); err != nil {
+		return result, err
+	}
+	if rolloutHash == state.HealthyHash {
+		result.healthy = true
+		return result, nil
+	}
+
+	if rolloutHash != state.PendingHash {
+		r.Log.Info("verifying new metal3 revision", "rolloutHash", rolloutHash)
+		state.PendingHash = rolloutHash
+		state.PendingSince = time.Now()
+		result.verifying = true
+		return result, r.saveRolloutState(prov, state)
+	}
+
+	reason, err := r.checkMetal3Health(deployment, rolloutHash)
+	if err != nil {
+		return result, err
+	}
+	if reason == "" {
+		r.Log.Info("new metal3 revision is healthy", "rolloutHash", rolloutHash)
+		state.HealthyHash = rolloutHash
+		state.HealthyDeployment = deployment
+		state.PendingHash = ""
+		state.PendingSince = time.Time{}
+		state.FailedHash = ""
+		result.healthy = true
+		return result, r.saveRolloutState(prov, state)
+	}
+	if time.Since(state.PendingSince) < operandRolloutTimeout {
+		r.Log.V(1).Info("waiting for new metal3 revision to become healthy", "rolloutHash", rolloutHash, "reason", reason)
+		result.verifying = true
+		return result, nil
+	}
+
+	result.failure = fmt.Sprintf("metal3 revision %s did not become healthy within %v: %s", rolloutHash, operandRolloutTimeout, reason)
+	state.FailedHash = rolloutHash
+	state.PendingHash = ""
+	state.PendingSince = time.Time{}
+	if state.HealthyDeployment != nil {
+		result.failure += fmt.Sprintf(", rolled back to revision %s", state.HealthyHash)
+		if err := r.applyMetal3Revision(prov, state.HealthyDeployment); err != nil {
+			return result, err
+		}
+	}
+	r.Log.Info("metal3 rollout failed", "reason", result.failure)
+	if r.EventRecorder != nil {
+		r.EventRecorder.Event(prov, corev1.EventTypeWarning, string(ReasonOperandRolloutFailed), result.failure)
+	}
+	return result, r.saveRolloutState(prov, state)
+}
+
+// setLastSuccessfulConfiguration records the spec of the Provisioning as
+// the last one that produced healthy metal3 pods. The time it was first
+// found healthy is kept until the spec changes.
+func setLastSuccessfulConfiguration(status *metal3iov1alpha1.ProvisioningStatus, prov *metal3iov1alpha1.Provisioning, rolloutHash string, now time.Time) {
+	last := status.LastSuccessfulConfiguration
+	if last != nil && last.Generation == prov.Generation && last.RolloutHash == rolloutHash {
+		return
+	}
+	status.LastSuccessfulConfiguration = &metal3iov1alpha1.SuccessfulConfiguration{
+		Spec:        *prov.Spec.DeepCopy(),
+		Generation:  prov.Generation,
+		RolloutHash: rolloutHash,
+		Time:        metav1.NewTime(now),
+	}
+}
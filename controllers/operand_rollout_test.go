@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	healthyRolloutHash = "1111111111111111"
+	newRolloutHash     = "2222222222222222"
+)
+
+func newMetal3Revision(image string, rolloutHash string) *appsv1.Deployment {
+	deployment := newMetal3Deployment(image)
+	provisioning.SetRolloutHash(deployment, rolloutHash)
+	return deployment
+}
+
+func newRolledOutMetal3Deployment(image string, rolloutHash string) *appsv1.Deployment {
+	deployment := newMetal3Revision(image, rolloutHash)
+	deployment.Generation = 1
+	deployment.Status = appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+	return deployment
+}
+
+func newMetal3Pod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "metal3-abcde",
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{"k8s-app": "metal3"},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "192.168.111.20"},
+	}
+}
+
+func newRolloutStateConfigMap(t *testing.T, state *provisioning.RolloutState) *corev1.ConfigMap {
+	configMap, err := provisioning.NewRolloutStateConfigMap(ComponentNamespace, state)
+	assert.NoError(t, err)
+	return configMap
+}
+
+func TestRolloutMetal3Deployment(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+	}
+	healthy := newMetal3Revision("ironic:1", healthyRolloutHash)
+
+	tCases := []struct {
+		name            string
+		objects         func(t *testing.T) []runtime.Object
+		probeErr        error
+		expected        rolloutResult
+		expectedImage   string
+		expectedHealthy string
+		expectedPending string
+		expectedFailed  string
+	}{
+		{
+			name:            "FirstRollout",
+			objects:         func(t *testing.T) []runtime.Object { return nil },
+			expected:        rolloutResult{verifying: true},
+			expectedImage:   "ironic:2",
+			expectedPending: newRolloutHash,
+		},
+		{
+			name: "Healthy",
+			objects: func(t *testing.T) []runtime.Object {
+				return []runtime.Object{newRolledOutMetal3Deployment("ironic:2", newRolloutHash), newMetal3Pod(),
+					newRolloutStateConfigMap(t, &provisioning.RolloutState{PendingHash: newRolloutHash, PendingSince: time.Now()})}
+			},
+			expected:        rolloutResult{healthy: true},
+			expectedImage:   "ironic:2",
+			expectedHealthy: newRolloutHash,
+		},
+		{
+			name: "Verifying",
+			objects: func(t *testing.T) []runtime.Object {
+				return []runtime.Object{newRolledOutMetal3Deployment("ironic:2", newRolloutHash), newMetal3Pod(),
+					newRolloutStateConfigMap(t, &provisioning.RolloutState{
+						HealthyHash: healthyRolloutHash, HealthyDeployment: healthy,
+						PendingHash: newRolloutHash, PendingSince: time.Now()})}
+			},
+			probeErr:        fmt.Errorf("connection refused"),
+			expected:        rolloutResult{verifying: true},
+			expectedImage:   "ironic:2",
+			expectedHealthy: healthyRolloutHash,
+			expectedPending: newRolloutHash,
+		},
+		{
+			name: "RolledBack",
+			objects: func(t *testing.T) []runtime.Object {
+				return []runtime.Object{newRolledOutMetal3Deployment("ironic:2", newRolloutHash), newMetal3Pod(),
+					newRolloutStateConfigMap(t, &provisioning.RolloutState{
+						HealthyHash: healthyRolloutHash, HealthyDeployment: healthy,
+						PendingHash: newRolloutHash, PendingSince: time.Now().Add(-operandRolloutTimeout - time.Minute)})}
+			},
+			probeErr: fmt.Errorf("connection refused"),
+			expected: rolloutResult{failure: "metal3 revision 2222222222222222 did not become healthy within 10m0s: " +
+				"health check failed: connection refused, rolled back to revision 1111111111111111"},
+			expectedImage:   "ironic:1",
+			expectedHealthy: healthyRolloutHash,
+			expectedFailed:  newRolloutHash,
+		},
+		{
+			name: "KnownFailure",
+			objects: func(t *testing.T) []runtime.Object {
+				return []runtime.Object{newRolledOutMetal3Deployment("ironic:1", healthyRolloutHash),
+					newRolloutStateConfigMap(t, &provisioning.RolloutState{
+						HealthyHash: healthyRolloutHash, HealthyDeployment: healthy, FailedHash: newRolloutHash})}
+			},
+			expected:        rolloutResult{failure: "metal3 revision 2222222222222222 failed to become healthy and was rolled back"},
+			expectedImage:   "ironic:1",
+			expectedHealthy: healthyRolloutHash,
+			expectedFailed:  newRolloutHash,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(tc.objects(t)...)
+			reconciler.operandHealthProbe = func(string) error { return tc.probeErr }
+
+			result, err := reconciler.rolloutMetal3Deployment(prov, &provisioning.Images{}, &prov.Spec,
+				newMetal3Revision("ironic:2", newRolloutHash), newRolloutHash)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+
+			deployment, err := reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).Get(context.Background(), "metal3", metav1.GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedImage, deployment.Spec.Template.Spec.Containers[0].Image)
+
+			state, err := provisioning.GetRolloutState(reconciler.KubeClient.CoreV1(), ComponentNamespace)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedHealthy, state.HealthyHash)
+			assert.Equal(t, tc.expectedPending, state.PendingHash)
+			assert.Equal(t, tc.expectedFailed, state.FailedHash)
+		})
+	}
+}
+
+func TestSetLastSuccessfulConfiguration(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR, Generation: 2},
+		Spec:       metal3iov1alpha1.ProvisioningSpec{ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkDisabled},
+	}
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	firstHealthy := time.Now().Add(-time.Hour)
+
+	setLastSuccessfulConfiguration(status, prov, healthyRolloutHash, firstHealthy)
+	if assert.NotNil(t, status.LastSuccessfulConfiguration) {
+		assert.Equal(t, prov.Spec, status.LastSuccessfulConfiguration.Spec)
+		assert.Equal(t, int64(2), status.LastSuccessfulConfiguration.Generation)
+		assert.Equal(t, healthyRolloutHash, status.LastSuccessfulConfiguration.RolloutHash)
+	}
+
+	// Later reconciles of the same spec keep the time it was first healthy
+	setLastSuccessfulConfiguration(status, prov, healthyRolloutHash, time.Now())
+	assert.Equal(t, firstHealthy.Unix(), status.LastSuccessfulConfiguration.Time.Unix())
+
+	prov.Generation = 3
+	prov.Spec.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkUnmanaged
+	setLastSuccessfulConfiguration(status, prov, newRolloutHash, time.Now())
+	assert.Equal(t, metal3iov1alpha1.ProvisioningNetworkUnmanaged, status.LastSuccessfulConfiguration.Spec.ProvisioningNetwork)
+	assert.Equal(t, newRolloutHash, status.LastSuccessfulConfiguration.RolloutHash)
+	assert.True(t, status.LastSuccessfulConfiguration.Time.After(firstHealthy))
+}
+
+func TestCheckMetal3HealthActivePassive(t *testing.T) {
+	standby := newMetal3Pod()
+	standby.Spec.NodeName = "master-0"
+	active := newMetal3Pod()
+	active.Name = "metal3-fghij"
+	active.Spec.NodeName = "master-1"
+	active.Status.PodIP = "192.168.111.21"
+
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &metal3iov1alpha1.Provisioning{})
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newRolledOutMetal3Deployment("ironic:2", newRolloutHash), standby, active)
+	// The standby pod does not run Ironic
+	reconciler.operandHealthProbe = func(url string) error {
+		if strings.Contains(url, standby.Status.PodIP) {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	}
+
+	reason, err := reconciler.checkMetal3Health(newMetal3Revision("ironic:2", newRolloutHash), newRolloutHash)
+	assert.NoError(t, err)
+	assert.Equal(t, "health check failed: connection refused", reason)
+
+	_, err = reconciler.KubeClient.CoreV1().ConfigMaps(ComponentNamespace).Create(context.Background(),
+		provisioning.NewProvisioningVIPConfigMap(ComponentNamespace, "master-1"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	reason, err = reconciler.checkMetal3Health(newMetal3Revision("ironic:2", newRolloutHash), newRolloutHash)
+	assert.NoError(t, err)
+	assert.Empty(t, reason)
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// orphanCleanupRetry is how soon the orphan cleanup runs again when no
+// metal3 pod was ready to answer
+const orphanCleanupRetry = time.Minute
+
+var orphanedNodesRemoved = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "metal3_orphaned_ironic_nodes_removed_total",
+	Help: "Number of Ironic nodes matching no BareMetalHost deleted by the operator.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(orphanedNodesRemoved)
+}
+
+// ironicAPIClient is the part of the Ironic API the operator uses
+type ironicAPIClient interface {
+	ListNodes() ([]provisioning.IronicNode, error)
+	ListPorts() ([]provisioning.IronicPort, error)
+	DeleteNode(uuid string) error
+	SetNodeConductorGroup(uuid string, group string) error
+	AddNodeTrait(uuid string, trait string) error
+	RemoveNodeTrait(uuid string, trait string) error
+	SetNodeInstanceTraits(uuid string, traits []string) error
+	ListDeployTemplates() ([]provisioning.IronicDeployTemplate, error)
+	CreateDeployTemplate(template provisioning.IronicDeployTemplate) error
+	UpdateDeployTemplate(template provisioning.IronicDeployTemplate) error
+	DeleteDeployTemplate(name string) error
+}
+
+// newIronicAPIClient returns a client of the Ironic API of the metal3 pod
+// with the given IP
+func (r *ProvisioningReconciler) newIronicAPIClient(podIP string, spec *metal3iov1alpha1.ProvisioningSpec) (ironicAPIClient, error) {
+	if r.ironicClient != nil {
+		return r.ironicClient(podIP)
+	}
+	return provisioning.NewIronicClient(r.KubeClient.CoreV1(), ComponentNamespace, podIP, spec)
+}
+
+// readyMetal3PodIP returns the IP of a ready metal3 pod, or an empty
+// string when there is none. With HighAvailability only the active pod
+// runs Ironic, the standby ones being ready as well.
+func (r *ProvisioningReconciler) readyMetal3PodIP() (string, error) {
+	active, err := provisioning.GetActiveMetal3Node(r.KubeClient.CoreV1(), ComponentNamespace)
+	if err != nil {
+		return "", err
+	}
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: metal3DeploymentPodSelector})
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if active != "" && pod.Spec.NodeName != active {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				return pod.Status.PodIP, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// listHostIdentities returns the Ironic node IDs recorded by the
+// BareMetalHosts, and the names the baremetal-operator gives their nodes
+func (r *ProvisioningReconciler) listHostIdentities() (map[string]bool, map[string]bool, error) {
+	hosts := newBareMetalHostList()
+	if err := r.Client.List(context.Background(), hosts, client.InNamespace(ComponentNamespace)); err != nil {
+		return nil, nil, err
+	}
+	ids := map[string]bool{}
+	names := map[string]bool{}
+	for i := range hosts.Items {
+		host := &hosts.Items[i]
+		if id := hostIronicID(host); id != "" {
+			ids[id] = true
+		}
+		names[host.GetName()] = true
+		names[host.GetNamespace()+"~"+host.GetName()] = true
+	}
+	return ids, names, nil
+}
+
+// cleanupOrphanedNodes deletes, every OrphanCleanup interval, the Ironic
+// nodes no BareMetalHost manages anymore, then asks dnsmasq to release
+// the leases of their ports. The outcome is recorded in the status, and
+// it returns when the next cleanup is due.
+func (r *ProvisioningReconciler) cleanupOrphanedNodes(prov *metal3iov1alpha1.Provisioning, status *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec, now time.Time) (time.Duration, error) {
+	if spec.OrphanCleanup == nil {
+		return 0, nil
+	}
+	interval := provisioning.GetOrphanCleanupInterval(spec)
+	if last := status.LastOrphanCleanup; last != nil && now.Sub(last.Time.Time) < interval {
+		return interval - now.Sub(last.Time.Time), nil
+	}
+
+	podIP, err := r.readyMetal3PodIP()
+	if err != nil || podIP == "" {
+		return orphanCleanupRetry, err
+	}
+	ironic, err := r.newIronicAPIClient(podIP, spec)
+	if err != nil {
+		return 0, err
+	}
+	nodes, err := ironic.ListNodes()
+	if err != nil {
+		return 0, err
+	}
+	ports, err := ironic.ListPorts()
+	if err != nil {
+		return 0, err
+	}
+	// Hosts are listed after the nodes, so that a node created meanwhile
+	// is matched by the host it was created for
+	hostIDs, hostNames, err := r.listHostIdentities()
+	if err != nil {
+		return 0, err
+	}
+
+	removable, skipped := provisioning.FindOrphanedNodes(nodes, hostIDs, hostNames)
+	outcome := &metal3iov1alpha1.OrphanCleanupStatus{Time: metav1.NewTime(now)}
+	removed := []provisioning.IronicNode{}
+	for _, node := range removable {
+		if err := ironic.DeleteNode(node.UUID); err != nil {
+			r.Log.Info("failed to delete orphaned Ironic node", "node", node.UUID, "error", err.Error())
+			outcome.SkippedNodes = append(outcome.SkippedNodes, node.UUID)
+			continue
+		}
+		r.Log.Info("deleted orphaned Ironic node", "node", node.UUID, "name", node.Name, "state", node.ProvisionState)
+		orphanedNodesRemoved.Inc()
+		removed = append(removed, node)
+		outcome.RemovedNodes = append(outcome.RemovedNodes, node.UUID)
+	}
+	for _, node := range skipped {
+		outcome.SkippedNodes = append(outcome.SkippedNodes, node.UUID)
+	}
+	sort.Strings(outcome.SkippedNodes)
+
+	if macs := provisioning.GetNodeMACs(ports, removed); len(macs) > 0 && provisioning.IsDnsmasqRequired(spec) {
+		configMap := provisioning.NewDHCPLeaseReleaseConfigMap(ComponentNamespace, now.UTC().Format(time.RFC3339), macs)
+		if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+			return 0, err
+		}
+		if err := provisioning.ApplyDHCPLeaseReleaseConfigMap(r.KubeClient.CoreV1(), configMap); err != nil {
+			return 0, err
+		}
+	}
+	status.LastOrphanCleanup = outcome
+	return interval, nil
+}
+
+// soonestRequeue returns the shortest of the given delays, ignoring those
+// that are zero as they request no requeue
+func soonestRequeue(delays ...time.Duration) time.Duration {
+	var soonest time.Duration
+	for _, delay := range delays {
+		if delay > 0 && (soonest == 0 || delay < soonest) {
+			soonest = delay
+		}
+	}
+	return soonest
+}
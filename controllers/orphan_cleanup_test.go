@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+type fakeIronicClient struct {
+	nodes     []provisioning.IronicNode
+	ports     []provisioning.IronicPort
+	templates []provisioning.IronicDeployTemplate
+	failing   map[string]bool
+	deleted   []string
+	calls     []string
+}
+
+func (c *fakeIronicClient) ListNodes() ([]provisioning.IronicNode, error) {
+	return c.nodes, nil
+}
+
+func (c *fakeIronicClient) ListPorts() ([]provisioning.IronicPort, error) {
+	return c.ports, nil
+}
+
+func (c *fakeIronicClient) DeleteNode(uuid string) error {
+	if c.failing[uuid] {
+		return fmt.Errorf("node %s is locked", uuid)
+	}
+	c.deleted = append(c.deleted, uuid)
+	return nil
+}
+
+func (c *fakeIronicClient) SetNodeConductorGroup(uuid string, group string) error {
+	if c.failing[uuid] {
+		return fmt.Errorf("node %s is locked", uuid)
+	}
+	c.calls = append(c.calls, "conductor_group "+uuid+"="+group)
+	return nil
+}
+
+func (c *fakeIronicClient) AddNodeTrait(uuid string, trait string) error {
+	if c.failing[uuid] {
+		return fmt.Errorf("node %s is locked", uuid)
+	}
+	c.calls = append(c.calls, "add trait "+uuid+"="+trait)
+	return nil
+}
+
+func (c *fakeIronicClient) RemoveNodeTrait(uuid string, trait string) error {
+	c.calls = append(c.calls, "remove trait "+uuid+"="+trait)
+	return nil
+}
+
+func (c *fakeIronicClient) SetNodeInstanceTraits(uuid string, traits []string) error {
+	c.calls = append(c.calls, "instance traits "+uuid+"="+strings.Join(traits, ","))
+	return nil
+}
+
+func (c *fakeIronicClient) ListDeployTemplates() ([]provisioning.IronicDeployTemplate, error) {
+	return c.templates, nil
+}
+
+func (c *fakeIronicClient) CreateDeployTemplate(template provisioning.IronicDeployTemplate) error {
+	if c.failing[template.Name] {
+		return fmt.Errorf("template %s is invalid", template.Name)
+	}
+	c.calls = append(c.calls, "create "+template.Name)
+	return nil
+}
+
+func (c *fakeIronicClient) UpdateDeployTemplate(template provisioning.IronicDeployTemplate) error {
+	c.calls = append(c.calls, "update "+template.Name)
+	return nil
+}
+
+func (c *fakeIronicClient) DeleteDeployTemplate(name string) error {
+	c.calls = append(c.calls, "delete "+name)
+	return nil
+}
+
+func TestCleanupOrphanedNodes(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:   "eth0",
+			ProvisioningIP:          "172.30.20.3",
+			ProvisioningNetworkCIDR: "172.30.20.0/24",
+			ProvisioningDHCPRange:   "172.30.20.11, 172.30.20.101",
+			ProvisioningNetwork:     metal3iov1alpha1.ProvisioningNetworkManaged,
+			OrphanCleanup:           &metal3iov1alpha1.OrphanCleanup{IntervalMinutes: 30},
+		},
+	}
+	registered := newTestBareMetalHost(ComponentNamespace, "worker-0", "provisioned")
+	_ = unstructured.SetNestedField(registered.Object, "uuid-0", "status", "provisioning", "ID")
+
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov, registered,
+		newTestBareMetalHost(ComponentNamespace, "worker-1", "registering"))
+	pod := newReadyMetal3Pod("metal3-a", "master-0")
+	pod.Status.PodIP = "192.168.111.20"
+	kubeClient := fakekube.NewSimpleClientset(pod)
+	reconciler.KubeClient = kubeClient
+	ironic := &fakeIronicClient{
+		nodes: []provisioning.IronicNode{
+			{UUID: "uuid-0", Name: "worker-0", ProvisionState: "active"},
+			{UUID: "uuid-1", Name: "worker-1", ProvisionState: "enroll"},
+			{UUID: "uuid-2", Name: "worker-2", ProvisionState: "available"},
+			{UUID: "uuid-3", Name: "worker-3", ProvisionState: "manageable"},
+			{UUID: "uuid-4", Name: "worker-4", ProvisionState: "deploying"},
+		},
+		ports: []provisioning.IronicPort{
+			{Address: "52:54:00:aa:00:02", NodeUUID: "uuid-2"},
+			{Address: "52:54:00:aa:00:03", NodeUUID: "uuid-3"},
+		},
+		failing: map[string]bool{"uuid-3": true},
+	}
+	podIPs := []string{}
+	reconciler.ironicClient = func(podIP string) (ironicAPIClient, error) {
+		podIPs = append(podIPs, podIP)
+		return ironic, nil
+	}
+
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	recheck, err := reconciler.cleanupOrphanedNodes(prov, status, &prov.Spec, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, recheck)
+	assert.Equal(t, []string{"192.168.111.20"}, podIPs)
+	assert.Equal(t, []string{"uuid-2"}, ironic.deleted)
+	assert.Equal(t, &metal3iov1alpha1.OrphanCleanupStatus{
+		Time:         metav1.NewTime(now),
+		RemovedNodes: []string{"uuid-2"},
+		SkippedNodes: []string{"uuid-3", "uuid-4"},
+	}, status.LastOrphanCleanup)
+	configMap, err := kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(), provisioning.DHCPLeaseReleaseConfigMapName, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"id": "2021-01-01T12:00:00Z", "macs": "52:54:00:aa:00:02"}, configMap.Data)
+	}
+
+	// The next cleanup waits for the interval
+	recheck, err = reconciler.cleanupOrphanedNodes(prov, status, &prov.Spec, now.Add(10*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 20*time.Minute, recheck)
+	assert.Len(t, podIPs, 1)
+
+	// Nothing happens when disabled
+	prov.Spec.OrphanCleanup = nil
+	recheck, err = reconciler.cleanupOrphanedNodes(prov, status, &prov.Spec, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Len(t, podIPs, 1)
+}
+
+func TestCleanupOrphanedNodesWithoutReadyPod(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec:       metal3iov1alpha1.ProvisioningSpec{OrphanCleanup: &metal3iov1alpha1.OrphanCleanup{}},
+	}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newReadyMetal3Pod("metal3-a", "master-0"))
+	reconciler.ironicClient = func(podIP string) (ironicAPIClient, error) {
+		t.Fatal("no Ironic client expected without a pod IP")
+		return nil, nil
+	}
+
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	recheck, err := reconciler.cleanupOrphanedNodes(prov, status, &prov.Spec, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, orphanCleanupRetry, recheck)
+	assert.Nil(t, status.LastOrphanCleanup)
+}
+
+func TestReadyMetal3PodIP(t *testing.T) {
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &metal3iov1alpha1.Provisioning{})
+	standby := newReadyMetal3Pod("metal3-a", "master-0")
+	standby.Status.PodIP = "192.168.111.20"
+	active := newReadyMetal3Pod("metal3-b", "master-1")
+	active.Status.PodIP = "192.168.111.21"
+	kubeClient := fakekube.NewSimpleClientset(standby, active)
+	reconciler.KubeClient = kubeClient
+
+	podIP, err := reconciler.readyMetal3PodIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.111.20", podIP)
+
+	// Only the active pod runs Ironic with HighAvailability
+	_, err = kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Create(context.Background(),
+		provisioning.NewProvisioningVIPConfigMap(ComponentNamespace, "master-1"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	podIP, err = reconciler.readyMetal3PodIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.111.21", podIP)
+}
+
+func TestSoonestRequeue(t *testing.T) {
+	assert.Equal(t, time.Duration(0), soonestRequeue())
+	assert.Equal(t, time.Duration(0), soonestRequeue(0, 0))
+	assert.Equal(t, time.Minute, soonestRequeue(0, time.Hour, time.Minute))
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+// resolveOSImage returns the provisioning OS image that the metal3 image
+// cache should serve. When AutoUpdateOSImage is not set this is always the
+// ProvisioningOSDownloadURL from the spec. Otherwise it is the image of
+// the architecture of the control plane nodes in the stream metadata, and
+// an error matching ErrInvalidSpec when there is none.
+func (r *ProvisioningReconciler) resolveOSImage(prov *metal3iov1alpha1.Provisioning) (*provisioning.OSImage, error) {
+	specImage := &provisioning.OSImage{URL: prov.Spec.ProvisioningOSDownloadURL}
+	if !prov.Spec.AutoUpdateOSImage {
+		return specImage, nil
+	}
+
+	// Until stream metadata is available keep serving whatever image is
+	// already cached, so that a missing ConfigMap never rolls the image back.
+	fallback := specImage
+	if prov.Status.OSImage.URL != "" {
+		fallback = &provisioning.OSImage{
+			URL:     prov.Status.OSImage.URL,
+			Version: prov.Status.OSImage.Version,
+		}
+	}
+
+	cm := &corev1.ConfigMap{}
+	err := r.Client.Get(context.Background(), client.ObjectKey{
+		Namespace: provisioning.CoreOSBootImagesNamespace,
+		Name:      provisioning.CoreOSBootImagesConfigMap,
+	}, cm)
+	if apierrors.IsNotFound(err) {
+		r.Log.V(1).Info("RHCOS stream metadata not found, not updating OS image",
+			"configmap", provisioning.CoreOSBootImagesConfigMap)
+		return fallback, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	arch, err := r.getCoreOSArchitecture()
+	if err != nil {
+		return nil, err
+	}
+	image, err := provisioning.GetOSImageFromStream(cm, arch)
+	if errors.Is(err, provisioning.ErrInvalidSpec) {
+		return nil, err
+	}
+	if err != nil {
+		r.Log.Error(err, "invalid RHCOS stream metadata, not updating OS image")
+		return fallback, nil
+	}
+	return image, nil
+}
+
+// getCoreOSArchitecture returns the RHCOS stream architecture of the
+// control plane nodes
+func (r *ProvisioningReconciler) getCoreOSArchitecture() (string, error) {
+	selector := labels.SelectorFromSet(provisioning.ControlPlaneNodeLabels())
+	nodes, err := r.KubeClient.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return "", err
+	}
+	return provisioning.GetCoreOSArchitecture(nodes.Items)
+}
+
+// setOSImageStatus records the OS image in use in the Provisioning
+// status, keeping track of the image it replaced.
+func (r *ProvisioningReconciler) setOSImageStatus(provStatus *metal3iov1alpha1.ProvisioningStatus, image *provisioning.OSImage) {
+	current := provStatus.OSImage
+	if current.URL == image.URL && current.Version == image.Version {
+		return
+	}
+
+	status := metal3iov1alpha1.OSImageStatus{
+		URL:             image.URL,
+		Version:         image.Version,
+		PreviousURL:     current.PreviousURL,
+		PreviousVersion: current.PreviousVersion,
+	}
+	if current.URL != "" && current.URL != image.URL {
+		status.PreviousURL = current.URL
+		status.PreviousVersion = current.Version
+		r.Log.Info("updating provisioning OS image",
+			"oldVersion", current.Version, "newVersion", image.Version)
+	}
+
+	provStatus.OSImage = status
+}
+
+// osImageStreamToProvisioning maps changes to the coreos-bootimages
+// ConfigMap to a reconcile of the Provisioning singleton.
+func osImageStreamToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() != provisioning.CoreOSBootImagesNamespace ||
+		obj.Meta.GetName() != provisioning.CoreOSBootImagesConfigMap {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+	}
+}
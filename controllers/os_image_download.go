@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// osImageDownloadRecheck is how often the progress of the download of the
+// OS image is read while it runs
+const osImageDownloadRecheck = 30 * time.Second
+
+// setOSImageDownloadStatus records the progress of the download of the OS
+// image by the metal3 pod, and returns when to read it again. The last
+// progress read is kept when no pod reports it, and the download is not
+// followed anymore once complete.
+func (r *ProvisioningReconciler) setOSImageDownloadStatus(provStatus *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec, now time.Time) time.Duration {
+	if !provisioning.ReportsOSImageDownloadProgress(spec) {
+		provStatus.OSImage.Download = nil
+		return 0
+	}
+	current := provStatus.OSImage.Download
+	if current != nil && current.State == metal3iov1alpha1.OSImageDownloadComplete {
+		return 0
+	}
+
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: metal3DeploymentPodSelector})
+	if err != nil {
+		r.Log.Info("failed to list the metal3 pods", "error", err.Error())
+		return osImageDownloadRecheck
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		data, err := r.fetchOperandMetrics(provisioning.OSImageDownloadProgressURL(pod.Status.PodIP))
+		if err != nil {
+			r.Log.Info("failed to read the OS image download progress", "pod", pod.Name, "error", err.Error())
+			continue
+		}
+		download, err := provisioning.ParseOSImageDownloadProgress(data, now)
+		if err != nil {
+			r.Log.Info("failed to read the OS image download progress", "pod", pod.Name, "error", err.Error())
+			continue
+		}
+		provStatus.OSImage.Download = download
+		if download.State == metal3iov1alpha1.OSImageDownloadComplete {
+			return 0
+		}
+		return osImageDownloadRecheck
+	}
+	return osImageDownloadRecheck
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newDownloadingMetal3Pod(name string, podIP string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{"k8s-app": "metal3", "controller": "metal3"},
+		},
+		Status: corev1.PodStatus{Phase: phase, PodIP: podIP},
+	}
+}
+
+func TestSetOSImageDownloadStatus(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:     "eth0",
+			ProvisioningIP:            "172.30.20.3",
+			ProvisioningNetworkCIDR:   "172.30.20.0/24",
+			ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+			ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkManaged,
+			ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-ootpa.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		newDownloadingMetal3Pod("metal3-old", "192.168.111.20", corev1.PodPending),
+		newDownloadingMetal3Pod("metal3-new", "192.168.111.21", corev1.PodRunning),
+	)
+	progress := map[string]string{
+		"http://192.168.111.21:6180/images/download-progress.json": `{"state": "Downloading", "bytesDownloaded": 512, "bytesTotal": 2048}`,
+	}
+	fetched := []string{}
+	reconciler.operandMetricsFetch = func(url string) ([]byte, error) {
+		fetched = append(fetched, url)
+		if body, ok := progress[url]; ok {
+			return []byte(body), nil
+		}
+		return nil, fmt.Errorf("%s is unreachable", url)
+	}
+
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	assert.Equal(t, osImageDownloadRecheck, reconciler.setOSImageDownloadStatus(status, &prov.Spec, now))
+	assert.Equal(t, []string{"http://192.168.111.21:6180/images/download-progress.json"}, fetched)
+	if assert.NotNil(t, status.OSImage.Download) {
+		assert.Equal(t, metal3iov1alpha1.OSImageDownloading, status.OSImage.Download.State)
+		assert.Equal(t, int64(512), status.OSImage.Download.BytesDownloaded)
+		assert.Equal(t, int32(25), *status.OSImage.Download.PercentComplete)
+	}
+
+	// The last progress is kept while the pod cannot be reached
+	delete(progress, "http://192.168.111.21:6180/images/download-progress.json")
+	assert.Equal(t, osImageDownloadRecheck, reconciler.setOSImageDownloadStatus(status, &prov.Spec, now.Add(time.Minute)))
+	assert.Equal(t, int64(512), status.OSImage.Download.BytesDownloaded)
+
+	progress["http://192.168.111.21:6180/images/download-progress.json"] = `{"state": "Complete", "bytesDownloaded": 2048, "bytesTotal": 2048}`
+	assert.Equal(t, time.Duration(0), reconciler.setOSImageDownloadStatus(status, &prov.Spec, now.Add(2*time.Minute)))
+	assert.Equal(t, metal3iov1alpha1.OSImageDownloadComplete, status.OSImage.Download.State)
+	assert.Equal(t, int32(100), *status.OSImage.Download.PercentComplete)
+
+	// A complete download is not read again
+	fetched = nil
+	assert.Equal(t, time.Duration(0), reconciler.setOSImageDownloadStatus(status, &prov.Spec, now.Add(3*time.Minute)))
+	assert.Empty(t, fetched)
+
+	// The progress is not reported when the image is prepared before the
+	// metal3 services start
+	prov.Spec.ConvertOSImageToRaw = true
+	assert.Equal(t, time.Duration(0), reconciler.setOSImageDownloadStatus(status, &prov.Spec, now))
+	assert.Nil(t, status.OSImage.Download)
+	assert.Empty(t, fetched)
+}
@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	testSpecOSImageURL   = "http://172.22.0.1/images/rhcos-45.qcow2.gz?sha256=abcd"
+	testStreamOSImageURL = "https://example.com/rhcos-46.qcow2.gz?sha256=1234"
+	testStreamOSVersion  = "46.82.202008181646-0"
+	testStream           = `{"architectures": {"x86_64": {"artifacts": {"openstack": {"release": "46.82.202008181646-0",
+"formats": {"qcow2.gz": {"disk": {"location": "https://example.com/rhcos-46.qcow2.gz", "uncompressed-sha256": "1234"}}}}}}}}`
+)
+
+func newOSImageReconciler(objs ...runtime.Object) *ProvisioningReconciler {
+	scheme := setUpSchemeForReconciler()
+	_ = corev1.AddToScheme(scheme)
+	return &ProvisioningReconciler{
+		Client:     fakeclient.NewFakeClientWithScheme(scheme, objs...),
+		KubeClient: fakekube.NewSimpleClientset(newArchMasterNode("amd64")),
+		Log:        ctrl.Log.WithName("controllers").WithName("Provisioning"),
+		Scheme:     scheme,
+	}
+}
+
+func newArchMasterNode(arch string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "master-0",
+			Labels: map[string]string{
+				"node-role.kubernetes.io/master": "",
+				corev1.LabelArchStable:           arch,
+			},
+		},
+	}
+}
+
+func newOSImageProvisioning(autoUpdate bool, status metal3iov1alpha1.OSImageStatus) *metal3iov1alpha1.Provisioning {
+	return &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: BaremetalProvisioningCR,
+		},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningOSDownloadURL: testSpecOSImageURL,
+			AutoUpdateOSImage:         autoUpdate,
+		},
+		Status: metal3iov1alpha1.ProvisioningStatus{
+			OSImage: status,
+		},
+	}
+}
+
+func TestResolveOSImage(t *testing.T) {
+	streamCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      provisioning.CoreOSBootImagesConfigMap,
+			Namespace: provisioning.CoreOSBootImagesNamespace,
+		},
+		Data: map[string]string{"stream": testStream},
+	}
+	invalidCM := streamCM.DeepCopy()
+	invalidCM.Data = map[string]string{"stream": "{"}
+
+	tCases := []struct {
+		name          string
+		prov          *metal3iov1alpha1.Provisioning
+		configMap     *corev1.ConfigMap
+		arch          string
+		expectedImage provisioning.OSImage
+		invalidSpec   bool
+	}{
+		{
+			name:          "AutoUpdateDisabled",
+			prov:          newOSImageProvisioning(false, metal3iov1alpha1.OSImageStatus{}),
+			configMap:     streamCM,
+			expectedImage: provisioning.OSImage{URL: testSpecOSImageURL},
+		},
+		{
+			name:          "AutoUpdateFromStream",
+			prov:          newOSImageProvisioning(true, metal3iov1alpha1.OSImageStatus{}),
+			configMap:     streamCM,
+			expectedImage: provisioning.OSImage{URL: testStreamOSImageURL, Version: testStreamOSVersion},
+		},
+		{
+			name:          "AutoUpdateMissingStream",
+			prov:          newOSImageProvisioning(true, metal3iov1alpha1.OSImageStatus{}),
+			expectedImage: provisioning.OSImage{URL: testSpecOSImageURL},
+		},
+		{
+			name: "AutoUpdateInvalidStreamKeepsCurrentImage",
+			prov: newOSImageProvisioning(true, metal3iov1alpha1.OSImageStatus{
+				URL:     "https://example.com/rhcos-45.qcow2.gz?sha256=5678",
+				Version: "45.82.202008010929-0",
+			}),
+			configMap: invalidCM,
+			expectedImage: provisioning.OSImage{
+				URL:     "https://example.com/rhcos-45.qcow2.gz?sha256=5678",
+				Version: "45.82.202008010929-0",
+			},
+		},
+		{
+			name:        "AutoUpdateMissingArchitecture",
+			prov:        newOSImageProvisioning(true, metal3iov1alpha1.OSImageStatus{}),
+			configMap:   streamCM,
+			arch:        "arm64",
+			invalidSpec: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objs := []runtime.Object{tc.prov}
+			if tc.configMap != nil {
+				objs = append(objs, tc.configMap)
+			}
+			reconciler := newOSImageReconciler(objs...)
+			if tc.arch != "" {
+				reconciler.KubeClient = fakekube.NewSimpleClientset(newArchMasterNode(tc.arch))
+			}
+			image, err := reconciler.resolveOSImage(tc.prov)
+			if tc.invalidSpec {
+				assert.True(t, errors.Is(err, provisioning.ErrInvalidSpec), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedImage, *image)
+		})
+	}
+}
+
+func TestSetOSImageStatus(t *testing.T) {
+	tCases := []struct {
+		name           string
+		current        metal3iov1alpha1.OSImageStatus
+		image          provisioning.OSImage
+		expectedStatus metal3iov1alpha1.OSImageStatus
+	}{
+		{
+			name:  "InitialImage",
+			image: provisioning.OSImage{URL: testSpecOSImageURL},
+			expectedStatus: metal3iov1alpha1.OSImageStatus{
+				URL: testSpecOSImageURL,
+			},
+		},
+		{
+			name:    "UpdatedImage",
+			current: metal3iov1alpha1.OSImageStatus{URL: testSpecOSImageURL},
+			image:   provisioning.OSImage{URL: testStreamOSImageURL, Version: testStreamOSVersion},
+			expectedStatus: metal3iov1alpha1.OSImageStatus{
+				URL:         testStreamOSImageURL,
+				Version:     testStreamOSVersion,
+				PreviousURL: testSpecOSImageURL,
+			},
+		},
+		{
+			name: "UnchangedImage",
+			current: metal3iov1alpha1.OSImageStatus{
+				URL:         testStreamOSImageURL,
+				Version:     testStreamOSVersion,
+				PreviousURL: testSpecOSImageURL,
+			},
+			image: provisioning.OSImage{URL: testStreamOSImageURL, Version: testStreamOSVersion},
+			expectedStatus: metal3iov1alpha1.OSImageStatus{
+				URL:         testStreamOSImageURL,
+				Version:     testStreamOSVersion,
+				PreviousURL: testSpecOSImageURL,
+			},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := newOSImageReconciler()
+			status := &metal3iov1alpha1.ProvisioningStatus{OSImage: tc.current}
+			reconciler.setOSImageStatus(status, &tc.image)
+			assert.Equal(t, tc.expectedStatus, status.OSImage)
+		})
+	}
+}
+
+func TestUpdateProvisioningStatus(t *testing.T) {
+	prov := newOSImageProvisioning(true, metal3iov1alpha1.OSImageStatus{})
+	reconciler := newOSImageReconciler(prov)
+
+	newStatus := prov.Status.DeepCopy()
+	newStatus.OSImage.URL = testStreamOSImageURL
+	err := reconciler.updateProvisioningStatus(prov, newStatus)
+	assert.NoError(t, err)
+
+	updated := &metal3iov1alpha1.Provisioning{}
+	err = reconciler.Client.Get(context.Background(), types.NamespacedName{Name: BaremetalProvisioningCR}, updated)
+	assert.NoError(t, err)
+	assert.Equal(t, testStreamOSImageURL, updated.Status.OSImage.URL)
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=update
+
+// labelNamespacePodSecurity sets the Pod Security labels the
+// PodSecurityMode needs on ComponentNamespace. The labels are left in
+// place when the mode changes back, as the namespace may have carried
+// them before.
+func (r *ProvisioningReconciler) labelNamespacePodSecurity(spec *metal3iov1alpha1.ProvisioningSpec) error {
+	labels := provisioning.GetPodSecurityNamespaceLabels(spec)
+	if len(labels) == 0 {
+		return nil
+	}
+	namespaces := r.KubeClient.CoreV1().Namespaces()
+	namespace, err := namespaces.Get(context.Background(), ComponentNamespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	changed := false
+	for key, value := range labels {
+		if namespace.Labels[key] != value {
+			if namespace.Labels == nil {
+				namespace.Labels = map[string]string{}
+			}
+			namespace.Labels[key] = value
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	_, err = namespaces.Update(context.Background(), namespace, metav1.UpdateOptions{})
+	return err
+}
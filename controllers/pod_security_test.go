@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestLabelNamespacePodSecurity(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR}}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	kubeClient := fakekube.NewSimpleClientset(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: ComponentNamespace, Labels: map[string]string{
+			"pod-security.kubernetes.io/enforce": "privileged",
+			"openshift.io/cluster-monitoring":    "true",
+		}},
+	})
+	reconciler.KubeClient = kubeClient
+	getLabels := func() map[string]string {
+		namespace, err := kubeClient.CoreV1().Namespaces().Get(context.Background(), ComponentNamespace, metav1.GetOptions{})
+		assert.NoError(t, err)
+		return namespace.Labels
+	}
+
+	// The namespace is left alone by default
+	assert.NoError(t, reconciler.labelNamespacePodSecurity(&prov.Spec))
+	assert.Len(t, getLabels(), 2)
+
+	prov.Spec.PodSecurityMode = metal3iov1alpha1.PodSecurityModeRestricted
+	assert.NoError(t, reconciler.labelNamespacePodSecurity(&prov.Spec))
+	assert.Equal(t, map[string]string{
+		"pod-security.kubernetes.io/enforce":             "privileged",
+		"pod-security.kubernetes.io/audit":               "restricted",
+		"security.openshift.io/scc.podSecurityLabelSync": "false",
+		"openshift.io/cluster-monitoring":                "true",
+	}, getLabels())
+
+	// Nothing is updated once labelled
+	kubeClient.ClearActions()
+	assert.NoError(t, reconciler.labelNamespacePodSecurity(&prov.Spec))
+	for _, action := range kubeClient.Actions() {
+		assert.NotEqual(t, "update", action.GetVerb())
+	}
+}
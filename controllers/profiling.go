@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// profileTimeFormat names the profiles of a reconcile after its start, so
+// that they sort in the order they were taken
+const profileTimeFormat = "20060102T150405.000000000"
+
+// profilingServer serves the pprof endpoints on a listener of its own
+type profilingServer struct {
+	addr string
+}
+
+func newProfilingMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Start serves the pprof endpoints until the manager stops
+func (s *profilingServer) Start(stop <-chan struct{}) error {
+	server := &http.Server{Addr: s.addr, Handler: newProfilingMux()}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	select {
+	case <-stop:
+		return server.Shutdown(context.Background())
+	case err := <-errs:
+		return err
+	}
+}
+
+// NeedLeaderElection lets every replica be profiled, not only the leader
+func (s *profilingServer) NeedLeaderElection() bool {
+	return false
+}
+
+// SetupProfilingHandlers serves the pprof endpoints under /debug/pprof/ on
+// the given address, which has to be a loopback one: the endpoints are
+// only reached through kubectl port-forward, as the metrics endpoint is
+// not behind any authorization when the operator runs without its auth
+// proxy
+func SetupProfilingHandlers(mgr ctrl.Manager, addr string) error {
+	if err := validateProfilingAddr(addr); err != nil {
+		return err
+	}
+	return mgr.Add(&profilingServer{addr: addr})
+}
+
+func validateProfilingAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("the profiling address %s is not a loopback address", addr)
+	}
+	return nil
+}
+
+// ReconcileProfiler writes a CPU and a heap profile of each reconcile of
+// the Provisioning controller to Dir, keeping the most recent ones
+type ReconcileProfiler struct {
+	Dir string
+	// Keep is the number of reconciles whose profiles are kept
+	Keep int
+	Log  logr.Logger
+
+	// lock serializes the profiles, as only one CPU profile runs at a time
+	lock sync.Mutex
+	// now replaces time.Now when set
+	now func() time.Time
+}
+
+// Start starts the profiles of the reconcile of the named resource, and
+// returns the function writing them once it returns. A reconcile that
+// cannot be profiled, e.g. while a CPU profile is served by the pprof
+// endpoints, is not.
+func (p *ReconcileProfiler) Start(name string) func() {
+	p.lock.Lock()
+	now := time.Now
+	if p.now != nil {
+		now = p.now
+	}
+	prefix := filepath.Join(p.Dir, fmt.Sprintf("reconcile-%s-%s", now().UTC().Format(profileTimeFormat), name))
+
+	cpu, err := os.Create(prefix + ".cpu.pprof")
+	if err == nil {
+		err = runtimepprof.StartCPUProfile(cpu)
+		if err != nil {
+			cpu.Close()
+			os.Remove(cpu.Name())
+		}
+	}
+	if err != nil {
+		p.Log.Info("unable to profile the reconcile", "name", name, "error", err.Error())
+		p.lock.Unlock()
+		return func() {}
+	}
+
+	return func() {
+		defer p.lock.Unlock()
+		runtimepprof.StopCPUProfile()
+		cpu.Close()
+		if err := p.writeHeapProfile(prefix + ".heap.pprof"); err != nil {
+			p.Log.Info("unable to write the heap profile of the reconcile", "name", name, "error", err.Error())
+		}
+		if err := p.prune(); err != nil {
+			p.Log.Info("unable to remove the old reconcile profiles", "error", err.Error())
+		}
+	}
+}
+
+func (p *ReconcileProfiler) writeHeapProfile(path string) error {
+	heap, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer heap.Close()
+	// The heap profile is as of the last garbage collection
+	runtime.GC()
+	return runtimepprof.WriteHeapProfile(heap)
+}
+
+// prune removes the profiles of all but the Keep most recent reconciles
+func (p *ReconcileProfiler) prune() error {
+	if p.Keep <= 0 {
+		return nil
+	}
+	for _, kind := range []string{"cpu", "heap"} {
+		profiles, err := filepath.Glob(filepath.Join(p.Dir, "reconcile-*."+kind+".pprof"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(profiles)
+		for len(profiles) > p.Keep {
+			if err := os.Remove(profiles[0]); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			profiles = profiles[1:]
+		}
+	}
+	return nil
+}
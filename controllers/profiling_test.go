@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func listProfiles(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}
+
+func TestReconcileProfiler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	reconciles := 0
+	profiler := &ReconcileProfiler{
+		Dir:  dir,
+		Keep: 2,
+		Log:  ctrl.Log.WithName("profiler"),
+		now: func() time.Time {
+			return start.Add(time.Duration(reconciles) * time.Second)
+		},
+	}
+	for ; reconciles < 3; reconciles++ {
+		profiler.Start("provisioning-configuration")()
+	}
+
+	assert.Equal(t, []string{
+		"reconcile-20210301T100001.000000000-provisioning-configuration.cpu.pprof",
+		"reconcile-20210301T100001.000000000-provisioning-configuration.heap.pprof",
+		"reconcile-20210301T100002.000000000-provisioning-configuration.cpu.pprof",
+		"reconcile-20210301T100002.000000000-provisioning-configuration.heap.pprof",
+	}, listProfiles(t, dir))
+	for _, name := range listProfiles(t, dir) {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NotZero(t, info.Size(), name)
+	}
+}
+
+func TestReconcileProfilerBusy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile-profiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A CPU profile served by the pprof endpoints is running
+	if err := pprof.StartCPUProfile(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	defer pprof.StopCPUProfile()
+
+	profiler := &ReconcileProfiler{Dir: dir, Keep: 2, Log: ctrl.Log.WithName("profiler")}
+	profiler.Start("provisioning-configuration")()
+
+	assert.Empty(t, listProfiles(t, dir))
+}
+
+func TestProfilingServer(t *testing.T) {
+	assert.NoError(t, validateProfilingAddr("127.0.0.1:6060"))
+	assert.NoError(t, validateProfilingAddr("[::1]:6060"))
+	assert.NoError(t, validateProfilingAddr("localhost:6060"))
+	assert.EqualError(t, validateProfilingAddr(":6060"), "the profiling address :6060 is not a loopback address")
+	assert.EqualError(t, validateProfilingAddr("0.0.0.0:6060"), "the profiling address 0.0.0.0:6060 is not a loopback address")
+
+	server := httptest.NewServer(newProfilingMux())
+	defer server.Close()
+	response, err := http.Get(server.URL + "/debug/pprof/cmdline")
+	if assert.NoError(t, err) {
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+}
@@ -18,15 +18,26 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	osconfigv1 "github.com/openshift/api/config/v1"
 	osclientset "github.com/openshift/client-go/config/clientset/versioned"
@@ -34,17 +45,20 @@ import (
 	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
 )
 
+// ComponentNamespace is the namespace of the metal3 services and of the
+// objects the operator reads their configuration from
+var ComponentNamespace = "openshift-machine-api"
+
 const (
-	// ComponentNamespace is namespace where CBO resides
-	ComponentNamespace = "openshift-machine-api"
 	// ComponentName is the full name of CBO
 	ComponentName = "cluster-baremetal-operator"
 	// BaremetalProvisioningCR is the name of the provisioning resource
-	BaremetalProvisioningCR = "provisioning-configuration"
-	// ContainerImagesFile volume mounted file containing the images configmap
-	ContainerImagesFile = "/etc/cluster-baremetal-operator/images/images.json"
+	BaremetalProvisioningCR = metal3iov1alpha1.ProvisioningSingletonName
 )
 
+// ContainerImagesFile volume mounted file containing the images configmap
+var ContainerImagesFile = "/etc/cluster-baremetal-operator/images/images.json"
+
 // ProvisioningReconciler reconciles a Provisioning object
 type ProvisioningReconciler struct {
 	// This client, initialized using mgr.Client() above, is a split client
@@ -54,14 +68,50 @@ type ProvisioningReconciler struct {
 	Log            logr.Logger
 	OSClient       osclientset.Interface
 	EventRecorder  record.EventRecorder
-	kubeClient     kubernetes.Interface
+	KubeClient     kubernetes.Interface
 	ReleaseVersion string
+	// CreateNamespace creates ComponentNamespace when it is missing
+	CreateNamespace bool
+	// Profiler profiles the reconciles when set
+	Profiler *ReconcileProfiler
+
+	// operandFailures counts the consecutive reconciles that found the
+	// metal3 pod failing, to back off retries
+	operandFailures int
+	// operandHealthProbe replaces the HTTP health checks of the metal3
+	// pods when set
+	operandHealthProbe func(url string) error
+	// operandMetricsFetch replaces the HTTP requests reading the metrics
+	// of the metal3 pods when set
+	operandMetricsFetch func(url string) ([]byte, error)
+	// ironicClient replaces the Ironic API client of the conductor groups,
+	// of the orphan cleanup and of the deploy templates when set
+	ironicClient func(podIP string) (ironicAPIClient, error)
+	// upgradeBlockers are the operations in progress that block cluster
+	// upgrades, keyed by the reason reported in the Upgradeable condition
+	upgradeBlockers map[StatusReason]string
+	// startupTimingReported holds the metal3 pods whose startup timing
+	// has been reported
+	startupTimingReported map[types.UID]bool
 }
 
 // +kubebuilder:rbac:groups=metal3.io,resources=provisionings,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=metal3.io,resources=provisionings/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=create;update
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
 
-func (r *ProvisioningReconciler) isEnabled() (bool, error) {
+// The baremetal-operator in the metal3 pod runs with the operator service
+// account, and needs access to the BareMetalHosts and their credentials.
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=metal3.io,resources=baremetalhosts/status;baremetalhosts/finalizers,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// isEnabled returns the platform of the cluster, and whether the operator
+// runs on it. Besides bare metal clusters, the operator runs on any
+// platform when the Provisioning singleton asks for it.
+func (r *ProvisioningReconciler) isEnabled() (osconfigv1.PlatformType, bool, error) {
 	ctx := context.Background()
 
 	infra := &osconfigv1.Infrastructure{}
@@ -69,17 +119,28 @@ func (r *ProvisioningReconciler) isEnabled() (bool, error) {
 		Name: "cluster",
 	}, infra)
 	if err != nil {
-		return false, errors.Wrap(err, "unable to determine Platform")
+		return "", false, errors.Wrap(err, "unable to determine Platform")
 	}
+	platform := provisioning.GetPlatformType(infra)
 
-	// Disable ourselves on platforms other than bare metal
-	if infra.Status.Platform != osconfigv1.BareMetalPlatformType {
-		r.Log.V(1).Info("disabled", "platform", infra.Status.Platform)
-		return false, nil
+	if platform == osconfigv1.BareMetalPlatformType {
+		r.Log.V(1).Info("enabled", "platform", platform)
+		return platform, true, nil
+	}
+
+	prov := &metal3iov1alpha1.Provisioning{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: BaremetalProvisioningCR}, prov)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", false, errors.Wrap(err, "unable to read Provisioning CR")
+	}
+	if err == nil && prov.Spec.EnableOnAnyPlatform {
+		r.Log.V(1).Info("enabled by Provisioning CR", "platform", platform)
+		return platform, true, nil
 	}
 
-	r.Log.V(1).Info("enabled", "platform", infra.Status.Platform)
-	return true, nil
+	// Disable ourselves on platforms other than bare metal
+	r.Log.V(1).Info("disabled", "platform", platform)
+	return platform, false, nil
 }
 
 func (r *ProvisioningReconciler) readProvisioningCR(req ctrl.Request) (*metal3iov1alpha1.Provisioning, error) {
@@ -105,8 +166,11 @@ func (r *ProvisioningReconciler) readProvisioningCR(req ctrl.Request) (*metal3io
 // resource changes
 func (r *ProvisioningReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	//log := r.Log.WithValues("provisioning", req.NamespacedName)
+	if r.Profiler != nil {
+		defer r.Profiler.Start(req.Name)()
+	}
 
-	enabled, err := r.isEnabled()
+	platform, enabled, err := r.isEnabled()
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "could not determine whether to run")
 	}
@@ -121,6 +185,10 @@ func (r *ProvisioningReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		return ctrl.Result{}, nil
 	}
 
+	if req.Name != BaremetalProvisioningCR {
+		return r.reconcileProvisioningDomain(req, platform)
+	}
+
 	baremetalConfig, err := r.readProvisioningCR(req)
 	if err != nil {
 		// Error reading the object - requeue the request.
@@ -132,21 +200,51 @@ func (r *ProvisioningReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		r.Log.V(1).Info("Provisioning CR not found")
 		return ctrl.Result{}, nil
 	}
-	if err := provisioning.ValidateBaremetalProvisioningConfig(baremetalConfig); err != nil {
-		// Provisioning configuration is not valid.
-		// Requeue request.
-		r.Log.Error(err, "invalid config in Provisioning CR")
-		err = r.updateCOStatus(ReasonInvalidConfiguration, err.Error(), "Unable to apply Provisioning CR: invalid configuration")
+	// The spec of a disabled instance is not needed to tear metal3 down,
+	// so it is not validated
+	if !provisioning.IsProvisioningEnabled(&baremetalConfig.Spec) {
+		busyHosts, err := r.disableProvisioning(baremetalConfig)
 		if err != nil {
-			return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Degraded state: %v", clusterOperatorName, err)
+			return r.reconcileError(errors.Wrap(err, "failed to tear metal3 down"), ReasonEmpty, "")
+		}
+		if err := r.setUpgradeBlocker(ReasonHostsProvisioning, busyHostsMessage(busyHosts)); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update Upgradeable condition")
+		}
+		if len(busyHosts) > 0 {
+			r.Log.Info("waiting to tear metal3 down", "reason", busyHostsMessage(busyHosts))
+			if err := r.updateCOStatus(ReasonSyncing, "", "Waiting to tear metal3 down: "+busyHostsMessage(busyHosts)); err != nil {
+				return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Syncing state: %v", clusterOperatorName, err)
+			}
+			// The hosts are watched, so the end of their operations is noticed
+			return ctrl.Result{}, nil
+		}
+		if err := r.updateCOStatus(ReasonProvisioningDisabled, provisioningDisabledMessage, ""); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in ProvisioningDisabled state: %v", clusterOperatorName, err)
 		}
-		// Temporarily not requeuing request
 		return ctrl.Result{}, nil
 	}
+	// Instances created without the webhook are not normalized yet
+	if err := provisioning.NormalizeProvisioningSpec(&baremetalConfig.Spec); err != nil {
+		return r.reconcileError(provisioning.NewInvalidSpecError(err), ReasonInvalidConfiguration, "Unable to apply Provisioning CR: invalid configuration")
+	}
+	if err := provisioning.ValidateBaremetalProvisioningConfig(baremetalConfig); err != nil {
+		return r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: invalid configuration")
+	}
+	if err := provisioning.ValidatePlatformSupport(baremetalConfig, platform); err != nil {
+		// Deploying anyway would leave the metal3 pods crash looping
+		return r.reconcileError(err, ReasonUnsupportedConfiguration, "Unable to apply Provisioning CR: unsupported on this platform")
+	}
+	featureSet, err := r.getFeatureSet()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to read cluster feature set")
+	}
+	if err := provisioning.ValidateFeatureSet(baremetalConfig, featureSet); err != nil {
+		return r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: tech preview features not enabled")
+	}
 
 	// Read container images from Config Map
-	var containerImages Images
-	if err := GetContainerImages(&containerImages, ContainerImagesFile); err != nil {
+	var containerImages provisioning.Images
+	if err := provisioning.GetContainerImages(&containerImages, ContainerImagesFile); err != nil {
 		// Images config map is not valid
 		// Provisioning configuration is not valid.
 		// Requeue request.
@@ -158,23 +256,505 @@ func (r *ProvisioningReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error
 		return ctrl.Result{}, err
 	}
 
+	if err := r.ensureComponentNamespace(); err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to create the metal3 namespace"), ReasonEmpty, "")
+	}
+	if err := r.labelNamespacePodSecurity(&baremetalConfig.Spec); err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to label the metal3 namespace"), ReasonEmpty, "")
+	}
+	if err := r.applyOperatorFlowSchema(); err != nil {
+		// The requests are only queued with those of the other service
+		// accounts meanwhile
+		r.Log.Info("failed to apply the flow schema of the operator", "error", err.Error())
+	}
+
+	// Take over the metal3 resources of the machine-api-operator before
+	// creating ours, so that two Ironics never run at once
+	migrated, err := r.migrateLegacyMetal3()
+	if err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to migrate legacy metal3 resources"), ReasonEmpty, "")
+	}
+	// Restarting Ironic would fail the deployment or cleaning of hosts
+	busyHosts, err := r.listBusyHosts()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list BareMetalHosts")
+	}
+	if err := r.setUpgradeBlocker(ReasonHostsProvisioning, busyHostsMessage(busyHosts)); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update Upgradeable condition")
+	}
+	takeoverMsg := ""
+	if !migrated {
+		takeoverMsg = "the metal3 resources of the machine-api-operator are being taken over"
+	}
+	if err := r.setUpgradeBlocker(ReasonLegacyTakeoverInProgress, takeoverMsg); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update Upgradeable condition")
+	}
+	if !migrated {
+		r.Log.Info("waiting for the machine-api-operator metal3 resources to be taken over")
+		if err := r.updateCOStatus(ReasonSyncing, "", "Taking over metal3 resources from machine-api-operator"); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Syncing state: %v", clusterOperatorName, err)
+		}
+		return ctrl.Result{RequeueAfter: legacyMigrationRequeueAfter}, nil
+	}
+
+	if err := r.runRequestedAction(baremetalConfig, time.Now()); err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to run requested action"), ReasonEmpty, "")
+	}
+
+	// The Auto network is handled as the Managed one it resolves to
+	var detectedNetwork *metal3iov1alpha1.DetectedProvisioningNetwork
+	if provisioning.IsAutoProvisioningNetwork(&baremetalConfig.Spec) {
+		resolved, detected, err := r.resolveAutoProvisioningNetwork(baremetalConfig, &containerImages)
+		if errors.Is(err, provisioning.ErrInvalidSpec) {
+			if _, err := r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: provisioning network not detected"); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: networkDetectionRequeueAfter}, nil
+		}
+		if err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to detect provisioning network")
+		}
+		if resolved == nil {
+			if err := r.updateCOStatus(ReasonSyncing, "", "Detecting the provisioning network"); err != nil {
+				return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Syncing state: %v", clusterOperatorName, err)
+			}
+			// The detection pod is watched, but the nodes are not
+			return ctrl.Result{RequeueAfter: networkDetectionRequeueAfter}, nil
+		}
+		baremetalConfig, detectedNetwork = resolved, detected
+	}
+
 	//Create Secrets needed for Metal3 deployment
-	if err := provisioning.CreateMariadbPasswordSecret(r.kubeClient.CoreV1(), ComponentNamespace); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "failed to create Mariadb password")
+	if err := provisioning.CreateMariadbPasswordSecret(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to create Mariadb password"), ReasonEmpty, "")
+	}
+	if err := provisioning.CreateIronicPasswordSecret(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to create Ironic password"), ReasonEmpty, "")
+	}
+	if err := provisioning.CreateInspectorPasswordSecret(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to create Inspector password"), ReasonEmpty, "")
+	}
+	if baremetalConfig.Spec.ImageServerHTTPS {
+		if err := provisioning.CreateImageServerTlsSecret(r.KubeClient.CoreV1(), ComponentNamespace, baremetalConfig.Spec.ProvisioningIP,
+			provisioning.GetCertificateExpiryWarning(&baremetalConfig.Spec)); err != nil {
+			return r.reconcileError(errors.Wrap(err, "failed to create image server TLS certificate"), ReasonEmpty, "")
+		}
+	}
+	if err := provisioning.ApplyOperandSecretsMetadata(r.KubeClient.CoreV1(), ComponentNamespace, &baremetalConfig.Spec); err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to set metadata of secrets"), ReasonEmpty, "")
+	}
+
+	if baremetalConfig.Spec.Maintenance {
+		busyHosts, err := r.enterMaintenance(baremetalConfig)
+		if err != nil {
+			return r.reconcileError(errors.Wrap(err, "failed to scale down metal3"), ReasonEmpty, "")
+		}
+		if len(busyHosts) > 0 {
+			r.Log.Info("waiting to scale metal3 down for maintenance", "reason", busyHostsMessage(busyHosts))
+			if err := r.updateCOStatus(ReasonSyncing, "", "Waiting to scale metal3 down for maintenance: "+busyHostsMessage(busyHosts)); err != nil {
+				return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Syncing state: %v", clusterOperatorName, err)
+			}
+			// The hosts are watched, so the end of their operations is noticed
+			return ctrl.Result{}, nil
+		}
+		if err := r.updateCOStatus(ReasonMaintenance, maintenanceMessage, ""); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Maintenance state: %v", clusterOperatorName, err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Without eligible nodes the metal3 pods would stay pending forever,
+	// which is only possible when running on selected workers
+	nodes, err := r.listProvisioningNodes(&baremetalConfig.Spec)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list provisioning nodes")
+	}
+	if len(nodes) == 0 {
+		msg := fmt.Sprintf("no node matches the metal3 node selector %v", provisioning.GetMetal3NodeSelector(&baremetalConfig.Spec))
+		r.Log.Info("unable to place metal3 pods", "reason", msg)
+		if err := r.updateCOStatus(ReasonNoProvisioningNodes, msg, ""); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Degraded state: %v", clusterOperatorName, err)
+		}
+		// Nodes are not watched, so check again later
+		return ctrl.Result{RequeueAfter: noProvisioningNodesRequeueAfter}, nil
+	}
+
+	// Pre-flight check so that a missing interface is reported clearly
+	// instead of the metal3 pod crash looping on some of the nodes.
+	// Nodes on which the check is still running do not block the deployment.
+	ifaceCheck, err := r.checkProvisioningInterface(baremetalConfig, &containerImages, time.Now())
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to check provisioning interface")
+	}
+	if len(ifaceCheck.missing) > 0 {
+		// The failed checks run again once they expire, and the check pods
+		// are watched, so a fixed interface is noticed
+		err := provisioning.NewInvalidSpecError(errors.New(ifaceCheck.message(baremetalConfig.Spec.ProvisioningInterface)))
+		result, err := r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: provisioning interface not found")
+		if err == nil {
+			result.RequeueAfter = ifaceCheck.recheck
+		}
+		return result, err
+	}
+
+	if err := r.checkInspectorRules(baremetalConfig); err != nil {
+		if errors.Is(err, provisioning.ErrInvalidSpec) {
+			// The ConfigMap is watched, so fixed rules are noticed
+			return r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: invalid inspector rules")
+		}
+		return ctrl.Result{}, errors.Wrap(err, "failed to read inspector rules")
+	}
+
+	imagePullSecretHash, err := r.syncImagePullSecret(&baremetalConfig.Spec)
+	if err != nil {
+		if errors.Is(err, provisioning.ErrInvalidSpec) {
+			// The Secret is watched, so its creation is noticed
+			return r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: invalid image pull secret")
+		}
+		return ctrl.Result{}, errors.Wrap(err, "failed to write image pull credentials")
+	}
+
+	osImage, err := r.resolveOSImage(baremetalConfig)
+	if err != nil {
+		// The coreos-bootimages ConfigMap is watched, so an update adding
+		// the architecture is noticed
+		return r.reconcileError(errors.Wrap(err, "failed to determine provisioning OS image"), ReasonEmpty, "")
+	}
+
+	// The spec is never modified; the resolved OS image is only used to
+	// render the metal3 deployment so that the image cache is refreshed.
+	spec := baremetalConfig.Spec.DeepCopy()
+	if osImage.URL != spec.ProvisioningOSDownloadURL {
+		// The checksum and format of the spec image are not the ones
+		// of the image from the stream metadata
+		spec.ProvisioningOSImage = nil
+	}
+	spec.ProvisioningOSDownloadURL = osImage.URL
+	// The metal3 pods keep running on the previous provisioning network
+	// until the hosts they are provisioning or cleaning are done, and no
+	// other host starts meanwhile
+	migration, err := r.reconcileNetworkMigration(baremetalConfig, spec, len(busyHosts) > 0)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	draining := isNetworkMigrationDraining(migration)
+	if err := provisioning.SetDefaultDHCPRange(spec); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to compute default DHCP range")
+	}
+
+	servingCertsHash, issued, err := r.syncServingCerts(baremetalConfig)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to sync serving certificates")
+	}
+	if !issued {
+		return ctrl.Result{RequeueAfter: servingCertsRequeueAfter}, nil
+	}
+
+	profile, err := r.getTopologyProfile()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to determine control plane topology")
+	}
+	if profile == metal3iov1alpha1.TopologyProfileSingleNode && len(spec.ConductorGroups) > 0 {
+		// Conductors never share a node with the metal3 pod
+		err := provisioning.NewInvalidSpecError(errors.New("ConductorGroups are not supported on single node clusters"))
+		return r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: unsupported on a single node")
+	}
+
+	ha, err := r.reconcileHighAvailability(baremetalConfig, spec, nodes)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	metal3Deployment := provisioning.NewMetal3Deployment(ComponentNamespace, &containerImages, spec)
+	metal3Deployment.Spec.Replicas = pointer.Int32Ptr(getPlaceableReplicas(spec, nodes))
+	provisioning.ApplyTopologyProfile(&metal3Deployment.Spec.Template.Spec, profile)
+	if servingCertsHash != "" {
+		provisioning.SetServingCertsHash(&metal3Deployment.Spec.Template, servingCertsHash)
+	}
+	if imagePullSecretHash != "" {
+		provisioning.SetImagePullSecretHash(&metal3Deployment.Spec.Template, imagePullSecretHash)
+	}
+	if refreshGeneration := provisioning.GetImageRefreshGeneration(baremetalConfig); refreshGeneration != "" {
+		provisioning.SetImageRefreshGeneration(&metal3Deployment.Spec.Template, refreshGeneration)
+	}
+	var dnsmasqDaemonSet *appsv1.DaemonSet
+	if provisioning.IsDnsmasqRequired(spec) {
+		dnsmasqDaemonSet = provisioning.NewDnsmasqDaemonSet(ComponentNamespace, &containerImages, spec)
+		provisioning.ApplyTopologyProfile(&dnsmasqDaemonSet.Spec.Template.Spec, profile)
+	}
+	rolloutHash, err := setOperandsRolloutHash(metal3Deployment, dnsmasqDaemonSet)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to compute rollout hash")
+	}
+
+	history, err := provisioning.GetRevisionHistory(r.KubeClient.CoreV1(), ComponentNamespace)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to read operand revision history")
+	}
+	revision := r.selectOperandRevision(baremetalConfig, history,
+		provisioning.NewOperandRevision(rolloutHash, metal3Deployment, dnsmasqDaemonSet))
+	if revision == nil {
+		err := provisioning.NewInvalidSpecError(errors.Errorf("revision %q requested by the %s annotation is not in the revision history",
+			baremetalConfig.Annotations[provisioning.RollbackRevisionAnnotation], provisioning.RollbackRevisionAnnotation))
+		return r.reconcileError(err, ReasonInvalidConfiguration, "Unable to apply Provisioning CR: unknown revision")
+	}
+	metal3Deployment, dnsmasqDaemonSet, rolloutHash = revision.Deployment, revision.DaemonSet, revision.RolloutHash
+
+	// Starting a second conductor next to a standalone Ironic, or another
+	// DHCP server, would leave both answering the DHCP and PXE requests
+	// of the hosts
+	conflicts, err := r.checkIronicConflicts(baremetalConfig, &containerImages, nodes, metal3Deployment.Name, time.Now())
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to check for another Ironic")
+	}
+	if len(conflicts.conflicts) > 0 {
+		msg := conflicts.message()
+		r.Log.Info("unable to deploy metal3", "reason", msg)
+		if err := r.updateCOStatus(ReasonIronicConflict, msg, ""); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Degraded state: %v", clusterOperatorName, err)
+		}
+		return ctrl.Result{RequeueAfter: ironicConflictRequeueAfter}, nil
+	}
+	if conflicts.pending && !conflicts.deployed {
+		// The check pods are watched, so their completion is noticed
+		return ctrl.Result{}, nil
+	}
+
+	rollout, err := r.rolloutMetal3Deployment(baremetalConfig, &containerImages, spec, metal3Deployment, rolloutHash)
+	if err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to roll out metal3 deployment"), ReasonEmpty, "")
+	}
+	if rollout.prePulling {
+		return ctrl.Result{RequeueAfter: imagePrePullRequeueAfter}, nil
+	}
+	rolloutMsg := ""
+	if rollout.verifying {
+		rolloutMsg = fmt.Sprintf("metal3 revision %s is being verified", rolloutHash)
+	}
+	if err := r.setUpgradeBlocker(ReasonOperandRolloutInProgress, rolloutMsg); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update Upgradeable condition")
+	}
+	if err := r.recordOperandRevision(baremetalConfig, history, *revision); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to record operand revision")
+	}
+	if dnsmasqDaemonSet != nil {
+		if err := controllerutil.SetControllerReference(baremetalConfig, dnsmasqDaemonSet, r.Scheme); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to set owner of dnsmasq daemonset")
+		}
+		if _, err := provisioning.ApplyDnsmasqDaemonSet(r.KubeClient.AppsV1(), dnsmasqDaemonSet); err != nil {
+			return r.reconcileError(errors.Wrap(err, "failed to apply dnsmasq daemonset"), ReasonEmpty, "")
+		}
+	} else if err := provisioning.DeleteDnsmasqDaemonSet(r.KubeClient.AppsV1(), ComponentNamespace); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to delete dnsmasq daemonset")
+	}
+	if err := r.ensureIronicAPIExposure(baremetalConfig); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to expose Ironic API")
+	}
+	if err := r.ensureProvisioningInterfaceMachineConfig(baremetalConfig); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to configure the provisioning interface")
+	}
+	if err := r.ensureIgnitionOverrides(baremetalConfig); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to publish ignition overrides")
+	}
+	conductorGroups, err := r.syncConductorGroups(baremetalConfig, &containerImages, spec)
+	if err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to deploy conductor groups"), ReasonEmpty, "")
+	}
+
+	if rollout.failure != "" {
+		// The previous revision keeps running, so the failure is not
+		// overridden by the state of the metal3 pods
+		return r.reconcileError(provisioning.NewOperandDegradedError(errors.New(rollout.failure)), ReasonOperandRolloutFailed, "")
+	}
+
+	failure, err := r.checkMetal3Pods()
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to check metal3 pods")
 	}
-	if err := provisioning.CreateIronicPasswordSecret(r.kubeClient.CoreV1(), ComponentNamespace); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "failed to create Ironic password")
+	if failure != nil {
+		r.operandFailures++
+		r.Log.Info("metal3 pod is failing", "reason", failure.reason, "container", failure.container, "message", failure.message)
+		if err := r.updateCOStatus(failure.reason, failure.String(), ""); err != nil {
+			return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Degraded state: %v", clusterOperatorName, err)
+		}
+	} else {
+		r.operandFailures = 0
+		if draining {
+			msg := networkMigrationMessage(migration, busyHosts)
+			r.Log.Info("waiting to move the provisioning network", "reason", msg)
+			if err := r.updateCOStatus(ReasonSyncing, "", msg); err != nil {
+				return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Syncing state: %v", clusterOperatorName, err)
+			}
+		}
 	}
-	if err := provisioning.CreateInspectorPasswordSecret(r.kubeClient.CoreV1(), ComponentNamespace); err != nil {
-		return ctrl.Result{}, errors.Wrap(err, "failed to create Inspector password")
+	if err := r.reportStartupTiming(baremetalConfig); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to report metal3 pod startup timing")
 	}
 
-	return ctrl.Result{}, nil
+	newStatus := baremetalConfig.Status.DeepCopy()
+	newStatus.ObservedGeneration = baremetalConfig.Generation
+	newStatus.RolloutHash = rolloutHash
+	newStatus.TopologyProfile = profile
+	newStatus.DetectedNetwork = detectedNetwork
+	newStatus.ConductorGroups = conductorGroups
+	r.setOSImageStatus(newStatus, osImage)
+	downloadRecheck := r.setOSImageDownloadStatus(newStatus, spec, time.Now())
+	// The spec does not describe the running operands when a previous
+	// revision is requested, or while they are kept on the previous network
+	if rollout.healthy && failure == nil && !draining && baremetalConfig.Annotations[provisioning.RollbackRevisionAnnotation] == "" {
+		setLastSuccessfulConfiguration(newStatus, baremetalConfig, rolloutHash, time.Now())
+	}
+	if rollout.healthy {
+		newStatus.OperandLogLevel = provisioning.GetOperandLogLevel(spec)
+	}
+	// The move is complete once the pods are healthy on the new network
+	newStatus.NetworkMigration = updateNetworkMigration(migration, newStatus.LastSuccessfulConfiguration, &baremetalConfig.Spec, len(busyHosts) > 0, time.Now())
+	setHighAvailability(newStatus, spec, nodes, ha)
+	setMaintenanceCondition(newStatus, false)
+	setProvisioningDisabledCondition(newStatus, false)
+	setReadyCondition(newStatus, rollout, failure)
+	certificateRecheck, err := r.checkCertificateExpiry(newStatus, spec, time.Now())
+	if err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to check operand certificates"), ReasonEmpty, "")
+	}
+	groupsRecheck, err := r.syncNodeConductorGroups(baremetalConfig, spec)
+	if err != nil {
+		r.Log.Info("failed to set the conductor groups of the Ironic nodes", "error", err.Error())
+		groupsRecheck = conductorGroupsRetry
+	}
+	orphanRecheck, err := r.cleanupOrphanedNodes(baremetalConfig, newStatus, spec, time.Now())
+	if err != nil {
+		// Ironic may be briefly unreachable, which should not fail the
+		// whole reconcile
+		r.Log.Info("failed to clean up orphaned Ironic nodes", "error", err.Error())
+		orphanRecheck = orphanCleanupRetry
+	}
+	templatesRecheck, err := r.syncDeployTemplates(newStatus, spec)
+	if err != nil {
+		r.Log.Info("failed to register the Ironic deploy templates", "error", err.Error())
+		templatesRecheck = deployTemplatesRetry
+	}
+	traitsRecheck, err := r.syncNodeDeployTraits(baremetalConfig, spec)
+	if err != nil {
+		r.Log.Info("failed to set the deploy traits of the Ironic nodes", "error", err.Error())
+		traitsRecheck = deployTemplatesRetry
+	}
+	smokeRecheck, err := r.runSmokeTest(baremetalConfig, newStatus, &containerImages, rolloutHash, rollout.healthy && failure == nil, time.Now())
+	if err != nil {
+		r.Log.Info("failed to run the metal3 smoke test", "error", err.Error())
+		smokeRecheck = smokeTestRecheck
+	}
+	dbRecoveryRecheck, err := r.recoverIronicDB(baremetalConfig, newStatus, time.Now())
+	if err != nil {
+		r.Log.Info("failed to recover the Ironic database", "error", err.Error())
+		dbRecoveryRecheck = ironicDBRecoveryRecheck
+	}
+	footprintRecheck, err := r.measureResourceFootprint(baremetalConfig, newStatus, rolloutHash, rollout.healthy && failure == nil, time.Now())
+	if err != nil {
+		r.Log.Info("failed to measure the metal3 resource footprint", "error", err.Error())
+		footprintRecheck = resourceFootprintSampleInterval
+	}
+	newStatus.DHCPRange = provisioning.GetServedDHCPRange(spec)
+	newStatus.Capacity = provisioning.GetProvisioningCapacity(spec, r.countDHCPLeases(spec))
+	newStatus.IronicEndpoint = provisioning.GetIronicEndpoint(spec)
+	newStatus.IgnitionOverridesURL = provisioning.GetIgnitionOverridesURL(spec)
+	imageServer, err := r.publishBootArtifacts(baremetalConfig, spec)
+	if err != nil {
+		return r.reconcileError(errors.Wrap(err, "failed to publish boot artifacts"), ReasonEmpty, "")
+	}
+	newStatus.ImageServer = imageServer
+	if err := r.updateProvisioningStatus(baremetalConfig, newStatus); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update Provisioning status")
+	}
+
+	if failure != nil {
+		// Errors are not returned for operand failures, as the default rate
+		// limiting would retry far more often than a crash loop can recover
+		return ctrl.Result{RequeueAfter: operandFailureBackoff(r.operandFailures)}, nil
+	}
+	if rollout.verifying {
+		return ctrl.Result{RequeueAfter: operandRolloutRequeueAfter}, nil
+	}
+	// The hosts are watched, so the end of their operations is noticed
+	migrationRecheck := networkMigrationRecheck(newStatus.NetworkMigration, time.Now())
+	return ctrl.Result{RequeueAfter: soonestRequeue(migrationRecheck, conflicts.recheck, certificateRecheck, orphanRecheck, templatesRecheck, traitsRecheck, groupsRecheck, downloadRecheck, smokeRecheck, dbRecoveryRecheck, footprintRecheck)}, nil
+}
+
+// setOperandsRolloutHash records on the metal3 Deployment and, when
+// deployed, the dnsmasq DaemonSet the rollout hash of their specs
+func setOperandsRolloutHash(deployment *appsv1.Deployment, daemonSet *appsv1.DaemonSet) (string, error) {
+	specs := []interface{}{deployment.Spec}
+	if daemonSet != nil {
+		specs = append(specs, daemonSet.Spec)
+	}
+	rolloutHash, err := provisioning.GetRolloutHash(specs...)
+	if err != nil {
+		return "", err
+	}
+	provisioning.SetRolloutHash(deployment, rolloutHash)
+	if daemonSet != nil {
+		provisioning.SetRolloutHash(daemonSet, rolloutHash)
+	}
+	return rolloutHash, nil
+}
+
+// publishBootArtifacts returns where the image server serves the boot
+// artifacts, and publishes those URLs in a ConfigMap for the installation
+// flows that boot hosts without going through Ironic
+func (r *ProvisioningReconciler) publishBootArtifacts(prov *metal3iov1alpha1.Provisioning, spec *metal3iov1alpha1.ProvisioningSpec) (metal3iov1alpha1.ImageServerStatus, error) {
+	imageServer := provisioning.GetImageServerStatus(spec)
+	bootArtifacts := provisioning.NewBootArtifactsConfigMap(ComponentNamespace, imageServer)
+	if err := controllerutil.SetControllerReference(prov, bootArtifacts, r.Scheme); err != nil {
+		return metal3iov1alpha1.ImageServerStatus{}, errors.Wrap(err, "failed to set owner of boot artifacts configmap")
+	}
+	if err := provisioning.ApplyBootArtifactsConfigMap(r.KubeClient.CoreV1(), bootArtifacts); err != nil {
+		return metal3iov1alpha1.ImageServerStatus{}, err
+	}
+	return imageServer, nil
+}
+
+// updateProvisioningStatus writes the given status to the Provisioning CR
+// when it differs from the current one.
+func (r *ProvisioningReconciler) updateProvisioningStatus(prov *metal3iov1alpha1.Provisioning, newStatus *metal3iov1alpha1.ProvisioningStatus) error {
+	if equality.Semantic.DeepEqual(prov.Status, *newStatus) {
+		return nil
+	}
+	prov.Status = *newStatus
+	return r.Client.Status().Update(context.Background(), prov)
 }
 
 // SetupWithManager configures the manager to run the controller
 func (r *ProvisioningReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := setupFieldIndexers(mgr); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&metal3iov1alpha1.Provisioning{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.DaemonSet{}).
+		Owns(&batchv1.Job{}).
+		Owns(&corev1.Pod{}).
+		Owns(&corev1.Service{}).
+		Watches(&source.Kind{Type: &metal3iov1alpha1.Provisioning{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.otherProvisioningDomains)}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(osImageStreamToProvisioning)}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(serviceCAToProvisioning)}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(ignitionOverrideToProvisioning)}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.inspectorRulesToProvisioning)}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.biosProfilesToProvisioning)}).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(r.imagePullSecretToProvisioning)}).
+		Watches(&source.Kind{Type: &osconfigv1.FeatureGate{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(featureGateToProvisioning)}).
+		Watches(&source.Kind{Type: &osconfigv1.Infrastructure{}},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(infrastructureToProvisioning)}).
+		Watches(&source.Kind{Type: newBareMetalHost()},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(bareMetalHostToProvisioning)},
+			builder.WithPredicates(hostStateChanged)).
 		Complete(r)
 }
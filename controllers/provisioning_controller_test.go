@@ -5,8 +5,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -21,6 +23,14 @@ func setUpSchemeForReconciler() *runtime.Scheme {
 	// the infrastructure CR
 	_ = configv1.Install(scheme)
 	_ = metal3iov1alpha1.AddToScheme(scheme)
+	// the interface check pods are read from the cache
+	_ = clientgoscheme.AddToScheme(scheme)
+	// the BareMetalHosts are read as unstructured objects
+	scheme.AddKnownTypeWithName(bareMetalHostGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(newBareMetalHostList().GroupVersionKind(), &unstructured.UnstructuredList{})
+	// and so are the resource metrics of the metal3 pods
+	scheme.AddKnownTypeWithName(podMetricsListGVK.GroupVersion().WithKind("PodMetrics"), &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(podMetricsListGVK, &unstructured.UnstructuredList{})
 	return scheme
 }
 
@@ -57,6 +67,21 @@ func TestReconcile(t *testing.T) {
 			expectedError: false,
 			isEnabled:     true,
 		},
+		{
+			name: "BaremetalPlatformStatus",
+			infra: &configv1.Infrastructure{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "cluster",
+				},
+				Status: configv1.InfrastructureStatus{
+					PlatformStatus: &configv1.PlatformStatus{
+						Type: configv1.BareMetalPlatformType,
+					},
+				},
+			},
+			expectedError: false,
+			isEnabled:     true,
+		},
 		{
 			name: "NoPlatform",
 			infra: &configv1.Infrastructure{
@@ -86,7 +111,7 @@ func TestReconcile(t *testing.T) {
 			t.Logf("Testing tc : %s", tc.name)
 
 			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), tc.infra)
-			enabled, err := reconciler.isEnabled()
+			_, enabled, err := reconciler.isEnabled()
 			if tc.expectedError && err == nil {
 				t.Error("should have produced an error")
 				return
@@ -100,6 +125,59 @@ func TestReconcile(t *testing.T) {
 	}
 }
 
+func TestIsEnabledOnAnyPlatform(t *testing.T) {
+	infra := &configv1.Infrastructure{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status: configv1.InfrastructureStatus{
+			PlatformStatus: &configv1.PlatformStatus{Type: configv1.NonePlatformType},
+		},
+	}
+	testCases := []struct {
+		name      string
+		prov      *metal3iov1alpha1.Provisioning
+		isEnabled bool
+	}{
+		{
+			name: "NoProvisioningCR",
+		},
+		{
+			name: "NotRequested",
+			prov: &metal3iov1alpha1.Provisioning{ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR}},
+		},
+		{
+			name: "Requested",
+			prov: &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+				Spec:       metal3iov1alpha1.ProvisioningSpec{EnableOnAnyPlatform: true},
+			},
+			isEnabled: true,
+		},
+		{
+			name: "RequestedByOtherInstance",
+			prov: &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: "other"},
+				Spec:       metal3iov1alpha1.ProvisioningSpec{EnableOnAnyPlatform: true},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []runtime.Object{infra.DeepCopy()}
+			if tc.prov != nil {
+				objects = append(objects, tc.prov)
+			}
+			reconciler := &ProvisioningReconciler{
+				Client: fakeclient.NewFakeClientWithScheme(setUpSchemeForReconciler(), objects...),
+				Log:    ctrl.Log.WithName("controllers").WithName("Provisioning"),
+			}
+			platform, enabled, err := reconciler.isEnabled()
+			assert.NoError(t, err)
+			assert.Equal(t, configv1.NonePlatformType, platform)
+			assert.Equal(t, tc.isEnabled, enabled)
+		})
+	}
+}
+
 func TestProvisioningCRName(t *testing.T) {
 	testCases := []struct {
 		name           string
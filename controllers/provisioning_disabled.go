@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	// provisioningDisabledCondition reports that the metal3 stack is torn
+	// down as the spec disables provisioning
+	provisioningDisabledCondition = "ProvisioningDisabled"
+	reasonDisabledInSpec          = "DisabledInSpec"
+	reasonTornDown                = "TornDown"
+
+	provisioningDisabledMessage = "metal3 is torn down as provisioning is disabled"
+)
+
+// disableProvisioning tears the metal3 stack down and records it in the
+// status. Deleting the metal3 pods would fail the deployment or cleaning
+// of hosts, so nothing is deleted while some are busy; their names are
+// returned instead.
+func (r *ProvisioningReconciler) disableProvisioning(prov *metal3iov1alpha1.Provisioning) ([]string, error) {
+	busyHosts, err := r.listBusyHosts()
+	if err != nil {
+		return nil, err
+	}
+	if len(busyHosts) > 0 {
+		return busyHosts, nil
+	}
+	if err := r.teardownMetal3(); err != nil {
+		return nil, err
+	}
+
+	newStatus := prov.Status.DeepCopy()
+	newStatus.ObservedGeneration = prov.Generation
+	clearOperandsStatus(newStatus)
+	setMaintenanceCondition(newStatus, false)
+	setProvisioningDisabledCondition(newStatus, true)
+	setProvisioningCondition(newStatus, provisioningReadyCondition, operatorv1.ConditionFalse, reasonTornDown, provisioningDisabledMessage)
+	return nil, r.updateProvisioningStatus(prov, newStatus)
+}
+
+// teardownMetal3 deletes the operands of the main Provisioning instance.
+// The Secrets and the revision history are kept, so that enabling it
+// again deploys the same revision with the same passwords. The stacks of
+// the provisioning domains are deleted when their instances are
+// reconciled.
+func (r *ProvisioningReconciler) teardownMetal3() error {
+	if err := provisioning.DeleteMetal3Deployment(r.KubeClient.AppsV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	if err := provisioning.DeleteDnsmasqDaemonSet(r.KubeClient.AppsV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	if err := provisioning.DeleteStaleConductorGroups(r.KubeClient.AppsV1(), ComponentNamespace, nil); err != nil {
+		return err
+	}
+	if err := provisioning.DeleteMetal3DatabaseService(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	if err := provisioning.DeleteImagePrePullDaemonSet(r.KubeClient.AppsV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	if err := r.deleteSmokeTestJob(); err != nil {
+		return err
+	}
+	if err := r.deleteIronicAPIRoute(); err != nil {
+		return err
+	}
+	if err := r.deleteIronicAPIProxyService(); err != nil {
+		return err
+	}
+	// The URLs of the image server and the overrides it serves are not
+	// published anymore, and the leases left to release go with dnsmasq
+	if err := provisioning.DeleteBootArtifactsConfigMap(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	if err := provisioning.DeleteIgnitionOverridesConfigMap(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	if err := provisioning.DeleteDHCPLeaseReleaseConfigMap(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	// The addresses are allocated again from the pool on the next
+	// deployment
+	if err := provisioning.DeleteProvisioningIPsConfigMap(r.KubeClient.CoreV1(), ComponentNamespace); err != nil {
+		return err
+	}
+	return provisioning.DeleteProvisioningVIPConfigMap(r.KubeClient.CoreV1(), ComponentNamespace)
+}
+
+// clearOperandsStatus removes from the status what the deleted operands
+// served
+func clearOperandsStatus(status *metal3iov1alpha1.ProvisioningStatus) {
+	status.RolloutHash = ""
+	status.DHCPRange = ""
+	status.IronicEndpoint = ""
+	status.ImageServer = metal3iov1alpha1.ImageServerStatus{}
+	status.IgnitionOverridesURL = ""
+	status.ConductorGroups = nil
+	status.ProvisioningIPs = nil
+	status.ProvisioningVIPNode = ""
+	status.Capacity = nil
+	status.SmokeTest = nil
+	status.ResourceFootprint = nil
+}
+
+// setProvisioningDisabledCondition records in the status whether the
+// metal3 stack is torn down as the spec disables provisioning
+func setProvisioningDisabledCondition(status *metal3iov1alpha1.ProvisioningStatus, disabled bool) {
+	if !disabled {
+		removeProvisioningCondition(status, provisioningDisabledCondition)
+		return
+	}
+	setProvisioningCondition(status, provisioningDisabledCondition, operatorv1.ConditionTrue, reasonDisabledInSpec, provisioningDisabledMessage)
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newDisabledProvisioning() *metal3iov1alpha1.Provisioning {
+	prov := newProvisioningDomain(BaremetalProvisioningCR, "eth0")
+	prov.Generation = 7
+	prov.Spec.HostSelector = nil
+	prov.Spec.Enabled = pointer.BoolPtr(false)
+	prov.Status.IronicEndpoint = "https://172.30.20.3:6385"
+	prov.Status.RolloutHash = "abcdef"
+	setMaintenanceCondition(&prov.Status, true)
+	return prov
+}
+
+func TestDisableProvisioning(t *testing.T) {
+	prov := newDisabledProvisioning()
+	images := &provisioning.Images{}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov,
+		newTestBareMetalHost(ComponentNamespace, "worker-0", "provisioned"))
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		provisioning.NewMetal3Deployment(ComponentNamespace, images, &prov.Spec),
+		provisioning.NewDnsmasqDaemonSet(ComponentNamespace, images, &prov.Spec),
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+			Name:      provisioning.ConductorGroupDeploymentName("rack-1"),
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{provisioning.ConductorGroupLabel: "rack-1"},
+		}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: provisioning.Metal3DatabaseServiceName, Namespace: ComponentNamespace}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: provisioning.IronicAPIProxyName, Namespace: ComponentNamespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: provisioning.BootArtifactsConfigMap, Namespace: ComponentNamespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: provisioning.IgnitionOverridesConfigMap, Namespace: ComponentNamespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: provisioning.DHCPLeaseReleaseConfigMapName, Namespace: ComponentNamespace}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: provisioning.RevisionHistoryConfigMapName, Namespace: ComponentNamespace}},
+	)
+
+	busyHosts, err := reconciler.disableProvisioning(prov)
+	assert.NoError(t, err)
+	assert.Empty(t, busyHosts)
+
+	deployments, err := reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, deployments.Items)
+	_, err = reconciler.KubeClient.AppsV1().DaemonSets(ComponentNamespace).Get(context.Background(), "metal3-dnsmasq", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	services, err := reconciler.KubeClient.CoreV1().Services(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, services.Items)
+	// The revision history is kept, so that the same revision is deployed
+	// again
+	configMaps, err := reconciler.KubeClient.CoreV1().ConfigMaps(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+	if assert.NoError(t, err) && assert.Len(t, configMaps.Items, 1) {
+		assert.Equal(t, provisioning.RevisionHistoryConfigMapName, configMaps.Items[0].Name)
+	}
+
+	// The instance is kept, and reports the teardown
+	updated := &metal3iov1alpha1.Provisioning{}
+	assert.NoError(t, reconciler.Client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, updated))
+	assert.Equal(t, int64(7), updated.Status.ObservedGeneration)
+	assert.Empty(t, updated.Status.IronicEndpoint)
+	assert.Empty(t, updated.Status.RolloutHash)
+	assert.Nil(t, findCondition(&updated.Status, maintenanceActiveCondition))
+	if cond := findCondition(&updated.Status, provisioningDisabledCondition); assert.NotNil(t, cond) {
+		assert.Equal(t, operatorv1.ConditionTrue, cond.Status)
+		assert.Equal(t, reasonDisabledInSpec, cond.Reason)
+	}
+	if cond := findCondition(&updated.Status, provisioningReadyCondition); assert.NotNil(t, cond) {
+		assert.Equal(t, operatorv1.ConditionFalse, cond.Status)
+		assert.Equal(t, reasonTornDown, cond.Reason)
+	}
+
+	setProvisioningDisabledCondition(&updated.Status, false)
+	assert.Nil(t, findCondition(&updated.Status, provisioningDisabledCondition))
+}
+
+func TestDisableProvisioningBusyHosts(t *testing.T) {
+	prov := newDisabledProvisioning()
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, prov,
+		newTestBareMetalHost(ComponentNamespace, "worker-0", "provisioning"),
+		newTestBareMetalHost(ComponentNamespace, "worker-1", "provisioned"))
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		provisioning.NewMetal3Deployment(ComponentNamespace, &provisioning.Images{}, &prov.Spec))
+
+	// Deleting Ironic would fail the deployment of the host
+	busyHosts, err := reconciler.disableProvisioning(prov)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"worker-0"}, busyHosts)
+	_, err = reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).Get(context.Background(), "metal3", metav1.GetOptions{})
+	assert.NoError(t, err)
+	updated := &metal3iov1alpha1.Provisioning{}
+	assert.NoError(t, reconciler.Client.Get(context.Background(), client.ObjectKey{Name: BaremetalProvisioningCR}, updated))
+	assert.Nil(t, findCondition(&updated.Status, provisioningDisabledCondition))
+}
+
+func TestReconcileProvisioningDomainDisabled(t *testing.T) {
+	main := newProvisioningDomain(BaremetalProvisioningCR, "eth1")
+	main.Spec.HostSelector = nil
+	main.Spec.EnableProvisioningDomains = true
+	main.Spec.Enabled = pointer.BoolPtr(false)
+	domain := newProvisioningDomain("rack-1", "eth2")
+	domain.Spec.ProvisioningIP = "172.30.21.3"
+	domain.Spec.ProvisioningNetworkCIDR = "172.30.21.0/24"
+	domain.Spec.ProvisioningDHCPRange = "172.30.21.11, 172.30.21.101"
+	domain.Status.DHCPRange = domain.Spec.ProvisioningDHCPRange
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, main)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, main, domain)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "metal3-rack-1", Namespace: ComponentNamespace},
+	})
+
+	_, err := reconciler.reconcileProvisioningDomain(ctrl.Request{NamespacedName: types.NamespacedName{Name: domain.Name}}, configv1.BareMetalPlatformType)
+	assert.NoError(t, err)
+
+	// The domains go with the main stack
+	deployments, err := reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, deployments.Items)
+	got := &metal3iov1alpha1.Provisioning{}
+	assert.NoError(t, reconciler.Client.Get(context.Background(), client.ObjectKey{Name: domain.Name}, got))
+	assert.Empty(t, got.Status.DHCPRange)
+	if cond := findCondition(&got.Status, provisioningDisabledCondition); assert.NotNil(t, cond) {
+		assert.Equal(t, operatorv1.ConditionTrue, cond.Status)
+	}
+}
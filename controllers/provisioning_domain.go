@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// reconcileProvisioningDomain deploys the metal3 stack of an additional
+// Provisioning instance when the singleton enables provisioning domains,
+// and marks the instance as ignored otherwise.
+func (r *ProvisioningReconciler) reconcileProvisioningDomain(req ctrl.Request, platform osconfigv1.PlatformType) (ctrl.Result, error) {
+	ctx := context.Background()
+	domain := &metal3iov1alpha1.Provisioning{}
+	if err := r.Client.Get(ctx, req.NamespacedName, domain); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "unable to read Provisioning CR")
+	}
+	newStatus := domain.Status.DeepCopy()
+
+	main := &metal3iov1alpha1.Provisioning{}
+	err := r.Client.Get(ctx, client.ObjectKey{Name: BaremetalProvisioningCR}, main)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return ctrl.Result{}, errors.Wrap(err, "unable to read Provisioning CR")
+	}
+	if apierrors.IsNotFound(err) || !main.Spec.EnableProvisioningDomains {
+		r.Log.V(1).Info("ignoring invalid CR", "name", req.Name)
+		if err := provisioning.DeleteProvisioningDomain(r.KubeClient.AppsV1(), ComponentNamespace, domain.Name); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to delete provisioning domain")
+		}
+		setProvisioningIgnoredCondition(newStatus, true, reasonNotSingleton, notSingletonMessage)
+		return ctrl.Result{}, r.updateProvisioningStatus(domain, newStatus)
+	}
+	if !provisioning.IsProvisioningEnabled(&main.Spec) || !provisioning.IsProvisioningEnabled(&domain.Spec) {
+		if err := provisioning.DeleteProvisioningDomain(r.KubeClient.AppsV1(), ComponentNamespace, domain.Name); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to delete provisioning domain")
+		}
+		newStatus.ObservedGeneration = domain.Generation
+		clearOperandsStatus(newStatus)
+		setProvisioningDisabledCondition(newStatus, true)
+		return ctrl.Result{}, r.updateProvisioningStatus(domain, newStatus)
+	}
+
+	instances := &metal3iov1alpha1.ProvisioningList{}
+	if err := r.Client.List(ctx, instances); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to list Provisioning CRs")
+	}
+	err = provisioning.NewInvalidSpecError(provisioning.NormalizeProvisioningSpec(&domain.Spec))
+	if err == nil {
+		err = provisioning.ValidateProvisioningDomain(domain, main, instances.Items)
+	}
+	if err == nil {
+		err = provisioning.ValidatePlatformSupport(domain, platform)
+	}
+	if errors.Is(err, provisioning.ErrInvalidSpec) {
+		// The stack already deployed, if any, is left as is until the
+		// configuration is fixed, like for the main instance
+		r.Log.Error(err, "invalid config in Provisioning CR", "name", domain.Name)
+		setProvisioningIgnoredCondition(newStatus, true, reasonInvalidDomain, err.Error())
+		return ctrl.Result{}, r.updateProvisioningStatus(domain, newStatus)
+	}
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to validate Provisioning CR")
+	}
+
+	var containerImages provisioning.Images
+	if err := provisioning.GetContainerImages(&containerImages, ContainerImagesFile); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "invalid contents in images Config Map")
+	}
+
+	spec := domain.Spec.DeepCopy()
+	if err := provisioning.SetDefaultDHCPRange(spec); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to compute default DHCP range")
+	}
+
+	imagePullSecretHash, err := r.syncImagePullSecret(spec)
+	if errors.Is(err, provisioning.ErrInvalidSpec) {
+		r.Log.Error(err, "invalid config in Provisioning CR", "name", domain.Name)
+		setProvisioningIgnoredCondition(newStatus, true, reasonInvalidDomain, err.Error())
+		return ctrl.Result{}, r.updateProvisioningStatus(domain, newStatus)
+	}
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to write image pull credentials")
+	}
+
+	deployment := provisioning.NewProvisioningDomainDeployment(ComponentNamespace, domain.Name, &containerImages, spec)
+	if imagePullSecretHash != "" {
+		provisioning.SetImagePullSecretHash(&deployment.Spec.Template, imagePullSecretHash)
+	}
+	// The images are refreshed with the RefreshImages action of the main
+	// instance
+	if refreshGeneration := provisioning.GetImageRefreshGeneration(main); refreshGeneration != "" {
+		provisioning.SetImageRefreshGeneration(&deployment.Spec.Template, refreshGeneration)
+	}
+	var daemonSet *appsv1.DaemonSet
+	if provisioning.IsDnsmasqRequired(spec) {
+		daemonSet = provisioning.NewProvisioningDomainDaemonSet(ComponentNamespace, domain.Name, &containerImages, spec)
+	}
+	rolloutHash, err := setOperandsRolloutHash(deployment, daemonSet)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to compute rollout hash")
+	}
+
+	if err := controllerutil.SetControllerReference(domain, deployment, r.Scheme); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to set owner of metal3 deployment")
+	}
+	if _, err := provisioning.ApplyMetal3Deployment(r.KubeClient.AppsV1(), deployment); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to apply metal3 deployment")
+	}
+	if daemonSet != nil {
+		if err := controllerutil.SetControllerReference(domain, daemonSet, r.Scheme); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to set owner of dnsmasq daemonset")
+		}
+		if _, err := provisioning.ApplyDnsmasqDaemonSet(r.KubeClient.AppsV1(), daemonSet); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to apply dnsmasq daemonset")
+		}
+	} else if err := provisioning.DeleteProvisioningDomainDaemonSet(r.KubeClient.AppsV1(), ComponentNamespace, domain.Name); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to delete dnsmasq daemonset")
+	}
+
+	setProvisioningIgnoredCondition(newStatus, false, reasonDomainDeployed, "")
+	setProvisioningDisabledCondition(newStatus, false)
+	newStatus.ObservedGeneration = domain.Generation
+	newStatus.RolloutHash = rolloutHash
+	newStatus.DHCPRange = provisioning.GetServedDHCPRange(spec)
+	newStatus.ImageServer = provisioning.GetImageServerStatus(spec)
+	if err := r.updateProvisioningStatus(domain, newStatus); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update Provisioning status")
+	}
+	return ctrl.Result{}, nil
+}
+
+// otherProvisioningDomains maps changes to a Provisioning instance to a
+// reconcile of every other provisioning domain, as whether they are
+// enabled depends on the singleton, and whether they are valid depends on
+// the host selectors of the other instances.
+func (r *ProvisioningReconciler) otherProvisioningDomains(obj handler.MapObject) []reconcile.Request {
+	instances := &metal3iov1alpha1.ProvisioningList{}
+	if err := r.Client.List(context.Background(), instances); err != nil {
+		r.Log.Error(err, "unable to list Provisioning CRs")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, instance := range instances.Items {
+		if instance.Name != BaremetalProvisioningCR && instance.Name != obj.Meta.GetName() {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: instance.Name},
+			})
+		}
+	}
+	return requests
+}
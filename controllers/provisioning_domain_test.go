@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newProvisioningDomain(name string, iface string) *metal3iov1alpha1.Provisioning {
+	return &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:     iface,
+			ProvisioningIP:            "172.30.20.3",
+			ProvisioningNetworkCIDR:   "172.30.20.0/24",
+			ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+			ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos.qcow2.gz?sha256=1234",
+			ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkManaged,
+			HostSelector:              &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "1"}},
+		},
+	}
+}
+
+func TestReconcileProvisioningDomainIgnored(t *testing.T) {
+	main := newProvisioningDomain(BaremetalProvisioningCR, "eth1")
+	main.Spec.HostSelector = nil
+	overlapping := newProvisioningDomain("rack-1", "eth2")
+
+	tCases := []struct {
+		name           string
+		enableDomains  bool
+		expectedReason string
+	}{
+		{
+			name:           "DomainsDisabled",
+			expectedReason: reasonNotSingleton,
+		},
+		{
+			name:           "InvalidDomain",
+			enableDomains:  true,
+			expectedReason: reasonInvalidDomain,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			main := main.DeepCopy()
+			main.Spec.EnableProvisioningDomains = tc.enableDomains
+			scheme := setUpSchemeForReconciler()
+			reconciler := newFakeProvisioningReconciler(scheme, main)
+			reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, main, overlapping.DeepCopy())
+			reconciler.KubeClient = fakekube.NewSimpleClientset(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "metal3-rack-1", Namespace: ComponentNamespace},
+			})
+
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Name: overlapping.Name}}
+			for i := 0; i < 2; i++ {
+				// Reconciling twice must not add a second condition
+				_, err := reconciler.reconcileProvisioningDomain(req, configv1.BareMetalPlatformType)
+				assert.NoError(t, err)
+			}
+
+			got := &metal3iov1alpha1.Provisioning{}
+			assert.NoError(t, reconciler.Client.Get(context.Background(), client.ObjectKey{Name: overlapping.Name}, got))
+			if assert.Len(t, got.Status.Conditions, 1) {
+				cond := got.Status.Conditions[0]
+				assert.Equal(t, provisioningIgnoredCondition, cond.Type)
+				assert.Equal(t, operatorv1.ConditionTrue, cond.Status)
+				assert.Equal(t, tc.expectedReason, cond.Reason)
+			}
+
+			// The stack of a disabled domain is removed, while an invalid
+			// configuration leaves it alone
+			deployments, err := reconciler.KubeClient.AppsV1().Deployments(ComponentNamespace).List(context.Background(), metav1.ListOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.enableDomains, len(deployments.Items) == 1)
+		})
+	}
+}
+
+func TestReconcileProvisioningDomainGone(t *testing.T) {
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &metal3iov1alpha1.Provisioning{})
+	_, err := reconciler.reconcileProvisioningDomain(ctrl.Request{NamespacedName: types.NamespacedName{Name: "gone"}}, configv1.BareMetalPlatformType)
+	assert.NoError(t, err)
+}
+
+func TestSetProvisioningIgnoredCondition(t *testing.T) {
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	setProvisioningIgnoredCondition(status, true, reasonNotSingleton, notSingletonMessage)
+	if assert.Len(t, status.Conditions, 1) {
+		assert.Equal(t, operatorv1.ConditionTrue, status.Conditions[0].Status)
+	}
+	setProvisioningIgnoredCondition(status, false, reasonDomainDeployed, "")
+	if assert.Len(t, status.Conditions, 1) {
+		assert.Equal(t, operatorv1.ConditionFalse, status.Conditions[0].Status)
+		assert.Equal(t, reasonDomainDeployed, status.Conditions[0].Reason)
+	}
+}
+
+func TestOtherProvisioningDomains(t *testing.T) {
+	scheme := setUpSchemeForReconciler()
+	main := newProvisioningDomain(BaremetalProvisioningCR, "eth1")
+	reconciler := newFakeProvisioningReconciler(scheme, main)
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme, []runtime.Object{main,
+		newProvisioningDomain("rack-1", "eth2"), newProvisioningDomain("rack-2", "eth3")}...)
+
+	requests := reconciler.otherProvisioningDomains(handler.MapObject{Meta: main})
+	assert.Len(t, requests, 2)
+	requests = reconciler.otherProvisioningDomains(handler.MapObject{Meta: newProvisioningDomain("rack-1", "eth2")})
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, "rack-2", requests[0].Name)
+	}
+}
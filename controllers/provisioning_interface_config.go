@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups=machineconfiguration.openshift.io,resources=machineconfigs,verbs=get;create;update;delete
+
+var machineConfigGVK = schema.GroupVersionKind{Group: "machineconfiguration.openshift.io", Version: "v1", Kind: "MachineConfig"}
+
+// ensureProvisioningInterfaceMachineConfig creates or updates the
+// MachineConfig configuring the provisioning interface, and removes it
+// once the interface is configured by the metal3 pods again. Every change
+// reboots the control plane nodes, so the MachineConfig is only updated
+// when its spec differs.
+func (r *ProvisioningReconciler) ensureProvisioningInterfaceMachineConfig(prov *metal3iov1alpha1.Provisioning) error {
+	if !provisioning.IsProvisioningInterfaceMachineConfig(&prov.Spec) {
+		return r.deleteProvisioningInterfaceMachineConfig()
+	}
+
+	machineConfig := provisioning.NewProvisioningInterfaceMachineConfig(&prov.Spec)
+	if err := controllerutil.SetControllerReference(prov, machineConfig, r.Scheme); err != nil {
+		return err
+	}
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(machineConfigGVK)
+	err := r.Client.Get(context.Background(), client.ObjectKey{Name: provisioning.ProvisioningInterfaceMachineConfigName}, existing)
+	if apierrors.IsNotFound(err) {
+		return r.Client.Create(context.Background(), machineConfig)
+	}
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(machineConfig.Object["spec"], existing.Object["spec"]) &&
+		equality.Semantic.DeepDerivative(machineConfig.GetLabels(), existing.GetLabels()) {
+		return nil
+	}
+	machineConfig.SetResourceVersion(existing.GetResourceVersion())
+	return r.Client.Update(context.Background(), machineConfig)
+}
+
+func (r *ProvisioningReconciler) deleteProvisioningInterfaceMachineConfig() error {
+	machineConfig := &unstructured.Unstructured{}
+	machineConfig.SetGroupVersionKind(machineConfigGVK)
+	err := r.Client.Get(context.Background(), client.ObjectKey{Name: provisioning.ProvisioningInterfaceMachineConfigName}, machineConfig)
+	if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		// Nothing to remove, including when the MachineConfig API is not served
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = r.Client.Delete(context.Background(), machineConfig)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
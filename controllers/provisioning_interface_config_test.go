@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func getProvisioningInterfaceMachineConfig(r *ProvisioningReconciler) (*unstructured.Unstructured, error) {
+	machineConfig := &unstructured.Unstructured{}
+	machineConfig.SetGroupVersionKind(machineConfigGVK)
+	err := r.Client.Get(context.Background(), client.ObjectKey{Name: provisioning.ProvisioningInterfaceMachineConfigName}, machineConfig)
+	return machineConfig, err
+}
+
+func TestEnsureProvisioningInterfaceMachineConfig(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:       "eth0",
+			ProvisioningIP:              "172.30.20.3",
+			ProvisioningNetworkCIDR:     "172.30.20.0/24",
+			ProvisioningNetwork:         metal3iov1alpha1.ProvisioningNetworkManaged,
+			ProvisioningInterfaceConfig: metal3iov1alpha1.ProvisioningInterfaceConfigMachineConfig,
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+
+	assert.NoError(t, reconciler.ensureProvisioningInterfaceMachineConfig(prov))
+	machineConfig, err := getProvisioningInterfaceMachineConfig(reconciler)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "master", machineConfig.GetLabels()["machineconfiguration.openshift.io/role"])
+	assert.Len(t, machineConfig.GetOwnerReferences(), 1)
+	resourceVersion := machineConfig.GetResourceVersion()
+
+	// An unchanged spec leaves the MachineConfig alone, not to reboot the nodes
+	assert.NoError(t, reconciler.ensureProvisioningInterfaceMachineConfig(prov))
+	machineConfig, err = getProvisioningInterfaceMachineConfig(reconciler)
+	if assert.NoError(t, err) {
+		assert.Equal(t, resourceVersion, machineConfig.GetResourceVersion())
+	}
+
+	prov.Spec.ProvisioningInterface = "eth1"
+	assert.NoError(t, reconciler.ensureProvisioningInterfaceMachineConfig(prov))
+	machineConfig, err = getProvisioningInterfaceMachineConfig(reconciler)
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, resourceVersion, machineConfig.GetResourceVersion())
+		expected := provisioning.NewProvisioningInterfaceMachineConfig(&prov.Spec)
+		assert.Equal(t, expected.Object["spec"], machineConfig.Object["spec"])
+	}
+
+	prov.Spec.ProvisioningInterfaceConfig = metal3iov1alpha1.ProvisioningInterfaceConfigPod
+	assert.NoError(t, reconciler.ensureProvisioningInterfaceMachineConfig(prov))
+	_, err = getProvisioningInterfaceMachineConfig(reconciler)
+	assert.True(t, apierrors.IsNotFound(err))
+	// Removing it again is a no-op
+	assert.NoError(t, reconciler.ensureProvisioningInterfaceMachineConfig(prov))
+}
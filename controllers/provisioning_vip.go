@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=delete
+
+const (
+	// provisioningVIPCondition reports whether a metal3 pod was elected
+	// to run the metal3 services and hold the ProvisioningIP
+	provisioningVIPCondition = "ProvisioningVIP"
+	reasonVIPHeld            = "VIPHeld"
+	reasonNoInitializedPod   = "NoInitializedPod"
+)
+
+// syncProvisioningVIP elects the active metal3 pod, which runs the metal3
+// services and holds the ProvisioningIP, and publishes its node to the
+// containers of the pods, or removes the election when HighAvailability
+// is not set. The pods are not watched, the metal3 Deployment status
+// changing when one of them is removed is what triggers a new election.
+func (r *ProvisioningReconciler) syncProvisioningVIP(prov *metal3iov1alpha1.Provisioning, spec *metal3iov1alpha1.ProvisioningSpec) (string, error) {
+	if !provisioning.IsActivePassive(spec) {
+		return "", provisioning.DeleteProvisioningVIPConfigMap(r.KubeClient.CoreV1(), ComponentNamespace)
+	}
+
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: metal3DeploymentPodSelector})
+	if err != nil {
+		return "", err
+	}
+	node := provisioning.SelectProvisioningVIPNode(pods.Items, prov.Status.ProvisioningVIPNode)
+	if node == "" {
+		return "", nil
+	}
+	configMap := provisioning.NewProvisioningVIPConfigMap(ComponentNamespace, node)
+	if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+		return "", err
+	}
+	if err := provisioning.ApplyProvisioningVIPConfigMap(r.KubeClient.CoreV1(), configMap); err != nil {
+		return "", err
+	}
+	return node, nil
+}
+
+// setProvisioningVIP records the node of the active metal3 pod in the
+// status
+func setProvisioningVIP(status *metal3iov1alpha1.ProvisioningStatus, spec *metal3iov1alpha1.ProvisioningSpec, node string) {
+	status.ProvisioningVIPNode = node
+	if !provisioning.IsActivePassive(spec) {
+		removeProvisioningCondition(status, provisioningVIPCondition)
+		return
+	}
+	if node == "" {
+		setProvisioningCondition(status, provisioningVIPCondition, operatorv1.ConditionFalse, reasonNoInitializedPod,
+			"no metal3 pod is initialized to become the active one")
+		return
+	}
+	setProvisioningCondition(status, provisioningVIPCondition, operatorv1.ConditionTrue, reasonVIPHeld,
+		fmt.Sprintf("the metal3 pod of node %s is active", node))
+}
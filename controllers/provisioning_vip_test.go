@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newReadyMetal3Pod(name string, node string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{"k8s-app": "metal3", "controller": "metal3"},
+		},
+		Spec: corev1.PodSpec{NodeName: node},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodInitialized, Status: corev1.ConditionTrue},
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			},
+		},
+	}
+}
+
+func TestSyncProvisioningVIP(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningIP:   "172.30.20.3",
+			HighAvailability: &metal3iov1alpha1.HighAvailability{Replicas: 2},
+			ProvisioningVIP:  &metal3iov1alpha1.ProvisioningVIP{},
+		},
+		Status: metal3iov1alpha1.ProvisioningStatus{ProvisioningVIPNode: "master-1"},
+	}
+
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	kubeClient := fakekube.NewSimpleClientset(newReadyMetal3Pod("metal3-a", "master-0"), newReadyMetal3Pod("metal3-b", "master-1"))
+	reconciler.KubeClient = kubeClient
+
+	node, err := reconciler.syncProvisioningVIP(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, "master-1", node)
+	configMap, err := kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(), provisioning.ProvisioningVIPConfigMapName, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"node": "master-1"}, configMap.Data)
+	}
+
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	setProvisioningVIP(status, &prov.Spec, node)
+	assert.Equal(t, "master-1", status.ProvisioningVIPNode)
+	if assert.Len(t, status.Conditions, 1) {
+		assert.Equal(t, provisioningVIPCondition, status.Conditions[0].Type)
+		assert.Equal(t, operatorv1.ConditionTrue, status.Conditions[0].Status)
+		assert.Equal(t, reasonVIPHeld, status.Conditions[0].Reason)
+	}
+
+	setProvisioningVIP(status, &prov.Spec, "")
+	assert.Equal(t, operatorv1.ConditionFalse, status.Conditions[0].Status)
+	assert.Equal(t, reasonNoInitializedPod, status.Conditions[0].Reason)
+
+	// Unsetting HighAvailability removes the election
+	prov.Spec.HighAvailability = nil
+	prov.Spec.ProvisioningVIP = nil
+	node, err = reconciler.syncProvisioningVIP(prov, &prov.Spec)
+	assert.NoError(t, err)
+	assert.Empty(t, node)
+	_, err = kubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(), provisioning.ProvisioningVIPConfigMapName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	setProvisioningVIP(status, &prov.Spec, node)
+	assert.Empty(t, status.ProvisioningVIPNode)
+	assert.Empty(t, status.Conditions)
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// provisioningReadyCondition reports whether the metal3 pods of the
+	// spec are running and serving, as shown by `oc get provisioning`
+	provisioningReadyCondition = "Ready"
+	reasonOperandsHealthy      = "OperandsHealthy"
+	reasonRolloutInProgress    = "RolloutInProgress"
+	reasonScaledDown           = "ScaledDown"
+)
+
+// setReadyCondition records in the status whether the metal3 pods are
+// ready to provision hosts
+func setReadyCondition(status *metal3iov1alpha1.ProvisioningStatus, rollout rolloutResult, failure *operandFailure) {
+	switch {
+	case failure != nil:
+		setProvisioningCondition(status, provisioningReadyCondition, operatorv1.ConditionFalse, string(failure.reason), failure.String())
+	case !rollout.healthy:
+		setProvisioningCondition(status, provisioningReadyCondition, operatorv1.ConditionFalse, reasonRolloutInProgress,
+			"metal3 pods are not healthy yet")
+	default:
+		setProvisioningCondition(status, provisioningReadyCondition, operatorv1.ConditionTrue, reasonOperandsHealthy, "")
+	}
+}
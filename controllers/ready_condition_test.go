@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestSetReadyCondition(t *testing.T) {
+	tests := []struct {
+		name           string
+		rollout        rolloutResult
+		failure        *operandFailure
+		expectedStatus operatorv1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name:           "Healthy",
+			rollout:        rolloutResult{healthy: true},
+			expectedStatus: operatorv1.ConditionTrue,
+			expectedReason: reasonOperandsHealthy,
+		},
+		{
+			name:           "Verifying",
+			rollout:        rolloutResult{verifying: true},
+			expectedStatus: operatorv1.ConditionFalse,
+			expectedReason: reasonRolloutInProgress,
+		},
+		{
+			name:           "PodFailing",
+			rollout:        rolloutResult{healthy: true},
+			failure:        &operandFailure{reason: ReasonDeploymentCrashLooping, container: "metal3-httpd", message: "back-off"},
+			expectedStatus: operatorv1.ConditionFalse,
+			expectedReason: string(ReasonDeploymentCrashLooping),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			status := &metal3iov1alpha1.ProvisioningStatus{}
+			setReadyCondition(status, tc.rollout, tc.failure)
+			if assert.Len(t, status.Conditions, 1) {
+				assert.Equal(t, provisioningReadyCondition, status.Conditions[0].Type)
+				assert.Equal(t, tc.expectedStatus, status.Conditions[0].Status)
+				assert.Equal(t, tc.expectedReason, status.Conditions[0].Reason)
+			}
+		})
+	}
+}
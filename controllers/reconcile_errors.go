@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// errorReason returns the ClusterOperator reason matching the class of
+// err, or ReasonEmpty when retrying may fix it. Transient API errors come
+// first, so that an API failure met while checking the spec or the
+// operands is retried rather than reported.
+func errorReason(err error) StatusReason {
+	switch {
+	case errors.Is(err, provisioning.ErrTransientAPI):
+		return ReasonEmpty
+	case errors.Is(err, provisioning.ErrInvalidSpec):
+		return ReasonInvalidConfiguration
+	case errors.Is(err, provisioning.ErrOperandDegraded):
+		return ReasonOperandRolloutFailed
+	}
+	return ReasonEmpty
+}
+
+// reconcileError returns the result of a reconcile failing with err.
+// Errors that retrying cannot fix degrade the ClusterOperator with reason,
+// or with the reason matching their class when reason is empty, and the
+// request is not requeued; the Provisioning resource or the operands being
+// watched, they are noticed once fixed. Transient API errors, and any
+// other error, are returned so that the request is retried with backoff,
+// without degrading the ClusterOperator.
+func (r *ProvisioningReconciler) reconcileError(err error, reason StatusReason, progressMsg string) (ctrl.Result, error) {
+	if errors.Is(err, provisioning.ErrTransientAPI) {
+		r.Log.Info("retrying after a transient API error", "message", err.Error())
+		return ctrl.Result{}, err
+	}
+	classReason := errorReason(err)
+	if classReason == ReasonEmpty {
+		return ctrl.Result{}, err
+	}
+	if reason == ReasonEmpty {
+		reason = classReason
+	}
+	r.Log.Info("unable to apply Provisioning CR", "reason", reason, "message", err.Error())
+	if err := r.updateCOStatus(reason, err.Error(), progressMsg); err != nil {
+		return ctrl.Result{}, fmt.Errorf("unable to put %q ClusterOperator in Degraded state: %v", clusterOperatorName, err)
+	}
+	return ctrl.Result{}, nil
+}
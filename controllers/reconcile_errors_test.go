@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	fakeconfigclientset "github.com/openshift/client-go/config/clientset/versioned/fake"
+	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestReconcileError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		reason         StatusReason
+		expectedErr    bool
+		expectedReason StatusReason
+	}{
+		{
+			name:        "Transient",
+			err:         provisioning.NewTransientAPIError(fmt.Errorf("connection refused")),
+			expectedErr: true,
+		},
+		{
+			name:        "TransientWithReason",
+			err:         errors.Wrap(provisioning.NewTransientAPIError(fmt.Errorf("connection refused")), "failed to create Mariadb password"),
+			reason:      ReasonInvalidConfiguration,
+			expectedErr: true,
+		},
+		{
+			name:        "TransientWhileValidating",
+			err:         provisioning.NewInvalidSpecError(provisioning.NewTransientAPIError(fmt.Errorf("timeout"))),
+			expectedErr: true,
+		},
+		{
+			name:        "Unclassified",
+			err:         fmt.Errorf("unexpected"),
+			expectedErr: true,
+		},
+		{
+			name:           "InvalidSpec",
+			err:            errors.Wrap(provisioning.NewInvalidSpecError(fmt.Errorf("invalid")), "failed to apply"),
+			expectedReason: ReasonInvalidConfiguration,
+		},
+		{
+			name:           "InvalidSpecWithReason",
+			err:            provisioning.NewInvalidSpecError(fmt.Errorf("unsupported")),
+			reason:         ReasonUnsupportedConfiguration,
+			expectedReason: ReasonUnsupportedConfiguration,
+		},
+		{
+			name:           "OperandDegraded",
+			err:            provisioning.NewOperandDegradedError(fmt.Errorf("rolled back")),
+			expectedReason: ReasonOperandRolloutFailed,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &osconfigv1.Infrastructure{})
+			co, _ := reconciler.createClusterOperator()
+			reconciler.OSClient = fakeconfigclientset.NewSimpleClientset(co)
+
+			result, err := reconciler.reconcileError(tc.err, tc.reason, "")
+			assert.Equal(t, tc.expectedErr, err != nil)
+			assert.Zero(t, result.RequeueAfter)
+
+			gotCO, _ := reconciler.OSClient.ConfigV1().ClusterOperators().Get(context.Background(), clusterOperatorName, metav1.GetOptions{})
+			degraded := v1helpers.FindStatusCondition(gotCO.Status.Conditions, osconfigv1.OperatorDegraded)
+			if tc.expectedErr {
+				assert.True(t, degraded == nil || degraded.Status != osconfigv1.ConditionTrue)
+				return
+			}
+			if assert.NotNil(t, degraded) {
+				assert.Equal(t, osconfigv1.ConditionTrue, degraded.Status)
+				assert.Equal(t, string(tc.expectedReason), degraded.Reason)
+				assert.Equal(t, tc.err.Error(), degraded.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list
+
+const (
+	// resourceFootprintWindow is how long the usage of the metal3
+	// containers is sampled before resources are recommended, long
+	// enough to include the deployment of hosts
+	resourceFootprintWindow = 24 * time.Hour
+
+	// resourceFootprintSampleInterval is how often the usage is sampled
+	resourceFootprintSampleInterval = 5 * time.Minute
+)
+
+var podMetricsListGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetricsList"}
+
+var (
+	metal3ContainerPeakUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "metal3_container_peak_usage",
+		Help: "Highest usage of the metal3 containers sampled since the current configuration rolled out, in CPU cores or memory bytes.",
+	}, []string{"container", "resource"})
+	metal3ContainerRecommendedResources = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "metal3_container_recommended_resources",
+		Help: "Resources recommended for the metal3 containers from their measured usage, in CPU cores or memory bytes.",
+	}, []string{"container", "resource"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(metal3ContainerPeakUsage, metal3ContainerRecommendedResources)
+}
+
+// measureResourceFootprint samples the usage of the containers of the
+// healthy metal3 pods into the status, and recommends resources for them
+// once the measurement window is over. It returns when the usage should
+// be sampled again, as the resource metrics are not watched.
+func (r *ProvisioningReconciler) measureResourceFootprint(prov *metal3iov1alpha1.Provisioning, status *metal3iov1alpha1.ProvisioningStatus, rolloutHash string, healthy bool, now time.Time) (time.Duration, error) {
+	footprint := status.ResourceFootprint
+	if footprint != nil && footprint.RolloutHash == rolloutHash && footprint.CompletionTime != nil {
+		exportResourceFootprint(footprint)
+		return 0, nil
+	}
+	if !healthy {
+		// A rollout in progress would mix the usage of two revisions
+		return 0, nil
+	}
+
+	usage, err := r.getMetal3ContainerUsage()
+	if meta.IsNoMatchError(err) {
+		// The resource metrics API is not served
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(usage) == 0 {
+		// The metrics of new pods are only collected after a while
+		return resourceFootprintSampleInterval, nil
+	}
+
+	if footprint == nil || footprint.RolloutHash != rolloutHash {
+		footprint = &metal3iov1alpha1.ResourceFootprint{RolloutHash: rolloutHash, StartTime: metav1.NewTime(now)}
+	} else {
+		footprint = footprint.DeepCopy()
+	}
+	provisioning.AddResourceUsageSample(footprint, usage)
+	status.ResourceFootprint = footprint
+	if now.Sub(footprint.StartTime.Time) < resourceFootprintWindow {
+		exportResourceFootprint(footprint)
+		return resourceFootprintSampleInterval, nil
+	}
+
+	completion := metav1.NewTime(now)
+	footprint.CompletionTime = &completion
+	provisioning.SetResourceRecommendations(footprint)
+	exportResourceFootprint(footprint)
+	if r.EventRecorder != nil {
+		r.EventRecorder.Event(prov, corev1.EventTypeNormal, "ResourceFootprintMeasured", resourceFootprintMessage(footprint))
+	}
+	return 0, nil
+}
+
+// getMetal3ContainerUsage returns the current usage of each container of
+// the metal3 pods, the highest of the replicas
+func (r *ProvisioningReconciler) getMetal3ContainerUsage() (map[string]corev1.ResourceList, error) {
+	selector, err := labels.Parse(metal3DeploymentPodSelector)
+	if err != nil {
+		return nil, err
+	}
+	podMetrics := &unstructured.UnstructuredList{}
+	podMetrics.SetGroupVersionKind(podMetricsListGVK)
+	if err := r.Client.List(context.Background(), podMetrics, client.InNamespace(ComponentNamespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	usage := map[string]corev1.ResourceList{}
+	for _, pod := range podMetrics.Items {
+		containers, _, _ := unstructured.NestedSlice(pod.Object, "containers")
+		for _, item := range containers {
+			container, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(container, "name")
+			used, _, _ := unstructured.NestedStringMap(container, "usage")
+			if name == "" {
+				continue
+			}
+			if usage[name] == nil {
+				usage[name] = corev1.ResourceList{}
+			}
+			for _, resourceName := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+				quantity, err := resource.ParseQuantity(used[string(resourceName)])
+				if err != nil {
+					continue
+				}
+				if current, ok := usage[name][resourceName]; !ok || quantity.Cmp(current) > 0 {
+					usage[name][resourceName] = quantity
+				}
+			}
+		}
+	}
+	return usage, nil
+}
+
+// exportResourceFootprint exports the peak usage of the containers and,
+// once measured, the resources recommended for them
+func exportResourceFootprint(footprint *metal3iov1alpha1.ResourceFootprint) {
+	metal3ContainerPeakUsage.Reset()
+	metal3ContainerRecommendedResources.Reset()
+	for _, container := range footprint.Containers {
+		metal3ContainerPeakUsage.WithLabelValues(container.Name, string(corev1.ResourceCPU)).Set(cpuCores(container.PeakCPU))
+		metal3ContainerPeakUsage.WithLabelValues(container.Name, string(corev1.ResourceMemory)).Set(float64(container.PeakMemory.Value()))
+		if container.RecommendedCPU != nil && container.RecommendedMemory != nil {
+			metal3ContainerRecommendedResources.WithLabelValues(container.Name, string(corev1.ResourceCPU)).Set(cpuCores(*container.RecommendedCPU))
+			metal3ContainerRecommendedResources.WithLabelValues(container.Name, string(corev1.ResourceMemory)).Set(float64(container.RecommendedMemory.Value()))
+		}
+	}
+}
+
+func cpuCores(quantity resource.Quantity) float64 {
+	return float64(quantity.MilliValue()) / 1000
+}
+
+func resourceFootprintMessage(footprint *metal3iov1alpha1.ResourceFootprint) string {
+	recommendations := []string{}
+	for _, container := range footprint.Containers {
+		recommendations = append(recommendations, fmt.Sprintf("%s cpu %s memory %s",
+			container.Name, container.RecommendedCPU, container.RecommendedMemory))
+	}
+	return fmt.Sprintf("resources recommended from %d samples of the usage of the metal3 containers: %s",
+		footprint.Samples, strings.Join(recommendations, ", "))
+}
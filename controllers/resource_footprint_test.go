@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newTestPodMetrics(name string, conductorCPU string, conductorMemory string) *unstructured.Unstructured {
+	podMetrics := &unstructured.Unstructured{}
+	podMetrics.SetAPIVersion("metrics.k8s.io/v1beta1")
+	podMetrics.SetKind("PodMetrics")
+	podMetrics.SetNamespace(ComponentNamespace)
+	podMetrics.SetName(name)
+	podMetrics.SetLabels(map[string]string{"k8s-app": "metal3", "controller": "metal3"})
+	podMetrics.Object["containers"] = []interface{}{
+		map[string]interface{}{
+			"name":  "metal3-ironic-conductor",
+			"usage": map[string]interface{}{"cpu": conductorCPU, "memory": conductorMemory},
+		},
+		map[string]interface{}{
+			"name":  "metal3-httpd",
+			"usage": map[string]interface{}{"cpu": "1m", "memory": "20Mi"},
+		},
+	}
+	return podMetrics
+}
+
+func gaugeValue(t *testing.T, gauge interface {
+	Write(*dto.Metric) error
+}) float64 {
+	metric := &dto.Metric{}
+	if err := gauge.Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestMeasureResourceFootprint(t *testing.T) {
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	prov := &metal3iov1alpha1.Provisioning{ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR}}
+	scheme := setUpSchemeForReconciler()
+	reconciler := newFakeProvisioningReconciler(scheme, prov)
+	// The pods of the provisioning domains are left out
+	provisioningDomainPodMetrics := newTestPodMetrics("metal3-rack-1-klmno", "900m", "900Mi")
+	provisioningDomainPodMetrics.SetLabels(map[string]string{"k8s-app": "metal3", "controller": "metal3-rack-1"})
+	// Both replicas are measured, and the busiest one is kept
+	reconciler.Client = fakeclient.NewFakeClientWithScheme(scheme,
+		newTestPodMetrics("metal3-abcde", "100m", "300Mi"),
+		newTestPodMetrics("metal3-fghij", "40m", "350Mi"),
+		provisioningDomainPodMetrics)
+	recorder := record.NewFakeRecorder(10)
+	reconciler.EventRecorder = recorder
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+
+	recheck, err := reconciler.measureResourceFootprint(prov, status, "abcdef", true, start)
+	assert.NoError(t, err)
+	assert.Equal(t, resourceFootprintSampleInterval, recheck)
+	footprint := status.ResourceFootprint
+	if !assert.NotNil(t, footprint) {
+		t.FailNow()
+	}
+	assert.Equal(t, "abcdef", footprint.RolloutHash)
+	assert.Equal(t, start, footprint.StartTime.Time.UTC())
+	assert.Equal(t, int32(1), footprint.Samples)
+	assert.Nil(t, footprint.CompletionTime)
+	if assert.Len(t, footprint.Containers, 2) {
+		conductor := footprint.Containers[1]
+		assert.Equal(t, "metal3-ironic-conductor", conductor.Name)
+		assert.Equal(t, "100m", conductor.PeakCPU.String())
+		assert.Equal(t, "350Mi", conductor.PeakMemory.String())
+		assert.Nil(t, conductor.RecommendedCPU)
+	}
+	assert.Equal(t, 0.1, gaugeValue(t, metal3ContainerPeakUsage.WithLabelValues("metal3-ironic-conductor", "cpu")))
+
+	// Resources are recommended once the window is over
+	recheck, err = reconciler.measureResourceFootprint(prov, status, "abcdef", true, start.Add(resourceFootprintWindow))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	footprint = status.ResourceFootprint
+	assert.Equal(t, int32(2), footprint.Samples)
+	assert.NotNil(t, footprint.CompletionTime)
+	conductor := footprint.Containers[1]
+	assert.Equal(t, "130m", conductor.RecommendedCPU.String())
+	assert.Equal(t, "528Mi", conductor.RecommendedMemory.String())
+	assert.Equal(t, float64(528*1024*1024), gaugeValue(t, metal3ContainerRecommendedResources.WithLabelValues("metal3-ironic-conductor", "memory")))
+	assert.Contains(t, <-recorder.Events, "metal3-ironic-conductor cpu 130m memory 528Mi")
+
+	// The measured footprint is kept for the revision
+	recheck, err = reconciler.measureResourceFootprint(prov, status, "abcdef", true, start.Add(2*resourceFootprintWindow))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Equal(t, int32(2), status.ResourceFootprint.Samples)
+
+	// and measured again for a new one, once it is healthy
+	recheck, err = reconciler.measureResourceFootprint(prov, status, "123456", false, start.Add(2*resourceFootprintWindow))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Equal(t, "abcdef", status.ResourceFootprint.RolloutHash)
+	recheck, err = reconciler.measureResourceFootprint(prov, status, "123456", true, start.Add(2*resourceFootprintWindow))
+	assert.NoError(t, err)
+	assert.Equal(t, resourceFootprintSampleInterval, recheck)
+	assert.Equal(t, "123456", status.ResourceFootprint.RolloutHash)
+	assert.Equal(t, int32(1), status.ResourceFootprint.Samples)
+	assert.Nil(t, status.ResourceFootprint.Containers[1].RecommendedCPU)
+}
+
+func TestMeasureResourceFootprintNoMetrics(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR}}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+
+	// The metrics of new pods are not available right away
+	recheck, err := reconciler.measureResourceFootprint(prov, status, "abcdef", true, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, resourceFootprintSampleInterval, recheck)
+	assert.Nil(t, status.ResourceFootprint)
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// selectOperandRevision returns the revision of the operands to roll out:
+// the rendered one, unless the Provisioning CR asks to roll back to a
+// revision of the history. It returns nil when the requested revision is
+// not in the history.
+func (r *ProvisioningReconciler) selectOperandRevision(prov *metal3iov1alpha1.Provisioning, history provisioning.RevisionHistory,
+	rendered provisioning.OperandRevision) *provisioning.OperandRevision {
+	rollbackTo := prov.Annotations[provisioning.RollbackRevisionAnnotation]
+	if rollbackTo == "" || rollbackTo == rendered.RolloutHash {
+		return &rendered
+	}
+	revision := history.Get(rollbackTo)
+	if revision != nil {
+		r.Log.Info("rolling back operands", "revision", rollbackTo, "rendered", rendered.RolloutHash)
+	}
+	return revision
+}
+
+// recordOperandRevision adds the rolled out revision to the history
+func (r *ProvisioningReconciler) recordOperandRevision(prov *metal3iov1alpha1.Provisioning, history provisioning.RevisionHistory,
+	revision provisioning.OperandRevision) error {
+	if !history.Record(revision) {
+		return nil
+	}
+	configMap, err := provisioning.NewRevisionHistoryConfigMap(ComponentNamespace, history)
+	if err != nil {
+		return err
+	}
+	if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+		return err
+	}
+	return provisioning.ApplyRevisionHistoryConfigMap(r.KubeClient.CoreV1(), configMap)
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestSelectOperandRevision(t *testing.T) {
+	history := provisioning.RevisionHistory{}
+	history.Record(provisioning.NewOperandRevision(healthyRolloutHash, newMetal3Deployment("ironic:1"), nil))
+	rendered := provisioning.NewOperandRevision(newRolloutHash, newMetal3Deployment("ironic:2"), nil)
+
+	tCases := []struct {
+		name     string
+		rollback string
+		expected *provisioning.OperandRevision
+	}{
+		{
+			name:     "Rendered",
+			expected: &rendered,
+		},
+		{
+			name:     "RollbackToRendered",
+			rollback: newRolloutHash,
+			expected: &rendered,
+		},
+		{
+			name:     "Rollback",
+			rollback: healthyRolloutHash,
+			expected: history.Get(healthyRolloutHash),
+		},
+		{
+			name:     "UnknownRevision",
+			rollback: "0000000000000000",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+			}
+			if tc.rollback != "" {
+				prov.Annotations = map[string]string{provisioning.RollbackRevisionAnnotation: tc.rollback}
+			}
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+
+			assert.Equal(t, tc.expected, reconciler.selectOperandRevision(prov, history, rendered))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=delete
+
+// servingCertsRequeueAfter is how often a serving certificate being
+// re-issued by the service CA operator is checked
+const servingCertsRequeueAfter = 15 * time.Second
+
+// syncServingCerts makes sure that the serving certificates issued by the
+// service CA are signed by its current CA, and returns a hash of the
+// serving certificates of the metal3 pod to restart it when they change,
+// such as when the operator renews the image server certificate. A
+// certificate signed by a CA the service CA bundle does not hold anymore
+// is deleted so that the service CA operator issues it again. It returns
+// false until then, so that the metal3 pod restarts only once, with the
+// new certificate.
+func (r *ProvisioningReconciler) syncServingCerts(prov *metal3iov1alpha1.Provisioning) (string, bool, error) {
+	secrets := []*corev1.Secret{}
+	if prov.Spec.IronicAPIExposure != nil {
+		secret, issued, err := r.syncIronicAPIProxyCert(prov)
+		if err != nil || !issued {
+			return "", false, err
+		}
+		if secret == nil {
+			// The metal3 pod waits for the certificate to be issued
+			return "", true, nil
+		}
+		secrets = append(secrets, secret)
+	}
+	if prov.Spec.ImageServerHTTPS {
+		secret, err := provisioning.GetImageServerTlsSecret(r.KubeClient.CoreV1(), ComponentNamespace)
+		if err != nil {
+			return "", false, err
+		}
+		if secret != nil {
+			secrets = append(secrets, secret)
+		}
+	}
+	if len(secrets) == 0 {
+		return "", true, nil
+	}
+	return provisioning.GetServingCertsHash(secrets...), true, nil
+}
+
+// syncIronicAPIProxyCert returns the serving certificate of the Ironic
+// API proxy, or nil until it has been issued, and false while it is being
+// re-issued
+func (r *ProvisioningReconciler) syncIronicAPIProxyCert(prov *metal3iov1alpha1.Provisioning) (*corev1.Secret, bool, error) {
+	configMap := provisioning.NewServiceCAConfigMap(ComponentNamespace)
+	if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+		return nil, false, err
+	}
+	if err := provisioning.ApplyServiceCAConfigMap(r.KubeClient.CoreV1(), configMap); err != nil {
+		return nil, false, err
+	}
+	bundle, err := provisioning.GetServiceCABundle(r.KubeClient.CoreV1(), ComponentNamespace)
+	if err != nil {
+		return nil, false, err
+	}
+
+	secret, err := provisioning.GetIronicAPIProxyTlsSecret(r.KubeClient.CoreV1(), ComponentNamespace)
+	if err != nil || secret == nil {
+		return nil, err == nil, err
+	}
+	if bundle != nil {
+		signed, err := provisioning.IsSignedByCABundle(secret.Data[corev1.TLSCertKey], bundle)
+		if err != nil {
+			r.Log.Info("unable to check serving certificate, re-issuing it", "secret", secret.Name, "error", err.Error())
+		}
+		if !signed {
+			r.Log.Info("service CA rotated, re-issuing serving certificate", "secret", secret.Name)
+			err := r.KubeClient.CoreV1().Secrets(ComponentNamespace).Delete(context.Background(), secret.Name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, false, err
+			}
+			return nil, false, nil
+		}
+	}
+	return secret, true, nil
+}
+
+// serviceCAToProvisioning maps changes to the service CA bundle to a
+// reconcile of the Provisioning singleton.
+func serviceCAToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetNamespace() != ComponentNamespace || obj.Meta.GetName() != provisioning.ServiceCAConfigMapName {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+	}
+}
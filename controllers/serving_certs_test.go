@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// newTestCA returns a PEM encoded serving certificate and the CA it is
+// signed by
+func newTestCA(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "openshift-service-serving-signer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, ca, ca, &key.PublicKey, key)
+	assert.NoError(t, err)
+	serving := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "metal3-ironic-api.openshift-machine-api.svc"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, serving, ca, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDer})
+}
+
+func newServiceCAConfigMap(bundle []byte) *corev1.ConfigMap {
+	configMap := provisioning.NewServiceCAConfigMap(ComponentNamespace)
+	configMap.Data = map[string]string{"service-ca.crt": string(bundle)}
+	return configMap
+}
+
+func newIronicAPITlsSecret(cert []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "metal3-ironic-api-tls", Namespace: ComponentNamespace},
+		Data:       map[string][]byte{corev1.TLSCertKey: cert},
+	}
+}
+
+func newImageServerTlsSecret(cert []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "metal3-image-server-tls", Namespace: ComponentNamespace},
+		Data:       map[string][]byte{corev1.TLSCertKey: cert},
+	}
+}
+
+func TestSyncServingCerts(t *testing.T) {
+	cert, ca := newTestCA(t)
+	_, rotatedCA := newTestCA(t)
+
+	tCases := []struct {
+		name           string
+		exposure       bool
+		imageServer    bool
+		objects        []runtime.Object
+		expectedHash   bool
+		expectedIssued bool
+		expectedSecret bool
+	}{
+		{
+			name:           "NoExposure",
+			expectedIssued: true,
+		},
+		{
+			name:           "CertificateNotIssued",
+			exposure:       true,
+			objects:        []runtime.Object{newServiceCAConfigMap(ca)},
+			expectedIssued: true,
+		},
+		{
+			name:           "BundleNotInjected",
+			exposure:       true,
+			objects:        []runtime.Object{newIronicAPITlsSecret(cert)},
+			expectedHash:   true,
+			expectedIssued: true,
+			expectedSecret: true,
+		},
+		{
+			name:           "CertificateSigned",
+			exposure:       true,
+			objects:        []runtime.Object{newServiceCAConfigMap(ca), newIronicAPITlsSecret(cert)},
+			expectedHash:   true,
+			expectedIssued: true,
+			expectedSecret: true,
+		},
+		{
+			name:           "ImageServerCertificate",
+			imageServer:    true,
+			objects:        []runtime.Object{newImageServerTlsSecret(cert)},
+			expectedHash:   true,
+			expectedIssued: true,
+		},
+		{
+			name:     "CARotated",
+			exposure: true,
+			objects:  []runtime.Object{newServiceCAConfigMap(rotatedCA), newIronicAPITlsSecret(cert)},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+			}
+			if tc.exposure {
+				prov.Spec.IronicAPIExposure = &metal3iov1alpha1.IronicAPIExposure{ClientCAConfigMap: "ironic-clients"}
+			}
+			prov.Spec.ImageServerHTTPS = tc.imageServer
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(tc.objects...)
+
+			hash, issued, err := reconciler.syncServingCerts(prov)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedHash, hash != "")
+			assert.Equal(t, tc.expectedIssued, issued)
+
+			_, err = reconciler.KubeClient.CoreV1().Secrets(ComponentNamespace).Get(context.Background(), "metal3-ironic-api-tls", metav1.GetOptions{})
+			assert.Equal(t, tc.expectedSecret, err == nil)
+			if tc.exposure {
+				configMap, err := reconciler.KubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(),
+					provisioning.ServiceCAConfigMapName, metav1.GetOptions{})
+				if assert.NoError(t, err) {
+					assert.Equal(t, "true", configMap.Annotations["service.beta.openshift.io/inject-cabundle"])
+				}
+			} else {
+				_, err := reconciler.KubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(),
+					provisioning.ServiceCAConfigMapName, metav1.GetOptions{})
+				assert.True(t, apierrors.IsNotFound(err))
+			}
+		})
+	}
+}
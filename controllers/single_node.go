@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups=config.openshift.io,resources=infrastructures,verbs=get;list;watch
+
+// clusterInfrastructureName is the name of the Infrastructure singleton
+const clusterInfrastructureName = "cluster"
+
+// getTopologyProfile returns how the metal3 pods are deployed for the
+// control plane topology of the cluster. The Infrastructure is read
+// unstructured, as the vendored API predates its controlPlaneTopology;
+// clusters that do not report it are not single node.
+func (r *ProvisioningReconciler) getTopologyProfile() (metal3iov1alpha1.TopologyProfile, error) {
+	infra := &unstructured.Unstructured{}
+	infra.SetGroupVersionKind(osconfigv1.GroupVersion.WithKind("Infrastructure"))
+	if err := r.Client.Get(context.Background(), client.ObjectKey{Name: clusterInfrastructureName}, infra); err != nil {
+		return "", err
+	}
+	topology, _, err := unstructured.NestedString(infra.Object, "status", "controlPlaneTopology")
+	if err != nil {
+		return "", err
+	}
+	return provisioning.GetTopologyProfile(topology), nil
+}
+
+// infrastructureToProvisioning maps changes to the cluster Infrastructure
+// to a reconcile of the Provisioning singleton, so that the topology
+// profile follows the control plane topology.
+func infrastructureToProvisioning(obj handler.MapObject) []reconcile.Request {
+	if obj.Meta.GetName() != clusterInfrastructureName {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: BaremetalProvisioningCR}},
+	}
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newInfrastructure(t *testing.T, topology string) *unstructured.Unstructured {
+	infra := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "config.openshift.io/v1",
+		"kind":       "Infrastructure",
+		"metadata":   map[string]interface{}{"name": "cluster"},
+		"status":     map[string]interface{}{"platform": "BareMetal"},
+	}}
+	if topology != "" {
+		assert.NoError(t, unstructured.SetNestedField(infra.Object, topology, "status", "controlPlaneTopology"))
+	}
+	return infra
+}
+
+func TestGetTopologyProfile(t *testing.T) {
+	tCases := []struct {
+		name     string
+		topology string
+		expected metal3iov1alpha1.TopologyProfile
+	}{
+		{
+			name:     "NotReported",
+			expected: metal3iov1alpha1.TopologyProfileHighlyAvailable,
+		},
+		{
+			name:     "HighlyAvailable",
+			topology: "HighlyAvailable",
+			expected: metal3iov1alpha1.TopologyProfileHighlyAvailable,
+		},
+		{
+			name:     "SingleReplica",
+			topology: "SingleReplica",
+			expected: metal3iov1alpha1.TopologyProfileSingleNode,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), newInfrastructure(t, tc.topology))
+			profile, err := reconciler.getTopologyProfile()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, profile)
+		})
+	}
+}
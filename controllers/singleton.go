@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"fmt"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// provisioningIgnoredCondition is set on the Provisioning instances
+	// which are not the singleton, and are therefore never reconciled
+	// unless provisioning domains are enabled
+	provisioningIgnoredCondition = "Ignored"
+	reasonNotSingleton           = "NotSingleton"
+	reasonInvalidDomain          = "InvalidConfiguration"
+	reasonDomainDeployed         = "ProvisioningDomain"
+)
+
+// notSingletonMessage explains why an additional Provisioning instance is
+// ignored. Instances only get there when created before the validating
+// webhook was in place, or when provisioning domains were disabled.
+var notSingletonMessage = fmt.Sprintf("Provisioning is a singleton, only the instance named %q is used "+
+	"unless it enables provisioning domains", BaremetalProvisioningCR)
+
+// setProvisioningIgnoredCondition records in the status whether the
+// Provisioning instance is ignored, so that whoever created it does not
+// wait for it to take effect
+func setProvisioningIgnoredCondition(status *metal3iov1alpha1.ProvisioningStatus, ignored bool, reason string, message string) {
+	condStatus := operatorv1.ConditionFalse
+	if ignored {
+		condStatus = operatorv1.ConditionTrue
+	}
+	setProvisioningCondition(status, provisioningIgnoredCondition, condStatus, reason, message)
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+
+// smokeTestRecheck is how often the smoke test Job is looked at while it
+// runs, in case its completion is missed
+const smokeTestRecheck = 30 * time.Second
+
+// runSmokeTest starts the smoke test of the operands once they rolled out
+// healthy, and records its outcome once the Job completes. Each rollout
+// hash is tested once: the Job of a previous hash is replaced, and a
+// completed test is not run again.
+func (r *ProvisioningReconciler) runSmokeTest(prov *metal3iov1alpha1.Provisioning, provStatus *metal3iov1alpha1.ProvisioningStatus, images *provisioning.Images, rolloutHash string, healthy bool, now time.Time) (time.Duration, error) {
+	jobs := r.KubeClient.BatchV1().Jobs(ComponentNamespace)
+	if !prov.Spec.SmokeTest {
+		provStatus.SmokeTest = nil
+		return 0, r.deleteSmokeTestJob()
+	}
+
+	job, err := jobs.Get(context.Background(), provisioning.SmokeTestJobName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return 0, err
+	}
+	if err == nil && job.Annotations[provisioning.RolloutHashAnnotation] != rolloutHash {
+		// The Job tested a previous configuration
+		return smokeTestRecheck, r.deleteSmokeTestJob()
+	}
+
+	if apierrors.IsNotFound(err) {
+		current := provStatus.SmokeTest
+		if current != nil && current.RolloutHash == rolloutHash && current.Result != metal3iov1alpha1.SmokeTestRunning {
+			return 0, nil
+		}
+		if !healthy {
+			return 0, nil
+		}
+		podIP, err := r.getMetal3PodIP()
+		if err != nil || podIP == "" {
+			return smokeTestRecheck, err
+		}
+		job, err = provisioning.NewSmokeTestJob(ComponentNamespace, images, &prov.Spec, podIP, rolloutHash)
+		if err != nil {
+			return 0, err
+		}
+		if err := controllerutil.SetControllerReference(prov, job, r.Scheme); err != nil {
+			return 0, err
+		}
+		if _, err := jobs.Create(context.Background(), job, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return 0, err
+		}
+		provStatus.SmokeTest = &metal3iov1alpha1.SmokeTestStatus{
+			RolloutHash: rolloutHash,
+			Result:      metal3iov1alpha1.SmokeTestRunning,
+			StartTime:   metav1.NewTime(now),
+		}
+		return smokeTestRecheck, nil
+	}
+
+	finished := false
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			finished = true
+		}
+	}
+	status := provStatus.SmokeTest
+	if status == nil || status.RolloutHash != rolloutHash {
+		status = &metal3iov1alpha1.SmokeTestStatus{
+			RolloutHash: rolloutHash,
+			Result:      metal3iov1alpha1.SmokeTestRunning,
+			StartTime:   job.CreationTimestamp,
+		}
+		provStatus.SmokeTest = status
+	}
+	if !finished {
+		return smokeTestRecheck, nil
+	}
+	if status.Result != metal3iov1alpha1.SmokeTestRunning {
+		return 0, nil
+	}
+
+	checks, err := r.getSmokeTestReport()
+	status.Result = metal3iov1alpha1.SmokeTestPassed
+	status.Checks = checks
+	if err != nil {
+		status.Result = metal3iov1alpha1.SmokeTestFailed
+		status.Checks = []metal3iov1alpha1.SmokeTestCheck{{Name: "report", Message: err.Error()}}
+	}
+	for _, check := range checks {
+		if !check.Passed {
+			status.Result = metal3iov1alpha1.SmokeTestFailed
+		}
+	}
+	completionTime := metav1.NewTime(now)
+	status.CompletionTime = &completionTime
+	r.Log.Info("metal3 smoke test completed", "result", status.Result, "rolloutHash", rolloutHash)
+	if r.EventRecorder != nil {
+		eventType := corev1.EventTypeNormal
+		if status.Result != metal3iov1alpha1.SmokeTestPassed {
+			eventType = corev1.EventTypeWarning
+		}
+		r.EventRecorder.Eventf(prov, eventType, "SmokeTest"+string(status.Result),
+			"smoke test of rollout %s %s", rolloutHash, status.Result)
+	}
+	return 0, nil
+}
+
+// getMetal3PodIP returns the IP of a running metal3 pod, or an empty
+// string when none runs
+func (r *ProvisioningReconciler) getMetal3PodIP() (string, error) {
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: metal3DeploymentPodSelector})
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" && pod.DeletionTimestamp == nil {
+			return pod.Status.PodIP, nil
+		}
+	}
+	return "", nil
+}
+
+// getSmokeTestReport returns the checks reported by the pod of the smoke
+// test Job
+func (r *ProvisioningReconciler) getSmokeTestReport() ([]metal3iov1alpha1.SmokeTestCheck, error) {
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: "k8s-app=" + provisioning.SmokeTestJobName})
+	if err != nil {
+		return nil, err
+	}
+	message := ""
+	for _, pod := range pods.Items {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil && status.State.Terminated.Message != "" {
+				message = status.State.Terminated.Message
+			}
+		}
+	}
+	return provisioning.ParseSmokeTestReport(message)
+}
+
+func (r *ProvisioningReconciler) deleteSmokeTestJob() error {
+	// The pods of the Job go with it
+	propagation := metav1.DeletePropagationBackground
+	err := r.KubeClient.BatchV1().Jobs(ComponentNamespace).Delete(context.Background(), provisioning.SmokeTestJobName,
+		metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
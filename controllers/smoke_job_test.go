@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newSmokeTestPod(message string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      provisioning.SmokeTestJobName + "-abcde",
+			Namespace: ComponentNamespace,
+			Labels:    map[string]string{"k8s-app": provisioning.SmokeTestJobName},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: message}}},
+			},
+		},
+	}
+}
+
+func finishSmokeTestJob(t *testing.T, reconciler *ProvisioningReconciler, conditionType batchv1.JobConditionType) {
+	jobs := reconciler.KubeClient.BatchV1().Jobs(ComponentNamespace)
+	job, err := jobs.Get(context.Background(), provisioning.SmokeTestJobName, metav1.GetOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: conditionType, Status: corev1.ConditionTrue}}
+	_, err = jobs.UpdateStatus(context.Background(), job, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+}
+
+func TestRunSmokeTest(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:   "eth0",
+			ProvisioningIP:          "172.30.20.3",
+			ProvisioningNetworkCIDR: "172.30.20.0/24",
+			ProvisioningDHCPRange:   "172.30.20.11, 172.30.20.101",
+			ProvisioningNetwork:     metal3iov1alpha1.ProvisioningNetworkManaged,
+			SmokeTest:               true,
+		},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(newDownloadingMetal3Pod("metal3-abcde", "192.168.111.20", corev1.PodRunning))
+	images := &provisioning.Images{BaremetalIronic: "ironic"}
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+	jobs := reconciler.KubeClient.BatchV1().Jobs(ComponentNamespace)
+
+	// Nothing is tested until the operands are healthy
+	recheck, err := reconciler.runSmokeTest(prov, status, images, "hash-1", false, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Nil(t, status.SmokeTest)
+
+	recheck, err = reconciler.runSmokeTest(prov, status, images, "hash-1", true, now)
+	assert.NoError(t, err)
+	assert.Equal(t, smokeTestRecheck, recheck)
+	job, err := jobs.Get(context.Background(), provisioning.SmokeTestJobName, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "hash-1", job.Annotations[provisioning.RolloutHashAnnotation])
+		assert.Contains(t, job.Spec.Template.Spec.Containers[0].Env[0].Value, "http://192.168.111.20:6385/v1/nodes")
+	}
+	if assert.NotNil(t, status.SmokeTest) {
+		assert.Equal(t, metal3iov1alpha1.SmokeTestRunning, status.SmokeTest.Result)
+	}
+
+	// Still running
+	recheck, err = reconciler.runSmokeTest(prov, status, images, "hash-1", true, now)
+	assert.NoError(t, err)
+	assert.Equal(t, smokeTestRecheck, recheck)
+
+	_, err = reconciler.KubeClient.CoreV1().Pods(ComponentNamespace).Create(context.Background(),
+		newSmokeTestPod(`{"checks": [{"name": "ironic-api", "passed": true}, {"name": "os-image", "passed": false, "message": "HTTP Error 404: Not Found"}]}`),
+		metav1.CreateOptions{})
+	assert.NoError(t, err)
+	finishSmokeTestJob(t, reconciler, batchv1.JobFailed)
+	recheck, err = reconciler.runSmokeTest(prov, status, images, "hash-1", true, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	assert.Equal(t, metal3iov1alpha1.SmokeTestFailed, status.SmokeTest.Result)
+	assert.Len(t, status.SmokeTest.Checks, 2)
+	assert.Equal(t, now.Add(time.Minute), status.SmokeTest.CompletionTime.Time)
+
+	// A new rollout replaces the Job, then tests again
+	recheck, err = reconciler.runSmokeTest(prov, status, images, "hash-2", true, now)
+	assert.NoError(t, err)
+	assert.Equal(t, smokeTestRecheck, recheck)
+	_, err = jobs.Get(context.Background(), provisioning.SmokeTestJobName, metav1.GetOptions{})
+	assert.Error(t, err)
+	_, err = reconciler.runSmokeTest(prov, status, images, "hash-2", true, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "hash-2", status.SmokeTest.RolloutHash)
+	assert.Equal(t, metal3iov1alpha1.SmokeTestRunning, status.SmokeTest.Result)
+
+	// Disabling the smoke test removes the Job and its outcome
+	prov.Spec.SmokeTest = false
+	_, err = reconciler.runSmokeTest(prov, status, images, "hash-2", true, now)
+	assert.NoError(t, err)
+	assert.Nil(t, status.SmokeTest)
+	_, err = jobs.Get(context.Background(), provisioning.SmokeTestJobName, metav1.GetOptions{})
+	assert.Error(t, err)
+}
+
+func TestRunSmokeTestPassed(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec:       metal3iov1alpha1.ProvisioningSpec{ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkDisabled, SmokeTest: true},
+	}
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		newDownloadingMetal3Pod("metal3-abcde", "192.168.111.20", corev1.PodRunning),
+		newSmokeTestPod(`{"checks": [{"name": "ironic-api", "passed": true}]}`),
+	)
+	images := &provisioning.Images{BaremetalIronic: "ironic"}
+	status := &metal3iov1alpha1.ProvisioningStatus{}
+
+	_, err := reconciler.runSmokeTest(prov, status, images, "hash-1", true, now)
+	assert.NoError(t, err)
+	finishSmokeTestJob(t, reconciler, batchv1.JobComplete)
+	_, err = reconciler.runSmokeTest(prov, status, images, "hash-1", true, now)
+	assert.NoError(t, err)
+	assert.Equal(t, metal3iov1alpha1.SmokeTestPassed, status.SmokeTest.Result)
+
+	// A completed test is not run again, even once its Job is gone
+	assert.NoError(t, reconciler.deleteSmokeTestJob())
+	recheck, err := reconciler.runSmokeTest(prov, status, images, "hash-1", true, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), recheck)
+	_, err = reconciler.KubeClient.BatchV1().Jobs(ComponentNamespace).Get(context.Background(), provisioning.SmokeTestJobName, metav1.GetOptions{})
+	assert.Error(t, err)
+}
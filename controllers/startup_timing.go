@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// metal3DeploymentPodSelector selects the pods of the metal3 Deployment,
+// leaving out those of the conductor groups and of dnsmasq
+const metal3DeploymentPodSelector = "k8s-app=metal3,controller=metal3"
+
+var metal3StartupPhaseSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "metal3_pod_startup_phase_seconds",
+	Help: "Duration of the startup phases of the last metal3 pod that became ready, in seconds.",
+}, []string{"phase"})
+
+func init() {
+	metrics.Registry.MustRegister(metal3StartupPhaseSeconds)
+}
+
+// reportStartupTiming exports how long the startup phases of the metal3
+// pods took, and records them in an event, once for each pod that became
+// ready
+func (r *ProvisioningReconciler) reportStartupTiming(prov *metal3iov1alpha1.Provisioning) error {
+	pods, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(),
+		metav1.ListOptions{LabelSelector: metal3DeploymentPodSelector})
+	if err != nil {
+		return err
+	}
+	reported := map[types.UID]bool{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if r.startupTimingReported[pod.UID] {
+			reported[pod.UID] = true
+			continue
+		}
+		phases, ready := provisioning.GetStartupPhases(pod)
+		if !ready {
+			continue
+		}
+		reported[pod.UID] = true
+
+		durations := []string{}
+		for _, phase := range phases {
+			metal3StartupPhaseSeconds.WithLabelValues(phase.Name).Set(phase.Duration.Seconds())
+			durations = append(durations, fmt.Sprintf("%s %s", phase.Name, phase.Duration))
+		}
+		r.Log.Info("metal3 pod started", "pod", pod.Name, "phases", durations)
+		if r.EventRecorder != nil {
+			r.EventRecorder.Event(prov, corev1.EventTypeNormal, "Metal3PodStarted",
+				fmt.Sprintf("metal3 pod %s started: %s", pod.Name, strings.Join(durations, ", ")))
+		}
+	}
+	// Pods that are gone are forgotten
+	r.startupTimingReported = reported
+	return nil
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newStartedMetal3Pod(ready bool) *corev1.Pod {
+	start := metav1.NewTime(time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC))
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+	pod := newMetal3Pod()
+	pod.UID = "5b7c0a4e"
+	pod.Labels["controller"] = "metal3"
+	pod.Status.StartTime = &start
+	pod.Status.Conditions = []corev1.PodCondition{
+		{Type: corev1.PodInitialized, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(start.Add(5 * time.Second))},
+		{Type: corev1.PodReady, Status: readyStatus, LastTransitionTime: metav1.NewTime(start.Add(65 * time.Second))},
+	}
+	return pod
+}
+
+func TestReportStartupTiming(t *testing.T) {
+	tCases := []struct {
+		name           string
+		pod            *corev1.Pod
+		expectedEvents int
+	}{
+		{
+			name: "NotReady",
+			pod:  newStartedMetal3Pod(false),
+		},
+		{
+			name:           "Ready",
+			pod:            newStartedMetal3Pod(true),
+			expectedEvents: 1,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+			}
+			recorder := record.NewFakeRecorder(10)
+			reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+			reconciler.KubeClient = fakekube.NewSimpleClientset(tc.pod)
+			reconciler.EventRecorder = recorder
+
+			// The pod is only reported once
+			assert.NoError(t, reconciler.reportStartupTiming(prov))
+			assert.NoError(t, reconciler.reportStartupTiming(prov))
+			assert.Len(t, recorder.Events, tc.expectedEvents)
+			if tc.expectedEvents == 0 {
+				return
+			}
+			assert.Equal(t, "Normal Metal3PodStarted metal3 pod metal3-abcde started: init 5s, containers 1m0s, total 1m5s", <-recorder.Events)
+
+			metric := &dto.Metric{}
+			assert.NoError(t, metal3StartupPhaseSeconds.WithLabelValues("containers").Write(metric))
+			assert.Equal(t, 60.0, metric.GetGauge().GetValue())
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+
+const (
+	// supportBundleLogLines and supportBundleLogBytes bound the logs of
+	// each container added to the support bundle
+	supportBundleLogLines = 500
+	supportBundleLogBytes = 128 * 1024
+
+	operatorPodSelector = "k8s-app=" + ComponentName
+)
+
+// collectSupportBundle writes the state needed to troubleshoot metal3 to
+// the support bundle ConfigMap. The logs are left out when the bundle
+// would not fit in a ConfigMap.
+func (r *ProvisioningReconciler) collectSupportBundle(prov *metal3iov1alpha1.Provisioning, now time.Time) (string, error) {
+	pods, err := r.listSupportBundlePods()
+	if err != nil {
+		return "", err
+	}
+	files, err := r.supportBundleConfigFiles(prov, pods)
+	if err != nil {
+		return "", err
+	}
+	logs := r.supportBundleLogFiles(pods)
+
+	bundle, err := provisioning.NewSupportBundle(append(files, logs...), now)
+	if err != nil {
+		return "", err
+	}
+	message := fmt.Sprintf("collected %d files in ConfigMap %s", len(files)+len(logs), provisioning.SupportBundleConfigMapName)
+	if len(bundle) > provisioning.MaxSupportBundleSize {
+		if bundle, err = provisioning.NewSupportBundle(files, now); err != nil {
+			return "", err
+		}
+		message = fmt.Sprintf("collected %d files in ConfigMap %s, logs left out to fit in a ConfigMap", len(files), provisioning.SupportBundleConfigMapName)
+	}
+
+	configMap := provisioning.NewSupportBundleConfigMap(ComponentNamespace, bundle)
+	if err := controllerutil.SetControllerReference(prov, configMap, r.Scheme); err != nil {
+		return "", err
+	}
+	if err := provisioning.ApplySupportBundleConfigMap(r.KubeClient.CoreV1(), configMap); err != nil {
+		return "", err
+	}
+	return message, nil
+}
+
+// supportBundleConfigFiles returns the Provisioning CR, its validation,
+// the metal3 workloads and the status of their pods
+func (r *ProvisioningReconciler) supportBundleConfigFiles(prov *metal3iov1alpha1.Provisioning, pods []corev1.Pod) ([]provisioning.SupportBundleFile, error) {
+	ctx := context.Background()
+	files := []provisioning.SupportBundleFile{}
+	add := func(name string, obj interface{}) error {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		files = append(files, provisioning.SupportBundleFile{Name: name, Data: data})
+		return nil
+	}
+
+	saved := prov.DeepCopy()
+	saved.ManagedFields = nil
+	if err := add("provisioning.yaml", saved); err != nil {
+		return nil, err
+	}
+	files = append(files, provisioning.SupportBundleFile{Name: "validation.txt", Data: []byte(r.supportBundleValidation(prov))})
+
+	listOptions := metav1.ListOptions{LabelSelector: metal3PodSelector}
+	deployments, err := r.KubeClient.AppsV1().Deployments(ComponentNamespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		deployment.ManagedFields = nil
+		provisioning.RedactPodSpec(&deployment.Spec.Template.Spec)
+		if err := add(path.Join("deployments", deployment.Name+".yaml"), deployment); err != nil {
+			return nil, err
+		}
+	}
+	daemonSets, err := r.KubeClient.AppsV1().DaemonSets(ComponentNamespace).List(ctx, listOptions)
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonSets.Items {
+		daemonSet := &daemonSets.Items[i]
+		daemonSet.ManagedFields = nil
+		provisioning.RedactPodSpec(&daemonSet.Spec.Template.Spec)
+		if err := add(path.Join("daemonsets", daemonSet.Name+".yaml"), daemonSet); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pod := range pods {
+		if err := add(path.Join("pods", pod.Name+".yaml"), pod.Status); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// supportBundleValidation describes whether the Provisioning CR is
+// valid, and the likely mistakes it holds
+func (r *ProvisioningReconciler) supportBundleValidation(prov *metal3iov1alpha1.Provisioning) string {
+	lines := []string{}
+	if err := provisioning.ValidateBaremetalProvisioningConfig(prov); err != nil {
+		lines = append(lines, "invalid: "+err.Error())
+	} else {
+		lines = append(lines, "valid")
+	}
+	if featureSet, err := r.getFeatureSet(); err != nil {
+		lines = append(lines, "unable to read cluster feature set: "+err.Error())
+	} else if err := provisioning.ValidateFeatureSet(prov, featureSet); err != nil {
+		lines = append(lines, "not enabled: "+err.Error())
+	}
+	for _, warning := range provisioning.GetConfigWarnings(&prov.Spec) {
+		lines = append(lines, "warning: "+warning)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// supportBundleLogFiles returns the recent logs of the containers of the
+// metal3 and operator pods. A log that cannot be read, as for a
+// container that never started, is replaced by the error.
+func (r *ProvisioningReconciler) supportBundleLogFiles(pods []corev1.Pod) []provisioning.SupportBundleFile {
+	tailLines, limitBytes := int64(supportBundleLogLines), int64(supportBundleLogBytes)
+	files := []provisioning.SupportBundleFile{}
+	for _, pod := range pods {
+		containers := append([]corev1.Container{}, pod.Spec.InitContainers...)
+		containers = append(containers, pod.Spec.Containers...)
+		for _, container := range containers {
+			data, err := r.KubeClient.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+				Container:  container.Name,
+				TailLines:  &tailLines,
+				LimitBytes: &limitBytes,
+			}).DoRaw(context.Background())
+			if err != nil {
+				data = []byte(fmt.Sprintf("unable to read logs: %v\n", err))
+			}
+			files = append(files, provisioning.SupportBundleFile{
+				Name: path.Join("logs", pod.Name, container.Name+".log"),
+				Data: data,
+			})
+		}
+	}
+	return files
+}
+
+// listSupportBundlePods returns the metal3 and operator pods
+func (r *ProvisioningReconciler) listSupportBundlePods() ([]corev1.Pod, error) {
+	pods := []corev1.Pod{}
+	for _, selector := range []string{metal3PodSelector, operatorPodSelector} {
+		list, err := r.KubeClient.CoreV1().Pods(ComponentNamespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, list.Items...)
+	}
+	return pods, nil
+}
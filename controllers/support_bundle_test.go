@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func TestCollectSupportBundle(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: BaremetalProvisioningCR},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:     "eth0",
+			ProvisioningIP:            "172.30.20.3",
+			ProvisioningNetworkCIDR:   "172.30.20.0/24",
+			ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+			ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+			ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkManaged,
+		},
+	}
+	pod := newMetal3Pod()
+	pod.Spec.Containers = []corev1.Container{{Name: "metal3-ironic"}}
+
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), prov)
+	reconciler.KubeClient = fakekube.NewSimpleClientset(
+		provisioning.NewMetal3Deployment(ComponentNamespace, &provisioning.Images{}, &prov.Spec), pod)
+
+	message, err := reconciler.collectSupportBundle(prov, time.Now())
+	assert.NoError(t, err)
+
+	configMap, err := reconciler.KubeClient.CoreV1().ConfigMaps(ComponentNamespace).Get(context.Background(),
+		provisioning.SupportBundleConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "collected 5 files in ConfigMap metal3-support-bundle", message)
+	if owner := metav1.GetControllerOf(configMap); assert.NotNil(t, owner) {
+		assert.Equal(t, BaremetalProvisioningCR, owner.Name)
+	}
+
+	files, err := provisioning.ReadSupportBundle(configMap.BinaryData["support-bundle.tar.gz"])
+	assert.NoError(t, err)
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		"deployments/metal3.yaml",
+		"logs/metal3-abcde/metal3-ironic.log",
+		"pods/metal3-abcde.yaml",
+		"provisioning.yaml",
+		"validation.txt",
+	}, names)
+	assert.True(t, strings.HasPrefix(files["validation.txt"], "valid\n"), files["validation.txt"])
+	assert.Equal(t, "fake logs", files["logs/metal3-abcde/metal3-ironic.log"])
+}
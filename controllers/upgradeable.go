@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"sort"
+	"strings"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+)
+
+// upgradeableCondition returns the Upgradeable condition of the
+// ClusterOperator, which is False while an operation that a cluster
+// upgrade restarting the operator or the metal3 pods would disrupt is in
+// progress
+func (r *ProvisioningReconciler) upgradeableCondition() osconfigv1.ClusterOperatorStatusCondition {
+	if len(r.upgradeBlockers) == 0 {
+		return setStatusCondition(osconfigv1.OperatorUpgradeable, osconfigv1.ConditionTrue, "", "")
+	}
+	reasons := []string{}
+	for reason := range r.upgradeBlockers {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	msgs := []string{}
+	for _, reason := range reasons {
+		msgs = append(msgs, r.upgradeBlockers[StatusReason(reason)])
+	}
+	return setStatusCondition(osconfigv1.OperatorUpgradeable, osconfigv1.ConditionFalse, reasons[0], strings.Join(msgs, "; "))
+}
+
+// setUpgradeBlocker records that the operation of the given reason blocks
+// cluster upgrades, or that it does not anymore when msg is empty, and
+// updates the ClusterOperator when its Upgradeable condition differs,
+// including when it was left by a previous run of the operator
+func (r *ProvisioningReconciler) setUpgradeBlocker(reason StatusReason, msg string) error {
+	if r.upgradeBlockers == nil {
+		r.upgradeBlockers = map[StatusReason]string{}
+	}
+	if msg == "" {
+		delete(r.upgradeBlockers, reason)
+	} else {
+		r.upgradeBlockers[reason] = msg
+	}
+
+	co, err := r.getOrCreateClusterOperator()
+	if err != nil {
+		return err
+	}
+	cond := r.upgradeableCondition()
+	if existing := v1helpers.FindStatusCondition(co.Status.Conditions, osconfigv1.OperatorUpgradeable); existing != nil &&
+		existing.Status == cond.Status && existing.Reason == cond.Reason && existing.Message == cond.Message {
+		return nil
+	}
+	return r.syncStatus(co, []osconfigv1.ClusterOperatorStatusCondition{cond})
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	fakeconfigclientset "github.com/openshift/client-go/config/clientset/versioned/fake"
+	"github.com/openshift/library-go/pkg/config/clusteroperator/v1helpers"
+)
+
+func TestSetUpgradeBlocker(t *testing.T) {
+	reconciler := newFakeProvisioningReconciler(setUpSchemeForReconciler(), &osconfigv1.Infrastructure{})
+	// A ClusterOperator created by a previous version of the operator
+	reconciler.OSClient = fakeconfigclientset.NewSimpleClientset(&osconfigv1.ClusterOperator{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterOperatorName},
+		Status: osconfigv1.ClusterOperatorStatus{
+			Conditions:     defaultStatusConditions(),
+			RelatedObjects: []osconfigv1.ObjectReference{{Resource: "namespaces", Name: ComponentNamespace}},
+		},
+	})
+	upgradeable := func() *osconfigv1.ClusterOperatorStatusCondition {
+		co, err := reconciler.OSClient.ConfigV1().ClusterOperators().Get(context.Background(), clusterOperatorName, metav1.GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, relatedObjects(), co.Status.RelatedObjects)
+		return v1helpers.FindStatusCondition(co.Status.Conditions, osconfigv1.OperatorUpgradeable)
+	}
+
+	assert.NoError(t, reconciler.setUpgradeBlocker(ReasonOperandRolloutInProgress, "metal3 revision 1234 is being verified"))
+	assert.NoError(t, reconciler.setUpgradeBlocker(ReasonLegacyTakeoverInProgress, "taking over"))
+	cond := upgradeable()
+	assert.Equal(t, osconfigv1.ConditionFalse, cond.Status)
+	assert.Equal(t, string(ReasonLegacyTakeoverInProgress), cond.Reason)
+	assert.Equal(t, "taking over; metal3 revision 1234 is being verified", cond.Message)
+
+	// Reporting another state keeps cluster upgrades blocked
+	assert.NoError(t, reconciler.updateCOStatus(ReasonDeploymentCrashLooping, "crash looping", ""))
+	assert.Equal(t, osconfigv1.ConditionFalse, upgradeable().Status)
+
+	assert.NoError(t, reconciler.setUpgradeBlocker(ReasonLegacyTakeoverInProgress, ""))
+	cond = upgradeable()
+	assert.Equal(t, osconfigv1.ConditionFalse, cond.Status)
+	assert.Equal(t, string(ReasonOperandRolloutInProgress), cond.Reason)
+
+	assert.NoError(t, reconciler.setUpgradeBlocker(ReasonOperandRolloutInProgress, ""))
+	assert.Equal(t, osconfigv1.ConditionTrue, upgradeable().Status)
+}
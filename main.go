@@ -18,9 +18,12 @@ package main
 import (
 	"flag"
 	"os"
+	"path/filepath"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/client-go/rest"
@@ -34,6 +37,7 @@ import (
 	osclientset "github.com/openshift/client-go/config/clientset/versioned"
 	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
 	"github.com/openshift/cluster-baremetal-operator/controllers"
+	"github.com/openshift/cluster-baremetal-operator/webhooks"
 )
 
 var (
@@ -60,11 +64,46 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == renderProvisioningCommand {
+		os.Exit(renderProvisioning(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
+	var enableWebhook bool
+	var healthAddr string
+	var webhookCertDir string
+	var resyncPeriod time.Duration
+	var createNamespace bool
+	var profilingAddr string
+	var reconcileProfileDir string
+	var reconcileProfileCount int
+	var kubeAPIQPS float64
+	var kubeAPIBurst int
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&healthAddr, "health-addr", ":9440", "The address the liveness and readiness probe endpoints bind to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhook, "enable-webhook", false,
+		"Serve the Provisioning mutating and validating webhooks. Requires a serving certificate in the webhook server certificate directory.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"),
+		"The directory holding the tls.crt and tls.key serving certificate of the webhook server. The certificate is reloaded when rotated.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 10*time.Hour,
+		"The minimum interval at which the watched resources are reconciled again when unchanged.")
+	flag.StringVar(&controllers.ComponentNamespace, "namespace", controllers.ComponentNamespace,
+		"The namespace the metal3 services run in, and the operator reads their configuration from.")
+	flag.BoolVar(&createNamespace, "create-namespace", false,
+		"Create the namespace of the metal3 services when it is missing.")
+	flag.StringVar(&profilingAddr, "profiling-addr", "",
+		"The loopback address the pprof endpoints are served on under /debug/pprof/, e.g. 127.0.0.1:6060. Profiling is disabled when empty.")
+	flag.StringVar(&reconcileProfileDir, "reconcile-profile-dir", "",
+		"Write a CPU and a heap profile of each reconcile to this directory. Profiling is disabled when empty.")
+	flag.IntVar(&reconcileProfileCount, "reconcile-profile-count", 20,
+		"The number of reconciles whose profiles are kept in the reconcile profile directory.")
+	flag.Float64Var(&kubeAPIQPS, "kube-api-qps", float64(rest.DefaultQPS),
+		"The sustained rate of the requests of each client of the operator to the API server, in queries per second.")
+	flag.IntVar(&kubeAPIBurst, "kube-api-burst", rest.DefaultBurst,
+		"The number of requests each client of the operator sends to the API server in a burst before being throttled.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(func(o *zap.Options) {
@@ -77,11 +116,15 @@ func main() {
 	}
 
 	config := ctrl.GetConfigOrDie()
+	config.QPS = float32(kubeAPIQPS)
+	config.Burst = kubeAPIBurst
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: metricsAddr,
-		LeaderElection:     enableLeaderElection,
-		Port:               9443,
+		Scheme:                 scheme,
+		MetricsBindAddress:     metricsAddr,
+		HealthProbeBindAddress: healthAddr,
+		LeaderElection:         enableLeaderElection,
+		SyncPeriod:             &resyncPeriod,
+		NewCache:               controllers.NewCache,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
@@ -89,19 +132,57 @@ func main() {
 	}
 
 	osClient := osclientset.NewForConfigOrDie(rest.AddUserAgent(config, controllers.ComponentName))
+	kubeClient := kubernetes.NewForConfigOrDie(rest.AddUserAgent(config, controllers.ComponentName))
 	recorder := record.NewBroadcaster().NewRecorder(clientgoscheme.Scheme, v1.EventSource{Component: controllers.ComponentName})
 
+	var profiler *controllers.ReconcileProfiler
+	if reconcileProfileDir != "" {
+		if err := os.MkdirAll(reconcileProfileDir, 0750); err != nil {
+			setupLog.Error(err, "unable to create the reconcile profile directory")
+			os.Exit(1)
+		}
+		profiler = &controllers.ReconcileProfiler{
+			Dir:  reconcileProfileDir,
+			Keep: reconcileProfileCount,
+			Log:  ctrl.Log.WithName("profiler"),
+		}
+	}
+
 	if err = (&controllers.ProvisioningReconciler{
-		Client:         mgr.GetClient(),
-		Log:            ctrl.Log.WithName("controllers").WithName("Provisioning"),
-		Scheme:         mgr.GetScheme(),
-		OSClient:       osClient,
-		EventRecorder:  recorder,
-		ReleaseVersion: releaseVersion,
+		Client:          mgr.GetClient(),
+		Log:             ctrl.Log.WithName("controllers").WithName("Provisioning"),
+		Scheme:          mgr.GetScheme(),
+		OSClient:        osClient,
+		KubeClient:      kubeClient,
+		EventRecorder:   recorder,
+		ReleaseVersion:  releaseVersion,
+		CreateNamespace: createNamespace,
+		Profiler:        profiler,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Provisioning")
 		os.Exit(1)
 	}
+	if enableWebhook {
+		if err := webhooks.SetupWithManager(mgr, webhookCertDir); err != nil {
+			setupLog.Error(err, "unable to set up the webhooks")
+			os.Exit(1)
+		}
+	}
+	if profilingAddr != "" {
+		if err := controllers.SetupProfilingHandlers(mgr, profilingAddr); err != nil {
+			setupLog.Error(err, "unable to set up the profiling endpoints")
+			os.Exit(1)
+		}
+	}
+
+	healthChecks := &controllers.HealthChecks{KubeClient: kubeClient}
+	if enableWebhook {
+		healthChecks.WebhookCertDir = webhookCertDir
+	}
+	if err := healthChecks.SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to set up health checks")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
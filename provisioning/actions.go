@@ -0,0 +1,121 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ActionAnnotation, when set on the Provisioning CR, requests an
+	// action to be run once. It is removed when the action has run, and
+	// the outcome recorded in the LastAction of the status.
+	ActionAnnotation = "baremetal.openshift.io/action"
+
+	// ActionRefreshImages rolls out the metal3 pods, which empty the
+	// image cache of their node before downloading the images again
+	ActionRefreshImages = "RefreshImages"
+	// ActionRotateCredentials replaces the Ironic and Inspector
+	// passwords, then restarts the metal3 pods
+	ActionRotateCredentials = "RotateCredentials"
+	// ActionRevalidate runs the provisioning interface checks again
+	ActionRevalidate = "Revalidate"
+	// ActionCollectSupportBundle writes a support bundle to the
+	// SupportBundleConfigMapName ConfigMap
+	ActionCollectSupportBundle = "CollectSupportBundle"
+
+	// ImageRefreshGenerationAnnotation counts the RefreshImages actions
+	// carried out. It is recorded on the Provisioning CR along with the
+	// removal of the ActionAnnotation, so that a refresh is carried out
+	// once even when the removal has to be retried, and copied to the
+	// metal3 pod templates.
+	ImageRefreshGenerationAnnotation = "baremetal.openshift.io/image-refresh-generation"
+
+	metal3PodLabelSelector = "k8s-app=" + metal3AppName
+)
+
+var supportedActions = []string{ActionRefreshImages, ActionRotateCredentials, ActionRevalidate, ActionCollectSupportBundle}
+
+// GetRequestedAction returns the action requested on the Provisioning CR,
+// or an empty string
+func GetRequestedAction(prov *metal3iov1alpha1.Provisioning) string {
+	return prov.Annotations[ActionAnnotation]
+}
+
+// ValidateAction checks that an action is supported
+func ValidateAction(action string) error {
+	for _, supported := range supportedActions {
+		if action == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action %q, must be one of %v", action, supportedActions)
+}
+
+// GetImageRefreshGeneration returns the image refresh generation of the
+// Provisioning CR, or an empty string when the images were never
+// refreshed
+func GetImageRefreshGeneration(prov *metal3iov1alpha1.Provisioning) string {
+	return prov.Annotations[ImageRefreshGenerationAnnotation]
+}
+
+// NextImageRefreshGeneration returns the image refresh generation
+// following the one of the Provisioning CR
+func NextImageRefreshGeneration(prov *metal3iov1alpha1.Provisioning) string {
+	generation, err := strconv.ParseInt(GetImageRefreshGeneration(prov), 10, 64)
+	if err != nil {
+		generation = 0
+	}
+	return strconv.FormatInt(generation+1, 10)
+}
+
+// SetImageRefreshGeneration records the image refresh generation on a pod
+// template running the downloaders, so that its pods are rolled out and
+// purge the image cache when the images are refreshed
+func SetImageRefreshGeneration(template *corev1.PodTemplateSpec, generation string) {
+	metav1.SetMetaDataAnnotation(&template.ObjectMeta, ImageRefreshGenerationAnnotation, generation)
+}
+
+// DeleteIronicPasswordSecrets removes the Ironic and Inspector passwords,
+// so that new ones are generated
+func DeleteIronicPasswordSecrets(client coreclientv1.SecretsGetter, targetNamespace string) error {
+	for _, name := range []string{ironicSecretName, inspectorSecretName} {
+		err := client.Secrets(targetNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return apiError(err)
+		}
+	}
+	return nil
+}
+
+// RestartMetal3Pods deletes the metal3 pods, of every provisioning domain,
+// so that their Deployments start them again. It returns how many pods
+// were restarted.
+func RestartMetal3Pods(client coreclientv1.PodsGetter, targetNamespace string) (int, error) {
+	pods, err := client.Pods(targetNamespace).List(context.Background(), metav1.ListOptions{LabelSelector: metal3PodLabelSelector})
+	if err != nil {
+		return 0, apiError(err)
+	}
+	restarted := 0
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		err := client.Pods(targetNamespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return restarted, apiError(err)
+		}
+		restarted++
+	}
+	return restarted, nil
+}
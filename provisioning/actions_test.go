@@ -0,0 +1,83 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newLabelledPod(name string, app string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels:    map[string]string{"k8s-app": app},
+		},
+	}
+}
+
+func TestValidateAction(t *testing.T) {
+	for _, action := range []string{ActionRefreshImages, ActionRotateCredentials, ActionRevalidate, ActionCollectSupportBundle} {
+		assert.NoError(t, ValidateAction(action), action)
+	}
+	assert.EqualError(t, ValidateAction("Reboot"),
+		`unknown action "Reboot", must be one of [RefreshImages RotateCredentials Revalidate CollectSupportBundle]`)
+}
+
+func TestImageRefreshGeneration(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{}
+	assert.Equal(t, "", GetImageRefreshGeneration(prov))
+	assert.Equal(t, "1", NextImageRefreshGeneration(prov))
+
+	prov.Annotations = map[string]string{ImageRefreshGenerationAnnotation: "41"}
+	assert.Equal(t, "42", NextImageRefreshGeneration(prov))
+	prov.Annotations[ImageRefreshGenerationAnnotation] = "invalid"
+	assert.Equal(t, "1", NextImageRefreshGeneration(prov))
+
+	template := &corev1.PodTemplateSpec{}
+	SetImageRefreshGeneration(template, "42")
+	assert.Equal(t, "42", template.Annotations[ImageRefreshGenerationAnnotation])
+}
+
+func TestRestartMetal3Pods(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset(
+		newLabelledPod("metal3-abcde", "metal3"),
+		newLabelledPod("metal3-rack2-fghij", "metal3"),
+		newLabelledPod("metal3-dnsmasq-klmno", "metal3-dnsmasq"))
+
+	restarted, err := RestartMetal3Pods(kubeClient.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restarted)
+
+	pods, err := kubeClient.CoreV1().Pods(testNamespace).List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, pods.Items, 1) {
+		assert.Equal(t, "metal3-dnsmasq-klmno", pods.Items[0].Name)
+	}
+}
+
+func TestDeleteIronicPasswordSecrets(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+	// Nothing to delete before the secrets are created
+	assert.NoError(t, DeleteIronicPasswordSecrets(kubeClient.CoreV1(), testNamespace))
+
+	assert.NoError(t, CreateMariadbPasswordSecret(kubeClient.CoreV1(), testNamespace))
+	assert.NoError(t, CreateIronicPasswordSecret(kubeClient.CoreV1(), testNamespace))
+	assert.NoError(t, CreateInspectorPasswordSecret(kubeClient.CoreV1(), testNamespace))
+	assert.NoError(t, DeleteIronicPasswordSecrets(kubeClient.CoreV1(), testNamespace))
+
+	secrets := kubeClient.CoreV1().Secrets(testNamespace)
+	for _, name := range []string{ironicSecretName, inspectorSecretName} {
+		_, err := secrets.Get(context.Background(), name, metav1.GetOptions{})
+		assert.True(t, apierrors.IsNotFound(err), name)
+	}
+	_, err := secrets.Get(context.Background(), baremetalSecretName, metav1.GetOptions{})
+	assert.NoError(t, err, "the database password is kept")
+}
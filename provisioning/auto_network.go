@@ -0,0 +1,195 @@
+package provisioning
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ProvisioningInterfaceLabel is the node label naming the provisioning
+	// interface used by the Auto ProvisioningNetwork mode
+	ProvisioningInterfaceLabel = "baremetal.openshift.io/provisioning-interface"
+
+	networkDetectionAppName = "metal3-network-detection"
+
+	// The network of the installer, used when none is set
+	defaultAutoNetworkCIDR    = "172.22.0.0/24"
+	defaultAutoProvisioningIP = "172.22.0.3"
+)
+
+// networkDetectionScript writes the only physical NIC of the node that has
+// a carrier, no address but link-local ones and is neither enslaved nor
+// used by a default route to the termination message, and fails with the
+// reason when there is not exactly one.
+const networkDetectionScript = `
+set -u
+default_interfaces=$(ip -o route show default; ip -o -6 route show default)
+candidates=""
+for path in /sys/class/net/*; do
+    iface=${path##*/}
+    [ -e "$path/device" ] && [ ! -e "$path/master" ] || continue
+    [ "$(cat "$path/carrier" 2>/dev/null)" = "1" ] || continue
+    if echo "$default_interfaces" | grep -qw "dev $iface"; then
+        continue
+    fi
+    if [ -n "$(ip -o address show dev "$iface" scope global)" ]; then
+        continue
+    fi
+    candidates="$candidates $iface"
+done
+candidates=${candidates# }
+case "$candidates" in
+"")
+    echo "no connected NIC with only link-local addresses" > /dev/termination-log
+    exit 1
+    ;;
+*" "*)
+    echo "several NICs with only link-local addresses: $candidates" > /dev/termination-log
+    exit 1
+    ;;
+esac
+echo -n "$candidates" > /dev/termination-log
+`
+
+// IsAutoProvisioningNetwork returns true when the provisioning network has
+// to be detected by the operator
+func IsAutoProvisioningNetwork(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.ProvisioningNetwork == metal3iov1alpha1.ProvisioningNetworkAuto
+}
+
+// validateAutoConfig checks the settings of the Auto mode, which are all
+// detected or defaulted except for the OS image
+func validateAutoConfig(prov *metal3iov1alpha1.Provisioning) error {
+	if prov.Spec.ProvisioningOSDownloadURL == "" {
+		return requiredFieldError("ProvisioningOSDownloadURL")
+	}
+	if (prov.Spec.ProvisioningIP == "") != (prov.Spec.ProvisioningNetworkCIDR == "") {
+		// The field left empty is reported
+		field := "spec.provisioningNetworkCIDR"
+		if prov.Spec.ProvisioningIP == "" {
+			field = "spec.provisioningIP"
+		}
+		return fieldErrorf(field, "ProvisioningIP and ProvisioningNetworkCIDR must be set together in %s mode", metal3iov1alpha1.ProvisioningNetworkAuto)
+	}
+	return nil
+}
+
+// ResolveAutoProvisioningNetwork returns the Managed configuration the
+// Auto mode resolves to with the given interface, which is only used when
+// the spec does not set one
+func ResolveAutoProvisioningNetwork(config *metal3iov1alpha1.ProvisioningSpec, iface string) *metal3iov1alpha1.ProvisioningSpec {
+	resolved := config.DeepCopy()
+	resolved.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkManaged
+	if resolved.ProvisioningInterface == "" {
+		resolved.ProvisioningInterface = iface
+	}
+	if resolved.ProvisioningNetworkCIDR == "" {
+		resolved.ProvisioningNetworkCIDR = defaultAutoNetworkCIDR
+		resolved.ProvisioningIP = defaultAutoProvisioningIP
+	}
+	return resolved
+}
+
+// GetDetectedNetwork returns the status of the network the Auto mode
+// resolved to
+func GetDetectedNetwork(resolved *metal3iov1alpha1.ProvisioningSpec, source metal3iov1alpha1.ProvisioningNetworkSource, node string) *metal3iov1alpha1.DetectedProvisioningNetwork {
+	return &metal3iov1alpha1.DetectedProvisioningNetwork{
+		Interface:               resolved.ProvisioningInterface,
+		Source:                  source,
+		Node:                    node,
+		ProvisioningIP:          resolved.ProvisioningIP,
+		ProvisioningNetworkCIDR: resolved.ProvisioningNetworkCIDR,
+	}
+}
+
+// GetLabelledProvisioningInterface returns the provisioning interface the
+// nodes are labelled with, or an empty string when none is. The nodes
+// labelled must agree on the interface.
+func GetLabelledProvisioningInterface(nodes []corev1.Node) (string, error) {
+	iface, labelledNode := "", ""
+	for _, node := range nodes {
+		value := node.Labels[ProvisioningInterfaceLabel]
+		if value == "" {
+			continue
+		}
+		if iface != "" && value != iface {
+			return "", fmt.Errorf("nodes %s and %s are labelled with different provisioning interfaces %q and %q",
+				labelledNode, node.Name, iface, value)
+		}
+		iface, labelledNode = value, node.Name
+	}
+	return iface, nil
+}
+
+// NetworkDetectionPodName returns the name of the pod detecting the
+// provisioning interface on the given node
+func NetworkDetectionPodName(nodeName string) string {
+	return networkDetectionAppName + "-" + nodeName
+}
+
+// NewNetworkDetectionPod returns a pod that succeeds with the provisioning
+// interface of the given node as termination message, and fails with the
+// reason it was not found otherwise
+func NewNetworkDetectionPod(targetNamespace string, nodeName string, images *Images) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      NetworkDetectionPodName(nodeName),
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": networkDetectionAppName,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:          nodeName,
+			HostNetwork:       true,
+			RestartPolicy:     corev1.RestartPolicyNever,
+			PriorityClassName: metal3PriorityClassName,
+			Containers: []corev1.Container{
+				{
+					Name:                     networkDetectionAppName,
+					Image:                    images.BaremetalStaticIpManager,
+					Command:                  []string{"/bin/bash", "-c", networkDetectionScript},
+					ImagePullPolicy:          "IfNotPresent",
+					TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+				},
+			},
+			ServiceAccountName:            serviceAccountName,
+			TerminationGracePeriodSeconds: pointer.Int64Ptr(0),
+			Tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+		},
+	}
+}
+
+// GetNetworkDetectionResult returns the interface found by a completed
+// detection pod, or the reason none was found. Both are empty while the
+// pod runs.
+func GetNetworkDetectionResult(pod *corev1.Pod) (iface string, failure string) {
+	message := ""
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil {
+			message = status.State.Terminated.Message
+		}
+	}
+	switch pod.Status.Phase {
+	case corev1.PodSucceeded:
+		if message != "" {
+			return message, ""
+		}
+		return "", "detection pod reported no interface"
+	case corev1.PodFailed:
+		if message == "" {
+			message = "detection pod failed"
+		}
+		return "", message
+	}
+	return "", ""
+}
@@ -0,0 +1,140 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func autoProvisioning() *metal3iov1alpha1.Provisioning {
+	return &metal3iov1alpha1.Provisioning{
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkAuto,
+			ProvisioningOSDownloadURL: "http://example.com/rhcos.qcow2.gz?sha256=abc",
+		},
+	}
+}
+
+func TestValidateAutoProvisioningConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		modify        func(*metal3iov1alpha1.ProvisioningSpec)
+		expectedError string
+	}{
+		{
+			name:   "Defaults",
+			modify: func(*metal3iov1alpha1.ProvisioningSpec) {},
+		},
+		{
+			name: "Network",
+			modify: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.ProvisioningIP = "fd00:1101::3"
+				spec.ProvisioningNetworkCIDR = "fd00:1101::0/64"
+			},
+		},
+		{
+			name:          "NoOSImage",
+			modify:        func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ProvisioningOSDownloadURL = "" },
+			expectedError: "ProvisioningOSDownloadURL is required but is empty",
+		},
+		{
+			name:          "IPWithoutNetwork",
+			modify:        func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ProvisioningIP = "172.22.0.3" },
+			expectedError: "ProvisioningIP and ProvisioningNetworkCIDR must be set together in Auto mode",
+		},
+		{
+			name: "RangeOutsideDefaultNetwork",
+			modify: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.ProvisioningDHCPRange = "172.30.20.11, 172.30.20.101"
+			},
+			expectedError: `ProvisioningDHCPRange address "172.30.20.11" is not in the ProvisioningNetworkCIDR "172.22.0.0/24"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := autoProvisioning()
+			tc.modify(&prov.Spec)
+			err := validateBaremetalProvisioningConfig(prov)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestResolveAutoProvisioningNetwork(t *testing.T) {
+	spec := &autoProvisioning().Spec
+	resolved := ResolveAutoProvisioningNetwork(spec, "ens3")
+	assert.Equal(t, metal3iov1alpha1.ProvisioningNetworkManaged, resolved.ProvisioningNetwork)
+	assert.Equal(t, "ens3", resolved.ProvisioningInterface)
+	assert.Equal(t, "172.22.0.3", resolved.ProvisioningIP)
+	assert.Equal(t, "172.22.0.0/24", resolved.ProvisioningNetworkCIDR)
+	assert.Equal(t, metal3iov1alpha1.ProvisioningNetworkAuto, spec.ProvisioningNetwork)
+	assert.True(t, IsDnsmasqRequired(resolved))
+
+	spec.ProvisioningInterface = "eth1"
+	spec.ProvisioningIP = "10.0.0.3"
+	spec.ProvisioningNetworkCIDR = "10.0.0.0/24"
+	resolved = ResolveAutoProvisioningNetwork(spec, "ens3")
+	assert.Equal(t, "eth1", resolved.ProvisioningInterface)
+	assert.Equal(t, "10.0.0.3", resolved.ProvisioningIP)
+	assert.Equal(t, "10.0.0.0/24", resolved.ProvisioningNetworkCIDR)
+
+	assert.NoError(t, ValidatePlatformSupport(autoProvisioning(), osconfigv1.BareMetalPlatformType))
+}
+
+func TestGetLabelledProvisioningInterface(t *testing.T) {
+	node := func(name string, iface string) corev1.Node {
+		labels := map[string]string{}
+		if iface != "" {
+			labels[ProvisioningInterfaceLabel] = iface
+		}
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	iface, err := GetLabelledProvisioningInterface([]corev1.Node{node("master-0", ""), node("master-1", "")})
+	assert.NoError(t, err)
+	assert.Equal(t, "", iface)
+
+	iface, err = GetLabelledProvisioningInterface([]corev1.Node{node("master-0", ""), node("master-1", "ens4"), node("master-2", "ens4")})
+	assert.NoError(t, err)
+	assert.Equal(t, "ens4", iface)
+
+	_, err = GetLabelledProvisioningInterface([]corev1.Node{node("master-0", "ens4"), node("master-1", "ens5")})
+	assert.EqualError(t, err, `nodes master-0 and master-1 are labelled with different provisioning interfaces "ens4" and "ens5"`)
+}
+
+func TestGetNetworkDetectionResult(t *testing.T) {
+	pod := NewNetworkDetectionPod(testNamespace, "master-0", &testImages)
+	assert.Equal(t, "master-0", pod.Spec.NodeName)
+	assert.True(t, pod.Spec.HostNetwork)
+
+	iface, failure := GetNetworkDetectionResult(pod)
+	assert.Equal(t, "", iface)
+	assert.Equal(t, "", failure)
+
+	pod.Status = corev1.PodStatus{
+		Phase: corev1.PodSucceeded,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Message: "ens3"}},
+		}},
+	}
+	iface, failure = GetNetworkDetectionResult(pod)
+	assert.Equal(t, "ens3", iface)
+	assert.Equal(t, "", failure)
+
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.ContainerStatuses[0].State.Terminated.Message = "no connected NIC with only link-local addresses"
+	iface, failure = GetNetworkDetectionResult(pod)
+	assert.Equal(t, "", iface)
+	assert.Equal(t, "no connected NIC with only link-local addresses", failure)
+}
@@ -17,7 +17,9 @@ package provisioning
 
 import (
 	"fmt"
+	"math/big"
 	"net"
+	"strings"
 
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -28,6 +30,7 @@ import (
 var (
 	log                            = ctrl.Log.WithName("provisioning")
 	baremetalHttpPort              = "6180"
+	baremetalHttpsPort             = "6183"
 	baremetalIronicPort            = "6385"
 	baremetalIronicInspectorPort   = "5050"
 	baremetalKernelUrlSubPath      = "images/ironic-python-agent.kernel"
@@ -40,21 +43,20 @@ var (
 	ironicEndpoint                 = "IRONIC_ENDPOINT"
 	ironicInspectorEndpoint        = "IRONIC_INSPECTOR_ENDPOINT"
 	httpPort                       = "HTTP_PORT"
+	httpsPort                      = "VMEDIA_TLS_PORT"
 	dhcpRange                      = "DHCP_RANGE"
 	machineImageUrl                = "RHCOS_IMAGE_URL"
 )
 
-// ValidateBaremetalProvisioningConfig validates the contents of the provisioning resource
+// ValidateBaremetalProvisioningConfig validates the contents of the
+// provisioning resource. The errors returned match ErrInvalidSpec.
 func ValidateBaremetalProvisioningConfig(prov *metal3iov1alpha1.Provisioning) error {
-	provisioningNetworkMode := getProvisioningNetworkMode(prov)
-	log.V(1).Info("provisioning network", "mode", provisioningNetworkMode)
-	switch provisioningNetworkMode {
-	case metal3iov1alpha1.ProvisioningNetworkManaged:
-		return validateManagedConfig(prov)
-	case metal3iov1alpha1.ProvisioningNetworkUnmanaged:
-		return validateUnmanagedConfig(prov)
-	case metal3iov1alpha1.ProvisioningNetworkDisabled:
-		return validateDisabledConfig(prov)
+	return NewInvalidSpecError(validateBaremetalProvisioningConfig(prov))
+}
+
+func validateBaremetalProvisioningConfig(prov *metal3iov1alpha1.Provisioning) error {
+	if errs := validateSpecFields(prov); len(errs) > 0 {
+		return errs[0]
 	}
 	return nil
 }
@@ -75,7 +77,7 @@ func getProvisioningNetworkMode(prov *metal3iov1alpha1.Provisioning) metal3iov1a
 }
 
 func validateManagedConfig(prov *metal3iov1alpha1.Provisioning) error {
-	for _, toTest := range []struct {
+	required := []struct {
 		Name  string
 		Value string
 	}{
@@ -83,11 +85,18 @@ func validateManagedConfig(prov *metal3iov1alpha1.Provisioning) error {
 		{Name: "ProvisioningInterface", Value: prov.Spec.ProvisioningInterface},
 		{Name: "ProvisioningIP", Value: prov.Spec.ProvisioningIP},
 		{Name: "ProvisioningNetworkCIDR", Value: prov.Spec.ProvisioningNetworkCIDR},
-		{Name: "ProvisioningDHCPRange", Value: prov.Spec.ProvisioningDHCPRange},
 		{Name: "ProvisioningOSDownloadURL", Value: prov.Spec.ProvisioningOSDownloadURL},
-	} {
+	}
+	// Without strict validation a default DHCP range is computed instead
+	if prov.Spec.StrictDHCPRangeValidation {
+		required = append(required, struct {
+			Name  string
+			Value string
+		}{Name: "ProvisioningDHCPRange", Value: prov.Spec.ProvisioningDHCPRange})
+	}
+	for _, toTest := range required {
 		if toTest.Value == "" {
-			return fmt.Errorf("%s is required but is empty", toTest.Name)
+			return requiredFieldError(toTest.Name)
 		}
 	}
 	return nil
@@ -105,7 +114,7 @@ func validateUnmanagedConfig(prov *metal3iov1alpha1.Provisioning) error {
 		{Name: "ProvisioningOSDownloadURL", Value: prov.Spec.ProvisioningOSDownloadURL},
 	} {
 		if toTest.Value == "" {
-			return fmt.Errorf("%s is required but is empty", toTest.Name)
+			return requiredFieldError(toTest.Name)
 		}
 	}
 	return nil
@@ -122,12 +131,179 @@ func validateDisabledConfig(prov *metal3iov1alpha1.Provisioning) error {
 		{Name: "ProvisioningOSDownloadURL", Value: prov.Spec.ProvisioningOSDownloadURL},
 	} {
 		if toTest.Value == "" {
-			return fmt.Errorf("%s is required but is empty", toTest.Name)
+			return requiredFieldError(toTest.Name)
+		}
+	}
+	return nil
+}
+
+// validateProvisioningAddresses checks that the provisioning addresses can
+// be parsed and belong to the provisioning network. IPv4 and IPv6 are
+// handled identically, but all addresses have to be of the same family
+// as the ProvisioningNetworkCIDR.
+func validateProvisioningAddresses(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	_, network, err := net.ParseCIDR(prov.Spec.ProvisioningNetworkCIDR)
+	if err != nil {
+		return fieldErrorf("spec.provisioningNetworkCIDR", "could not parse ProvisioningNetworkCIDR %q", prov.Spec.ProvisioningNetworkCIDR)
+	}
+
+	ip := net.ParseIP(prov.Spec.ProvisioningIP)
+	if ip == nil {
+		return fieldErrorf("spec.provisioningIP", "could not parse ProvisioningIP %q", prov.Spec.ProvisioningIP)
+	}
+	if !network.Contains(ip) {
+		return fieldErrorf("spec.provisioningIP", "ProvisioningIP %q is not in the ProvisioningNetworkCIDR %q", prov.Spec.ProvisioningIP, prov.Spec.ProvisioningNetworkCIDR)
+	}
+
+	if mode == metal3iov1alpha1.ProvisioningNetworkManaged {
+		if prov.Spec.ProvisioningDHCPRange == "" {
+			// The default range is computed from the network
+			if _, err := getDefaultDHCPRange(&prov.Spec); err != nil {
+				return newFieldError("spec.provisioningNetworkCIDR", err)
+			}
+			return nil
+		}
+		start, end, err := parseDHCPRange(prov.Spec.ProvisioningDHCPRange)
+		if err != nil {
+			return newFieldError("spec.provisioningDHCPRange", err)
+		}
+		for _, rangeIP := range []net.IP{start, end} {
+			if !network.Contains(rangeIP) {
+				return fieldErrorf("spec.provisioningDHCPRange", "ProvisioningDHCPRange address %q is not in the ProvisioningNetworkCIDR %q", rangeIP, prov.Spec.ProvisioningNetworkCIDR)
+			}
+		}
+		if compareIPs(start, end) > 0 {
+			return fieldErrorf("spec.provisioningDHCPRange", "ProvisioningDHCPRange %q starts after its end", prov.Spec.ProvisioningDHCPRange)
+		}
+	}
+	return nil
+}
+
+// parseDHCPRange returns the first and last address of a DHCP range
+// written as two comma separated IP addresses
+func parseDHCPRange(dhcpRange string) (net.IP, net.IP, error) {
+	addresses := strings.Split(dhcpRange, ",")
+	if len(addresses) != 2 {
+		return nil, nil, fmt.Errorf("ProvisioningDHCPRange %q must be two comma separated IP addresses", dhcpRange)
+	}
+	start := net.ParseIP(strings.TrimSpace(addresses[0]))
+	end := net.ParseIP(strings.TrimSpace(addresses[1]))
+	if start == nil || end == nil {
+		return nil, nil, fmt.Errorf("could not parse ProvisioningDHCPRange %q", dhcpRange)
+	}
+	return start, end, nil
+}
+
+// addToIP returns the IP address offset by n from ip
+func addToIP(ip net.IP, n int64) net.IP {
+	length := net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		length = net.IPv4len
+	}
+	value := new(big.Int).SetBytes(ip)
+	value.Add(value, big.NewInt(n))
+	bytes := value.Bytes()
+	if len(bytes) > length {
+		return nil
+	}
+	result := make(net.IP, length)
+	copy(result[length-len(bytes):], bytes)
+	return result
+}
+
+func compareIPs(a, b net.IP) int {
+	return new(big.Int).SetBytes(a.To16()).Cmp(new(big.Int).SetBytes(b.To16()))
+}
+
+// getDefaultDHCPRange computes the DHCP range used when none is
+// configured: from the .10 address of the ProvisioningNetworkCIDR up to
+// the penultimate address of the network. When the ProvisioningIP falls
+// within that range, the larger side of the range around it is used.
+func getDefaultDHCPRange(config *metal3iov1alpha1.ProvisioningSpec) (string, error) {
+	_, network, err := net.ParseCIDR(config.ProvisioningNetworkCIDR)
+	if err != nil {
+		return "", fmt.Errorf("could not parse ProvisioningNetworkCIDR %q", config.ProvisioningNetworkCIDR)
+	}
+
+	ones, bits := network.Mask.Size()
+	last := make(net.IP, len(network.IP))
+	for i := range network.IP {
+		last[i] = network.IP[i] | ^network.Mask[i]
+	}
+	start := addToIP(network.IP, 10)
+	end := addToIP(last, -1)
+	if start == nil || end == nil || bits-ones < 5 || compareIPs(start, end) > 0 {
+		return "", fmt.Errorf("ProvisioningNetworkCIDR %q is too small for a default ProvisioningDHCPRange", config.ProvisioningNetworkCIDR)
+	}
+
+	if ip := net.ParseIP(config.ProvisioningIP); ip != nil && compareIPs(ip, start) >= 0 && compareIPs(ip, end) <= 0 {
+		below := new(big.Int).Sub(new(big.Int).SetBytes(ip.To16()), new(big.Int).SetBytes(start.To16()))
+		above := new(big.Int).Sub(new(big.Int).SetBytes(end.To16()), new(big.Int).SetBytes(ip.To16()))
+		if below.Cmp(above) > 0 {
+			end = addToIP(ip, -1)
+		} else {
+			start = addToIP(ip, 1)
 		}
+		if compareIPs(start, end) > 0 {
+			return "", fmt.Errorf("ProvisioningNetworkCIDR %q is too small for a default ProvisioningDHCPRange", config.ProvisioningNetworkCIDR)
+		}
+	}
+	return fmt.Sprintf("%s,%s", start, end), nil
+}
+
+// NormalizeProvisioningSpec rewrites the values accepted in several forms
+// into the one the metal3 pods are rendered from, so that the pods are the
+// same whichever form is used
+func NormalizeProvisioningSpec(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if err := normalizeProvisioningIP(config); err != nil {
+		return err
+	}
+	return normalizeProvisioningOSImage(config)
+}
+
+// SetDefaultDHCPRange fills in the ProvisioningDHCPRange with a default
+// range when it is not set in Managed mode
+func SetDefaultDHCPRange(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if config.ProvisioningDHCPRange != "" || config.StrictDHCPRangeValidation ||
+		getProvisioningNetworkMode(&metal3iov1alpha1.Provisioning{Spec: *config}) != metal3iov1alpha1.ProvisioningNetworkManaged {
+		return nil
 	}
+	dhcpRange, err := getDefaultDHCPRange(config)
+	if err != nil {
+		return err
+	}
+	config.ProvisioningDHCPRange = dhcpRange
 	return nil
 }
 
+// GetServedDHCPRange returns the DHCP range served by the metal3 DHCP
+// server, which only runs when the provisioning network is Managed
+func GetServedDHCPRange(config *metal3iov1alpha1.ProvisioningSpec) string {
+	if getProvisioningNetworkMode(&metal3iov1alpha1.Provisioning{Spec: *config}) != metal3iov1alpha1.ProvisioningNetworkManaged {
+		return ""
+	}
+	return config.ProvisioningDHCPRange
+}
+
+func isIPv6Network(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	ip, _, err := net.ParseCIDR(config.ProvisioningNetworkCIDR)
+	return err == nil && ip.To4() == nil
+}
+
+// getDHCPRange returns the DHCP range for dnsmasq. For IPv6 networks the
+// prefix length is appended, as dnsmasq needs it to serve DHCPv6 and to
+// send router advertisements for the range.
+func getDHCPRange(config *metal3iov1alpha1.ProvisioningSpec) *string {
+	dhcpRange := config.ProvisioningDHCPRange
+	if dhcpRange != "" && isIPv6Network(config) {
+		_, network, _ := net.ParseCIDR(config.ProvisioningNetworkCIDR)
+		prefix, _ := network.Mask.Size()
+		dhcpRange = fmt.Sprintf("%s,%d", dhcpRange, prefix)
+	}
+	return &dhcpRange
+}
+
 func getProvisioningIPCIDR(config *metal3iov1alpha1.ProvisioningSpec) *string {
 	if config.ProvisioningNetworkCIDR != "" && config.ProvisioningIP != "" {
 		_, net, err := net.ParseCIDR(config.ProvisioningNetworkCIDR)
@@ -140,30 +316,75 @@ func getProvisioningIPCIDR(config *metal3iov1alpha1.ProvisioningSpec) *string {
 	return nil
 }
 
-func getDeployKernelUrl(config *metal3iov1alpha1.ProvisioningSpec) *string {
+func getImageServerUrl(config *metal3iov1alpha1.ProvisioningSpec, scheme string, port string, subPath string) *string {
 	if config.ProvisioningIP != "" {
-		deployKernelUrl := fmt.Sprintf("http://%s/%s", net.JoinHostPort(config.ProvisioningIP, baremetalHttpPort), baremetalKernelUrlSubPath)
-		return &deployKernelUrl
+		url := fmt.Sprintf("%s://%s/%s", scheme, net.JoinHostPort(config.ProvisioningIP, port), subPath)
+		return &url
 	}
 	return nil
 }
 
+func getDeployKernelUrl(config *metal3iov1alpha1.ProvisioningSpec) *string {
+	return getImageServerUrl(config, "http", baremetalHttpPort, baremetalKernelUrlSubPath)
+}
+
 func getDeployRamdiskUrl(config *metal3iov1alpha1.ProvisioningSpec) *string {
-	if config.ProvisioningIP != "" {
-		deployRamdiskUrl := fmt.Sprintf("http://%s/%s", net.JoinHostPort(config.ProvisioningIP, baremetalHttpPort), baremetalRamdiskUrlSubPath)
-		return &deployRamdiskUrl
+	return getImageServerUrl(config, "http", baremetalHttpPort, baremetalRamdiskUrlSubPath)
+}
+
+func getBootArtifactURLs(config *metal3iov1alpha1.ProvisioningSpec, scheme string, port string) metal3iov1alpha1.BootArtifactURLs {
+	urls := metal3iov1alpha1.BootArtifactURLs{}
+	if kernel := getImageServerUrl(config, scheme, port, baremetalKernelUrlSubPath); kernel != nil {
+		urls.DeployKernel = *kernel
 	}
-	return nil
+	if ramdisk := getImageServerUrl(config, scheme, port, baremetalRamdiskUrlSubPath); ramdisk != nil {
+		urls.DeployRamdisk = *ramdisk
+	}
+	if subPath := getCachedOSImageSubPath(config); subPath != "" {
+		if osImage := getImageServerUrl(config, scheme, port, subPath); osImage != nil {
+			urls.OSImage = *osImage
+			if config.ConvertOSImageToRaw {
+				urls.OSImageChecksum = *osImage + ".sha256sum"
+			}
+		}
+	}
+	urls.Firmware = getFirmwareURL(config, scheme, port)
+	urls.LiveKernel = getLivePXEURL(config, scheme, port, livePXEKernel)
+	urls.LiveInitramfs = getLivePXEURL(config, scheme, port, livePXEInitramfs)
+	urls.LiveRootfs = getLivePXEURL(config, scheme, port, livePXERootfs)
+	return urls
+}
+
+// GetImageServerStatus returns the URLs published by the image server
+// for the given configuration
+func GetImageServerStatus(config *metal3iov1alpha1.ProvisioningSpec) metal3iov1alpha1.ImageServerStatus {
+	status := metal3iov1alpha1.ImageServerStatus{
+		HTTP: getBootArtifactURLs(config, "http", baremetalHttpPort),
+	}
+	if config.ImageServerHTTPS {
+		httpsURLs := getBootArtifactURLs(config, "https", baremetalHttpsPort)
+		status.HTTPS = &httpsURLs
+	}
+	return status
 }
 
 func getIronicEndpoint(config *metal3iov1alpha1.ProvisioningSpec) *string {
 	if config.ProvisioningIP != "" {
-		ironicEndpoint := fmt.Sprintf("http://%s/%s", net.JoinHostPort(config.ProvisioningIP, baremetalIronicPort), baremetalIronicEndpointSubpath)
+		ironicEndpoint := fmt.Sprintf("http://%s/%s", net.JoinHostPort(config.ProvisioningIP, getIronicAPIPort(config)), baremetalIronicEndpointSubpath)
 		return &ironicEndpoint
 	}
 	return nil
 }
 
+// GetIronicEndpoint returns the URL of the Ironic API, or an empty string
+// when there is no ProvisioningIP to reach it on
+func GetIronicEndpoint(config *metal3iov1alpha1.ProvisioningSpec) string {
+	if endpoint := getIronicEndpoint(config); endpoint != nil {
+		return *endpoint
+	}
+	return ""
+}
+
 func getIronicInspectorEndpoint(config *metal3iov1alpha1.ProvisioningSpec) *string {
 	if config.ProvisioningIP != "" {
 		inspectorEndpoint := fmt.Sprintf("http://%s/%s", net.JoinHostPort(config.ProvisioningIP, baremetalIronicInspectorPort), baremetalIronicEndpointSubpath)
@@ -195,8 +416,10 @@ func getMetal3DeploymentConfig(name string, baremetalConfig *metal3iov1alpha1.Pr
 		return getIronicInspectorEndpoint(baremetalConfig)
 	case httpPort:
 		return pointer.StringPtr(baremetalHttpPort)
+	case httpsPort:
+		return pointer.StringPtr(baremetalHttpsPort)
 	case dhcpRange:
-		return &baremetalConfig.ProvisioningDHCPRange
+		return getDHCPRange(baremetalConfig)
 	case machineImageUrl:
 		return getProvisioningOSDownloadURL(baremetalConfig)
 	}
@@ -0,0 +1,87 @@
+//go:build go1.18
+// +build go1.18
+
+package provisioning
+
+import (
+	"testing"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// FuzzParseDHCPRange checks that parseDHCPRange never panics, and that the
+// size of any range it accepts can be computed
+func FuzzParseDHCPRange(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"172.30.20.11, 172.30.20.101",
+		"172.30.20.101,172.30.20.11",
+		"fd00:1101::a,fd00:1101::ffff",
+		"172.30.20.11,fd00:1101::ffff",
+		"172.30.20.11",
+		",,",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, dhcpRange string) {
+		start, end, err := parseDHCPRange(dhcpRange)
+		if err != nil {
+			return
+		}
+		if start == nil || end == nil {
+			t.Fatalf("parseDHCPRange(%q) returned a nil address without an error", dhcpRange)
+		}
+		size, err := getDHCPRangeSize(dhcpRange)
+		if err == nil && size.Sign() <= 0 {
+			t.Fatalf("getDHCPRangeSize(%q) returned %s", dhcpRange, size)
+		}
+	})
+}
+
+// FuzzValidateBaremetalProvisioningConfig checks that the validation never
+// panics on malformed addresses and URLs, and that the values derived from
+// an accepted configuration can always be computed
+func FuzzValidateBaremetalProvisioningConfig(f *testing.F) {
+	for _, seed := range []struct {
+		mode, ip, cidr, dhcpRange, url string
+	}{
+		{"Managed", "172.30.20.3", "172.30.20.0/24", "172.30.20.11, 172.30.20.101", "http://172.22.0.1/images/rhcos.qcow2.gz?sha256=1234"},
+		{"Managed", "172.30.20.3", "172.30.20.0/24", "", "http://172.22.0.1/images/rhcos.qcow2.gz?sha256=1234"},
+		{"Managed", "fd2e:6f44:5dd8:b856::3", "fd2e:6f44:5dd8:b856::/64", "", "https://[fd2e::1]/rhcos.qcow2"},
+		{"Managed", "172.30.20.3", "172.30.20.0/30", "", "http://172.22.0.1/rhcos.qcow2"},
+		{"Unmanaged", "172.30.20.3", "172.30.20.0/24", "", "http://172.22.0.1/rhcos.qcow2"},
+		{"Disabled", "172.30.20.3", "172.30.20.0/24", "", "ftp://%zz"},
+		{"", "172.30.20", "172.30.20.0/33", "172.30.20.101,172.30.20.11", ":"},
+	} {
+		f.Add(seed.mode, seed.ip, seed.cidr, seed.dhcpRange, seed.url)
+	}
+	f.Fuzz(func(t *testing.T, mode, ip, cidr, served, url string) {
+		prov := &metal3iov1alpha1.Provisioning{
+			Spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            ip,
+				ProvisioningNetworkCIDR:   cidr,
+				ProvisioningDHCPRange:     served,
+				ProvisioningOSDownloadURL: url,
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetwork(mode),
+			},
+		}
+		GetConfigWarnings(&prov.Spec)
+		if err := ValidateBaremetalProvisioningConfig(prov); err != nil {
+			return
+		}
+
+		if err := SetDefaultDHCPRange(&prov.Spec); err != nil {
+			t.Fatalf("no default DHCP range for a valid configuration: %v", err)
+		}
+		if GetServedDHCPRange(&prov.Spec) != "" {
+			if _, err := getDHCPRangeCapacity(&prov.Spec); err != nil {
+				t.Fatalf("invalid DHCP range in a valid configuration: %v", err)
+			}
+		}
+		for _, name := range []string{provisioningIP, dhcpRange, deployKernelUrl, ironicEndpoint} {
+			getMetal3DeploymentConfig(name, &prov.Spec)
+		}
+		getCachedOSImageSubPath(&prov.Spec)
+	})
+}
@@ -102,6 +102,93 @@ func TestValidateManagedProvisioningConfig(t *testing.T) {
 			expectedMode:  metal3iov1alpha1.ProvisioningNetworkManaged,
 			expectedMsg:   "ProvisioningInterface",
 		},
+		{
+			// IPv6 provisioning network managed by metal3
+			name: "ValidManagedIPv6",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "fd00:1101::3",
+				ProvisioningNetworkCIDR:   "fd00:1101::/64",
+				ProvisioningDHCPRange:     "fd00:1101::a, fd00:1101::64",
+				ProvisioningOSDownloadURL: "http://[fd00:1101::1]/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Managed",
+			},
+			expectedError: false,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkManaged,
+		},
+		{
+			// ProvisioningDHCPRange is not set, a default range is used
+			name: "DefaultDHCPRangeManaged",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Managed",
+			},
+			expectedError: false,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkManaged,
+		},
+		{
+			// ProvisioningDHCPRange is required with strict validation
+			name: "StrictDHCPRangeManaged",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Managed",
+				StrictDHCPRangeValidation: true,
+			},
+			expectedError: true,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkManaged,
+			expectedMsg:   "ProvisioningDHCPRange",
+		},
+		{
+			// ProvisioningDHCPRange is outside of the ProvisioningNetworkCIDR
+			name: "InvalidManagedDHCPRange",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningDHCPRange:     "172.30.20.11, 172.30.21.101",
+				ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Managed",
+			},
+			expectedError: true,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkManaged,
+			expectedMsg:   "ProvisioningDHCPRange",
+		},
+		{
+			// ProvisioningDHCPRange ends before it starts
+			name: "ReversedManagedDHCPRange",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningDHCPRange:     "172.30.20.101, 172.30.20.11",
+				ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Managed",
+			},
+			expectedError: true,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkManaged,
+			expectedMsg:   "starts after its end",
+		},
+		{
+			// ProvisioningIP is not an IP address
+			name: "InvalidManagedProvisioningIP",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningInterface:     "eth0",
+				ProvisioningIP:            "172.30.20",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+				ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Managed",
+			},
+			expectedError: true,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkManaged,
+			expectedMsg:   "ProvisioningIP",
+		},
 	}
 	for _, tc := range tCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -243,6 +330,31 @@ func TestValidateDisabledProvisioningConfig(t *testing.T) {
 			expectedMode:  metal3iov1alpha1.ProvisioningNetworkDisabled,
 			expectedMsg:   "ProvisioningOSDownloadURL",
 		},
+		{
+			// IPv6-only cluster without a provisioning network
+			name: "ValidDisabledIPv6",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:            "fd2e:6f44:5dd8:c956::14",
+				ProvisioningNetworkCIDR:   "fd2e:6f44:5dd8:c956::/120",
+				ProvisioningOSDownloadURL: "http://[fd2e:6f44:5dd8:c956::1]/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Disabled",
+			},
+			expectedError: false,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkDisabled,
+		},
+		{
+			// IPv4 ProvisioningIP on an IPv6 network
+			name: "InvalidDisabledMixedFamilies",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "fd2e:6f44:5dd8:c956::/120",
+				ProvisioningOSDownloadURL: "http://[fd2e:6f44:5dd8:c956::1]/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+				ProvisioningNetwork:       "Disabled",
+			},
+			expectedError: true,
+			expectedMode:  metal3iov1alpha1.ProvisioningNetworkDisabled,
+			expectedMsg:   "ProvisioningIP",
+		},
 	}
 	for _, tc := range tCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -286,12 +398,45 @@ func TestGetMetal3DeploymentConfig(t *testing.T) {
 		ProvisioningNetwork:       "Disabled",
 	}
 
+	managedIPv6Spec := metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningInterface:     "eth0",
+		ProvisioningIP:            "fd00:1101::3",
+		ProvisioningNetworkCIDR:   "fd00:1101::/64",
+		ProvisioningDHCPRange:     "fd00:1101::a,fd00:1101::64",
+		ProvisioningOSDownloadURL: "http://[fd00:1101::1]/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+		ProvisioningNetwork:       "Managed",
+	}
+
 	tCases := []struct {
 		name          string
 		configName    string
 		spec          metal3iov1alpha1.ProvisioningSpec
 		expectedValue string
 	}{
+		{
+			name:          "Managed IPv6 ProvisioningIPCIDR",
+			configName:    provisioningIP,
+			spec:          managedIPv6Spec,
+			expectedValue: "fd00:1101::3/64",
+		},
+		{
+			name:          "Managed IPv6 DHCPRange",
+			configName:    dhcpRange,
+			spec:          managedIPv6Spec,
+			expectedValue: "fd00:1101::a,fd00:1101::64,64",
+		},
+		{
+			name:          "Managed IPv6 IronicEndpoint",
+			configName:    ironicEndpoint,
+			spec:          managedIPv6Spec,
+			expectedValue: "http://[fd00:1101::3]:6385/v1/",
+		},
+		{
+			name:          "Managed IPv6 DeployKernelUrl",
+			configName:    deployKernelUrl,
+			spec:          managedIPv6Spec,
+			expectedValue: "http://[fd00:1101::3]:6180/images/ironic-python-agent.kernel",
+		},
 		{
 			name:          "Managed ProvisioningIPCIDR",
 			configName:    provisioningIP,
@@ -363,3 +508,150 @@ func TestGetMetal3DeploymentConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestGetImageServerStatus(t *testing.T) {
+	spec := metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningIP: "172.30.20.3",
+	}
+	httpURLs := metal3iov1alpha1.BootArtifactURLs{
+		DeployKernel:  "http://172.30.20.3:6180/images/ironic-python-agent.kernel",
+		DeployRamdisk: "http://172.30.20.3:6180/images/ironic-python-agent.initramfs",
+	}
+
+	status := GetImageServerStatus(&spec)
+	assert.Equal(t, httpURLs, status.HTTP)
+	assert.Nil(t, status.HTTPS)
+
+	spec.ImageServerHTTPS = true
+	status = GetImageServerStatus(&spec)
+	assert.Equal(t, httpURLs, status.HTTP)
+	assert.Equal(t, &metal3iov1alpha1.BootArtifactURLs{
+		DeployKernel:  "https://172.30.20.3:6183/images/ironic-python-agent.kernel",
+		DeployRamdisk: "https://172.30.20.3:6183/images/ironic-python-agent.initramfs",
+	}, status.HTTPS)
+
+	spec.ProvisioningOSDownloadURL = "https://mirror.example.com/rhcos-47.84.qcow2.gz?sha256=abc"
+	spec.ConvertOSImageToRaw = true
+	status = GetImageServerStatus(&spec)
+	assert.Equal(t, "http://172.30.20.3:6180/images/rhcos-47.84.qcow2/rhcos-47.84.qcow2.raw", status.HTTP.OSImage)
+	assert.Equal(t, "http://172.30.20.3:6180/images/rhcos-47.84.qcow2/rhcos-47.84.qcow2.raw.sha256sum", status.HTTP.OSImageChecksum)
+	assert.Equal(t, "https://172.30.20.3:6183/images/rhcos-47.84.qcow2/rhcos-47.84.qcow2.raw.sha256sum", status.HTTPS.OSImageChecksum)
+}
+
+func TestGetIronicEndpoint(t *testing.T) {
+	spec := managedProvisioning()
+	assert.Equal(t, "http://172.30.20.3:6385/v1/", GetIronicEndpoint(spec))
+
+	spec.ProvisioningIP = ""
+	assert.Equal(t, "", GetIronicEndpoint(spec))
+}
+
+func TestGetDefaultDHCPRange(t *testing.T) {
+	tCases := []struct {
+		name          string
+		ip            string
+		cidr          string
+		expectedRange string
+		expectedError bool
+	}{
+		{
+			name:          "IPv4",
+			ip:            "172.30.20.3",
+			cidr:          "172.30.20.0/24",
+			expectedRange: "172.30.20.10,172.30.20.254",
+		},
+		{
+			name:          "IPv4ProvisioningIPInRange",
+			ip:            "172.30.20.200",
+			cidr:          "172.30.20.0/24",
+			expectedRange: "172.30.20.10,172.30.20.199",
+		},
+		{
+			name:          "IPv4ProvisioningIPLowInRange",
+			ip:            "172.30.20.12",
+			cidr:          "172.30.20.0/24",
+			expectedRange: "172.30.20.13,172.30.20.254",
+		},
+		{
+			name:          "IPv6",
+			ip:            "fd00:1101::3",
+			cidr:          "fd00:1101::/120",
+			expectedRange: "fd00:1101::a,fd00:1101::fe",
+		},
+		{
+			name:          "TooSmall",
+			ip:            "172.30.20.1",
+			cidr:          "172.30.20.0/29",
+			expectedError: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := getDefaultDHCPRange(&metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:          tc.ip,
+				ProvisioningNetworkCIDR: tc.cidr,
+			})
+			if tc.expectedError != (err != nil) {
+				t.Errorf("ExpectedError: %v, got: %v", tc.expectedError, err)
+			}
+			assert.Equal(t, tc.expectedRange, actual)
+		})
+	}
+}
+
+func TestSetDefaultDHCPRange(t *testing.T) {
+	tCases := []struct {
+		name          string
+		spec          metal3iov1alpha1.ProvisioningSpec
+		expectedRange string
+		expectedError bool
+	}{
+		{
+			name: "ManagedDefault",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:          "172.30.20.3",
+				ProvisioningNetworkCIDR: "172.30.20.0/24",
+				ProvisioningNetwork:     "Managed",
+			},
+			expectedRange: "172.30.20.10,172.30.20.254",
+		},
+		{
+			name: "ManagedConfigured",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:          "172.30.20.3",
+				ProvisioningNetworkCIDR: "172.30.20.0/24",
+				ProvisioningDHCPRange:   "172.30.20.11, 172.30.20.101",
+				ProvisioningNetwork:     "Managed",
+			},
+			expectedRange: "172.30.20.11, 172.30.20.101",
+		},
+		{
+			name: "ManagedStrict",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:            "172.30.20.3",
+				ProvisioningNetworkCIDR:   "172.30.20.0/24",
+				ProvisioningNetwork:       "Managed",
+				StrictDHCPRangeValidation: true,
+			},
+			expectedRange: "",
+		},
+		{
+			name: "Unmanaged",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningIP:          "172.30.20.3",
+				ProvisioningNetworkCIDR: "172.30.20.0/24",
+				ProvisioningNetwork:     "Unmanaged",
+			},
+			expectedRange: "",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := SetDefaultDHCPRange(&tc.spec)
+			if tc.expectedError != (err != nil) {
+				t.Errorf("ExpectedError: %v, got: %v", tc.expectedError, err)
+			}
+			assert.Equal(t, tc.expectedRange, tc.spec.ProvisioningDHCPRange)
+		})
+	}
+}
@@ -0,0 +1,678 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioning
+
+import (
+	"context"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	metal3AppName                    = "metal3"
+	baremetalDeploymentName          = "metal3"
+	baremetalSharedVolume            = "metal3-shared"
+	metal3AuthRootDir                = "/auth"
+	ironicCredentialsVolume          = "metal3-ironic-basic-auth"
+	ironicInspectorCredentialsVolume = "metal3-inspector-basic-auth"
+	imageServerTlsVolume             = "metal3-image-server-tls"
+	imageServerTlsMountPath          = "/certs/vmedia"
+	htpasswdEnvVar                   = "HTTP_BASIC_HTPASSWD" // #nosec
+	mariadbPwdEnvVar                 = "MARIADB_PASSWORD"    // #nosec
+	serviceAccountName               = "cluster-baremetal-operator"
+	// metal3PriorityClassName is the priority class of the metal3
+	// operands, wherever they are deployed from, and of the pods checking
+	// the nodes they run on
+	metal3PriorityClassName = "system-node-critical"
+)
+
+var sharedVolumeMount = corev1.VolumeMount{
+	Name:      baremetalSharedVolume,
+	MountPath: "/shared",
+}
+
+var ironicCredentialsMount = corev1.VolumeMount{
+	Name:      ironicCredentialsVolume,
+	MountPath: metal3AuthRootDir + "/ironic",
+	ReadOnly:  true,
+}
+
+var inspectorCredentialsMount = corev1.VolumeMount{
+	Name:      ironicInspectorCredentialsVolume,
+	MountPath: metal3AuthRootDir + "/ironic-inspector",
+	ReadOnly:  true,
+}
+
+var imageServerTlsMount = corev1.VolumeMount{
+	Name:      imageServerTlsVolume,
+	MountPath: imageServerTlsMountPath,
+	ReadOnly:  true,
+}
+
+var metal3Volumes = []corev1.Volume{
+	{
+		Name: baremetalSharedVolume,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	},
+	{
+		Name: ironicCredentialsVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: ironicSecretName,
+				Items: []corev1.KeyToPath{
+					{Key: ironicConfigKey, Path: ironicConfigKey},
+				},
+			},
+		},
+	},
+	{
+		Name: ironicInspectorCredentialsVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: inspectorSecretName,
+				Items: []corev1.KeyToPath{
+					{Key: ironicConfigKey, Path: ironicConfigKey},
+				},
+			},
+		},
+	},
+}
+
+func newMetal3Volumes(config *metal3iov1alpha1.ProvisioningSpec) []corev1.Volume {
+	volumes := append([]corev1.Volume{}, metal3Volumes...)
+	volumes = append(volumes, newStaticIPStateVolume())
+	volumes = append(volumes, newIronicDBRecoveryVolumes()...)
+	if HasProvisioningIPPool(config) {
+		volumes = append(volumes, newProvisioningIPsVolume())
+	}
+	if IsActivePassive(config) {
+		volumes = append(volumes, newProvisioningVIPVolume())
+	}
+	if config.IronicAPIExposure != nil {
+		volumes = append(volumes, newIronicAPIProxyVolumes(config.IronicAPIExposure)...)
+	}
+	if config.OSImageSignatureRef != nil {
+		volumes = append(volumes, newOSImageSignatureKeyVolume(config.OSImageSignatureRef))
+	}
+	if config.IronicAPIAudit != nil {
+		volumes = append(volumes, newIronicAPIAuditVolume())
+	}
+	if config.ImagePullSecretRef != nil {
+		volumes = append(volumes, newImagePullSecretVolume(config.ImagePullSecretRef))
+	}
+	if config.ImageServerHTTPS {
+		volumes = append(volumes, corev1.Volume{
+			Name: imageServerTlsVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: imageServerTlsSecretName,
+				},
+			},
+		})
+	}
+	if config.ImageCache != nil {
+		volumes = append(volumes, newImageCacheVolume())
+	}
+	volumes = append(volumes, newImageServerMountVolumes(config.ImageServerMounts)...)
+	if config.PreStagedImagePVC != "" {
+		volumes = append(volumes, newPreStagedImageVolume(config.PreStagedImagePVC))
+	}
+	if config.FirmwareUpdates != nil {
+		volumes = append(volumes, newFirmwareBundlesVolume(config))
+	}
+	if config.EnableIgnitionOverrides {
+		volumes = append(volumes, newIgnitionOverridesVolume())
+	}
+	if config.InspectorRules != nil {
+		volumes = append(volumes, newInspectorRulesVolume(config.InspectorRules))
+	}
+	return volumes
+}
+
+func buildEnvVar(name string, baremetalProvisioningConfig *metal3iov1alpha1.ProvisioningSpec) corev1.EnvVar {
+	value := getMetal3DeploymentConfig(name, baremetalProvisioningConfig)
+	if value != nil {
+		return corev1.EnvVar{
+			Name:  name,
+			Value: *value,
+		}
+	}
+	return corev1.EnvVar{
+		Name: name,
+	}
+}
+
+func setMariadbPassword() corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: mariadbPwdEnvVar,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: baremetalSecretName,
+				},
+				Key: baremetalSecretKey,
+			},
+		},
+	}
+}
+
+func setIronicHtpasswdHash(name string, secretName string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secretName,
+				},
+				Key: ironicHtpasswdKey,
+			},
+		},
+	}
+}
+
+func newMetal3InitContainers(images *Images, config *metal3iov1alpha1.ProvisioningSpec) []corev1.Container {
+	// The address is configured first, as the images may be downloaded
+	// from the provisioning network. It stays an init container, the
+	// metal3 services listening on it, and only takes an instant.
+	initContainers := []corev1.Container{createInitContainerStaticIpSet(images, config)}
+	if config.ImageCache != nil {
+		initContainers = append(initContainers, createInitContainerImageCachePurge(images))
+	}
+	// The other downloaders run alongside the metal3 services
+	if needsOSImagePreparation(config) {
+		initContainers = append(initContainers, createInitContainerMachineOsDownloader(images, config))
+	}
+	// The signature has to be verified before the image is converted
+	if config.OSImageSignatureRef != nil {
+		initContainers = append(initContainers, createInitContainerOSImageVerifier(images, config))
+	}
+	// The conversion has to run after the OS image has been downloaded
+	if config.ConvertOSImageToRaw {
+		initContainers = append(initContainers, createInitContainerImageConverter(images))
+	}
+	return initContainers
+}
+
+func createInitContainerIpaDownloader(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-ipa-downloader",
+		Image:           images.BaremetalIpaDownloader,
+		Command:         []string{"/usr/local/bin/get-resource.sh"},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env:          []corev1.EnvVar{},
+	}
+	addImagePullSecret(&container, config)
+	return container
+}
+
+func createInitContainerMachineOsDownloader(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-machine-os-downloader",
+		Image:           images.BaremetalMachineOsDownloader,
+		Command:         getMachineOsDownloaderCommand(config),
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env: append(append([]corev1.EnvVar{
+			buildEnvVar(machineImageUrl, config),
+		}, newOSImageMirrorsEnv(config)...), newOSImageZstdEnv(config)...),
+	}
+	addImagePullSecret(&container, config)
+	return container
+}
+
+func createInitContainerImageConverter(images *Images) corev1.Container {
+	return corev1.Container{
+		Name:            "metal3-image-converter",
+		Image:           images.BaremetalIronic,
+		Command:         []string{"/bin/bash", "-c", rawImageConverterScript},
+		ImagePullPolicy: "IfNotPresent",
+		VolumeMounts:    []corev1.VolumeMount{sharedVolumeMount},
+	}
+}
+
+func createInitContainerStaticIpSet(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-static-ip-set",
+		Image:           images.BaremetalStaticIpManager,
+		Command:         []string{"/bin/bash", "-c", staticIPSetScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{staticIPStateMount},
+		Env: append([]corev1.EnvVar{
+			buildEnvVar(provisioningIP, config),
+			buildEnvVar(provisioningInterface, config),
+		}, staticIPStateEnv()...),
+	}
+	if IsActivePassive(config) {
+		container.Command = []string{"/bin/bash", "-c", provisioningVIPSetScript}
+	}
+	addNodeAddresses(&container, config)
+	return container
+}
+
+func newMetal3Containers(images *Images, config *metal3iov1alpha1.ProvisioningSpec) []corev1.Container {
+	containers := []corev1.Container{
+		createContainerMetal3BaremetalOperator(images, config),
+		createContainerMetal3Mariadb(images),
+		createContainerMetal3Httpd(images, config),
+	}
+	containers = append(containers, newIronicContainers(images, config)...)
+	containers = append(containers,
+		createContainerMetal3IronicInspector(images, config),
+		createContainerMetal3StaticIpManager(images, config),
+	)
+	downloaders := newMetal3DownloaderContainers(images, config)
+	waitForDownloads(containers, downloaders)
+	containers = append(containers, downloaders...)
+	if ReportsOSImageDownloadProgress(config) {
+		containers = append(containers, createContainerMetal3OSImageProgress(images, config))
+	}
+	if config.IronicAPIExposure != nil {
+		containers = append(containers, createContainerMetal3IronicAPIProxy(images, config))
+	}
+	if config.IronicAPIAudit != nil {
+		containers = append(containers, createContainerMetal3IronicAPIAudit(images, config))
+	}
+	if config.ImageCache != nil {
+		containers = append(containers, createContainerMetal3ImageCacheJanitor(images, config))
+	}
+	if config.EnableIgnitionOverrides {
+		containers = append(containers, createContainerMetal3IgnitionServer(images, config))
+	}
+	if config.HardwareMetrics != nil {
+		containers = append(containers, createContainerMetal3IronicExporter(images))
+	}
+	if config.InspectorRules != nil {
+		containers = append(containers, createContainerMetal3InspectorRulesLoader(images, config))
+	}
+	if config.BMCNetwork != nil {
+		containers = append(containers, createContainerMetal3BMCRoutes(images, config))
+	}
+	if IsMinimalProfile(config) {
+		return applyMinimalProfile(containers)
+	}
+	return containers
+}
+
+func createContainerMetal3BaremetalOperator(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:  "metal3-baremetal-operator",
+		Image: images.BaremetalOperator,
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "metrics",
+				ContainerPort: 60000,
+			},
+		},
+		Command:         []string{"/baremetal-operator"},
+		ImagePullPolicy: "IfNotPresent",
+		VolumeMounts: []corev1.VolumeMount{
+			ironicCredentialsMount,
+			inspectorCredentialsMount,
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name: "WATCH_NAMESPACE",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.namespace",
+					},
+				},
+			},
+			{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: "metadata.name",
+					},
+				},
+			},
+			{
+				Name:  "OPERATOR_NAME",
+				Value: "baremetal-operator",
+			},
+			buildEnvVar(deployKernelUrl, config),
+			buildEnvVar(deployRamdiskUrl, config),
+			buildEnvVar(ironicEndpoint, config),
+			buildEnvVar(ironicInspectorEndpoint, config),
+			{
+				Name:  "METAL3_AUTH_ROOT_DIR",
+				Value: metal3AuthRootDir,
+			},
+		},
+	}
+	// Invalid selectors are rejected by the validation of the spec
+	if selector, err := getHostLabelSelector(config); err == nil && selector != nil {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  hostLabelSelectorEnvVar,
+			Value: *selector,
+		})
+	}
+	if config.ProvisioningLimit > 0 {
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  provisioningLimitEnvVar,
+			Value: strconv.Itoa(int(config.ProvisioningLimit)),
+		})
+	}
+	return container
+}
+
+func createContainerMetal3Mariadb(images *Images) corev1.Container {
+	return corev1.Container{
+		Name:            "metal3-mariadb",
+		Image:           images.BaremetalIronic,
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		Command:      []string{"/bin/runmariadb"},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env: []corev1.EnvVar{
+			setMariadbPassword(),
+		},
+	}
+}
+
+func createContainerMetal3Httpd(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-httpd",
+		Image:           images.BaremetalIronic,
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		Command:      []string{"/bin/runhttpd"},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env: []corev1.EnvVar{
+			buildEnvVar(httpPort, config),
+			buildEnvVar(provisioningIP, config),
+			buildEnvVar(provisioningInterface, config),
+		},
+	}
+	// The HTTPS listener is served alongside the HTTP one, never instead of it
+	if config.ImageServerHTTPS {
+		container.VolumeMounts = append(container.VolumeMounts, imageServerTlsMount)
+		container.Env = append(container.Env,
+			buildEnvVar(httpsPort, config),
+			corev1.EnvVar{
+				Name:  "IRONIC_VMEDIA_CERT_FILE",
+				Value: imageServerTlsMountPath + "/" + corev1.TLSCertKey,
+			},
+			corev1.EnvVar{
+				Name:  "IRONIC_VMEDIA_KEY_FILE",
+				Value: imageServerTlsMountPath + "/" + corev1.TLSPrivateKeyKey,
+			},
+		)
+	}
+	container.VolumeMounts = append(container.VolumeMounts, newImageServerVolumeMounts(config.ImageServerMounts)...)
+	if config.PreStagedImagePVC != "" {
+		container.VolumeMounts = append(container.VolumeMounts, newPreStagedImageMount(config))
+	}
+	if config.FirmwareUpdates != nil {
+		container.VolumeMounts = append(container.VolumeMounts, newFirmwareBundlesMount())
+	}
+	return container
+}
+
+func createContainerMetal3IronicConductor(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-ironic-conductor",
+		Image:           images.BaremetalIronic,
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		Command: []string{"/bin/runironic-conductor"},
+		Ports:   newJSONRPCPorts(config),
+		VolumeMounts: []corev1.VolumeMount{
+			sharedVolumeMount,
+			inspectorCredentialsMount,
+		},
+		Env: []corev1.EnvVar{
+			setMariadbPassword(),
+			buildEnvVar(httpPort, config),
+			buildEnvVar(provisioningIP, config),
+			buildEnvVar(provisioningInterface, config),
+			{
+				Name:  "METAL3_AUTH_ROOT_DIR",
+				Value: metal3AuthRootDir,
+			},
+		},
+	}
+	container.Env = append(container.Env, secureBootIronicEnv(config)...)
+	container.Env = append(container.Env, grubBootIronicEnv(config)...)
+	container.Env = append(container.Env, newHardwareMetricsConductorEnv(config)...)
+	container.Env = append(container.Env, newVendorExtensionsEnv(config)...)
+	container.Env = append(container.Env, newFirmwareUpdatesEnv(config)...)
+	container.Env = append(container.Env, newBMCProxyEnv(config)...)
+	container.Env = append(container.Env, newOperandLogLevelEnv(config)...)
+	container.Env = append(container.Env, newIronicRPCEnv(config)...)
+	return container
+}
+
+func createContainerMetal3IronicApi(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-ironic-api",
+		Image:           images.BaremetalIronic,
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		Command:      []string{"/bin/runironic-api"},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env: []corev1.EnvVar{
+			setMariadbPassword(),
+			setIronicHtpasswdHash(htpasswdEnvVar, ironicSecretName),
+			buildEnvVar(httpPort, config),
+			buildEnvVar(provisioningIP, config),
+			buildEnvVar(provisioningInterface, config),
+		},
+	}
+	container.Env = append(container.Env, newOperandLogLevelEnv(config)...)
+	container.Env = append(container.Env, newIronicRPCEnv(config)...)
+	return container
+}
+
+func createContainerMetal3IronicInspector(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-ironic-inspector",
+		Image:           images.BaremetalIronicInspector,
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			sharedVolumeMount,
+			ironicCredentialsMount,
+		},
+		Env: []corev1.EnvVar{
+			setIronicHtpasswdHash(htpasswdEnvVar, inspectorSecretName),
+			buildEnvVar(provisioningIP, config),
+			buildEnvVar(provisioningInterface, config),
+		},
+	}
+	container.Env = append(container.Env, newOperandLogLevelEnv(config)...)
+	return container
+}
+
+func createContainerMetal3StaticIpManager(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            StaticIPManagerContainerName,
+		Image:           images.BaremetalStaticIpManager,
+		Command:         []string{"/bin/bash", "-c", staticIPManagerScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		ReadinessProbe: newStaticIPConfiguredProbe(),
+		Env: []corev1.EnvVar{
+			buildEnvVar(provisioningIP, config),
+			buildEnvVar(provisioningInterface, config),
+			{
+				Name:  "STATIC_IP_REPAIR_INTERVAL",
+				Value: staticIPRepairInterval,
+			},
+		},
+	}
+	if IsActivePassive(config) {
+		container.VolumeMounts = append(container.VolumeMounts, staticIPStateMount)
+		container.Env = append(container.Env, staticIPStateEnv()...)
+		useProvisioningVIPManager(&container)
+	}
+	addNodeAddresses(&container, config)
+	return container
+}
+
+func newMetal3PodTemplateSpec(images *Images, config *metal3iov1alpha1.ProvisioningSpec) *corev1.PodTemplateSpec {
+	initContainers := newMetal3InitContainers(images, config)
+	containers := newMetal3Containers(images, config)
+	setTerminationMessagePolicy(initContainers, containers)
+	if config.ImageCache != nil {
+		addImageCacheMounts(initContainers, containers)
+	}
+
+	template := &corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"k8s-app":    metal3AppName,
+				"controller": metal3AppName,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Volumes:           newMetal3Volumes(config),
+			InitContainers:    initContainers,
+			Containers:        containers,
+			HostNetwork:       true,
+			DNSPolicy:         corev1.DNSClusterFirstWithHostNet,
+			PriorityClassName: metal3PriorityClassName,
+			NodeSelector:      GetMetal3NodeSelector(config),
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: pointer.BoolPtr(false),
+			},
+			ServiceAccountName:            serviceAccountName,
+			Tolerations:                   newMetal3Tolerations(config),
+			Affinity:                      newMetal3Affinity(config),
+			TopologySpreadConstraints:     newMetal3TopologySpreadConstraints(config),
+			TerminationGracePeriodSeconds: config.TerminationGracePeriodSeconds,
+		},
+	}
+	applyIronicDBRecovery(&template.Spec)
+	applyActivePassive(&template.Spec, config)
+	applyOperandLogFormat(template, config)
+	applyPodSecurityMode(template, config)
+	return template
+}
+
+// setTerminationMessagePolicy keeps the end of the logs of failed
+// containers in their status, so that crash loops can be diagnosed from
+// the pod status
+func setTerminationMessagePolicy(containerLists ...[]corev1.Container) {
+	for _, list := range containerLists {
+		for i := range list {
+			list[i].TerminationMessagePolicy = corev1.TerminationMessageFallbackToLogsOnError
+		}
+	}
+}
+
+// NewMetal3Deployment returns the Deployment running the metal3 pod
+func NewMetal3Deployment(targetNamespace string, images *Images, config *metal3iov1alpha1.ProvisioningSpec) *appsv1.Deployment {
+	selector := metal3PodSelector()
+	template := newMetal3PodTemplateSpec(images, config)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      baremetalDeploymentName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32Ptr(GetMetal3Replicas(config)),
+			Selector: selector,
+			Template: *template,
+			Strategy: newMetal3DeploymentStrategy(config),
+		},
+	}
+	SetOperandMetadata(deployment, config)
+	return deployment
+}
+
+// ApplyMetal3Deployment creates the metal3 Deployment, or updates it when
+// it was last updated from a different rendered object or changed since.
+// It returns true when the Deployment was created or updated.
+func ApplyMetal3Deployment(client appsclientv1.DeploymentsGetter, deployment *appsv1.Deployment) (bool, error) {
+	deployment = deployment.DeepCopy()
+	if err := setSpecHash(deployment, deployment.Spec); err != nil {
+		return false, err
+	}
+	existing, err := client.Deployments(deployment.Namespace).Get(context.Background(), deployment.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		created, err := client.Deployments(deployment.Namespace).Create(context.Background(), deployment, metav1.CreateOptions{})
+		if err != nil {
+			return false, apiError(err)
+		}
+		return true, recordDeploymentSpecGeneration(client, created)
+	}
+	if err != nil {
+		return false, apiError(err)
+	}
+
+	if isSpecApplied(existing, deployment) {
+		return false, nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Labels = deployment.Labels
+	for key, value := range deployment.Annotations {
+		metav1.SetMetaDataAnnotation(&updated.ObjectMeta, key, value)
+	}
+	updated.OwnerReferences = deployment.OwnerReferences
+	updated.Spec = deployment.Spec
+	updated, err = client.Deployments(deployment.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		return false, apiError(err)
+	}
+	return true, recordDeploymentSpecGeneration(client, updated)
+}
+
+// recordDeploymentSpecGeneration records the generation the Deployment got
+// once updated from the rendered object
+func recordDeploymentSpecGeneration(client appsclientv1.DeploymentsGetter, deployment *appsv1.Deployment) error {
+	if !setSpecGeneration(deployment) {
+		return nil
+	}
+	_, err := client.Deployments(deployment.Namespace).Update(context.Background(), deployment, metav1.UpdateOptions{})
+	return apiError(err)
+}
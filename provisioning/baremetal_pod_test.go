@@ -0,0 +1,268 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+var testImages = Images{
+	BaremetalOperator:            expectedBaremetalOperator,
+	BaremetalIronic:              expectedIronic,
+	BaremetalIronicInspector:     expectedIronicInspector,
+	BaremetalIpaDownloader:       expectedIronicIpaDownloader,
+	BaremetalMachineOsDownloader: expectedMachineOsDownloader,
+	BaremetalStaticIpManager:     expectedIronicStaticIpManager,
+}
+
+func managedProvisioning() *metal3iov1alpha1.ProvisioningSpec {
+	return &metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningInterface:     "eth0",
+		ProvisioningIP:            "172.30.20.3",
+		ProvisioningNetworkCIDR:   "172.30.20.0/24",
+		ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+		ProvisioningOSDownloadURL: "http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+		ProvisioningNetwork:       "Managed",
+	}
+}
+
+func containerNames(containers []corev1.Container) []string {
+	names := []string{}
+	for _, c := range containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func findContainer(containers []corev1.Container, name string) *corev1.Container {
+	for i := range containers {
+		if containers[i].Name == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+func envValue(container *corev1.Container, name string) string {
+	for _, e := range container.Env {
+		if e.Name == name {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+func TestNewMetal3Containers(t *testing.T) {
+	unmanaged := managedProvisioning()
+	unmanaged.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkUnmanaged
+
+	tCases := []struct {
+		name               string
+		config             *metal3iov1alpha1.ProvisioningSpec
+		expectedContainers []string
+	}{
+		{
+			name:   "ManagedSpec",
+			config: managedProvisioning(),
+			expectedContainers: []string{
+				"metal3-baremetal-operator",
+				"metal3-mariadb",
+				"metal3-httpd",
+				"metal3-ironic-conductor",
+				"metal3-ironic-api",
+				"metal3-ironic-inspector",
+				"metal3-static-ip-manager",
+				"metal3-ipa-downloader",
+				"metal3-machine-os-downloader",
+				"metal3-os-image-progress",
+			},
+		},
+		{
+			name:   "UnmanagedSpec",
+			config: unmanaged,
+			expectedContainers: []string{
+				"metal3-baremetal-operator",
+				"metal3-mariadb",
+				"metal3-httpd",
+				"metal3-ironic-conductor",
+				"metal3-ironic-api",
+				"metal3-ironic-inspector",
+				"metal3-static-ip-manager",
+				"metal3-ipa-downloader",
+				"metal3-machine-os-downloader",
+				"metal3-os-image-progress",
+			},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualContainers := newMetal3Containers(&testImages, tc.config)
+			assert.Equal(t, tc.expectedContainers, containerNames(actualContainers))
+		})
+	}
+}
+
+func TestNewMetal3Deployment(t *testing.T) {
+	deployment := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+
+	assert.Equal(t, baremetalDeploymentName, deployment.Name)
+	assert.Equal(t, testNamespace, deployment.Namespace)
+	assert.Equal(t, deployment.Spec.Selector.MatchLabels, deployment.Spec.Template.Labels)
+	assert.Equal(t, []string{"metal3-static-ip-set"}, containerNames(deployment.Spec.Template.Spec.InitContainers))
+
+	downloader := findContainer(deployment.Spec.Template.Spec.Containers, "metal3-machine-os-downloader")
+	assert.Equal(t, managedProvisioning().ProvisioningOSDownloadURL, envValue(downloader, machineImageUrl))
+
+	staticIP := findContainer(deployment.Spec.Template.Spec.InitContainers, "metal3-static-ip-set")
+	assert.Equal(t, "172.30.20.3/24", envValue(staticIP, provisioningIP))
+}
+
+func TestMetal3PriorityClass(t *testing.T) {
+	config := managedProvisioning()
+	templates := map[string]corev1.PodTemplateSpec{
+		"metal3":                NewMetal3Deployment(testNamespace, &testImages, config).Spec.Template,
+		"metal3-dnsmasq":        NewDnsmasqDaemonSet(testNamespace, &testImages, config).Spec.Template,
+		"domain deployment":     NewProvisioningDomainDeployment(testNamespace, "rack-1", &testImages, config).Spec.Template,
+		"domain daemonset":      NewProvisioningDomainDaemonSet(testNamespace, "rack-1", &testImages, config).Spec.Template,
+		"interface check":       {Spec: NewInterfaceCheckPod(testNamespace, "master-0", &testImages, config).Spec},
+		"ironic conflict check": {Spec: NewIronicConflictCheckPod(testNamespace, "master-0", &testImages, config, false).Spec},
+		"conductor group":       NewConductorGroupDeployment(testNamespace, &testImages, config, &metal3iov1alpha1.ConductorGroup{Name: "rack-a"}).Spec.Template,
+	}
+	for name, template := range templates {
+		assert.Equal(t, metal3PriorityClassName, template.Spec.PriorityClassName, name)
+	}
+}
+
+func TestApplyMetal3Deployment(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+	config := managedProvisioning()
+
+	updated, err := ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.True(t, updated, "expected the deployment to be created")
+
+	updated, err = ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.False(t, updated, "expected an unchanged deployment not to be updated")
+
+	config.ProvisioningOSDownloadURL = "http://172.22.0.1/images/rhcos-46.qcow2.gz?sha256=1234"
+	updated, err = ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.True(t, updated, "expected a changed deployment to be updated")
+
+	deployment, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	downloader := findContainer(deployment.Spec.Template.Spec.Containers, "metal3-machine-os-downloader")
+	assert.Equal(t, config.ProvisioningOSDownloadURL, envValue(downloader, machineImageUrl))
+}
+
+func TestNewMetal3InitContainers(t *testing.T) {
+	convert := managedProvisioning()
+	convert.ConvertOSImageToRaw = true
+
+	tCases := []struct {
+		name                   string
+		config                 *metal3iov1alpha1.ProvisioningSpec
+		expectedInitContainers []string
+	}{
+		{
+			name:                   "NoConversion",
+			config:                 managedProvisioning(),
+			expectedInitContainers: []string{"metal3-static-ip-set"},
+		},
+		{
+			name:                   "ConvertToRaw",
+			config:                 convert,
+			expectedInitContainers: []string{"metal3-static-ip-set", "metal3-machine-os-downloader", "metal3-image-converter"},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actualContainers := newMetal3InitContainers(&testImages, tc.config)
+			assert.Equal(t, tc.expectedInitContainers, containerNames(actualContainers))
+		})
+	}
+
+	converter := findContainer(newMetal3InitContainers(&testImages, convert), "metal3-image-converter")
+	assert.Nil(t, converter.SecurityContext, "the image converter only needs access to the shared volume")
+}
+
+func TestImageServerHTTPS(t *testing.T) {
+	config := managedProvisioning()
+	config.ImageServerHTTPS = true
+
+	template := newMetal3PodTemplateSpec(&testImages, config)
+	httpd := findContainer(template.Spec.Containers, "metal3-httpd")
+	assert.Equal(t, baremetalHttpPort, envValue(httpd, httpPort))
+	assert.Equal(t, baremetalHttpsPort, envValue(httpd, httpsPort))
+	assert.Contains(t, httpd.VolumeMounts, imageServerTlsMount)
+
+	found := false
+	for _, v := range template.Spec.Volumes {
+		if v.Name == imageServerTlsVolume {
+			found = true
+		}
+	}
+	assert.True(t, found, "image server TLS volume not found")
+
+	plain := newMetal3PodTemplateSpec(&testImages, managedProvisioning())
+	httpd = findContainer(plain.Spec.Containers, "metal3-httpd")
+	assert.Equal(t, "", envValue(httpd, httpsPort))
+	assert.NotContains(t, httpd.VolumeMounts, imageServerTlsMount)
+}
+
+// TestMetal3DeploymentURLs renders the metal3 deployment for every
+// provisioning network mode on IPv4 and IPv6-only networks, and checks
+// that every generated URL is valid and points at the ProvisioningIP.
+func TestMetal3DeploymentURLs(t *testing.T) {
+	ipv4 := managedProvisioning()
+	ipv6 := &metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningInterface:     "eth0",
+		ProvisioningIP:            "fd00:1101::3",
+		ProvisioningNetworkCIDR:   "fd00:1101::/64",
+		ProvisioningDHCPRange:     "fd00:1101::a,fd00:1101::64",
+		ProvisioningOSDownloadURL: "http://[fd00:1101::1]/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234",
+	}
+	urlEnvVars := []string{deployKernelUrl, deployRamdiskUrl, ironicEndpoint, ironicInspectorEndpoint}
+
+	for _, family := range []struct {
+		name string
+		spec *metal3iov1alpha1.ProvisioningSpec
+	}{{"IPv4", ipv4}, {"IPv6", ipv6}} {
+		for _, mode := range []metal3iov1alpha1.ProvisioningNetwork{
+			metal3iov1alpha1.ProvisioningNetworkManaged,
+			metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			metal3iov1alpha1.ProvisioningNetworkDisabled,
+		} {
+			t.Run(fmt.Sprintf("%s %s", family.name, mode), func(t *testing.T) {
+				config := family.spec.DeepCopy()
+				config.ProvisioningNetwork = mode
+				config.ImageServerHTTPS = true
+				assert.NoError(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *config}))
+
+				deployment := NewMetal3Deployment(testNamespace, &testImages, config)
+				bmo := findContainer(deployment.Spec.Template.Spec.Containers, "metal3-baremetal-operator")
+				for _, name := range urlEnvVars {
+					u, err := url.Parse(envValue(bmo, name))
+					assert.NoError(t, err, name)
+					assert.Equal(t, config.ProvisioningIP, u.Hostname(), name)
+				}
+
+				status := GetImageServerStatus(config)
+				for _, artifactURL := range []string{status.HTTP.DeployKernel, status.HTTPS.DeployKernel} {
+					u, err := url.Parse(artifactURL)
+					assert.NoError(t, err)
+					assert.Equal(t, config.ProvisioningIP, u.Hostname())
+				}
+			})
+		}
+	}
+}
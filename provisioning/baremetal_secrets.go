@@ -48,7 +48,7 @@ func generateRandomPassword() (string, error) {
 func CreateMariadbPasswordSecret(client coreclientv1.SecretsGetter, targetNamespace string) error {
 	_, err := client.Secrets(targetNamespace).Get(context.Background(), baremetalSecretName, metav1.GetOptions{})
 	if !apierrors.IsNotFound(err) {
-		return err
+		return apiError(err)
 	}
 
 	// Secret does not already exist. So, create one.
@@ -69,7 +69,7 @@ func CreateMariadbPasswordSecret(client coreclientv1.SecretsGetter, targetNamesp
 		},
 		metav1.CreateOptions{},
 	)
-	return err
+	return apiError(err)
 }
 
 // CreateIronicPasswordSecret creates a Secret for the Ironic Password
@@ -85,7 +85,7 @@ func CreateInspectorPasswordSecret(client coreclientv1.SecretsGetter, targetName
 func createIronicSecret(client coreclientv1.SecretsGetter, targetNamespace string, name string, username string, configSection string) error {
 	_, err := client.Secrets(targetNamespace).Get(context.Background(), name, metav1.GetOptions{})
 	if !apierrors.IsNotFound(err) {
-		return err
+		return apiError(err)
 	}
 
 	// Secret does not already exist. So, create one.
@@ -131,5 +131,5 @@ password = %s
 		},
 		metav1.CreateOptions{},
 	)
-	return err
+	return apiError(err)
 }
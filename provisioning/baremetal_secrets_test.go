@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	pkgerrors "github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -11,6 +12,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	fakekube "k8s.io/client-go/kubernetes/fake"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	faketesting "k8s.io/client-go/testing"
 )
 
@@ -114,7 +116,16 @@ func TestCreateMariadbPasswordSecret(t *testing.T) {
 					t.Errorf("Error creating Ironic secret.")
 				}
 				assert.True(t, strings.Compare(secret.(*v1.Secret).StringData[ironicUsernameKey], inspectorUsername) == 0, "inspector password created incorrectly")
-
+			case "error-fetching-secret":
+				for _, create := range []func(coreclientv1.SecretsGetter, string) error{
+					CreateMariadbPasswordSecret,
+					CreateIronicPasswordSecret,
+					CreateInspectorPasswordSecret,
+				} {
+					err := create(kubeClient.CoreV1(), testNamespace)
+					assert.EqualError(t, err, tc.expectedError.Error())
+					assert.True(t, pkgerrors.Is(err, ErrTransientAPI), "API errors are transient")
+				}
 			}
 		})
 	}
@@ -0,0 +1,126 @@
+package provisioning
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	imageServerTlsSecretName = "metal3-image-server-tls" // #nosec
+	imageServerCertValidity  = 2 * 365 * 24 * time.Hour
+)
+
+// generateSelfSignedCertificate returns a PEM encoded certificate and key
+// valid for the given host, which may be an IP address or a DNS name.
+func generateSelfSignedCertificate(host string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: host},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(imageServerCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	return certPEM, keyPEM, nil
+}
+
+// CreateImageServerTlsSecret creates a Secret with a self-signed
+// certificate for the HTTPS listener of the image server. The certificate
+// is issued again when it expires within renewBefore, or when it is not
+// valid for host anymore, such as when the ProvisioningIP changed.
+func CreateImageServerTlsSecret(client coreclientv1.SecretsGetter, targetNamespace string, host string, renewBefore time.Duration) error {
+	existing, err := client.Secrets(targetNamespace).Get(context.Background(), imageServerTlsSecretName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return apiError(err)
+	}
+	now := time.Now()
+	found := err == nil
+	if found {
+		certs, err := parseCertificates(existing.Data[corev1.TLSCertKey])
+		if err == nil && certs[0].NotAfter.After(now.Add(renewBefore)) && (host == "" || certs[0].VerifyHostname(host) == nil) {
+			return nil
+		}
+	}
+
+	cert, key, err := generateSelfSignedCertificate(host, now)
+	if err != nil {
+		return err
+	}
+	data := map[string][]byte{
+		corev1.TLSCertKey:       cert,
+		corev1.TLSPrivateKeyKey: key,
+	}
+	if found {
+		// An expiring, unreadable or stale certificate is renewed
+		updated := existing.DeepCopy()
+		updated.Data = data
+		_, err = client.Secrets(targetNamespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+		return apiError(err)
+	}
+	_, err = client.Secrets(targetNamespace).Create(
+		context.Background(),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      imageServerTlsSecretName,
+				Namespace: targetNamespace,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: data,
+		},
+		metav1.CreateOptions{},
+	)
+	return apiError(err)
+}
+
+// GetImageServerTlsSecret returns the serving certificate of the image
+// server, or nil until it has been created
+func GetImageServerTlsSecret(client coreclientv1.SecretsGetter, targetNamespace string) (*corev1.Secret, error) {
+	secret, err := client.Secrets(targetNamespace).Get(context.Background(), imageServerTlsSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return secret, nil
+}
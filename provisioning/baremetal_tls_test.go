@@ -0,0 +1,112 @@
+package provisioning
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestGenerateSelfSignedCertificate(t *testing.T) {
+	now := time.Now()
+	for _, host := range []string{"172.30.20.3", "fd2e:6f44:5dd8:b856::2", "metal3.example.com"} {
+		t.Run(host, func(t *testing.T) {
+			certPEM, keyPEM, err := generateSelfSignedCertificate(host, now)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, keyPEM)
+
+			block, _ := pem.Decode(certPEM)
+			if block == nil {
+				t.Fatal("certificate is not PEM encoded")
+			}
+			cert, err := x509.ParseCertificate(block.Bytes)
+			assert.NoError(t, err)
+			assert.NoError(t, cert.VerifyHostname(host))
+			assert.True(t, cert.NotAfter.After(now.Add(365*24*time.Hour)))
+			if ip := net.ParseIP(host); ip != nil {
+				assert.Len(t, cert.IPAddresses, 1)
+			}
+		})
+	}
+}
+
+func TestCreateImageServerTlsSecret(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+
+	err := CreateImageServerTlsSecret(kubeClient.CoreV1(), testNamespace, "172.30.20.3", 30*24*time.Hour)
+	assert.NoError(t, err)
+	secret, err := kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), imageServerTlsSecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, corev1.SecretTypeTLS, secret.Type)
+
+	// An existing certificate must not be regenerated
+	err = CreateImageServerTlsSecret(kubeClient.CoreV1(), testNamespace, "172.30.20.3", 30*24*time.Hour)
+	assert.NoError(t, err)
+	again, err := kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), imageServerTlsSecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, secret.Data, again.Data)
+
+	// Unless it expires within the renewal period
+	err = CreateImageServerTlsSecret(kubeClient.CoreV1(), testNamespace, "172.30.20.3", imageServerCertValidity)
+	assert.NoError(t, err)
+	renewed, err := kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), imageServerTlsSecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, secret.Data, renewed.Data)
+	assert.Equal(t, corev1.SecretTypeTLS, renewed.Type)
+
+	// Or it is not valid for the ProvisioningIP anymore
+	err = CreateImageServerTlsSecret(kubeClient.CoreV1(), testNamespace, "172.30.20.4", 30*24*time.Hour)
+	assert.NoError(t, err)
+	moved, err := kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), imageServerTlsSecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	certs, err := parseCertificates(moved.Data[corev1.TLSCertKey])
+	if assert.NoError(t, err) {
+		assert.NoError(t, certs[0].VerifyHostname("172.30.20.4"))
+	}
+
+	// Without a ProvisioningIP, there is no address to check
+	err = CreateImageServerTlsSecret(kubeClient.CoreV1(), testNamespace, "", 30*24*time.Hour)
+	assert.NoError(t, err)
+	again, err = kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), imageServerTlsSecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, moved.Data, again.Data)
+}
+
+func TestGetOperandCertificates(t *testing.T) {
+	cert, key, err := generateSelfSignedCertificate("172.30.20.3", time.Now())
+	assert.NoError(t, err)
+	kubeClient := fakekube.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: imageServerTlsSecretName, Namespace: testNamespace},
+			Data:       map[string][]byte{corev1.TLSCertKey: cert, corev1.TLSPrivateKeyKey: key},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: ironicAPIProxyTlsSecretName, Namespace: testNamespace},
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte("not a certificate")},
+		},
+	)
+
+	certs, err := GetOperandCertificates(kubeClient.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	if assert.Len(t, certs, 2) {
+		assert.Equal(t, imageServerTlsSecretName, certs[0].SecretName)
+		assert.True(t, certs[0].SelfIssued)
+		assert.True(t, certs[0].NotAfter.After(time.Now().Add(365*24*time.Hour)))
+		// An unreadable certificate is reported as expired
+		assert.Equal(t, ironicAPIProxyTlsSecretName, certs[1].SecretName)
+		assert.False(t, certs[1].SelfIssued)
+		assert.True(t, certs[1].NotAfter.IsZero())
+	}
+
+	assert.Equal(t, 30*24*time.Hour, GetCertificateExpiryWarning(&metal3iov1alpha1.ProvisioningSpec{}))
+	assert.Equal(t, 7*24*time.Hour, GetCertificateExpiryWarning(&metal3iov1alpha1.ProvisioningSpec{CertificateExpiryWarningDays: 7}))
+}
@@ -0,0 +1,71 @@
+package provisioning
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// BIOSProfileLabel names the BIOSProfiles profile a BareMetalHost is
+	// deployed with
+	BIOSProfileLabel = "baremetal.openshift.io/bios-profile"
+
+	biosProfileTraitPrefix = "CUSTOM_OCP_BIOS_"
+	// biosDeployStepPriority applies the BIOS settings before the RAID
+	// configuration, as they may change the mode of the RAID controllers
+	biosDeployStepPriority = 160
+)
+
+// BIOSProfileTrait returns the trait, and name, of the deploy template of
+// a BIOS profile
+func BIOSProfileTrait(name string) string {
+	return biosProfileTraitPrefix + name
+}
+
+// validateBIOSProfiles checks that the BIOS profiles can be told apart and
+// reference valid ConfigMap names
+func validateBIOSProfiles(config *metal3iov1alpha1.ProvisioningSpec) error {
+	names := map[string]bool{}
+	for _, profile := range config.BIOSProfiles {
+		if names[profile.Name] {
+			return fmt.Errorf("BIOSProfiles: duplicate profile %q", profile.Name)
+		}
+		names[profile.Name] = true
+		if errs := validation.IsDNS1123Subdomain(profile.ConfigMapName); len(errs) > 0 {
+			return fmt.Errorf("BIOSProfiles: invalid ConfigMapName %q of profile %q: %s", profile.ConfigMapName, profile.Name, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// NewBIOSDeployTemplate returns the Ironic deploy template applying the
+// settings held by the ConfigMap of a BIOS profile
+func NewBIOSDeployTemplate(profile metal3iov1alpha1.BIOSProfile, configMap *corev1.ConfigMap) (IronicDeployTemplate, error) {
+	if len(configMap.Data) == 0 {
+		return IronicDeployTemplate{}, fmt.Errorf("BIOSProfiles: ConfigMap %s of profile %q holds no settings", configMap.Name, profile.Name)
+	}
+	names := make([]string, 0, len(configMap.Data))
+	for name := range configMap.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	settings := make([]interface{}, len(names))
+	for i, name := range names {
+		settings[i] = map[string]interface{}{"name": name, "value": configMap.Data[name]}
+	}
+	return IronicDeployTemplate{
+		Name: BIOSProfileTrait(profile.Name),
+		Steps: []IronicDeployStep{{
+			Interface: "bios",
+			Step:      "apply_configuration",
+			Args:      map[string]interface{}{"settings": settings},
+			Priority:  biosDeployStepPriority,
+		}},
+	}, nil
+}
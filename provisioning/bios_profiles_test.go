@@ -0,0 +1,91 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateBIOSProfiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		profiles      []metal3iov1alpha1.BIOSProfile
+		expectedError string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name: "Valid",
+			profiles: []metal3iov1alpha1.BIOSProfile{
+				{Name: "STORAGE", ConfigMapName: "bios-storage"},
+				{Name: "COMPUTE", ConfigMapName: "bios-compute"},
+			},
+		},
+		{
+			name: "Duplicate",
+			profiles: []metal3iov1alpha1.BIOSProfile{
+				{Name: "STORAGE", ConfigMapName: "bios-storage"},
+				{Name: "STORAGE", ConfigMapName: "bios-compute"},
+			},
+			expectedError: `BIOSProfiles: duplicate profile "STORAGE"`,
+		},
+		{
+			name: "InvalidConfigMapName",
+			profiles: []metal3iov1alpha1.BIOSProfile{
+				{Name: "STORAGE", ConfigMapName: "BIOS"},
+			},
+			expectedError: `BIOSProfiles: invalid ConfigMapName "BIOS" of profile "STORAGE": a DNS-1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.BIOSProfiles = tc.profiles
+			err := validateBIOSProfiles(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestNewBIOSDeployTemplate(t *testing.T) {
+	profile := metal3iov1alpha1.BIOSProfile{Name: "STORAGE", ConfigMapName: "bios-storage"}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "bios-storage", Namespace: testNamespace},
+		Data: map[string]string{
+			"ProcVirtualization": "Disabled",
+			"BootMode":           "Uefi",
+		},
+	}
+	template, err := NewBIOSDeployTemplate(profile, configMap)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, err := json.Marshal(template)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "CUSTOM_OCP_BIOS_STORAGE",
+		"steps": [{
+			"interface": "bios",
+			"step": "apply_configuration",
+			"priority": 160,
+			"args": {"settings": [
+				{"name": "BootMode", "value": "Uefi"},
+				{"name": "ProcVirtualization", "value": "Disabled"}
+			]}
+		}]
+	}`, string(data))
+
+	configMap.Data = nil
+	_, err = NewBIOSDeployTemplate(profile, configMap)
+	assert.EqualError(t, err, `BIOSProfiles: ConfigMap bios-storage of profile "STORAGE" holds no settings`)
+}
@@ -0,0 +1,102 @@
+package provisioning
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// bmcRoutesContainerName is the container routing the subnets of the BMCs
+// through the out-of-band network
+const bmcRoutesContainerName = "metal3-bmc-routes"
+
+// bmcRoutesScript keeps the subnets of the BMCs routed through the
+// out-of-band interface, configuring the routes again when they were
+// removed or the interface was not up yet. The routes are removed when
+// the pod stops, so that they do not outlive the pod on its node.
+const bmcRoutesScript = `
+set -u
+add_routes() {
+    local subnet
+    for subnet in $BMC_SUBNETS; do
+        ip route replace "$subnet" ${BMC_GATEWAY:+via "$BMC_GATEWAY"} dev "$BMC_INTERFACE" || return 1
+    done
+}
+remove_routes() {
+    local subnet
+    for subnet in $BMC_SUBNETS; do
+        echo "removing route to $subnet from $BMC_INTERFACE"
+        ip route del "$subnet" dev "$BMC_INTERFACE" || true
+    done
+    exit 0
+}
+trap remove_routes TERM INT
+while true; do
+    add_routes || echo "failed to route the BMC subnets through $BMC_INTERFACE"
+    sleep "$BMC_ROUTES_REPAIR_INTERVAL" &
+    wait $!
+done
+`
+
+// validateBMCNetwork checks that the subnets of the BMCs can be routed
+// through the out-of-band network, apart from the provisioning network
+func validateBMCNetwork(config *metal3iov1alpha1.ProvisioningSpec) error {
+	network := config.BMCNetwork
+	if network == nil {
+		return nil
+	}
+	if network.Interface == "" {
+		return fmt.Errorf("BMCNetwork: Interface is required")
+	}
+	if network.Interface == config.ProvisioningInterface {
+		return fmt.Errorf("BMCNetwork: Interface %q is the ProvisioningInterface, the BMCs must be on a separate network", network.Interface)
+	}
+	if len(network.Subnets) == 0 {
+		return fmt.Errorf("BMCNetwork: at least one of the Subnets is required")
+	}
+	var gateway net.IP
+	if network.Gateway != "" {
+		if gateway = net.ParseIP(network.Gateway); gateway == nil {
+			return fmt.Errorf("BMCNetwork: invalid Gateway %q", network.Gateway)
+		}
+	}
+	for _, subnet := range network.Subnets {
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return fmt.Errorf("BMCNetwork: invalid subnet %q: %v", subnet, err)
+		}
+		if gateway != nil && (gateway.To4() == nil) != (ipNet.IP.To4() == nil) {
+			return fmt.Errorf("BMCNetwork: Gateway %s and subnet %s are not of the same IP family", network.Gateway, subnet)
+		}
+		if config.ProvisioningNetworkCIDR != "" && cidrsOverlap(subnet, config.ProvisioningNetworkCIDR) {
+			return fmt.Errorf("BMCNetwork: subnet %s overlaps the ProvisioningNetworkCIDR %s", subnet, config.ProvisioningNetworkCIDR)
+		}
+	}
+	return nil
+}
+
+// createContainerMetal3BMCRoutes returns the container routing the
+// subnets of the BMCs through the out-of-band network of the node
+func createContainerMetal3BMCRoutes(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	network := config.BMCNetwork
+	return corev1.Container{
+		Name:            bmcRoutesContainerName,
+		Image:           images.BaremetalStaticIpManager,
+		Command:         []string{"/bin/bash", "-c", bmcRoutesScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		Env: []corev1.EnvVar{
+			{Name: "BMC_INTERFACE", Value: network.Interface},
+			{Name: "BMC_SUBNETS", Value: strings.Join(network.Subnets, " ")},
+			{Name: "BMC_GATEWAY", Value: network.Gateway},
+			{Name: "BMC_ROUTES_REPAIR_INTERVAL", Value: staticIPRepairInterval},
+		},
+	}
+}
@@ -0,0 +1,88 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateBMCNetwork(t *testing.T) {
+	tests := []struct {
+		name          string
+		network       *metal3iov1alpha1.BMCNetwork
+		expectedError string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name:    "Routed",
+			network: &metal3iov1alpha1.BMCNetwork{Interface: "eno2", Subnets: []string{"10.10.0.0/24", "10.20.0.0/24"}, Gateway: "10.0.0.1"},
+		},
+		{
+			name:    "DirectlyConnected",
+			network: &metal3iov1alpha1.BMCNetwork{Interface: "eno2", Subnets: []string{"fd00:10::/64"}},
+		},
+		{
+			name:          "ProvisioningInterface",
+			network:       &metal3iov1alpha1.BMCNetwork{Interface: "eth0", Subnets: []string{"10.10.0.0/24"}},
+			expectedError: `BMCNetwork: Interface "eth0" is the ProvisioningInterface, the BMCs must be on a separate network`,
+		},
+		{
+			name:          "NoSubnets",
+			network:       &metal3iov1alpha1.BMCNetwork{Interface: "eno2"},
+			expectedError: "BMCNetwork: at least one of the Subnets is required",
+		},
+		{
+			name:          "InvalidSubnet",
+			network:       &metal3iov1alpha1.BMCNetwork{Interface: "eno2", Subnets: []string{"10.10.0.0"}},
+			expectedError: `BMCNetwork: invalid subnet "10.10.0.0": invalid CIDR address: 10.10.0.0`,
+		},
+		{
+			name:          "InvalidGateway",
+			network:       &metal3iov1alpha1.BMCNetwork{Interface: "eno2", Subnets: []string{"10.10.0.0/24"}, Gateway: "router"},
+			expectedError: `BMCNetwork: invalid Gateway "router"`,
+		},
+		{
+			name:          "MixedFamilies",
+			network:       &metal3iov1alpha1.BMCNetwork{Interface: "eno2", Subnets: []string{"fd00:10::/64"}, Gateway: "10.0.0.1"},
+			expectedError: "BMCNetwork: Gateway 10.0.0.1 and subnet fd00:10::/64 are not of the same IP family",
+		},
+		{
+			name:          "ProvisioningNetworkOverlap",
+			network:       &metal3iov1alpha1.BMCNetwork{Interface: "eno2", Subnets: []string{"172.30.0.0/16"}},
+			expectedError: "BMCNetwork: subnet 172.30.0.0/16 overlaps the ProvisioningNetworkCIDR 172.30.20.0/24",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.BMCNetwork = tc.network
+			err := validateBMCNetwork(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestBMCRoutesContainer(t *testing.T) {
+	spec := managedProvisioning()
+	assert.Nil(t, findContainer(NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, bmcRoutesContainerName))
+
+	spec.BMCNetwork = &metal3iov1alpha1.BMCNetwork{Interface: "eno2", Subnets: []string{"10.10.0.0/24", "10.20.0.0/24"}, Gateway: "10.0.0.1"}
+	routes := findContainer(NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, bmcRoutesContainerName)
+	if assert.NotNil(t, routes) {
+		assert.Equal(t, "eno2", envValue(routes, "BMC_INTERFACE"))
+		assert.Equal(t, "10.10.0.0/24 10.20.0.0/24", envValue(routes, "BMC_SUBNETS"))
+		assert.Equal(t, "10.0.0.1", envValue(routes, "BMC_GATEWAY"))
+	}
+
+	group := &metal3iov1alpha1.ConductorGroup{Name: "rack1"}
+	containers := NewConductorGroupDeployment(testNamespace, &testImages, spec, group).Spec.Template.Spec.Containers
+	assert.NotNil(t, findContainer(containers, bmcRoutesContainerName))
+}
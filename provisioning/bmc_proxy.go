@@ -0,0 +1,124 @@
+package provisioning
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	bmcProxyUsernameEnv = "BMC_PROXY_USERNAME"
+	bmcProxyPasswordEnv = "BMC_PROXY_PASSWORD"
+)
+
+// bmcProxySchemes are the proxy schemes understood by the Python requests
+// library used by the Ironic drivers
+var bmcProxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// defaultNoProxy are always reached without the BMC proxy, so that the
+// conductors keep reaching the database, each other and the cluster
+var defaultNoProxy = []string{"localhost", "127.0.0.1", "::1", ".svc", ".cluster.local"}
+
+// validateBMCProxy checks that the BMC proxy can be rendered into the
+// environment of the Ironic conductors
+func validateBMCProxy(config *metal3iov1alpha1.ProvisioningSpec) error {
+	proxy := config.BMCProxy
+	if proxy == nil {
+		return nil
+	}
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		return fmt.Errorf("BMCProxy: invalid URL %q: %v", proxy.URL, err)
+	}
+	supported := false
+	for _, scheme := range bmcProxySchemes {
+		supported = supported || proxyURL.Scheme == scheme
+	}
+	if !supported {
+		return fmt.Errorf("BMCProxy: unsupported URL scheme %q, must be one of %s", proxyURL.Scheme, strings.Join(bmcProxySchemes, ", "))
+	}
+	if proxyURL.Host == "" {
+		return fmt.Errorf("BMCProxy: URL %q has no host", proxy.URL)
+	}
+	if proxyURL.User != nil {
+		return fmt.Errorf("BMCProxy: URL cannot contain credentials, use CredentialsSecretName instead")
+	}
+	if proxy.CredentialsSecretName != "" {
+		if errs := validation.IsDNS1123Subdomain(proxy.CredentialsSecretName); len(errs) > 0 {
+			return fmt.Errorf("BMCProxy: invalid CredentialsSecretName %q: %s", proxy.CredentialsSecretName, strings.Join(errs, ", "))
+		}
+	}
+	for _, host := range proxy.NoProxy {
+		if host == "" || strings.ContainsAny(host, ", \t") {
+			return fmt.Errorf("BMCProxy: invalid NoProxy entry %q", host)
+		}
+	}
+	return nil
+}
+
+// getBMCProxyURL returns the URL of the BMC proxy, with references to the
+// credentials expanded by the kubelet when a Secret holds them
+func getBMCProxyURL(proxy *metal3iov1alpha1.BMCProxy) string {
+	if proxy.CredentialsSecretName == "" {
+		return proxy.URL
+	}
+	parts := strings.SplitN(proxy.URL, "://", 2)
+	return fmt.Sprintf("%s://$(%s):$(%s)@%s", parts[0], bmcProxyUsernameEnv, bmcProxyPasswordEnv, parts[1])
+}
+
+// getBMCNoProxy returns the hosts reached without the BMC proxy
+func getBMCNoProxy(config *metal3iov1alpha1.ProvisioningSpec) string {
+	noProxy := append([]string{}, defaultNoProxy...)
+	if config.ProvisioningIP != "" {
+		noProxy = append(noProxy, config.ProvisioningIP)
+	}
+	if config.ProvisioningNetworkCIDR != "" {
+		noProxy = append(noProxy, config.ProvisioningNetworkCIDR)
+	}
+	noProxy = append(noProxy, config.BMCProxy.NoProxy...)
+	return strings.Join(noProxy, ",")
+}
+
+func newBMCProxyCredentialEnv(name string, secretName string, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: secretName,
+				},
+				Key: key,
+			},
+		},
+	}
+}
+
+// newBMCProxyEnv returns the environment of the Ironic conductor sending
+// its HTTP traffic through the BMC proxy. The Ironic drivers talk to the
+// BMCs through the requests library, which reads the proxy from the
+// environment, so the agents on the provisioning network and the other
+// conductors are kept out of it through NO_PROXY.
+func newBMCProxyEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	proxy := config.BMCProxy
+	if proxy == nil {
+		return nil
+	}
+	env := []corev1.EnvVar{}
+	if proxy.CredentialsSecretName != "" {
+		// Variables must be defined before being referenced
+		env = append(env,
+			newBMCProxyCredentialEnv(bmcProxyUsernameEnv, proxy.CredentialsSecretName, corev1.BasicAuthUsernameKey),
+			newBMCProxyCredentialEnv(bmcProxyPasswordEnv, proxy.CredentialsSecretName, corev1.BasicAuthPasswordKey))
+	}
+	proxyURL := getBMCProxyURL(proxy)
+	return append(env,
+		corev1.EnvVar{Name: "HTTP_PROXY", Value: proxyURL},
+		corev1.EnvVar{Name: "HTTPS_PROXY", Value: proxyURL},
+		corev1.EnvVar{Name: "NO_PROXY", Value: getBMCNoProxy(config)},
+	)
+}
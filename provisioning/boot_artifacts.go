@@ -0,0 +1,132 @@
+package provisioning
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// BootArtifactsConfigMap is the name of the ConfigMap publishing the URLs
+// of the boot artifacts, for the installation flows that boot hosts
+// without going through Ironic
+const BootArtifactsConfigMap = "metal3-boot-artifacts"
+
+// getCachedImageName returns the name an image downloaded from the given
+// URL is cached under, which is that of the uncompressed image
+func getCachedImageName(downloadURL string) string {
+	imageURL, err := url.Parse(downloadURL)
+	if err != nil || imageURL.Path == "" {
+		return ""
+	}
+	name := path.Base(imageURL.Path)
+	for _, suffix := range []string{".gz", ".xz", zstdSuffix} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
+
+// getCachedOSImageSubPath returns the path the machine-os-downloader
+// caches the provisioning OS image under, relative to the image server
+// root, or an empty string when the download URL cannot be parsed
+func getCachedOSImageSubPath(config *metal3iov1alpha1.ProvisioningSpec) string {
+	name := getCachedImageName(config.ProvisioningOSDownloadURL)
+	if name == "" {
+		return ""
+	}
+	subPath := "images/" + name + "/" + name
+	if config.ConvertOSImageToRaw {
+		subPath += ".raw"
+	}
+	return subPath
+}
+
+func addBootArtifactURLs(data map[string]string, prefix string, urls metal3iov1alpha1.BootArtifactURLs) {
+	for key, value := range map[string]string{
+		"deployKernel":    urls.DeployKernel,
+		"deployRamdisk":   urls.DeployRamdisk,
+		"osImage":         urls.OSImage,
+		"osImageChecksum": urls.OSImageChecksum,
+		"firmware":        urls.Firmware,
+		"liveKernel":      urls.LiveKernel,
+		"liveInitramfs":   urls.LiveInitramfs,
+		"liveRootfs":      urls.LiveRootfs,
+	} {
+		if value != "" {
+			data[prefix+key] = value
+		}
+	}
+}
+
+// NewBootArtifactsConfigMap returns the ConfigMap publishing the URLs of
+// the image server. HTTPS URLs, when enabled, use keys prefixed with
+// "https.".
+func NewBootArtifactsConfigMap(targetNamespace string, status metal3iov1alpha1.ImageServerStatus) *corev1.ConfigMap {
+	data := map[string]string{}
+	addBootArtifactURLs(data, "", status.HTTP)
+	if status.HTTPS != nil {
+		addBootArtifactURLs(data, "https.", *status.HTTPS)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      BootArtifactsConfigMap,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: data,
+	}
+}
+
+// ApplyBootArtifactsConfigMap creates or updates the ConfigMap publishing
+// the URLs of the boot artifacts
+func ApplyBootArtifactsConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	return applyConfigMap(client, configMap)
+}
+
+// DeleteBootArtifactsConfigMap removes the ConfigMap publishing the URLs
+// of the boot artifacts, once the image server is deleted
+func DeleteBootArtifactsConfigMap(client coreclientv1.ConfigMapsGetter, targetNamespace string) error {
+	return deleteConfigMap(client, targetNamespace, BootArtifactsConfigMap)
+}
+
+// applyConfigMap creates or updates a ConfigMap generated by the operator
+func applyConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	existing, err := client.ConfigMaps(configMap.Namespace).Get(context.Background(), configMap.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.ConfigMaps(configMap.Namespace).Create(context.Background(), configMap, metav1.CreateOptions{})
+		return apiError(err)
+	}
+	if err != nil {
+		return apiError(err)
+	}
+	if equality.Semantic.DeepEqual(configMap.Data, existing.Data) &&
+		equality.Semantic.DeepDerivative(configMap.OwnerReferences, existing.OwnerReferences) {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Labels = configMap.Labels
+	updated.OwnerReferences = configMap.OwnerReferences
+	updated.Data = configMap.Data
+	_, err = client.ConfigMaps(configMap.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	return apiError(err)
+}
+
+// deleteConfigMap removes a ConfigMap generated by the operator, if any
+func deleteConfigMap(client coreclientv1.ConfigMapsGetter, targetNamespace, name string) error {
+	err := client.ConfigMaps(targetNamespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return apiError(err)
+}
@@ -0,0 +1,55 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetCachedOSImageSubPath(t *testing.T) {
+	config := managedProvisioning()
+	assert.Equal(t, "images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2",
+		getCachedOSImageSubPath(config))
+
+	config.ConvertOSImageToRaw = true
+	assert.Equal(t, "images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.raw",
+		getCachedOSImageSubPath(config))
+
+	config.ConvertOSImageToRaw = false
+	config.ProvisioningOSDownloadURL = "https://mirror.example.com/rhcos-47.84.qcow2.zst?sha256=abc"
+	assert.Equal(t, "images/rhcos-47.84.qcow2/rhcos-47.84.qcow2", getCachedOSImageSubPath(config))
+
+	config.ProvisioningOSDownloadURL = ""
+	assert.Equal(t, "", getCachedOSImageSubPath(config))
+}
+
+func TestBootArtifactsConfigMap(t *testing.T) {
+	config := managedProvisioning()
+	config.ImageServerHTTPS = true
+	configMap := NewBootArtifactsConfigMap(testNamespace, GetImageServerStatus(config))
+
+	assert.Equal(t, BootArtifactsConfigMap, configMap.Name)
+	assert.Equal(t, "http://172.30.20.3:6180/images/ironic-python-agent.kernel", configMap.Data["deployKernel"])
+	assert.Equal(t, "https://172.30.20.3:6183/images/ironic-python-agent.initramfs", configMap.Data["https.deployRamdisk"])
+	assert.Contains(t, configMap.Data["osImage"], "http://172.30.20.3:6180/images/rhcos-44")
+	assert.Len(t, configMap.Data, 6)
+
+	kubeClient := fakekube.NewSimpleClientset()
+	assert.NoError(t, ApplyBootArtifactsConfigMap(kubeClient.CoreV1(), configMap))
+
+	config.ImageServerHTTPS = false
+	assert.NoError(t, ApplyBootArtifactsConfigMap(kubeClient.CoreV1(), NewBootArtifactsConfigMap(testNamespace, GetImageServerStatus(config))))
+	published, err := kubeClient.CoreV1().ConfigMaps(testNamespace).Get(context.Background(), BootArtifactsConfigMap, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, published.Data, 3)
+	assert.NotContains(t, published.Data, "https.deployKernel")
+
+	config.LivePXEArtifacts = testLivePXEArtifacts()
+	configMap = NewBootArtifactsConfigMap(testNamespace, GetImageServerStatus(config))
+	assert.Equal(t, "http://172.30.20.3:6180/images/live/kernel", configMap.Data["liveKernel"])
+	assert.Equal(t, "http://172.30.20.3:6180/images/live/initramfs.img", configMap.Data["liveInitramfs"])
+	assert.Equal(t, "http://172.30.20.3:6180/images/live/rootfs.img", configMap.Data["liveRootfs"])
+}
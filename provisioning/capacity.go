@@ -0,0 +1,104 @@
+package provisioning
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// defaultProvisioningLimit is the number of hosts the
+	// baremetal-operator provisions at once when its PROVISIONING_LIMIT
+	// is not set
+	defaultProvisioningLimit = 20
+	provisioningLimitEnvVar  = "PROVISIONING_LIMIT"
+	// dhcpLeasesMetric is the metric of the lease exporter counting the
+	// dnsmasq leases
+	dhcpLeasesMetric = "metal3_dhcp_leases"
+)
+
+var (
+	// conductorBaseMemory is the memory the Ironic conductor uses when
+	// idle
+	conductorBaseMemory = resource.MustParse("256Mi")
+	// conductorDeployMemory is the memory the Ironic conductor uses for
+	// each deployment in progress, mostly for the image checksums and
+	// conversions
+	conductorDeployMemory = resource.MustParse("64Mi")
+)
+
+// DHCPMetricsURL returns the URL of the lease exporter of the dnsmasq pod
+// with the given IP
+func DHCPMetricsURL(podIP string) string {
+	return fmt.Sprintf("http://%s/metrics", net.JoinHostPort(podIP, strconv.Itoa(dhcpMetricsPort)))
+}
+
+// ParseDHCPLeaseCount returns the number of leases in the metrics of the
+// lease exporter
+func ParseDHCPLeaseCount(metrics []byte) (int64, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(metrics))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == dhcpLeasesMetric {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("no %s metric found", dhcpLeasesMetric)
+}
+
+// getProvisioningLimit returns the number of hosts the
+// baremetal-operator provisions at once
+func getProvisioningLimit(config *metal3iov1alpha1.ProvisioningSpec) int32 {
+	if config.ProvisioningLimit > 0 {
+		return config.ProvisioningLimit
+	}
+	return defaultProvisioningLimit
+}
+
+// getMaxParallelDeploys returns the number of hosts that can be
+// provisioned at once. The memory limit of the conductor, set by the
+// Minimal profile, bounds it below the limit of the baremetal-operator.
+func getMaxParallelDeploys(config *metal3iov1alpha1.ProvisioningSpec) int32 {
+	provisioningLimit := getProvisioningLimit(config)
+	if !IsMinimalProfile(config) {
+		return provisioningLimit
+	}
+	limit := minimalMemoryLimits["metal3-ironic-conductor"]
+	deploys := (limit.Value() - conductorBaseMemory.Value()) / conductorDeployMemory.Value()
+	if deploys < 1 {
+		return 1
+	}
+	if deploys > int64(provisioningLimit) {
+		return provisioningLimit
+	}
+	return int32(deploys)
+}
+
+// GetProvisioningCapacity returns how many hosts the metal3 services can
+// serve at once, given the number of DHCP leases when known
+func GetProvisioningCapacity(config *metal3iov1alpha1.ProvisioningSpec, leases *int64) *metal3iov1alpha1.ProvisioningCapacity {
+	capacity := &metal3iov1alpha1.ProvisioningCapacity{
+		MaxParallelDeploys: getMaxParallelDeploys(config),
+	}
+	if !IsDnsmasqRequired(config) {
+		return capacity
+	}
+	if addresses, err := getDHCPRangeCapacity(config); err == nil {
+		count := int64(math.MaxInt64)
+		// IPv6 ranges may hold more addresses than can be counted
+		if addresses.IsInt64() {
+			count = addresses.Int64()
+		}
+		capacity.DHCPAddresses = &count
+	}
+	capacity.DHCPLeases = leases
+	return capacity
+}
@@ -0,0 +1,78 @@
+package provisioning
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestParseDHCPLeaseCount(t *testing.T) {
+	leases, err := ParseDHCPLeaseCount([]byte(`# HELP metal3_dhcp_leases Number of active leases of the provisioning DHCP server.
+# TYPE metal3_dhcp_leases gauge
+metal3_dhcp_leases 12
+# TYPE metal3_dhcp_range_capacity gauge
+metal3_dhcp_range_capacity 91
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(12), leases)
+
+	_, err = ParseDHCPLeaseCount([]byte("metal3_dhcp_range_capacity 91\n"))
+	assert.EqualError(t, err, "no metal3_dhcp_leases metric found")
+	assert.Equal(t, "http://172.22.0.3:60002/metrics", DHCPMetricsURL("172.22.0.3"))
+	assert.Equal(t, "http://[fd00:1101::3]:60002/metrics", DHCPMetricsURL("fd00:1101::3"))
+}
+
+func TestGetProvisioningCapacity(t *testing.T) {
+	leases := int64(12)
+	spec := managedProvisioning()
+	spec.ProvisioningDHCPRange = "172.30.20.11, 172.30.20.101"
+	capacity := GetProvisioningCapacity(spec, &leases)
+	if assert.NotNil(t, capacity.DHCPAddresses) {
+		assert.Equal(t, int64(91), *capacity.DHCPAddresses)
+	}
+	assert.Equal(t, &leases, capacity.DHCPLeases)
+	assert.Equal(t, int32(20), capacity.MaxParallelDeploys)
+
+	spec.DHCPRelayRanges = []metal3iov1alpha1.DHCPRelayRange{{DHCPRange: "172.30.21.10,172.30.21.19"}}
+	capacity = GetProvisioningCapacity(spec, nil)
+	assert.Equal(t, int64(101), *capacity.DHCPAddresses)
+	assert.Nil(t, capacity.DHCPLeases)
+
+	// IPv6 ranges can be too large to count
+	spec = managedProvisioning()
+	spec.ProvisioningNetworkCIDR = "fd00:1101::/64"
+	spec.ProvisioningIP = "fd00:1101::3"
+	spec.ProvisioningDHCPRange = "fd00:1101::10,fd00:1101::ffff:ffff:ffff:ffff"
+	assert.Equal(t, int64(math.MaxInt64), *GetProvisioningCapacity(spec, nil).DHCPAddresses)
+
+	// No DHCP is served without a managed provisioning network, and the
+	// Minimal profile limits the memory of the conductor
+	spec = &metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkDisabled,
+		Profile:             metal3iov1alpha1.ProvisioningProfileMinimal,
+	}
+	capacity = GetProvisioningCapacity(spec, &leases)
+	assert.Nil(t, capacity.DHCPAddresses)
+	assert.Nil(t, capacity.DHCPLeases)
+	assert.Equal(t, int32(4), capacity.MaxParallelDeploys)
+
+	// The limit of the baremetal-operator applies to both
+	spec.ProvisioningLimit = 2
+	assert.Equal(t, int32(2), GetProvisioningCapacity(spec, nil).MaxParallelDeploys)
+	spec.Profile = ""
+	spec.ProvisioningLimit = 50
+	assert.Equal(t, int32(50), GetProvisioningCapacity(spec, nil).MaxParallelDeploys)
+}
+
+func TestProvisioningLimit(t *testing.T) {
+	config := managedProvisioning()
+	bmo := createContainerMetal3BaremetalOperator(&testImages, config)
+	assert.Equal(t, "", envValue(&bmo, provisioningLimitEnvVar))
+
+	config.ProvisioningLimit = 50
+	bmo = createContainerMetal3BaremetalOperator(&testImages, config)
+	assert.Equal(t, "50", envValue(&bmo, provisioningLimitEnvVar))
+}
@@ -0,0 +1,77 @@
+package provisioning
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const defaultCertificateExpiryWarningDays = 30
+
+// operandCertificateSecrets are the Secrets holding the serving
+// certificates of the operands. The image server certificate is issued by
+// the operator, the others by the service CA operator, which renews them.
+var operandCertificateSecrets = []struct {
+	name       string
+	selfIssued bool
+}{
+	{name: imageServerTlsSecretName, selfIssued: true},
+	{name: ironicAPIProxyTlsSecretName},
+}
+
+// OperandCertificate is the serving certificate of an operand
+type OperandCertificate struct {
+	SecretName string
+	NotAfter   time.Time
+	// SelfIssued is true for the certificates the operator issues and
+	// renews itself
+	SelfIssued bool
+}
+
+// GetCertificateExpiryWarning returns how long before they expire the
+// operand certificates are reported and, when self-issued, renewed
+func GetCertificateExpiryWarning(config *metal3iov1alpha1.ProvisioningSpec) time.Duration {
+	days := config.CertificateExpiryWarningDays
+	if days == 0 {
+		days = defaultCertificateExpiryWarningDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// GetOperandCertificates returns the serving certificates of the operands
+// issued so far. An unreadable certificate is returned as already
+// expired, as no client can use it either.
+func GetOperandCertificates(client coreclientv1.SecretsGetter, targetNamespace string) ([]OperandCertificate, error) {
+	certs := []OperandCertificate{}
+	for _, s := range operandCertificateSecrets {
+		secret, err := client.Secrets(targetNamespace).Get(context.Background(), s.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cert := OperandCertificate{SecretName: s.name, SelfIssued: s.selfIssued}
+		if notAfter, err := certificateNotAfter(secret.Data[corev1.TLSCertKey]); err == nil {
+			cert.NotAfter = notAfter
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// certificateNotAfter returns the expiry of the first certificate of a
+// PEM encoded chain
+func certificateNotAfter(certPEM []byte) (time.Time, error) {
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return certs[0].NotAfter, nil
+}
@@ -0,0 +1,324 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ConductorGroupLabel holds the conductor group of the BareMetalHosts
+	// and of the conductors serving them
+	ConductorGroupLabel = "baremetal.openshift.io/conductor-group"
+	// Metal3DatabaseServiceName is the Service the conductors of the
+	// groups reach the database of the metal3 pod through
+	Metal3DatabaseServiceName = "metal3-database"
+	// ConductorJSONRPCPort is the port conductors are called on by the
+	// Ironic API
+	ConductorJSONRPCPort = 8089
+
+	conductorGroupBaseName = "metal3-conductor"
+	databasePortName       = "mysql"
+	databasePort           = 3306
+)
+
+// ConductorGroupDeploymentName returns the name of the Deployment running
+// the conductors of a group
+func ConductorGroupDeploymentName(group string) string {
+	return conductorGroupBaseName + "-" + group
+}
+
+// GetConductorGroupReplicas returns the number of conductors of a group
+func GetConductorGroupReplicas(group *metal3iov1alpha1.ConductorGroup) int32 {
+	if group.Replicas == nil {
+		return 1
+	}
+	return *group.Replicas
+}
+
+// withoutEnv returns the environment without the given variable
+func withoutEnv(env []corev1.EnvVar, name string) []corev1.EnvVar {
+	kept := []corev1.EnvVar{}
+	for _, e := range env {
+		if e.Name != name {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// newConductorGroupContainers returns a conductor joining the group and
+// the image server boot configurations it writes are served from. They
+// use the address of their node on the provisioning interface rather
+// than the ProvisioningIP, which belongs to the metal3 pod.
+func newConductorGroupContainers(images *Images, config *metal3iov1alpha1.ProvisioningSpec, group string) []corev1.Container {
+	conductor := createContainerMetal3IronicConductor(images, config)
+	conductor.Env = append(withoutEnv(conductor.Env, provisioningIP),
+		corev1.EnvVar{Name: "OS_CONDUCTOR__CONDUCTOR_GROUP", Value: group},
+		corev1.EnvVar{
+			Name: "OS_DEFAULT__HOST",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+			},
+		},
+		// Expanded from the MARIADB_PASSWORD defined before it
+		corev1.EnvVar{
+			Name: "OS_DATABASE__CONNECTION",
+			Value: fmt.Sprintf("mysql+pymysql://ironic:$(%s)@%s:%d/ironic?charset=utf8",
+				mariadbPwdEnvVar, Metal3DatabaseServiceName, databasePort),
+		},
+	)
+	httpd := createContainerMetal3Httpd(images, config)
+	httpd.Env = withoutEnv(httpd.Env, provisioningIP)
+	// The deploy ramdisk is served by the httpd of the group
+	containers := []corev1.Container{conductor, httpd}
+	downloaders := []corev1.Container{runAlongside(createInitContainerIpaDownloader(images, config))}
+	waitForDownloads(containers, downloaders)
+	containers = append(containers, downloaders...)
+	// The sensors of the hosts of the group are read by its conductors
+	if config.HardwareMetrics != nil {
+		containers = append(containers, createContainerMetal3IronicExporter(images))
+	}
+	// The conductors of the group reach the BMCs from their own nodes
+	if config.BMCNetwork != nil {
+		containers = append(containers, createContainerMetal3BMCRoutes(images, config))
+	}
+	setTerminationMessagePolicy(containers)
+	return containers
+}
+
+// NewConductorGroupDeployment returns the Deployment running the
+// conductors of a group. Like the metal3 pods they use host ports, so no
+// two of them, nor a conductor and a metal3 pod, share a node.
+func NewConductorGroupDeployment(targetNamespace string, images *Images, config *metal3iov1alpha1.ProvisioningSpec, group *metal3iov1alpha1.ConductorGroup) *appsv1.Deployment {
+	name := ConductorGroupDeploymentName(group.Name)
+	labels := map[string]string{
+		"k8s-app":           metal3AppName,
+		"controller":        name,
+		ConductorGroupLabel: group.Name,
+	}
+	nodeSelector := group.NodeSelector
+	if len(nodeSelector) == 0 {
+		nodeSelector = GetMetal3NodeSelector(config)
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app":           metal3AppName,
+				ConductorGroupLabel: group.Name,
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32Ptr(GetConductorGroupReplicas(group)),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Volumes:           newMetal3Volumes(config),
+					Containers:        newConductorGroupContainers(images, config, group.Name),
+					HostNetwork:       true,
+					DNSPolicy:         corev1.DNSClusterFirstWithHostNet,
+					PriorityClassName: metal3PriorityClassName,
+					NodeSelector:      nodeSelector,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot: pointer.BoolPtr(false),
+					},
+					ServiceAccountName: serviceAccountName,
+					Tolerations:        newMetal3Tolerations(config),
+					Affinity: &corev1.Affinity{
+						PodAntiAffinity: &corev1.PodAntiAffinity{
+							RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+								{
+									LabelSelector: &metav1.LabelSelector{
+										MatchLabels: map[string]string{"k8s-app": metal3AppName},
+									},
+									TopologyKey: hostnameTopologyKey,
+								},
+							},
+						},
+					},
+				},
+			},
+			Strategy: appsv1.DeploymentStrategy{
+				Type: appsv1.RecreateDeploymentStrategyType,
+			},
+		},
+	}
+	applyOperandLogFormat(&deployment.Spec.Template, config)
+	applyPodSecurityMode(&deployment.Spec.Template, config)
+	SetOperandMetadata(deployment, config)
+	return deployment
+}
+
+// NewMetal3DatabaseService returns the Service exposing the database of
+// the metal3 pod to the conductors of the groups
+func NewMetal3DatabaseService(targetNamespace string, config *metal3iov1alpha1.ProvisioningSpec) *corev1.Service {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      Metal3DatabaseServiceName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: metal3PodSelector().MatchLabels,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       databasePortName,
+					Port:       databasePort,
+					TargetPort: intstr.FromInt(databasePort),
+				},
+			},
+		},
+	}
+	SetOperandMetadata(service, config)
+	return service
+}
+
+// ApplyMetal3DatabaseService creates or updates the Service exposing the
+// database of the metal3 pod
+func ApplyMetal3DatabaseService(client coreclientv1.ServicesGetter, service *corev1.Service) error {
+	existing, err := client.Services(service.Namespace).Get(context.Background(), service.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
+		return apiError(err)
+	}
+	if err != nil {
+		return apiError(err)
+	}
+	if equality.Semantic.DeepDerivative(service.Spec, existing.Spec) &&
+		equality.Semantic.DeepDerivative(service.Labels, existing.Labels) &&
+		equality.Semantic.DeepDerivative(service.OwnerReferences, existing.OwnerReferences) {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	// ClusterIP is immutable, keep the allocated one
+	service.Spec.ClusterIP = existing.Spec.ClusterIP
+	updated.Spec = service.Spec
+	updated.Labels = service.Labels
+	updated.OwnerReferences = service.OwnerReferences
+	_, err = client.Services(service.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	return apiError(err)
+}
+
+// DeleteMetal3DatabaseService removes the Service exposing the database
+// of the metal3 pod, if it exists
+func DeleteMetal3DatabaseService(client coreclientv1.ServicesGetter, targetNamespace string) error {
+	err := client.Services(targetNamespace).Delete(context.Background(), Metal3DatabaseServiceName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return apiError(err)
+}
+
+// DeleteStaleConductorGroups removes the Deployments of the conductor
+// groups that are not configured anymore
+func DeleteStaleConductorGroups(client appsclientv1.DeploymentsGetter, targetNamespace string, groups []metal3iov1alpha1.ConductorGroup) error {
+	configured := map[string]bool{}
+	for _, group := range groups {
+		configured[ConductorGroupDeploymentName(group.Name)] = true
+	}
+	deployments, err := client.Deployments(targetNamespace).List(context.Background(), metav1.ListOptions{LabelSelector: ConductorGroupLabel})
+	if err != nil {
+		return apiError(err)
+	}
+	for _, deployment := range deployments.Items {
+		if configured[deployment.Name] {
+			continue
+		}
+		err := client.Deployments(targetNamespace).Delete(context.Background(), deployment.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return apiError(err)
+		}
+	}
+	return nil
+}
+
+// GetConductorEndpoints returns the JSON-RPC endpoints of the ready
+// conductor pods
+func GetConductorEndpoints(pods []corev1.Pod) []string {
+	endpoints := []string{}
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" || pod.DeletionTimestamp != nil || !isPodReady(&pod) {
+			continue
+		}
+		endpoints = append(endpoints, pod.Status.PodIP+":"+strconv.Itoa(ConductorJSONRPCPort))
+	}
+	return endpoints
+}
+
+// DiffNodeConductorGroups returns the conductor group each Ironic node
+// has to be moved to, keyed by node UUID. hostGroups holds the value of
+// the ConductorGroupLabel of the BareMetalHosts, keyed by the UUID of
+// their node. Hosts labelled with a group that is not configured stay
+// with the conductor of the metal3 pod, as no conductor would serve them
+// otherwise. Nodes no BareMetalHost records are left alone.
+func DiffNodeConductorGroups(nodes []IronicNode, hostGroups map[string]string, groups []metal3iov1alpha1.ConductorGroup) map[string]string {
+	configured := map[string]bool{}
+	for _, group := range groups {
+		configured[group.Name] = true
+	}
+	changes := map[string]string{}
+	for _, node := range nodes {
+		label, ok := hostGroups[node.UUID]
+		if !ok {
+			continue
+		}
+		desired := ""
+		if configured[label] {
+			desired = label
+		}
+		if node.ConductorGroup != desired {
+			changes[node.UUID] = desired
+		}
+	}
+	return changes
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func validateConductorGroups(config *metal3iov1alpha1.ProvisioningSpec) error {
+	names := map[string]bool{}
+	for _, group := range config.ConductorGroups {
+		if group.Name == "" {
+			return fmt.Errorf("ConductorGroups must have a name")
+		}
+		if names[group.Name] {
+			return fmt.Errorf("ConductorGroup %q is configured more than once", group.Name)
+		}
+		names[group.Name] = true
+		if group.Replicas != nil && *group.Replicas < 1 {
+			return fmt.Errorf("Replicas of ConductorGroup %q must be at least 1", group.Name)
+		}
+	}
+	// The conductors of the groups need the database of the metal3 pod
+	if len(config.ConductorGroups) > 0 && IsMinimalProfile(config) {
+		return fmt.Errorf("ConductorGroups cannot be used with Profile %s, which keeps the database in SQLite",
+			metal3iov1alpha1.ProvisioningProfileMinimal)
+	}
+	return nil
+}
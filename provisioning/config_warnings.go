@@ -0,0 +1,61 @@
+package provisioning
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// minDHCPRangeSize is the number of addresses below which the DHCP
+	// range is considered too small to provision a cluster
+	minDHCPRangeSize = 16
+	// maxIPv4NetworkPrefix is the shortest IPv4 provisioning network
+	// prefix not considered suspiciously large
+	maxIPv4NetworkPrefix = 16
+)
+
+// GetConfigWarnings returns the settings of a valid configuration that are
+// likely mistakes, or not suitable for production
+func GetConfigWarnings(config *metal3iov1alpha1.ProvisioningSpec) []string {
+	warnings := []string{}
+	for _, warning := range getConfigWarnings(config) {
+		warnings = append(warnings, warning.Message)
+	}
+	return warnings
+}
+
+func getConfigWarnings(config *metal3iov1alpha1.ProvisioningSpec) []Warning {
+	warnings := []Warning{}
+
+	if size, err := getDHCPRangeSize(config.ProvisioningDHCPRange); err == nil && size.Cmp(big.NewInt(minDHCPRangeSize)) < 0 {
+		warnings = append(warnings, Warning{
+			Field: "spec.provisioningDHCPRange",
+			Message: fmt.Sprintf("ProvisioningDHCPRange %q only holds %s addresses, hosts may fail to get a lease",
+				config.ProvisioningDHCPRange, size),
+		})
+	}
+
+	if imageURL, err := url.Parse(config.ProvisioningOSDownloadURL); err == nil && imageURL.Scheme == "http" {
+		warnings = append(warnings, Warning{
+			Field: "spec.provisioningOSDownloadURL",
+			Message: fmt.Sprintf("ProvisioningOSDownloadURL %q is not downloaded over TLS",
+				config.ProvisioningOSDownloadURL),
+		})
+	}
+
+	if _, network, err := net.ParseCIDR(config.ProvisioningNetworkCIDR); err == nil && network.IP.To4() != nil {
+		if ones, _ := network.Mask.Size(); ones < maxIPv4NetworkPrefix {
+			warnings = append(warnings, Warning{
+				Field: "spec.provisioningNetworkCIDR",
+				Message: fmt.Sprintf("ProvisioningNetworkCIDR %q is larger than a /%d",
+					config.ProvisioningNetworkCIDR, maxIPv4NetworkPrefix),
+			})
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,68 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestGetConfigWarnings(t *testing.T) {
+	tCases := []struct {
+		name             string
+		modify           func(*metal3iov1alpha1.ProvisioningSpec)
+		expectedWarnings int
+	}{
+		{
+			name: "NoWarnings",
+			modify: func(s *metal3iov1alpha1.ProvisioningSpec) {
+				s.ProvisioningOSDownloadURL = "https://mirror.example.com/rhcos.qcow2.gz?sha256=1234"
+			},
+		},
+		{
+			name:             "PlainHTTPImage",
+			modify:           func(*metal3iov1alpha1.ProvisioningSpec) {},
+			expectedWarnings: 1,
+		},
+		{
+			name: "SmallDHCPRange",
+			modify: func(s *metal3iov1alpha1.ProvisioningSpec) {
+				s.ProvisioningOSDownloadURL = "https://mirror.example.com/rhcos.qcow2.gz?sha256=1234"
+				s.ProvisioningDHCPRange = "172.30.20.11, 172.30.20.20"
+			},
+			expectedWarnings: 1,
+		},
+		{
+			name: "LargeNetwork",
+			modify: func(s *metal3iov1alpha1.ProvisioningSpec) {
+				s.ProvisioningOSDownloadURL = "https://mirror.example.com/rhcos.qcow2.gz?sha256=1234"
+				s.ProvisioningNetworkCIDR = "172.16.0.0/12"
+			},
+			expectedWarnings: 1,
+		},
+		{
+			name: "LargeIPv6Network",
+			modify: func(s *metal3iov1alpha1.ProvisioningSpec) {
+				s.ProvisioningOSDownloadURL = "https://mirror.example.com/rhcos.qcow2.gz?sha256=1234"
+				s.ProvisioningNetworkCIDR = "fd00::/48"
+				s.ProvisioningDHCPRange = ""
+			},
+		},
+		{
+			name: "AllWarnings",
+			modify: func(s *metal3iov1alpha1.ProvisioningSpec) {
+				s.ProvisioningDHCPRange = "172.30.20.11, 172.30.20.11"
+				s.ProvisioningNetworkCIDR = "172.16.0.0/8"
+			},
+			expectedWarnings: 3,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			tc.modify(config)
+			assert.Len(t, GetConfigWarnings(config), tc.expectedWarnings)
+		})
+	}
+}
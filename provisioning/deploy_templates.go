@@ -0,0 +1,171 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// deployTraitNodeStates are the provision states in which the traits of
+// an Ironic node are updated. The instance_info of a node is only read
+// when it is deployed, and Ironic clears it once the node is torn down,
+// so the requested traits are set again each time it is available.
+var deployTraitNodeStates = map[string]bool{
+	"manageable": true,
+	"available":  true,
+}
+
+// NodeTraitsChange is how the traits of an Ironic node have to change for
+// its next deployment to run the deploy templates its host requests
+type NodeTraitsChange struct {
+	// Add and Remove are the traits to add to and remove from the node
+	Add    []string
+	Remove []string
+	// InstanceTraits are the traits the next deployment has to request,
+	// when SetInstanceTraits is true
+	InstanceTraits    []string
+	SetInstanceTraits bool
+}
+
+// operatorDeployTemplatePrefixes name the deploy templates owned by the
+// operator, so that stale ones can be deleted without touching the
+// templates created by others
+var operatorDeployTemplatePrefixes = []string{raidTemplateTraitPrefix, biosProfileTraitPrefix}
+
+func isOperatorDeployTemplate(name string) bool {
+	for _, prefix := range operatorDeployTemplatePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffDeployTemplates compares the desired deploy templates with those
+// registered in Ironic. It returns the templates to create and to update,
+// and the names of the templates of the operator no longer desired.
+// Templates without an operator prefix are left alone.
+func DiffDeployTemplates(desired, existing []IronicDeployTemplate) (create, update []IronicDeployTemplate, remove []string) {
+	registered := map[string]IronicDeployTemplate{}
+	for _, template := range existing {
+		registered[template.Name] = template
+	}
+	wanted := map[string]bool{}
+	for _, template := range desired {
+		wanted[template.Name] = true
+		current, found := registered[template.Name]
+		switch {
+		case !found:
+			create = append(create, template)
+		case !sameDeploySteps(current.Steps, template.Steps):
+			update = append(update, template)
+		}
+	}
+	for _, template := range existing {
+		if !wanted[template.Name] && isOperatorDeployTemplate(template.Name) {
+			remove = append(remove, template.Name)
+		}
+	}
+	sort.Strings(remove)
+	return create, update, remove
+}
+
+// sameDeploySteps compares deploy steps the way they read once decoded
+// from JSON, as Ironic returns the numbers of the arguments as floats
+func sameDeploySteps(a, b []IronicDeployStep) bool {
+	decodedA, errA := decodeDeploySteps(a)
+	decodedB, errB := decodeDeploySteps(b)
+	return errA == nil && errB == nil && reflect.DeepEqual(decodedA, decodedB)
+}
+
+func decodeDeploySteps(steps []IronicDeployStep) (interface{}, error) {
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	err = json.Unmarshal(data, &decoded)
+	return decoded, err
+}
+
+// HostDeployTraits returns the traits of the deploy templates requested by
+// the labels of a BareMetalHost. Labels naming a layout or a profile that
+// is not configured are ignored, as no template would match their trait.
+func HostDeployTraits(labels map[string]string, config *metal3iov1alpha1.ProvisioningSpec) []string {
+	traits := []string{}
+	if name, ok := labels[RAIDTemplateLabel]; ok {
+		for _, template := range config.DefaultRAIDConfig {
+			if template.Name == name {
+				traits = append(traits, RAIDTemplateTrait(name))
+			}
+		}
+	}
+	if name, ok := labels[BIOSProfileLabel]; ok {
+		for _, profile := range config.BIOSProfiles {
+			if profile.Name == name {
+				traits = append(traits, BIOSProfileTrait(name))
+			}
+		}
+	}
+	return traits
+}
+
+// DiffNodeTraits returns the changes to the traits of the Ironic nodes,
+// keyed by node UUID, so that each node carries and requests the traits
+// of the deploy templates of its host. hostTraits holds the traits
+// requested by the BareMetalHosts, keyed by the UUID of their node. Only
+// the nodes that are manageable or available are changed, and the
+// traits without an operator prefix are left alone. Nodes no
+// BareMetalHost records are skipped.
+func DiffNodeTraits(nodes []IronicNode, hostTraits map[string][]string) map[string]NodeTraitsChange {
+	changes := map[string]NodeTraitsChange{}
+	for _, node := range nodes {
+		desired, ok := hostTraits[node.UUID]
+		if !ok || !deployTraitNodeStates[node.ProvisionState] {
+			continue
+		}
+		wanted := map[string]bool{}
+		for _, trait := range desired {
+			wanted[trait] = true
+		}
+
+		change := NodeTraitsChange{}
+		current := map[string]bool{}
+		for _, trait := range node.Traits {
+			current[trait] = true
+			if !wanted[trait] && isOperatorDeployTemplate(trait) {
+				change.Remove = append(change.Remove, trait)
+			}
+		}
+		for _, trait := range desired {
+			if !current[trait] {
+				change.Add = append(change.Add, trait)
+			}
+		}
+
+		instanceTraits := []string{}
+		for _, trait := range node.InstanceInfo.Traits {
+			if !isOperatorDeployTemplate(trait) {
+				instanceTraits = append(instanceTraits, trait)
+			}
+		}
+		instanceTraits = append(instanceTraits, desired...)
+		sort.Strings(instanceTraits)
+		requested := append([]string{}, node.InstanceInfo.Traits...)
+		sort.Strings(requested)
+		if !reflect.DeepEqual(instanceTraits, requested) {
+			change.InstanceTraits = instanceTraits
+			change.SetInstanceTraits = true
+		}
+
+		sort.Strings(change.Add)
+		sort.Strings(change.Remove)
+		if len(change.Add) > 0 || len(change.Remove) > 0 || change.SetInstanceTraits {
+			changes[node.UUID] = change
+		}
+	}
+	return changes
+}
@@ -0,0 +1,104 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestDiffDeployTemplates(t *testing.T) {
+	size := int32(100)
+	spec := managedProvisioning()
+	spec.DefaultRAIDConfig = []metal3iov1alpha1.RAIDTemplate{
+		{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "1", SizeGB: &size}}},
+		{Name: "STRIPE", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "0"}}},
+		{Name: "PARITY", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "5"}}},
+	}
+	desired := NewRAIDDeployTemplates(spec)
+
+	// The templates read back from Ironic, as decoded from JSON
+	existing := []IronicDeployTemplate{}
+	data, _ := json.Marshal(desired[:2])
+	assert.NoError(t, json.Unmarshal(data, &existing))
+	existing[1].Steps[0].Args["delete_existing"] = false
+	existing = append(existing,
+		IronicDeployTemplate{Name: "CUSTOM_OCP_RAID_LEGACY"},
+		IronicDeployTemplate{Name: "CUSTOM_OCP_BIOS_LEGACY"},
+		IronicDeployTemplate{Name: "CUSTOM_HYPERTHREADING_OFF"},
+	)
+
+	create, update, remove := DiffDeployTemplates(desired, existing)
+	if assert.Len(t, create, 1) {
+		assert.Equal(t, "CUSTOM_OCP_RAID_STRIPE", create[0].Name)
+	}
+	if assert.Len(t, update, 1) {
+		assert.Equal(t, "CUSTOM_OCP_RAID_PARITY", update[0].Name)
+	}
+	assert.Equal(t, []string{"CUSTOM_OCP_BIOS_LEGACY", "CUSTOM_OCP_RAID_LEGACY"}, remove)
+}
+
+func TestHostDeployTraits(t *testing.T) {
+	spec := managedProvisioning()
+	spec.DefaultRAIDConfig = []metal3iov1alpha1.RAIDTemplate{{Name: "MIRROR"}}
+	spec.BIOSProfiles = []metal3iov1alpha1.BIOSProfile{{Name: "STORAGE", ConfigMapName: "bios-storage"}}
+
+	assert.Equal(t, []string{"CUSTOM_OCP_RAID_MIRROR"}, HostDeployTraits(map[string]string{RAIDTemplateLabel: "MIRROR"}, spec))
+	assert.Equal(t, []string{"CUSTOM_OCP_RAID_MIRROR", "CUSTOM_OCP_BIOS_STORAGE"},
+		HostDeployTraits(map[string]string{RAIDTemplateLabel: "MIRROR", BIOSProfileLabel: "STORAGE"}, spec))
+	assert.Empty(t, HostDeployTraits(map[string]string{RAIDTemplateLabel: "STRIPE", BIOSProfileLabel: "COMPUTE"}, spec))
+	assert.Empty(t, HostDeployTraits(nil, spec))
+}
+
+func TestDiffNodeTraits(t *testing.T) {
+	nodes := []IronicNode{
+		// Requests a template for the first time
+		{UUID: "uuid-1", ProvisionState: "available", Traits: []string{"CUSTOM_GPU"}},
+		// Moves to another template
+		{
+			UUID: "uuid-2", ProvisionState: "manageable",
+			Traits:       []string{"CUSTOM_OCP_RAID_STRIPE"},
+			InstanceInfo: IronicInstanceInfo{Traits: []string{"CUSTOM_GPU", "CUSTOM_OCP_RAID_STRIPE"}},
+		},
+		// Already up to date
+		{
+			UUID: "uuid-3", ProvisionState: "available",
+			Traits:       []string{"CUSTOM_OCP_RAID_MIRROR"},
+			InstanceInfo: IronicInstanceInfo{Traits: []string{"CUSTOM_OCP_RAID_MIRROR"}},
+		},
+		// Being deployed
+		{UUID: "uuid-4", ProvisionState: "deploying"},
+		// No longer requests a template
+		{
+			UUID: "uuid-5", ProvisionState: "available",
+			Traits:       []string{"CUSTOM_OCP_RAID_MIRROR", "CUSTOM_GPU"},
+			InstanceInfo: IronicInstanceInfo{Traits: []string{"CUSTOM_OCP_RAID_MIRROR"}},
+		},
+		// Unknown to the BareMetalHosts
+		{UUID: "uuid-6", ProvisionState: "available", Traits: []string{"CUSTOM_OCP_RAID_MIRROR"}},
+	}
+	hostTraits := map[string][]string{
+		"uuid-1": {"CUSTOM_OCP_RAID_MIRROR"},
+		"uuid-2": {"CUSTOM_OCP_RAID_MIRROR"},
+		"uuid-3": {"CUSTOM_OCP_RAID_MIRROR"},
+		"uuid-4": {"CUSTOM_OCP_RAID_MIRROR"},
+		"uuid-5": {},
+	}
+
+	assert.Equal(t, map[string]NodeTraitsChange{
+		"uuid-1": {
+			Add:            []string{"CUSTOM_OCP_RAID_MIRROR"},
+			InstanceTraits: []string{"CUSTOM_OCP_RAID_MIRROR"}, SetInstanceTraits: true,
+		},
+		"uuid-2": {
+			Add: []string{"CUSTOM_OCP_RAID_MIRROR"}, Remove: []string{"CUSTOM_OCP_RAID_STRIPE"},
+			InstanceTraits: []string{"CUSTOM_GPU", "CUSTOM_OCP_RAID_MIRROR"}, SetInstanceTraits: true,
+		},
+		"uuid-5": {
+			Remove:         []string{"CUSTOM_OCP_RAID_MIRROR"},
+			InstanceTraits: []string{}, SetInstanceTraits: true,
+		},
+	}, DiffNodeTraits(nodes, hostTraits))
+}
@@ -0,0 +1,167 @@
+package provisioning
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	dnsmasqLeasesVolume    = "metal3-dnsmasq-leases"
+	dnsmasqLeasesMountPath = "/var/lib/dnsmasq"
+	dnsmasqLeasesHostPath  = "/var/lib/metal3/dnsmasq"
+	dhcpMetricsPort        = 60002
+	dhcpRangeCapacityEnv   = "DHCP_RANGE_CAPACITY"
+)
+
+var dnsmasqLeasesMount = corev1.VolumeMount{
+	Name:      dnsmasqLeasesVolume,
+	MountPath: dnsmasqLeasesMountPath,
+}
+
+// newDnsmasqLeasesVolume returns the volume holding the dnsmasq lease
+// database. It outlives the dnsmasq pod, so that dnsmasq reloads the
+// existing leases after a restart or rollout and does not hand out
+// addresses already in use by hosts that are in the middle of PXE booting.
+func newDnsmasqLeasesVolume(config *metal3iov1alpha1.ProvisioningSpec) corev1.Volume {
+	if config.DHCPLeasesVolumeClaim != "" {
+		return corev1.Volume{
+			Name: dnsmasqLeasesVolume,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: config.DHCPLeasesVolumeClaim,
+				},
+			},
+		}
+	}
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	return corev1.Volume{
+		Name: dnsmasqLeasesVolume,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: dnsmasqLeasesHostPath,
+				Type: &hostPathType,
+			},
+		},
+	}
+}
+
+// dhcpLeaseExporterScript serves the number of dnsmasq leases and the
+// capacity of the DHCP range in the Prometheus text format
+const dhcpLeaseExporterScript = `
+import http.server
+import os
+
+LEASES = "/var/lib/dnsmasq/dnsmasq.leases"
+CAPACITY = os.environ.get("DHCP_RANGE_CAPACITY", "0")
+PORT = int(os.environ.get("METRICS_PORT", "60002"))
+
+
+def count_leases():
+    try:
+        with open(LEASES) as f:
+            # DHCPv6 lease files start with a "duid" line
+            return sum(1 for line in f if line.strip() and not line.startswith("duid"))
+    except FileNotFoundError:
+        return 0
+
+
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        if self.path != "/metrics":
+            self.send_error(404)
+            return
+        body = (
+            "# HELP metal3_dhcp_leases Number of active leases of the provisioning DHCP server.\n"
+            "# TYPE metal3_dhcp_leases gauge\n"
+            "metal3_dhcp_leases %d\n"
+            "# HELP metal3_dhcp_range_capacity Number of addresses in the provisioning DHCP range.\n"
+            "# TYPE metal3_dhcp_range_capacity gauge\n"
+            "metal3_dhcp_range_capacity %s\n" % (count_leases(), CAPACITY)
+        ).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain; version=0.0.4")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+http.server.HTTPServer(("", PORT), Handler).serve_forever()
+`
+
+// getDHCPRangeSize returns the number of addresses in a DHCP range
+func getDHCPRangeSize(dhcpRange string) (*big.Int, error) {
+	start, end, err := parseDHCPRange(dhcpRange)
+	if err != nil {
+		return nil, err
+	}
+	if compareIPs(start, end) > 0 {
+		return nil, fmt.Errorf("ProvisioningDHCPRange %q starts after its end", dhcpRange)
+	}
+	size := new(big.Int).Sub(new(big.Int).SetBytes(end.To16()), new(big.Int).SetBytes(start.To16()))
+	return size.Add(size, big.NewInt(1)), nil
+}
+
+// getDHCPRangeCapacity returns the number of addresses served by dnsmasq,
+// including the relayed ranges
+func getDHCPRangeCapacity(config *metal3iov1alpha1.ProvisioningSpec) (*big.Int, error) {
+	capacity, err := getDHCPRangeSize(config.ProvisioningDHCPRange)
+	if err != nil {
+		return nil, err
+	}
+	for i, relay := range config.DHCPRelayRanges {
+		size, err := getDHCPRangeSize(relay.DHCPRange)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DHCPRelayRanges[%d].DHCPRange: %v", i, err)
+		}
+		capacity.Add(capacity, size)
+	}
+	return capacity, nil
+}
+
+func createContainerMetal3DhcpLeaseExporter(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	capacity := "0"
+	if c, err := getDHCPRangeCapacity(config); err == nil {
+		capacity = c.String()
+	}
+	return corev1.Container{
+		Name:            "metal3-dhcp-lease-exporter",
+		Image:           images.BaremetalIronic,
+		Command:         []string{"python3", "-c", dhcpLeaseExporterScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "dhcp-metrics",
+				ContainerPort: dhcpMetricsPort,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      dnsmasqLeasesVolume,
+				MountPath: dnsmasqLeasesMountPath,
+				ReadOnly:  true,
+			},
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name:  dhcpRangeCapacityEnv,
+				Value: capacity,
+			},
+			{
+				Name:  "METRICS_PORT",
+				Value: strconv.Itoa(dhcpMetricsPort),
+			},
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestGetDHCPRangeCapacity(t *testing.T) {
+	tCases := []struct {
+		name             string
+		dhcpRange        string
+		expectedCapacity string
+	}{
+		{name: "IPv4", dhcpRange: "172.30.20.11, 172.30.20.101", expectedCapacity: "91"},
+		{name: "IPv6", dhcpRange: "fd00:1101::a,fd00:1101::ffff", expectedCapacity: "65526"},
+		{name: "SingleAddress", dhcpRange: "172.30.20.11,172.30.20.11", expectedCapacity: "1"},
+		{name: "Reversed", dhcpRange: "172.30.20.101,172.30.20.11"},
+		{name: "Empty", dhcpRange: ""},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			capacity, err := getDHCPRangeCapacity(&metal3iov1alpha1.ProvisioningSpec{ProvisioningDHCPRange: tc.dhcpRange})
+			if tc.expectedCapacity == "" {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCapacity, capacity.String())
+		})
+	}
+}
+
+func TestDhcpLeaseExporterContainer(t *testing.T) {
+	container := createContainerMetal3DhcpLeaseExporter(&testImages, managedProvisioning())
+	assert.Equal(t, "91", envValue(&container, dhcpRangeCapacityEnv))
+	assert.Equal(t, int32(dhcpMetricsPort), container.Ports[0].ContainerPort)
+	assert.Equal(t, dnsmasqLeasesMountPath, container.VolumeMounts[0].MountPath)
+}
+
+func TestNewDnsmasqLeasesVolume(t *testing.T) {
+	config := managedProvisioning()
+	volume := newDnsmasqLeasesVolume(config)
+	assert.Equal(t, dnsmasqLeasesVolume, volume.Name)
+	if assert.NotNil(t, volume.HostPath) {
+		assert.Equal(t, dnsmasqLeasesHostPath, volume.HostPath.Path)
+	}
+
+	config.DHCPLeasesVolumeClaim = "metal3-leases"
+	volume = newDnsmasqLeasesVolume(config)
+	assert.Nil(t, volume.HostPath)
+	if assert.NotNil(t, volume.PersistentVolumeClaim) {
+		assert.Equal(t, "metal3-leases", volume.PersistentVolumeClaim.ClaimName)
+	}
+}
@@ -0,0 +1,67 @@
+package provisioning
+
+import (
+	"fmt"
+	"net"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// reservedAddress is an address of the provisioning network that dnsmasq
+// must never lease
+type reservedAddress struct {
+	name string
+	ip   net.IP
+}
+
+// getReservedAddresses returns the addresses of the provisioning network
+// that the ProvisioningDHCPRange must leave out. The ProvisioningIPPool is
+// checked along with the rest of the pool by validateProvisioningIPPool.
+func getReservedAddresses(config *metal3iov1alpha1.ProvisioningSpec, network *net.IPNet) []reservedAddress {
+	reserved := []reservedAddress{{name: "the network address", ip: network.IP}}
+	if network.IP.To4() != nil {
+		broadcast := make(net.IP, len(network.IP))
+		for i := range network.IP {
+			broadcast[i] = network.IP[i] | ^network.Mask[i]
+		}
+		reserved = append(reserved, reservedAddress{name: "the broadcast address", ip: broadcast})
+	}
+	if ip := net.ParseIP(config.ProvisioningIP); ip != nil {
+		reserved = append(reserved, reservedAddress{name: "the ProvisioningIP", ip: ip})
+	}
+	for i, mapping := range config.DHCPCircuitIDMappings {
+		if ip := net.ParseIP(mapping.IPAddress); ip != nil {
+			reserved = append(reserved, reservedAddress{name: fmt.Sprintf("the DHCPCircuitIDMappings[%d].IPAddress", i), ip: ip})
+		}
+	}
+	return reserved
+}
+
+// validateDHCPRangeExclusions checks that the DHCP range served in
+// Managed mode, whether configured or computed, does not contain any of
+// the reserved addresses, naming the first one it contains
+func validateDHCPRangeExclusions(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	if mode != metal3iov1alpha1.ProvisioningNetworkManaged {
+		return nil
+	}
+	_, network, err := net.ParseCIDR(config.ProvisioningNetworkCIDR)
+	if err != nil {
+		return fmt.Errorf("could not parse ProvisioningNetworkCIDR %q", config.ProvisioningNetworkCIDR)
+	}
+	dhcpRange := config.ProvisioningDHCPRange
+	if dhcpRange == "" {
+		if dhcpRange, err = getDefaultDHCPRange(config); err != nil {
+			return err
+		}
+	}
+	start, end, err := parseDHCPRange(dhcpRange)
+	if err != nil {
+		return err
+	}
+	for _, address := range getReservedAddresses(config, network) {
+		if compareIPs(address.ip, start) >= 0 && compareIPs(address.ip, end) <= 0 {
+			return fmt.Errorf("ProvisioningDHCPRange %s,%s contains %s %s", start, end, address.name, address.ip)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateDHCPRangeExclusions(t *testing.T) {
+	tests := []struct {
+		name          string
+		cidr          string
+		ip            string
+		dhcpRange     string
+		mappings      []metal3iov1alpha1.DHCPCircuitIDMapping
+		mode          metal3iov1alpha1.ProvisioningNetwork
+		expectedError string
+	}{
+		{
+			name:      "Valid",
+			dhcpRange: "172.30.20.11,172.30.20.101",
+			mappings:  []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "rack1", IPAddress: "172.30.20.200"}},
+		},
+		{
+			name:     "Default",
+			mappings: []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "rack1", BootFile: "custom.ipxe"}},
+		},
+		{
+			name:          "NetworkAddress",
+			dhcpRange:     "172.30.20.0,172.30.20.2",
+			expectedError: "ProvisioningDHCPRange 172.30.20.0,172.30.20.2 contains the network address 172.30.20.0",
+		},
+		{
+			name:          "BroadcastAddress",
+			dhcpRange:     "172.30.20.11,172.30.20.255",
+			expectedError: "ProvisioningDHCPRange 172.30.20.11,172.30.20.255 contains the broadcast address 172.30.20.255",
+		},
+		{
+			name:          "ProvisioningIP",
+			dhcpRange:     "172.30.20.2,172.30.20.101",
+			expectedError: "ProvisioningDHCPRange 172.30.20.2,172.30.20.101 contains the ProvisioningIP 172.30.20.3",
+		},
+		{
+			name:      "CircuitIDMapping",
+			dhcpRange: "172.30.20.11,172.30.20.101",
+			mappings: []metal3iov1alpha1.DHCPCircuitIDMapping{
+				{CircuitID: "rack1", IPAddress: "172.30.20.200"},
+				{CircuitID: "rack2", IPAddress: "172.30.20.50"},
+			},
+			expectedError: "ProvisioningDHCPRange 172.30.20.11,172.30.20.101 contains the DHCPCircuitIDMappings[1].IPAddress 172.30.20.50",
+		},
+		{
+			name:          "DefaultCircuitIDMapping",
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "rack1", IPAddress: "172.30.20.50"}},
+			expectedError: "ProvisioningDHCPRange 172.30.20.10,172.30.20.254 contains the DHCPCircuitIDMappings[0].IPAddress 172.30.20.50",
+		},
+		{
+			// IPv6 has no broadcast address
+			name:      "IPv6",
+			cidr:      "fd00:1101::/64",
+			ip:        "fd00:1101::3",
+			dhcpRange: "fd00:1101::a,fd00:1101::ffff:ffff:ffff:ffff",
+		},
+		{
+			name:          "IPv6NetworkAddress",
+			cidr:          "fd00:1101::/64",
+			ip:            "fd00:1101::3",
+			dhcpRange:     "fd00:1101::,fd00:1101::2",
+			expectedError: "ProvisioningDHCPRange fd00:1101::,fd00:1101::2 contains the network address fd00:1101::",
+		},
+		{
+			name:      "Unmanaged",
+			dhcpRange: "172.30.20.0,172.30.20.255",
+			mode:      metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			if tc.cidr != "" {
+				spec.ProvisioningNetworkCIDR = tc.cidr
+				spec.ProvisioningIP = tc.ip
+			}
+			spec.ProvisioningDHCPRange = tc.dhcpRange
+			spec.DHCPCircuitIDMappings = tc.mappings
+			mode := tc.mode
+			if mode == "" {
+				mode = metal3iov1alpha1.ProvisioningNetworkManaged
+			}
+			err := validateDHCPRangeExclusions(spec, mode)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package provisioning
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	dhcpRelayConfigEnvVar = "DHCP_RELAY_CONFIG"
+	// dnsmasqConfigDir is included by dnsmasq in addition to its main
+	// configuration file
+	dnsmasqConfigDir = "/etc/dnsmasq.d"
+)
+
+// validateDHCPRelayRanges checks that every relayed range belongs to a
+// routed subnet, distinct from the provisioning network and from the
+// other relayed subnets
+func validateDHCPRelayRanges(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	if len(config.DHCPRelayRanges) == 0 {
+		return nil
+	}
+	if mode != metal3iov1alpha1.ProvisioningNetworkManaged {
+		return fmt.Errorf("DHCPRelayRanges are only supported when the ProvisioningNetwork is Managed")
+	}
+
+	networks := []string{config.ProvisioningNetworkCIDR}
+	for i, relay := range config.DHCPRelayRanges {
+		_, network, err := net.ParseCIDR(relay.NetworkCIDR)
+		if err != nil {
+			return fmt.Errorf("could not parse DHCPRelayRanges[%d].NetworkCIDR %q", i, relay.NetworkCIDR)
+		}
+		for _, other := range networks {
+			if cidrsOverlap(relay.NetworkCIDR, other) {
+				return fmt.Errorf("DHCPRelayRanges[%d].NetworkCIDR %q overlaps with %q", i, relay.NetworkCIDR, other)
+			}
+		}
+		networks = append(networks, relay.NetworkCIDR)
+
+		start, end, err := parseDHCPRange(relay.DHCPRange)
+		if err != nil {
+			return fmt.Errorf("invalid DHCPRelayRanges[%d].DHCPRange: %v", i, err)
+		}
+		if !network.Contains(start) || !network.Contains(end) || compareIPs(start, end) > 0 {
+			return fmt.Errorf("DHCPRelayRanges[%d].DHCPRange %q is not a range of %q", i, relay.DHCPRange, relay.NetworkCIDR)
+		}
+
+		if network.IP.To4() == nil {
+			if relay.Router != "" {
+				return fmt.Errorf("DHCPRelayRanges[%d].Router is not supported for IPv6 subnets", i)
+			}
+			continue
+		}
+		router := net.ParseIP(relay.Router)
+		if router == nil {
+			return fmt.Errorf("could not parse DHCPRelayRanges[%d].Router %q", i, relay.Router)
+		}
+		if !network.Contains(router) {
+			return fmt.Errorf("DHCPRelayRanges[%d].Router %q is not in %q", i, relay.Router, relay.NetworkCIDR)
+		}
+		if compareIPs(start, router) <= 0 && compareIPs(router, end) <= 0 {
+			return fmt.Errorf("DHCPRelayRanges[%d].Router %q is within its DHCPRange", i, relay.Router)
+		}
+	}
+	return nil
+}
+
+// validateDHCPCircuitIDMappings checks that the circuit ID mappings are
+// distinct, and that their addresses belong to a served network
+func validateDHCPCircuitIDMappings(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	if len(config.DHCPCircuitIDMappings) == 0 {
+		return nil
+	}
+	if mode != metal3iov1alpha1.ProvisioningNetworkManaged {
+		return fmt.Errorf("DHCPCircuitIDMappings are only supported when the ProvisioningNetwork is Managed")
+	}
+
+	networks := []string{config.ProvisioningNetworkCIDR}
+	for _, relay := range config.DHCPRelayRanges {
+		networks = append(networks, relay.NetworkCIDR)
+	}
+	circuitIDs := map[string]bool{}
+	addresses := map[string]bool{}
+	for i, mapping := range config.DHCPCircuitIDMappings {
+		if mapping.CircuitID == "" || strings.ContainsAny(mapping.CircuitID, ", \t\"\n") {
+			return fmt.Errorf("DHCPCircuitIDMappings[%d].CircuitID %q must not be empty nor contain commas, quotes or spaces", i, mapping.CircuitID)
+		}
+		if circuitIDs[mapping.CircuitID] {
+			return fmt.Errorf("DHCPCircuitIDMappings[%d].CircuitID %q is mapped more than once", i, mapping.CircuitID)
+		}
+		circuitIDs[mapping.CircuitID] = true
+		if mapping.IPAddress == "" && mapping.BootFile == "" {
+			return fmt.Errorf("DHCPCircuitIDMappings[%d] must set an IPAddress or a BootFile", i)
+		}
+		if strings.ContainsAny(mapping.BootFile, ", \t\"\n") {
+			return fmt.Errorf("DHCPCircuitIDMappings[%d].BootFile %q must not contain commas, quotes or spaces", i, mapping.BootFile)
+		}
+		if mapping.IPAddress == "" {
+			continue
+		}
+		ip := net.ParseIP(mapping.IPAddress)
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("DHCPCircuitIDMappings[%d].IPAddress %q is not an IPv4 address", i, mapping.IPAddress)
+		}
+		if addresses[ip.String()] {
+			return fmt.Errorf("DHCPCircuitIDMappings[%d].IPAddress %q is mapped more than once", i, mapping.IPAddress)
+		}
+		addresses[ip.String()] = true
+		served := false
+		for _, cidr := range networks {
+			if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+				served = true
+				break
+			}
+		}
+		if !served {
+			return fmt.Errorf("DHCPCircuitIDMappings[%d].IPAddress %q is not in the provisioning network nor in a DHCPRelayRanges network", i, mapping.IPAddress)
+		}
+		for j, relay := range config.DHCPRelayRanges {
+			if start, end, err := parseDHCPRange(relay.DHCPRange); err == nil && compareIPs(ip, start) >= 0 && compareIPs(ip, end) <= 0 {
+				return fmt.Errorf("DHCPCircuitIDMappings[%d].IPAddress %q is in DHCPRelayRanges[%d].DHCPRange", i, mapping.IPAddress, j)
+			}
+		}
+	}
+	return nil
+}
+
+// getCircuitIDNetmask returns the netmask of the served network holding
+// the address of a circuit ID mapping
+func getCircuitIDNetmask(config *metal3iov1alpha1.ProvisioningSpec, ip net.IP) (net.IP, bool) {
+	networks := []string{config.ProvisioningNetworkCIDR}
+	for _, relay := range config.DHCPRelayRanges {
+		networks = append(networks, relay.NetworkCIDR)
+	}
+	for _, cidr := range networks {
+		if _, network, err := net.ParseCIDR(cidr); err == nil && network.Contains(ip) {
+			return net.IP(network.Mask), true
+		}
+	}
+	return nil, false
+}
+
+// getDHCPRelayConfig returns the dnsmasq configuration serving the
+// relayed ranges and the circuit ID mappings. dnsmasq picks the range
+// matching the gateway address set by the relay, and the router option is
+// scoped to it with a tag; the hosts matching a circuit ID are tagged too,
+// so that their address and boot file only apply to them. The address of
+// a circuit ID is served as a range of its own restricted to its tag, as
+// dnsmasq only matches dhcp-host entries on a MAC address, client ID or
+// name. Invalid ranges are skipped, they are rejected by the validation.
+func getDHCPRelayConfig(config *metal3iov1alpha1.ProvisioningSpec) string {
+	lines := []string{}
+	for i, relay := range config.DHCPRelayRanges {
+		_, network, err := net.ParseCIDR(relay.NetworkCIDR)
+		if err != nil {
+			continue
+		}
+		start, end, err := parseDHCPRange(relay.DHCPRange)
+		if err != nil {
+			continue
+		}
+		tag := fmt.Sprintf("relay%d", i)
+		if network.IP.To4() == nil {
+			prefix, _ := network.Mask.Size()
+			lines = append(lines, fmt.Sprintf("dhcp-range=set:%s,%s,%s,%d", tag, start, end, prefix))
+			continue
+		}
+		// Non-local IPv4 ranges need their netmask, which cannot be
+		// deduced from an interface
+		lines = append(lines, fmt.Sprintf("dhcp-range=set:%s,%s,%s,%s", tag, start, end, net.IP(network.Mask)))
+		if relay.Router != "" {
+			lines = append(lines, fmt.Sprintf("dhcp-option=tag:%s,option:router,%s", tag, relay.Router))
+		}
+	}
+	for i, mapping := range config.DHCPCircuitIDMappings {
+		tag := fmt.Sprintf("circuit%d", i)
+		lines = append(lines, fmt.Sprintf("dhcp-circuitid=set:%s,%s", tag, mapping.CircuitID))
+		if ip := net.ParseIP(mapping.IPAddress); ip != nil {
+			if netmask, ok := getCircuitIDNetmask(config, ip); ok {
+				lines = append(lines, fmt.Sprintf("dhcp-range=tag:%s,%s,%s,%s", tag, ip, ip, netmask))
+			}
+		}
+		if mapping.BootFile != "" {
+			lines = append(lines, fmt.Sprintf("dhcp-boot=tag:%s,%s", tag, mapping.BootFile))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
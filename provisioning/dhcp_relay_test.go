@@ -0,0 +1,235 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateDHCPRelayRanges(t *testing.T) {
+	tCases := []struct {
+		name          string
+		mode          metal3iov1alpha1.ProvisioningNetwork
+		relays        []metal3iov1alpha1.DHCPRelayRange
+		expectedError string
+	}{
+		{
+			name: "Valid",
+			mode: metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays: []metal3iov1alpha1.DHCPRelayRange{
+				{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.10.100", Router: "192.168.10.1"},
+				{NetworkCIDR: "192.168.11.0/24", DHCPRange: "192.168.11.10, 192.168.11.100", Router: "192.168.11.254"},
+			},
+		},
+		{
+			name: "ValidIPv6",
+			mode: metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays: []metal3iov1alpha1.DHCPRelayRange{
+				{NetworkCIDR: "fd00:10::/64", DHCPRange: "fd00:10::10, fd00:10::ff"},
+			},
+		},
+		{
+			name:          "Unmanaged",
+			mode:          metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			relays:        []metal3iov1alpha1.DHCPRelayRange{{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.10.100", Router: "192.168.10.1"}},
+			expectedError: "only supported",
+		},
+		{
+			name:          "LocalNetwork",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays:        []metal3iov1alpha1.DHCPRelayRange{{NetworkCIDR: "172.30.20.128/25", DHCPRange: "172.30.20.130, 172.30.20.140", Router: "172.30.20.129"}},
+			expectedError: "overlaps",
+		},
+		{
+			name: "OverlappingRelays",
+			mode: metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays: []metal3iov1alpha1.DHCPRelayRange{
+				{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.10.100", Router: "192.168.10.1"},
+				{NetworkCIDR: "192.168.0.0/16", DHCPRange: "192.168.20.10, 192.168.20.100", Router: "192.168.0.1"},
+			},
+			expectedError: "overlaps",
+		},
+		{
+			name:          "RangeOutsideNetwork",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays:        []metal3iov1alpha1.DHCPRelayRange{{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.11.100", Router: "192.168.10.1"}},
+			expectedError: "is not a range of",
+		},
+		{
+			name:          "MissingRouter",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays:        []metal3iov1alpha1.DHCPRelayRange{{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.10.100"}},
+			expectedError: "could not parse",
+		},
+		{
+			name:          "RouterInRange",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays:        []metal3iov1alpha1.DHCPRelayRange{{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.1, 192.168.10.100", Router: "192.168.10.1"}},
+			expectedError: "within its DHCPRange",
+		},
+		{
+			name:          "IPv6Router",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			relays:        []metal3iov1alpha1.DHCPRelayRange{{NetworkCIDR: "fd00:10::/64", DHCPRange: "fd00:10::10, fd00:10::ff", Router: "fd00:10::1"}},
+			expectedError: "not supported for IPv6",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			config.DHCPRelayRanges = tc.relays
+			err := validateDHCPRelayRanges(config, tc.mode)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestDHCPRelayConfig(t *testing.T) {
+	config := managedProvisioning()
+	dnsmasq := createContainerMetal3Dnsmasq(&testImages, config)
+	assert.Equal(t, []string{"/bin/bash", "-c", dnsmasqServerScript + "exec /bin/rundnsmasq"}, dnsmasq.Command)
+	assert.Equal(t, "", envValue(&dnsmasq, dhcpRelayConfigEnvVar))
+
+	config.DHCPRelayRanges = []metal3iov1alpha1.DHCPRelayRange{
+		{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.10.100", Router: "192.168.10.1"},
+		{NetworkCIDR: "fd00:10::/64", DHCPRange: "fd00:10::10, fd00:10::ff"},
+	}
+	assert.Equal(t, "dhcp-range=set:relay0,192.168.10.10,192.168.10.100,255.255.255.0\n"+
+		"dhcp-option=tag:relay0,option:router,192.168.10.1\n"+
+		"dhcp-range=set:relay1,fd00:10::10,fd00:10::ff,64", getDHCPRelayConfig(config))
+
+	dnsmasq = createContainerMetal3Dnsmasq(&testImages, config)
+	assert.Equal(t, getDHCPRelayConfig(config), envValue(&dnsmasq, dhcpRelayConfigEnvVar))
+	assert.Contains(t, dnsmasq.Command[2], "/etc/dnsmasq.d/relay.conf")
+
+	// 91 addresses in the local range, 91 and 240 in the relayed ones
+	capacity, err := getDHCPRangeCapacity(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "422", capacity.String())
+}
+
+func TestValidateDHCPCircuitIDMappings(t *testing.T) {
+	relay := metal3iov1alpha1.DHCPRelayRange{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.10.100", Router: "192.168.10.1"}
+	tCases := []struct {
+		name          string
+		mode          metal3iov1alpha1.ProvisioningNetwork
+		mappings      []metal3iov1alpha1.DHCPCircuitIDMapping
+		expectedError string
+	}{
+		{
+			name: "Valid",
+			mode: metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings: []metal3iov1alpha1.DHCPCircuitIDMapping{
+				{CircuitID: "Ethernet1/1", IPAddress: "172.30.20.50"},
+				{CircuitID: "00:01:00:04", IPAddress: "192.168.10.200", BootFile: "http://172.30.20.3/custom.ipxe"},
+				{CircuitID: "Ethernet1/3", BootFile: "snponly.efi"},
+			},
+		},
+		{
+			name:          "Unmanaged",
+			mode:          metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "Ethernet1/1", IPAddress: "172.30.20.50"}},
+			expectedError: "only supported",
+		},
+		{
+			name:          "InvalidCircuitID",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "port 1,2", IPAddress: "172.30.20.50"}},
+			expectedError: "must not be empty",
+		},
+		{
+			name: "DuplicateCircuitID",
+			mode: metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings: []metal3iov1alpha1.DHCPCircuitIDMapping{
+				{CircuitID: "Ethernet1/1", IPAddress: "172.30.20.50"},
+				{CircuitID: "Ethernet1/1", IPAddress: "172.30.20.51"},
+			},
+			expectedError: "CircuitID \"Ethernet1/1\" is mapped more than once",
+		},
+		{
+			name: "DuplicateAddress",
+			mode: metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings: []metal3iov1alpha1.DHCPCircuitIDMapping{
+				{CircuitID: "Ethernet1/1", IPAddress: "172.30.20.50"},
+				{CircuitID: "Ethernet1/2", IPAddress: "172.30.20.50"},
+			},
+			expectedError: "IPAddress \"172.30.20.50\" is mapped more than once",
+		},
+		{
+			name:          "NothingMapped",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "Ethernet1/1"}},
+			expectedError: "must set an IPAddress or a BootFile",
+		},
+		{
+			name:          "IPv6Address",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "Ethernet1/1", IPAddress: "fd00::50"}},
+			expectedError: "not an IPv4 address",
+		},
+		{
+			name:          "AddressNotServed",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "Ethernet1/1", IPAddress: "10.0.0.50"}},
+			expectedError: "is not in the provisioning network",
+		},
+		{
+			name:          "AddressInRelayRange",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "Ethernet1/1", IPAddress: "192.168.10.50"}},
+			expectedError: "is in DHCPRelayRanges[0].DHCPRange",
+		},
+		{
+			name:          "InvalidBootFile",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			mappings:      []metal3iov1alpha1.DHCPCircuitIDMapping{{CircuitID: "Ethernet1/1", BootFile: "boot.ipxe,extra"}},
+			expectedError: "BootFile",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			config.DHCPRelayRanges = []metal3iov1alpha1.DHCPRelayRange{relay}
+			config.DHCPCircuitIDMappings = tc.mappings
+			err := validateDHCPCircuitIDMappings(config, tc.mode)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestDHCPCircuitIDConfig(t *testing.T) {
+	config := managedProvisioning()
+	config.DHCPCircuitIDMappings = []metal3iov1alpha1.DHCPCircuitIDMapping{
+		{CircuitID: "Ethernet1/1", IPAddress: "172.30.20.50"},
+		{CircuitID: "00:01:00:04", BootFile: "http://172.30.20.3/custom.ipxe"},
+	}
+	assert.Equal(t, "dhcp-circuitid=set:circuit0,Ethernet1/1\n"+
+		"dhcp-range=tag:circuit0,172.30.20.50,172.30.20.50,255.255.255.0\n"+
+		"dhcp-circuitid=set:circuit1,00:01:00:04\n"+
+		"dhcp-boot=tag:circuit1,http://172.30.20.3/custom.ipxe", getDHCPRelayConfig(config))
+
+	dnsmasq := createContainerMetal3Dnsmasq(&testImages, config)
+	assert.Equal(t, getDHCPRelayConfig(config), envValue(&dnsmasq, dhcpRelayConfigEnvVar))
+
+	// The address of a relayed host gets the netmask of its subnet
+	config.DHCPRelayRanges = []metal3iov1alpha1.DHCPRelayRange{
+		{NetworkCIDR: "192.168.10.0/25", DHCPRange: "192.168.10.10, 192.168.10.100", Router: "192.168.10.1"},
+	}
+	config.DHCPCircuitIDMappings = []metal3iov1alpha1.DHCPCircuitIDMapping{
+		{CircuitID: "Ethernet1/1", IPAddress: "192.168.10.120"},
+	}
+	assert.Equal(t, "dhcp-range=set:relay0,192.168.10.10,192.168.10.100,255.255.255.128\n"+
+		"dhcp-option=tag:relay0,option:router,192.168.10.1\n"+
+		"dhcp-circuitid=set:circuit0,Ethernet1/1\n"+
+		"dhcp-range=tag:circuit0,192.168.10.120,192.168.10.120,255.255.255.128", getDHCPRelayConfig(config))
+}
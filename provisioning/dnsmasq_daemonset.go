@@ -0,0 +1,245 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// DnsmasqAppName is the k8s-app label of the dnsmasq pods
+	DnsmasqAppName       = "metal3-dnsmasq"
+	dnsmasqDaemonSetName = "metal3-dnsmasq"
+)
+
+// dnsmasqServerScript only lets dnsmasq start on the node holding the
+// ProvisioningIP, where the metal3 pod runs, and stops it once the address
+// moved to another node. Each node keeps a lease database of its own, so
+// that several nodes serving the same DHCP range would lease an address to
+// several hosts. dnsmasq replaces the shell, which the monitor then
+// signals.
+const dnsmasqServerScript = `holds_provisioning_ip() {
+    ip -o address show dev "$PROVISIONING_INTERFACE" | grep -q " $PROVISIONING_IP "
+}
+until holds_provisioning_ip; do
+    sleep ${ACTIVE_CHECK_INTERVAL}
+done
+(
+    while holds_provisioning_ip; do
+        sleep ${ACTIVE_CHECK_INTERVAL}
+    done
+    echo "node $(hostname) no longer holds the provisioning IP, stopping"
+    kill -TERM $$
+) &
+`
+
+// IsDnsmasqRequired returns true when the DHCP and TFTP server must run,
+// which is only the case when the provisioning network is managed by metal3
+func IsDnsmasqRequired(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return getProvisioningNetworkMode(&metal3iov1alpha1.Provisioning{Spec: *config}) == metal3iov1alpha1.ProvisioningNetworkManaged
+}
+
+func createContainerMetal3Dnsmasq(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	container := corev1.Container{
+		Name:            "metal3-dnsmasq",
+		Image:           images.BaremetalIronic,
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			sharedVolumeMount,
+			dnsmasqLeasesMount,
+		},
+		Env: []corev1.EnvVar{
+			buildEnvVar(httpPort, config),
+			buildEnvVar(provisioningInterface, config),
+			buildEnvVar(provisioningIP, config),
+			buildEnvVar(dhcpRange, config),
+			{
+				Name:  "ACTIVE_CHECK_INTERVAL",
+				Value: activeMetal3CheckInterval,
+			},
+		},
+	}
+	// The additional configuration files are passed through the
+	// environment and written to the directory dnsmasq includes
+	commands := []string{}
+	for _, extra := range []struct {
+		envVar, file, content string
+	}{
+		{envVar: dhcpRelayConfigEnvVar, file: "relay.conf", content: getDHCPRelayConfig(config)},
+		{envVar: tftpConfigEnvVar, file: "tftp.conf", content: getTFTPConfig(config)},
+		{envVar: secureBootConfigEnvVar, file: "secureboot.conf", content: getSecureBootConfig(config)},
+		{envVar: grubBootConfigEnvVar, file: "grubboot.conf", content: getGRUBBootConfig(config)},
+		{envVar: dnsmasqLoggingConfigEnvVar, file: "logging.conf", content: getDnsmasqLoggingConfig(config)},
+	} {
+		if extra.content == "" {
+			continue
+		}
+		commands = append(commands, fmt.Sprintf(`echo "${%s}" > %s/%s`, extra.envVar, dnsmasqConfigDir, extra.file))
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  extra.envVar,
+			Value: extra.content,
+		})
+	}
+	start := "exec /bin/rundnsmasq"
+	if len(commands) > 0 {
+		start = fmt.Sprintf("mkdir -p %s && %s && %s", dnsmasqConfigDir, strings.Join(commands, " && "), start)
+	}
+	container.Command = []string{"/bin/bash", "-c", dnsmasqServerScript + start}
+	if volume := newTFTPFilesVolume(config); volume != nil {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: tftpFilesMountPath,
+			ReadOnly:  true,
+		})
+	}
+	return container
+}
+
+func newDnsmasqPodTemplateSpec(images *Images, config *metal3iov1alpha1.ProvisioningSpec) *corev1.PodTemplateSpec {
+	containers := []corev1.Container{
+		createContainerMetal3Dnsmasq(images, config),
+		createContainerMetal3DhcpLeaseExporter(images, config),
+	}
+	if config.OrphanCleanup != nil {
+		containers = append(containers, createContainerMetal3DhcpLeaseRelease(images, config))
+	}
+	setTerminationMessagePolicy(containers)
+
+	template := &corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"k8s-app":    DnsmasqAppName,
+				"controller": metal3AppName,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{
+					// Holds the TFTP boot files, which dnsmasq populates itself
+					Name: baremetalSharedVolume,
+					VolumeSource: corev1.VolumeSource{
+						EmptyDir: &corev1.EmptyDirVolumeSource{},
+					},
+				},
+				newDnsmasqLeasesVolume(config),
+			},
+			Containers:        containers,
+			HostNetwork:       true,
+			DNSPolicy:         corev1.DNSClusterFirstWithHostNet,
+			PriorityClassName: metal3PriorityClassName,
+			NodeSelector:      GetMetal3NodeSelector(config),
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: pointer.BoolPtr(false),
+			},
+			ServiceAccountName: serviceAccountName,
+			Tolerations:        newMetal3Tolerations(config),
+		},
+	}
+	if volume := newTFTPFilesVolume(config); volume != nil {
+		template.Spec.Volumes = append(template.Spec.Volumes, *volume)
+	}
+	if config.OrphanCleanup != nil {
+		template.Spec.Volumes = append(template.Spec.Volumes, newDHCPLeaseReleaseVolume())
+	}
+	applyOperandLogFormat(template, config)
+	applyPodSecurityMode(template, config)
+	return template
+}
+
+// NewDnsmasqDaemonSet returns the DaemonSet running the DHCP and TFTP
+// server on every node the metal3 pods may run on, of which only the one
+// on the node holding the ProvisioningIP serves. It is kept apart from
+// the metal3 Deployment so that dnsmasq is ready to serve as soon as the
+// address moves, and so that both can be scheduled independently.
+func NewDnsmasqDaemonSet(targetNamespace string, images *Images, config *metal3iov1alpha1.ProvisioningSpec) *appsv1.DaemonSet {
+	template := newDnsmasqPodTemplateSpec(images, config)
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dnsmasqDaemonSetName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": DnsmasqAppName,
+			},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: template.Labels,
+			},
+			Template: *template,
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+	SetOperandMetadata(daemonSet, config)
+	return daemonSet
+}
+
+// ApplyDnsmasqDaemonSet creates the dnsmasq DaemonSet, or updates it when
+// it was last updated from a different rendered object or changed since.
+// It returns true when the DaemonSet was created or updated.
+func ApplyDnsmasqDaemonSet(client appsclientv1.DaemonSetsGetter, daemonSet *appsv1.DaemonSet) (bool, error) {
+	daemonSet = daemonSet.DeepCopy()
+	if err := setSpecHash(daemonSet, daemonSet.Spec); err != nil {
+		return false, err
+	}
+	existing, err := client.DaemonSets(daemonSet.Namespace).Get(context.Background(), daemonSet.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		created, err := client.DaemonSets(daemonSet.Namespace).Create(context.Background(), daemonSet, metav1.CreateOptions{})
+		if err != nil {
+			return false, apiError(err)
+		}
+		return true, recordDaemonSetSpecGeneration(client, created)
+	}
+	if err != nil {
+		return false, apiError(err)
+	}
+
+	if isSpecApplied(existing, daemonSet) {
+		return false, nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Labels = daemonSet.Labels
+	for key, value := range daemonSet.Annotations {
+		metav1.SetMetaDataAnnotation(&updated.ObjectMeta, key, value)
+	}
+	updated.OwnerReferences = daemonSet.OwnerReferences
+	updated.Spec = daemonSet.Spec
+	updated, err = client.DaemonSets(daemonSet.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		return false, apiError(err)
+	}
+	return true, recordDaemonSetSpecGeneration(client, updated)
+}
+
+// recordDaemonSetSpecGeneration records the generation the DaemonSet got
+// once updated from the rendered object
+func recordDaemonSetSpecGeneration(client appsclientv1.DaemonSetsGetter, daemonSet *appsv1.DaemonSet) error {
+	if !setSpecGeneration(daemonSet) {
+		return nil
+	}
+	_, err := client.DaemonSets(daemonSet.Namespace).Update(context.Background(), daemonSet, metav1.UpdateOptions{})
+	return apiError(err)
+}
+
+// DeleteDnsmasqDaemonSet removes the dnsmasq DaemonSet, if it exists
+func DeleteDnsmasqDaemonSet(client appsclientv1.DaemonSetsGetter, targetNamespace string) error {
+	err := client.DaemonSets(targetNamespace).Delete(context.Background(), dnsmasqDaemonSetName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestIsDnsmasqRequired(t *testing.T) {
+	config := managedProvisioning()
+	assert.True(t, IsDnsmasqRequired(config))
+
+	config.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkUnmanaged
+	assert.False(t, IsDnsmasqRequired(config))
+}
+
+func TestNewDnsmasqDaemonSet(t *testing.T) {
+	daemonSet := NewDnsmasqDaemonSet(testNamespace, &testImages, managedProvisioning())
+
+	assert.Equal(t, dnsmasqDaemonSetName, daemonSet.Name)
+	assert.Equal(t, daemonSet.Spec.Selector.MatchLabels, daemonSet.Spec.Template.Labels)
+	assert.Equal(t, DnsmasqAppName, daemonSet.Spec.Template.Labels["k8s-app"])
+	assert.True(t, daemonSet.Spec.Template.Spec.HostNetwork)
+	assert.Equal(t, []string{"metal3-dnsmasq", "metal3-dhcp-lease-exporter"},
+		containerNames(daemonSet.Spec.Template.Spec.Containers))
+
+	dnsmasq := findContainer(daemonSet.Spec.Template.Spec.Containers, "metal3-dnsmasq")
+	assert.Equal(t, "172.30.20.11, 172.30.20.101", envValue(dnsmasq, dhcpRange))
+	assert.Equal(t, "eth0", envValue(dnsmasq, provisioningInterface))
+	// Only the node holding the ProvisioningIP serves DHCP
+	assert.Equal(t, "172.30.20.3/24", envValue(dnsmasq, provisioningIP))
+	assert.Equal(t, []string{"/bin/bash", "-c", dnsmasqServerScript + "exec /bin/rundnsmasq"}, dnsmasq.Command)
+
+	// The DHCP selector must not match the metal3 pods, or they would be
+	// managed by both controllers
+	deployment := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+	assert.NotEqual(t, deployment.Spec.Selector.MatchLabels, daemonSet.Spec.Selector.MatchLabels)
+}
+
+func TestApplyDnsmasqDaemonSet(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+	config := managedProvisioning()
+
+	updated, err := ApplyDnsmasqDaemonSet(kubeClient.AppsV1(), NewDnsmasqDaemonSet(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.True(t, updated, "expected the daemonset to be created")
+
+	updated, err = ApplyDnsmasqDaemonSet(kubeClient.AppsV1(), NewDnsmasqDaemonSet(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.False(t, updated, "expected an unchanged daemonset not to be updated")
+
+	// Rolling out Ironic must not touch the DHCP server
+	config.ProvisioningOSDownloadURL = "http://172.22.0.1/images/rhcos-46.qcow2.gz?sha256=1234"
+	updated, err = ApplyDnsmasqDaemonSet(kubeClient.AppsV1(), NewDnsmasqDaemonSet(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.False(t, updated, "expected an OS image change not to update the daemonset")
+
+	config.ProvisioningDHCPRange = "172.30.20.11, 172.30.20.201"
+	updated, err = ApplyDnsmasqDaemonSet(kubeClient.AppsV1(), NewDnsmasqDaemonSet(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.True(t, updated, "expected a changed daemonset to be updated")
+
+	assert.NoError(t, DeleteDnsmasqDaemonSet(kubeClient.AppsV1(), testNamespace))
+	_, err = kubeClient.AppsV1().DaemonSets(testNamespace).Get(context.Background(), dnsmasqDaemonSetName, metav1.GetOptions{})
+	assert.Error(t, err)
+	assert.NoError(t, DeleteDnsmasqDaemonSet(kubeClient.AppsV1(), testNamespace))
+}
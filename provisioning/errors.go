@@ -0,0 +1,69 @@
+package provisioning
+
+import (
+	"errors"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var (
+	// ErrInvalidSpec is matched by errors caused by the Provisioning
+	// resource, which retrying cannot fix until the resource changes
+	ErrInvalidSpec = errors.New("invalid Provisioning spec")
+	// ErrTransientAPI is matched by API server errors that retrying may fix
+	ErrTransientAPI = errors.New("transient API error")
+	// ErrOperandDegraded is matched by errors reporting metal3 operands
+	// that do not work, which retrying cannot fix until they recover
+	ErrOperandDegraded = errors.New("operand degraded")
+)
+
+// classifiedError is an error matching one of the error classes above
+// with errors.Is, while keeping the message of the underlying error
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.class
+}
+
+func classify(class error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &classifiedError{class: class, err: err}
+}
+
+// NewInvalidSpecError returns err classified as ErrInvalidSpec
+func NewInvalidSpecError(err error) error {
+	return classify(ErrInvalidSpec, err)
+}
+
+// NewTransientAPIError returns err classified as ErrTransientAPI
+func NewTransientAPIError(err error) error {
+	return classify(ErrTransientAPI, err)
+}
+
+// NewOperandDegradedError returns err classified as ErrOperandDegraded
+func NewOperandDegradedError(err error) error {
+	return classify(ErrOperandDegraded, err)
+}
+
+// apiError classifies an error returned by the API server. Objects the API
+// server rejects are rendered from the Provisioning spec, so retrying
+// cannot fix them.
+func apiError(err error) error {
+	if apierrors.IsInvalid(err) {
+		return NewInvalidSpecError(err)
+	}
+	return NewTransientAPIError(err)
+}
@@ -0,0 +1,79 @@
+package provisioning
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestErrorClasses(t *testing.T) {
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name          string
+		err           error
+		expectedClass error
+	}{
+		{
+			name:          "InvalidSpec",
+			err:           NewInvalidSpecError(fmt.Errorf("ProvisioningIP is required")),
+			expectedClass: ErrInvalidSpec,
+		},
+		{
+			name:          "WrappedInvalidSpec",
+			err:           pkgerrors.Wrap(NewInvalidSpecError(fmt.Errorf("ProvisioningIP is required")), "failed"),
+			expectedClass: ErrInvalidSpec,
+		},
+		{
+			name:          "OperandDegraded",
+			err:           NewOperandDegradedError(fmt.Errorf("metal3 revision failed")),
+			expectedClass: ErrOperandDegraded,
+		},
+		{
+			name:          "APIServerUnavailable",
+			err:           apiError(apierrors.NewServiceUnavailable("unavailable")),
+			expectedClass: ErrTransientAPI,
+		},
+		{
+			name:          "APIConflict",
+			err:           apiError(apierrors.NewConflict(deployments, "metal3", fmt.Errorf("modified"))),
+			expectedClass: ErrTransientAPI,
+		},
+		{
+			name: "APIInvalid",
+			err: apiError(apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "metal3",
+				field.ErrorList{field.Invalid(field.NewPath("spec"), "", "invalid")})),
+			expectedClass: ErrInvalidSpec,
+		},
+		{
+			name: "ValidationError",
+			err: ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{
+				Spec: metal3iov1alpha1.ProvisioningSpec{ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkManaged},
+			}),
+			expectedClass: ErrInvalidSpec,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, class := range []error{ErrInvalidSpec, ErrTransientAPI, ErrOperandDegraded} {
+				assert.Equal(t, class == tc.expectedClass, errors.Is(tc.err, class), "%v", class)
+			}
+		})
+	}
+}
+
+func TestClassifiedErrorMessage(t *testing.T) {
+	cause := fmt.Errorf("ProvisioningIP is required")
+	err := NewInvalidSpecError(cause)
+	assert.Equal(t, cause.Error(), err.Error())
+	assert.True(t, errors.Is(err, cause))
+	assert.NoError(t, NewInvalidSpecError(nil))
+	assert.NoError(t, apiError(nil))
+}
@@ -0,0 +1,134 @@
+package provisioning
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// exampleProvisioningIPOffset is the offset in the provisioning network of
+// the default ProvisioningIP of an example manifest, the one used by the
+// installer
+const exampleProvisioningIPOffset = 3
+
+// ExampleManifestOptions are the settings an example Provisioning manifest
+// is rendered from
+type ExampleManifestOptions struct {
+	Mode                    metal3iov1alpha1.ProvisioningNetwork
+	ProvisioningInterface   string
+	ProvisioningNetworkCIDR string
+	// ProvisioningIP defaults to the third address of the
+	// ProvisioningNetworkCIDR when empty
+	ProvisioningIP            string
+	ProvisioningOSDownloadURL string
+}
+
+var exampleModeComments = map[metal3iov1alpha1.ProvisioningNetwork]string{
+	metal3iov1alpha1.ProvisioningNetworkManaged:   "The metal3 pod serves DHCP on the provisioning network, which is dedicated to it.",
+	metal3iov1alpha1.ProvisioningNetworkUnmanaged: "The provisioning network is shared, an external DHCP server answers the hosts.",
+	metal3iov1alpha1.ProvisioningNetworkDisabled:  "There is no provisioning network, the hosts boot from virtual media.",
+	metal3iov1alpha1.ProvisioningNetworkAuto:      "The provisioning interface is detected on the control plane nodes, and the network is then Managed.",
+}
+
+var exampleManifestTemplate = template.Must(template.New("provisioning").Funcs(template.FuncMap{
+	"quote": strconv.Quote,
+}).Parse(`# Provisioning configuration of the metal3 services, rendered by
+# cluster-baremetal-operator render-provisioning.
+{{- range .Warnings}}
+# WARNING: {{.Field}}: {{.Message}}
+{{- end}}
+apiVersion: {{.APIVersion}}
+kind: Provisioning
+metadata:
+  name: {{.Name}}
+spec:
+  # {{.ModeComment}}
+  provisioningNetwork: {{.Spec.ProvisioningNetwork}}
+{{- if .Spec.ProvisioningInterface}}
+  # The NIC of the control plane nodes on the provisioning network.
+  provisioningInterface: {{quote .Spec.ProvisioningInterface}}
+{{- end}}
+{{- if .Spec.ProvisioningNetworkCIDR}}
+  # The provisioning network.
+  provisioningNetworkCIDR: {{quote .Spec.ProvisioningNetworkCIDR}}
+{{- end}}
+{{- if .Spec.ProvisioningIP}}
+  # The address the metal3 pod serves Ironic on, which moves with the pod
+  # between the control plane nodes. It must not be used by any host.
+  provisioningIP: {{quote .Spec.ProvisioningIP}}
+{{- end}}
+{{- if .Spec.ProvisioningDHCPRange}}
+  # The addresses leased to the hosts, computed from the provisioning
+  # network without its first addresses and the provisioningIP.
+  provisioningDHCPRange: {{quote .Spec.ProvisioningDHCPRange}}
+{{- end}}
+  # The image written to the disk of the hosts, with its sha256 checksum.
+  provisioningOSDownloadURL: {{quote .Spec.ProvisioningOSDownloadURL}}
+`))
+
+// RenderExampleManifest returns a commented Provisioning manifest with the
+// given settings, the ProvisioningIP and the DHCP range being computed
+// when not set. The spec is validated as the webhook would, and its
+// warnings are added to the header of the manifest.
+func RenderExampleManifest(options ExampleManifestOptions) ([]byte, error) {
+	mode := options.Mode
+	if mode == "" {
+		mode = metal3iov1alpha1.ProvisioningNetworkManaged
+	}
+	modeComment, ok := exampleModeComments[mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown ProvisioningNetwork mode %q", mode)
+	}
+	spec := metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningNetwork:       mode,
+		ProvisioningInterface:     options.ProvisioningInterface,
+		ProvisioningNetworkCIDR:   options.ProvisioningNetworkCIDR,
+		ProvisioningIP:            options.ProvisioningIP,
+		ProvisioningOSDownloadURL: options.ProvisioningOSDownloadURL,
+	}
+	if spec.ProvisioningIP == "" && spec.ProvisioningNetworkCIDR != "" {
+		_, network, err := net.ParseCIDR(spec.ProvisioningNetworkCIDR)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse ProvisioningNetworkCIDR %q", spec.ProvisioningNetworkCIDR)
+		}
+		if ip := addToIP(network.IP, exampleProvisioningIPOffset); ip != nil && network.Contains(ip) {
+			spec.ProvisioningIP = ip.String()
+		}
+	}
+	if err := SetDefaultDHCPRange(&spec); err != nil {
+		return nil, err
+	}
+
+	errs, warnings := ValidateSpecDetailed(&spec)
+	if len(errs) > 0 {
+		messages := make([]string, 0, len(errs))
+		for _, err := range errs {
+			messages = append(messages, err.Error())
+		}
+		return nil, fmt.Errorf("invalid Provisioning: %s", strings.Join(messages, "; "))
+	}
+
+	var manifest bytes.Buffer
+	err := exampleManifestTemplate.Execute(&manifest, struct {
+		APIVersion  string
+		Name        string
+		ModeComment string
+		Spec        metal3iov1alpha1.ProvisioningSpec
+		Warnings    []Warning
+	}{
+		APIVersion:  metal3iov1alpha1.GroupVersion.String(),
+		Name:        metal3iov1alpha1.ProvisioningSingletonName,
+		ModeComment: modeComment,
+		Spec:        spec,
+		Warnings:    warnings,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Bytes(), nil
+}
@@ -0,0 +1,152 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const exampleImageURL = "https://mirror.example.com/rhcos.qcow2.gz?sha256=1234"
+
+func TestRenderExampleManifest(t *testing.T) {
+	tCases := []struct {
+		name             string
+		options          ExampleManifestOptions
+		expectedSpec     metal3iov1alpha1.ProvisioningSpec
+		expectedComments []string
+	}{
+		{
+			name: "Managed",
+			options: ExampleManifestOptions{
+				ProvisioningInterface:     "eth1",
+				ProvisioningNetworkCIDR:   "172.22.0.0/24",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedSpec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkManaged,
+				ProvisioningInterface:     "eth1",
+				ProvisioningNetworkCIDR:   "172.22.0.0/24",
+				ProvisioningIP:            "172.22.0.3",
+				ProvisioningDHCPRange:     "172.22.0.10,172.22.0.254",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedComments: []string{"# The metal3 pod serves DHCP", "# The addresses leased to the hosts"},
+		},
+		{
+			name: "ManagedProvisioningIPInRange",
+			options: ExampleManifestOptions{
+				Mode:                      metal3iov1alpha1.ProvisioningNetworkManaged,
+				ProvisioningInterface:     "eth1",
+				ProvisioningNetworkCIDR:   "fd00:1101::/64",
+				ProvisioningIP:            "fd00:1101::20",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedSpec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkManaged,
+				ProvisioningInterface:     "eth1",
+				ProvisioningNetworkCIDR:   "fd00:1101::/64",
+				ProvisioningIP:            "fd00:1101::20",
+				ProvisioningDHCPRange:     "fd00:1101::21,fd00:1101::ffff:ffff:ffff:fffe",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+		},
+		{
+			name: "Disabled",
+			options: ExampleManifestOptions{
+				Mode:                      metal3iov1alpha1.ProvisioningNetworkDisabled,
+				ProvisioningNetworkCIDR:   "192.168.111.0/24",
+				ProvisioningIP:            "192.168.111.5",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedSpec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkDisabled,
+				ProvisioningNetworkCIDR:   "192.168.111.0/24",
+				ProvisioningIP:            "192.168.111.5",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedComments: []string{"# There is no provisioning network"},
+		},
+		{
+			name: "AutoPlainHTTPImage",
+			options: ExampleManifestOptions{
+				Mode:                      metal3iov1alpha1.ProvisioningNetworkAuto,
+				ProvisioningOSDownloadURL: "http://mirror.example.com/rhcos.qcow2.gz?sha256=1234",
+			},
+			expectedSpec: metal3iov1alpha1.ProvisioningSpec{
+				ProvisioningNetwork:       metal3iov1alpha1.ProvisioningNetworkAuto,
+				ProvisioningOSDownloadURL: "http://mirror.example.com/rhcos.qcow2.gz?sha256=1234",
+			},
+			expectedComments: []string{"# WARNING: spec.provisioningOSDownloadURL: "},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manifest, err := RenderExampleManifest(tc.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			prov := &metal3iov1alpha1.Provisioning{}
+			if err := yaml.UnmarshalStrict(manifest, prov); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "Provisioning", prov.Kind)
+			assert.Equal(t, metal3iov1alpha1.GroupVersion.String(), prov.APIVersion)
+			assert.Equal(t, metal3iov1alpha1.ProvisioningSingletonName, prov.Name)
+			assert.Equal(t, tc.expectedSpec, prov.Spec)
+			for _, comment := range tc.expectedComments {
+				assert.Contains(t, string(manifest), comment)
+			}
+		})
+	}
+}
+
+func TestRenderExampleManifestInvalid(t *testing.T) {
+	tCases := []struct {
+		name          string
+		options       ExampleManifestOptions
+		expectedError string
+	}{
+		{
+			name:          "UnknownMode",
+			options:       ExampleManifestOptions{Mode: "Dedicated"},
+			expectedError: `unknown ProvisioningNetwork mode "Dedicated"`,
+		},
+		{
+			name: "MissingInterface",
+			options: ExampleManifestOptions{
+				ProvisioningNetworkCIDR:   "172.22.0.0/24",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedError: "ProvisioningInterface",
+		},
+		{
+			name: "InvalidCIDR",
+			options: ExampleManifestOptions{
+				ProvisioningInterface:     "eth1",
+				ProvisioningNetworkCIDR:   "172.22.0.0",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedError: `could not parse ProvisioningNetworkCIDR "172.22.0.0"`,
+		},
+		{
+			name: "NetworkTooSmall",
+			options: ExampleManifestOptions{
+				ProvisioningInterface:     "eth1",
+				ProvisioningNetworkCIDR:   "172.22.0.0/29",
+				ProvisioningOSDownloadURL: exampleImageURL,
+			},
+			expectedError: "too small",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := RenderExampleManifest(tc.options)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package provisioning
+
+import (
+	"fmt"
+	"strings"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// ClusterFeatureGateName is the name of the FeatureGate singleton
+const ClusterFeatureGateName = "cluster"
+
+// techPreviewFeatures are the experimental Provisioning settings, only
+// honoured on clusters enabling the TechPreviewNoUpgrade feature set. The
+// ProvisioningNetwork modes but Auto, including the virtual media only
+// Disabled mode, are supported and thus not gated.
+var techPreviewFeatures = []struct {
+	field string
+	used  func(*metal3iov1alpha1.ProvisioningSpec) bool
+}{
+	{
+		field: "ProvisioningNetwork Auto",
+		used: func(spec *metal3iov1alpha1.ProvisioningSpec) bool {
+			return spec.ProvisioningNetwork == metal3iov1alpha1.ProvisioningNetworkAuto
+		},
+	},
+	{
+		field: "HighAvailability",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return spec.HighAvailability != nil },
+	},
+	{
+		field: "ProvisioningIPPool",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return len(spec.ProvisioningIPPool) > 0 },
+	},
+	{
+		field: "ProvisioningVIP",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return spec.ProvisioningVIP != nil },
+	},
+	{
+		field: "ConductorGroups",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return len(spec.ConductorGroups) > 0 },
+	},
+	{
+		field: "EnableProvisioningDomains",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return spec.EnableProvisioningDomains },
+	},
+	{
+		field: "SecureBoot",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return spec.SecureBoot },
+	},
+	{
+		field: "EnableIgnitionOverrides",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return spec.EnableIgnitionOverrides },
+	},
+	{
+		field: "SmokeTest",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return spec.SmokeTest },
+	},
+	{
+		field: "IronicDBRecovery",
+		used:  func(spec *metal3iov1alpha1.ProvisioningSpec) bool { return spec.IronicDBRecovery },
+	},
+}
+
+// GetFeatureSet returns the feature set enabled by the cluster FeatureGate,
+// which is the default one when the FeatureGate does not exist
+func GetFeatureSet(featureGate *osconfigv1.FeatureGate) osconfigv1.FeatureSet {
+	if featureGate == nil {
+		return osconfigv1.Default
+	}
+	return featureGate.Spec.FeatureSet
+}
+
+// ValidateFeatureSet checks that the Provisioning resource only uses the
+// tech preview settings when the cluster enables them. The errors returned
+// match ErrInvalidSpec.
+func ValidateFeatureSet(prov *metal3iov1alpha1.Provisioning, featureSet osconfigv1.FeatureSet) error {
+	if featureSet == osconfigv1.TechPreviewNoUpgrade {
+		return nil
+	}
+	gated := []string{}
+	for _, feature := range techPreviewFeatures {
+		if feature.used(&prov.Spec) {
+			gated = append(gated, feature.field)
+		}
+	}
+	if len(gated) == 0 {
+		return nil
+	}
+	return NewInvalidSpecError(fmt.Errorf("%s can only be used when the cluster FeatureGate enables the %s feature set",
+		strings.Join(gated, ", "), osconfigv1.TechPreviewNoUpgrade))
+}
@@ -0,0 +1,97 @@
+package provisioning
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateFeatureSet(t *testing.T) {
+	tCases := []struct {
+		name          string
+		spec          metal3iov1alpha1.ProvisioningSpec
+		featureSet    osconfigv1.FeatureSet
+		expectedError string
+	}{
+		{
+			name:       "NoTechPreviewFeature",
+			spec:       metal3iov1alpha1.ProvisioningSpec{ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkDisabled},
+			featureSet: osconfigv1.Default,
+		},
+		{
+			name:          "AutoNetworkOnDefaultCluster",
+			spec:          metal3iov1alpha1.ProvisioningSpec{ProvisioningNetwork: metal3iov1alpha1.ProvisioningNetworkAuto},
+			featureSet:    osconfigv1.Default,
+			expectedError: "ProvisioningNetwork Auto can only be used",
+		},
+		{
+			name:          "HighAvailabilityOnDefaultCluster",
+			spec:          metal3iov1alpha1.ProvisioningSpec{HighAvailability: &metal3iov1alpha1.HighAvailability{Replicas: 2}},
+			featureSet:    osconfigv1.Default,
+			expectedError: "HighAvailability can only be used",
+		},
+		{
+			name: "ProvisioningIPPoolOnDefaultCluster",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				HighAvailability:   &metal3iov1alpha1.HighAvailability{Replicas: 2},
+				ProvisioningIPPool: []string{"172.30.20.4", "172.30.20.5"},
+			},
+			featureSet:    osconfigv1.Default,
+			expectedError: "HighAvailability, ProvisioningIPPool can only be used",
+		},
+		{
+			name: "ProvisioningVIPOnDefaultCluster",
+			spec: metal3iov1alpha1.ProvisioningSpec{
+				HighAvailability: &metal3iov1alpha1.HighAvailability{Replicas: 2},
+				ProvisioningVIP:  &metal3iov1alpha1.ProvisioningVIP{},
+			},
+			featureSet:    osconfigv1.Default,
+			expectedError: "HighAvailability, ProvisioningVIP can only be used",
+		},
+		{
+			name:          "SeveralFeatures",
+			spec:          metal3iov1alpha1.ProvisioningSpec{SecureBoot: true, EnableIgnitionOverrides: true},
+			featureSet:    osconfigv1.CustomNoUpgrade,
+			expectedError: "SecureBoot, EnableIgnitionOverrides can only be used",
+		},
+		{
+			name:          "SmokeTestOnDefaultCluster",
+			spec:          metal3iov1alpha1.ProvisioningSpec{SmokeTest: true},
+			featureSet:    osconfigv1.Default,
+			expectedError: "SmokeTest can only be used",
+		},
+		{
+			name:          "IronicDBRecoveryOnDefaultCluster",
+			spec:          metal3iov1alpha1.ProvisioningSpec{IronicDBRecovery: true},
+			featureSet:    osconfigv1.Default,
+			expectedError: "IronicDBRecovery can only be used",
+		},
+		{
+			name:       "TechPreviewCluster",
+			spec:       metal3iov1alpha1.ProvisioningSpec{HighAvailability: &metal3iov1alpha1.HighAvailability{Replicas: 2}, SecureBoot: true},
+			featureSet: osconfigv1.TechPreviewNoUpgrade,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateFeatureSet(&metal3iov1alpha1.Provisioning{Spec: tc.spec}, tc.featureSet)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+				assert.True(t, errors.Is(err, ErrInvalidSpec))
+			}
+		})
+	}
+
+	assert.Equal(t, osconfigv1.Default, GetFeatureSet(nil))
+	assert.Equal(t, osconfigv1.TechPreviewNoUpgrade, GetFeatureSet(&osconfigv1.FeatureGate{
+		Spec: osconfigv1.FeatureGateSpec{FeatureGateSelection: osconfigv1.FeatureGateSelection{FeatureSet: osconfigv1.TechPreviewNoUpgrade}},
+	}))
+}
@@ -0,0 +1,104 @@
+package provisioning
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	firmwareBundlesVolume = "metal3-firmware-bundles"
+	// firmwareBundlesPath is where the bundles are served, relative to
+	// the image server root
+	firmwareBundlesPath = "firmware-bundles"
+	// ironicFirmwareStagingPath is where Ironic stages the bundles it
+	// downloads, relative to the image server root
+	ironicFirmwareStagingPath = "firmware"
+)
+
+func getFirmwareStaging(config *metal3iov1alpha1.ProvisioningSpec) metal3iov1alpha1.FirmwareStaging {
+	if config.FirmwareUpdates.Staging == "" {
+		return metal3iov1alpha1.FirmwareStagingImageServer
+	}
+	return config.FirmwareUpdates.Staging
+}
+
+// validateFirmwareUpdates checks that the firmware bundles can be served
+// by the image server
+func validateFirmwareUpdates(config *metal3iov1alpha1.ProvisioningSpec) error {
+	firmware := config.FirmwareUpdates
+	if firmware == nil {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(firmware.PersistentVolumeClaimName); len(errs) > 0 {
+		return fmt.Errorf("FirmwareUpdates: invalid PersistentVolumeClaimName %q: %s", firmware.PersistentVolumeClaimName, strings.Join(errs, ", "))
+	}
+	if config.ProvisioningIP == "" {
+		return fmt.Errorf("FirmwareUpdates requires a ProvisioningIP to serve the firmware bundles from")
+	}
+	for _, mount := range config.ImageServerMounts {
+		for _, reserved := range []string{firmwareBundlesPath, ironicFirmwareStagingPath} {
+			if overlaps(mount.Path, reserved) {
+				return fmt.Errorf("ImageServerMounts path %q collides with %q, which is used by FirmwareUpdates", mount.Path, reserved)
+			}
+		}
+	}
+	return nil
+}
+
+// getFirmwareURL returns the URL of the directory the firmware bundles
+// are served from, or an empty string when they are not
+func getFirmwareURL(config *metal3iov1alpha1.ProvisioningSpec, scheme string, port string) string {
+	if config.FirmwareUpdates == nil {
+		return ""
+	}
+	if url := getImageServerUrl(config, scheme, port, firmwareBundlesPath+"/"); url != nil {
+		return *url
+	}
+	return ""
+}
+
+// newFirmwareBundlesVolume returns the volume of the firmware bundles
+func newFirmwareBundlesVolume(config *metal3iov1alpha1.ProvisioningSpec) corev1.Volume {
+	return corev1.Volume{
+		Name: firmwareBundlesVolume,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: config.FirmwareUpdates.PersistentVolumeClaimName,
+				ReadOnly:  true,
+			},
+		},
+	}
+}
+
+// newFirmwareBundlesMount mounts the firmware bundles in the httpd
+// container, over the shared volume
+func newFirmwareBundlesMount() corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      firmwareBundlesVolume,
+		MountPath: path.Join(imageServerRoot, firmwareBundlesPath),
+		ReadOnly:  true,
+	}
+}
+
+// newFirmwareUpdatesEnv returns the environment of the Ironic conductor
+// choosing where the Redfish firmware update interface hands the bundles
+// to the BMCs from. Staged bundles are written under the image server
+// root, which the conductor shares with httpd.
+func newFirmwareUpdatesEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	if config.FirmwareUpdates == nil {
+		return nil
+	}
+	source := "local"
+	if getFirmwareStaging(config) == metal3iov1alpha1.FirmwareStagingDirect {
+		source = "http"
+	}
+	return []corev1.EnvVar{
+		{Name: "OS_REDFISH__FIRMWARE_SOURCE", Value: source},
+	}
+}
@@ -0,0 +1,93 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateFirmwareUpdates(t *testing.T) {
+	tests := []struct {
+		name           string
+		firmware       *metal3iov1alpha1.FirmwareUpdates
+		provisioningIP string
+		mounts         []metal3iov1alpha1.ImageServerMount
+		expectedError  string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name:     "Valid",
+			firmware: &metal3iov1alpha1.FirmwareUpdates{PersistentVolumeClaimName: "firmware"},
+			mounts:   []metal3iov1alpha1.ImageServerMount{{Path: "isos", ConfigMapName: "isos"}},
+		},
+		{
+			name:          "InvalidClaim",
+			firmware:      &metal3iov1alpha1.FirmwareUpdates{PersistentVolumeClaimName: "Firmware"},
+			expectedError: `FirmwareUpdates: invalid PersistentVolumeClaimName "Firmware": a DNS-1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+		{
+			name:           "NoProvisioningIP",
+			firmware:       &metal3iov1alpha1.FirmwareUpdates{PersistentVolumeClaimName: "firmware"},
+			provisioningIP: "none",
+			expectedError:  "FirmwareUpdates requires a ProvisioningIP to serve the firmware bundles from",
+		},
+		{
+			name:          "MountCollision",
+			firmware:      &metal3iov1alpha1.FirmwareUpdates{PersistentVolumeClaimName: "firmware"},
+			mounts:        []metal3iov1alpha1.ImageServerMount{{Path: "firmware/dell", ConfigMapName: "dell"}},
+			expectedError: `ImageServerMounts path "firmware/dell" collides with "firmware", which is used by FirmwareUpdates`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.FirmwareUpdates = tc.firmware
+			spec.ImageServerMounts = tc.mounts
+			if tc.provisioningIP == "none" {
+				spec.ProvisioningIP = ""
+			}
+			err := validateFirmwareUpdates(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestFirmwareUpdates(t *testing.T) {
+	spec := managedProvisioning()
+	podSpec := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.NotContains(t, podSpec.Volumes, newFirmwareBundlesVolume(&metal3iov1alpha1.ProvisioningSpec{FirmwareUpdates: &metal3iov1alpha1.FirmwareUpdates{}}))
+	assert.Equal(t, "", GetImageServerStatus(spec).HTTP.Firmware)
+
+	spec.FirmwareUpdates = &metal3iov1alpha1.FirmwareUpdates{PersistentVolumeClaimName: "firmware"}
+	spec.ImageServerHTTPS = true
+	podSpec = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.Contains(t, podSpec.Volumes, newFirmwareBundlesVolume(spec))
+	httpd := findContainer(podSpec.Containers, "metal3-httpd")
+	if assert.NotNil(t, httpd) {
+		assert.Contains(t, httpd.VolumeMounts, newFirmwareBundlesMount())
+		assert.Equal(t, "/shared/html/firmware-bundles", newFirmwareBundlesMount().MountPath)
+	}
+	conductor := findContainer(podSpec.Containers, "metal3-ironic-conductor")
+	assert.Equal(t, "local", envValue(conductor, "OS_REDFISH__FIRMWARE_SOURCE"))
+
+	status := GetImageServerStatus(spec)
+	assert.Equal(t, "http://172.30.20.3:6180/firmware-bundles/", status.HTTP.Firmware)
+	if assert.NotNil(t, status.HTTPS) {
+		assert.Equal(t, "https://172.30.20.3:6183/firmware-bundles/", status.HTTPS.Firmware)
+	}
+	configMap := NewBootArtifactsConfigMap(testNamespace, status)
+	assert.Equal(t, "http://172.30.20.3:6180/firmware-bundles/", configMap.Data["firmware"])
+	assert.Equal(t, "https://172.30.20.3:6183/firmware-bundles/", configMap.Data["https.firmware"])
+
+	spec.FirmwareUpdates.Staging = metal3iov1alpha1.FirmwareStagingDirect
+	conductor = findContainer(NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, "metal3-ironic-conductor")
+	assert.Equal(t, "http", envValue(conductor, "OS_REDFISH__FIRMWARE_SOURCE"))
+}
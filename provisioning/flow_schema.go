@@ -0,0 +1,91 @@
+package provisioning
+
+import (
+	"context"
+
+	flowcontrolv1alpha1 "k8s.io/api/flowcontrol/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	flowcontrolclientv1alpha1 "k8s.io/client-go/kubernetes/typed/flowcontrol/v1alpha1"
+)
+
+const (
+	// OperatorFlowSchemaName is the name of the API Priority and Fairness
+	// flow schema of the requests of the operator
+	OperatorFlowSchemaName = "openshift-cluster-baremetal-operator"
+	// operatorFlowSchemaPrecedence is ahead of the catch-all
+	// service-accounts flow schema, so that the requests of the operator
+	// are not queued behind those of the workloads
+	operatorFlowSchemaPrecedence = 1000
+	operatorPriorityLevel        = "workload-high"
+)
+
+// NewOperatorFlowSchema returns the flow schema matching the requests of
+// the service account of the operator and of the metal3 services, in the
+// namespace they run in
+func NewOperatorFlowSchema(targetNamespace string) *flowcontrolv1alpha1.FlowSchema {
+	return &flowcontrolv1alpha1.FlowSchema{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: OperatorFlowSchemaName,
+		},
+		Spec: flowcontrolv1alpha1.FlowSchemaSpec{
+			MatchingPrecedence: operatorFlowSchemaPrecedence,
+			PriorityLevelConfiguration: flowcontrolv1alpha1.PriorityLevelConfigurationReference{
+				Name: operatorPriorityLevel,
+			},
+			DistinguisherMethod: &flowcontrolv1alpha1.FlowDistinguisherMethod{
+				Type: flowcontrolv1alpha1.FlowDistinguisherMethodByUserType,
+			},
+			Rules: []flowcontrolv1alpha1.PolicyRulesWithSubjects{
+				{
+					Subjects: []flowcontrolv1alpha1.Subject{
+						{
+							Kind: flowcontrolv1alpha1.SubjectKindServiceAccount,
+							ServiceAccount: &flowcontrolv1alpha1.ServiceAccountSubject{
+								Name:      serviceAccountName,
+								Namespace: targetNamespace,
+							},
+						},
+					},
+					ResourceRules: []flowcontrolv1alpha1.ResourcePolicyRule{
+						{
+							Verbs:        []string{flowcontrolv1alpha1.VerbAll},
+							APIGroups:    []string{flowcontrolv1alpha1.APIGroupAll},
+							Resources:    []string{flowcontrolv1alpha1.ResourceAll},
+							ClusterScope: true,
+							Namespaces:   []string{flowcontrolv1alpha1.NamespaceEvery},
+						},
+					},
+					NonResourceRules: []flowcontrolv1alpha1.NonResourcePolicyRule{
+						{
+							Verbs:           []string{flowcontrolv1alpha1.VerbAll},
+							NonResourceURLs: []string{flowcontrolv1alpha1.NonResourceAll},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ApplyOperatorFlowSchema creates the flow schema of the operator, or
+// updates its spec when it changed, such as when the operator was moved
+// to another namespace
+func ApplyOperatorFlowSchema(client flowcontrolclientv1alpha1.FlowSchemasGetter, flowSchema *flowcontrolv1alpha1.FlowSchema) error {
+	existing, err := client.FlowSchemas().Get(context.Background(), flowSchema.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.FlowSchemas().Create(context.Background(), flowSchema, metav1.CreateOptions{})
+		return apiError(err)
+	}
+	if err != nil {
+		return apiError(err)
+	}
+	if equality.Semantic.DeepEqual(existing.Spec, flowSchema.Spec) {
+		return nil
+	}
+	updated := existing.DeepCopy()
+	updated.Spec = flowSchema.Spec
+	_, err = client.FlowSchemas().Update(context.Background(), updated, metav1.UpdateOptions{})
+	return apiError(err)
+}
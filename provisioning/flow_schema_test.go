@@ -0,0 +1,31 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestApplyOperatorFlowSchema(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+	flowSchemas := kubeClient.FlowcontrolV1alpha1().FlowSchemas()
+
+	assert.NoError(t, ApplyOperatorFlowSchema(kubeClient.FlowcontrolV1alpha1(), NewOperatorFlowSchema(testNamespace)))
+	flowSchema, err := flowSchemas.Get(context.Background(), OperatorFlowSchemaName, metav1.GetOptions{})
+	if assert.NoError(t, err) && assert.Len(t, flowSchema.Spec.Rules, 1) {
+		subject := flowSchema.Spec.Rules[0].Subjects[0].ServiceAccount
+		assert.Equal(t, serviceAccountName, subject.Name)
+		assert.Equal(t, testNamespace, subject.Namespace)
+	}
+
+	// The service account of another namespace is matched once the
+	// operator runs there
+	assert.NoError(t, ApplyOperatorFlowSchema(kubeClient.FlowcontrolV1alpha1(), NewOperatorFlowSchema("metal3")))
+	flowSchema, err = flowSchemas.Get(context.Background(), OperatorFlowSchemaName, metav1.GetOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "metal3", flowSchema.Spec.Rules[0].Subjects[0].ServiceAccount.Namespace)
+	}
+}
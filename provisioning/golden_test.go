@@ -0,0 +1,123 @@
+package provisioning
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "rewrite the golden files in testdata/golden")
+
+// goldenNamespace is the namespace the operands are rendered in, so that
+// the fixtures read like the deployed resources
+const goldenNamespace = "openshift-machine-api"
+
+// goldenConfigs are the configurations whose operands are rendered in
+// testdata/golden, one file each
+func goldenConfigs() map[string]*metal3iov1alpha1.ProvisioningSpec {
+	configs := map[string]*metal3iov1alpha1.ProvisioningSpec{}
+
+	configs["managed"] = managedProvisioning()
+
+	ipv6 := managedProvisioning()
+	ipv6.ProvisioningIP = "fd2e:6f44:5dd8:b856::3"
+	ipv6.ProvisioningNetworkCIDR = "fd2e:6f44:5dd8:b856::/64"
+	ipv6.ProvisioningDHCPRange = "fd2e:6f44:5dd8:b856::10,fd2e:6f44:5dd8:b856::ff"
+	configs["managed-ipv6"] = ipv6
+
+	// IPv6-only clusters may run without a provisioning network too
+	disabledIPv6 := managedProvisioning()
+	disabledIPv6.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkDisabled
+	disabledIPv6.ProvisioningInterface = ""
+	disabledIPv6.ProvisioningIP = "fd2e:6f44:5dd8:b856::3"
+	disabledIPv6.ProvisioningNetworkCIDR = "fd2e:6f44:5dd8:b856::/64"
+	disabledIPv6.ProvisioningDHCPRange = ""
+	configs["disabled-ipv6"] = disabledIPv6
+
+	unmanaged := managedProvisioning()
+	unmanaged.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkUnmanaged
+	unmanaged.ProvisioningDHCPRange = ""
+	configs["unmanaged"] = unmanaged
+
+	disabled := managedProvisioning()
+	disabled.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkDisabled
+	disabled.ProvisioningInterface = ""
+	disabled.ProvisioningDHCPRange = ""
+	configs["disabled"] = disabled
+
+	options := managedProvisioning()
+	options.ImageServerHTTPS = true
+	options.ConvertOSImageToRaw = true
+	options.DHCPLeasesVolumeClaim = "dhcp-leases"
+	options.DHCPRelayRanges = []metal3iov1alpha1.DHCPRelayRange{
+		{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10,192.168.10.100", Router: "192.168.10.1"},
+	}
+	options.IronicAPIExposure = &metal3iov1alpha1.IronicAPIExposure{ClientCAConfigMap: "ironic-client-ca"}
+	options.IronicAPIAudit = &metal3iov1alpha1.IronicAPIAudit{SyslogEndpoint: "syslog.example.com:514"}
+	options.HardwareMetrics = &metal3iov1alpha1.HardwareMetrics{IntervalSeconds: 300}
+	options.ControlPlaneOnly = pointer.BoolPtr(false)
+	options.NodeSelector = map[string]string{"node-role.kubernetes.io/provisioning": ""}
+	options.HostSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"site": "main"}}
+	cacheSize := resource.MustParse("50Gi")
+	options.ImageCache = &metal3iov1alpha1.ImageCache{Size: &cacheSize, Retention: &metav1.Duration{Duration: 24 * time.Hour}}
+	configs["managed-options"] = options
+
+	return configs
+}
+
+// renderGolden returns the YAML of the operands deployed for a configuration
+func renderGolden(t *testing.T, config *metal3iov1alpha1.ProvisioningSpec) []byte {
+	objects := []runtime.Object{NewMetal3Deployment(goldenNamespace, &testImages, config)}
+	if IsDnsmasqRequired(config) {
+		objects = append(objects, NewDnsmasqDaemonSet(goldenNamespace, &testImages, config))
+	}
+
+	buf := &bytes.Buffer{}
+	for _, obj := range objects {
+		gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvks[0])
+		data, err := yaml.Marshal(obj)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		buf.WriteString("---\n")
+		buf.Write(data)
+	}
+	return buf.Bytes()
+}
+
+// TestGoldenOperands compares the rendered operands with the checked-in
+// fixtures. Run with -update-golden to accept intended changes.
+func TestGoldenOperands(t *testing.T) {
+	for name, config := range goldenConfigs() {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join("testdata", "golden", name+".yaml")
+			rendered := renderGolden(t, config)
+			if *updateGolden {
+				assert.NoError(t, ioutil.WriteFile(path, rendered, 0644))
+				return
+			}
+			expected, err := ioutil.ReadFile(path)
+			if !assert.NoError(t, err, "run go test ./provisioning -run TestGoldenOperands -update-golden to create it") {
+				return
+			}
+			assert.Equal(t, string(expected), string(rendered), "operands of %s changed, run with -update-golden if intended", name)
+		})
+	}
+}
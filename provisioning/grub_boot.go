@@ -0,0 +1,95 @@
+package provisioning
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	grubBootConfigEnvVar = "GRUB_BOOT_CONFIG"
+	grubBootTagX64       = "grub-x64"
+	grubBootTagARM64     = "grub-aa64"
+	grubBootFileX64      = "grubx64.efi"
+	grubBootFileARM64    = "grubaa64.efi"
+)
+
+// usesGRUBNetworkBoot returns true when the UEFI hosts boot GRUB rather
+// than iPXE from the network
+func usesGRUBNetworkBoot(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.UEFIBootLoader == metal3iov1alpha1.UEFIBootLoaderGRUB
+}
+
+// validateUEFIBootLoader checks that the GRUB builds handed out to the
+// hosts are served by the TFTP server, as the metal3 image only holds the
+// iPXE ones, and that they are not also expected to be loaded by the shim
+func validateUEFIBootLoader(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	switch config.UEFIBootLoader {
+	case "", metal3iov1alpha1.UEFIBootLoaderIPXE:
+		return nil
+	case metal3iov1alpha1.UEFIBootLoaderGRUB:
+	default:
+		return fmt.Errorf("unsupported UEFIBootLoader %q", config.UEFIBootLoader)
+	}
+	if mode != metal3iov1alpha1.ProvisioningNetworkManaged {
+		return fmt.Errorf("UEFIBootLoader %s is only supported when the ProvisioningNetwork is Managed", config.UEFIBootLoader)
+	}
+	if config.SecureBoot {
+		return fmt.Errorf("UEFIBootLoader %s cannot be combined with SecureBoot, which boots GRUB through the shim", config.UEFIBootLoader)
+	}
+	if config.TFTP == nil || config.TFTP.FilesConfigMap == "" {
+		return fmt.Errorf("UEFIBootLoader %s requires a TFTP.FilesConfigMap holding %s and %s", config.UEFIBootLoader, grubBootFileX64, grubBootFileARM64)
+	}
+	return nil
+}
+
+// getGRUBBootConfig returns the dnsmasq configuration handing out the GRUB
+// network boot image of their architecture to the UEFI hosts. Unlike with
+// SecureBoot, the 32-bit UEFI hosts are left to iPXE as GRUB is only
+// served for x86_64 and aarch64. BIOS hosts are handed out the iPXE
+// builds as before.
+func getGRUBBootConfig(config *metal3iov1alpha1.ProvisioningSpec) string {
+	if !usesGRUBNetworkBoot(config) {
+		return ""
+	}
+	return getUEFIBootFileConfig(config, []uefiBootFile{
+		{tag: grubBootTagX64, file: grubBootFileX64, archs: []int{clientArchEFIX64, clientArchEFIBC}},
+		{tag: grubBootTagARM64, file: grubBootFileARM64, archs: []int{clientArchEFIARM64}},
+	})
+}
+
+// grubBootIronicEnv returns the configuration of the Ironic conductor
+// passed through the environment, so that the pxe boot interface
+// generates the GRUB configuration of each UEFI host, and names the GRUB
+// build of its architecture as boot file. The pxe boot interface is made
+// the default, as the hosts left to ipxe would be handed out GRUB by DHCP
+// and an iPXE script by Ironic.
+func grubBootIronicEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	if !usesGRUBNetworkBoot(config) {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  ironicDefaultEnvPrefix + "DEFAULT_BOOT_INTERFACE",
+			Value: "pxe",
+		},
+		{
+			Name:  ironicPXEEnvPrefix + "UEFI_PXE_BOOTFILE_NAME",
+			Value: grubBootFileX64,
+		},
+		{
+			Name:  ironicPXEEnvPrefix + "UEFI_PXE_CONFIG_TEMPLATE",
+			Value: grubConfigTemplate,
+		},
+		{
+			Name:  ironicPXEEnvPrefix + "PXE_BOOTFILE_NAME_BY_ARCH",
+			Value: "aarch64:" + grubBootFileARM64,
+		},
+		{
+			Name:  ironicPXEEnvPrefix + "PXE_CONFIG_TEMPLATE_BY_ARCH",
+			Value: "aarch64:" + grubConfigTemplate,
+		},
+	}
+}
@@ -0,0 +1,117 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateUEFIBootLoader(t *testing.T) {
+	tCases := []struct {
+		name          string
+		mode          metal3iov1alpha1.ProvisioningNetwork
+		bootLoader    metal3iov1alpha1.UEFIBootLoader
+		secureBoot    bool
+		noFiles       bool
+		expectedError string
+	}{
+		{
+			name: "NotSet",
+			mode: metal3iov1alpha1.ProvisioningNetworkDisabled,
+		},
+		{
+			name:       "IPXE",
+			mode:       metal3iov1alpha1.ProvisioningNetworkDisabled,
+			bootLoader: metal3iov1alpha1.UEFIBootLoaderIPXE,
+		},
+		{
+			name:       "Managed",
+			mode:       metal3iov1alpha1.ProvisioningNetworkManaged,
+			bootLoader: metal3iov1alpha1.UEFIBootLoaderGRUB,
+		},
+		{
+			name:          "NoFilesConfigMap",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			bootLoader:    metal3iov1alpha1.UEFIBootLoaderGRUB,
+			noFiles:       true,
+			expectedError: "UEFIBootLoader GRUB requires a TFTP.FilesConfigMap holding grubx64.efi and grubaa64.efi",
+		},
+		{
+			name:          "Unmanaged",
+			mode:          metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			bootLoader:    metal3iov1alpha1.UEFIBootLoaderGRUB,
+			expectedError: "UEFIBootLoader GRUB is only supported when the ProvisioningNetwork is Managed",
+		},
+		{
+			name:          "Disabled",
+			mode:          metal3iov1alpha1.ProvisioningNetworkDisabled,
+			bootLoader:    metal3iov1alpha1.UEFIBootLoaderGRUB,
+			expectedError: "UEFIBootLoader GRUB is only supported when the ProvisioningNetwork is Managed",
+		},
+		{
+			name:          "SecureBoot",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			bootLoader:    metal3iov1alpha1.UEFIBootLoaderGRUB,
+			secureBoot:    true,
+			expectedError: "UEFIBootLoader GRUB cannot be combined with SecureBoot, which boots GRUB through the shim",
+		},
+		{
+			name:          "Unsupported",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			bootLoader:    "systemd-boot",
+			expectedError: `unsupported UEFIBootLoader "systemd-boot"`,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			config.UEFIBootLoader = tc.bootLoader
+			config.SecureBoot = tc.secureBoot
+			if !tc.noFiles {
+				config.TFTP = &metal3iov1alpha1.TFTPConfig{FilesConfigMap: "grub-files"}
+			}
+			err := validateUEFIBootLoader(config, tc.mode)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestGRUBBootConfig(t *testing.T) {
+	config := managedProvisioning()
+	config.UEFIBootLoader = metal3iov1alpha1.UEFIBootLoaderIPXE
+	assert.Equal(t, "", getGRUBBootConfig(config))
+	assert.Empty(t, grubBootIronicEnv(config))
+
+	config.UEFIBootLoader = metal3iov1alpha1.UEFIBootLoaderGRUB
+	assert.Equal(t, "dhcp-match=set:grub-x64,option:client-arch,7\n"+
+		"dhcp-match=set:grub-x64,option:client-arch,9\n"+
+		"dhcp-boot=tag:grub-x64,grubx64.efi\n"+
+		"dhcp-match=set:grub-aa64,option:client-arch,11\n"+
+		"dhcp-boot=tag:grub-aa64,grubaa64.efi", getGRUBBootConfig(config))
+
+	dnsmasq := findContainer(newDnsmasqPodTemplateSpec(&testImages, config).Spec.Containers, "metal3-dnsmasq")
+	assert.Equal(t, getGRUBBootConfig(config), envValue(dnsmasq, grubBootConfigEnvVar))
+	assert.Equal(t, dnsmasqServerScript+`mkdir -p /etc/dnsmasq.d && echo "${GRUB_BOOT_CONFIG}" > /etc/dnsmasq.d/grubboot.conf && exec /bin/rundnsmasq`,
+		dnsmasq.Command[2])
+
+	conductor := createContainerMetal3IronicConductor(&testImages, config)
+	assert.Equal(t, "pxe", envValue(&conductor, "OS_DEFAULT__DEFAULT_BOOT_INTERFACE"))
+	assert.Equal(t, "grubx64.efi", envValue(&conductor, "OS_PXE__UEFI_PXE_BOOTFILE_NAME"))
+	assert.Equal(t, grubConfigTemplate, envValue(&conductor, "OS_PXE__UEFI_PXE_CONFIG_TEMPLATE"))
+	assert.Equal(t, "aarch64:grubaa64.efi", envValue(&conductor, "OS_PXE__PXE_BOOTFILE_NAME_BY_ARCH"))
+	assert.Equal(t, "aarch64:"+grubConfigTemplate, envValue(&conductor, "OS_PXE__PXE_CONFIG_TEMPLATE_BY_ARCH"))
+
+	config.ProvisioningIP = "fd00:1101::3"
+	config.ProvisioningNetworkCIDR = "fd00:1101::/64"
+	assert.Equal(t, "dhcp-match=set:grub-x64,option6:61,7\n"+
+		"dhcp-match=set:grub-x64,option6:61,9\n"+
+		"dhcp-option=tag:grub-x64,option6:bootfile-url,tftp://[fd00:1101::3]/grubx64.efi\n"+
+		"dhcp-match=set:grub-aa64,option6:61,11\n"+
+		"dhcp-option=tag:grub-aa64,option6:bootfile-url,tftp://[fd00:1101::3]/grubaa64.efi", getGRUBBootConfig(config))
+}
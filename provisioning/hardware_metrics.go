@@ -0,0 +1,82 @@
+package provisioning
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	hardwareMetricsPort            = 9608
+	hardwareMetricsPortName        = "hw-metrics"
+	defaultHardwareMetricsInterval = 600
+	minHardwareMetricsInterval     = 60
+)
+
+// getHardwareMetricsInterval returns how often, in seconds, the sensors
+// of the hosts are read
+func getHardwareMetricsInterval(metrics *metal3iov1alpha1.HardwareMetrics) int32 {
+	if metrics.IntervalSeconds == 0 {
+		return defaultHardwareMetricsInterval
+	}
+	return metrics.IntervalSeconds
+}
+
+// newHardwareMetricsConductorEnv has the conductor read the sensors of the
+// hosts and hand them to the exporter, through files on the shared volume
+func newHardwareMetricsConductorEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	if config.HardwareMetrics == nil {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  "SEND_SENSOR_DATA",
+			Value: "true",
+		},
+		{
+			Name:  "OS_SENSOR_DATA__INTERVAL",
+			Value: strconv.Itoa(int(getHardwareMetricsInterval(config.HardwareMetrics))),
+		},
+	}
+}
+
+// createContainerMetal3IronicExporter returns the ironic-prometheus-exporter
+// serving the sensor data written by the conductor of the pod
+func createContainerMetal3IronicExporter(images *Images) corev1.Container {
+	return corev1.Container{
+		Name:            "metal3-ironic-exporter",
+		Image:           images.BaremetalIronic,
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		Command: []string{"/bin/runironic-exporter"},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          hardwareMetricsPortName,
+				ContainerPort: hardwareMetricsPort,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "IRONIC_PROMETHEUS_EXPORTER_PORT",
+				Value: strconv.Itoa(hardwareMetricsPort),
+			},
+		},
+	}
+}
+
+func validateHardwareMetrics(metrics *metal3iov1alpha1.HardwareMetrics) error {
+	if metrics == nil {
+		return nil
+	}
+	if metrics.IntervalSeconds != 0 && metrics.IntervalSeconds < minHardwareMetricsInterval {
+		return fmt.Errorf("HardwareMetrics.IntervalSeconds must be at least %d", minHardwareMetricsInterval)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestHardwareMetrics(t *testing.T) {
+	spec := managedProvisioning()
+	containers := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+	assert.NotContains(t, containerNames(containers), "metal3-ironic-exporter")
+	for _, env := range findContainer(containers, "metal3-ironic-conductor").Env {
+		assert.NotEqual(t, "SEND_SENSOR_DATA", env.Name)
+	}
+
+	spec.HardwareMetrics = &metal3iov1alpha1.HardwareMetrics{}
+	assert.NoError(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec}))
+	containers = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+	exporter := findContainer(containers, "metal3-ironic-exporter")
+	if assert.NotNil(t, exporter) {
+		assert.Equal(t, []corev1.ContainerPort{{Name: "hw-metrics", ContainerPort: 9608}}, exporter.Ports)
+		assert.Contains(t, exporter.VolumeMounts, sharedVolumeMount)
+	}
+	conductorEnv := findContainer(containers, "metal3-ironic-conductor").Env
+	assert.Contains(t, conductorEnv, corev1.EnvVar{Name: "SEND_SENSOR_DATA", Value: "true"})
+	assert.Contains(t, conductorEnv, corev1.EnvVar{Name: "OS_SENSOR_DATA__INTERVAL", Value: "600"})
+
+	group := NewConductorGroupDeployment(testNamespace, &testImages, spec, &metal3iov1alpha1.ConductorGroup{Name: "rack-a"})
+	assert.Contains(t, containerNames(group.Spec.Template.Spec.Containers), "metal3-ironic-exporter")
+
+	spec.HardwareMetrics.IntervalSeconds = 10
+	assert.EqualError(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec}),
+		"HardwareMetrics.IntervalSeconds must be at least 60")
+}
@@ -0,0 +1,188 @@
+package provisioning
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// activeMetal3CommandName names the wrapper of the services of the
+	// active metal3 pod in the process list
+	activeMetal3CommandName = "metal3-active"
+	// activeMetal3CheckInterval is how often the services check whether
+	// their pod is the active metal3 pod
+	activeMetal3CheckInterval = "5"
+	ironicInspectorCommand    = "/bin/runironic-inspector"
+)
+
+// activeMetal3Script starts the service of a container once the node of
+// the pod was elected to run the metal3 services, and stops it should
+// another node be elected. The operator only elects another node once the
+// pod is gone, so the latter only happens when the pod was force deleted
+// while its node was unreachable. The address of the node from the
+// ProvisioningIPPool is exported to the variables of NODE_IP_ENV.
+const activeMetal3Script = provisioningVIPFunctions + `
+until holds_provisioning_ip; do
+    sleep ${ACTIVE_CHECK_INTERVAL}
+done
+if [ -n "${NODE_IP_ENV:-}" ] && [ -n "${NODE_IP_FILE:-}" ] && [ -f "$NODE_IP_FILE" ]; then
+    read -r node_ip < "$NODE_IP_FILE" || true
+    for name in $NODE_IP_ENV; do
+        export "$name=${node_ip%/*}"
+    done
+fi
+"$@" &
+service=$!
+trap 'kill -TERM "$service" 2>/dev/null; wait "$service"; exit $?' TERM INT
+while kill -0 "$service" 2>/dev/null; do
+    if ! holds_provisioning_ip; then
+        echo "node $NODE_NAME is no longer running the active metal3 pod, stopping"
+        kill -TERM "$service" 2>/dev/null
+        wait "$service"
+        exit 1
+    fi
+    sleep ${ACTIVE_CHECK_INTERVAL} &
+    wait $!
+done
+wait "$service"
+`
+
+// standbyContainers keep running in the metal3 pods that are not active,
+// so that a standby pod takes over with the images downloaded and the
+// address of its node configured
+var standbyContainers = map[string]bool{
+	StaticIPManagerContainerName:   true,
+	"metal3-ipa-downloader":        true,
+	"metal3-machine-os-downloader": true,
+	osImageProgressContainerName:   true,
+	"metal3-image-cache-janitor":   true,
+	bmcRoutesContainerName:         true,
+}
+
+// imageEntrypoints are the commands of the containers started with the
+// entrypoint of their image
+var imageEntrypoints = map[string][]string{
+	inspectorContainerName: {ironicInspectorCommand},
+}
+
+// nodeIPEnv are the variables of the containers set to the address of
+// their node from the ProvisioningIPPool. The Ironic API calls the
+// conductor at the address it registers, which then does not move with
+// the ProvisioningIP.
+var nodeIPEnv = map[string]string{
+	ironicConductorContainerName: "OS_DEFAULT__HOST OS_JSON_RPC__HOST_IP",
+}
+
+// GetMetal3Replicas returns the number of metal3 pods requested
+func GetMetal3Replicas(config *metal3iov1alpha1.ProvisioningSpec) int32 {
+	if config.HighAvailability == nil {
+		return 1
+	}
+	return config.HighAvailability.Replicas
+}
+
+func validateHighAvailability(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if config.HighAvailability != nil && config.HighAvailability.Replicas < 2 {
+		return fmt.Errorf("HighAvailability.Replicas must be at least 2")
+	}
+	return nil
+}
+
+func metal3PodSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			"k8s-app":    metal3AppName,
+			"controller": metal3AppName,
+		},
+	}
+}
+
+// newMetal3Affinity keeps the replicas of the metal3 pod on distinct
+// nodes. This is required rather than preferred, as the host network
+// ports of the pods would conflict anyway.
+func newMetal3Affinity(config *metal3iov1alpha1.ProvisioningSpec) *corev1.Affinity {
+	if config.HighAvailability == nil {
+		return nil
+	}
+	return &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: metal3PodSelector(),
+					TopologyKey:   corev1.LabelHostname,
+				},
+			},
+		},
+	}
+}
+
+// newMetal3TopologySpreadConstraints spreads the replicas of the metal3
+// pod across zones. Bare metal nodes often have no zone, so pods are
+// still scheduled when the spread cannot be achieved.
+func newMetal3TopologySpreadConstraints(config *metal3iov1alpha1.ProvisioningSpec) []corev1.TopologySpreadConstraint {
+	if config.HighAvailability == nil {
+		return nil
+	}
+	return []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       corev1.LabelZoneFailureDomainStable,
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+			LabelSelector:     metal3PodSelector(),
+		},
+	}
+}
+
+// GetNodeZones returns the number of distinct zones of the nodes, and
+// whether all of them have a zone
+func GetNodeZones(nodes []corev1.Node) (int, bool) {
+	zones := map[string]bool{}
+	for _, node := range nodes {
+		zone, ok := node.Labels[corev1.LabelZoneFailureDomainStable]
+		if !ok {
+			return 0, false
+		}
+		zones[zone] = true
+	}
+	return len(zones), true
+}
+
+// IsActivePassive returns whether a single metal3 pod, elected by the
+// operator, runs the metal3 services and holds the ProvisioningIP while
+// the other replicas stand by
+func IsActivePassive(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.HighAvailability != nil
+}
+
+// applyActivePassive only starts the services of the metal3 pod once it is
+// the active one. Each replica would otherwise run its own
+// baremetal-operator, database and Ironic, all of them provisioning the
+// same hosts.
+func applyActivePassive(spec *corev1.PodSpec, config *metal3iov1alpha1.ProvisioningSpec) {
+	if !IsActivePassive(config) {
+		return
+	}
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		if standbyContainers[container.Name] {
+			continue
+		}
+		command := container.Command
+		if len(command) == 0 {
+			command = imageEntrypoints[container.Name]
+		}
+		container.Command = append([]string{"/bin/sh", "-c", activeMetal3Script, activeMetal3CommandName}, command...)
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "ACTIVE_CHECK_INTERVAL",
+			Value: activeMetal3CheckInterval,
+		})
+		if env, ok := nodeIPEnv[container.Name]; ok && HasProvisioningIPPool(config) {
+			container.Env = append(container.Env, corev1.EnvVar{Name: "NODE_IP_ENV", Value: env})
+		}
+		addNodeAddresses(container, config)
+	}
+}
@@ -0,0 +1,122 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestMetal3HighAvailability(t *testing.T) {
+	spec := managedProvisioning()
+	deployment := NewMetal3Deployment(testNamespace, &testImages, spec)
+	assert.Equal(t, int32(1), *deployment.Spec.Replicas)
+	assert.Nil(t, deployment.Spec.Template.Spec.Affinity)
+	assert.Empty(t, deployment.Spec.Template.Spec.TopologySpreadConstraints)
+
+	spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: 3}
+	assert.NoError(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec}))
+	deployment = NewMetal3Deployment(testNamespace, &testImages, spec)
+	assert.Equal(t, int32(3), *deployment.Spec.Replicas)
+
+	podSpec := deployment.Spec.Template.Spec
+	if assert.NotNil(t, podSpec.Affinity) && assert.NotNil(t, podSpec.Affinity.PodAntiAffinity) {
+		terms := podSpec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+		if assert.Len(t, terms, 1) {
+			assert.Equal(t, corev1.LabelHostname, terms[0].TopologyKey)
+			assert.Equal(t, deployment.Spec.Selector, terms[0].LabelSelector)
+		}
+	}
+	if assert.Len(t, podSpec.TopologySpreadConstraints, 1) {
+		constraint := podSpec.TopologySpreadConstraints[0]
+		assert.Equal(t, corev1.LabelZoneFailureDomainStable, constraint.TopologyKey)
+		assert.Equal(t, corev1.ScheduleAnyway, constraint.WhenUnsatisfiable)
+		assert.Equal(t, deployment.Spec.Selector, constraint.LabelSelector)
+	}
+
+	spec.HighAvailability.Replicas = 1
+	assert.Error(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec}))
+}
+
+func TestMetal3ActivePassive(t *testing.T) {
+	spec := managedProvisioning()
+	containers := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+	assert.Equal(t, []string{"/baremetal-operator"}, findContainer(containers, "metal3-baremetal-operator").Command)
+	assert.Empty(t, findContainer(containers, inspectorContainerName).Command)
+
+	spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: 2}
+	containers = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+	gate := []string{"/bin/sh", "-c", activeMetal3Script, activeMetal3CommandName}
+
+	bmo := findContainer(containers, "metal3-baremetal-operator")
+	assert.Equal(t, append(gate, "/baremetal-operator"), bmo.Command)
+	assert.Equal(t, provisioningVIPMountPath+"/node", envValue(bmo, "VIP_HOLDER_FILE"))
+	assert.Equal(t, activeMetal3CheckInterval, envValue(bmo, "ACTIVE_CHECK_INTERVAL"))
+	assert.Equal(t, append(gate, ironicInspectorCommand), findContainer(containers, inspectorContainerName).Command)
+	// The database recovery runs once the pod is active
+	assert.Equal(t, gate, findContainer(containers, mariadbContainerName).Command[:4])
+	assert.Equal(t, ironicDBRecoveryCommandName, findContainer(containers, mariadbContainerName).Command[7])
+
+	// The standby pods download the images and configure their node
+	for _, name := range []string{StaticIPManagerContainerName, "metal3-ipa-downloader", "metal3-machine-os-downloader"} {
+		container := findContainer(containers, name)
+		if assert.NotNil(t, container, name) {
+			assert.NotEqual(t, activeMetal3CommandName, container.Command[len(container.Command)-1], name)
+			assert.Empty(t, envValue(container, "ACTIVE_CHECK_INTERVAL"), name)
+		}
+	}
+
+	conductor := findContainer(containers, ironicConductorContainerName)
+	assert.Empty(t, envValue(conductor, "NODE_IP_ENV"))
+
+	// The conductor of the active pod is reached at the address of its node
+	spec.ProvisioningIPPool = []string{"172.30.20.4", "172.30.20.5"}
+	containers = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+	conductor = findContainer(containers, ironicConductorContainerName)
+	assert.Equal(t, "OS_DEFAULT__HOST OS_JSON_RPC__HOST_IP", envValue(conductor, "NODE_IP_ENV"))
+	assert.Equal(t, provisioningIPsMountPath+"/$(NODE_NAME)", envValue(conductor, "NODE_IP_FILE"))
+	assert.Empty(t, envValue(findContainer(containers, "metal3-ironic-api"), "NODE_IP_ENV"))
+}
+
+func TestGetNodeZones(t *testing.T) {
+	node := func(zone string) corev1.Node {
+		n := corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{}}}
+		if zone != "" {
+			n.Labels[corev1.LabelZoneFailureDomainStable] = zone
+		}
+		return n
+	}
+	tests := []struct {
+		name             string
+		nodes            []corev1.Node
+		expectedZones    int
+		expectedLabelled bool
+	}{
+		{
+			name:             "DistinctZones",
+			nodes:            []corev1.Node{node("a"), node("b"), node("c")},
+			expectedZones:    3,
+			expectedLabelled: true,
+		},
+		{
+			name:             "SharedZone",
+			nodes:            []corev1.Node{node("a"), node("a"), node("b")},
+			expectedZones:    2,
+			expectedLabelled: true,
+		},
+		{
+			name:  "Unlabelled",
+			nodes: []corev1.Node{node("a"), node("")},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			zones, labelled := GetNodeZones(tc.nodes)
+			assert.Equal(t, tc.expectedZones, zones)
+			assert.Equal(t, tc.expectedLabelled, labelled)
+		})
+	}
+}
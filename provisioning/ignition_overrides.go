@@ -0,0 +1,226 @@
+package provisioning
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// IgnitionOverrideHostLabel labels the ConfigMaps holding the ignition
+	// overrides of a host, its value being the name of the host
+	IgnitionOverrideHostLabel = "baremetal.openshift.io/ignition-override-host"
+	// IgnitionOverridesConfigMap is the name of the ConfigMap gathering the
+	// ignition overrides of every host, served by the ignition server
+	IgnitionOverridesConfigMap = "metal3-ignition-overrides"
+
+	ignitionOverrideKey        = "ignition"
+	networkOverrideKey         = "network"
+	ignitionOverridesPort      = 6390
+	ignitionOverridesVolume    = "metal3-ignition-overrides"
+	ignitionOverridesMountPath = "/etc/metal3-ignition-overrides"
+	// maxIgnitionOverridesSize is the size the keys and values of the
+	// gathered overrides must fit in, the limit of the data of a ConfigMap
+	maxIgnitionOverridesSize = corev1.MaxSecretSize
+)
+
+// ignitionServerScript serves the overrides gathered in the mounted
+// ConfigMap at /<host>/ignition and /<host>/network. The files are read
+// on each request, so that changes to the overrides are served once the
+// kubelet refreshes the volume, without restarting the pod. The ignition
+// may hold secrets, so it is only served on the ProvisioningIP rather
+// than on every interface of the host network, waiting for the address
+// to be configured.
+const ignitionServerScript = `
+import http.server
+import os
+import re
+import socket
+import time
+
+ROOT = os.environ.get("IGNITION_OVERRIDES_DIR", "/etc/metal3-ignition-overrides")
+ADDRESS = os.environ["IGNITION_OVERRIDES_ADDRESS"]
+PORT = int(os.environ.get("IGNITION_OVERRIDES_PORT", "6390"))
+PATH = re.compile(r"^/([a-z0-9]([-a-z0-9.]*[a-z0-9])?)/(ignition|network)$")
+CONTENT_TYPES = {
+    "ignition": "application/vnd.coreos.ignition+json",
+    "network": "application/yaml",
+}
+
+
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        match = PATH.match(self.path)
+        if match is None:
+            self.send_error(404)
+            return
+        host, kind = match.group(1), match.group(3)
+        try:
+            with open(os.path.join(ROOT, "%s.%s" % (host, kind)), "rb") as f:
+                body = f.read()
+        except FileNotFoundError:
+            self.send_error(404)
+            return
+        self.send_response(200)
+        self.send_header("Content-Type", CONTENT_TYPES[kind])
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+
+class Server(http.server.HTTPServer):
+    address_family = socket.AF_INET6 if ":" in ADDRESS else socket.AF_INET
+
+
+while True:
+    try:
+        server = Server((ADDRESS, PORT), Handler)
+        break
+    except OSError as e:
+        print("waiting for %s to be configured: %s" % (ADDRESS, e), flush=True)
+        time.sleep(5)
+server.serve_forever()
+`
+
+// validateIgnitionOverrides checks that the ignition overrides can be
+// served at a stable URL
+func validateIgnitionOverrides(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if config.EnableIgnitionOverrides && config.ProvisioningIP == "" {
+		return fmt.Errorf("EnableIgnitionOverrides requires a ProvisioningIP")
+	}
+	return nil
+}
+
+// GetIgnitionOverridesURL returns the URL the ignition overrides are
+// served under, or an empty string when they are not served
+func GetIgnitionOverridesURL(config *metal3iov1alpha1.ProvisioningSpec) string {
+	if !config.EnableIgnitionOverrides || config.ProvisioningIP == "" {
+		return ""
+	}
+	return fmt.Sprintf("http://%s", net.JoinHostPort(config.ProvisioningIP, strconv.Itoa(ignitionOverridesPort)))
+}
+
+// NewIgnitionOverridesConfigMap returns the ConfigMap gathering the
+// overrides of the given source ConfigMaps, with one <host>.ignition and
+// <host>.network key per host. When several sources are labelled with the
+// same host, the first one by name is used. The sources whose overrides
+// no longer fit in the ConfigMap are left out as well, rather than failing
+// to write the overrides of every host; the names of the ignored sources
+// are returned along with the ConfigMap.
+func NewIgnitionOverridesConfigMap(targetNamespace string, sources []corev1.ConfigMap) (*corev1.ConfigMap, []string) {
+	sorted := append([]corev1.ConfigMap{}, sources...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	data := map[string]string{}
+	hosts := map[string]bool{}
+	ignored := []string{}
+	size := 0
+	for _, source := range sorted {
+		host := source.Labels[IgnitionOverrideHostLabel]
+		if len(validation.IsDNS1123Subdomain(host)) > 0 || hosts[host] {
+			ignored = append(ignored, source.Name)
+			continue
+		}
+		overrides := map[string]string{}
+		sourceSize := 0
+		for _, key := range []string{ignitionOverrideKey, networkOverrideKey} {
+			if value, ok := source.Data[key]; ok {
+				overrides[host+"."+key] = value
+				sourceSize += len(host) + 1 + len(key) + len(value)
+			}
+		}
+		if size+sourceSize > maxIgnitionOverridesSize {
+			ignored = append(ignored, source.Name)
+			continue
+		}
+		hosts[host] = true
+		size += sourceSize
+		for key, value := range overrides {
+			data[key] = value
+		}
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IgnitionOverridesConfigMap,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: data,
+	}, ignored
+}
+
+// ApplyIgnitionOverridesConfigMap creates or updates the ConfigMap
+// gathering the ignition overrides
+func ApplyIgnitionOverridesConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	return applyConfigMap(client, configMap)
+}
+
+// DeleteIgnitionOverridesConfigMap removes the ConfigMap gathering the
+// ignition overrides, once they are not served anymore
+func DeleteIgnitionOverridesConfigMap(client coreclientv1.ConfigMapsGetter, targetNamespace string) error {
+	return deleteConfigMap(client, targetNamespace, IgnitionOverridesConfigMap)
+}
+
+// newIgnitionOverridesVolume returns the volume of the gathered overrides.
+// It is optional, so that the pod starts before the ConfigMap is created.
+func newIgnitionOverridesVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: ignitionOverridesVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: IgnitionOverridesConfigMap},
+				Optional:             pointer.BoolPtr(true),
+			},
+		},
+	}
+}
+
+func createContainerMetal3IgnitionServer(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	return corev1.Container{
+		Name:            "metal3-ignition-server",
+		Image:           images.BaremetalIronic,
+		Command:         []string{"python3", "-c", ignitionServerScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "ignition",
+				ContainerPort: ignitionOverridesPort,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      ignitionOverridesVolume,
+				MountPath: ignitionOverridesMountPath,
+				ReadOnly:  true,
+			},
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "IGNITION_OVERRIDES_DIR",
+				Value: ignitionOverridesMountPath,
+			},
+			{
+				Name:  "IGNITION_OVERRIDES_ADDRESS",
+				Value: config.ProvisioningIP,
+			},
+			{
+				Name:  "IGNITION_OVERRIDES_PORT",
+				Value: strconv.Itoa(ignitionOverridesPort),
+			},
+		},
+	}
+}
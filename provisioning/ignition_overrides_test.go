@@ -0,0 +1,81 @@
+package provisioning
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newIgnitionOverrideSource(name, host string, data map[string]string) corev1.ConfigMap {
+	return corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: testNamespace,
+			Labels:    map[string]string{IgnitionOverrideHostLabel: host},
+		},
+		Data: data,
+	}
+}
+
+func TestNewIgnitionOverridesConfigMap(t *testing.T) {
+	sources := []corev1.ConfigMap{
+		newIgnitionOverrideSource("worker-1-duplicate", "worker-1", map[string]string{"ignition": "duplicate"}),
+		newIgnitionOverrideSource("worker-0", "worker-0", map[string]string{"ignition": "ign-0", "network": "net-0", "other": "ignored"}),
+		newIgnitionOverrideSource("worker-1", "worker-1", map[string]string{"network": "net-1"}),
+		newIgnitionOverrideSource("invalid", "Invalid_Host", map[string]string{"ignition": "invalid"}),
+	}
+	configMap, ignored := NewIgnitionOverridesConfigMap(testNamespace, sources)
+
+	assert.Equal(t, IgnitionOverridesConfigMap, configMap.Name)
+	assert.Equal(t, testNamespace, configMap.Namespace)
+	assert.Equal(t, map[string]string{
+		"worker-0.ignition": "ign-0",
+		"worker-0.network":  "net-0",
+		"worker-1.network":  "net-1",
+	}, configMap.Data)
+	assert.Equal(t, []string{"invalid", "worker-1-duplicate"}, ignored)
+
+	// The overrides that would not fit in the ConfigMap are left out
+	large := strings.Repeat("x", maxIgnitionOverridesSize/2)
+	configMap, ignored = NewIgnitionOverridesConfigMap(testNamespace, []corev1.ConfigMap{
+		newIgnitionOverrideSource("worker-0", "worker-0", map[string]string{"ignition": large}),
+		newIgnitionOverrideSource("worker-1", "worker-1", map[string]string{"ignition": large}),
+		newIgnitionOverrideSource("worker-2", "worker-2", map[string]string{"network": "net-2"}),
+	})
+	assert.Equal(t, []string{"worker-0.ignition", "worker-2.network"}, sortedKeys(configMap.Data))
+	assert.Equal(t, []string{"worker-1"}, ignored)
+}
+
+func TestIgnitionOverridesServer(t *testing.T) {
+	config := managedProvisioning()
+	assert.Empty(t, GetIgnitionOverridesURL(config))
+	assert.Nil(t, findContainer(newMetal3Containers(&testImages, config), "metal3-ignition-server"))
+
+	config.EnableIgnitionOverrides = true
+	assert.NoError(t, validateIgnitionOverrides(config))
+	assert.Equal(t, "http://"+config.ProvisioningIP+":6390", GetIgnitionOverridesURL(config))
+
+	podTemplate := newMetal3PodTemplateSpec(&testImages, config)
+	server := findContainer(podTemplate.Spec.Containers, "metal3-ignition-server")
+	if assert.NotNil(t, server) {
+		assert.Equal(t, ignitionOverridesVolume, server.VolumeMounts[0].Name)
+		assert.True(t, server.VolumeMounts[0].ReadOnly)
+		// The ignition is not served on the other interfaces of the host
+		assert.Equal(t, config.ProvisioningIP, envValue(server, "IGNITION_OVERRIDES_ADDRESS"))
+	}
+	found := false
+	for _, volume := range podTemplate.Spec.Volumes {
+		if volume.Name == ignitionOverridesVolume {
+			found = true
+			assert.Equal(t, IgnitionOverridesConfigMap, volume.ConfigMap.Name)
+			assert.True(t, *volume.ConfigMap.Optional)
+		}
+	}
+	assert.True(t, found)
+
+	config.ProvisioningIP = ""
+	assert.Error(t, validateIgnitionOverrides(config))
+}
@@ -0,0 +1,277 @@
+package provisioning
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	imageCacheVolume        = "metal3-image-cache"
+	imageCacheMountPath     = "/shared/html/images"
+	imageCacheHostPath      = "/var/lib/metal3/images"
+	imageCacheMetricsPort   = 60003
+	imageCacheMaxSizeEnv    = "IMAGE_CACHE_MAX_SIZE"
+	imageCacheRetentionEnv  = "IMAGE_CACHE_RETENTION"
+	imageCacheCurrentEnv    = "IMAGE_CACHE_CURRENT_IMAGE"
+	imageCacheCheckInterval = 600
+	imageRefreshEnv         = "IMAGE_REFRESH_GENERATION"
+)
+
+var imageCacheMount = corev1.VolumeMount{
+	Name:      imageCacheVolume,
+	MountPath: imageCacheMountPath,
+}
+
+// imageCacheJanitorScript evicts the cached OS images that were not used
+// for longer than the retention, then the least recently used ones while
+// the cache is larger than its maximum size. Only directories holding an
+// image file of the same name, as written by the machine-os-downloader,
+// are considered, and the image in use is always kept. The cache size and
+// the evictions are served in the Prometheus text format.
+const imageCacheJanitorScript = `
+import http.server
+import os
+import shutil
+import threading
+import time
+
+CACHE = "/shared/html/images"
+MAX_SIZE = int(os.environ.get("IMAGE_CACHE_MAX_SIZE", "0"))
+RETENTION = int(os.environ.get("IMAGE_CACHE_RETENTION", "0"))
+CURRENT = os.environ.get("IMAGE_CACHE_CURRENT_IMAGE", "")
+INTERVAL = int(os.environ.get("IMAGE_CACHE_INTERVAL", "600"))
+PORT = int(os.environ.get("METRICS_PORT", "60003"))
+
+lock = threading.Lock()
+state = {"size": 0, "images": 0, "evictions": 0}
+
+
+def cached_images():
+    images = []
+    for name in os.listdir(CACHE):
+        image_dir = os.path.join(CACHE, name)
+        if not os.path.isfile(os.path.join(image_dir, name)):
+            continue
+        size, last_used = 0, 0
+        for root, _, files in os.walk(image_dir):
+            for f in files:
+                st = os.stat(os.path.join(root, f))
+                size += st.st_size
+                last_used = max(last_used, st.st_atime, st.st_mtime)
+        images.append((last_used, size, name, image_dir))
+    return sorted(images)
+
+
+def evict():
+    now = time.time()
+    images = cached_images()
+    total = sum(size for _, size, _, _ in images)
+    evicted = 0
+    for last_used, size, name, image_dir in images:
+        if name == CURRENT:
+            continue
+        expired = RETENTION > 0 and now - last_used > RETENTION
+        oversized = MAX_SIZE > 0 and total > MAX_SIZE
+        if not (expired or oversized):
+            continue
+        print("evicting cached image %s" % name, flush=True)
+        shutil.rmtree(image_dir, ignore_errors=True)
+        total -= size
+        evicted += 1
+    with lock:
+        state["size"] = total
+        state["images"] = len(images) - evicted
+        state["evictions"] += evicted
+
+
+def run():
+    while True:
+        try:
+            evict()
+        except OSError as e:
+            print("image cache eviction failed: %s" % e, flush=True)
+        time.sleep(INTERVAL)
+
+
+class Handler(http.server.BaseHTTPRequestHandler):
+    def do_GET(self):
+        if self.path != "/metrics":
+            self.send_error(404)
+            return
+        with lock:
+            body = (
+                "# HELP metal3_image_cache_size_bytes Disk space used by the cached provisioning OS images.\n"
+                "# TYPE metal3_image_cache_size_bytes gauge\n"
+                "metal3_image_cache_size_bytes %d\n"
+                "# HELP metal3_image_cache_max_size_bytes Disk space the cached images may use, 0 when unlimited.\n"
+                "# TYPE metal3_image_cache_max_size_bytes gauge\n"
+                "metal3_image_cache_max_size_bytes %d\n"
+                "# HELP metal3_image_cache_images Number of cached provisioning OS images.\n"
+                "# TYPE metal3_image_cache_images gauge\n"
+                "metal3_image_cache_images %d\n"
+                "# HELP metal3_image_cache_evictions_total Number of cached images evicted.\n"
+                "# TYPE metal3_image_cache_evictions_total counter\n"
+                "metal3_image_cache_evictions_total %d\n"
+                % (state["size"], MAX_SIZE, state["images"], state["evictions"])
+            ).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "text/plain; version=0.0.4")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, *args):
+        pass
+
+
+threading.Thread(target=run, daemon=True).start()
+http.server.HTTPServer(("", PORT), Handler).serve_forever()
+`
+
+// imageCachePurgeScript empties the image cache of the node when the
+// images were refreshed since it was last purged. The generation it was
+// purged for is kept in the cache, as the nodes are purged as the metal3
+// pod lands on them.
+const imageCachePurgeScript = `set -eu
+marker=/shared/html/images/.refresh-generation
+if [ -z "${IMAGE_REFRESH_GENERATION}" ] || [ "$(cat "${marker}" 2>/dev/null)" = "${IMAGE_REFRESH_GENERATION}" ]; then
+    exit 0
+fi
+echo "purging the image cache for image refresh ${IMAGE_REFRESH_GENERATION}"
+find /shared/html/images -mindepth 1 -maxdepth 1 ! -name .refresh-generation -exec rm -rf {} +
+echo "${IMAGE_REFRESH_GENERATION}" > "${marker}"
+`
+
+// validateImageCache checks the image cache eviction policy
+func validateImageCache(cache *metal3iov1alpha1.ImageCache) error {
+	if cache == nil {
+		return nil
+	}
+	if cache.Size != nil && cache.Size.Sign() <= 0 {
+		return fmt.Errorf("ImageCache.Size must be positive")
+	}
+	if cache.Retention != nil && cache.Retention.Duration <= 0 {
+		return fmt.Errorf("ImageCache.Retention must be positive")
+	}
+	return nil
+}
+
+// newImageCacheVolume returns the volume the OS images are cached in. It
+// outlives the metal3 pod, so that images are only downloaded once on
+// each node.
+func newImageCacheVolume() corev1.Volume {
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	return corev1.Volume{
+		Name: imageCacheVolume,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: imageCacheHostPath,
+				Type: &hostPathType,
+			},
+		},
+	}
+}
+
+// addImageCacheMounts mounts the image cache over the images directory of
+// the shared volume, in every container using the shared volume
+func addImageCacheMounts(containerLists ...[]corev1.Container) {
+	for _, list := range containerLists {
+		for i := range list {
+			for _, mount := range list[i].VolumeMounts {
+				if mount.Name == baremetalSharedVolume {
+					list[i].VolumeMounts = append(list[i].VolumeMounts, imageCacheMount)
+					break
+				}
+			}
+		}
+	}
+}
+
+// getCurrentCachedImage returns the name of the cached image in use
+func getCurrentCachedImage(config *metal3iov1alpha1.ProvisioningSpec) string {
+	subPath := getCachedOSImageSubPath(config)
+	if subPath == "" {
+		return ""
+	}
+	return path.Base(path.Dir(subPath))
+}
+
+func createContainerMetal3ImageCacheJanitor(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	maxSize, retention := int64(0), int64(0)
+	if config.ImageCache.Size != nil {
+		maxSize = config.ImageCache.Size.Value()
+	}
+	if config.ImageCache.Retention != nil {
+		retention = int64(config.ImageCache.Retention.Seconds())
+	}
+	return corev1.Container{
+		Name:            "metal3-image-cache-janitor",
+		Image:           images.BaremetalIronic,
+		Command:         []string{"python3", "-c", imageCacheJanitorScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          "cache-metrics",
+				ContainerPort: imageCacheMetricsPort,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{imageCacheMount},
+		Env: []corev1.EnvVar{
+			{
+				Name:  imageCacheMaxSizeEnv,
+				Value: strconv.FormatInt(maxSize, 10),
+			},
+			{
+				Name:  imageCacheRetentionEnv,
+				Value: strconv.FormatInt(retention, 10),
+			},
+			{
+				Name:  imageCacheCurrentEnv,
+				Value: getCurrentCachedImage(config),
+			},
+			{
+				Name:  "IMAGE_CACHE_INTERVAL",
+				Value: strconv.Itoa(imageCacheCheckInterval),
+			},
+			{
+				Name:  "METRICS_PORT",
+				Value: strconv.Itoa(imageCacheMetricsPort),
+			},
+		},
+	}
+}
+
+// createInitContainerImageCachePurge returns the init container emptying
+// the image cache when the images are refreshed. It reads the refresh
+// generation of the pod template, so it has to run before the downloaders.
+func createInitContainerImageCachePurge(images *Images) corev1.Container {
+	return corev1.Container{
+		Name:            "metal3-image-cache-purge",
+		Image:           images.BaremetalIronic,
+		Command:         []string{"/bin/bash", "-c", imageCachePurgeScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		VolumeMounts: []corev1.VolumeMount{imageCacheMount},
+		Env: []corev1.EnvVar{
+			{
+				Name: imageRefreshEnv,
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{
+						FieldPath: fmt.Sprintf("metadata.annotations['%s']", ImageRefreshGenerationAnnotation),
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,106 @@
+package provisioning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateImageCache(t *testing.T) {
+	size := resource.MustParse("50Gi")
+	zero := resource.MustParse("0")
+	tCases := []struct {
+		name        string
+		cache       *metal3iov1alpha1.ImageCache
+		expectedErr string
+	}{
+		{name: "NotSet"},
+		{name: "Empty", cache: &metal3iov1alpha1.ImageCache{}},
+		{
+			name:  "Valid",
+			cache: &metal3iov1alpha1.ImageCache{Size: &size, Retention: &metav1.Duration{Duration: 24 * time.Hour}},
+		},
+		{
+			name:        "ZeroSize",
+			cache:       &metal3iov1alpha1.ImageCache{Size: &zero},
+			expectedErr: "ImageCache.Size",
+		},
+		{
+			name:        "NegativeRetention",
+			cache:       &metal3iov1alpha1.ImageCache{Retention: &metav1.Duration{Duration: -time.Hour}},
+			expectedErr: "ImageCache.Retention",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateImageCache(tc.cache)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestImageCacheJanitor(t *testing.T) {
+	size := resource.MustParse("10Gi")
+	config := managedProvisioning()
+	config.ImageCache = &metal3iov1alpha1.ImageCache{
+		Size:      &size,
+		Retention: &metav1.Duration{Duration: 2 * time.Hour},
+	}
+
+	podSpec := newMetal3PodTemplateSpec(&testImages, config).Spec
+	janitor := findContainer(podSpec.Containers, "metal3-image-cache-janitor")
+	if !assert.NotNil(t, janitor) {
+		return
+	}
+	assert.Equal(t, "10737418240", envValue(janitor, imageCacheMaxSizeEnv))
+	assert.Equal(t, "7200", envValue(janitor, imageCacheRetentionEnv))
+	assert.Equal(t, "rhcos-44.81.202001171431.0-openstack.x86_64.qcow2", envValue(janitor, imageCacheCurrentEnv))
+
+	// The cache is purged before anything is downloaded to it
+	purge := findContainer(podSpec.InitContainers, "metal3-image-cache-purge")
+	if !assert.NotNil(t, purge) {
+		return
+	}
+	assert.Equal(t, []string{"metal3-static-ip-set", "metal3-image-cache-purge"}, containerNames(podSpec.InitContainers[:2]))
+	assert.Equal(t, []corev1.VolumeMount{imageCacheMount}, purge.VolumeMounts)
+	if assert.Len(t, purge.Env, 1) {
+		assert.Equal(t, "metadata.annotations['baremetal.openshift.io/image-refresh-generation']", purge.Env[0].ValueFrom.FieldRef.FieldPath)
+	}
+
+	volumeNames := []string{}
+	for _, v := range podSpec.Volumes {
+		volumeNames = append(volumeNames, v.Name)
+	}
+	assert.Contains(t, volumeNames, imageCacheVolume)
+
+	// Every container using the shared volume sees the cache
+	for _, c := range append(podSpec.InitContainers, podSpec.Containers...) {
+		shared, cached := false, false
+		for _, m := range c.VolumeMounts {
+			shared = shared || m.Name == baremetalSharedVolume
+			cached = cached || m.Name == imageCacheVolume
+		}
+		assert.Equal(t, shared, cached && c.Name != janitor.Name && c.Name != purge.Name, "container %s", c.Name)
+	}
+}
+
+func TestNoImageCache(t *testing.T) {
+	podSpec := newMetal3PodTemplateSpec(&testImages, managedProvisioning()).Spec
+	assert.Nil(t, findContainer(podSpec.Containers, "metal3-image-cache-janitor"))
+	assert.Nil(t, findContainer(podSpec.InitContainers, "metal3-image-cache-purge"))
+	for _, v := range podSpec.Volumes {
+		assert.NotEqual(t, imageCacheVolume, v.Name)
+	}
+}
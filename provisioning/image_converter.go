@@ -0,0 +1,24 @@
+package provisioning
+
+// rawImageConverterScript converts every cached qcow2 OS image that has
+// not been converted yet to the raw format. The raw image is written
+// next to the qcow2 image, so it is served by the metal3 httpd under the
+// same path with a .raw suffix, together with .raw.sha256sum and
+// .raw.md5sum checksum files. Conversion goes through a temporary file
+// so that a restart never leaves a truncated image being served.
+const rawImageConverterScript = `set -euo pipefail
+shopt -s nullglob
+for qcow in /shared/html/images/*/*.qcow2; do
+    raw="${qcow}.raw"
+    if [ -f "${raw}.sha256sum" ]; then
+        echo "${raw} already converted"
+        continue
+    fi
+    echo "converting ${qcow} to ${raw}"
+    qemu-img convert -O raw "${qcow}" "${raw}.tmp"
+    sha256sum "${raw}.tmp" | cut -d ' ' -f 1 > "${raw}.sha256sum.tmp"
+    md5sum "${raw}.tmp" | cut -d ' ' -f 1 > "${raw}.md5sum"
+    mv "${raw}.tmp" "${raw}"
+    mv "${raw}.sha256sum.tmp" "${raw}.sha256sum"
+done
+`
@@ -0,0 +1,147 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	imagePrePullAppName       = "metal3-image-prepull"
+	imagePrePullDaemonSetName = "metal3-image-prepull"
+)
+
+// GetPodTemplateImages returns the sorted, distinct images of the
+// containers of a pod template
+func GetPodTemplateImages(template *corev1.PodTemplateSpec) []string {
+	seen := map[string]bool{}
+	images := []string{}
+	for _, list := range [][]corev1.Container{template.Spec.InitContainers, template.Spec.Containers} {
+		for _, c := range list {
+			if !seen[c.Image] {
+				seen[c.Image] = true
+				images = append(images, c.Image)
+			}
+		}
+	}
+	sort.Strings(images)
+	return images
+}
+
+// GetNewImages returns the images of the desired pod template that the
+// current one does not use yet
+func GetNewImages(desired *corev1.PodTemplateSpec, current *corev1.PodTemplateSpec) []string {
+	currentImages := map[string]bool{}
+	for _, image := range GetPodTemplateImages(current) {
+		currentImages[image] = true
+	}
+	newImages := []string{}
+	for _, image := range GetPodTemplateImages(desired) {
+		if !currentImages[image] {
+			newImages = append(newImages, image)
+		}
+	}
+	return newImages
+}
+
+// NewImagePrePullDaemonSet returns a DaemonSet pulling the given images on
+// every node the metal3 pods may run on. Each image is pulled by an init
+// container that exits immediately, then the pod idles until the
+// DaemonSet is deleted, so that it becomes ready once all images are
+// pulled.
+func NewImagePrePullDaemonSet(targetNamespace string, images *Images, config *metal3iov1alpha1.ProvisioningSpec, prePull []string) *appsv1.DaemonSet {
+	initContainers := []corev1.Container{}
+	for i, image := range prePull {
+		initContainers = append(initContainers, corev1.Container{
+			Name:            fmt.Sprintf("pull-%d", i),
+			Image:           image,
+			Command:         []string{"/bin/sh", "-c", "exit 0"},
+			ImagePullPolicy: "IfNotPresent",
+		})
+	}
+	labels := map[string]string{
+		"k8s-app": imagePrePullAppName,
+	}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      imagePrePullDaemonSetName,
+			Namespace: targetNamespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: initContainers,
+					Containers: []corev1.Container{
+						{
+							Name:            imagePrePullAppName,
+							Image:           images.BaremetalIronic,
+							Command:         []string{"/bin/sh", "-c", "trap 'exit 0' TERM; sleep infinity & wait"},
+							ImagePullPolicy: "IfNotPresent",
+						},
+					},
+					NodeSelector:                  GetMetal3NodeSelector(config),
+					PriorityClassName:             metal3PriorityClassName,
+					ServiceAccountName:            serviceAccountName,
+					TerminationGracePeriodSeconds: pointer.Int64Ptr(0),
+					Tolerations:                   newMetal3Tolerations(config),
+				},
+			},
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
+
+// ApplyImagePrePullDaemonSet creates or updates the image pre-pull
+// DaemonSet. It returns true when the DaemonSet was created or updated.
+func ApplyImagePrePullDaemonSet(client appsclientv1.DaemonSetsGetter, daemonSet *appsv1.DaemonSet) (bool, error) {
+	return ApplyDnsmasqDaemonSet(client, daemonSet)
+}
+
+// IsImagePrePullComplete returns true once every pod of the up to date
+// pre-pull DaemonSet is ready, meaning that its images were pulled. A
+// DaemonSet not yet observed by its controller is never complete.
+func IsImagePrePullComplete(daemonSet *appsv1.DaemonSet) bool {
+	status := daemonSet.Status
+	return status.ObservedGeneration > 0 &&
+		status.ObservedGeneration >= daemonSet.Generation &&
+		status.UpdatedNumberScheduled == status.DesiredNumberScheduled &&
+		status.NumberReady == status.DesiredNumberScheduled
+}
+
+// GetImagePrePullDaemonSet returns the image pre-pull DaemonSet, or nil
+// when it does not exist
+func GetImagePrePullDaemonSet(client appsclientv1.DaemonSetsGetter, targetNamespace string) (*appsv1.DaemonSet, error) {
+	daemonSet, err := client.DaemonSets(targetNamespace).Get(context.Background(), imagePrePullDaemonSetName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return daemonSet, err
+}
+
+// DeleteImagePrePullDaemonSet removes the image pre-pull DaemonSet, if it
+// exists
+func DeleteImagePrePullDaemonSet(client appsclientv1.DaemonSetsGetter, targetNamespace string) error {
+	err := client.DaemonSets(targetNamespace).Delete(context.Background(), imagePrePullDaemonSetName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
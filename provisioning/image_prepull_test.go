@@ -0,0 +1,86 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func podTemplate(images ...string) *corev1.PodTemplateSpec {
+	template := &corev1.PodTemplateSpec{}
+	for _, image := range images {
+		template.Spec.Containers = append(template.Spec.Containers, corev1.Container{Image: image})
+	}
+	return template
+}
+
+func TestGetNewImages(t *testing.T) {
+	tCases := []struct {
+		name     string
+		desired  *corev1.PodTemplateSpec
+		current  *corev1.PodTemplateSpec
+		expected []string
+	}{
+		{
+			name:     "Unchanged",
+			desired:  podTemplate("ironic:1", "inspector:1"),
+			current:  podTemplate("inspector:1", "ironic:1"),
+			expected: []string{},
+		},
+		{
+			name:     "Upgraded",
+			desired:  podTemplate("ironic:2", "inspector:2", "ironic:2", "static-ip:1"),
+			current:  podTemplate("ironic:1", "inspector:1", "static-ip:1"),
+			expected: []string{"inspector:2", "ironic:2"},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, GetNewImages(tc.desired, tc.current))
+		})
+	}
+}
+
+func TestNewImagePrePullDaemonSet(t *testing.T) {
+	daemonSet := NewImagePrePullDaemonSet(testNamespace, &testImages, managedProvisioning(), []string{"ironic:2", "inspector:2"})
+
+	assert.Equal(t, "metal3-image-prepull", daemonSet.Name)
+	if assert.Len(t, daemonSet.Spec.Template.Spec.InitContainers, 2) {
+		assert.Equal(t, "pull-0", daemonSet.Spec.Template.Spec.InitContainers[0].Name)
+		assert.Equal(t, "ironic:2", daemonSet.Spec.Template.Spec.InitContainers[0].Image)
+		assert.Equal(t, "inspector:2", daemonSet.Spec.Template.Spec.InitContainers[1].Image)
+	}
+	assert.Equal(t, testImages.BaremetalIronic, daemonSet.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, map[string]string{"node-role.kubernetes.io/master": ""}, daemonSet.Spec.Template.Spec.NodeSelector)
+}
+
+func TestIsImagePrePullComplete(t *testing.T) {
+	tCases := []struct {
+		name     string
+		status   appsv1.DaemonSetStatus
+		expected bool
+	}{
+		{
+			name:     "Pulled",
+			status:   appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 3},
+			expected: true,
+		},
+		{
+			name:   "Pulling",
+			status: appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 1},
+		},
+		{
+			name:   "NotObserved",
+			status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 3},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			daemonSet := &appsv1.DaemonSet{Status: tc.status}
+			daemonSet.Generation = 1
+			assert.Equal(t, tc.expected, IsImagePrePullComplete(daemonSet))
+		})
+	}
+}
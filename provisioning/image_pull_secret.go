@@ -0,0 +1,251 @@
+package provisioning
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ImagePullSecretHashAnnotation records on the pod templates running
+	// the downloaders the credentials they were started with, so that they
+	// download again when the credentials are rotated
+	ImagePullSecretHashAnnotation = "baremetal.openshift.io/image-pull-secret-hash"
+
+	imagePullSecretVolume    = "metal3-image-pull-secret"
+	imagePullSecretMountPath = "/run/secrets/metal3-image-pull"
+	// imagePullCredentialsPrefix names the Secret holding the credentials
+	// of an ImagePullSecretRef in the formats of the downloaders
+	imagePullCredentialsPrefix = "metal3-image-pull-"
+	// registryAuthFileEnv is where the downloaders read the credentials of
+	// the registries they pull from
+	registryAuthFileEnv = "REGISTRY_AUTH_FILE"
+	// curlHomeEnv is where curl reads its .curlrc, which points it at the
+	// netrc file holding the credentials of the HTTP servers
+	curlHomeEnv   = "CURL_HOME"
+	curlrcKey     = ".curlrc"
+	netrcKey      = "netrc"
+	maxSecretName = 253
+)
+
+// imagePullCredential is an entry of the auths of a dockerconfigjson
+type imagePullCredential struct {
+	Auth     string `json:"auth"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// validateImagePullSecretRef checks the reference to the download
+// credentials, the Secret itself being checked by the controller
+func validateImagePullSecretRef(ref *metal3iov1alpha1.ImagePullSecretRef) error {
+	if ref != nil && ref.Name == "" {
+		return fmt.Errorf("ImagePullSecretRef.Name is required")
+	}
+	if ref != nil && len(ImagePullCredentialsSecretName(ref)) > maxSecretName {
+		return fmt.Errorf("ImagePullSecretRef.Name must be at most %d characters", maxSecretName-len(imagePullCredentialsPrefix))
+	}
+	return nil
+}
+
+// ImagePullCredentialsSecretName returns the name of the Secret holding
+// the credentials of an ImagePullSecretRef in the formats of the
+// downloaders
+func ImagePullCredentialsSecretName(ref *metal3iov1alpha1.ImagePullSecretRef) string {
+	return imagePullCredentialsPrefix + ref.Name
+}
+
+// netrcHost returns the host curl matches the credentials of a
+// dockerconfigjson entry against, which may be a URL or a host with a
+// port
+func netrcHost(registry string) string {
+	if !strings.Contains(registry, "://") {
+		registry = "https://" + registry
+	}
+	registryURL, err := url.Parse(registry)
+	if err != nil {
+		return ""
+	}
+	return registryURL.Hostname()
+}
+
+// decodeImagePullCredential returns the login and password of a
+// dockerconfigjson entry
+func decodeImagePullCredential(credential imagePullCredential) (string, string, error) {
+	if credential.Auth == "" {
+		return credential.Username, credential.Password, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(credential.Auth)
+	if err != nil {
+		return "", "", fmt.Errorf("auth is not base64 encoded")
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("auth is not a login and password")
+	}
+	return parts[0], parts[1], nil
+}
+
+// getNetrc returns the credentials of a dockerconfigjson as a netrc
+// file, sorted by host
+func getNetrc(data []byte) ([]byte, error) {
+	config := struct {
+		Auths map[string]imagePullCredential `json:"auths"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	hosts := []string{}
+	for registry := range config.Auths {
+		hosts = append(hosts, registry)
+	}
+	sort.Strings(hosts)
+	lines := []string{}
+	for _, registry := range hosts {
+		host := netrcHost(registry)
+		if host == "" {
+			return nil, fmt.Errorf("invalid host %q", registry)
+		}
+		login, password, err := decodeImagePullCredential(config.Auths[registry])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", registry, err)
+		}
+		// netrc tokens are separated by whitespace, and curl only
+		// understands quoted ones in its recent releases
+		if login == "" || strings.ContainsAny(login+password, " \t\n\"") {
+			return nil, fmt.Errorf("%s: the login is empty, or the credentials hold whitespace or quotes", registry)
+		}
+		lines = append(lines, fmt.Sprintf("machine %s login %s password %s\n", host, login, password))
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
+
+// ValidateImagePullSecret checks that the Secret holds credentials in the
+// format of the pull secrets, which can be passed to curl
+func ValidateImagePullSecret(secret *corev1.Secret) error {
+	data, ok := secret.Data[corev1.DockerConfigJsonKey]
+	if !ok {
+		return fmt.Errorf("ImagePullSecretRef Secret %s has no %s key", secret.Name, corev1.DockerConfigJsonKey)
+	}
+	config := struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("ImagePullSecretRef Secret %s: invalid %s: %v", secret.Name, corev1.DockerConfigJsonKey, err)
+	}
+	if len(config.Auths) == 0 {
+		return fmt.Errorf("ImagePullSecretRef Secret %s holds no credentials", secret.Name)
+	}
+	if _, err := getNetrc(data); err != nil {
+		return fmt.Errorf("ImagePullSecretRef Secret %s: invalid credentials for %v", secret.Name, err)
+	}
+	return nil
+}
+
+// NewImagePullCredentialsSecret returns the Secret passing the credentials
+// of an ImagePullSecretRef to the downloaders: the dockerconfigjson for
+// the registries, and a netrc file for the HTTP servers curl downloads
+// from
+func NewImagePullCredentialsSecret(targetNamespace string, ref *metal3iov1alpha1.ImagePullSecretRef, secret *corev1.Secret) (*corev1.Secret, error) {
+	netrc, err := getNetrc(secret.Data[corev1.DockerConfigJsonKey])
+	if err != nil {
+		return nil, err
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ImagePullCredentialsSecretName(ref),
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: secret.Data[corev1.DockerConfigJsonKey],
+			netrcKey:                   netrc,
+			curlrcKey:                  []byte(fmt.Sprintf("netrc-file = \"%s\"\n", path.Join(imagePullSecretMountPath, netrcKey))),
+		},
+	}, nil
+}
+
+// ApplyImagePullCredentialsSecret creates or updates the Secret passing
+// the credentials of an ImagePullSecretRef to the downloaders
+func ApplyImagePullCredentialsSecret(client coreclientv1.SecretsGetter, secret *corev1.Secret) error {
+	existing, err := client.Secrets(secret.Namespace).Get(context.Background(), secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Secrets(secret.Namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+		return apiError(err)
+	}
+	if err != nil {
+		return apiError(err)
+	}
+	if equality.Semantic.DeepEqual(secret.Data, existing.Data) {
+		return nil
+	}
+	updated := existing.DeepCopy()
+	updated.Labels = secret.Labels
+	updated.Data = secret.Data
+	_, err = client.Secrets(secret.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	return apiError(err)
+}
+
+// GetImagePullSecretHash returns a hash of the download credentials
+func GetImagePullSecretHash(secret *corev1.Secret) string {
+	hash := sha256.Sum256(secret.Data[corev1.DockerConfigJsonKey])
+	return hex.EncodeToString(hash[:])[:16]
+}
+
+// SetImagePullSecretHash records the download credentials hash on a pod
+// template running the downloaders
+func SetImagePullSecretHash(template *corev1.PodTemplateSpec, hash string) {
+	metav1.SetMetaDataAnnotation(&template.ObjectMeta, ImagePullSecretHashAnnotation, hash)
+}
+
+func newImagePullSecretVolume(ref *metal3iov1alpha1.ImagePullSecretRef) corev1.Volume {
+	return corev1.Volume{
+		Name: imagePullSecretVolume,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: ImagePullCredentialsSecretName(ref),
+			},
+		},
+	}
+}
+
+// addImagePullSecret passes the download credentials to a downloader.
+// curl finds them through the .curlrc of its CURL_HOME, so that the
+// downloads of the machine-os-downloader and of the operator scripts are
+// authenticated alike.
+func addImagePullSecret(container *corev1.Container, config *metal3iov1alpha1.ProvisioningSpec) {
+	if config.ImagePullSecretRef == nil {
+		return
+	}
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      imagePullSecretVolume,
+		MountPath: imagePullSecretMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env,
+		corev1.EnvVar{
+			Name:  registryAuthFileEnv,
+			Value: path.Join(imagePullSecretMountPath, corev1.DockerConfigJsonKey),
+		},
+		corev1.EnvVar{
+			Name:  curlHomeEnv,
+			Value: imagePullSecretMountPath,
+		},
+	)
+}
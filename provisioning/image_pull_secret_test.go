@@ -0,0 +1,150 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateImagePullSecret(t *testing.T) {
+	tCases := []struct {
+		name          string
+		data          map[string][]byte
+		expectedError string
+	}{
+		{
+			name: "Valid",
+			data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths": {"mirror.example.com": {"auth": "dXNlcjpwYXNz"}}}`),
+			},
+		},
+		{
+			name:          "NoDockerConfig",
+			data:          map[string][]byte{".netrc": []byte("machine mirror.example.com login user password pass")},
+			expectedError: "ImagePullSecretRef Secret mirror-pull-secret has no .dockerconfigjson key",
+		},
+		{
+			name:          "NotJSON",
+			data:          map[string][]byte{corev1.DockerConfigJsonKey: []byte("user:pass")},
+			expectedError: "ImagePullSecretRef Secret mirror-pull-secret: invalid .dockerconfigjson: invalid character 'u' looking for beginning of value",
+		},
+		{
+			name:          "NoCredentials",
+			data:          map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths": {}}`)},
+			expectedError: "ImagePullSecretRef Secret mirror-pull-secret holds no credentials",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateImagePullSecret(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "mirror-pull-secret"},
+				Data:       tc.data,
+			})
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+
+	assert.EqualError(t, validateImagePullSecretRef(&metal3iov1alpha1.ImagePullSecretRef{}), "ImagePullSecretRef.Name is required")
+}
+
+func TestGetImagePullSecretHash(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{
+		corev1.DockerConfigJsonKey: []byte(`{"auths": {"mirror.example.com": {"auth": "dXNlcjpwYXNz"}}}`),
+	}}
+	hash := GetImagePullSecretHash(secret)
+	assert.Len(t, hash, 16)
+
+	rotated := secret.DeepCopy()
+	rotated.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths": {"mirror.example.com": {"auth": "dXNlcjpyb3RhdGVk"}}}`)
+	assert.NotEqual(t, hash, GetImagePullSecretHash(rotated))
+}
+
+func TestImagePullSecretMounts(t *testing.T) {
+	config := managedProvisioning()
+	config.ImagePullSecretRef = &metal3iov1alpha1.ImagePullSecretRef{Name: "mirror-pull-secret"}
+
+	deployment := NewMetal3Deployment(testNamespace, &testImages, config)
+	podSpec := deployment.Spec.Template.Spec
+	var volume *corev1.Volume
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == imagePullSecretVolume {
+			volume = &podSpec.Volumes[i]
+		}
+	}
+	if assert.NotNil(t, volume) && assert.NotNil(t, volume.Secret) {
+		assert.Equal(t, "metal3-image-pull-mirror-pull-secret", volume.Secret.SecretName)
+	}
+
+	containers := append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	for _, name := range []string{"metal3-machine-os-downloader", "metal3-ipa-downloader"} {
+		downloader := findContainer(containers, name)
+		if !assert.NotNil(t, downloader, name) {
+			continue
+		}
+		assert.Equal(t, "/run/secrets/metal3-image-pull/.dockerconfigjson", envValue(downloader, registryAuthFileEnv), name)
+		assert.Equal(t, "/run/secrets/metal3-image-pull", envValue(downloader, curlHomeEnv), name)
+		assert.Contains(t, downloader.VolumeMounts, corev1.VolumeMount{
+			Name:      imagePullSecretVolume,
+			MountPath: imagePullSecretMountPath,
+			ReadOnly:  true,
+		}, name)
+	}
+	assert.Empty(t, envValue(findContainer(containers, "metal3-httpd"), registryAuthFileEnv))
+
+	config.ImagePullSecretRef = nil
+	podSpec = NewMetal3Deployment(testNamespace, &testImages, config).Spec.Template.Spec
+	containers = append(append([]corev1.Container{}, podSpec.InitContainers...), podSpec.Containers...)
+	if downloader := findContainer(containers, "metal3-machine-os-downloader"); assert.NotNil(t, downloader) {
+		assert.Empty(t, envValue(downloader, registryAuthFileEnv))
+	}
+}
+
+func TestImagePullCredentialsSecret(t *testing.T) {
+	ref := &metal3iov1alpha1.ImagePullSecretRef{Name: "mirror-pull-secret"}
+	secret := &corev1.Secret{Data: map[string][]byte{
+		corev1.DockerConfigJsonKey: []byte(`{"auths": {
+			"mirror.example.com:5000": {"auth": "dXNlcjpwYXNz"},
+			"https://artifacts.example.com/rhcos/": {"username": "robot", "password": "p@ss:word"}
+		}}`),
+	}}
+
+	credentials, err := NewImagePullCredentialsSecret(testNamespace, ref, secret)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "metal3-image-pull-mirror-pull-secret", credentials.Name)
+	assert.Equal(t, secret.Data[corev1.DockerConfigJsonKey], credentials.Data[corev1.DockerConfigJsonKey])
+	assert.Equal(t, "machine artifacts.example.com login robot password p@ss:word\n"+
+		"machine mirror.example.com login user password pass\n", string(credentials.Data[netrcKey]))
+	assert.Equal(t, `netrc-file = "/run/secrets/metal3-image-pull/netrc"`+"\n", string(credentials.Data[curlrcKey]))
+
+	secret.Data[corev1.DockerConfigJsonKey] = []byte(`{"auths": {"mirror.example.com": {"auth": "not base64"}}}`)
+	_, err = NewImagePullCredentialsSecret(testNamespace, ref, secret)
+	assert.EqualError(t, err, "mirror.example.com: auth is not base64 encoded")
+}
+
+func TestImagePullSecretImageCache(t *testing.T) {
+	// The cached image is downloaded by the operator script, whose curl
+	// finds the credentials through its CURL_HOME too
+	config := deltaProvisioning()
+	config.ImagePullSecretRef = &metal3iov1alpha1.ImagePullSecretRef{Name: "mirror-pull-secret"}
+	assert.NoError(t, NormalizeProvisioningSpec(config))
+
+	downloader := createInitContainerMachineOsDownloader(&testImages, config)
+	assert.Equal(t, []string{"/bin/sh", "-c", osImageZstdScript}, downloader.Command)
+	assert.Contains(t, osImageZstdScript, "curl --fail")
+	assert.Equal(t, imagePullSecretMountPath, envValue(&downloader, curlHomeEnv))
+	assert.Contains(t, downloader.VolumeMounts, corev1.VolumeMount{
+		Name:      imagePullSecretVolume,
+		MountPath: imagePullSecretMountPath,
+		ReadOnly:  true,
+	})
+}
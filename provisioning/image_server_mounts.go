@@ -0,0 +1,106 @@
+package provisioning
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const imageServerRoot = "/shared/html"
+
+// imageServerReservedPaths are the paths of the image server root written
+// by the metal3 containers, which extra mounts would hide
+var imageServerReservedPaths = []string{
+	"images",
+	"boot.ipxe",
+	"inspector.ipxe",
+	"redfish",
+	"ilo",
+}
+
+// overlaps tells whether one of the paths is the other or one of its
+// parent directories
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// validateImageServerMounts checks that the extra image server mounts
+// reference exactly one volume each, and that their paths are distinct
+// and do not hide the files of the image server
+func validateImageServerMounts(mounts []metal3iov1alpha1.ImageServerMount) error {
+	for i, mount := range mounts {
+		if mount.Path == "" || path.IsAbs(mount.Path) || path.Clean(mount.Path) != mount.Path ||
+			mount.Path == "." || mount.Path == ".." || strings.HasPrefix(mount.Path, "../") {
+			return fmt.Errorf("ImageServerMounts path %q must be a relative path inside the image server root", mount.Path)
+		}
+		switch {
+		case mount.ConfigMapName != "" && mount.PersistentVolumeClaimName != "":
+			return fmt.Errorf("ImageServerMounts path %q must reference either a ConfigMap or a PersistentVolumeClaim, not both", mount.Path)
+		case mount.ConfigMapName != "":
+			if errs := validation.IsDNS1123Subdomain(mount.ConfigMapName); len(errs) > 0 {
+				return fmt.Errorf("ImageServerMounts path %q: invalid ConfigMap name %q: %s", mount.Path, mount.ConfigMapName, strings.Join(errs, ", "))
+			}
+		case mount.PersistentVolumeClaimName != "":
+			if errs := validation.IsDNS1123Subdomain(mount.PersistentVolumeClaimName); len(errs) > 0 {
+				return fmt.Errorf("ImageServerMounts path %q: invalid PersistentVolumeClaim name %q: %s", mount.Path, mount.PersistentVolumeClaimName, strings.Join(errs, ", "))
+			}
+		default:
+			return fmt.Errorf("ImageServerMounts path %q must reference a ConfigMap or a PersistentVolumeClaim", mount.Path)
+		}
+		for _, reserved := range imageServerReservedPaths {
+			if overlaps(mount.Path, reserved) {
+				return fmt.Errorf("ImageServerMounts path %q collides with %q, which is used by the image server", mount.Path, reserved)
+			}
+		}
+		for _, other := range mounts[:i] {
+			if overlaps(mount.Path, other.Path) {
+				return fmt.Errorf("ImageServerMounts paths %q and %q collide", other.Path, mount.Path)
+			}
+		}
+	}
+	return nil
+}
+
+func imageServerMountVolumeName(index int) string {
+	return fmt.Sprintf("metal3-image-server-mount-%d", index)
+}
+
+// newImageServerMountVolumes returns the volumes of the extra image server
+// mounts
+func newImageServerMountVolumes(mounts []metal3iov1alpha1.ImageServerMount) []corev1.Volume {
+	volumes := []corev1.Volume{}
+	for i, mount := range mounts {
+		volume := corev1.Volume{Name: imageServerMountVolumeName(i)}
+		if mount.ConfigMapName != "" {
+			volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: mount.ConfigMapName},
+			}
+		} else {
+			volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: mount.PersistentVolumeClaimName,
+				ReadOnly:  true,
+			}
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// newImageServerVolumeMounts mounts the extra image server volumes in the
+// httpd container, over the shared volume
+func newImageServerVolumeMounts(mounts []metal3iov1alpha1.ImageServerMount) []corev1.VolumeMount {
+	volumeMounts := []corev1.VolumeMount{}
+	for i, mount := range mounts {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      imageServerMountVolumeName(i),
+			MountPath: path.Join(imageServerRoot, mount.Path),
+			ReadOnly:  true,
+		})
+	}
+	return volumeMounts
+}
@@ -0,0 +1,134 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateImageServerMounts(t *testing.T) {
+	tCases := []struct {
+		name        string
+		mounts      []metal3iov1alpha1.ImageServerMount
+		expectedErr string
+	}{
+		{name: "NotSet"},
+		{
+			name: "Valid",
+			mounts: []metal3iov1alpha1.ImageServerMount{
+				{Path: "firmware/vendor", PersistentVolumeClaimName: "firmware"},
+				{Path: "firmware/vendor-extra", ConfigMapName: "extra"},
+				{Path: "ignition", ConfigMapName: "ignition"},
+			},
+		},
+		{
+			name:        "NoVolume",
+			mounts:      []metal3iov1alpha1.ImageServerMount{{Path: "firmware"}},
+			expectedErr: "must reference a ConfigMap or a PersistentVolumeClaim",
+		},
+		{
+			name:        "BothVolumes",
+			mounts:      []metal3iov1alpha1.ImageServerMount{{Path: "firmware", ConfigMapName: "a", PersistentVolumeClaimName: "b"}},
+			expectedErr: "not both",
+		},
+		{
+			name:        "InvalidName",
+			mounts:      []metal3iov1alpha1.ImageServerMount{{Path: "firmware", ConfigMapName: "Firmware_Files"}},
+			expectedErr: "invalid ConfigMap name",
+		},
+		{
+			name:        "AbsolutePath",
+			mounts:      []metal3iov1alpha1.ImageServerMount{{Path: "/etc", ConfigMapName: "a"}},
+			expectedErr: "relative path",
+		},
+		{
+			name:        "ParentPath",
+			mounts:      []metal3iov1alpha1.ImageServerMount{{Path: "../etc", ConfigMapName: "a"}},
+			expectedErr: "relative path",
+		},
+		{
+			name:        "UncleanPath",
+			mounts:      []metal3iov1alpha1.ImageServerMount{{Path: "firmware/../images", ConfigMapName: "a"}},
+			expectedErr: "relative path",
+		},
+		{
+			name:        "ReservedPath",
+			mounts:      []metal3iov1alpha1.ImageServerMount{{Path: "images/firmware", ConfigMapName: "a"}},
+			expectedErr: `collides with "images"`,
+		},
+		{
+			name: "SamePath",
+			mounts: []metal3iov1alpha1.ImageServerMount{
+				{Path: "firmware", ConfigMapName: "a"},
+				{Path: "firmware", ConfigMapName: "b"},
+			},
+			expectedErr: `paths "firmware" and "firmware" collide`,
+		},
+		{
+			name: "NestedPath",
+			mounts: []metal3iov1alpha1.ImageServerMount{
+				{Path: "firmware/vendor", ConfigMapName: "a"},
+				{Path: "firmware", PersistentVolumeClaimName: "b"},
+			},
+			expectedErr: `paths "firmware/vendor" and "firmware" collide`,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateImageServerMounts(tc.mounts)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestImageServerMounts(t *testing.T) {
+	config := managedProvisioning()
+	config.ImageServerMounts = []metal3iov1alpha1.ImageServerMount{
+		{Path: "firmware", PersistentVolumeClaimName: "firmware"},
+		{Path: "ignition/custom", ConfigMapName: "ignition"},
+	}
+	podTemplate := newMetal3PodTemplateSpec(&testImages, config)
+
+	volumes := map[string]bool{}
+	for _, volume := range podTemplate.Spec.Volumes {
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			assert.Equal(t, "firmware", volume.PersistentVolumeClaim.ClaimName)
+			assert.True(t, volume.PersistentVolumeClaim.ReadOnly)
+			volumes[volume.Name] = true
+		case volume.ConfigMap != nil && volume.ConfigMap.Name == "ignition":
+			volumes[volume.Name] = true
+		}
+	}
+	assert.Len(t, volumes, 2)
+
+	mountPaths := map[string]string{}
+	httpd := findContainer(podTemplate.Spec.Containers, "metal3-httpd")
+	if assert.NotNil(t, httpd) {
+		for _, mount := range httpd.VolumeMounts {
+			if volumes[mount.Name] {
+				assert.True(t, mount.ReadOnly)
+				mountPaths[mount.MountPath] = mount.Name
+			}
+		}
+	}
+	assert.Contains(t, mountPaths, "/shared/html/firmware")
+	assert.Contains(t, mountPaths, "/shared/html/ignition/custom")
+
+	for _, container := range podTemplate.Spec.Containers {
+		if container.Name == "metal3-httpd" {
+			continue
+		}
+		for _, mount := range container.VolumeMounts {
+			assert.False(t, volumes[mount.Name], "%s mounts %s", container.Name, mount.Name)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	inspectorRulesVolume    = "metal3-inspector-rules"
+	inspectorRulesMountPath = "/etc/inspector-rules"
+	defaultInspectorRuleKey = "rules.json"
+)
+
+// inspectorRulesLoaderScript loads the introspection rules into Ironic
+// Inspector once it answers, then again whenever the mounted ConfigMap
+// changes or Ironic Inspector restarts and loses them. The rules of
+// Ironic Inspector are all replaced, so that removed rules do not linger.
+const inspectorRulesLoaderScript = `
+import base64
+import hashlib
+import json
+import os
+import time
+import urllib.request
+
+RULES = os.environ["INSPECTOR_RULES_FILE"]
+ENDPOINT = os.environ["IRONIC_INSPECTOR_ENDPOINT"].rstrip("/")
+AUTH = os.path.join(os.environ["METAL3_AUTH_ROOT_DIR"], "ironic-inspector")
+INTERVAL = 30
+
+
+def request(method, path, body=None):
+    with open(os.path.join(AUTH, "username")) as u, open(os.path.join(AUTH, "password")) as p:
+        credentials = "%s:%s" % (u.read().strip(), p.read().strip())
+    data = json.dumps(body).encode() if body is not None else None
+    req = urllib.request.Request(ENDPOINT + path, data=data, method=method)
+    req.add_header("Authorization", "Basic " + base64.b64encode(credentials.encode()).decode())
+    req.add_header("Content-Type", "application/json")
+    with urllib.request.urlopen(req, timeout=30) as resp:
+        return json.loads(resp.read() or b"null")
+
+
+def load(rules):
+    request("DELETE", "/rules")
+    for rule in rules:
+        request("POST", "/rules", rule)
+    print("loaded %d introspection rules" % len(rules), flush=True)
+
+
+loaded = None
+while True:
+    try:
+        with open(RULES, "rb") as f:
+            content = f.read()
+        digest = hashlib.sha256(content).hexdigest()
+        current = len(request("GET", "/rules").get("rules", []))
+        rules = json.loads(content)
+        if digest != loaded or current != len(rules):
+            load(rules)
+            loaded = digest
+    except Exception as e:
+        print("unable to load introspection rules: %s" % e, flush=True)
+    time.sleep(INTERVAL)
+`
+
+// getInspectorRulesKey returns the key of the ConfigMap holding the rules
+func getInspectorRulesKey(rules *metal3iov1alpha1.InspectorRules) string {
+	if rules.Key == "" {
+		return defaultInspectorRuleKey
+	}
+	return rules.Key
+}
+
+func newInspectorRulesVolume(rules *metal3iov1alpha1.InspectorRules) corev1.Volume {
+	return corev1.Volume{
+		Name: inspectorRulesVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: rules.ConfigMapName},
+				Items: []corev1.KeyToPath{
+					{Key: getInspectorRulesKey(rules), Path: defaultInspectorRuleKey},
+				},
+			},
+		},
+	}
+}
+
+func createContainerMetal3InspectorRulesLoader(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	return corev1.Container{
+		Name:            "metal3-inspector-rules-loader",
+		Image:           images.BaremetalIronicInspector,
+		Command:         []string{"python3", "-c", inspectorRulesLoaderScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			inspectorCredentialsMount,
+			{
+				Name:      inspectorRulesVolume,
+				MountPath: inspectorRulesMountPath,
+				ReadOnly:  true,
+			},
+		},
+		Env: []corev1.EnvVar{
+			buildEnvVar(ironicInspectorEndpoint, config),
+			{
+				Name:  "INSPECTOR_RULES_FILE",
+				Value: inspectorRulesMountPath + "/" + defaultInspectorRuleKey,
+			},
+			{
+				Name:  "METAL3_AUTH_ROOT_DIR",
+				Value: metal3AuthRootDir,
+			},
+		},
+	}
+}
+
+func validateInspectorRulesConfig(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if config.InspectorRules == nil {
+		return nil
+	}
+	if config.InspectorRules.ConfigMapName == "" {
+		return fmt.Errorf("InspectorRules.ConfigMapName is required")
+	}
+	if config.ProvisioningIP == "" {
+		return fmt.Errorf("InspectorRules requires a ProvisioningIP")
+	}
+	if IsMinimalProfile(config) {
+		return fmt.Errorf("InspectorRules cannot be used with Profile %s, which runs no Ironic Inspector",
+			metal3iov1alpha1.ProvisioningProfileMinimal)
+	}
+	return nil
+}
+
+// ValidateInspectorRules checks that the ConfigMap referenced by the
+// InspectorRules holds a list of rules, each with actions. The rules
+// themselves are checked by Ironic Inspector when they are loaded.
+func ValidateInspectorRules(configMap *corev1.ConfigMap, rules *metal3iov1alpha1.InspectorRules) error {
+	key := getInspectorRulesKey(rules)
+	data, ok := configMap.Data[key]
+	if !ok {
+		return fmt.Errorf("ConfigMap %s has no %s key", configMap.Name, key)
+	}
+	parsed := []map[string]interface{}{}
+	if err := json.Unmarshal([]byte(data), &parsed); err != nil {
+		return fmt.Errorf("key %s of ConfigMap %s is not a JSON list of rules: %v", key, configMap.Name, err)
+	}
+	for i, rule := range parsed {
+		if actions, ok := rule["actions"].([]interface{}); !ok || len(actions) == 0 {
+			return fmt.Errorf("rule %d of ConfigMap %s has no actions", i, configMap.Name)
+		}
+	}
+	return nil
+}
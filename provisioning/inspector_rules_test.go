@@ -0,0 +1,76 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestInspectorRulesLoader(t *testing.T) {
+	spec := managedProvisioning()
+	spec.InspectorRules = &metal3iov1alpha1.InspectorRules{ConfigMapName: "inspector-rules", Key: "root-device.json"}
+	assert.NoError(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec}))
+
+	podSpec := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	loader := findContainer(podSpec.Containers, "metal3-inspector-rules-loader")
+	if assert.NotNil(t, loader) {
+		assert.Contains(t, loader.Env, corev1.EnvVar{Name: "IRONIC_INSPECTOR_ENDPOINT", Value: "http://172.30.20.3:5050/v1/"})
+		assert.Contains(t, loader.Env, corev1.EnvVar{Name: "INSPECTOR_RULES_FILE", Value: "/etc/inspector-rules/rules.json"})
+	}
+	assert.Contains(t, podSpec.Volumes, corev1.Volume{
+		Name: "metal3-inspector-rules",
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "inspector-rules"},
+				Items:                []corev1.KeyToPath{{Key: "root-device.json", Path: "rules.json"}},
+			},
+		},
+	})
+
+	spec.ProvisioningIP = ""
+	assert.EqualError(t, validateInspectorRulesConfig(spec), "InspectorRules requires a ProvisioningIP")
+}
+
+func TestValidateInspectorRules(t *testing.T) {
+	tCases := []struct {
+		name          string
+		data          map[string]string
+		expectedError string
+	}{
+		{
+			name: "Valid",
+			data: map[string]string{"rules.json": `[{"description": "root device", "conditions": [], ` +
+				`"actions": [{"action": "set-attribute", "path": "/properties/root_device", "value": {"size": 100}}]}]`},
+		},
+		{
+			name:          "MissingKey",
+			data:          map[string]string{"other.json": "[]"},
+			expectedError: "ConfigMap inspector-rules has no rules.json key",
+		},
+		{
+			name:          "NotAList",
+			data:          map[string]string{"rules.json": `{"actions": []}`},
+			expectedError: "key rules.json of ConfigMap inspector-rules is not a JSON list of rules: json: cannot unmarshal object into Go value of type []map[string]interface {}",
+		},
+		{
+			name:          "NoActions",
+			data:          map[string]string{"rules.json": `[{"conditions": []}]`},
+			expectedError: "rule 0 of ConfigMap inspector-rules has no actions",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "inspector-rules"}, Data: tc.data}
+			err := ValidateInspectorRules(configMap, &metal3iov1alpha1.InspectorRules{ConfigMapName: "inspector-rules"})
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
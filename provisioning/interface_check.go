@@ -0,0 +1,62 @@
+package provisioning
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	interfaceCheckAppName = "metal3-interface-check"
+	// InterfaceCheckInterfaceAnnotation records the interface checked by an interface check pod
+	InterfaceCheckInterfaceAnnotation = "baremetal.openshift.io/provisioning-interface"
+)
+
+// InterfaceCheckPodName returns the name of the pod checking the
+// provisioning interface on the given node
+func InterfaceCheckPodName(nodeName string) string {
+	return interfaceCheckAppName + "-" + nodeName
+}
+
+// NewInterfaceCheckPod returns a pod that succeeds when the
+// ProvisioningInterface exists on the given node, and fails otherwise
+func NewInterfaceCheckPod(targetNamespace string, nodeName string, images *Images, config *metal3iov1alpha1.ProvisioningSpec) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      InterfaceCheckPodName(nodeName),
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": interfaceCheckAppName,
+			},
+			Annotations: map[string]string{
+				InterfaceCheckInterfaceAnnotation: config.ProvisioningInterface,
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:          nodeName,
+			HostNetwork:       true,
+			RestartPolicy:     corev1.RestartPolicyNever,
+			PriorityClassName: metal3PriorityClassName,
+			Containers: []corev1.Container{
+				{
+					Name:            interfaceCheckAppName,
+					Image:           images.BaremetalStaticIpManager,
+					Command:         []string{"/bin/sh", "-c", `ip link show dev "${PROVISIONING_INTERFACE}"`},
+					ImagePullPolicy: "IfNotPresent",
+					Env: []corev1.EnvVar{
+						buildEnvVar(provisioningInterface, config),
+					},
+				},
+			},
+			ServiceAccountName:            serviceAccountName,
+			TerminationGracePeriodSeconds: pointer.Int64Ptr(0),
+			Tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+		},
+	}
+}
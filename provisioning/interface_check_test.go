@@ -0,0 +1,18 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewInterfaceCheckPod(t *testing.T) {
+	pod := NewInterfaceCheckPod(testNamespace, "master-0", &testImages, managedProvisioning())
+
+	assert.Equal(t, "metal3-interface-check-master-0", pod.Name)
+	assert.Equal(t, "master-0", pod.Spec.NodeName)
+	assert.Equal(t, corev1.RestartPolicyNever, pod.Spec.RestartPolicy)
+	assert.Equal(t, "eth0", pod.Annotations[InterfaceCheckInterfaceAnnotation])
+	assert.Equal(t, "eth0", envValue(&pod.Spec.Containers[0], provisioningInterface))
+}
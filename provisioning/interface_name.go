@@ -0,0 +1,75 @@
+package provisioning
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// maxInterfaceNameLength is IFNAMSIZ minus the trailing NUL
+	maxInterfaceNameLength = 15
+	maxVLANID              = 4094
+)
+
+// interfaceNameRegexp matches the names udev, NetworkManager and the
+// usual bond, team and bridge conventions produce. The kernel accepts
+// more, but such names do not survive the shell scripts of the pods.
+var interfaceNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// validateInterfaceName checks that name can be the name of a network
+// interface, with dedicated messages for the addresses mistaken for one
+func validateInterfaceName(field string, name string) error {
+	if ip := net.ParseIP(name); ip != nil {
+		return fmt.Errorf("%s %q is an IP address, it must be the name of a network interface such as eth1 or ens3", field, name)
+	}
+	if _, _, err := net.ParseCIDR(name); err == nil {
+		return fmt.Errorf("%s %q is a network, it must be the name of a network interface such as eth1 or ens3", field, name)
+	}
+	if _, err := net.ParseMAC(name); err == nil {
+		return fmt.Errorf("%s %q is a MAC address, it must be the name of a network interface such as eth1 or ens3", field, name)
+	}
+	if len(name) > maxInterfaceNameLength {
+		return fmt.Errorf("%s %q is longer than the %d characters of a network interface name", field, name, maxInterfaceNameLength)
+	}
+	if !interfaceNameRegexp.MatchString(name) {
+		return fmt.Errorf("%s %q must start with a letter or digit and only contain letters, digits, '_', '-' and '.'", field, name)
+	}
+	// A numeric suffix after the last dot is the ID of a VLAN of the
+	// parent interface, as in bond0.100
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		parent, suffix := name[:dot], name[dot+1:]
+		if id, err := strconv.Atoi(suffix); err == nil {
+			if id < 1 || id > maxVLANID {
+				return fmt.Errorf("%s %q has VLAN ID %d, it must be between 1 and %d", field, name, id, maxVLANID)
+			}
+			if strings.HasSuffix(parent, ".") {
+				return fmt.Errorf("%s %q has an empty parent interface", field, name)
+			}
+		} else if suffix == "" {
+			return fmt.Errorf("%s %q must not end with '.'", field, name)
+		}
+	}
+	return nil
+}
+
+// validateInterfaceNames checks the names of the interfaces set in the
+// spec. The ProvisioningInterface is optional in some modes, so only the
+// names set are checked.
+func validateInterfaceNames(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if config.ProvisioningInterface != "" {
+		if err := validateInterfaceName("ProvisioningInterface", config.ProvisioningInterface); err != nil {
+			return err
+		}
+	}
+	if config.BMCNetwork != nil && config.BMCNetwork.Interface != "" {
+		if err := validateInterfaceName("BMCNetwork: Interface", config.BMCNetwork.Interface); err != nil {
+			return err
+		}
+	}
+	return nil
+}
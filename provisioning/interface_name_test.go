@@ -0,0 +1,101 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateInterfaceName(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedError string
+	}{
+		{name: "eth1"},
+		{name: "ens3"},
+		{name: "enp0s31f6"},
+		{name: "bond0"},
+		{name: "bond0.100"},
+		{name: "br-ex"},
+		{name: "team_0"},
+		{name: "enp175s0f0.4094"},
+		{
+			name:          "172.22.0.3",
+			expectedError: `ProvisioningInterface "172.22.0.3" is an IP address, it must be the name of a network interface such as eth1 or ens3`,
+		},
+		{
+			name:          "fd00:1101::3",
+			expectedError: `ProvisioningInterface "fd00:1101::3" is an IP address, it must be the name of a network interface such as eth1 or ens3`,
+		},
+		{
+			name:          "172.22.0.0/24",
+			expectedError: `ProvisioningInterface "172.22.0.0/24" is a network, it must be the name of a network interface such as eth1 or ens3`,
+		},
+		{
+			name:          "52:54:00:aa:00:01",
+			expectedError: `ProvisioningInterface "52:54:00:aa:00:01" is a MAC address, it must be the name of a network interface such as eth1 or ens3`,
+		},
+		{
+			name:          "enp175s0f0np0.100",
+			expectedError: `ProvisioningInterface "enp175s0f0np0.100" is longer than the 15 characters of a network interface name`,
+		},
+		{
+			name:          "eth 1",
+			expectedError: `ProvisioningInterface "eth 1" must start with a letter or digit and only contain letters, digits, '_', '-' and '.'`,
+		},
+		{
+			name:          "eth0:1",
+			expectedError: `ProvisioningInterface "eth0:1" must start with a letter or digit and only contain letters, digits, '_', '-' and '.'`,
+		},
+		{
+			name:          "-eth0",
+			expectedError: `ProvisioningInterface "-eth0" must start with a letter or digit and only contain letters, digits, '_', '-' and '.'`,
+		},
+		{
+			name:          "bond0.4095",
+			expectedError: `ProvisioningInterface "bond0.4095" has VLAN ID 4095, it must be between 1 and 4094`,
+		},
+		{
+			name:          "bond0.0",
+			expectedError: `ProvisioningInterface "bond0.0" has VLAN ID 0, it must be between 1 and 4094`,
+		},
+		{
+			name:          "bond0..100",
+			expectedError: `ProvisioningInterface "bond0..100" has an empty parent interface`,
+		},
+		{
+			name:          "bond0.",
+			expectedError: `ProvisioningInterface "bond0." must not end with '.'`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateInterfaceName("ProvisioningInterface", tc.name)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestValidateInterfaceNames(t *testing.T) {
+	spec := managedProvisioning()
+	assert.NoError(t, validateInterfaceNames(spec))
+
+	// The interface is optional in the Disabled and Auto modes
+	spec.ProvisioningInterface = ""
+	assert.NoError(t, validateInterfaceNames(spec))
+
+	spec.ProvisioningInterface = "172.30.20.3"
+	assert.EqualError(t, validateInterfaceNames(spec),
+		`ProvisioningInterface "172.30.20.3" is an IP address, it must be the name of a network interface such as eth1 or ens3`)
+
+	spec.ProvisioningInterface = "eth0"
+	spec.BMCNetwork = &metal3iov1alpha1.BMCNetwork{Interface: "eth1:0", Subnets: []string{"10.1.0.0/24"}}
+	assert.EqualError(t, validateInterfaceNames(spec),
+		`BMCNetwork: Interface "eth1:0" must start with a letter or digit and only contain letters, digits, '_', '-' and '.'`)
+}
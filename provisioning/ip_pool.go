@@ -0,0 +1,209 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ProvisioningIPsConfigMapName is the ConfigMap holding the address
+	// allocated to each node from the ProvisioningIPPool, keyed by node
+	// name
+	ProvisioningIPsConfigMapName = "metal3-provisioning-ips"
+
+	provisioningIPsVolume    = "metal3-provisioning-ips"
+	provisioningIPsMountPath = "/etc/metal3/provisioning-ips"
+)
+
+// HasProvisioningIPPool returns whether the nodes running the metal3 pods
+// get an address of their own
+func HasProvisioningIPPool(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.HighAvailability != nil && len(config.ProvisioningIPPool) > 0
+}
+
+func validateProvisioningIPPool(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	if len(config.ProvisioningIPPool) == 0 {
+		return nil
+	}
+	if config.HighAvailability == nil {
+		return fmt.Errorf("ProvisioningIPPool requires HighAvailability")
+	}
+	if mode == metal3iov1alpha1.ProvisioningNetworkDisabled {
+		return fmt.Errorf("ProvisioningIPPool cannot be used when the provisioning network is Disabled")
+	}
+	if len(config.ProvisioningIPPool) < int(config.HighAvailability.Replicas) {
+		return fmt.Errorf("ProvisioningIPPool has %d addresses, fewer than the %d HighAvailability.Replicas",
+			len(config.ProvisioningIPPool), config.HighAvailability.Replicas)
+	}
+
+	_, network, err := net.ParseCIDR(config.ProvisioningNetworkCIDR)
+	if err != nil {
+		return fmt.Errorf("could not parse ProvisioningNetworkCIDR %q", config.ProvisioningNetworkCIDR)
+	}
+	var start, end net.IP
+	if mode == metal3iov1alpha1.ProvisioningNetworkManaged {
+		dhcpRange := config.ProvisioningDHCPRange
+		if dhcpRange == "" {
+			if dhcpRange, err = getDefaultDHCPRange(config); err != nil {
+				return err
+			}
+		}
+		if start, end, err = parseDHCPRange(dhcpRange); err != nil {
+			return err
+		}
+	}
+	provisioningIP := net.ParseIP(config.ProvisioningIP)
+	seen := map[string]bool{}
+	for _, address := range config.ProvisioningIPPool {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return fmt.Errorf("could not parse ProvisioningIPPool address %q", address)
+		}
+		if !network.Contains(ip) {
+			return fmt.Errorf("ProvisioningIPPool address %q is not in the ProvisioningNetworkCIDR %q", address, config.ProvisioningNetworkCIDR)
+		}
+		if ip.Equal(provisioningIP) {
+			return fmt.Errorf("ProvisioningIPPool cannot contain the ProvisioningIP %q", address)
+		}
+		if start != nil && compareIPs(ip, start) >= 0 && compareIPs(ip, end) <= 0 {
+			return fmt.Errorf("ProvisioningIPPool address %q is in the ProvisioningDHCPRange %s,%s", address, start, end)
+		}
+		if seen[ip.String()] {
+			return fmt.Errorf("ProvisioningIPPool address %q is listed more than once", address)
+		}
+		seen[ip.String()] = true
+	}
+	return nil
+}
+
+// AllocateProvisioningIPs allocates an address of the pool to each of the
+// nodes. Nodes keep the address of the current allocations while it is
+// still in the pool, so that the addresses do not move between nodes
+// when nodes are added or removed. The nodes left without an address
+// when the pool is exhausted are returned as well.
+func AllocateProvisioningIPs(pool []string, nodeNames []string, current []metal3iov1alpha1.NodeProvisioningIP) ([]metal3iov1alpha1.NodeProvisioningIP, []string) {
+	inPool := map[string]bool{}
+	for _, ip := range pool {
+		inPool[ip] = true
+	}
+	nodes := append([]string{}, nodeNames...)
+	sort.Strings(nodes)
+	isNode := map[string]bool{}
+	for _, node := range nodes {
+		isNode[node] = true
+	}
+
+	allocated := map[string]string{}
+	used := map[string]bool{}
+	for _, allocation := range current {
+		if isNode[allocation.Node] && inPool[allocation.IP] && !used[allocation.IP] {
+			allocated[allocation.Node] = allocation.IP
+			used[allocation.IP] = true
+		}
+	}
+
+	var unallocated []string
+	free := 0
+	for _, node := range nodes {
+		if _, ok := allocated[node]; ok {
+			continue
+		}
+		for free < len(pool) && used[pool[free]] {
+			free++
+		}
+		if free == len(pool) {
+			unallocated = append(unallocated, node)
+			continue
+		}
+		allocated[node] = pool[free]
+		used[pool[free]] = true
+	}
+
+	var allocations []metal3iov1alpha1.NodeProvisioningIP
+	for _, node := range nodes {
+		if ip, ok := allocated[node]; ok {
+			allocations = append(allocations, metal3iov1alpha1.NodeProvisioningIP{Node: node, IP: ip})
+		}
+	}
+	return allocations, unallocated
+}
+
+// NewProvisioningIPsConfigMap returns the ConfigMap the static IP
+// containers read the address of their node from, with the prefix length
+// of the provisioning network
+func NewProvisioningIPsConfigMap(targetNamespace string, config *metal3iov1alpha1.ProvisioningSpec, allocations []metal3iov1alpha1.NodeProvisioningIP) *corev1.ConfigMap {
+	prefix := 0
+	if _, network, err := net.ParseCIDR(config.ProvisioningNetworkCIDR); err == nil {
+		prefix, _ = network.Mask.Size()
+	}
+	data := map[string]string{}
+	for _, allocation := range allocations {
+		data[allocation.Node] = fmt.Sprintf("%s/%d", allocation.IP, prefix)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ProvisioningIPsConfigMapName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: data,
+	}
+}
+
+// ApplyProvisioningIPsConfigMap creates or updates the ConfigMap of the
+// allocated node addresses
+func ApplyProvisioningIPsConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	return applyConfigMap(client, configMap)
+}
+
+// DeleteProvisioningIPsConfigMap removes the ConfigMap of the allocated
+// node addresses, once no pool is configured
+func DeleteProvisioningIPsConfigMap(client coreclientv1.ConfigMapsGetter, targetNamespace string) error {
+	err := client.ConfigMaps(targetNamespace).Delete(context.Background(), ProvisioningIPsConfigMapName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return apiError(err)
+}
+
+// newProvisioningIPsVolume returns the volume of the allocated node
+// addresses. It is optional, so that the pod starts before the ConfigMap
+// is created, only without an address of its own.
+func newProvisioningIPsVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: provisioningIPsVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ProvisioningIPsConfigMapName},
+				Optional:             pointer.BoolPtr(true),
+			},
+		},
+	}
+}
+
+// addProvisioningIPPool lets a static IP container find the address of
+// its node. The whole ConfigMap is mounted rather than the key of the
+// node, so that allocation changes reach the running pods.
+func addProvisioningIPPool(container *corev1.Container) {
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      provisioningIPsVolume,
+		MountPath: provisioningIPsMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "NODE_IP_FILE",
+		Value: provisioningIPsMountPath + "/$(NODE_NAME)",
+	})
+}
@@ -0,0 +1,191 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateProvisioningIPPool(t *testing.T) {
+	tests := []struct {
+		name          string
+		pool          []string
+		replicas      int32
+		network       metal3iov1alpha1.ProvisioningNetwork
+		expectedError string
+	}{
+		{
+			name: "NoPool",
+		},
+		{
+			name:     "Valid",
+			pool:     []string{"172.30.20.4", "172.30.20.5", "172.30.20.6"},
+			replicas: 3,
+		},
+		{
+			name:          "WithoutHighAvailability",
+			pool:          []string{"172.30.20.4"},
+			expectedError: "ProvisioningIPPool requires HighAvailability",
+		},
+		{
+			name:          "TooSmall",
+			pool:          []string{"172.30.20.4", "172.30.20.5"},
+			replicas:      3,
+			expectedError: "ProvisioningIPPool has 2 addresses, fewer than the 3 HighAvailability.Replicas",
+		},
+		{
+			name:          "OutsideNetwork",
+			pool:          []string{"172.30.20.4", "172.30.21.5"},
+			replicas:      2,
+			expectedError: `ProvisioningIPPool address "172.30.21.5" is not in the ProvisioningNetworkCIDR "172.30.20.0/24"`,
+		},
+		{
+			name:          "ProvisioningIP",
+			pool:          []string{"172.30.20.3", "172.30.20.4"},
+			replicas:      2,
+			expectedError: `ProvisioningIPPool cannot contain the ProvisioningIP "172.30.20.3"`,
+		},
+		{
+			name:          "InDHCPRange",
+			pool:          []string{"172.30.20.4", "172.30.20.50"},
+			replicas:      2,
+			expectedError: `ProvisioningIPPool address "172.30.20.50" is in the ProvisioningDHCPRange 172.30.20.11,172.30.20.101`,
+		},
+		{
+			name:     "DHCPRangeIgnoredWhenUnmanaged",
+			pool:     []string{"172.30.20.4", "172.30.20.50"},
+			replicas: 2,
+			network:  metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+		},
+		{
+			name:          "Duplicate",
+			pool:          []string{"172.30.20.4", "172.30.20.4"},
+			replicas:      2,
+			expectedError: `ProvisioningIPPool address "172.30.20.4" is listed more than once`,
+		},
+		{
+			name:          "Disabled",
+			pool:          []string{"172.30.20.4", "172.30.20.5"},
+			replicas:      2,
+			network:       metal3iov1alpha1.ProvisioningNetworkDisabled,
+			expectedError: "ProvisioningIPPool cannot be used when the provisioning network is Disabled",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.ProvisioningIPPool = tc.pool
+			if tc.replicas > 0 {
+				spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: tc.replicas}
+			}
+			network := tc.network
+			if network == "" {
+				network = metal3iov1alpha1.ProvisioningNetworkManaged
+			}
+			err := validateProvisioningIPPool(spec, network)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestAllocateProvisioningIPs(t *testing.T) {
+	pool := []string{"172.30.20.4", "172.30.20.5", "172.30.20.6"}
+	tests := []struct {
+		name                string
+		pool                []string
+		nodes               []string
+		current             []metal3iov1alpha1.NodeProvisioningIP
+		expectedAllocations []metal3iov1alpha1.NodeProvisioningIP
+		expectedUnallocated []string
+	}{
+		{
+			name:  "Initial",
+			pool:  pool,
+			nodes: []string{"master-2", "master-0", "master-1"},
+			expectedAllocations: []metal3iov1alpha1.NodeProvisioningIP{
+				{Node: "master-0", IP: "172.30.20.4"},
+				{Node: "master-1", IP: "172.30.20.5"},
+				{Node: "master-2", IP: "172.30.20.6"},
+			},
+		},
+		{
+			name:  "KeepsCurrent",
+			pool:  pool,
+			nodes: []string{"master-0", "master-1", "master-3"},
+			current: []metal3iov1alpha1.NodeProvisioningIP{
+				{Node: "master-0", IP: "172.30.20.4"},
+				{Node: "master-1", IP: "172.30.20.6"},
+				{Node: "master-2", IP: "172.30.20.5"},
+			},
+			expectedAllocations: []metal3iov1alpha1.NodeProvisioningIP{
+				{Node: "master-0", IP: "172.30.20.4"},
+				{Node: "master-1", IP: "172.30.20.6"},
+				{Node: "master-3", IP: "172.30.20.5"},
+			},
+		},
+		{
+			name:  "RemovedFromPool",
+			pool:  []string{"172.30.20.4", "172.30.20.7"},
+			nodes: []string{"master-0", "master-1"},
+			current: []metal3iov1alpha1.NodeProvisioningIP{
+				{Node: "master-0", IP: "172.30.20.4"},
+				{Node: "master-1", IP: "172.30.20.5"},
+			},
+			expectedAllocations: []metal3iov1alpha1.NodeProvisioningIP{
+				{Node: "master-0", IP: "172.30.20.4"},
+				{Node: "master-1", IP: "172.30.20.7"},
+			},
+		},
+		{
+			name:  "Exhausted",
+			pool:  pool[:2],
+			nodes: []string{"master-0", "master-1", "master-2"},
+			expectedAllocations: []metal3iov1alpha1.NodeProvisioningIP{
+				{Node: "master-0", IP: "172.30.20.4"},
+				{Node: "master-1", IP: "172.30.20.5"},
+			},
+			expectedUnallocated: []string{"master-2"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allocations, unallocated := AllocateProvisioningIPs(tc.pool, tc.nodes, tc.current)
+			assert.Equal(t, tc.expectedAllocations, allocations)
+			assert.Equal(t, tc.expectedUnallocated, unallocated)
+		})
+	}
+}
+
+func TestMetal3ProvisioningIPPool(t *testing.T) {
+	spec := managedProvisioning()
+	podSpec := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	for _, volume := range podSpec.Volumes {
+		assert.NotEqual(t, provisioningIPsVolume, volume.Name)
+	}
+
+	spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: 2}
+	spec.ProvisioningIPPool = []string{"172.30.20.4", "172.30.20.5"}
+	podSpec = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.Contains(t, podSpec.Volumes, newProvisioningIPsVolume())
+
+	set := findContainer(podSpec.InitContainers, "metal3-static-ip-set")
+	manager := findContainer(podSpec.Containers, StaticIPManagerContainerName)
+	for _, container := range []*corev1.Container{set, manager} {
+		if assert.NotNil(t, container) {
+			assert.Contains(t, container.VolumeMounts, staticIPStateMount)
+			assert.Equal(t, provisioningIPsMountPath+"/$(NODE_NAME)", envValue(container, "NODE_IP_FILE"))
+		}
+	}
+
+	configMap := NewProvisioningIPsConfigMap(testNamespace, spec, []metal3iov1alpha1.NodeProvisioningIP{
+		{Node: "master-0", IP: "172.30.20.4"},
+	})
+	assert.Equal(t, map[string]string{"master-0": "172.30.20.4/24"}, configMap.Data)
+}
@@ -0,0 +1,178 @@
+package provisioning
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	ironicAPIAuditPort          = 6389
+	ironicAPIAuditPortName      = "ironic-audit"
+	ironicAPIAuditVolume        = "metal3-ironic-api-audit"
+	ironicAPIAuditHostPath      = "/var/log/metal3"
+	ironicAPIAuditLogFile       = "ironic-api-audit.log"
+	ironicAPIAuditMaxFileSizeMB = 100
+	ironicAPIAuditMaxFiles      = 5
+)
+
+// ironicAPIAuditConfig is the httpd configuration of the sidecar auditing
+// the Ironic API. Credentials are checked against the Ironic htpasswd so
+// that the authenticated user is recorded; requests without credentials
+// are passed through, as Ironic serves some endpoints anonymously, while
+// requests with invalid credentials are rejected like Ironic would. The
+// Authorization header is forwarded so that Ironic still checks it.
+const ironicAPIAuditConfig = `ServerRoot "/etc/httpd"
+Listen ${AUDIT_PORT}
+Include conf.modules.d/*.conf
+User apache
+Group apache
+PidFile /tmp/ironic-api-audit.pid
+ErrorLog /dev/stderr
+LogFormat "%{%Y-%m-%dT%H:%M:%S%z}t user=%u client=%a method=%m path=\"%U%q\" status=%>s bytes=%B duration_us=%D" audit
+CustomLog "|/usr/sbin/rotatelogs -n ${AUDIT_MAX_FILES} ${AUDIT_LOG} ${AUDIT_MAX_FILE_SIZE}M" audit
+<IfDefine FORWARD>
+    CustomLog "|/usr/bin/logger --tcp --server ${AUDIT_SYSLOG_HOST} --port ${AUDIT_SYSLOG_PORT} --tag ironic-api-audit" audit
+</IfDefine>
+<VirtualHost *:${AUDIT_PORT}>
+    <Location />
+        AuthType Basic
+        AuthName "Ironic API"
+        AuthBasicProvider file
+        AuthUserFile /tmp/ironic-api-audit.htpasswd
+        <RequireAny>
+            Require valid-user
+            Require expr -z %{HTTP:Authorization}
+        </RequireAny>
+    </Location>
+    ProxyPreserveHost On
+    ProxyPass / ${IRONIC_UPSTREAM}
+    ProxyPassReverse / ${IRONIC_UPSTREAM}
+</VirtualHost>
+`
+
+// validateIronicAPIAudit checks the Ironic API audit settings
+func validateIronicAPIAudit(config *metal3iov1alpha1.ProvisioningSpec) error {
+	audit := config.IronicAPIAudit
+	if audit == nil {
+		return nil
+	}
+	if config.ProvisioningIP == "" {
+		return fmt.Errorf("IronicAPIAudit requires a ProvisioningIP")
+	}
+	if audit.MaxFileSizeMB < 0 || audit.MaxFiles < 0 {
+		return fmt.Errorf("IronicAPIAudit.MaxFileSizeMB and IronicAPIAudit.MaxFiles must be positive")
+	}
+	if audit.SyslogEndpoint != "" {
+		if host, port, err := net.SplitHostPort(audit.SyslogEndpoint); err != nil || host == "" || port == "" {
+			return fmt.Errorf("IronicAPIAudit.SyslogEndpoint must be a host:port, got %q", audit.SyslogEndpoint)
+		}
+	}
+	return nil
+}
+
+// getIronicAPIPort returns the port clients in the metal3 pod reach the
+// Ironic API on, which is the audit proxy when enabled
+func getIronicAPIPort(config *metal3iov1alpha1.ProvisioningSpec) string {
+	if config.IronicAPIAudit != nil {
+		return strconv.Itoa(ironicAPIAuditPort)
+	}
+	return baremetalIronicPort
+}
+
+func newIronicAPIAuditVolume() corev1.Volume {
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	return corev1.Volume{
+		Name: ironicAPIAuditVolume,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: ironicAPIAuditHostPath,
+				Type: &hostPathType,
+			},
+		},
+	}
+}
+
+func createContainerMetal3IronicAPIAudit(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	audit := config.IronicAPIAudit
+	maxFileSize, maxFiles := int32(ironicAPIAuditMaxFileSizeMB), int32(ironicAPIAuditMaxFiles)
+	if audit.MaxFileSizeMB > 0 {
+		maxFileSize = audit.MaxFileSizeMB
+	}
+	if audit.MaxFiles > 0 {
+		maxFiles = audit.MaxFiles
+	}
+	syslogHost, syslogPort := "", ""
+	if audit.SyslogEndpoint != "" {
+		// Invalid endpoints are rejected by the validation of the spec
+		syslogHost, syslogPort, _ = net.SplitHostPort(audit.SyslogEndpoint)
+	}
+	upstream := ""
+	if ironicURL := getImageServerUrl(config, "http", baremetalIronicPort, ""); ironicURL != nil {
+		upstream = *ironicURL
+	}
+
+	return corev1.Container{
+		Name:  "metal3-ironic-api-audit",
+		Image: images.BaremetalIronic,
+		Command: []string{"/bin/bash", "-c", `echo "${AUDIT_CONFIG}" > /tmp/ironic-api-audit.conf && ` +
+			`echo "${` + htpasswdEnvVar + `}" > /tmp/ironic-api-audit.htpasswd && ` +
+			`exec httpd -DFOREGROUND ${AUDIT_SYSLOG_HOST:+-DFORWARD} -f /tmp/ironic-api-audit.conf`},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          ironicAPIAuditPortName,
+				ContainerPort: ironicAPIAuditPort,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      ironicAPIAuditVolume,
+				MountPath: ironicAPIAuditHostPath,
+			},
+		},
+		Env: []corev1.EnvVar{
+			setIronicHtpasswdHash(htpasswdEnvVar, ironicSecretName),
+			{
+				Name:  "AUDIT_CONFIG",
+				Value: ironicAPIAuditConfig,
+			},
+			{
+				Name:  "AUDIT_PORT",
+				Value: strconv.Itoa(ironicAPIAuditPort),
+			},
+			{
+				Name:  "AUDIT_LOG",
+				Value: ironicAPIAuditHostPath + "/" + ironicAPIAuditLogFile,
+			},
+			{
+				Name:  "AUDIT_MAX_FILE_SIZE",
+				Value: strconv.Itoa(int(maxFileSize)),
+			},
+			{
+				Name:  "AUDIT_MAX_FILES",
+				Value: strconv.Itoa(int(maxFiles)),
+			},
+			{
+				Name:  "AUDIT_SYSLOG_HOST",
+				Value: syslogHost,
+			},
+			{
+				Name:  "AUDIT_SYSLOG_PORT",
+				Value: syslogPort,
+			},
+			{
+				Name:  "IRONIC_UPSTREAM",
+				Value: upstream,
+			},
+		},
+	}
+}
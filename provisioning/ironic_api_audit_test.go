@@ -0,0 +1,89 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateIronicAPIAudit(t *testing.T) {
+	tCases := []struct {
+		name           string
+		audit          *metal3iov1alpha1.IronicAPIAudit
+		provisioningIP string
+		expectedError  string
+	}{
+		{
+			name:           "NotSet",
+			provisioningIP: "172.30.20.3",
+		},
+		{
+			name:           "Defaults",
+			audit:          &metal3iov1alpha1.IronicAPIAudit{},
+			provisioningIP: "172.30.20.3",
+		},
+		{
+			name:           "Syslog",
+			audit:          &metal3iov1alpha1.IronicAPIAudit{SyslogEndpoint: "[fd00::1]:514"},
+			provisioningIP: "172.30.20.3",
+		},
+		{
+			name:          "NoProvisioningIP",
+			audit:         &metal3iov1alpha1.IronicAPIAudit{},
+			expectedError: "IronicAPIAudit requires a ProvisioningIP",
+		},
+		{
+			name:           "SyslogWithoutPort",
+			audit:          &metal3iov1alpha1.IronicAPIAudit{SyslogEndpoint: "syslog.example.com"},
+			provisioningIP: "172.30.20.3",
+			expectedError:  `IronicAPIAudit.SyslogEndpoint must be a host:port, got "syslog.example.com"`,
+		},
+		{
+			name:           "NegativeRotation",
+			audit:          &metal3iov1alpha1.IronicAPIAudit{MaxFiles: -1},
+			provisioningIP: "172.30.20.3",
+			expectedError:  "IronicAPIAudit.MaxFileSizeMB and IronicAPIAudit.MaxFiles must be positive",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &metal3iov1alpha1.ProvisioningSpec{ProvisioningIP: tc.provisioningIP, IronicAPIAudit: tc.audit}
+			err := validateIronicAPIAudit(config)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestIronicAPIAudit(t *testing.T) {
+	config := managedProvisioning()
+	config.IronicAPIExposure = &metal3iov1alpha1.IronicAPIExposure{ClientCAConfigMap: "ironic-clients"}
+	config.IronicAPIAudit = &metal3iov1alpha1.IronicAPIAudit{MaxFiles: 10, SyslogEndpoint: "syslog.example.com:514"}
+
+	containers := newMetal3Containers(&testImages, config)
+	audit := findContainer(containers, "metal3-ironic-api-audit")
+	if assert.NotNil(t, audit) {
+		assert.Equal(t, "100", envValue(audit, "AUDIT_MAX_FILE_SIZE"))
+		assert.Equal(t, "10", envValue(audit, "AUDIT_MAX_FILES"))
+		assert.Equal(t, "syslog.example.com", envValue(audit, "AUDIT_SYSLOG_HOST"))
+		assert.Equal(t, "514", envValue(audit, "AUDIT_SYSLOG_PORT"))
+		assert.Equal(t, "http://172.30.20.3:6385/", envValue(audit, "IRONIC_UPSTREAM"))
+		assert.Equal(t, "/var/log/metal3/ironic-api-audit.log", envValue(audit, "AUDIT_LOG"))
+	}
+
+	// Clients in the pod go through the audit proxy
+	bmo := findContainer(containers, "metal3-baremetal-operator")
+	assert.Equal(t, "http://172.30.20.3:6389/v1/", envValue(bmo, ironicEndpoint))
+	proxy := findContainer(containers, "metal3-ironic-api-proxy")
+	assert.Equal(t, "http://172.30.20.3:6389/", envValue(proxy, "IRONIC_UPSTREAM"))
+
+	config.IronicAPIAudit = nil
+	containers = newMetal3Containers(&testImages, config)
+	assert.Nil(t, findContainer(containers, "metal3-ironic-api-audit"))
+	assert.Equal(t, "http://172.30.20.3:6385/v1/", envValue(findContainer(containers, "metal3-baremetal-operator"), ironicEndpoint))
+}
@@ -0,0 +1,230 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// IronicAPIProxyName is the name of the Service and Route exposing the Ironic API
+	IronicAPIProxyName = "metal3-ironic-api"
+
+	ironicAPIProxyPort          = 6388
+	ironicAPIProxyPortName      = "ironic-api"
+	ironicAPIProxyTlsSecretName = "metal3-ironic-api-tls" // #nosec
+	ironicAPIProxyTlsVolume     = "metal3-ironic-api-tls"
+	ironicAPIProxyTlsMountPath  = "/certs/ironic-api-proxy"
+	ironicAPIClientCAVolume     = "metal3-ironic-api-client-ca"
+	ironicAPIClientCAMountPath  = "/certs/ironic-api-client-ca"
+	ironicAPIClientCAKey        = "ca-bundle.crt"
+	servingCertAnnotation       = "service.beta.openshift.io/serving-cert-secret-name"
+)
+
+// ironicAPIProxyConfig is the httpd configuration of the sidecar exposing
+// the Ironic API. Requests are only proxied when they present a client
+// certificate signed by one of the configured CAs; the Authorization
+// header is left untouched so that Ironic still checks its own
+// credentials.
+const ironicAPIProxyConfig = `ServerRoot "/etc/httpd"
+Listen ${PROXY_PORT}
+Include conf.modules.d/*.conf
+User apache
+Group apache
+PidFile /tmp/ironic-api-proxy.pid
+ErrorLog /dev/stderr
+LogFormat "%h %{SSL_CLIENT_S_DN}x \"%r\" %>s %b" proxy
+CustomLog /dev/stdout proxy
+<VirtualHost *:${PROXY_PORT}>
+    SSLEngine on
+    SSLProtocol -all +TLSv1.2 +TLSv1.3
+    SSLCertificateFile ` + ironicAPIProxyTlsMountPath + `/tls.crt
+    SSLCertificateKeyFile ` + ironicAPIProxyTlsMountPath + `/tls.key
+    SSLCACertificateFile ` + ironicAPIClientCAMountPath + `/` + ironicAPIClientCAKey + `
+    SSLVerifyClient require
+    SSLVerifyDepth 5
+    ProxyPreserveHost On
+    ProxyPass / ${IRONIC_UPSTREAM}
+    ProxyPassReverse / ${IRONIC_UPSTREAM}
+</VirtualHost>
+`
+
+func newIronicAPIProxyVolumes(exposure *metal3iov1alpha1.IronicAPIExposure) []corev1.Volume {
+	return []corev1.Volume{
+		{
+			Name: ironicAPIProxyTlsVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: ironicAPIProxyTlsSecretName,
+				},
+			},
+		},
+		{
+			Name: ironicAPIClientCAVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: exposure.ClientCAConfigMap,
+					},
+				},
+			},
+		},
+	}
+}
+
+func createContainerMetal3IronicAPIProxy(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	upstream := ""
+	// Calls through the Route are audited when IronicAPIAudit is set
+	if ironicURL := getImageServerUrl(config, "http", getIronicAPIPort(config), ""); ironicURL != nil {
+		upstream = *ironicURL
+	}
+	return corev1.Container{
+		Name:            "metal3-ironic-api-proxy",
+		Image:           images.BaremetalIronic,
+		Command:         []string{"/bin/bash", "-c", `echo "${PROXY_CONFIG}" > /tmp/ironic-api-proxy.conf && exec httpd -DFOREGROUND -f /tmp/ironic-api-proxy.conf`},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          ironicAPIProxyPortName,
+				ContainerPort: ironicAPIProxyPort,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      ironicAPIProxyTlsVolume,
+				MountPath: ironicAPIProxyTlsMountPath,
+				ReadOnly:  true,
+			},
+			{
+				Name:      ironicAPIClientCAVolume,
+				MountPath: ironicAPIClientCAMountPath,
+				ReadOnly:  true,
+			},
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "PROXY_CONFIG",
+				Value: ironicAPIProxyConfig,
+			},
+			{
+				Name:  "PROXY_PORT",
+				Value: strconv.Itoa(ironicAPIProxyPort),
+			},
+			{
+				Name:  "IRONIC_UPSTREAM",
+				Value: upstream,
+			},
+		},
+	}
+}
+
+// NewIronicAPIProxyService returns the Service exposing the Ironic API
+// sidecar. Its serving certificate is issued by the service CA.
+func NewIronicAPIProxyService(targetNamespace string, config *metal3iov1alpha1.ProvisioningSpec) *corev1.Service {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IronicAPIProxyName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+			Annotations: map[string]string{
+				servingCertAnnotation: ironicAPIProxyTlsSecretName,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       ironicAPIProxyPortName,
+					Port:       ironicAPIProxyPort,
+					TargetPort: intstr.FromString(ironicAPIProxyPortName),
+				},
+			},
+		},
+	}
+	SetOperandMetadata(service, config)
+	return service
+}
+
+// NewIronicAPIRoute returns a passthrough Route to the Ironic API Service.
+// The Route API is not vendored, so it is built as an unstructured object.
+func NewIronicAPIRoute(targetNamespace string) *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetAPIVersion("route.openshift.io/v1")
+	route.SetKind("Route")
+	route.SetName(IronicAPIProxyName)
+	route.SetNamespace(targetNamespace)
+	route.SetLabels(map[string]string{"k8s-app": metal3AppName})
+	route.Object["spec"] = map[string]interface{}{
+		"to": map[string]interface{}{
+			"kind": "Service",
+			"name": IronicAPIProxyName,
+		},
+		"port": map[string]interface{}{
+			"targetPort": ironicAPIProxyPortName,
+		},
+		"tls": map[string]interface{}{
+			"termination":                   "passthrough",
+			"insecureEdgeTerminationPolicy": "None",
+		},
+	}
+	return route
+}
+
+// ApplyIronicAPIProxyService creates or updates the Service exposing the
+// Ironic API
+func ApplyIronicAPIProxyService(client coreclientv1.ServicesGetter, service *corev1.Service) error {
+	existing, err := client.Services(service.Namespace).Get(context.Background(), service.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Services(service.Namespace).Create(context.Background(), service, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepDerivative(service.Spec, existing.Spec) &&
+		equality.Semantic.DeepDerivative(service.Annotations, existing.Annotations) &&
+		equality.Semantic.DeepDerivative(service.Labels, existing.Labels) &&
+		equality.Semantic.DeepDerivative(service.OwnerReferences, existing.OwnerReferences) {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	// ClusterIP is immutable, keep the allocated one
+	service.Spec.ClusterIP = existing.Spec.ClusterIP
+	updated.Spec = service.Spec
+	updated.Labels = service.Labels
+	updated.OwnerReferences = service.OwnerReferences
+	if updated.Annotations == nil {
+		updated.Annotations = map[string]string{}
+	}
+	for k, v := range service.Annotations {
+		updated.Annotations[k] = v
+	}
+	_, err = client.Services(service.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	return err
+}
+
+func validateIronicAPIExposure(exposure *metal3iov1alpha1.IronicAPIExposure) error {
+	if exposure != nil && exposure.ClientCAConfigMap == "" {
+		return fmt.Errorf("IronicAPIExposure.ClientCAConfigMap is required but is empty")
+	}
+	return nil
+}
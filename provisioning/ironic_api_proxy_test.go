@@ -0,0 +1,74 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestIronicAPIProxyContainer(t *testing.T) {
+	spec := managedProvisioning()
+	assert.Nil(t, findContainer(newMetal3Containers(&testImages, spec), "metal3-ironic-api-proxy"))
+
+	spec.IronicAPIExposure = &metal3iov1alpha1.IronicAPIExposure{ClientCAConfigMap: "ironic-clients"}
+	proxy := findContainer(newMetal3Containers(&testImages, spec), "metal3-ironic-api-proxy")
+	if assert.NotNil(t, proxy) {
+		assert.Equal(t, "http://172.30.20.3:6385/", envValue(proxy, "IRONIC_UPSTREAM"))
+		assert.Equal(t, "6388", envValue(proxy, "PROXY_PORT"))
+		assert.Contains(t, envValue(proxy, "PROXY_CONFIG"), "SSLVerifyClient require")
+	}
+
+	volumes := newMetal3Volumes(spec)
+	var clientCA string
+	for _, v := range volumes {
+		if v.Name == ironicAPIClientCAVolume {
+			clientCA = v.ConfigMap.Name
+		}
+	}
+	assert.Equal(t, "ironic-clients", clientCA)
+}
+
+func TestApplyIronicAPIProxyService(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+
+	service := NewIronicAPIProxyService(testNamespace, managedProvisioning())
+	assert.Equal(t, ironicAPIProxyTlsSecretName, service.Annotations[servingCertAnnotation])
+	assert.NoError(t, ApplyIronicAPIProxyService(kubeClient.CoreV1(), service))
+
+	// Emulate the allocated ClusterIP and annotations added by the service CA
+	existing, err := kubeClient.CoreV1().Services(testNamespace).Get(context.Background(), IronicAPIProxyName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	existing.Spec.ClusterIP = "172.30.0.10"
+	existing.Annotations["service.alpha.openshift.io/serving-cert-signed-by"] = "openshift-service-serving-signer"
+	existing.Spec.Ports[0].Port = 1234
+	_, err = kubeClient.CoreV1().Services(testNamespace).Update(context.Background(), existing, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, ApplyIronicAPIProxyService(kubeClient.CoreV1(), NewIronicAPIProxyService(testNamespace, managedProvisioning())))
+	updated, err := kubeClient.CoreV1().Services(testNamespace).Get(context.Background(), IronicAPIProxyName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "172.30.0.10", updated.Spec.ClusterIP)
+	assert.Equal(t, int32(ironicAPIProxyPort), updated.Spec.Ports[0].Port)
+	assert.Contains(t, updated.Annotations, "service.alpha.openshift.io/serving-cert-signed-by")
+}
+
+func TestNewIronicAPIRoute(t *testing.T) {
+	route := NewIronicAPIRoute(testNamespace)
+
+	termination, _, _ := unstructured.NestedString(route.Object, "spec", "tls", "termination")
+	assert.Equal(t, "passthrough", termination)
+	target, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+	assert.Equal(t, IronicAPIProxyName, target)
+}
+
+func TestValidateIronicAPIExposure(t *testing.T) {
+	assert.NoError(t, validateIronicAPIExposure(nil))
+	assert.Error(t, validateIronicAPIExposure(&metal3iov1alpha1.IronicAPIExposure{}))
+	assert.NoError(t, validateIronicAPIExposure(&metal3iov1alpha1.IronicAPIExposure{ClientCAConfigMap: "ca"}))
+}
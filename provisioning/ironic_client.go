@@ -0,0 +1,211 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ironicAPIVersion is the microversion the operator requests, the
+	// first one supporting the deploy templates
+	ironicAPIVersion = "1.55"
+	ironicAPITimeout = 30 * time.Second
+)
+
+// IronicNode is a node of the Ironic API
+type IronicNode struct {
+	UUID           string             `json:"uuid"`
+	Name           string             `json:"name"`
+	ProvisionState string             `json:"provision_state"`
+	ConductorGroup string             `json:"conductor_group"`
+	Traits         []string           `json:"traits"`
+	InstanceInfo   IronicInstanceInfo `json:"instance_info"`
+}
+
+// IronicInstanceInfo holds what the next deployment of a node requests
+type IronicInstanceInfo struct {
+	Traits []string `json:"traits,omitempty"`
+}
+
+// IronicPort is a port of the Ironic API
+type IronicPort struct {
+	Address  string `json:"address"`
+	NodeUUID string `json:"node_uuid"`
+}
+
+// IronicClient calls the Ironic API of a metal3 pod with the credentials
+// of the operator
+type IronicClient struct {
+	endpoint string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewIronicClient returns a client of the Ironic API of the metal3 pod
+// with the given IP, reading the Ironic credentials from their Secret
+func NewIronicClient(client coreclientv1.SecretsGetter, targetNamespace string, podIP string, config *metal3iov1alpha1.ProvisioningSpec) (*IronicClient, error) {
+	secret, err := client.Secrets(targetNamespace).Get(context.Background(), ironicSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return &IronicClient{
+		endpoint: fmt.Sprintf("http://%s/v1", net.JoinHostPort(podIP, getIronicAPIPort(config))),
+		username: string(secret.Data[ironicUsernameKey]),
+		password: string(secret.Data[ironicPasswordKey]),
+		client:   &http.Client{Timeout: ironicAPITimeout},
+	}, nil
+}
+
+func (c *IronicClient) do(method string, path string, body io.Reader, result interface{}) error {
+	req, err := http.NewRequest(method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("X-OpenStack-Ironic-API-Version", ironicAPIVersion)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// ListNodes returns all the Ironic nodes
+func (c *IronicClient) ListNodes() ([]IronicNode, error) {
+	result := struct {
+		Nodes []IronicNode `json:"nodes"`
+	}{}
+	err := c.do(http.MethodGet, "/nodes?fields=uuid,name,provision_state,conductor_group,traits,instance_info", nil, &result)
+	return result.Nodes, err
+}
+
+// ListPorts returns all the Ironic ports
+func (c *IronicClient) ListPorts() ([]IronicPort, error) {
+	result := struct {
+		Ports []IronicPort `json:"ports"`
+	}{}
+	err := c.do(http.MethodGet, "/ports?fields=address,node_uuid", nil, &result)
+	return result.Ports, err
+}
+
+// DeleteNode deletes an Ironic node, putting it in maintenance first as
+// Ironic refuses to delete available nodes otherwise
+func (c *IronicClient) DeleteNode(uuid string) error {
+	path := "/nodes/" + url.PathEscape(uuid)
+	patch := `[{"op": "replace", "path": "/maintenance", "value": true}]`
+	if err := c.do(http.MethodPatch, path, strings.NewReader(patch), nil); err != nil {
+		return err
+	}
+	return c.do(http.MethodDelete, path, nil, nil)
+}
+
+// SetNodeConductorGroup moves an Ironic node to the conductors of a group,
+// or to the conductor of the metal3 pod when the group is empty
+func (c *IronicClient) SetNodeConductorGroup(uuid string, group string) error {
+	body, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/conductor_group", "value": group},
+	})
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPatch, "/nodes/"+url.PathEscape(uuid), strings.NewReader(string(body)), nil)
+}
+
+// AddNodeTrait adds a trait to an Ironic node
+func (c *IronicClient) AddNodeTrait(uuid string, trait string) error {
+	return c.do(http.MethodPut, "/nodes/"+url.PathEscape(uuid)+"/traits/"+url.PathEscape(trait), nil, nil)
+}
+
+// RemoveNodeTrait removes a trait from an Ironic node
+func (c *IronicClient) RemoveNodeTrait(uuid string, trait string) error {
+	return c.do(http.MethodDelete, "/nodes/"+url.PathEscape(uuid)+"/traits/"+url.PathEscape(trait), nil, nil)
+}
+
+// SetNodeInstanceTraits sets the traits requested by the next deployment
+// of an Ironic node, which selects the deploy templates it runs
+func (c *IronicClient) SetNodeInstanceTraits(uuid string, traits []string) error {
+	body, err := json.Marshal([]map[string]interface{}{
+		{"op": "add", "path": "/instance_info/traits", "value": traits},
+	})
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPatch, "/nodes/"+url.PathEscape(uuid), strings.NewReader(string(body)), nil)
+}
+
+// IronicDeployStep is a step of an Ironic deploy template
+type IronicDeployStep struct {
+	Interface string                 `json:"interface"`
+	Step      string                 `json:"step"`
+	Args      map[string]interface{} `json:"args"`
+	Priority  int                    `json:"priority"`
+}
+
+// IronicDeployTemplate is a deploy template of the Ironic API, applied to
+// the deployments requesting the trait it is named after
+type IronicDeployTemplate struct {
+	Name  string             `json:"name"`
+	Steps []IronicDeployStep `json:"steps"`
+}
+
+// ListDeployTemplates returns all the Ironic deploy templates
+func (c *IronicClient) ListDeployTemplates() ([]IronicDeployTemplate, error) {
+	result := struct {
+		Templates []IronicDeployTemplate `json:"deploy_templates"`
+	}{}
+	err := c.do(http.MethodGet, "/deploy_templates?fields=name,steps", nil, &result)
+	return result.Templates, err
+}
+
+// CreateDeployTemplate creates an Ironic deploy template
+func (c *IronicClient) CreateDeployTemplate(template IronicDeployTemplate) error {
+	body, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPost, "/deploy_templates", strings.NewReader(string(body)), nil)
+}
+
+// UpdateDeployTemplate replaces the steps of an Ironic deploy template
+func (c *IronicClient) UpdateDeployTemplate(template IronicDeployTemplate) error {
+	body, err := json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/steps", "value": template.Steps},
+	})
+	if err != nil {
+		return err
+	}
+	return c.do(http.MethodPatch, "/deploy_templates/"+url.PathEscape(template.Name), strings.NewReader(string(body)), nil)
+}
+
+// DeleteDeployTemplate deletes an Ironic deploy template
+func (c *IronicClient) DeleteDeployTemplate(name string) error {
+	return c.do(http.MethodDelete, "/deploy_templates/"+url.PathEscape(name), nil, nil)
+}
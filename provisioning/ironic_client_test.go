@@ -0,0 +1,113 @@
+package provisioning
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestIronicClient(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "ironic-user", username)
+		assert.Equal(t, "ironic-password", password)
+		assert.Equal(t, ironicAPIVersion, r.Header.Get("X-OpenStack-Ironic-API-Version"))
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/nodes":
+			_, _ = w.Write([]byte(`{"nodes": [{"uuid": "uuid-1", "name": "worker-1", "provision_state": "available", "conductor_group": "rack-a", "traits": ["CUSTOM_OCP_RAID_MIRROR"], "instance_info": {"traits": ["CUSTOM_OCP_RAID_MIRROR"]}}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/ports":
+			_, _ = w.Write([]byte(`{"ports": [{"address": "52:54:00:aa:00:01", "node_uuid": "uuid-1"}]}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/v1/nodes/uuid-3":
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `[{"op": "replace", "path": "/conductor_group", "value": ""}]`, string(body))
+		case r.Method == http.MethodPatch && r.URL.Path == "/v1/nodes/uuid-4":
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `[{"op": "add", "path": "/instance_info/traits", "value": ["CUSTOM_OCP_RAID_MIRROR"]}]`, string(body))
+		case r.Method == http.MethodPut && r.URL.Path == "/v1/nodes/uuid-4/traits/CUSTOM_OCP_RAID_MIRROR":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/nodes/uuid-4/traits/CUSTOM_OCP_RAID_STRIPE":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1/nodes/uuid-1":
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/deploy_templates":
+			_, _ = w.Write([]byte(`{"deploy_templates": [{"name": "CUSTOM_OCP_RAID_MIRROR", "steps": [{"interface": "raid", "step": "apply_configuration", "args": {}, "priority": 90}]}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/deploy_templates":
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `{"name": "CUSTOM_OCP_RAID_STRIPE", "steps": []}`, string(body))
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodPatch && r.URL.Path == "/v1/deploy_templates/CUSTOM_OCP_RAID_MIRROR":
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, `[{"op": "replace", "path": "/steps", "value": []}]`, string(body))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/deploy_templates/CUSTOM_OCP_RAID_MIRROR":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	host, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	kubeClient := fakekube.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ironicSecretName, Namespace: testNamespace},
+		Data: map[string][]byte{
+			ironicUsernameKey: []byte("ironic-user"),
+			ironicPasswordKey: []byte("ironic-password"),
+		},
+	})
+	client, err := NewIronicClient(kubeClient.CoreV1(), testNamespace, host, managedProvisioning())
+	if !assert.NoError(t, err) {
+		return
+	}
+	// The port of the test server replaces the one of Ironic
+	client.endpoint = "http://" + net.JoinHostPort(host, port) + "/v1"
+
+	nodes, err := client.ListNodes()
+	assert.NoError(t, err)
+	assert.Equal(t, []IronicNode{{UUID: "uuid-1", Name: "worker-1", ProvisionState: "available", ConductorGroup: "rack-a",
+		Traits: []string{"CUSTOM_OCP_RAID_MIRROR"}, InstanceInfo: IronicInstanceInfo{Traits: []string{"CUSTOM_OCP_RAID_MIRROR"}},
+	}}, nodes)
+	ports, err := client.ListPorts()
+	assert.NoError(t, err)
+	assert.Equal(t, []IronicPort{{Address: "52:54:00:aa:00:01", NodeUUID: "uuid-1"}}, ports)
+	assert.NoError(t, client.DeleteNode("uuid-1"))
+	assert.EqualError(t, client.DeleteNode("uuid-2"), "PATCH /nodes/uuid-2 returned 404 Not Found: not found")
+	assert.Equal(t, []string{
+		"GET /v1/nodes", "GET /v1/ports", "PATCH /v1/nodes/uuid-1", "DELETE /v1/nodes/uuid-1", "PATCH /v1/nodes/uuid-2",
+	}, requests)
+
+	requests = []string{}
+	assert.NoError(t, client.SetNodeConductorGroup("uuid-3", ""))
+	assert.Equal(t, []string{"PATCH /v1/nodes/uuid-3"}, requests)
+
+	requests = []string{}
+	assert.NoError(t, client.AddNodeTrait("uuid-4", "CUSTOM_OCP_RAID_MIRROR"))
+	assert.NoError(t, client.RemoveNodeTrait("uuid-4", "CUSTOM_OCP_RAID_STRIPE"))
+	assert.NoError(t, client.SetNodeInstanceTraits("uuid-4", []string{"CUSTOM_OCP_RAID_MIRROR"}))
+	assert.Equal(t, []string{
+		"PUT /v1/nodes/uuid-4/traits/CUSTOM_OCP_RAID_MIRROR", "DELETE /v1/nodes/uuid-4/traits/CUSTOM_OCP_RAID_STRIPE", "PATCH /v1/nodes/uuid-4",
+	}, requests)
+
+	requests = []string{}
+	templates, err := client.ListDeployTemplates()
+	assert.NoError(t, err)
+	assert.Equal(t, []IronicDeployTemplate{{
+		Name:  "CUSTOM_OCP_RAID_MIRROR",
+		Steps: []IronicDeployStep{{Interface: "raid", Step: "apply_configuration", Args: map[string]interface{}{}, Priority: 90}},
+	}}, templates)
+	assert.NoError(t, client.CreateDeployTemplate(IronicDeployTemplate{Name: "CUSTOM_OCP_RAID_STRIPE", Steps: []IronicDeployStep{}}))
+	assert.NoError(t, client.UpdateDeployTemplate(IronicDeployTemplate{Name: "CUSTOM_OCP_RAID_MIRROR", Steps: []IronicDeployStep{}}))
+	assert.NoError(t, client.DeleteDeployTemplate("CUSTOM_OCP_RAID_MIRROR"))
+	assert.Equal(t, []string{
+		"GET /v1/deploy_templates", "POST /v1/deploy_templates",
+		"PATCH /v1/deploy_templates/CUSTOM_OCP_RAID_MIRROR", "DELETE /v1/deploy_templates/CUSTOM_OCP_RAID_MIRROR",
+	}, requests)
+}
@@ -0,0 +1,238 @@
+package provisioning
+
+import (
+	"net"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	ironicConflictCheckAppName = "metal3-ironic-conflict-check"
+	// IronicConflictCheckAnnotation records the ports and address checked
+	// by an Ironic conflict check pod
+	IronicConflictCheckAnnotation = "baremetal.openshift.io/ironic-conflict-check"
+
+	dhcpPort = "67"
+	tftpPort = "69"
+)
+
+// dhcpProbeScript broadcasts a DHCPDISCOVER on the provisioning
+// interface and prints the DHCP servers that answered, other than those of
+// the metal3 stack. Servers behind a relay are named by the server
+// identifier of their offer. Only DHCPv4 is probed.
+const dhcpProbeScript = `
+import os, random, select, socket, struct, time
+
+iface = os.environ["DHCP_INTERFACE"]
+allowed = set(os.environ.get("DHCP_SERVERS", "").split())
+with open("/sys/class/net/%s/address" % iface) as f:
+    chaddr = bytes.fromhex(f.read().strip().replace(":", ""))
+xid = random.getrandbits(32)
+discover = struct.pack("!BBBBIHH16s16s192s", 1, 1, 6, 0, xid, 0, 0x8000,
+                       bytes(16), chaddr.ljust(16, bytes(1)), bytes(192))
+discover += bytes([99, 130, 83, 99, 53, 1, 1, 255])
+
+s = socket.socket(socket.AF_INET, socket.SOCK_DGRAM)
+s.setsockopt(socket.SOL_SOCKET, socket.SO_REUSEADDR, 1)
+s.setsockopt(socket.SOL_SOCKET, socket.SO_BROADCAST, 1)
+s.setsockopt(socket.SOL_SOCKET, socket.SO_BINDTODEVICE, iface.encode())
+s.bind(("", 68))
+s.sendto(discover, ("255.255.255.255", 67))
+
+servers = set()
+deadline = time.time() + int(os.environ.get("DHCP_TIMEOUT", "5"))
+while True:
+    remaining = deadline - time.time()
+    if remaining <= 0 or not select.select([s], [], [], remaining)[0]:
+        break
+    offer, (server, _) = s.recvfrom(4096)
+    if len(offer) < 240 or offer[0] != 2 or struct.unpack("!I", offer[4:8])[0] != xid:
+        continue
+    i = 240
+    while i + 1 < len(offer) and offer[i] != 255:
+        if offer[i] == 0:
+            i += 1
+            continue
+        code, length = offer[i], offer[i + 1]
+        if code == 54 and length == 4:
+            server = socket.inet_ntoa(offer[i + 2:i + 6])
+        i += 2 + length
+    if server not in allowed:
+        servers.add(server)
+print(" ".join(sorted(servers)))
+`
+
+// ironicConflictCheckScript fails when one of the ports of the metal3
+// stack is already bound on the node, when an Ironic API already answers
+// on the ProvisioningIP, or when another DHCP server answers on the
+// provisioning network, reporting the conflicts in its termination
+// message.
+const ironicConflictCheckScript = `set -u
+conflicts=""
+for port in ${TCP_PORTS}; do
+    if ss -Hltn "sport = :${port}" | grep -q .; then
+        conflicts="${conflicts} tcp/${port}"
+    fi
+done
+for port in ${UDP_PORTS}; do
+    if ss -Hlun "sport = :${port}" | grep -q .; then
+        conflicts="${conflicts} udp/${port}"
+    fi
+done
+if [ -n "${IRONIC_URL}" ] && curl --silent --max-time 3 "${IRONIC_URL}" | grep -q '"default_version"'; then
+    conflicts="${conflicts} ${IRONIC_URL}"
+fi
+if [ -n "${DHCP_INTERFACE}" ]; then
+    for server in $(python3 -c "${DHCP_PROBE}"); do
+        conflicts="${conflicts} dhcp/${server}"
+    done
+fi
+if [ -n "${conflicts}" ]; then
+    echo "another Ironic or DHCP server is running:${conflicts}" | tee /dev/termination-log
+    exit 1
+fi
+`
+
+// IronicConflictCheckPodName returns the name of the pod checking for
+// another Ironic on the given node
+func IronicConflictCheckPodName(nodeName string) string {
+	return ironicConflictCheckAppName + "-" + nodeName
+}
+
+// getIronicConflictPorts returns the TCP and UDP ports the metal3 stack
+// binds on the host network, which another Ironic would also bind. They
+// are bound by metal3 itself once deployed.
+func getIronicConflictPorts(config *metal3iov1alpha1.ProvisioningSpec, deployed bool) ([]string, []string) {
+	if deployed {
+		return []string{}, []string{}
+	}
+	tcpPorts := []string{baremetalIronicPort, baremetalIronicInspectorPort, baremetalHttpPort}
+	udpPorts := []string{}
+	if IsDnsmasqRequired(config) {
+		udpPorts = append(udpPorts, dhcpPort, tftpPort)
+	}
+	return tcpPorts, udpPorts
+}
+
+// getIronicConflictURL returns the URL another Ironic would answer on,
+// or an empty string once metal3 is deployed and answers on it
+func getIronicConflictURL(config *metal3iov1alpha1.ProvisioningSpec, deployed bool) string {
+	if deployed || config.ProvisioningIP == "" {
+		return ""
+	}
+	return "http://" + net.JoinHostPort(config.ProvisioningIP, baremetalIronicPort) + "/"
+}
+
+// getDHCPProbeInterface returns the interface the DHCP servers are probed
+// on, or an empty string when metal3 serves no DHCPv4
+func getDHCPProbeInterface(config *metal3iov1alpha1.ProvisioningSpec) string {
+	if !IsDnsmasqRequired(config) || isIPv6Network(config) {
+		return ""
+	}
+	return config.ProvisioningInterface
+}
+
+// ProbesDHCPServers returns true when the Ironic conflict check pods look
+// for other DHCP servers on the provisioning network
+func ProbesDHCPServers(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return getDHCPProbeInterface(config) != ""
+}
+
+// getMetal3DHCPServers returns the addresses the DHCP server of the
+// metal3 stack answers from
+func getMetal3DHCPServers(config *metal3iov1alpha1.ProvisioningSpec) []string {
+	return append([]string{config.ProvisioningIP}, config.ProvisioningIPPool...)
+}
+
+// GetIronicConflictCheck returns what the Ironic conflict check pods
+// verify for the given configuration, so that they run again when it
+// changes
+func GetIronicConflictCheck(config *metal3iov1alpha1.ProvisioningSpec, deployed bool) string {
+	tcpPorts, udpPorts := getIronicConflictPorts(config, deployed)
+	return strings.Join([]string{
+		strings.Join(tcpPorts, ","),
+		strings.Join(udpPorts, ","),
+		getIronicConflictURL(config, deployed),
+		getDHCPProbeInterface(config),
+		strings.Join(getMetal3DHCPServers(config), ","),
+	}, ";")
+}
+
+// NewIronicConflictCheckPod returns a pod that fails when another Ironic,
+// such as a standalone one deployed by the user, already runs on the given
+// node or on the provisioning network, or when another DHCP server answers
+// on the provisioning network. Once metal3 is deployed, only the DHCP
+// servers are checked.
+func NewIronicConflictCheckPod(targetNamespace string, nodeName string, images *Images, config *metal3iov1alpha1.ProvisioningSpec, deployed bool) *corev1.Pod {
+	tcpPorts, udpPorts := getIronicConflictPorts(config, deployed)
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      IronicConflictCheckPodName(nodeName),
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": ironicConflictCheckAppName,
+			},
+			Annotations: map[string]string{
+				IronicConflictCheckAnnotation: GetIronicConflictCheck(config, deployed),
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:          nodeName,
+			HostNetwork:       true,
+			RestartPolicy:     corev1.RestartPolicyNever,
+			PriorityClassName: metal3PriorityClassName,
+			Containers: []corev1.Container{
+				{
+					Name:                     ironicConflictCheckAppName,
+					Image:                    images.BaremetalIronic,
+					Command:                  []string{"/bin/bash", "-c", ironicConflictCheckScript},
+					ImagePullPolicy:          "IfNotPresent",
+					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					// The DHCP client port is bound on the provisioning
+					// interface
+					SecurityContext: &corev1.SecurityContext{
+						Privileged: pointer.BoolPtr(true),
+					},
+					Env: []corev1.EnvVar{
+						{
+							Name:  "TCP_PORTS",
+							Value: strings.Join(tcpPorts, " "),
+						},
+						{
+							Name:  "UDP_PORTS",
+							Value: strings.Join(udpPorts, " "),
+						},
+						{
+							Name:  "IRONIC_URL",
+							Value: getIronicConflictURL(config, deployed),
+						},
+						{
+							Name:  "DHCP_INTERFACE",
+							Value: getDHCPProbeInterface(config),
+						},
+						{
+							Name:  "DHCP_SERVERS",
+							Value: strings.Join(getMetal3DHCPServers(config), " "),
+						},
+						{
+							Name:  "DHCP_PROBE",
+							Value: dhcpProbeScript,
+						},
+					},
+				},
+			},
+			ServiceAccountName:            serviceAccountName,
+			TerminationGracePeriodSeconds: pointer.Int64Ptr(0),
+			Tolerations: []corev1.Toleration{
+				{
+					Operator: corev1.TolerationOpExists,
+				},
+			},
+		},
+	}
+}
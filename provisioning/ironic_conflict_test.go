@@ -0,0 +1,87 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestNewIronicConflictCheckPod(t *testing.T) {
+	tCases := []struct {
+		name              string
+		network           metal3iov1alpha1.ProvisioningNetwork
+		provisioningIP    string
+		networkCIDR       string
+		deployed          bool
+		expectedTCPPorts  string
+		expectedUDPPorts  string
+		expectedIronicURL string
+		expectedDHCP      string
+	}{
+		{
+			name:              "Managed",
+			network:           metal3iov1alpha1.ProvisioningNetworkManaged,
+			provisioningIP:    "172.30.20.3",
+			expectedTCPPorts:  "6385 5050 6180",
+			expectedUDPPorts:  "67 69",
+			expectedIronicURL: "http://172.30.20.3:6385/",
+			expectedDHCP:      "eth0",
+		},
+		{
+			name:           "ManagedDeployed",
+			network:        metal3iov1alpha1.ProvisioningNetworkManaged,
+			provisioningIP: "172.30.20.3",
+			deployed:       true,
+			expectedDHCP:   "eth0",
+		},
+		{
+			name:              "ManagedIPv6",
+			network:           metal3iov1alpha1.ProvisioningNetworkManaged,
+			provisioningIP:    "fd00:1101::3",
+			networkCIDR:       "fd00:1101::/64",
+			expectedTCPPorts:  "6385 5050 6180",
+			expectedUDPPorts:  "67 69",
+			expectedIronicURL: "http://[fd00:1101::3]:6385/",
+		},
+		{
+			name:              "UnmanagedIPv6",
+			network:           metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			provisioningIP:    "fd00:1101::3",
+			networkCIDR:       "fd00:1101::/64",
+			expectedTCPPorts:  "6385 5050 6180",
+			expectedIronicURL: "http://[fd00:1101::3]:6385/",
+		},
+		{
+			name:             "Disabled",
+			network:          metal3iov1alpha1.ProvisioningNetworkDisabled,
+			expectedTCPPorts: "6385 5050 6180",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			config.ProvisioningNetwork = tc.network
+			config.ProvisioningIP = tc.provisioningIP
+			if tc.networkCIDR != "" {
+				config.ProvisioningNetworkCIDR = tc.networkCIDR
+			}
+			pod := NewIronicConflictCheckPod(testNamespace, "master-0", &testImages, config, tc.deployed)
+
+			assert.Equal(t, IronicConflictCheckPodName("master-0"), pod.Name)
+			assert.Equal(t, "master-0", pod.Spec.NodeName)
+			assert.True(t, pod.Spec.HostNetwork)
+			assert.Equal(t, GetIronicConflictCheck(config, tc.deployed), pod.Annotations[IronicConflictCheckAnnotation])
+			assert.NotEqual(t, GetIronicConflictCheck(config, !tc.deployed), pod.Annotations[IronicConflictCheckAnnotation])
+			container := &pod.Spec.Containers[0]
+			assert.Equal(t, tc.expectedTCPPorts, envValue(container, "TCP_PORTS"))
+			assert.Equal(t, tc.expectedUDPPorts, envValue(container, "UDP_PORTS"))
+			assert.Equal(t, tc.expectedIronicURL, envValue(container, "IRONIC_URL"))
+			assert.Equal(t, tc.expectedDHCP, envValue(container, "DHCP_INTERFACE"))
+			assert.Equal(t, tc.expectedDHCP != "", ProbesDHCPServers(config))
+			assert.Equal(t, tc.provisioningIP, envValue(container, "DHCP_SERVERS"))
+			assert.Equal(t, dhcpProbeScript, envValue(container, "DHCP_PROBE"))
+		})
+	}
+}
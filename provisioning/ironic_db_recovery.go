@@ -0,0 +1,185 @@
+package provisioning
+
+import (
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// IronicDBRecoveryAnnotation on a metal3 pod requests the quarantine
+	// of its Ironic database. Its value names the recovery, and the
+	// database is quarantined once for each name.
+	IronicDBRecoveryAnnotation = "metal3.io/ironic-db-recovery"
+	// IronicDBQuarantineHostPath is the node directory corrupted
+	// databases are moved to
+	IronicDBQuarantineHostPath = "/var/lib/metal3/ironic-db-quarantine"
+	// ironicDBQuarantineRetention is how many quarantined databases are
+	// kept on a node for each metal3 Deployment, the oldest being removed
+	ironicDBQuarantineRetention = 3
+
+	ironicDBVolume                = "metal3-ironic-db"
+	ironicDBQuarantineVolume      = "metal3-ironic-db-quarantine"
+	ironicDBRecoveryVolume        = "metal3-ironic-db-recovery"
+	ironicDBRecoveryMountPath     = "/etc/metal3-ironic-db-recovery"
+	ironicDBRecoveryRequestFile   = "request"
+	ironicDBDirEnvVar             = "IRONIC_DB_DIR"
+	ironicDBQuarantineDirEnvVar   = "IRONIC_DB_QUARANTINE_DIR"
+	ironicDBQuarantineKeepEnvVar  = "IRONIC_DB_QUARANTINE_KEEP"
+	ironicConductorContainerName  = "metal3-ironic-conductor"
+	ironicDBRecoveryCommandName   = "ironic-db-recovery"
+	ironicDBRecoveryRequestedPath = ironicDBRecoveryMountPath + "/" + ironicDBRecoveryRequestFile
+)
+
+// ironicDBDirs are the directories the database of Ironic is kept in by
+// the containers that may hold it. The conductor only holds it when
+// there is no MariaDB container, and Ironic uses SQLite.
+var ironicDBDirs = map[string]string{
+	mariadbContainerName:         "/var/lib/mysql",
+	ironicConductorContainerName: "/var/lib/ironic",
+}
+
+// ironicDBCorruptionSignatures are the errors MariaDB and Ironic log when
+// they fail to start on a corrupted database
+var ironicDBCorruptionSignatures = []string{
+	"innodb: database page corruption",
+	"innodb: corrupted page",
+	"innodb: plugin initialization aborted",
+	"is marked as crashed and should be repaired",
+	"incorrect information in file",
+	"database disk image is malformed",
+	"file is not a database",
+}
+
+// ironicDBQuarantineScript moves the database aside when a recovery was
+// requested through the pod annotation, then starts the container
+// command on an empty directory, which the command initializes. The
+// quarantine directory is only renamed in place once the move completed,
+// so that a container restarting halfway through moves the rest. Only
+// the most recent quarantined databases are kept, so that the node
+// directory does not grow with each recovery; a move still in progress
+// is never removed.
+const ironicDBQuarantineScript = `set -eu
+request="$(cat ` + ironicDBRecoveryRequestedPath + ` 2>/dev/null || true)"
+quarantine="${IRONIC_DB_QUARANTINE_DIR}/${request}"
+if [ -n "${request}" ] && [ ! -d "${quarantine}" ]; then
+    mkdir -p "${quarantine}.part"
+    find "${IRONIC_DB_DIR}" -mindepth 1 -maxdepth 1 -exec mv {} "${quarantine}.part/" \;
+    mv "${quarantine}.part" "${quarantine}"
+    echo "quarantined the Ironic database in ${quarantine}"
+fi
+if [ -d "${IRONIC_DB_QUARANTINE_DIR}" ]; then
+    find "${IRONIC_DB_QUARANTINE_DIR}" -mindepth 1 -maxdepth 1 -type d ! -name '*.part' -printf '%T@ %f\n' | sort -rn | tail -n +$((IRONIC_DB_QUARANTINE_KEEP + 1)) | while read -r mtime expired; do
+        rm -rf "${IRONIC_DB_QUARANTINE_DIR:?}/${expired}"
+        echo "removed the quarantined Ironic database ${expired}"
+    done
+fi
+exec "$@"`
+
+// IsIronicDBContainer returns true when the named container may hold the
+// database of Ironic
+func IsIronicDBContainer(name string) bool {
+	_, ok := ironicDBDirs[name]
+	return ok
+}
+
+// IsIronicDBCorruption returns true when a message logged by a container
+// holding the database of Ironic shows that the database is corrupted
+func IsIronicDBCorruption(message string) bool {
+	lower := strings.ToLower(message)
+	for _, signature := range ironicDBCorruptionSignatures {
+		if strings.Contains(lower, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyIronicDBRecovery keeps the database of Ironic on a volume of the
+// pod, so that it is quarantined rather than lost when corrupted, and
+// starts the container holding it through ironicDBQuarantineScript. It
+// is applied again once the topology removes the MariaDB container. The
+// volumes are those of newIronicDBRecoveryVolumes.
+func applyIronicDBRecovery(spec *corev1.PodSpec) {
+	holder := -1
+	for i, container := range spec.Containers {
+		if container.Name == mariadbContainerName {
+			holder = i
+			break
+		}
+		if container.Name == ironicConductorContainerName {
+			holder = i
+		}
+	}
+	if holder < 0 {
+		return
+	}
+	container := &spec.Containers[holder]
+	for _, env := range container.Env {
+		if env.Name == ironicDBDirEnvVar {
+			return
+		}
+	}
+
+	dir := ironicDBDirs[container.Name]
+	container.Command = append([]string{"/bin/sh", "-c", ironicDBQuarantineScript, ironicDBRecoveryCommandName}, container.Command...)
+	container.VolumeMounts = append(container.VolumeMounts,
+		corev1.VolumeMount{Name: ironicDBVolume, MountPath: dir},
+		corev1.VolumeMount{Name: ironicDBQuarantineVolume, MountPath: IronicDBQuarantineHostPath},
+		corev1.VolumeMount{Name: ironicDBRecoveryVolume, MountPath: ironicDBRecoveryMountPath, ReadOnly: true},
+	)
+	container.Env = append(container.Env,
+		corev1.EnvVar{Name: ironicDBDirEnvVar, Value: dir},
+		corev1.EnvVar{
+			Name: "METAL3_CONTROLLER",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.labels['controller']",
+				},
+			},
+		},
+		// Each metal3 Deployment quarantines in its own directory
+		corev1.EnvVar{Name: ironicDBQuarantineDirEnvVar, Value: IronicDBQuarantineHostPath + "/$(METAL3_CONTROLLER)"},
+		corev1.EnvVar{Name: ironicDBQuarantineKeepEnvVar, Value: strconv.Itoa(ironicDBQuarantineRetention)},
+	)
+}
+
+// newIronicDBRecoveryVolumes returns the volume the database is kept in,
+// the node directory it is quarantined in, and the recovery request read
+// from the annotation of the pod, which is updated in running pods
+func newIronicDBRecoveryVolumes() []corev1.Volume {
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	return []corev1.Volume{
+		{
+			Name: ironicDBVolume,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		},
+		{
+			Name: ironicDBQuarantineVolume,
+			VolumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: IronicDBQuarantineHostPath,
+					Type: &hostPathType,
+				},
+			},
+		},
+		{
+			Name: ironicDBRecoveryVolume,
+			VolumeSource: corev1.VolumeSource{
+				DownwardAPI: &corev1.DownwardAPIVolumeSource{
+					Items: []corev1.DownwardAPIVolumeFile{
+						{
+							Path: ironicDBRecoveryRequestFile,
+							FieldRef: &corev1.ObjectFieldSelector{
+								FieldPath: "metadata.annotations['" + IronicDBRecoveryAnnotation + "']",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package provisioning
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func hasVolumeMount(container *corev1.Container, name string, path string) bool {
+	for _, mount := range container.VolumeMounts {
+		if mount.Name == name && mount.MountPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+func findVolume(volumes []corev1.Volume, name string) *corev1.Volume {
+	for i := range volumes {
+		if volumes[i].Name == name {
+			return &volumes[i]
+		}
+	}
+	return nil
+}
+
+func TestIronicDBRecoveryMariadb(t *testing.T) {
+	spec := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning()).Spec.Template.Spec
+
+	mariadb := findContainer(spec.Containers, "metal3-mariadb")
+	if assert.NotNil(t, mariadb) {
+		assert.Equal(t, []string{"/bin/sh", "-c", ironicDBQuarantineScript, "ironic-db-recovery", "/bin/runmariadb"}, mariadb.Command)
+		assert.Equal(t, "/var/lib/mysql", envValue(mariadb, "IRONIC_DB_DIR"))
+		assert.Equal(t, "/var/lib/metal3/ironic-db-quarantine/$(METAL3_CONTROLLER)", envValue(mariadb, "IRONIC_DB_QUARANTINE_DIR"))
+		assert.Equal(t, "3", envValue(mariadb, "IRONIC_DB_QUARANTINE_KEEP"))
+		assert.True(t, hasVolumeMount(mariadb, ironicDBVolume, "/var/lib/mysql"))
+		assert.True(t, hasVolumeMount(mariadb, ironicDBQuarantineVolume, IronicDBQuarantineHostPath))
+		assert.True(t, hasVolumeMount(mariadb, ironicDBRecoveryVolume, ironicDBRecoveryMountPath))
+	}
+	conductor := findContainer(spec.Containers, "metal3-ironic-conductor")
+	if assert.NotNil(t, conductor) {
+		assert.Equal(t, waitingForDownloads("/bin/runironic-conductor"), conductor.Command)
+		assert.Equal(t, "", envValue(conductor, "IRONIC_DB_DIR"))
+	}
+
+	request := findVolume(spec.Volumes, ironicDBRecoveryVolume)
+	if assert.NotNil(t, request) && assert.NotNil(t, request.DownwardAPI) {
+		assert.Equal(t, "metadata.annotations['metal3.io/ironic-db-recovery']", request.DownwardAPI.Items[0].FieldRef.FieldPath)
+	}
+	quarantine := findVolume(spec.Volumes, ironicDBQuarantineVolume)
+	if assert.NotNil(t, quarantine) && assert.NotNil(t, quarantine.HostPath) {
+		assert.Equal(t, IronicDBQuarantineHostPath, quarantine.HostPath.Path)
+	}
+}
+
+func TestIronicDBRecoverySQLite(t *testing.T) {
+	t.Run("MinimalProfile", func(t *testing.T) {
+		config := managedProvisioning()
+		config.Profile = metal3iov1alpha1.ProvisioningProfileMinimal
+		containers := NewMetal3Deployment(testNamespace, &testImages, config).Spec.Template.Spec.Containers
+
+		conductor := findContainer(containers, "metal3-ironic-conductor")
+		if assert.NotNil(t, conductor) {
+			assert.Equal(t, append([]string{"/bin/sh", "-c", ironicDBQuarantineScript, "ironic-db-recovery"}, waitingForDownloads("/bin/runironic-conductor")...), conductor.Command)
+			assert.Equal(t, "/var/lib/ironic", envValue(conductor, "IRONIC_DB_DIR"))
+			assert.True(t, hasVolumeMount(conductor, ironicDBVolume, "/var/lib/ironic"))
+		}
+	})
+
+	t.Run("SingleNode", func(t *testing.T) {
+		spec := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning()).Spec.Template.Spec
+		ApplyTopologyProfile(&spec, metal3iov1alpha1.TopologyProfileSingleNode)
+
+		assert.Nil(t, findContainer(spec.Containers, "metal3-mariadb"))
+		conductor := findContainer(spec.Containers, "metal3-ironic-conductor")
+		if assert.NotNil(t, conductor) {
+			assert.Equal(t, "/var/lib/ironic", envValue(conductor, "IRONIC_DB_DIR"))
+			assert.Equal(t, append([]string{"/bin/sh", "-c", ironicDBQuarantineScript, "ironic-db-recovery"}, waitingForDownloads("/bin/runironic-conductor")...), conductor.Command)
+		}
+
+		// Applying the profile again does not wrap the command twice
+		ApplyTopologyProfile(&spec, metal3iov1alpha1.TopologyProfileSingleNode)
+		conductor = findContainer(spec.Containers, "metal3-ironic-conductor")
+		assert.Len(t, conductor.Command, 9)
+	})
+}
+
+func TestIsIronicDBCorruption(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected bool
+	}{
+		{message: "2021-01-01 12:00:00 0 [ERROR] InnoDB: Database page corruption on disk or a failed file read of tablespace ironic/nodes page [page id: space=5, page number=3]", expected: true},
+		{message: "[ERROR] mysqld: Table './ironic/nodes' is marked as crashed and should be repaired", expected: true},
+		{message: "[ERROR] Plugin 'InnoDB' init function returned error.\n[ERROR] InnoDB: Plugin initialization aborted with error Data structure corruption", expected: true},
+		{message: "sqlite3.DatabaseError: database disk image is malformed", expected: true},
+		{message: "sqlalchemy.exc.OperationalError: (pymysql.err.OperationalError) (2003, \"Can't connect to MySQL server\")"},
+		{message: ""},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, IsIronicDBCorruption(tc.message), tc.message)
+	}
+}
+
+func TestIsIronicDBContainer(t *testing.T) {
+	assert.True(t, IsIronicDBContainer("metal3-mariadb"))
+	assert.True(t, IsIronicDBContainer("metal3-ironic-conductor"))
+	assert.False(t, IsIronicDBContainer("metal3-ironic-api"))
+}
+
+func TestIronicDBQuarantineScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ironic-db-recovery")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	dbDir := filepath.Join(dir, "db")
+	quarantineDir := filepath.Join(dir, "quarantine")
+	requestPath := filepath.Join(dir, "request")
+	assert.NoError(t, os.MkdirAll(dbDir, 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dbDir, "ibdata1"), []byte("corrupted"), 0644))
+	assert.NoError(t, ioutil.WriteFile(requestPath, []byte("recovery-4"), 0644))
+	// The older quarantined databases, and a move a restart interrupted
+	now := time.Now()
+	for i, name := range []string{"recovery-1", "recovery-2", "recovery-3", "recovery-0.part"} {
+		path := filepath.Join(quarantineDir, name)
+		assert.NoError(t, os.MkdirAll(path, 0755))
+		mtime := now.Add(time.Duration(i-10) * time.Hour)
+		assert.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	script := strings.Replace(ironicDBQuarantineScript, ironicDBRecoveryRequestedPath, requestPath, 1)
+	cmd := exec.Command("sh", "-c", script, ironicDBRecoveryCommandName, "true")
+	cmd.Env = append(os.Environ(),
+		"IRONIC_DB_DIR="+dbDir,
+		"IRONIC_DB_QUARANTINE_DIR="+quarantineDir,
+		"IRONIC_DB_QUARANTINE_KEEP=2")
+	output, err := cmd.CombinedOutput()
+	if !assert.NoError(t, err, string(output)) {
+		return
+	}
+
+	entries, err := ioutil.ReadDir(quarantineDir)
+	if !assert.NoError(t, err) {
+		return
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{"recovery-0.part", "recovery-3", "recovery-4"}, names)
+	assert.FileExists(t, filepath.Join(quarantineDir, "recovery-4", "ibdata1"))
+	db, err := ioutil.ReadDir(dbDir)
+	assert.NoError(t, err)
+	assert.Empty(t, db)
+}
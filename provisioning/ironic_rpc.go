@@ -0,0 +1,73 @@
+package provisioning
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	ironicRPCTransportEnvVar = ironicDefaultEnvPrefix + "RPC_TRANSPORT"
+	jsonRPCPortEnvVar        = "OS_JSON_RPC__PORT"
+	jsonRPCPortName          = "json-rpc"
+	// ironicCommand runs the Ironic API and conductor in a single process
+	ironicCommand = "/bin/runironic"
+)
+
+// GetIronicRPCTransport returns how the Ironic API calls the conductors
+func GetIronicRPCTransport(config *metal3iov1alpha1.ProvisioningSpec) metal3iov1alpha1.IronicRPCTransport {
+	if config.IronicRPCTransport == "" {
+		return metal3iov1alpha1.IronicRPCTransportJSONRPC
+	}
+	return config.IronicRPCTransport
+}
+
+// newIronicRPCEnv returns the environment of the Ironic API and conductor
+// selecting the RPC transport
+func newIronicRPCEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	if GetIronicRPCTransport(config) == metal3iov1alpha1.IronicRPCTransportLocal {
+		return []corev1.EnvVar{{Name: ironicRPCTransportEnvVar, Value: "none"}}
+	}
+	return []corev1.EnvVar{
+		{Name: ironicRPCTransportEnvVar, Value: "json-rpc"},
+		{Name: jsonRPCPortEnvVar, Value: strconv.Itoa(ConductorJSONRPCPort)},
+	}
+}
+
+// newJSONRPCPorts returns the port the conductor is called on, if any
+func newJSONRPCPorts(config *metal3iov1alpha1.ProvisioningSpec) []corev1.ContainerPort {
+	if GetIronicRPCTransport(config) == metal3iov1alpha1.IronicRPCTransportLocal {
+		return nil
+	}
+	return []corev1.ContainerPort{{Name: jsonRPCPortName, ContainerPort: ConductorJSONRPCPort}}
+}
+
+// newIronicContainers returns the containers of the Ironic conductor and
+// API. With the Local transport the conductor container serves the API
+// as well, authenticating its clients like the API container does.
+func newIronicContainers(images *Images, config *metal3iov1alpha1.ProvisioningSpec) []corev1.Container {
+	conductor := createContainerMetal3IronicConductor(images, config)
+	if GetIronicRPCTransport(config) != metal3iov1alpha1.IronicRPCTransportLocal {
+		return []corev1.Container{conductor, createContainerMetal3IronicApi(images, config)}
+	}
+	conductor.Command = []string{ironicCommand}
+	conductor.Env = append(conductor.Env, setIronicHtpasswdHash(htpasswdEnvVar, ironicSecretName))
+	return []corev1.Container{conductor}
+}
+
+// validateIronicRPCTransport checks that the Local transport is only used
+// when the metal3 pod runs the only conductor, which is still the case
+// with HighAvailability as only the active metal3 pod runs one
+func validateIronicRPCTransport(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if GetIronicRPCTransport(config) != metal3iov1alpha1.IronicRPCTransportLocal {
+		return nil
+	}
+	if len(config.ConductorGroups) > 0 {
+		return fmt.Errorf("IronicRPCTransport %s cannot be used with ConductorGroups, the Ironic API calls the conductors of the groups",
+			metal3iov1alpha1.IronicRPCTransportLocal)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestIronicRPCTransportJSONRPC(t *testing.T) {
+	spec := managedProvisioning()
+	containers := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+
+	conductor := findContainer(containers, "metal3-ironic-conductor")
+	api := findContainer(containers, "metal3-ironic-api")
+	if assert.NotNil(t, conductor) && assert.NotNil(t, api) {
+		assert.Equal(t, waitingForDownloads("/bin/runironic-conductor"), conductor.Command)
+		assert.Equal(t, []corev1.ContainerPort{{Name: "json-rpc", ContainerPort: 8089}}, conductor.Ports)
+		for _, container := range []*corev1.Container{conductor, api} {
+			assert.Equal(t, "json-rpc", envValue(container, "OS_DEFAULT__RPC_TRANSPORT"), container.Name)
+			assert.Equal(t, "8089", envValue(container, "OS_JSON_RPC__PORT"), container.Name)
+		}
+	}
+
+	// The conductors of the groups are called by the API of the metal3 pod
+	group := newConductorGroupContainers(&testImages, spec, "rack-a")
+	assert.Equal(t, "json-rpc", envValue(findContainer(group, "metal3-ironic-conductor"), "OS_DEFAULT__RPC_TRANSPORT"))
+}
+
+func TestIronicRPCTransportLocal(t *testing.T) {
+	spec := managedProvisioning()
+	spec.IronicRPCTransport = metal3iov1alpha1.IronicRPCTransportLocal
+	containers := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+
+	assert.Nil(t, findContainer(containers, "metal3-ironic-api"))
+	conductor := findContainer(containers, "metal3-ironic-conductor")
+	if assert.NotNil(t, conductor) {
+		assert.Equal(t, waitingForDownloads("/bin/runironic"), conductor.Command)
+		assert.Empty(t, conductor.Ports)
+		assert.Equal(t, "none", envValue(conductor, "OS_DEFAULT__RPC_TRANSPORT"))
+		assert.Equal(t, "", envValue(conductor, "OS_JSON_RPC__PORT"))
+		assert.Contains(t, conductor.Env, setIronicHtpasswdHash(htpasswdEnvVar, ironicSecretName))
+	}
+}
+
+func TestValidateIronicRPCTransport(t *testing.T) {
+	tests := []struct {
+		name          string
+		transport     metal3iov1alpha1.IronicRPCTransport
+		configure     func(*metal3iov1alpha1.ProvisioningSpec)
+		expectedError string
+	}{
+		{
+			name: "Default",
+		},
+		{
+			name:      "Local",
+			transport: metal3iov1alpha1.IronicRPCTransportLocal,
+		},
+		{
+			name:      "JSONRPCWithHighAvailability",
+			transport: metal3iov1alpha1.IronicRPCTransportJSONRPC,
+			configure: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: 2}
+			},
+		},
+		{
+			name:      "LocalWithHighAvailability",
+			transport: metal3iov1alpha1.IronicRPCTransportLocal,
+			configure: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: 2}
+			},
+		},
+		{
+			name:      "LocalWithConductorGroups",
+			transport: metal3iov1alpha1.IronicRPCTransportLocal,
+			configure: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.ConductorGroups = []metal3iov1alpha1.ConductorGroup{{Name: "rack-a"}}
+			},
+			expectedError: "cannot be used with ConductorGroups",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.IronicRPCTransport = tc.transport
+			if tc.configure != nil {
+				tc.configure(spec)
+			}
+			err := validateIronicRPCTransport(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package provisioning
+
+import (
+	"context"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// LegacyTakeoverRequestedAnnotation is set on the metal3 Deployment of
+	// the machine-api-operator, with the time the operator asked to take
+	// it over
+	LegacyTakeoverRequestedAnnotation = "baremetal.openshift.io/takeover-requested"
+	// LegacyTakeoverAcknowledgedAnnotation is set by the machine-api-operator
+	// on its metal3 Deployment once it stopped managing it
+	LegacyTakeoverAcknowledgedAnnotation = "baremetal.openshift.io/takeover-acknowledged"
+
+	// legacyTakeoverTimeout is how long the machine-api-operator is given
+	// to acknowledge the takeover. The versions predating the handshake
+	// stopped managing metal3 once upgraded, and never acknowledge it.
+	legacyTakeoverTimeout = 5 * time.Minute
+)
+
+// legacySecretKeys are the keys the secrets of the metal3 pod must hold.
+// Secrets left by the machine-api-operator without them are removed, so
+// that they are created again.
+var legacySecretKeys = []struct {
+	name string
+	keys []string
+}{
+	{name: baremetalSecretName, keys: []string{baremetalSecretKey}},
+	{name: ironicSecretName, keys: []string{ironicUsernameKey, ironicPasswordKey, ironicHtpasswdKey, ironicConfigKey}},
+	{name: inspectorSecretName, keys: []string{ironicUsernameKey, ironicPasswordKey, ironicHtpasswdKey, ironicConfigKey}},
+}
+
+// isLegacyMetal3Deployment tells whether the metal3 Deployment was not
+// created by the operator, which always makes a Provisioning its controller
+func isLegacyMetal3Deployment(deployment *appsv1.Deployment) bool {
+	owner := metav1.GetControllerOf(deployment)
+	return owner == nil || owner.Kind != "Provisioning" ||
+		owner.APIVersion != metal3iov1alpha1.GroupVersion.String()
+}
+
+// takeoverAllowed tells whether the machine-api-operator acknowledged the
+// takeover of its Deployment, or failed to within legacyTakeoverTimeout
+func takeoverAllowed(deployment *appsv1.Deployment, now time.Time) bool {
+	if _, ok := deployment.Annotations[LegacyTakeoverAcknowledgedAnnotation]; ok {
+		return true
+	}
+	requested, err := time.Parse(time.RFC3339, deployment.Annotations[LegacyTakeoverRequestedAnnotation])
+	return err == nil && now.Sub(requested) >= legacyTakeoverTimeout
+}
+
+// removeIncompatibleSecrets deletes the secrets of the metal3 pod missing
+// any of the keys the operator relies on
+func removeIncompatibleSecrets(client coreclientv1.SecretsGetter, namespace string) error {
+	for _, expected := range legacySecretKeys {
+		secret, err := client.Secrets(namespace).Get(context.Background(), expected.name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return apiError(err)
+		}
+		if hasSecretKeys(secret, expected.keys) {
+			continue
+		}
+		err = client.Secrets(namespace).Delete(context.Background(), expected.name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return apiError(err)
+		}
+	}
+	return nil
+}
+
+func hasSecretKeys(secret *corev1.Secret, keys []string) bool {
+	for _, key := range keys {
+		if len(secret.Data[key]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// MigrateLegacyMetal3 takes over the metal3 resources left by the
+// machine-api-operator, so that two Ironics never run at once. Their
+// Deployment is first annotated to request the takeover; once it is
+// acknowledged, the secrets the operator cannot use are removed and the
+// Deployment is adopted when its selector matches the operator's,
+// otherwise it is deleted along with its pods. It returns false while the
+// migration is in progress.
+func MigrateLegacyMetal3(apps appsclientv1.DeploymentsGetter, core coreclientv1.SecretsGetter, namespace string, now time.Time) (bool, error) {
+	deployment, err := apps.Deployments(namespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, apiError(err)
+	}
+	if !isLegacyMetal3Deployment(deployment) {
+		return true, nil
+	}
+	if deployment.DeletionTimestamp != nil {
+		// Waiting for the legacy pods to be removed
+		return false, nil
+	}
+
+	if _, ok := deployment.Annotations[LegacyTakeoverRequestedAnnotation]; !ok {
+		updated := deployment.DeepCopy()
+		metav1.SetMetaDataAnnotation(&updated.ObjectMeta, LegacyTakeoverRequestedAnnotation, now.UTC().Format(time.RFC3339))
+		_, err := apps.Deployments(namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+		return false, apiError(err)
+	}
+	if !takeoverAllowed(deployment, now) {
+		return false, nil
+	}
+
+	if err := removeIncompatibleSecrets(core, namespace); err != nil {
+		return false, err
+	}
+	if equality.Semantic.DeepEqual(deployment.Spec.Selector, metal3PodSelector()) {
+		// The Deployment is updated, and made owned by the Provisioning
+		// resource, when applying the metal3 Deployment
+		return true, nil
+	}
+	// The selector of a Deployment cannot be changed. The pods are removed
+	// before the Deployment, so that they are gone once it is recreated.
+	propagation := metav1.DeletePropagationForeground
+	err = apps.Deployments(namespace).Delete(context.Background(), baremetalDeploymentName, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return false, apiError(err)
+	}
+	return false, nil
+}
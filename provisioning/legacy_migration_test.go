@@ -0,0 +1,148 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func newLegacyMetal3Deployment(selector map[string]string, annotations map[string]string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        baremetalDeploymentName,
+			Namespace:   testNamespace,
+			Annotations: annotations,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+		},
+	}
+}
+
+func TestMigrateLegacyMetal3(t *testing.T) {
+	now := time.Now()
+	requested := map[string]string{LegacyTakeoverRequestedAnnotation: now.Add(-time.Minute).UTC().Format(time.RFC3339)}
+	acknowledged := map[string]string{
+		LegacyTakeoverRequestedAnnotation:    now.Add(-time.Minute).UTC().Format(time.RFC3339),
+		LegacyTakeoverAcknowledgedAnnotation: "",
+	}
+	timedOut := map[string]string{LegacyTakeoverRequestedAnnotation: now.Add(-legacyTakeoverTimeout).UTC().Format(time.RFC3339)}
+	legacySelector := map[string]string{"api": "clusterapi", "k8s-app": "controller"}
+	owned := newLegacyMetal3Deployment(metal3PodSelector().MatchLabels, nil)
+	owned.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: metal3iov1alpha1.GroupVersion.String(),
+		Kind:       "Provisioning",
+		Name:       metal3iov1alpha1.ProvisioningSingletonName,
+		Controller: pointer.BoolPtr(true),
+	}}
+
+	tCases := []struct {
+		name               string
+		deployment         *appsv1.Deployment
+		expectedMigrated   bool
+		expectedRequested  bool
+		expectedDeployment bool
+	}{
+		{
+			name:             "NoDeployment",
+			expectedMigrated: true,
+		},
+		{
+			name:               "OwnedDeployment",
+			deployment:         owned,
+			expectedMigrated:   true,
+			expectedDeployment: true,
+		},
+		{
+			name:               "TakeoverRequested",
+			deployment:         newLegacyMetal3Deployment(legacySelector, nil),
+			expectedRequested:  true,
+			expectedDeployment: true,
+		},
+		{
+			name:               "WaitingForAcknowledgement",
+			deployment:         newLegacyMetal3Deployment(legacySelector, requested),
+			expectedRequested:  true,
+			expectedDeployment: true,
+		},
+		{
+			name:              "AcknowledgedDeleted",
+			deployment:        newLegacyMetal3Deployment(legacySelector, acknowledged),
+			expectedRequested: true,
+		},
+		{
+			name:              "TimedOutDeleted",
+			deployment:        newLegacyMetal3Deployment(legacySelector, timedOut),
+			expectedRequested: true,
+		},
+		{
+			name:               "AcknowledgedAdopted",
+			deployment:         newLegacyMetal3Deployment(metal3PodSelector().MatchLabels, acknowledged),
+			expectedMigrated:   true,
+			expectedRequested:  true,
+			expectedDeployment: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objects := []runtime.Object{}
+			if tc.deployment != nil {
+				objects = append(objects, tc.deployment)
+			}
+			kubeClient := fakekube.NewSimpleClientset(objects...)
+
+			migrated, err := MigrateLegacyMetal3(kubeClient.AppsV1(), kubeClient.CoreV1(), testNamespace, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedMigrated, migrated)
+
+			deployment, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+			if !tc.expectedDeployment {
+				assert.True(t, apierrors.IsNotFound(err))
+				return
+			}
+			assert.NoError(t, err)
+			_, requested := deployment.Annotations[LegacyTakeoverRequestedAnnotation]
+			assert.Equal(t, tc.expectedRequested, requested)
+		})
+	}
+}
+
+func TestMigrateLegacyMetal3Secrets(t *testing.T) {
+	deployment := newLegacyMetal3Deployment(map[string]string{"k8s-app": "controller"}, map[string]string{
+		LegacyTakeoverRequestedAnnotation:    time.Now().UTC().Format(time.RFC3339),
+		LegacyTakeoverAcknowledgedAnnotation: "",
+	})
+	mariadb := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: baremetalSecretName, Namespace: testNamespace},
+		Data:       map[string][]byte{baremetalSecretKey: []byte("password")},
+	}
+	ironic := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ironicSecretName, Namespace: testNamespace},
+		Data:       map[string][]byte{ironicPasswordKey: []byte("password")},
+	}
+	kubeClient := fakekube.NewSimpleClientset(deployment, mariadb, ironic)
+
+	migrated, err := MigrateLegacyMetal3(kubeClient.AppsV1(), kubeClient.CoreV1(), testNamespace, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, migrated)
+
+	_, err = kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), baremetalSecretName, metav1.GetOptions{})
+	assert.NoError(t, err, "compatible secret kept")
+	_, err = kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), ironicSecretName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "incompatible secret removed")
+
+	migrated, err = MigrateLegacyMetal3(kubeClient.AppsV1(), kubeClient.CoreV1(), testNamespace, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, migrated)
+}
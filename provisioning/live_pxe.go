@@ -0,0 +1,135 @@
+package provisioning
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	livePXEDownloaderName = "metal3-live-pxe-downloader"
+	// livePXESubPath is the directory of the image server the live PXE
+	// artifacts are published in, under names that do not change with
+	// the release so that their URLs stay stable
+	livePXESubPath   = "images/live"
+	livePXEKernel    = "kernel"
+	livePXEInitramfs = "initramfs.img"
+	livePXERootfs    = "rootfs.img"
+)
+
+// livePXEDownloaderScript downloads each live PXE artifact next to its
+// published file, checks its sha256 when one is given, and only then
+// moves it in place. The URL it was downloaded from is recorded, so that
+// a restart of the pod does not download the artifacts again unless they
+// were changed.
+const livePXEDownloaderScript = `set -eu
+mkdir -p /shared/html/images/live
+download() {
+    url="$1"
+    checksum="$2"
+    target="/shared/html/images/live/$3"
+    if [ -f "${target}" ] && [ "$(cat "${target}.source" 2>/dev/null)" = "${url}#${checksum}" ]; then
+        echo "${target} already downloaded"
+        return
+    fi
+    echo "downloading ${url} to ${target}"
+    curl -g --fail --silent --show-error --location --retry 5 -o "${target}.part" "${url}"
+    if [ -n "${checksum}" ]; then
+        echo "${checksum}  ${target}.part" | sha256sum -c -
+    fi
+    mv "${target}.part" "${target}"
+    echo "${url}#${checksum}" > "${target}.source"
+}
+download "${LIVE_PXE_KERNEL_URL}" "${LIVE_PXE_KERNEL_SHA256}" kernel
+download "${LIVE_PXE_INITRAMFS_URL}" "${LIVE_PXE_INITRAMFS_SHA256}" initramfs.img
+download "${LIVE_PXE_ROOTFS_URL}" "${LIVE_PXE_ROOTFS_SHA256}" rootfs.img
+`
+
+// livePXEArtifact is a live PXE artifact, as named in the spec and in the
+// environment of the downloader
+type livePXEArtifact struct {
+	field string
+	env   string
+	url   string
+}
+
+func getLivePXEArtifacts(artifacts *metal3iov1alpha1.LivePXEArtifacts) []livePXEArtifact {
+	return []livePXEArtifact{
+		{field: "KernelURL", env: "LIVE_PXE_KERNEL", url: artifacts.KernelURL},
+		{field: "InitramfsURL", env: "LIVE_PXE_INITRAMFS", url: artifacts.InitramfsURL},
+		{field: "RootfsURL", env: "LIVE_PXE_ROOTFS", url: artifacts.RootfsURL},
+	}
+}
+
+// splitLivePXEURL returns the URL of a live PXE artifact without its
+// sha256 query parameter, along with the checksum it held
+func splitLivePXEURL(rawURL string) (string, string) {
+	artifactURL, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL, ""
+	}
+	query, checksum := splitChecksumQuery(artifactURL.RawQuery)
+	artifactURL.RawQuery = query
+	return artifactURL.String(), checksum
+}
+
+// validateLivePXEArtifacts checks that the live PXE artifacts are
+// downloaded from http or https URLs, with a well-formed checksum
+func validateLivePXEArtifacts(artifacts *metal3iov1alpha1.LivePXEArtifacts) error {
+	if artifacts == nil {
+		return nil
+	}
+	length := osImageChecksumLengths[metal3iov1alpha1.OSImageChecksumSHA256]
+	for _, artifact := range getLivePXEArtifacts(artifacts) {
+		artifactURL, err := url.Parse(artifact.url)
+		if err != nil || (artifactURL.Scheme != "http" && artifactURL.Scheme != "https") || artifactURL.Host == "" {
+			return fmt.Errorf("LivePXEArtifacts: invalid %s %q, an http or https URL is required", artifact.field, artifact.url)
+		}
+		_, checksum := splitChecksumQuery(artifactURL.RawQuery)
+		if checksum != "" && (len(checksum) != length || strings.Trim(strings.ToLower(checksum), "0123456789abcdef") != "") {
+			return fmt.Errorf("LivePXEArtifacts: the %s of %s must be %d hexadecimal digits", osImageChecksumQuery, artifact.field, length)
+		}
+	}
+	return nil
+}
+
+// getLivePXEURL returns the URL a live PXE artifact is published at
+func getLivePXEURL(config *metal3iov1alpha1.ProvisioningSpec, scheme string, port string, file string) string {
+	if config.LivePXEArtifacts == nil {
+		return ""
+	}
+	if artifactURL := getImageServerUrl(config, scheme, port, path.Join(livePXESubPath, file)); artifactURL != nil {
+		return *artifactURL
+	}
+	return ""
+}
+
+func createContainerMetal3LivePXEDownloader(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	env := []corev1.EnvVar{}
+	for _, artifact := range getLivePXEArtifacts(config.LivePXEArtifacts) {
+		artifactURL, checksum := splitLivePXEURL(artifact.url)
+		env = append(env,
+			corev1.EnvVar{Name: artifact.env + "_URL", Value: artifactURL},
+			corev1.EnvVar{Name: artifact.env + "_SHA256", Value: checksum},
+		)
+	}
+	container := corev1.Container{
+		Name:            livePXEDownloaderName,
+		Image:           images.BaremetalMachineOsDownloader,
+		Command:         []string{"/bin/sh", "-c", livePXEDownloaderScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env:          env,
+	}
+	addImagePullSecret(&container, config)
+	return container
+}
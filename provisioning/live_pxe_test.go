@@ -0,0 +1,94 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func testLivePXEArtifacts() *metal3iov1alpha1.LivePXEArtifacts {
+	return &metal3iov1alpha1.LivePXEArtifacts{
+		KernelURL:    "https://mirror.example.com/rhcos-live-kernel-x86_64?sha256=" + testSHA256,
+		InitramfsURL: "https://mirror.example.com/rhcos-live-initramfs.x86_64.img",
+		RootfsURL:    "https://mirror.example.com/rhcos-live-rootfs.x86_64.img?arch=x86_64&sha256=" + testSHA256,
+	}
+}
+
+func TestValidateLivePXEArtifacts(t *testing.T) {
+	tCases := []struct {
+		name          string
+		artifacts     func(*metal3iov1alpha1.LivePXEArtifacts)
+		expectedError string
+	}{
+		{
+			name:      "Valid",
+			artifacts: func(*metal3iov1alpha1.LivePXEArtifacts) {},
+		},
+		{
+			name: "NotHTTP",
+			artifacts: func(artifacts *metal3iov1alpha1.LivePXEArtifacts) {
+				artifacts.InitramfsURL = "file:///var/lib/rhcos-live-initramfs.x86_64.img"
+			},
+			expectedError: `LivePXEArtifacts: invalid InitramfsURL "file:///var/lib/rhcos-live-initramfs.x86_64.img", an http or https URL is required`,
+		},
+		{
+			name: "NoHost",
+			artifacts: func(artifacts *metal3iov1alpha1.LivePXEArtifacts) {
+				artifacts.KernelURL = "http:///rhcos-live-kernel-x86_64"
+			},
+			expectedError: `LivePXEArtifacts: invalid KernelURL "http:///rhcos-live-kernel-x86_64", an http or https URL is required`,
+		},
+		{
+			name: "InvalidChecksum",
+			artifacts: func(artifacts *metal3iov1alpha1.LivePXEArtifacts) {
+				artifacts.RootfsURL = "https://mirror.example.com/rhcos-live-rootfs.x86_64.img?sha256=abc"
+			},
+			expectedError: "LivePXEArtifacts: the sha256 of RootfsURL must be 64 hexadecimal digits",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			artifacts := testLivePXEArtifacts()
+			tc.artifacts(artifacts)
+			err := validateLivePXEArtifacts(artifacts)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+	assert.NoError(t, validateLivePXEArtifacts(nil))
+}
+
+func TestLivePXEDownloader(t *testing.T) {
+	config := managedProvisioning()
+	assert.Nil(t, findContainer(newMetal3DownloaderContainers(&testImages, config), livePXEDownloaderName))
+
+	config.LivePXEArtifacts = testLivePXEArtifacts()
+	downloader := findContainer(newMetal3DownloaderContainers(&testImages, config), livePXEDownloaderName)
+	if !assert.NotNil(t, downloader) {
+		return
+	}
+	assert.Equal(t, testImages.BaremetalMachineOsDownloader, downloader.Image)
+	assert.Contains(t, downloader.Command[2], livePXEDownloaderScript)
+	assert.Equal(t, "https://mirror.example.com/rhcos-live-kernel-x86_64", envValue(downloader, "LIVE_PXE_KERNEL_URL"))
+	assert.Equal(t, testSHA256, envValue(downloader, "LIVE_PXE_KERNEL_SHA256"))
+	assert.Equal(t, "https://mirror.example.com/rhcos-live-initramfs.x86_64.img", envValue(downloader, "LIVE_PXE_INITRAMFS_URL"))
+	assert.Equal(t, "", envValue(downloader, "LIVE_PXE_INITRAMFS_SHA256"))
+	assert.Equal(t, "https://mirror.example.com/rhcos-live-rootfs.x86_64.img?arch=x86_64", envValue(downloader, "LIVE_PXE_ROOTFS_URL"))
+	assert.Equal(t, testSHA256, envValue(downloader, "LIVE_PXE_ROOTFS_SHA256"))
+
+	// The artifacts are published once downloaded
+	httpd := findContainer(newMetal3Containers(&testImages, config), "metal3-httpd")
+	if assert.NotNil(t, httpd) {
+		assert.Contains(t, envValue(httpd, "DOWNLOADED_MARKERS"), "/shared/downloaded/"+livePXEDownloaderName)
+	}
+
+	status := GetImageServerStatus(config)
+	assert.Equal(t, "http://172.30.20.3:6180/images/live/kernel", status.HTTP.LiveKernel)
+	assert.Equal(t, "http://172.30.20.3:6180/images/live/initramfs.img", status.HTTP.LiveInitramfs)
+	assert.Equal(t, "http://172.30.20.3:6180/images/live/rootfs.img", status.HTTP.LiveRootfs)
+}
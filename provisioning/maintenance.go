@@ -0,0 +1,32 @@
+package provisioning
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+)
+
+// ScaleDownMetal3Deployment scales the metal3 Deployment to zero replicas.
+// Its spec is otherwise kept, so that leaving the maintenance mode scales
+// the same revision up again without a new rollout.
+func ScaleDownMetal3Deployment(client appsclientv1.DeploymentsGetter, targetNamespace string) error {
+	existing, err := client.Deployments(targetNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return apiError(err)
+	}
+	if existing.Spec.Replicas != nil && *existing.Spec.Replicas == 0 {
+		return nil
+	}
+	updated := existing.DeepCopy()
+	var replicas int32
+	updated.Spec.Replicas = &replicas
+	// The rendered Deployment is applied again when leaving the maintenance
+	delete(updated.Annotations, SpecHashAnnotation)
+	_, err = client.Deployments(targetNamespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	return apiError(err)
+}
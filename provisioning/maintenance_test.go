@@ -0,0 +1,35 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+)
+
+func TestScaleDownMetal3Deployment(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+	// Nothing to scale down before the first deployment
+	assert.NoError(t, ScaleDownMetal3Deployment(kubeClient.AppsV1(), testNamespace))
+
+	deployment := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+	deployment.Spec.Replicas = pointer.Int32Ptr(2)
+	kubeClient = fakekube.NewSimpleClientset(deployment)
+	assert.NoError(t, ScaleDownMetal3Deployment(kubeClient.AppsV1(), testNamespace))
+
+	scaled, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), *scaled.Spec.Replicas)
+	assert.Equal(t, deployment.Spec.Template, scaled.Spec.Template)
+
+	// Scaling up again restores the same revision
+	updated, err := ApplyMetal3Deployment(kubeClient.AppsV1(), deployment)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	restored, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, deployment.Spec, restored.Spec)
+}
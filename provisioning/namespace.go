@@ -0,0 +1,36 @@
+package provisioning
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namespaceLabels are the labels of the namespace of the metal3 services.
+// dnsmasq and the static IP manager need the host network and privileges,
+// which the privileged Pod Security level allows. The label sync of the
+// security context constraints would lower that level again, so it is
+// turned off. The metrics of the namespace are scraped by the cluster
+// monitoring.
+var namespaceLabels = map[string]string{
+	"pod-security.kubernetes.io/enforce":             "privileged",
+	"pod-security.kubernetes.io/audit":               "privileged",
+	"pod-security.kubernetes.io/warn":                "privileged",
+	"security.openshift.io/scc.podSecurityLabelSync": "false",
+	"openshift.io/cluster-monitoring":                "true",
+	"app.kubernetes.io/managed-by":                   "cluster-baremetal-operator",
+}
+
+// NewOperandNamespace returns the namespace the metal3 services run in,
+// for when it does not exist yet
+func NewOperandNamespace(name string) *corev1.Namespace {
+	labels := map[string]string{}
+	for key, value := range namespaceLabels {
+		labels[key] = value
+	}
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
@@ -0,0 +1,18 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOperandNamespace(t *testing.T) {
+	namespace := NewOperandNamespace("metal3")
+	assert.Equal(t, "metal3", namespace.Name)
+	assert.Equal(t, "privileged", namespace.Labels["pod-security.kubernetes.io/enforce"])
+	assert.Equal(t, "true", namespace.Labels["openshift.io/cluster-monitoring"])
+
+	// The labels of a namespace are not shared with the others
+	namespace.Labels["openshift.io/cluster-monitoring"] = "false"
+	assert.Equal(t, "true", NewOperandNamespace("other").Labels["openshift.io/cluster-monitoring"])
+}
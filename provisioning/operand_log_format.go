@@ -0,0 +1,150 @@
+package provisioning
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// LogFormatAnnotation tells the log collectors the format of the logs
+	// of the metal3 pods
+	LogFormatAnnotation = operatorMetadataPrefix + "log-format"
+
+	jsonLogWrapperEnvVar = "JSON_LOG_WRAPPER"
+	logComponentEnvVar   = "LOG_COMPONENT"
+
+	// inspectorCommand is the entrypoint of the Ironic Inspector image
+	inspectorCommand = "/bin/runironic-inspector"
+)
+
+// jsonLogComponents are the containers whose logs are written as JSON,
+// with the component each one reports
+var jsonLogComponents = map[string]string{
+	"metal3-ironic-conductor": "ironic-conductor",
+	"metal3-ironic-api":       "ironic-api",
+	"metal3-ironic-inspector": "ironic-inspector",
+	"metal3-dnsmasq":          "dnsmasq",
+}
+
+// jsonLogWrapperScript turns the output of a service into JSON records
+// with the same fields for every service: time, level, component, logger
+// and message, along with the request_id and traceback when known. The
+// records Ironic writes as JSON are converted, other lines are taken as
+// messages whose level is guessed from their content.
+const jsonLogWrapperScript = `
+import datetime
+import json
+import os
+import sys
+
+COMPONENT = os.environ.get("LOG_COMPONENT", "")
+
+
+def timestamp(created=None):
+    if created is None:
+        when = datetime.datetime.utcnow()
+    else:
+        when = datetime.datetime.utcfromtimestamp(created)
+    return when.strftime("%Y-%m-%dT%H:%M:%S.%fZ")
+
+
+def guess_level(line):
+    lower = line.lower()
+    if "warning" in lower:
+        return "warning"
+    if "error" in lower or "failed" in lower or "cannot" in lower:
+        return "error"
+    return "info"
+
+
+def from_oslo(record):
+    converted = {
+        "time": timestamp(record.get("created")),
+        "level": str(record.get("levelname", "info")).lower(),
+        "logger": record.get("name", ""),
+        "message": record.get("message", ""),
+    }
+    context = record.get("context") or {}
+    if isinstance(context, dict) and context.get("request_id"):
+        converted["request_id"] = context["request_id"]
+    traceback = record.get("traceback")
+    if traceback:
+        if isinstance(traceback, list):
+            traceback = "\n".join(traceback)
+        converted["traceback"] = str(traceback)
+    return converted
+
+
+for line in sys.stdin:
+    line = line.rstrip("\n")
+    if not line:
+        continue
+    record = None
+    if line.startswith("{"):
+        try:
+            parsed = json.loads(line)
+        except ValueError:
+            parsed = None
+        if isinstance(parsed, dict) and "levelname" in parsed:
+            record = from_oslo(parsed)
+    if record is None:
+        record = {"time": timestamp(), "level": guess_level(line), "logger": "", "message": line}
+    record["component"] = COMPONENT
+    print(json.dumps(record, sort_keys=True), flush=True)
+`
+
+// jsonLogRedirect sends the output of the shell, and of the service it
+// then executes, through the JSON log wrapper
+const jsonLogRedirect = `exec > >(python3 -u -c "$` + jsonLogWrapperEnvVar + `") 2>&1
+`
+
+// IsJSONLogFormat returns whether the metal3 services log JSON records
+func IsJSONLogFormat(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.OperandLogFormat == metal3iov1alpha1.OperandLogFormatJSON
+}
+
+// wrapJSONLogs runs the command of a container with its output converted
+// to JSON records. The service keeps the PID of the shell, so that it
+// still receives the signals sent to the container.
+func wrapJSONLogs(container *corev1.Container, command []string, component string) {
+	if len(command) == 3 && command[0] == "/bin/bash" && command[1] == "-c" {
+		container.Command = []string{"/bin/bash", "-c", jsonLogRedirect + command[2]}
+	} else {
+		container.Command = append([]string{"/bin/bash", "-c", jsonLogRedirect + `exec "$@"`, "json-log-wrapper"}, command...)
+	}
+	container.Env = append(container.Env,
+		corev1.EnvVar{Name: jsonLogWrapperEnvVar, Value: jsonLogWrapperScript},
+		corev1.EnvVar{Name: logComponentEnvVar, Value: component},
+	)
+}
+
+// applyOperandLogFormat makes the Ironic, Ironic Inspector and dnsmasq
+// containers of a pod template log JSON records when requested, and
+// annotates the template so that the log collectors parse them. Ironic
+// and Ironic Inspector write JSON themselves, so that their tracebacks
+// stay in a single record.
+func applyOperandLogFormat(template *corev1.PodTemplateSpec, config *metal3iov1alpha1.ProvisioningSpec) {
+	if !IsJSONLogFormat(config) {
+		return
+	}
+	for i := range template.Spec.Containers {
+		container := &template.Spec.Containers[i]
+		component, ok := jsonLogComponents[container.Name]
+		if !ok {
+			continue
+		}
+		command := container.Command
+		if len(command) == 0 && container.Name == "metal3-ironic-inspector" {
+			command = []string{inspectorCommand}
+		}
+		wrapJSONLogs(container, command, component)
+		if component != "dnsmasq" {
+			container.Env = append(container.Env, corev1.EnvVar{Name: ironicDefaultEnvPrefix + "USE_JSON", Value: "true"})
+		}
+	}
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[LogFormatAnnotation] = "json"
+}
@@ -0,0 +1,78 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestOperandLogFormat(t *testing.T) {
+	spec := managedProvisioning()
+	template := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template
+	assert.NotContains(t, template.Annotations, LogFormatAnnotation)
+	conductor := findContainer(template.Spec.Containers, "metal3-ironic-conductor")
+	assert.Equal(t, waitingForDownloads("/bin/runironic-conductor"), conductor.Command)
+	assert.Equal(t, "", envValue(conductor, jsonLogWrapperEnvVar))
+
+	spec.OperandLogFormat = metal3iov1alpha1.OperandLogFormatJSON
+	template = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template
+	assert.Equal(t, "json", template.Annotations[LogFormatAnnotation])
+	tests := []struct {
+		container string
+		component string
+		command   []string
+	}{
+		{
+			container: "metal3-ironic-conductor",
+			component: "ironic-conductor",
+			command:   append([]string{"/bin/bash", "-c", jsonLogRedirect + `exec "$@"`, "json-log-wrapper"}, waitingForDownloads("/bin/runironic-conductor")...),
+		},
+		{
+			container: "metal3-ironic-api",
+			component: "ironic-api",
+			command:   []string{"/bin/bash", "-c", jsonLogRedirect + `exec "$@"`, "json-log-wrapper", "/bin/runironic-api"},
+		},
+		{
+			container: "metal3-ironic-inspector",
+			component: "ironic-inspector",
+			command:   []string{"/bin/bash", "-c", jsonLogRedirect + `exec "$@"`, "json-log-wrapper", inspectorCommand},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.container, func(t *testing.T) {
+			container := findContainer(template.Spec.Containers, tc.container)
+			if assert.NotNil(t, container) {
+				assert.Equal(t, tc.command, container.Command)
+				assert.Equal(t, tc.component, envValue(container, logComponentEnvVar))
+				assert.Equal(t, jsonLogWrapperScript, envValue(container, jsonLogWrapperEnvVar))
+				assert.Equal(t, "true", envValue(container, "OS_DEFAULT__USE_JSON"))
+			}
+		})
+	}
+	// The other containers keep their logs
+	operator := findContainer(template.Spec.Containers, "metal3-baremetal-operator")
+	assert.Equal(t, []string{"/baremetal-operator"}, operator.Command)
+
+	dnsmasqTemplate := NewDnsmasqDaemonSet(testNamespace, &testImages, spec).Spec.Template
+	assert.Equal(t, "json", dnsmasqTemplate.Annotations[LogFormatAnnotation])
+	dnsmasq := findContainer(dnsmasqTemplate.Spec.Containers, "metal3-dnsmasq")
+	if assert.NotNil(t, dnsmasq) {
+		assert.Equal(t, "dnsmasq", envValue(dnsmasq, logComponentEnvVar))
+		assert.Equal(t, "", envValue(dnsmasq, "OS_DEFAULT__USE_JSON"))
+		assert.Equal(t, []string{"/bin/bash", "-c", jsonLogRedirect + dnsmasqServerScript + "exec /bin/rundnsmasq"}, dnsmasq.Command)
+	}
+}
+
+func TestWrapJSONLogsShellCommand(t *testing.T) {
+	spec := managedProvisioning()
+	spec.OperandLogFormat = metal3iov1alpha1.OperandLogFormatJSON
+	spec.OperandLogLevel = metal3iov1alpha1.OperandLogLevelDebug
+	dnsmasq := findContainer(NewDnsmasqDaemonSet(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, "metal3-dnsmasq")
+	if assert.NotNil(t, dnsmasq) && assert.Len(t, dnsmasq.Command, 3) {
+		// The configuration files are still written by the same shell
+		assert.Equal(t, "/bin/bash", dnsmasq.Command[0])
+		assert.Regexp(t, `^exec > >\(python3 .*\) 2>&1\nholds_provisioning_ip\(\) {(?s:.*)\nmkdir -p .* && exec /bin/rundnsmasq$`, dnsmasq.Command[2])
+	}
+}
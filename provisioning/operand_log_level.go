@@ -0,0 +1,68 @@
+package provisioning
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	dnsmasqLoggingConfigEnvVar = "DNSMASQ_LOGGING_CONFIG"
+	// Ironic and Ironic Inspector read the options of their [DEFAULT]
+	// section from the environment
+	ironicDefaultEnvPrefix = "OS_DEFAULT__"
+)
+
+// traceLogLevels are the oslo.log default_log_levels at the Trace level,
+// which keeps the libraries quieted by the Ironic defaults at DEBUG
+var traceLogLevels = []string{
+	"sqlalchemy=DEBUG",
+	"urllib3.connectionpool=DEBUG",
+	"requests=DEBUG",
+	"oslo_messaging=DEBUG",
+	"oslo_policy=DEBUG",
+	"keystoneauth=DEBUG",
+	"ironic_lib=DEBUG",
+	"sushy=DEBUG",
+	"eventlet.wsgi.server=DEBUG",
+}
+
+// GetOperandLogLevel returns the verbosity of the metal3 services
+func GetOperandLogLevel(config *metal3iov1alpha1.ProvisioningSpec) metal3iov1alpha1.OperandLogLevel {
+	if config.OperandLogLevel == "" {
+		return metal3iov1alpha1.OperandLogLevelNormal
+	}
+	return config.OperandLogLevel
+}
+
+// newOperandLogLevelEnv returns the environment of the Ironic and Ironic
+// Inspector containers setting their verbosity. Nothing is set at the
+// Normal level, so that the defaults of the images apply.
+func newOperandLogLevelEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	switch GetOperandLogLevel(config) {
+	case metal3iov1alpha1.OperandLogLevelDebug:
+		return []corev1.EnvVar{
+			{Name: ironicDefaultEnvPrefix + "DEBUG", Value: "true"},
+		}
+	case metal3iov1alpha1.OperandLogLevelTrace:
+		return []corev1.EnvVar{
+			{Name: ironicDefaultEnvPrefix + "DEBUG", Value: "true"},
+			{Name: ironicDefaultEnvPrefix + "DEFAULT_LOG_LEVELS", Value: strings.Join(traceLogLevels, ",")},
+		}
+	}
+	return nil
+}
+
+// getDnsmasqLoggingConfig returns the dnsmasq configuration logging the
+// DHCP exchanges, along with the debug messages at the Trace level
+func getDnsmasqLoggingConfig(config *metal3iov1alpha1.ProvisioningSpec) string {
+	switch GetOperandLogLevel(config) {
+	case metal3iov1alpha1.OperandLogLevelDebug:
+		return "log-dhcp"
+	case metal3iov1alpha1.OperandLogLevelTrace:
+		return "log-dhcp\nlog-debug"
+	}
+	return ""
+}
@@ -0,0 +1,68 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestOperandLogLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         metal3iov1alpha1.OperandLogLevel
+		expectedLevel metal3iov1alpha1.OperandLogLevel
+		expectedDebug string
+		expectedTrace bool
+		expectedConf  string
+	}{
+		{
+			name:          "Default",
+			expectedLevel: metal3iov1alpha1.OperandLogLevelNormal,
+		},
+		{
+			name:          "Normal",
+			level:         metal3iov1alpha1.OperandLogLevelNormal,
+			expectedLevel: metal3iov1alpha1.OperandLogLevelNormal,
+		},
+		{
+			name:          "Debug",
+			level:         metal3iov1alpha1.OperandLogLevelDebug,
+			expectedLevel: metal3iov1alpha1.OperandLogLevelDebug,
+			expectedDebug: "true",
+			expectedConf:  "log-dhcp",
+		},
+		{
+			name:          "Trace",
+			level:         metal3iov1alpha1.OperandLogLevelTrace,
+			expectedLevel: metal3iov1alpha1.OperandLogLevelTrace,
+			expectedDebug: "true",
+			expectedTrace: true,
+			expectedConf:  "log-dhcp\nlog-debug",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.OperandLogLevel = tc.level
+			assert.Equal(t, tc.expectedLevel, GetOperandLogLevel(spec))
+
+			podSpec := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+			for _, name := range []string{"metal3-ironic-conductor", "metal3-ironic-api", "metal3-ironic-inspector"} {
+				container := findContainer(podSpec.Containers, name)
+				if assert.NotNil(t, container, name) {
+					assert.Equal(t, tc.expectedDebug, envValue(container, "OS_DEFAULT__DEBUG"), name)
+					assert.Equal(t, tc.expectedTrace, envValue(container, "OS_DEFAULT__DEFAULT_LOG_LEVELS") != "", name)
+				}
+			}
+			// The baremetal-operator keeps its own verbosity
+			assert.Equal(t, "", envValue(findContainer(podSpec.Containers, "metal3-baremetal-operator"), "OS_DEFAULT__DEBUG"))
+
+			dnsmasq := findContainer(NewDnsmasqDaemonSet(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, "metal3-dnsmasq")
+			if assert.NotNil(t, dnsmasq) {
+				assert.Equal(t, tc.expectedConf, envValue(dnsmasq, dnsmasqLoggingConfigEnvVar))
+			}
+		})
+	}
+}
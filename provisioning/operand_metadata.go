@@ -0,0 +1,121 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// operatorMetadataPrefix is the prefix of the labels and annotations
+// owned by the operator
+const operatorMetadataPrefix = "baremetal.openshift.io/"
+
+// operatorLabels are the labels the operator selects its operands with
+var operatorLabels = map[string]bool{
+	"k8s-app":    true,
+	"controller": true,
+}
+
+func isOperatorMetadataKey(key string) bool {
+	return operatorLabels[key] || strings.HasPrefix(key, operatorMetadataPrefix)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validateOperandMetadata(metadata *metal3iov1alpha1.OperandMetadata) error {
+	if metadata == nil {
+		return nil
+	}
+	for _, key := range sortedKeys(metadata.Labels) {
+		if isOperatorMetadataKey(key) {
+			return fmt.Errorf("OperandMetadata.Labels: %s is set by the operator", key)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("OperandMetadata.Labels: invalid key %s: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(metadata.Labels[key]); len(errs) > 0 {
+			return fmt.Errorf("OperandMetadata.Labels: invalid value for %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for _, key := range sortedKeys(metadata.Annotations) {
+		if isOperatorMetadataKey(key) {
+			return fmt.Errorf("OperandMetadata.Annotations: %s is set by the operator", key)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("OperandMetadata.Annotations: invalid key %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	return nil
+}
+
+// SetOperandMetadata adds the OperandMetadata to a generated resource,
+// without overriding the labels and annotations set by the operator. It
+// returns true when the resource was modified.
+func SetOperandMetadata(obj metav1.Object, config *metal3iov1alpha1.ProvisioningSpec) bool {
+	if config.OperandMetadata == nil {
+		return false
+	}
+	labels, labelsChanged := mergeMetadata(obj.GetLabels(), config.OperandMetadata.Labels)
+	annotations, annotationsChanged := mergeMetadata(obj.GetAnnotations(), config.OperandMetadata.Annotations)
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return labelsChanged || annotationsChanged
+}
+
+func mergeMetadata(existing map[string]string, added map[string]string) (map[string]string, bool) {
+	changed := false
+	for key, value := range added {
+		if isOperatorMetadataKey(key) {
+			continue
+		}
+		if current, ok := existing[key]; ok && current == value {
+			continue
+		}
+		if existing == nil {
+			existing = map[string]string{}
+		}
+		existing[key] = value
+		changed = true
+	}
+	return existing, changed
+}
+
+// ApplyOperandSecretsMetadata adds the OperandMetadata to the Secrets
+// generated by the operator. Those are only created once, so their
+// metadata is updated separately.
+func ApplyOperandSecretsMetadata(client coreclientv1.SecretsGetter, targetNamespace string, config *metal3iov1alpha1.ProvisioningSpec) error {
+	if config.OperandMetadata == nil {
+		return nil
+	}
+	for _, name := range []string{baremetalSecretName, ironicSecretName, inspectorSecretName, imageServerTlsSecretName} {
+		secret, err := client.Secrets(targetNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return apiError(err)
+		}
+		if !SetOperandMetadata(secret, config) {
+			continue
+		}
+		if _, err := client.Secrets(targetNamespace).Update(context.Background(), secret, metav1.UpdateOptions{}); err != nil {
+			return apiError(err)
+		}
+	}
+	return nil
+}
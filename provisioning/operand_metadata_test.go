@@ -0,0 +1,123 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateOperandMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		metadata    *metal3iov1alpha1.OperandMetadata
+		expectedErr bool
+	}{
+		{
+			name: "NotSet",
+		},
+		{
+			name: "Valid",
+			metadata: &metal3iov1alpha1.OperandMetadata{
+				Labels:      map[string]string{"example.com/cost-center": "1234"},
+				Annotations: map[string]string{"example.com/owner": "Infrastructure team <infra@example.com>"},
+			},
+		},
+		{
+			name:        "OperatorLabel",
+			metadata:    &metal3iov1alpha1.OperandMetadata{Labels: map[string]string{"k8s-app": "other"}},
+			expectedErr: true,
+		},
+		{
+			name:        "OperatorAnnotation",
+			metadata:    &metal3iov1alpha1.OperandMetadata{Annotations: map[string]string{RolloutHashAnnotation: "0"}},
+			expectedErr: true,
+		},
+		{
+			name:        "InvalidLabelKey",
+			metadata:    &metal3iov1alpha1.OperandMetadata{Labels: map[string]string{"cost center": "1234"}},
+			expectedErr: true,
+		},
+		{
+			name:        "InvalidLabelValue",
+			metadata:    &metal3iov1alpha1.OperandMetadata{Labels: map[string]string{"owner": "infra@example.com"}},
+			expectedErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateOperandMetadata(tc.metadata)
+			assert.Equal(t, tc.expectedErr, err != nil, "%v", err)
+		})
+	}
+}
+
+func TestOperandMetadataPropagation(t *testing.T) {
+	spec := managedProvisioning()
+	spec.OperandMetadata = &metal3iov1alpha1.OperandMetadata{
+		Labels:      map[string]string{"example.com/cost-center": "1234"},
+		Annotations: map[string]string{"example.com/owner": "infra"},
+	}
+	for name, obj := range map[string]metav1.Object{
+		"Deployment": NewMetal3Deployment(testNamespace, &testImages, spec),
+		"DaemonSet":  NewDnsmasqDaemonSet(testNamespace, &testImages, spec),
+		"Service":    NewIronicAPIProxyService(testNamespace, spec),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, "1234", obj.GetLabels()["example.com/cost-center"])
+			assert.Equal(t, "infra", obj.GetAnnotations()["example.com/owner"])
+			assert.NotEmpty(t, obj.GetLabels()["k8s-app"])
+		})
+	}
+}
+
+func TestApplyMetal3DeploymentLabels(t *testing.T) {
+	spec := managedProvisioning()
+	kubeClient := fakekube.NewSimpleClientset()
+	updated, err := ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, spec))
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	spec.OperandMetadata = &metal3iov1alpha1.OperandMetadata{Labels: map[string]string{"example.com/cost-center": "1234"}}
+	updated, err = ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, spec))
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	deployment, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", deployment.Labels["example.com/cost-center"])
+
+	updated, err = ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, spec))
+	assert.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func TestApplyOperandSecretsMetadata(t *testing.T) {
+	spec := managedProvisioning()
+	spec.OperandMetadata = &metal3iov1alpha1.OperandMetadata{
+		Labels: map[string]string{"example.com/cost-center": "1234"},
+	}
+	kubeClient := fakekube.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ironicSecretName,
+			Namespace: testNamespace,
+			Labels:    map[string]string{"example.com/cost-center": "old", "example.com/team": "infra"},
+		},
+	})
+
+	assert.NoError(t, ApplyOperandSecretsMetadata(kubeClient.CoreV1(), testNamespace, spec))
+	secret, err := kubeClient.CoreV1().Secrets(testNamespace).Get(context.Background(), ironicSecretName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"example.com/cost-center": "1234", "example.com/team": "infra"}, secret.Labels)
+
+	kubeClient.ClearActions()
+	assert.NoError(t, ApplyOperandSecretsMetadata(kubeClient.CoreV1(), testNamespace, spec))
+	for _, action := range kubeClient.Actions() {
+		assert.NotEqual(t, "update", action.GetVerb())
+	}
+}
@@ -0,0 +1,139 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RolloutStateConfigMapName is the name of the ConfigMap tracking the
+	// verification of the metal3 Deployment rollouts
+	RolloutStateConfigMapName = "metal3-rollout-state"
+
+	rolloutHealthyHashKey       = "healthy-rollout-hash"
+	rolloutHealthyDeploymentKey = "healthy-deployment.yaml"
+	rolloutPendingHashKey       = "pending-rollout-hash"
+	rolloutPendingSinceKey      = "pending-since"
+	rolloutFailedHashKey        = "failed-rollout-hash"
+)
+
+// RolloutState records the last revision of the metal3 Deployment found
+// healthy, the revision being verified, and the last one rolled back.
+// Revisions are identified by their rollout hash.
+type RolloutState struct {
+	HealthyHash       string
+	HealthyDeployment *appsv1.Deployment
+	PendingHash       string
+	PendingSince      time.Time
+	FailedHash        string
+}
+
+// GetRolloutState reads the rollout state, which is empty before the first
+// rollout
+func GetRolloutState(client coreclientv1.ConfigMapsGetter, targetNamespace string) (*RolloutState, error) {
+	state := &RolloutState{}
+	configMap, err := client.ConfigMaps(targetNamespace).Get(context.Background(), RolloutStateConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state.HealthyHash = configMap.Data[rolloutHealthyHashKey]
+	state.PendingHash = configMap.Data[rolloutPendingHashKey]
+	state.FailedHash = configMap.Data[rolloutFailedHashKey]
+	if since := configMap.Data[rolloutPendingSinceKey]; since != "" {
+		if state.PendingSince, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, fmt.Errorf("invalid %s in %s ConfigMap: %v", rolloutPendingSinceKey, RolloutStateConfigMapName, err)
+		}
+	}
+	if manifest := configMap.Data[rolloutHealthyDeploymentKey]; manifest != "" {
+		state.HealthyDeployment = &appsv1.Deployment{}
+		if err := yaml.Unmarshal([]byte(manifest), state.HealthyDeployment); err != nil {
+			return nil, fmt.Errorf("invalid %s in %s ConfigMap: %v", rolloutHealthyDeploymentKey, RolloutStateConfigMapName, err)
+		}
+	}
+	return state, nil
+}
+
+// NewRolloutStateConfigMap returns the ConfigMap storing the rollout state
+func NewRolloutStateConfigMap(targetNamespace string, state *RolloutState) (*corev1.ConfigMap, error) {
+	data := map[string]string{
+		rolloutHealthyHashKey: state.HealthyHash,
+		rolloutPendingHashKey: state.PendingHash,
+		rolloutFailedHashKey:  state.FailedHash,
+	}
+	if !state.PendingSince.IsZero() {
+		data[rolloutPendingSinceKey] = state.PendingSince.UTC().Format(time.RFC3339)
+	}
+	if state.HealthyDeployment != nil {
+		manifest, err := yaml.Marshal(trimDeployment(state.HealthyDeployment))
+		if err != nil {
+			return nil, err
+		}
+		data[rolloutHealthyDeploymentKey] = string(manifest)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RolloutStateConfigMapName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: data,
+	}, nil
+}
+
+// trimDeployment only keeps what is needed to apply a rendered Deployment
+// again
+func trimDeployment(deployment *appsv1.Deployment) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        deployment.Name,
+			Namespace:   deployment.Namespace,
+			Labels:      deployment.Labels,
+			Annotations: deployment.Annotations,
+		},
+		Spec: deployment.Spec,
+	}
+}
+
+// ApplyRolloutStateConfigMap creates or updates the rollout state ConfigMap
+func ApplyRolloutStateConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	return ApplyBootArtifactsConfigMap(client, configMap)
+}
+
+// IsDeploymentRolledOut returns true once every replica of the Deployment
+// runs its current pod template and is available
+func IsDeploymentRolledOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	return status.ObservedGeneration >= deployment.Generation &&
+		status.UpdatedReplicas == replicas &&
+		status.AvailableReplicas == replicas &&
+		status.Replicas == replicas
+}
+
+// GetOperandHealthURLs returns the endpoints answering once the Ironic and
+// Inspector APIs of a metal3 pod are up. The pod uses the host network,
+// so its IP is the one of its node.
+func GetOperandHealthURLs(podIP string) []string {
+	return []string{
+		fmt.Sprintf("http://%s/", net.JoinHostPort(podIP, baremetalIronicPort)),
+		fmt.Sprintf("http://%s/", net.JoinHostPort(podIP, baremetalIronicInspectorPort)),
+	}
+}
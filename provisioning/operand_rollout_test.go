@@ -0,0 +1,82 @@
+package provisioning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+)
+
+func TestRolloutState(t *testing.T) {
+	client := fakekube.NewSimpleClientset()
+
+	state, err := GetRolloutState(client.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	assert.Equal(t, &RolloutState{}, state)
+
+	deployment := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+	SetRolloutHash(deployment, "0123456789abcdef")
+	state = &RolloutState{
+		HealthyHash:       "0123456789abcdef",
+		HealthyDeployment: deployment,
+		PendingHash:       "fedcba9876543210",
+		PendingSince:      time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	configMap, err := NewRolloutStateConfigMap(testNamespace, state)
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyRolloutStateConfigMap(client.CoreV1(), configMap))
+
+	actual, err := GetRolloutState(client.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	assert.Equal(t, state.HealthyHash, actual.HealthyHash)
+	assert.Equal(t, state.PendingHash, actual.PendingHash)
+	assert.True(t, state.PendingSince.Equal(actual.PendingSince))
+	assert.Empty(t, actual.FailedHash)
+	if assert.NotNil(t, actual.HealthyDeployment) {
+		assert.Equal(t, deployment.Name, actual.HealthyDeployment.Name)
+		assert.Equal(t, deployment.Annotations, actual.HealthyDeployment.Annotations)
+		assert.Equal(t, GetPodTemplateImages(&deployment.Spec.Template), GetPodTemplateImages(&actual.HealthyDeployment.Spec.Template))
+	}
+}
+
+func TestIsDeploymentRolledOut(t *testing.T) {
+	tCases := []struct {
+		name     string
+		status   appsv1.DeploymentStatus
+		expected bool
+	}{
+		{
+			name:     "RolledOut",
+			status:   appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			expected: true,
+		},
+		{
+			name:   "NotObserved",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+		{
+			name:   "Unavailable",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1},
+		},
+		{
+			name:   "OldReplicaRemaining",
+			status: appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{Status: tc.status}
+			deployment.Generation = 2
+			deployment.Spec.Replicas = pointer.Int32Ptr(1)
+			assert.Equal(t, tc.expected, IsDeploymentRolledOut(deployment))
+		})
+	}
+}
+
+func TestGetOperandHealthURLs(t *testing.T) {
+	assert.Equal(t, []string{"http://192.168.111.20:6385/", "http://192.168.111.20:5050/"}, GetOperandHealthURLs("192.168.111.20"))
+	assert.Equal(t, []string{"http://[fd00::20]:6385/", "http://[fd00::20]:5050/"}, GetOperandHealthURLs("fd00::20"))
+}
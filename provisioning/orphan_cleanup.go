@@ -0,0 +1,233 @@
+package provisioning
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// DHCPLeaseReleaseConfigMapName is the ConfigMap listing the MAC
+	// addresses whose DHCP leases dnsmasq must release
+	DHCPLeaseReleaseConfigMapName = "metal3-dhcp-lease-release"
+
+	dhcpLeaseReleaseVolume    = "metal3-dhcp-lease-release"
+	dhcpLeaseReleaseMountPath = "/etc/metal3/dhcp-lease-release"
+	dhcpLeaseReleaseIDKey     = "id"
+	dhcpLeaseReleaseMACsKey   = "macs"
+
+	defaultOrphanCleanupIntervalMinutes = 60
+)
+
+// orphanDeletableStates are the provision states of the Ironic nodes the
+// orphan cleanup deletes. Deployed nodes and nodes in the middle of an
+// operation are kept, as deleting them would leave the host running or
+// half written.
+var orphanDeletableStates = map[string]bool{
+	"enroll":         true,
+	"manageable":     true,
+	"available":      true,
+	"inspect failed": true,
+	"clean failed":   true,
+	"adopt failed":   true,
+}
+
+// dhcpLeaseReleaseScript releases the dnsmasq leases of the listed MAC
+// addresses by sending a DHCPRELEASE on their behalf, as dnsmasq only
+// reads its lease file on startup. Each request is handled once, the id
+// of the last one being recorded alongside the leases, so that a MAC
+// address reused by a new host later on keeps its lease. DHCPv6 leases
+// expire instead.
+const dhcpLeaseReleaseScript = `
+import ipaddress
+import os
+import random
+import socket
+import struct
+import subprocess
+import time
+
+LEASES = "/var/lib/dnsmasq/dnsmasq.leases"
+STATE = "/var/lib/dnsmasq/released-leases.id"
+REQUEST = os.environ["LEASE_RELEASE_DIR"]
+INTERFACE = os.environ["PROVISIONING_INTERFACE"]
+SO_BINDTODEVICE = getattr(socket, "SO_BINDTODEVICE", 25)
+
+
+def read(path):
+    try:
+        with open(path) as f:
+            return f.read().strip()
+    except FileNotFoundError:
+        return ""
+
+
+def server_addresses():
+    out = subprocess.run(["ip", "-4", "-o", "address", "show", "dev", INTERFACE],
+                         stdout=subprocess.PIPE, universal_newlines=True).stdout
+    return [ipaddress.ip_interface(line.split()[3]) for line in out.splitlines()]
+
+
+def release(mac, ip):
+    chaddr = bytes.fromhex(mac.replace(":", "")).ljust(16, b"\0")
+    for server in server_addresses():
+        if ip not in server.network:
+            continue
+        packet = struct.pack("!BBBBIHH4s4s4s4s16s192s4s", 1, 1, 6, 0, random.getrandbits(32), 0, 0,
+                             ip.packed, bytes(4), bytes(4), bytes(4), chaddr, bytes(192), b"\x63\x82\x53\x63")
+        packet += bytes([53, 1, 7, 54, 4]) + server.ip.packed + bytes([255])
+        sock = socket.socket(socket.AF_INET, socket.SOCK_DGRAM)
+        try:
+            sock.setsockopt(socket.SOL_SOCKET, SO_BINDTODEVICE, INTERFACE.encode())
+            sock.sendto(packet, (str(server.ip), 67))
+        finally:
+            sock.close()
+        print("released lease of %s for %s" % (ip, mac), flush=True)
+
+
+while True:
+    request = read(os.path.join(REQUEST, "id"))
+    if request and request != read(STATE):
+        macs = set(read(os.path.join(REQUEST, "macs")).lower().split())
+        for line in read(LEASES).splitlines():
+            fields = line.split()
+            if len(fields) < 3 or fields[1].lower() not in macs:
+                continue
+            ip = ipaddress.ip_address(fields[2])
+            if ip.version == 4:
+                release(fields[1], ip)
+        with open(STATE, "w") as f:
+            f.write(request)
+    time.sleep(30)
+`
+
+// GetOrphanCleanupInterval returns how often orphaned Ironic nodes are
+// looked for
+func GetOrphanCleanupInterval(config *metal3iov1alpha1.ProvisioningSpec) time.Duration {
+	minutes := int32(defaultOrphanCleanupIntervalMinutes)
+	if config.OrphanCleanup != nil && config.OrphanCleanup.IntervalMinutes > 0 {
+		minutes = config.OrphanCleanup.IntervalMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// FindOrphanedNodes returns the Ironic nodes matching no BareMetalHost,
+// by the Ironic node ID of their status or by name, split between those
+// that can be deleted and those kept for their provision state. Hosts
+// are matched by name as well since the baremetal-operator creates the
+// node before recording its ID.
+func FindOrphanedNodes(nodes []IronicNode, hostIDs map[string]bool, hostNames map[string]bool) ([]IronicNode, []IronicNode) {
+	var removable, skipped []IronicNode
+	for _, node := range nodes {
+		if hostIDs[node.UUID] || (node.Name != "" && hostNames[node.Name]) {
+			continue
+		}
+		if orphanDeletableStates[node.ProvisionState] {
+			removable = append(removable, node)
+		} else {
+			skipped = append(skipped, node)
+		}
+	}
+	return removable, skipped
+}
+
+// GetNodeMACs returns the MAC addresses of the ports of the given nodes
+func GetNodeMACs(ports []IronicPort, nodes []IronicNode) []string {
+	uuids := map[string]bool{}
+	for _, node := range nodes {
+		uuids[node.UUID] = true
+	}
+	macs := []string{}
+	for _, port := range ports {
+		if uuids[port.NodeUUID] && port.Address != "" {
+			macs = append(macs, strings.ToLower(port.Address))
+		}
+	}
+	sort.Strings(macs)
+	return macs
+}
+
+// NewDHCPLeaseReleaseConfigMap returns the ConfigMap requesting dnsmasq to
+// release the leases of the given MAC addresses. The id tells the
+// requests apart, so that each one is only handled once.
+func NewDHCPLeaseReleaseConfigMap(targetNamespace string, id string, macs []string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      DHCPLeaseReleaseConfigMapName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: map[string]string{
+			dhcpLeaseReleaseIDKey:   id,
+			dhcpLeaseReleaseMACsKey: strings.Join(macs, "\n"),
+		},
+	}
+}
+
+// ApplyDHCPLeaseReleaseConfigMap creates or updates the ConfigMap of the
+// leases to release
+func ApplyDHCPLeaseReleaseConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	return applyConfigMap(client, configMap)
+}
+
+// DeleteDHCPLeaseReleaseConfigMap removes the ConfigMap of the leases to
+// release, once dnsmasq is deleted
+func DeleteDHCPLeaseReleaseConfigMap(client coreclientv1.ConfigMapsGetter, targetNamespace string) error {
+	return deleteConfigMap(client, targetNamespace, DHCPLeaseReleaseConfigMapName)
+}
+
+// newDHCPLeaseReleaseVolume returns the volume of the leases to release.
+// It is optional, as it only exists once orphaned nodes were removed.
+func newDHCPLeaseReleaseVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: dhcpLeaseReleaseVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: DHCPLeaseReleaseConfigMapName},
+				Optional:             pointer.BoolPtr(true),
+			},
+		},
+	}
+}
+
+// createContainerMetal3DhcpLeaseRelease returns the container releasing
+// the leases of the ports of the orphaned Ironic nodes. Binding the
+// release to the provisioning interface requires NET_RAW.
+func createContainerMetal3DhcpLeaseRelease(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	return corev1.Container{
+		Name:            "metal3-dhcp-lease-release",
+		Image:           images.BaremetalIronic,
+		Command:         []string{"python3", "-c", dhcpLeaseReleaseScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+			Capabilities: &corev1.Capabilities{
+				Add: []corev1.Capability{"NET_RAW"},
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			dnsmasqLeasesMount,
+			{
+				Name:      dhcpLeaseReleaseVolume,
+				MountPath: dhcpLeaseReleaseMountPath,
+				ReadOnly:  true,
+			},
+		},
+		Env: []corev1.EnvVar{
+			buildEnvVar(provisioningInterface, config),
+			{
+				Name:  "LEASE_RELEASE_DIR",
+				Value: dhcpLeaseReleaseMountPath,
+			},
+		},
+	}
+}
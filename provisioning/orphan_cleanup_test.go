@@ -0,0 +1,70 @@
+package provisioning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestGetOrphanCleanupInterval(t *testing.T) {
+	spec := managedProvisioning()
+	spec.OrphanCleanup = &metal3iov1alpha1.OrphanCleanup{}
+	assert.Equal(t, time.Hour, GetOrphanCleanupInterval(spec))
+
+	spec.OrphanCleanup.IntervalMinutes = 15
+	assert.Equal(t, 15*time.Minute, GetOrphanCleanupInterval(spec))
+}
+
+func TestFindOrphanedNodes(t *testing.T) {
+	nodes := []IronicNode{
+		{UUID: "uuid-0", Name: "openshift-machine-api~worker-0", ProvisionState: "active"},
+		{UUID: "uuid-1", Name: "worker-1", ProvisionState: "manageable"},
+		{UUID: "uuid-2", Name: "worker-2", ProvisionState: "available"},
+		{UUID: "uuid-3", ProvisionState: "deploy failed"},
+		{UUID: "uuid-4", Name: "worker-4", ProvisionState: "enroll"},
+		{UUID: "uuid-5", ProvisionState: "active"},
+	}
+	hostIDs := map[string]bool{"uuid-0": true}
+	hostNames := map[string]bool{"worker-4": true}
+
+	removable, skipped := FindOrphanedNodes(nodes, hostIDs, hostNames)
+	assert.Equal(t, []IronicNode{nodes[1], nodes[2]}, removable)
+	assert.Equal(t, []IronicNode{nodes[3], nodes[5]}, skipped)
+}
+
+func TestGetNodeMACs(t *testing.T) {
+	ports := []IronicPort{
+		{Address: "52:54:00:AA:00:02", NodeUUID: "uuid-1"},
+		{Address: "52:54:00:aa:00:01", NodeUUID: "uuid-1"},
+		{Address: "52:54:00:aa:00:03", NodeUUID: "uuid-2"},
+	}
+	macs := GetNodeMACs(ports, []IronicNode{{UUID: "uuid-1"}})
+	assert.Equal(t, []string{"52:54:00:aa:00:01", "52:54:00:aa:00:02"}, macs)
+
+	configMap := NewDHCPLeaseReleaseConfigMap(testNamespace, "2021-01-01T00:00:00Z", macs)
+	assert.Equal(t, map[string]string{
+		"id":   "2021-01-01T00:00:00Z",
+		"macs": "52:54:00:aa:00:01\n52:54:00:aa:00:02",
+	}, configMap.Data)
+}
+
+func TestDnsmasqDhcpLeaseRelease(t *testing.T) {
+	spec := managedProvisioning()
+	podSpec := NewDnsmasqDaemonSet(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.Nil(t, findContainer(podSpec.Containers, "metal3-dhcp-lease-release"))
+	assert.NotContains(t, podSpec.Volumes, newDHCPLeaseReleaseVolume())
+
+	spec.OrphanCleanup = &metal3iov1alpha1.OrphanCleanup{}
+	podSpec = NewDnsmasqDaemonSet(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.Contains(t, podSpec.Volumes, newDHCPLeaseReleaseVolume())
+	container := findContainer(podSpec.Containers, "metal3-dhcp-lease-release")
+	if assert.NotNil(t, container) {
+		assert.Equal(t, testImages.BaremetalIronic, container.Image)
+		assert.Equal(t, "eth0", envValue(container, "PROVISIONING_INTERFACE"))
+		assert.Equal(t, dhcpLeaseReleaseMountPath, envValue(container, "LEASE_RELEASE_DIR"))
+		assert.Contains(t, container.VolumeMounts, dnsmasqLeasesMount)
+	}
+}
@@ -0,0 +1,118 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	osImageProgressContainerName = "metal3-os-image-progress"
+	// osImageProgressSubPath is where the progress of the download is
+	// written, relative to the image server root, so that the operator
+	// reads it through httpd
+	osImageProgressSubPath = "images/download-progress.json"
+	// osImageProgressInterval is how often the progress is written, in
+	// seconds
+	osImageProgressInterval = "10"
+)
+
+// osImageProgressScript writes the progress of the machine-os-downloader
+// until the cached image shows up. The size of the image comes from the
+// server, and the size downloaded so far from the partial files named
+// after the image, wherever the downloader writes them under the shared
+// volume. The URL is not written, as it may hold credentials and the
+// file is served to anyone reaching the image server.
+const osImageProgressScript = `set -u
+name="$(basename "${RHCOS_IMAGE_URL%%\?*}")"
+cached="/shared/html/${OS_IMAGE_CACHED_PATH}"
+progress="/shared/html/${OS_IMAGE_PROGRESS_PATH}"
+total="$(curl -sfIL "${RHCOS_IMAGE_URL}" | tr -d '\r' | awk 'tolower($1) == "content-length:" { size = $2 } END { print size + 0 }')"
+while true; do
+    if [ -e "${cached}" ]; then
+        state=Complete
+        downloaded="${total}"
+    else
+        state=Downloading
+        downloaded="$(find /shared/html -type f -name "${name}*" -printf '%s\n' 2>/dev/null | awk '{ size += $1 } END { print size + 0 }')"
+    fi
+    mkdir -p "$(dirname "${progress}")"
+    printf '{"state": "%s", "bytesDownloaded": %d, "bytesTotal": %d}\n' "${state}" "${downloaded}" "${total}" > "${progress}.tmp"
+    mv "${progress}.tmp" "${progress}"
+    if [ "${state}" = Complete ]; then
+        exec sleep infinity
+    fi
+    sleep "${OS_IMAGE_PROGRESS_INTERVAL}"
+done
+`
+
+// ReportsOSImageDownloadProgress returns true when the metal3 pod writes
+// the progress of the download of the OS image. It can only be read while
+// httpd runs, so not when the image is downloaded by an init container,
+// and the Minimal profile does without it.
+func ReportsOSImageDownloadProgress(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return downloadsOSImageAlongside(config) && getCachedOSImageSubPath(config) != "" && !IsMinimalProfile(config)
+}
+
+func createContainerMetal3OSImageProgress(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	return corev1.Container{
+		Name:            osImageProgressContainerName,
+		Image:           images.BaremetalIronic,
+		Command:         []string{"/bin/bash", "-c", osImageProgressScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(false),
+		},
+		VolumeMounts: []corev1.VolumeMount{sharedVolumeMount},
+		Env: []corev1.EnvVar{
+			buildEnvVar(machineImageUrl, config),
+			{Name: "OS_IMAGE_CACHED_PATH", Value: getCachedOSImageSubPath(config)},
+			{Name: "OS_IMAGE_PROGRESS_PATH", Value: osImageProgressSubPath},
+			{Name: "OS_IMAGE_PROGRESS_INTERVAL", Value: osImageProgressInterval},
+		},
+	}
+}
+
+// OSImageDownloadProgressURL returns the URL the progress of the download
+// is read from on the given metal3 pod
+func OSImageDownloadProgressURL(podIP string) string {
+	return fmt.Sprintf("http://%s/%s", net.JoinHostPort(podIP, baremetalHttpPort), path.Clean(osImageProgressSubPath))
+}
+
+// ParseOSImageDownloadProgress returns the download status written by the
+// metal3 pod
+func ParseOSImageDownloadProgress(data []byte, now time.Time) (*metal3iov1alpha1.OSImageDownloadStatus, error) {
+	progress := struct {
+		State           metal3iov1alpha1.OSImageDownloadState `json:"state"`
+		BytesDownloaded int64                                 `json:"bytesDownloaded"`
+		BytesTotal      int64                                 `json:"bytesTotal"`
+	}{}
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil, fmt.Errorf("invalid OS image download progress: %v", err)
+	}
+	if progress.State != metal3iov1alpha1.OSImageDownloading && progress.State != metal3iov1alpha1.OSImageDownloadComplete {
+		return nil, fmt.Errorf("invalid OS image download state %q", progress.State)
+	}
+	status := &metal3iov1alpha1.OSImageDownloadStatus{
+		State:           progress.State,
+		BytesDownloaded: progress.BytesDownloaded,
+		BytesTotal:      progress.BytesTotal,
+		LastUpdateTime:  metav1.NewTime(now),
+	}
+	if progress.BytesTotal > 0 {
+		percent := progress.BytesDownloaded * 100 / progress.BytesTotal
+		if percent > 100 {
+			percent = 100
+		}
+		status.PercentComplete = pointer.Int32Ptr(int32(percent))
+	}
+	return status, nil
+}
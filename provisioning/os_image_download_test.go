@@ -0,0 +1,151 @@
+package provisioning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestReportsOSImageDownloadProgress(t *testing.T) {
+	tCases := []struct {
+		name     string
+		update   func(*metal3iov1alpha1.ProvisioningSpec)
+		expected bool
+	}{
+		{
+			name:     "alongside",
+			update:   func(*metal3iov1alpha1.ProvisioningSpec) {},
+			expected: true,
+		},
+		{
+			name:     "converted",
+			update:   func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ConvertOSImageToRaw = true },
+			expected: false,
+		},
+		{
+			name:     "pre-staged",
+			update:   func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.PreStagedImagePVC = "rhcos" },
+			expected: false,
+		},
+		{
+			name:     "no image",
+			update:   func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ProvisioningOSDownloadURL = "" },
+			expected: false,
+		},
+		{
+			name: "minimal profile",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkUnmanaged
+				spec.Profile = metal3iov1alpha1.ProvisioningProfileMinimal
+			},
+			expected: false,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			tc.update(spec)
+			assert.Equal(t, tc.expected, ReportsOSImageDownloadProgress(spec))
+
+			containers := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+			assert.Equal(t, tc.expected, findContainer(containers, osImageProgressContainerName) != nil)
+		})
+	}
+}
+
+func TestOSImageProgressContainer(t *testing.T) {
+	spec := managedProvisioning()
+	container := createContainerMetal3OSImageProgress(&testImages, spec)
+
+	assert.Equal(t, expectedIronic, container.Image)
+	assert.Equal(t, []string{"/bin/bash", "-c", osImageProgressScript}, container.Command)
+	assert.Equal(t, spec.ProvisioningOSDownloadURL, envValue(&container, "RHCOS_IMAGE_URL"))
+	assert.Equal(t, getCachedOSImageSubPath(spec), envValue(&container, "OS_IMAGE_CACHED_PATH"))
+	assert.Equal(t, osImageProgressSubPath, envValue(&container, "OS_IMAGE_PROGRESS_PATH"))
+	assert.Contains(t, container.VolumeMounts, sharedVolumeMount)
+}
+
+func TestOSImageDownloadProgressURL(t *testing.T) {
+	assert.Equal(t, "http://10.0.0.5:6180/images/download-progress.json", OSImageDownloadProgressURL("10.0.0.5"))
+	assert.Equal(t, "http://[fd00::5]:6180/images/download-progress.json", OSImageDownloadProgressURL("fd00::5"))
+}
+
+func TestParseOSImageDownloadProgress(t *testing.T) {
+	now := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tCases := []struct {
+		name          string
+		data          string
+		expected      *metal3iov1alpha1.OSImageDownloadStatus
+		expectedError string
+	}{
+		{
+			name: "downloading",
+			data: `{"state": "Downloading", "bytesDownloaded": 256, "bytesTotal": 1024}`,
+			expected: &metal3iov1alpha1.OSImageDownloadStatus{
+				State:           metal3iov1alpha1.OSImageDownloading,
+				BytesDownloaded: 256,
+				BytesTotal:      1024,
+				PercentComplete: pointer.Int32Ptr(25),
+			},
+		},
+		{
+			name: "unknown size",
+			data: `{"state": "Downloading", "bytesDownloaded": 256, "bytesTotal": 0}`,
+			expected: &metal3iov1alpha1.OSImageDownloadStatus{
+				State:           metal3iov1alpha1.OSImageDownloading,
+				BytesDownloaded: 256,
+			},
+		},
+		{
+			// The partial files of a retried download add up to more
+			// than the image
+			name: "beyond the size",
+			data: `{"state": "Downloading", "bytesDownloaded": 2048, "bytesTotal": 1024}`,
+			expected: &metal3iov1alpha1.OSImageDownloadStatus{
+				State:           metal3iov1alpha1.OSImageDownloading,
+				BytesDownloaded: 2048,
+				BytesTotal:      1024,
+				PercentComplete: pointer.Int32Ptr(100),
+			},
+		},
+		{
+			name: "complete",
+			data: `{"state": "Complete", "bytesDownloaded": 1024, "bytesTotal": 1024}`,
+			expected: &metal3iov1alpha1.OSImageDownloadStatus{
+				State:           metal3iov1alpha1.OSImageDownloadComplete,
+				BytesDownloaded: 1024,
+				BytesTotal:      1024,
+				PercentComplete: pointer.Int32Ptr(100),
+			},
+		},
+		{
+			name:          "invalid state",
+			data:          `{"state": "Failed", "bytesDownloaded": 0, "bytesTotal": 0}`,
+			expectedError: `invalid OS image download state "Failed"`,
+		},
+		{
+			name:          "invalid JSON",
+			data:          `<html>Not Found</html>`,
+			expectedError: "invalid OS image download progress: invalid character '<' looking for beginning of value",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, err := ParseOSImageDownloadProgress([]byte(tc.data), now)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			if tc.expected != nil {
+				tc.expected.LastUpdateTime.Time = now
+			}
+			assert.Equal(t, tc.expected, status)
+		})
+	}
+}
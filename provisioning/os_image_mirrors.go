@@ -0,0 +1,109 @@
+package provisioning
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	getResourceCommand = "/usr/local/bin/get-resource.sh"
+	osImageMirrorsEnv  = "RHCOS_IMAGE_MIRRORS"
+)
+
+// osImageMirrorsScript runs the downloader with each of the URL and the
+// mirrors of the OS image in turn, until one of the downloads succeeds.
+// Globbing is disabled as the query of the URLs holds '?'.
+const osImageMirrorsScript = `set -f
+for url in ${RHCOS_IMAGE_URL} ${RHCOS_IMAGE_MIRRORS:-}; do
+    if RHCOS_IMAGE_URL="${url}" ` + getResourceCommand + `; then
+        exit 0
+    fi
+    echo "failed to download the OS image from ${url%%\?*}" >&2
+done
+exit 1`
+
+// getOSImageMirrorURLs returns the download URLs of the mirrors of the OS
+// image, carrying the checksum the same way as the
+// ProvisioningOSDownloadURL. The checksums the URLs cannot carry are
+// verified by osImageZstdScript, which then downloads from the mirrors
+// itself.
+func getOSImageMirrorURLs(config *metal3iov1alpha1.ProvisioningSpec) []string {
+	image := config.ProvisioningOSImage
+	if image == nil {
+		return nil
+	}
+	urls := []string{}
+	for _, mirror := range image.Mirrors {
+		mirrorImage := *image
+		mirrorImage.URL = mirror
+		urls = append(urls, GetProvisioningOSDownloadURL(&mirrorImage))
+	}
+	return urls
+}
+
+// getMachineOsDownloaderCommand returns the command of the
+// machine-os-downloader, which goes through the mirrors when there are
+// some
+func getMachineOsDownloaderCommand(config *metal3iov1alpha1.ProvisioningSpec) []string {
+	switch {
+	case usesOSImageZstdScript(config):
+		return []string{"/bin/sh", "-c", osImageZstdScript}
+	case len(getOSImageMirrorURLs(config)) > 0:
+		return []string{"/bin/sh", "-c", osImageMirrorsScript}
+	default:
+		return []string{getResourceCommand}
+	}
+}
+
+func newOSImageMirrorsEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	urls := getOSImageMirrorURLs(config)
+	if len(urls) == 0 {
+		return nil
+	}
+	return []corev1.EnvVar{{Name: osImageMirrorsEnv, Value: strings.Join(urls, " ")}}
+}
+
+// validateOSImageMirrors checks that the mirrors serve an image that is
+// cached under the same name and verified against the same checksum as
+// the image of the URL
+func validateOSImageMirrors(config *metal3iov1alpha1.ProvisioningSpec) error {
+	image := config.ProvisioningOSImage
+	if image == nil || len(image.Mirrors) == 0 {
+		return nil
+	}
+	if image.Checksum == "" {
+		return fmt.Errorf("ProvisioningOSImage: Mirrors require a Checksum, so that the image is verified wherever it is downloaded from")
+	}
+	imageURL, err := url.Parse(image.URL)
+	if err != nil {
+		return fmt.Errorf("ProvisioningOSImage: invalid URL %q", image.URL)
+	}
+	name := path.Base(imageURL.Path)
+	seen := map[string]bool{image.URL: true}
+	for _, mirror := range image.Mirrors {
+		mirrorURL, err := url.Parse(mirror)
+		if err != nil || (mirrorURL.Scheme != "http" && mirrorURL.Scheme != "https") || mirrorURL.Host == "" {
+			return fmt.Errorf("ProvisioningOSImage: invalid mirror %q, an http or https URL is required", mirror)
+		}
+		if strings.ContainsAny(mirror, " \t\n") {
+			return fmt.Errorf("ProvisioningOSImage: mirror %q must not contain whitespace", mirror)
+		}
+		if _, checksum := splitChecksumQuery(mirrorURL.RawQuery); checksum != "" {
+			return fmt.Errorf("ProvisioningOSImage: mirror %q must not carry the checksum, it goes in Checksum", mirror)
+		}
+		if path.Base(mirrorURL.Path) != name {
+			return fmt.Errorf("ProvisioningOSImage: mirror %q must serve the image as %s, like the URL", mirror, name)
+		}
+		if seen[mirror] {
+			return fmt.Errorf("ProvisioningOSImage: mirror %q is listed more than once", mirror)
+		}
+		seen[mirror] = true
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func mirroredProvisioning() *metal3iov1alpha1.ProvisioningSpec {
+	spec := managedProvisioning()
+	spec.ProvisioningOSDownloadURL = ""
+	spec.ProvisioningOSImage = &metal3iov1alpha1.ProvisioningOSImage{
+		URL:      "https://eu.example.com/rhcos.qcow2.gz",
+		Checksum: testSHA256,
+		Mirrors: []string{
+			"https://us.example.com/images/rhcos.qcow2.gz",
+			"http://172.22.0.1/rhcos.qcow2.gz?token=abc",
+		},
+	}
+	return spec
+}
+
+func TestOSImageMirrorsDownloader(t *testing.T) {
+	spec := mirroredProvisioning()
+	assert.NoError(t, NormalizeProvisioningSpec(spec))
+
+	downloader := findContainer(NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, "metal3-machine-os-downloader")
+	if !assert.NotNil(t, downloader) {
+		return
+	}
+	assert.Equal(t, "https://eu.example.com/rhcos.qcow2.gz?sha256="+testSHA256, envValue(downloader, "RHCOS_IMAGE_URL"))
+	assert.Equal(t, "https://us.example.com/images/rhcos.qcow2.gz?sha256="+testSHA256+
+		" http://172.22.0.1/rhcos.qcow2.gz?token=abc&sha256="+testSHA256, envValue(downloader, "RHCOS_IMAGE_MIRRORS"))
+	assert.Equal(t, []string{"/bin/sh", "-c",
+		"set -e; (" + osImageMirrorsScript + "); mkdir -p /shared/downloaded; touch /shared/downloaded/metal3-machine-os-downloader; exec sleep infinity"}, downloader.Command)
+
+	// The init container runs the script as it is
+	spec.ConvertOSImageToRaw = true
+	downloader = findContainer(newMetal3InitContainers(&testImages, spec), "metal3-machine-os-downloader")
+	if assert.NotNil(t, downloader) {
+		assert.Equal(t, []string{"/bin/sh", "-c", osImageMirrorsScript}, downloader.Command)
+	}
+
+	// The mirrors of an image with a sha512 checksum are verified by the
+	// script, as their URLs cannot carry it
+	spec = mirroredProvisioning()
+	spec.ProvisioningOSImage.Checksum = testSHA512
+	spec.ProvisioningOSImage.ChecksumType = metal3iov1alpha1.OSImageChecksumSHA512
+	assert.NoError(t, NormalizeProvisioningSpec(spec))
+	init := createInitContainerMachineOsDownloader(&testImages, spec)
+	assert.Equal(t, []string{"/bin/sh", "-c", osImageZstdScript}, init.Command)
+	assert.Equal(t, "https://us.example.com/images/rhcos.qcow2.gz http://172.22.0.1/rhcos.qcow2.gz?token=abc",
+		envValue(&init, "RHCOS_IMAGE_MIRRORS"))
+	assert.Equal(t, "true", envValue(&init, "OS_IMAGE_FETCH"))
+	assert.Equal(t, testSHA512, envValue(&init, "OS_IMAGE_CHECKSUM"))
+	assert.Equal(t, "sha512", envValue(&init, "OS_IMAGE_CHECKSUM_TYPE"))
+
+	// Without mirrors the downloader runs as before
+	spec = managedProvisioning()
+	downloader = findContainer(newMetal3InitContainers(&testImages, spec), "metal3-machine-os-downloader")
+	assert.Nil(t, downloader)
+	downloader = findContainer(NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, "metal3-machine-os-downloader")
+	if assert.NotNil(t, downloader) {
+		assert.Equal(t, "", envValue(downloader, "RHCOS_IMAGE_MIRRORS"))
+	}
+}
+
+func TestValidateOSImageMirrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		update        func(*metal3iov1alpha1.ProvisioningOSImage)
+		expectedError string
+	}{
+		{
+			name:   "Valid",
+			update: func(*metal3iov1alpha1.ProvisioningOSImage) {},
+		},
+		{
+			name:          "NoChecksum",
+			update:        func(image *metal3iov1alpha1.ProvisioningOSImage) { image.Checksum = "" },
+			expectedError: "ProvisioningOSImage: Mirrors require a Checksum, so that the image is verified wherever it is downloaded from",
+		},
+		{
+			name: "NotHTTP",
+			update: func(image *metal3iov1alpha1.ProvisioningOSImage) {
+				image.Mirrors[0] = "ftp://us.example.com/rhcos.qcow2.gz"
+			},
+			expectedError: `ProvisioningOSImage: invalid mirror "ftp://us.example.com/rhcos.qcow2.gz", an http or https URL is required`,
+		},
+		{
+			name: "Whitespace",
+			update: func(image *metal3iov1alpha1.ProvisioningOSImage) {
+				image.Mirrors[0] = "https://us.example.com/a b/rhcos.qcow2.gz"
+			},
+			expectedError: `ProvisioningOSImage: mirror "https://us.example.com/a b/rhcos.qcow2.gz" must not contain whitespace`,
+		},
+		{
+			name: "ChecksumInURL",
+			update: func(image *metal3iov1alpha1.ProvisioningOSImage) {
+				image.Mirrors[0] = "https://us.example.com/rhcos.qcow2.gz?sha256=" + testSHA256
+			},
+			expectedError: `ProvisioningOSImage: mirror "https://us.example.com/rhcos.qcow2.gz?sha256=` + testSHA256 + `" must not carry the checksum, it goes in Checksum`,
+		},
+		{
+			name: "OtherName",
+			update: func(image *metal3iov1alpha1.ProvisioningOSImage) {
+				image.Mirrors[0] = "https://us.example.com/rhcos-latest.qcow2.gz"
+			},
+			expectedError: `ProvisioningOSImage: mirror "https://us.example.com/rhcos-latest.qcow2.gz" must serve the image as rhcos.qcow2.gz, like the URL`,
+		},
+		{
+			name:          "SameAsURL",
+			update:        func(image *metal3iov1alpha1.ProvisioningOSImage) { image.Mirrors[0] = image.URL },
+			expectedError: `ProvisioningOSImage: mirror "https://eu.example.com/rhcos.qcow2.gz" is listed more than once`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := mirroredProvisioning()
+			tc.update(spec.ProvisioningOSImage)
+			err := validateOSImageMirrors(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
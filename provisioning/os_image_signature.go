@@ -0,0 +1,145 @@
+package provisioning
+
+import (
+	"fmt"
+	"net/url"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// OSImageVerifierContainerName is the name of the init container
+	// verifying the signature of the provisioning OS image
+	OSImageVerifierContainerName = "metal3-os-image-verifier"
+
+	osImageSignatureKeyVolume    = "metal3-os-image-signature-key"
+	osImageSignatureKeyMountPath = "/etc/metal3-os-image-signature"
+	osImageSignatureKey          = "key.pub"
+)
+
+// osImageVerifierScript verifies the signature of the cached OS image. A
+// marker next to the image records the signature and key it was verified
+// with, so that the verification only runs again when they change. An
+// image failing the verification is removed from the cache, so that it is
+// downloaded again on the next attempt and never served meanwhile.
+const osImageVerifierScript = `set -euo pipefail
+image_dir="/shared/html/images/${OS_IMAGE_NAME}"
+image="${image_dir}/${OS_IMAGE_NAME}"
+key="` + osImageSignatureKeyMountPath + `/` + osImageSignatureKey + `"
+marker="${image}.verified"
+expected="${OS_IMAGE_SIGNATURE_TYPE} ${OS_IMAGE_SIGNATURE_URL} $(sha256sum "${key}" | cut -d ' ' -f 1)"
+
+if [ -f "${marker}" ] && [ "$(cat "${marker}")" = "${expected}" ]; then
+    echo "${image} already verified"
+    exit 0
+fi
+
+fail() {
+    rm -rf "${image_dir}"
+    echo "OS image signature verification failed: $1" >&2
+    exit 1
+}
+
+work=$(mktemp -d)
+trap 'rm -rf "${work}"' EXIT
+curl --fail --silent --show-error --location --output "${work}/signature" "${OS_IMAGE_SIGNATURE_URL}" ||
+    fail "unable to download signature from ${OS_IMAGE_SIGNATURE_URL}"
+
+case "${OS_IMAGE_SIGNATURE_TYPE}" in
+GPG)
+    gpg --batch --quiet --homedir "${work}" --import "${key}" ||
+        fail "invalid GPG public key"
+    gpg --batch --homedir "${work}" --verify "${work}/signature" "${image}" ||
+        fail "bad GPG signature for ${OS_IMAGE_NAME}"
+    ;;
+Sigstore)
+    base64 -d "${work}/signature" > "${work}/signature.bin" ||
+        fail "invalid sigstore signature"
+    openssl dgst -sha256 -verify "${key}" -signature "${work}/signature.bin" "${image}" ||
+        fail "bad sigstore signature for ${OS_IMAGE_NAME}"
+    ;;
+*)
+    fail "unsupported signature type ${OS_IMAGE_SIGNATURE_TYPE}"
+    ;;
+esac
+
+echo "${expected}" > "${marker}"
+echo "${image} signature verified"
+`
+
+// validateOSImageSignatureRef checks the OS image signature verification
+// settings
+func validateOSImageSignatureRef(ref *metal3iov1alpha1.OSImageSignatureRef) error {
+	if ref == nil {
+		return nil
+	}
+	switch ref.Type {
+	case metal3iov1alpha1.OSImageSignatureGPG, metal3iov1alpha1.OSImageSignatureSigstore:
+	default:
+		return fmt.Errorf("unsupported OSImageSignatureRef.Type %q", ref.Type)
+	}
+	signatureURL, err := url.Parse(ref.SignatureURL)
+	if err != nil || (signatureURL.Scheme != "http" && signatureURL.Scheme != "https") || signatureURL.Host == "" {
+		return fmt.Errorf("OSImageSignatureRef.SignatureURL must be an http or https URL")
+	}
+	if ref.KeyConfigMap == "" {
+		return fmt.Errorf("OSImageSignatureRef.KeyConfigMap is required")
+	}
+	return nil
+}
+
+func newOSImageSignatureKeyVolume(ref *metal3iov1alpha1.OSImageSignatureRef) corev1.Volume {
+	return corev1.Volume{
+		Name: osImageSignatureKeyVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: ref.KeyConfigMap,
+				},
+				Items: []corev1.KeyToPath{
+					{
+						Key:  osImageSignatureKey,
+						Path: osImageSignatureKey,
+					},
+				},
+			},
+		},
+	}
+}
+
+func createInitContainerOSImageVerifier(images *Images, config *metal3iov1alpha1.ProvisioningSpec) corev1.Container {
+	return corev1.Container{
+		Name:            OSImageVerifierContainerName,
+		Image:           images.BaremetalIronic,
+		Command:         []string{"/bin/bash", "-c", osImageVerifierScript},
+		ImagePullPolicy: "IfNotPresent",
+		SecurityContext: &corev1.SecurityContext{
+			Privileged: pointer.BoolPtr(true),
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			sharedVolumeMount,
+			{
+				Name:      osImageSignatureKeyVolume,
+				MountPath: osImageSignatureKeyMountPath,
+				ReadOnly:  true,
+			},
+		},
+		Env: []corev1.EnvVar{
+			{
+				Name:  "OS_IMAGE_NAME",
+				Value: getCurrentCachedImage(config),
+			},
+			{
+				Name:  "OS_IMAGE_SIGNATURE_TYPE",
+				Value: string(config.OSImageSignatureRef.Type),
+			},
+			{
+				Name:  "OS_IMAGE_SIGNATURE_URL",
+				Value: config.OSImageSignatureRef.SignatureURL,
+			},
+		},
+	}
+}
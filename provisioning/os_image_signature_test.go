@@ -0,0 +1,94 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateOSImageSignatureRef(t *testing.T) {
+	tCases := []struct {
+		name          string
+		ref           *metal3iov1alpha1.OSImageSignatureRef
+		expectedError string
+	}{
+		{
+			name: "NotSet",
+		},
+		{
+			name: "GPG",
+			ref: &metal3iov1alpha1.OSImageSignatureRef{
+				Type:         metal3iov1alpha1.OSImageSignatureGPG,
+				SignatureURL: "https://mirror.example.com/rhcos.qcow2.sig",
+				KeyConfigMap: "rhcos-signing-key",
+			},
+		},
+		{
+			name: "UnsupportedType",
+			ref: &metal3iov1alpha1.OSImageSignatureRef{
+				Type:         "X509",
+				SignatureURL: "https://mirror.example.com/rhcos.qcow2.sig",
+				KeyConfigMap: "rhcos-signing-key",
+			},
+			expectedError: `unsupported OSImageSignatureRef.Type "X509"`,
+		},
+		{
+			name: "InvalidSignatureURL",
+			ref: &metal3iov1alpha1.OSImageSignatureRef{
+				Type:         metal3iov1alpha1.OSImageSignatureSigstore,
+				SignatureURL: "file:///rhcos.qcow2.sig",
+				KeyConfigMap: "rhcos-signing-key",
+			},
+			expectedError: "OSImageSignatureRef.SignatureURL must be an http or https URL",
+		},
+		{
+			name: "MissingKey",
+			ref: &metal3iov1alpha1.OSImageSignatureRef{
+				Type:         metal3iov1alpha1.OSImageSignatureSigstore,
+				SignatureURL: "https://mirror.example.com/rhcos.qcow2.sig",
+			},
+			expectedError: "OSImageSignatureRef.KeyConfigMap is required",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateOSImageSignatureRef(tc.ref)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestOSImageVerifier(t *testing.T) {
+	config := managedProvisioning()
+	config.ConvertOSImageToRaw = true
+	config.OSImageSignatureRef = &metal3iov1alpha1.OSImageSignatureRef{
+		Type:         metal3iov1alpha1.OSImageSignatureGPG,
+		SignatureURL: "https://mirror.example.com/rhcos.qcow2.sig",
+		KeyConfigMap: "rhcos-signing-key",
+	}
+
+	initContainers := newMetal3InitContainers(&testImages, config)
+	assert.Equal(t, []string{"metal3-static-ip-set", "metal3-machine-os-downloader", "metal3-os-image-verifier",
+		"metal3-image-converter"}, containerNames(initContainers))
+
+	verifier := findContainer(initContainers, OSImageVerifierContainerName)
+	assert.Equal(t, "rhcos-44.81.202001171431.0-openstack.x86_64.qcow2", envValue(verifier, "OS_IMAGE_NAME"))
+	assert.Equal(t, "GPG", envValue(verifier, "OS_IMAGE_SIGNATURE_TYPE"))
+	assert.Equal(t, "https://mirror.example.com/rhcos.qcow2.sig", envValue(verifier, "OS_IMAGE_SIGNATURE_URL"))
+
+	volumes := newMetal3Volumes(config)
+	key := volumes[len(volumes)-1]
+	assert.Equal(t, osImageSignatureKeyVolume, key.Name)
+	if assert.NotNil(t, key.ConfigMap) {
+		assert.Equal(t, "rhcos-signing-key", key.ConfigMap.Name)
+	}
+
+	config.OSImageSignatureRef = nil
+	assert.Nil(t, findContainer(newMetal3InitContainers(&testImages, config), OSImageVerifierContainerName))
+}
@@ -0,0 +1,131 @@
+package provisioning
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// osImageChecksumQuery is the query parameter of the
+// ProvisioningOSDownloadURL holding the sha256 checksum of the image
+const osImageChecksumQuery = "sha256"
+
+// osImageChecksumLengths are the number of hexadecimal digits of the
+// checksums
+var osImageChecksumLengths = map[metal3iov1alpha1.OSImageChecksumType]int{
+	metal3iov1alpha1.OSImageChecksumSHA256: 64,
+	metal3iov1alpha1.OSImageChecksumSHA512: 128,
+}
+
+func getOSImageChecksumType(image *metal3iov1alpha1.ProvisioningOSImage) metal3iov1alpha1.OSImageChecksumType {
+	if image.ChecksumType == "" {
+		return metal3iov1alpha1.OSImageChecksumSHA256
+	}
+	return image.ChecksumType
+}
+
+// splitChecksumQuery returns the raw query without the sha256 parameter,
+// along with the checksum it held. The other parameters are kept as they
+// are, as reordering or re-encoding them breaks signed URLs.
+func splitChecksumQuery(rawQuery string) (string, string) {
+	params := []string{}
+	checksum := ""
+	for _, param := range strings.Split(rawQuery, "&") {
+		if strings.HasPrefix(param, osImageChecksumQuery+"=") {
+			checksum = strings.TrimPrefix(param, osImageChecksumQuery+"=")
+			continue
+		}
+		if param != "" {
+			params = append(params, param)
+		}
+	}
+	return strings.Join(params, "&"), checksum
+}
+
+// ParseProvisioningOSDownloadURL returns the structured form of a
+// ProvisioningOSDownloadURL, moving its sha256 query parameter to the
+// Checksum
+func ParseProvisioningOSDownloadURL(downloadURL string) (*metal3iov1alpha1.ProvisioningOSImage, error) {
+	imageURL, err := url.Parse(downloadURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ProvisioningOSDownloadURL %q", downloadURL)
+	}
+	image := &metal3iov1alpha1.ProvisioningOSImage{}
+	imageURL.RawQuery, image.Checksum = splitChecksumQuery(imageURL.RawQuery)
+	image.URL = imageURL.String()
+	if image.Checksum != "" {
+		image.ChecksumType = metal3iov1alpha1.OSImageChecksumSHA256
+	}
+	return image, nil
+}
+
+// GetProvisioningOSDownloadURL returns the ProvisioningOSDownloadURL of a
+// structured image. Only sha256 checksums can be passed in the URL, the
+// others are verified by osImageZstdScript.
+func GetProvisioningOSDownloadURL(image *metal3iov1alpha1.ProvisioningOSImage) string {
+	if image.Checksum == "" || getOSImageChecksumType(image) != metal3iov1alpha1.OSImageChecksumSHA256 {
+		return image.URL
+	}
+	separator := "?"
+	if strings.Contains(image.URL, "?") {
+		separator = "&"
+	}
+	return image.URL + separator + osImageChecksumQuery + "=" + strings.ToLower(image.Checksum)
+}
+
+// normalizeProvisioningOSImage derives the ProvisioningOSDownloadURL from
+// the ProvisioningOSImage when it is not set, and checks that both name
+// the same image otherwise
+func normalizeProvisioningOSImage(config *metal3iov1alpha1.ProvisioningSpec) error {
+	image := config.ProvisioningOSImage
+	if image == nil {
+		return nil
+	}
+	downloadURL := GetProvisioningOSDownloadURL(image)
+	if config.ProvisioningOSDownloadURL == "" {
+		config.ProvisioningOSDownloadURL = downloadURL
+		return nil
+	}
+	legacy, err := ParseProvisioningOSDownloadURL(config.ProvisioningOSDownloadURL)
+	if err != nil {
+		return err
+	}
+	if GetProvisioningOSDownloadURL(legacy) != downloadURL {
+		return fmt.Errorf("ProvisioningOSDownloadURL %q and ProvisioningOSImage %q name different images, only one of them needs to be set",
+			config.ProvisioningOSDownloadURL, downloadURL)
+	}
+	return nil
+}
+
+// validateProvisioningOSImage checks that the structured image can be
+// downloaded and verified
+func validateProvisioningOSImage(config *metal3iov1alpha1.ProvisioningSpec) error {
+	image := config.ProvisioningOSImage
+	if image == nil {
+		return nil
+	}
+	imageURL, err := url.Parse(image.URL)
+	if err != nil || (imageURL.Scheme != "http" && imageURL.Scheme != "https") || imageURL.Host == "" {
+		return fmt.Errorf("ProvisioningOSImage: invalid URL %q, an http or https URL is required", image.URL)
+	}
+	if _, checksum := splitChecksumQuery(imageURL.RawQuery); checksum != "" {
+		return fmt.Errorf("ProvisioningOSImage: URL %q must not carry the checksum, it goes in Checksum", image.URL)
+	}
+	if image.Checksum == "" {
+		if image.ChecksumType != "" {
+			return fmt.Errorf("ProvisioningOSImage: ChecksumType %s requires a Checksum", image.ChecksumType)
+		}
+		return nil
+	}
+	checksumType := getOSImageChecksumType(image)
+	length, ok := osImageChecksumLengths[checksumType]
+	if !ok {
+		return fmt.Errorf("ProvisioningOSImage: unsupported ChecksumType %q", checksumType)
+	}
+	if len(image.Checksum) != length || strings.Trim(strings.ToLower(image.Checksum), "0123456789abcdef") != "" {
+		return fmt.Errorf("ProvisioningOSImage: Checksum must be %d hexadecimal digits with ChecksumType %s", length, checksumType)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	testSHA256 = "cc9e1f5a2e2d7e3a4c7c7bf8dd9ee4cd23d2a1a3ce0f1ab5c3b9e57f8c2ea6a1"
+	testSHA512 = testSHA256 + testSHA256
+)
+
+func TestProvisioningOSDownloadURLConversion(t *testing.T) {
+	tests := []struct {
+		name        string
+		downloadURL string
+		image       metal3iov1alpha1.ProvisioningOSImage
+	}{
+		{
+			name:        "Checksum",
+			downloadURL: "https://mirror.example.com/rhcos.qcow2.gz?sha256=" + testSHA256,
+			image: metal3iov1alpha1.ProvisioningOSImage{
+				URL:          "https://mirror.example.com/rhcos.qcow2.gz",
+				Checksum:     testSHA256,
+				ChecksumType: metal3iov1alpha1.OSImageChecksumSHA256,
+			},
+		},
+		{
+			name:        "NoChecksum",
+			downloadURL: "https://mirror.example.com/rhcos.qcow2.gz",
+			image:       metal3iov1alpha1.ProvisioningOSImage{URL: "https://mirror.example.com/rhcos.qcow2.gz"},
+		},
+		{
+			// The other parameters of signed URLs are kept as they are
+			name:        "SignedURL",
+			downloadURL: "https://bucket.example.com/rhcos.qcow2.gz?X-Amz-Signature=a%2Fb&X-Amz-Expires=3600&sha256=" + testSHA256,
+			image: metal3iov1alpha1.ProvisioningOSImage{
+				URL:          "https://bucket.example.com/rhcos.qcow2.gz?X-Amz-Signature=a%2Fb&X-Amz-Expires=3600",
+				Checksum:     testSHA256,
+				ChecksumType: metal3iov1alpha1.OSImageChecksumSHA256,
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			image, err := ParseProvisioningOSDownloadURL(tc.downloadURL)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.image, *image)
+			}
+			assert.Equal(t, tc.downloadURL, GetProvisioningOSDownloadURL(&tc.image))
+		})
+	}
+
+	// SHA-512 checksums cannot be passed in the URL
+	assert.Equal(t, "https://mirror.example.com/rhcos.qcow2.gz", GetProvisioningOSDownloadURL(&metal3iov1alpha1.ProvisioningOSImage{
+		URL:          "https://mirror.example.com/rhcos.qcow2.gz",
+		Checksum:     testSHA512,
+		ChecksumType: metal3iov1alpha1.OSImageChecksumSHA512,
+	}))
+}
+
+func TestNormalizeProvisioningOSImage(t *testing.T) {
+	image := &metal3iov1alpha1.ProvisioningOSImage{
+		URL:      "https://mirror.example.com/rhcos.qcow2.gz",
+		Checksum: testSHA256,
+	}
+
+	spec := managedProvisioning()
+	spec.ProvisioningOSDownloadURL = ""
+	spec.ProvisioningOSImage = image
+	assert.NoError(t, NormalizeProvisioningSpec(spec))
+	assert.Equal(t, "https://mirror.example.com/rhcos.qcow2.gz?sha256="+testSHA256, spec.ProvisioningOSDownloadURL)
+	// Normalizing again changes nothing
+	assert.NoError(t, NormalizeProvisioningSpec(spec))
+
+	spec.ProvisioningOSDownloadURL = "https://mirror.example.com/rhcos.qcow2.gz?sha256=" + testSHA256[:63] + "0"
+	assert.EqualError(t, NormalizeProvisioningSpec(spec),
+		`ProvisioningOSDownloadURL "https://mirror.example.com/rhcos.qcow2.gz?sha256=`+testSHA256[:63]+`0" and ProvisioningOSImage "https://mirror.example.com/rhcos.qcow2.gz?sha256=`+testSHA256+`" name different images, only one of them needs to be set`)
+
+	// The legacy URL is left alone without a ProvisioningOSImage
+	spec = managedProvisioning()
+	downloadURL := spec.ProvisioningOSDownloadURL
+	assert.NoError(t, NormalizeProvisioningSpec(spec))
+	assert.Equal(t, downloadURL, spec.ProvisioningOSDownloadURL)
+	assert.Nil(t, spec.ProvisioningOSImage)
+}
+
+func TestValidateProvisioningOSImage(t *testing.T) {
+	tests := []struct {
+		name          string
+		image         *metal3iov1alpha1.ProvisioningOSImage
+		expectedError string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name:  "SHA256",
+			image: &metal3iov1alpha1.ProvisioningOSImage{URL: "https://mirror.example.com/rhcos.qcow2.gz", Checksum: testSHA256},
+		},
+		{
+			name: "SHA512Raw",
+			image: &metal3iov1alpha1.ProvisioningOSImage{
+				URL:          "http://mirror.example.com/rhcos.raw.xz",
+				Checksum:     testSHA512,
+				ChecksumType: metal3iov1alpha1.OSImageChecksumSHA512,
+				Format:       metal3iov1alpha1.OSImageFormatRaw,
+			},
+		},
+		{
+			name:          "InvalidURL",
+			image:         &metal3iov1alpha1.ProvisioningOSImage{URL: "mirror.example.com/rhcos.qcow2.gz"},
+			expectedError: `ProvisioningOSImage: invalid URL "mirror.example.com/rhcos.qcow2.gz", an http or https URL is required`,
+		},
+		{
+			name:          "ChecksumInURL",
+			image:         &metal3iov1alpha1.ProvisioningOSImage{URL: "https://mirror.example.com/rhcos.qcow2.gz?sha256=" + testSHA256},
+			expectedError: `ProvisioningOSImage: URL "https://mirror.example.com/rhcos.qcow2.gz?sha256=` + testSHA256 + `" must not carry the checksum, it goes in Checksum`,
+		},
+		{
+			name: "ChecksumTypeWithoutChecksum",
+			image: &metal3iov1alpha1.ProvisioningOSImage{
+				URL:          "https://mirror.example.com/rhcos.qcow2.gz",
+				ChecksumType: metal3iov1alpha1.OSImageChecksumSHA512,
+			},
+			expectedError: "ProvisioningOSImage: ChecksumType sha512 requires a Checksum",
+		},
+		{
+			name: "ChecksumLength",
+			image: &metal3iov1alpha1.ProvisioningOSImage{
+				URL:          "https://mirror.example.com/rhcos.qcow2.gz",
+				Checksum:     testSHA256,
+				ChecksumType: metal3iov1alpha1.OSImageChecksumSHA512,
+			},
+			expectedError: "ProvisioningOSImage: Checksum must be 128 hexadecimal digits with ChecksumType sha512",
+		},
+		{
+			name:          "ChecksumDigits",
+			image:         &metal3iov1alpha1.ProvisioningOSImage{URL: "https://mirror.example.com/rhcos.qcow2.gz", Checksum: testSHA256[:63] + "g"},
+			expectedError: "ProvisioningOSImage: Checksum must be 64 hexadecimal digits with ChecksumType sha256",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.ProvisioningOSImage = tc.image
+			err := validateProvisioningOSImage(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestProvisioningOSImageEnv(t *testing.T) {
+	spec := managedProvisioning()
+	downloader := createInitContainerMachineOsDownloader(&testImages, spec)
+	assert.Equal(t, []string{getResourceCommand}, downloader.Command)
+
+	// The machine-os-downloader only verifies the sha256 checksum of the
+	// URL, so the other checksums and the format are verified by the script
+	spec.ProvisioningOSImage = &metal3iov1alpha1.ProvisioningOSImage{
+		URL:          "https://mirror.example.com/rhcos.raw.gz",
+		Checksum:     testSHA512,
+		ChecksumType: metal3iov1alpha1.OSImageChecksumSHA512,
+		Format:       metal3iov1alpha1.OSImageFormatRaw,
+	}
+	spec.ProvisioningOSDownloadURL = ""
+	assert.NoError(t, NormalizeProvisioningSpec(spec))
+	downloader = createInitContainerMachineOsDownloader(&testImages, spec)
+	assert.Equal(t, []string{"/bin/sh", "-c", osImageZstdScript}, downloader.Command)
+	assert.Equal(t, "https://mirror.example.com/rhcos.raw.gz", envValue(&downloader, "RHCOS_IMAGE_URL"))
+	assert.Equal(t, "true", envValue(&downloader, "OS_IMAGE_FETCH"))
+	assert.Equal(t, testSHA512, envValue(&downloader, "OS_IMAGE_CHECKSUM"))
+	assert.Equal(t, "sha512", envValue(&downloader, "OS_IMAGE_CHECKSUM_TYPE"))
+	assert.Equal(t, "raw", envValue(&downloader, "OS_IMAGE_FORMAT"))
+
+	// A sha256 checksum is verified by the machine-os-downloader
+	spec.ProvisioningOSImage = &metal3iov1alpha1.ProvisioningOSImage{
+		URL:      "https://mirror.example.com/rhcos.qcow2.gz",
+		Checksum: testSHA256,
+	}
+	spec.ProvisioningOSDownloadURL = ""
+	assert.NoError(t, NormalizeProvisioningSpec(spec))
+	downloader = createInitContainerMachineOsDownloader(&testImages, spec)
+	assert.Equal(t, []string{getResourceCommand}, downloader.Command)
+	assert.Equal(t, "https://mirror.example.com/rhcos.qcow2.gz?sha256="+testSHA256, envValue(&downloader, "RHCOS_IMAGE_URL"))
+}
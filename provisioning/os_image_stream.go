@@ -0,0 +1,121 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// CoreOSBootImagesConfigMap is the ConfigMap published by the
+	// machine-config-operator containing the RHCOS stream metadata
+	CoreOSBootImagesConfigMap = "coreos-bootimages"
+	// CoreOSBootImagesNamespace is the namespace of the coreos-bootimages ConfigMap
+	CoreOSBootImagesNamespace = "openshift-machine-config-operator"
+
+	coreOSStreamKey      = "stream"
+	coreOSArtifact       = "openstack"
+	coreOSArtifactFormat = "qcow2.gz"
+)
+
+// coreOSArchitectures maps the kubernetes.io/arch label of the nodes to
+// the architectures of the RHCOS stream metadata
+var coreOSArchitectures = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// OSImage is a provisioning OS image resolved from the RHCOS stream metadata
+type OSImage struct {
+	// Version is the RHCOS release of the image
+	Version string
+	// URL is the download location of the image, including the
+	// sha256 checksum of the uncompressed image as expected by the
+	// machine-os-downloader
+	URL string
+}
+
+type coreOSStream struct {
+	Architectures map[string]struct {
+		Artifacts map[string]struct {
+			Release string `json:"release"`
+			Formats map[string]struct {
+				Disk struct {
+					Location           string `json:"location"`
+					Sha256             string `json:"sha256"`
+					UncompressedSha256 string `json:"uncompressed-sha256"`
+				} `json:"disk"`
+			} `json:"formats"`
+		} `json:"artifacts"`
+	} `json:"architectures"`
+}
+
+// ControlPlaneNodeLabels returns the labels of the control plane nodes,
+// whose architecture the hosts are provisioned with
+func ControlPlaneNodeLabels() map[string]string {
+	return map[string]string{masterNodeLabel: ""}
+}
+
+// GetCoreOSArchitecture returns the RHCOS stream architecture of the
+// control plane nodes
+func GetCoreOSArchitecture(nodes []corev1.Node) (string, error) {
+	if len(nodes) == 0 {
+		return "", fmt.Errorf("no control plane node found")
+	}
+	nodeArch := nodes[0].Labels[corev1.LabelArchStable]
+	for _, node := range nodes[1:] {
+		if node.Labels[corev1.LabelArchStable] != nodeArch {
+			return "", NewInvalidSpecError(fmt.Errorf("the control plane nodes %s and %s have different architectures",
+				nodes[0].Name, node.Name))
+		}
+	}
+	arch, ok := coreOSArchitectures[nodeArch]
+	if !ok {
+		return "", NewInvalidSpecError(fmt.Errorf("AutoUpdateOSImage does not support the %q architecture of node %s",
+			nodeArch, nodes[0].Name))
+	}
+	return arch, nil
+}
+
+// GetOSImageFromStream returns the provisioning OS image of the given
+// architecture described by the stream metadata in the coreos-bootimages
+// ConfigMap. A stream without the architecture is reported as an invalid
+// spec, as AutoUpdateOSImage cannot be honored.
+func GetOSImageFromStream(cm *corev1.ConfigMap, architecture string) (*OSImage, error) {
+	data, ok := cm.Data[coreOSStreamKey]
+	if !ok {
+		return nil, fmt.Errorf("%s ConfigMap has no %q key", cm.Name, coreOSStreamKey)
+	}
+
+	var stream coreOSStream
+	if err := json.Unmarshal([]byte(data), &stream); err != nil {
+		return nil, fmt.Errorf("unable to parse stream metadata in %s ConfigMap: %v", cm.Name, err)
+	}
+
+	arch, ok := stream.Architectures[architecture]
+	if !ok {
+		return nil, NewInvalidSpecError(fmt.Errorf("AutoUpdateOSImage: the stream metadata in the %s ConfigMap has no %s architecture",
+			cm.Name, architecture))
+	}
+	artifact, ok := arch.Artifacts[coreOSArtifact]
+	if !ok {
+		return nil, fmt.Errorf("stream metadata has no %s artifact", coreOSArtifact)
+	}
+	format, ok := artifact.Formats[coreOSArtifactFormat]
+	if !ok || format.Disk.Location == "" {
+		return nil, fmt.Errorf("stream metadata has no %s disk for the %s artifact", coreOSArtifactFormat, coreOSArtifact)
+	}
+
+	checksum := format.Disk.UncompressedSha256
+	if checksum == "" {
+		return nil, fmt.Errorf("stream metadata has no uncompressed-sha256 for %s", format.Disk.Location)
+	}
+
+	return &OSImage{
+		Version: artifact.Release,
+		URL:     fmt.Sprintf("%s?sha256=%s", format.Disk.Location, checksum),
+	}, nil
+}
@@ -0,0 +1,148 @@
+package provisioning
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testStream = `{
+  "stream": "stable",
+  "architectures": {
+    "x86_64": {
+      "artifacts": {
+        "openstack": {
+          "release": "46.82.202008181646-0",
+          "formats": {
+            "qcow2.gz": {
+              "disk": {
+                "location": "https://example.com/rhcos-46.82.202008181646-0-openstack.x86_64.qcow2.gz",
+                "sha256": "abcd",
+                "uncompressed-sha256": "1234"
+              }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+func TestGetOSImageFromStream(t *testing.T) {
+	tCases := []struct {
+		name          string
+		data          map[string]string
+		arch          string
+		expectedError bool
+		invalidSpec   bool
+		expectedImage *OSImage
+	}{
+		{
+			name: "ValidStream",
+			data: map[string]string{"stream": testStream},
+			expectedImage: &OSImage{
+				Version: "46.82.202008181646-0",
+				URL:     "https://example.com/rhcos-46.82.202008181646-0-openstack.x86_64.qcow2.gz?sha256=1234",
+			},
+		},
+		{
+			name:          "MissingStreamKey",
+			data:          map[string]string{},
+			expectedError: true,
+		},
+		{
+			name:          "InvalidJSON",
+			data:          map[string]string{"stream": "{"},
+			expectedError: true,
+		},
+		{
+			name:          "MissingArtifact",
+			data:          map[string]string{"stream": `{"architectures": {"x86_64": {"artifacts": {}}}}`},
+			expectedError: true,
+		},
+		{
+			name:          "MissingArchitecture",
+			data:          map[string]string{"stream": testStream},
+			arch:          "aarch64",
+			expectedError: true,
+			invalidSpec:   true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      CoreOSBootImagesConfigMap,
+					Namespace: CoreOSBootImagesNamespace,
+				},
+				Data: tc.data,
+			}
+			arch := tc.arch
+			if arch == "" {
+				arch = "x86_64"
+			}
+			image, err := GetOSImageFromStream(cm, arch)
+			if tc.expectedError != (err != nil) {
+				t.Errorf("ExpectedError: %v, got: %v", tc.expectedError, err)
+			}
+			assert.Equal(t, tc.invalidSpec, errors.Is(err, ErrInvalidSpec))
+			assert.Equal(t, tc.expectedImage, image)
+		})
+	}
+}
+
+func TestGetCoreOSArchitecture(t *testing.T) {
+	node := func(name string, arch string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{corev1.LabelArchStable: arch}}}
+	}
+	tCases := []struct {
+		name          string
+		nodes         []corev1.Node
+		expectedArch  string
+		expectedError string
+	}{
+		{
+			name:         "AMD64",
+			nodes:        []corev1.Node{node("master-0", "amd64"), node("master-1", "amd64")},
+			expectedArch: "x86_64",
+		},
+		{
+			name:         "ARM64",
+			nodes:        []corev1.Node{node("master-0", "arm64")},
+			expectedArch: "aarch64",
+		},
+		{
+			name:         "S390X",
+			nodes:        []corev1.Node{node("master-0", "s390x")},
+			expectedArch: "s390x",
+		},
+		{
+			name:          "Unsupported",
+			nodes:         []corev1.Node{node("master-0", "riscv64")},
+			expectedError: `AutoUpdateOSImage does not support the "riscv64" architecture of node master-0`,
+		},
+		{
+			name:          "Mixed",
+			nodes:         []corev1.Node{node("master-0", "amd64"), node("master-1", "arm64")},
+			expectedError: "the control plane nodes master-0 and master-1 have different architectures",
+		},
+		{
+			name:          "NoNode",
+			expectedError: "no control plane node found",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			arch, err := GetCoreOSArchitecture(tc.nodes)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedArch, arch)
+		})
+	}
+}
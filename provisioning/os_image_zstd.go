@@ -0,0 +1,216 @@
+package provisioning
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const zstdSuffix = ".zst"
+
+// osImageZstdScript caches the OS image when the machine-os-downloader
+// cannot: it patches the earlier image of the Delta when that one is
+// cached, decompresses zstd images, and verifies the checksums and
+// formats the machine-os-downloader does not check. Anything else is
+// left to the machine-os-downloader. The image is only moved in place
+// once verified, so that a failed patch or download is never served.
+const osImageZstdScript = `set -eu
+set -f
+image_dir="/shared/html/images/${OS_IMAGE_NAME}"
+image="${image_dir}/${OS_IMAGE_NAME}"
+part="${image_dir}/${OS_IMAGE_DOWNLOAD_NAME}.part"
+if [ -f "${image}" ]; then
+    echo "${image} already cached"
+    exit 0
+fi
+mkdir -p "${image_dir}"
+trap 'rm -f "${part}" "${image}.tmp"' EXIT
+
+verify() {
+    if [ -n "${OS_IMAGE_FORMAT}" ]; then
+        format=raw
+        if [ "$(head -c 3 "${image}.tmp")" = QFI ]; then
+            format=qcow2
+        fi
+        if [ "${format}" != "${OS_IMAGE_FORMAT}" ]; then
+            echo "${OS_IMAGE_NAME} is a ${format} image rather than ${OS_IMAGE_FORMAT}" >&2
+            return 1
+        fi
+    fi
+    if [ -z "${OS_IMAGE_CHECKSUM}" ]; then
+        return 0
+    fi
+    [ "$("${OS_IMAGE_CHECKSUM_TYPE}sum" "${image}.tmp" | cut -d ' ' -f 1)" = "${OS_IMAGE_CHECKSUM}" ]
+}
+
+decompress() {
+    case "${OS_IMAGE_DOWNLOAD_NAME}" in
+    *.zst) zstd --decompress --quiet --force --long=31 "${part}" -o "${image}.tmp" ;;
+    *.gz) gzip --decompress --stdout "${part}" > "${image}.tmp" ;;
+    *.xz) xz --decompress --stdout "${part}" > "${image}.tmp" ;;
+    *) mv "${part}" "${image}.tmp" ;;
+    esac
+}
+
+fetch_delta() {
+    base="/shared/html/images/${OS_IMAGE_DELTA_BASE}/${OS_IMAGE_DELTA_BASE}"
+    if [ -z "${OS_IMAGE_DELTA_URL}" ] || [ ! -f "${base}" ]; then
+        return 1
+    fi
+    echo "patching ${OS_IMAGE_DELTA_BASE} into ${OS_IMAGE_NAME}"
+    if curl --fail --silent --show-error --location --output "${part}" "${OS_IMAGE_DELTA_URL}" &&
+        zstd --decompress --quiet --force --long=31 --patch-from="${base}" "${part}" -o "${image}.tmp" &&
+        verify; then
+        return 0
+    fi
+    echo "failed to patch ${OS_IMAGE_DELTA_BASE}, downloading the whole image" >&2
+    rm -f "${part}" "${image}.tmp"
+    return 1
+}
+
+fetch_image() {
+    for url in ${RHCOS_IMAGE_URL} ${RHCOS_IMAGE_MIRRORS:-}; do
+        echo "downloading ${url%%\?*}"
+        if curl --fail --silent --show-error --location --output "${part}" "${url}" &&
+            decompress &&
+            verify; then
+            return 0
+        fi
+        echo "failed to download the OS image from ${url%%\?*}" >&2
+    done
+    return 1
+}
+
+if fetch_delta; then
+    :
+elif [ "${OS_IMAGE_FETCH}" = true ]; then
+    fetch_image
+else
+    (` + osImageMirrorsScript + `)
+    exit 0
+fi
+mv "${image}.tmp" "${image}"
+md5sum "${image}" | cut -d ' ' -f 1 > "${image}.md5sum"
+sha256sum "${image}" | cut -d ' ' -f 1 > "${image}.sha256sum"
+echo "${image} cached"`
+
+// isZstdOSImage returns true when the provisioning OS image is compressed
+// with zstd, which the machine-os-downloader does not decompress
+func isZstdOSImage(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	imageURL, err := url.Parse(config.ProvisioningOSDownloadURL)
+	return err == nil && strings.HasSuffix(imageURL.Path, zstdSuffix)
+}
+
+func getOSImageDelta(config *metal3iov1alpha1.ProvisioningSpec) *metal3iov1alpha1.OSImageDelta {
+	if config.ProvisioningOSImage == nil {
+		return nil
+	}
+	return config.ProvisioningOSImage.Delta
+}
+
+// isUnverifiedOSImage returns true when the ProvisioningOSImage has a
+// checksum or a format the machine-os-downloader does not check, as it
+// only verifies the sha256 checksum of the ProvisioningOSDownloadURL
+func isUnverifiedOSImage(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	image := config.ProvisioningOSImage
+	if image == nil {
+		return false
+	}
+	return image.Format != "" ||
+		(image.Checksum != "" && getOSImageChecksumType(image) != metal3iov1alpha1.OSImageChecksumSHA256)
+}
+
+// fetchesOSImage returns true when osImageZstdScript downloads the whole
+// OS image itself rather than through the machine-os-downloader
+func fetchesOSImage(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return isZstdOSImage(config) || isUnverifiedOSImage(config)
+}
+
+// usesOSImageZstdScript returns true when the OS image is cached by
+// osImageZstdScript rather than by the machine-os-downloader alone
+func usesOSImageZstdScript(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return fetchesOSImage(config) || getOSImageDelta(config) != nil
+}
+
+// getOSImageFormat returns the format the uncompressed image is checked
+// against, when one is set
+func getOSImageFormat(config *metal3iov1alpha1.ProvisioningSpec) metal3iov1alpha1.OSImageFormat {
+	if config.ProvisioningOSImage == nil {
+		return ""
+	}
+	return config.ProvisioningOSImage.Format
+}
+
+// getOSImageChecksum returns the checksum the uncompressed image is
+// verified against, from the ProvisioningOSImage or else from the sha256
+// query parameter of the ProvisioningOSDownloadURL
+func getOSImageChecksum(config *metal3iov1alpha1.ProvisioningSpec) (string, metal3iov1alpha1.OSImageChecksumType) {
+	image := config.ProvisioningOSImage
+	if image == nil {
+		parsed, err := ParseProvisioningOSDownloadURL(config.ProvisioningOSDownloadURL)
+		if err != nil {
+			return "", ""
+		}
+		image = parsed
+	}
+	if image.Checksum == "" {
+		return "", ""
+	}
+	return strings.ToLower(image.Checksum), getOSImageChecksumType(image)
+}
+
+func newOSImageZstdEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	if !usesOSImageZstdScript(config) {
+		return nil
+	}
+	downloadName := ""
+	if imageURL, err := url.Parse(config.ProvisioningOSDownloadURL); err == nil {
+		downloadName = path.Base(imageURL.Path)
+	}
+	deltaURL, deltaBase := "", ""
+	if delta := getOSImageDelta(config); delta != nil {
+		deltaURL, deltaBase = delta.URL, getCachedImageName(delta.BaseURL)
+	}
+	checksum, checksumType := getOSImageChecksum(config)
+	return []corev1.EnvVar{
+		{Name: "OS_IMAGE_NAME", Value: getCachedImageName(config.ProvisioningOSDownloadURL)},
+		{Name: "OS_IMAGE_DOWNLOAD_NAME", Value: downloadName},
+		{Name: "OS_IMAGE_FETCH", Value: strconv.FormatBool(fetchesOSImage(config))},
+		{Name: "OS_IMAGE_CHECKSUM", Value: checksum},
+		{Name: "OS_IMAGE_CHECKSUM_TYPE", Value: string(checksumType)},
+		{Name: "OS_IMAGE_FORMAT", Value: string(getOSImageFormat(config))},
+		{Name: "OS_IMAGE_DELTA_URL", Value: deltaURL},
+		{Name: "OS_IMAGE_DELTA_BASE", Value: deltaBase},
+	}
+}
+
+// validateOSImageDelta checks that the image the Delta applies to can be
+// found in the cache, and that the patched image can be verified
+func validateOSImageDelta(config *metal3iov1alpha1.ProvisioningSpec) error {
+	delta := getOSImageDelta(config)
+	if delta == nil {
+		return nil
+	}
+	for _, field := range []struct{ name, value string }{{"URL", delta.URL}, {"BaseURL", delta.BaseURL}} {
+		deltaURL, err := url.Parse(field.value)
+		if err != nil || (deltaURL.Scheme != "http" && deltaURL.Scheme != "https") || deltaURL.Host == "" {
+			return fmt.Errorf("ProvisioningOSImage: invalid Delta %s %q, an http or https URL is required", field.name, field.value)
+		}
+	}
+	if config.ProvisioningOSImage.Checksum == "" {
+		return fmt.Errorf("ProvisioningOSImage: Delta requires a Checksum, so that the patched image is verified")
+	}
+	if config.ImageCache == nil {
+		return fmt.Errorf("ProvisioningOSImage: Delta requires ImageCache, the image it applies to is only kept in the cache")
+	}
+	if getCachedImageName(delta.BaseURL) == getCachedImageName(config.ProvisioningOSImage.URL) {
+		return fmt.Errorf("ProvisioningOSImage: Delta BaseURL %q names the image itself", delta.BaseURL)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func deltaProvisioning() *metal3iov1alpha1.ProvisioningSpec {
+	spec := managedProvisioning()
+	spec.ProvisioningOSDownloadURL = ""
+	spec.ProvisioningOSImage = &metal3iov1alpha1.ProvisioningOSImage{
+		URL:      "https://mirror.example.com/rhcos-48.84.qcow2.gz",
+		Checksum: testSHA256,
+		Delta: &metal3iov1alpha1.OSImageDelta{
+			URL:     "https://mirror.example.com/rhcos-47.84-48.84.qcow2.patch.zst",
+			BaseURL: "https://mirror.example.com/rhcos-47.84.qcow2.gz",
+		},
+	}
+	cacheSize := resource.MustParse("50Gi")
+	spec.ImageCache = &metal3iov1alpha1.ImageCache{Size: &cacheSize}
+	return spec
+}
+
+func TestOSImageZstdDownloader(t *testing.T) {
+	tCases := []struct {
+		name           string
+		config         func() *metal3iov1alpha1.ProvisioningSpec
+		expectedScript bool
+		expectedEnv    map[string]string
+	}{
+		{
+			name:   "Gzip",
+			config: managedProvisioning,
+		},
+		{
+			name: "Zstd",
+			config: func() *metal3iov1alpha1.ProvisioningSpec {
+				spec := managedProvisioning()
+				spec.ProvisioningOSDownloadURL = "http://172.22.0.1/images/rhcos-48.84.qcow2.zst?sha256=" + testSHA256
+				return spec
+			},
+			expectedScript: true,
+			expectedEnv: map[string]string{
+				"OS_IMAGE_NAME":          "rhcos-48.84.qcow2",
+				"OS_IMAGE_DOWNLOAD_NAME": "rhcos-48.84.qcow2.zst",
+				"OS_IMAGE_FETCH":         "true",
+				"OS_IMAGE_CHECKSUM":      testSHA256,
+				"OS_IMAGE_CHECKSUM_TYPE": "sha256",
+				"OS_IMAGE_DELTA_URL":     "",
+			},
+		},
+		{
+			name: "Delta",
+			config: func() *metal3iov1alpha1.ProvisioningSpec {
+				spec := deltaProvisioning()
+				assert.NoError(t, NormalizeProvisioningSpec(spec))
+				return spec
+			},
+			expectedScript: true,
+			expectedEnv: map[string]string{
+				"OS_IMAGE_NAME":          "rhcos-48.84.qcow2",
+				"OS_IMAGE_DOWNLOAD_NAME": "rhcos-48.84.qcow2.gz",
+				"OS_IMAGE_FETCH":         "false",
+				"OS_IMAGE_CHECKSUM":      testSHA256,
+				"OS_IMAGE_DELTA_URL":     "https://mirror.example.com/rhcos-47.84-48.84.qcow2.patch.zst",
+				"OS_IMAGE_DELTA_BASE":    "rhcos-47.84.qcow2",
+			},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := tc.config()
+			downloader := createInitContainerMachineOsDownloader(&testImages, spec)
+			if !tc.expectedScript {
+				assert.Equal(t, []string{"/usr/local/bin/get-resource.sh"}, downloader.Command)
+				assert.Equal(t, "", envValue(&downloader, "OS_IMAGE_NAME"))
+				return
+			}
+			assert.Equal(t, []string{"/bin/sh", "-c", osImageZstdScript}, downloader.Command)
+			for name, value := range tc.expectedEnv {
+				assert.Equal(t, value, envValue(&downloader, name), name)
+			}
+		})
+	}
+}
+
+func TestValidateOSImageDelta(t *testing.T) {
+	tests := []struct {
+		name          string
+		update        func(*metal3iov1alpha1.ProvisioningSpec)
+		expectedError string
+	}{
+		{
+			name:   "Valid",
+			update: func(*metal3iov1alpha1.ProvisioningSpec) {},
+		},
+		{
+			name:          "InvalidURL",
+			update:        func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ProvisioningOSImage.Delta.URL = "delta.zst" },
+			expectedError: `ProvisioningOSImage: invalid Delta URL "delta.zst", an http or https URL is required`,
+		},
+		{
+			name:          "InvalidBaseURL",
+			update:        func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ProvisioningOSImage.Delta.BaseURL = "" },
+			expectedError: `ProvisioningOSImage: invalid Delta BaseURL "", an http or https URL is required`,
+		},
+		{
+			name:          "NoChecksum",
+			update:        func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ProvisioningOSImage.Checksum = "" },
+			expectedError: "ProvisioningOSImage: Delta requires a Checksum, so that the patched image is verified",
+		},
+		{
+			name:          "NoImageCache",
+			update:        func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.ImageCache = nil },
+			expectedError: "ProvisioningOSImage: Delta requires ImageCache, the image it applies to is only kept in the cache",
+		},
+		{
+			name: "SameImage",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.ProvisioningOSImage.Delta.BaseURL = "https://other.example.com/rhcos-48.84.qcow2.xz"
+			},
+			expectedError: `ProvisioningOSImage: Delta BaseURL "https://other.example.com/rhcos-48.84.qcow2.xz" names the image itself`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := deltaProvisioning()
+			tc.update(spec)
+			err := validateOSImageDelta(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package provisioning
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const masterNodeLabel = "node-role.kubernetes.io/master"
+
+// IsControlPlaneOnly returns true when the metal3 pods must run on the
+// control plane nodes
+func IsControlPlaneOnly(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.ControlPlaneOnly == nil || *config.ControlPlaneOnly
+}
+
+// GetMetal3NodeSelector returns the node selector of the metal3 pods
+func GetMetal3NodeSelector(config *metal3iov1alpha1.ProvisioningSpec) map[string]string {
+	if IsControlPlaneOnly(config) {
+		return map[string]string{masterNodeLabel: ""}
+	}
+	selector := map[string]string{}
+	for k, v := range config.NodeSelector {
+		selector[k] = v
+	}
+	return selector
+}
+
+// newMetal3Tolerations returns the tolerations of the metal3 pods. The
+// control plane taint is only tolerated when running on the control
+// plane, so that selecting workers never lands the pods on masters.
+func newMetal3Tolerations(config *metal3iov1alpha1.ProvisioningSpec) []corev1.Toleration {
+	tolerations := []corev1.Toleration{}
+	if IsControlPlaneOnly(config) {
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:      masterNodeLabel,
+			Effect:   corev1.TaintEffectNoSchedule,
+			Operator: corev1.TolerationOpExists,
+		})
+	}
+	return append(tolerations,
+		corev1.Toleration{
+			Key:      "CriticalAddonsOnly",
+			Operator: corev1.TolerationOpExists,
+		},
+		corev1.Toleration{
+			Key:               "node.kubernetes.io/not-ready",
+			Effect:            corev1.TaintEffectNoExecute,
+			Operator:          corev1.TolerationOpExists,
+			TolerationSeconds: pointer.Int64Ptr(120),
+		},
+		corev1.Toleration{
+			Key:               "node.kubernetes.io/unreachable",
+			Effect:            corev1.TaintEffectNoExecute,
+			Operator:          corev1.TolerationOpExists,
+			TolerationSeconds: pointer.Int64Ptr(120),
+		},
+	)
+}
+
+func validatePlacement(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if IsControlPlaneOnly(config) {
+		if len(config.NodeSelector) > 0 {
+			return fmt.Errorf("NodeSelector is only used when ControlPlaneOnly is false")
+		}
+		return nil
+	}
+	if len(config.NodeSelector) == 0 {
+		return fmt.Errorf("NodeSelector is required when ControlPlaneOnly is false")
+	}
+	return nil
+}
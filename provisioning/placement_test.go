@@ -0,0 +1,79 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/utils/pointer"
+)
+
+func TestMetal3Placement(t *testing.T) {
+
+	tCases := []struct {
+		name                     string
+		controlPlaneOnly         *bool
+		nodeSelector             map[string]string
+		expectedSelector         map[string]string
+		expectedMasterToleration bool
+		expectedValidateFail     bool
+	}{
+		{
+			name:                     "Default",
+			expectedSelector:         map[string]string{masterNodeLabel: ""},
+			expectedMasterToleration: true,
+		},
+		{
+			name:                     "ControlPlaneOnly",
+			controlPlaneOnly:         pointer.BoolPtr(true),
+			expectedSelector:         map[string]string{masterNodeLabel: ""},
+			expectedMasterToleration: true,
+		},
+		{
+			name:                     "SelectorWithControlPlane",
+			nodeSelector:             map[string]string{"metal3.io/provisioning": "true"},
+			expectedSelector:         map[string]string{masterNodeLabel: ""},
+			expectedMasterToleration: true,
+			expectedValidateFail:     true,
+		},
+		{
+			name:             "Workers",
+			controlPlaneOnly: pointer.BoolPtr(false),
+			nodeSelector:     map[string]string{"metal3.io/provisioning": "true"},
+			expectedSelector: map[string]string{"metal3.io/provisioning": "true"},
+		},
+		{
+			name:                 "WorkersWithoutSelector",
+			controlPlaneOnly:     pointer.BoolPtr(false),
+			expectedSelector:     map[string]string{},
+			expectedValidateFail: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			config.ControlPlaneOnly = tc.controlPlaneOnly
+			config.NodeSelector = tc.nodeSelector
+
+			assert.Equal(t, tc.expectedSelector, GetMetal3NodeSelector(config))
+			masterToleration := false
+			for _, toleration := range newMetal3Tolerations(config) {
+				if toleration.Key == masterNodeLabel {
+					masterToleration = true
+				}
+			}
+			assert.Equal(t, tc.expectedMasterToleration, masterToleration)
+
+			err := validatePlacement(config)
+			if tc.expectedValidateFail {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			deployment := NewMetal3Deployment(testNamespace, &testImages, config)
+			daemonSet := NewDnsmasqDaemonSet(testNamespace, &testImages, config)
+			assert.Equal(t, tc.expectedSelector, deployment.Spec.Template.Spec.NodeSelector)
+			assert.Equal(t, tc.expectedSelector, daemonSet.Spec.Template.Spec.NodeSelector)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package provisioning
+
+import (
+	"fmt"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// platformProvisioningNetworks lists the provisioning network modes
+// supported on each platform. A Managed provisioning network needs hosts
+// set up by the installer for bare metal provisioning, as the operator
+// then configures their provisioning interface and serves DHCP on it.
+var platformProvisioningNetworks = map[osconfigv1.PlatformType][]metal3iov1alpha1.ProvisioningNetwork{
+	osconfigv1.BareMetalPlatformType: {
+		metal3iov1alpha1.ProvisioningNetworkManaged,
+		metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+		metal3iov1alpha1.ProvisioningNetworkDisabled,
+	},
+	osconfigv1.NonePlatformType: {
+		metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+		metal3iov1alpha1.ProvisioningNetworkDisabled,
+	},
+}
+
+// GetPlatformType returns the platform of the cluster, falling back to the
+// deprecated Status.Platform field when the PlatformStatus is not set
+func GetPlatformType(infra *osconfigv1.Infrastructure) osconfigv1.PlatformType {
+	if infra.Status.PlatformStatus != nil && infra.Status.PlatformStatus.Type != "" {
+		return infra.Status.PlatformStatus.Type
+	}
+	return infra.Status.Platform
+}
+
+// ValidatePlatformSupport checks that the provisioning network mode of the
+// Provisioning resource is supported on the given platform. Platforms
+// without bare metal specific support are handled as platform None. The
+// errors returned match ErrInvalidSpec.
+func ValidatePlatformSupport(prov *metal3iov1alpha1.Provisioning, platform osconfigv1.PlatformType) error {
+	mode := getProvisioningNetworkMode(prov)
+	if mode == metal3iov1alpha1.ProvisioningNetworkAuto {
+		// Auto resolves to a Managed network
+		mode = metal3iov1alpha1.ProvisioningNetworkManaged
+	}
+	supported, ok := platformProvisioningNetworks[platform]
+	if !ok {
+		supported = platformProvisioningNetworks[osconfigv1.NonePlatformType]
+	}
+	for _, m := range supported {
+		if m == mode {
+			return nil
+		}
+	}
+	return NewInvalidSpecError(fmt.Errorf("ProvisioningNetwork %s is not supported on platform %q, use one of %v", mode, platform, supported))
+}
@@ -0,0 +1,64 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestGetPlatformType(t *testing.T) {
+	infra := &osconfigv1.Infrastructure{}
+	infra.Status.Platform = osconfigv1.BareMetalPlatformType
+	assert.Equal(t, osconfigv1.BareMetalPlatformType, GetPlatformType(infra))
+
+	infra.Status.PlatformStatus = &osconfigv1.PlatformStatus{Type: osconfigv1.NonePlatformType}
+	assert.Equal(t, osconfigv1.NonePlatformType, GetPlatformType(infra))
+}
+
+func TestValidatePlatformSupport(t *testing.T) {
+	tCases := []struct {
+		name        string
+		platform    osconfigv1.PlatformType
+		mode        metal3iov1alpha1.ProvisioningNetwork
+		expectedErr string
+	}{
+		{name: "BareMetalManaged", platform: osconfigv1.BareMetalPlatformType, mode: metal3iov1alpha1.ProvisioningNetworkManaged},
+		{name: "BareMetalDefault", platform: osconfigv1.BareMetalPlatformType},
+		{name: "NoneDisabled", platform: osconfigv1.NonePlatformType, mode: metal3iov1alpha1.ProvisioningNetworkDisabled},
+		{name: "NoneUnmanaged", platform: osconfigv1.NonePlatformType, mode: metal3iov1alpha1.ProvisioningNetworkUnmanaged},
+		{
+			name:        "NoneManaged",
+			platform:    osconfigv1.NonePlatformType,
+			mode:        metal3iov1alpha1.ProvisioningNetworkManaged,
+			expectedErr: "ProvisioningNetwork Managed is not supported on platform \"None\"",
+		},
+		{
+			name:        "NoneDefault",
+			platform:    osconfigv1.NonePlatformType,
+			expectedErr: "ProvisioningNetwork Managed is not supported",
+		},
+		{name: "VSphereDisabled", platform: osconfigv1.VSpherePlatformType, mode: metal3iov1alpha1.ProvisioningNetworkDisabled},
+		{
+			name:        "VSphereManaged",
+			platform:    osconfigv1.VSpherePlatformType,
+			mode:        metal3iov1alpha1.ProvisioningNetworkManaged,
+			expectedErr: "ProvisioningNetwork Managed is not supported on platform \"VSphere\"",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := &metal3iov1alpha1.Provisioning{Spec: metal3iov1alpha1.ProvisioningSpec{ProvisioningNetwork: tc.mode}}
+			err := ValidatePlatformSupport(prov, tc.platform)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package provisioning
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// restrictedCapabilities are the capabilities the metal3 containers keep
+// in the Restricted mode, as the restricted Pod Security profile drops
+// all the others. The containers configuring the host network and
+// serving DHCP need the network capabilities, while httpd and MariaDB
+// start as root before switching to their own users.
+var restrictedCapabilities = map[string][]corev1.Capability{
+	StaticIPManagerContainerName: {"NET_ADMIN", "NET_RAW"},
+	"metal3-static-ip-set":       {"NET_ADMIN", "NET_RAW"},
+	bmcRoutesContainerName:       {"NET_ADMIN"},
+	"metal3-dnsmasq":             {"NET_ADMIN", "NET_RAW", "NET_BIND_SERVICE"},
+	"metal3-httpd":               {"SETUID", "SETGID"},
+	"metal3-ironic-api-proxy":    {"SETUID", "SETGID"},
+	"metal3-mariadb":             {"SETUID", "SETGID", "CHOWN", "DAC_OVERRIDE", "FOWNER"},
+}
+
+// podSecurityNamespaceLabels are the labels of the namespace in the
+// Restricted mode. The host network of the metal3 pods is only allowed by
+// the privileged level, which is still enforced, while the restricted
+// level is audited so that the remaining exceptions are recorded.
+var podSecurityNamespaceLabels = map[string]string{
+	"pod-security.kubernetes.io/enforce":             "privileged",
+	"pod-security.kubernetes.io/audit":               "restricted",
+	"security.openshift.io/scc.podSecurityLabelSync": "false",
+}
+
+// IsRestrictedPodSecurity returns true when the metal3 containers follow
+// the restricted Pod Security profile wherever they can
+func IsRestrictedPodSecurity(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.PodSecurityMode == metal3iov1alpha1.PodSecurityModeRestricted
+}
+
+// GetPodSecurityNamespaceLabels returns the labels the namespace of the
+// metal3 pods needs for the PodSecurityMode, or nil when it needs none
+func GetPodSecurityNamespaceLabels(config *metal3iov1alpha1.ProvisioningSpec) map[string]string {
+	if !IsRestrictedPodSecurity(config) {
+		return nil
+	}
+	return podSecurityNamespaceLabels
+}
+
+func newRestrictedSecurityContext(container string) *corev1.SecurityContext {
+	return &corev1.SecurityContext{
+		Privileged:               pointer.BoolPtr(false),
+		AllowPrivilegeEscalation: pointer.BoolPtr(false),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
+			Add:  restrictedCapabilities[container],
+		},
+	}
+}
+
+// applyPodSecurityMode replaces the security context of the containers of
+// a pod template with the restricted one in the Restricted mode
+func applyPodSecurityMode(template *corev1.PodTemplateSpec, config *metal3iov1alpha1.ProvisioningSpec) {
+	if !IsRestrictedPodSecurity(config) {
+		return
+	}
+	if template.Spec.SecurityContext == nil {
+		template.Spec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	template.Spec.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	for _, containers := range [][]corev1.Container{template.Spec.InitContainers, template.Spec.Containers} {
+		for i := range containers {
+			containers[i].SecurityContext = newRestrictedSecurityContext(containers[i].Name)
+		}
+	}
+}
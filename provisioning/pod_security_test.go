@@ -0,0 +1,55 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestPodSecurityMode(t *testing.T) {
+	spec := managedProvisioning()
+	podSpec := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.Nil(t, podSpec.SecurityContext.SeccompProfile)
+	assert.True(t, *findContainer(podSpec.Containers, "metal3-ironic-conductor").SecurityContext.Privileged)
+	assert.Nil(t, GetPodSecurityNamespaceLabels(spec))
+
+	spec.PodSecurityMode = metal3iov1alpha1.PodSecurityModeRestricted
+	spec.ConductorGroups = []metal3iov1alpha1.ConductorGroup{{Name: "rack1"}}
+	templates := map[string]corev1.PodSpec{
+		"metal3":         NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec,
+		"dnsmasq":        NewDnsmasqDaemonSet(testNamespace, &testImages, spec).Spec.Template.Spec,
+		"conductorGroup": NewConductorGroupDeployment(testNamespace, &testImages, spec, &spec.ConductorGroups[0]).Spec.Template.Spec,
+	}
+	for name, podSpec := range templates {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, corev1.SeccompProfileTypeRuntimeDefault, podSpec.SecurityContext.SeccompProfile.Type)
+			for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+				context := container.SecurityContext
+				if !assert.NotNil(t, context, container.Name) {
+					continue
+				}
+				assert.False(t, *context.Privileged, container.Name)
+				assert.False(t, *context.AllowPrivilegeEscalation, container.Name)
+				assert.Equal(t, []corev1.Capability{"ALL"}, context.Capabilities.Drop, container.Name)
+			}
+		})
+	}
+
+	capabilities := func(podSpec corev1.PodSpec, name string) []corev1.Capability {
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			if container.Name == name {
+				return container.SecurityContext.Capabilities.Add
+			}
+		}
+		return nil
+	}
+	assert.Empty(t, capabilities(templates["metal3"], "metal3-ironic-conductor"))
+	assert.Equal(t, []corev1.Capability{"NET_ADMIN", "NET_RAW"}, capabilities(templates["metal3"], StaticIPManagerContainerName))
+	assert.Equal(t, []corev1.Capability{"NET_ADMIN", "NET_RAW"}, capabilities(templates["metal3"], "metal3-static-ip-set"))
+	assert.Equal(t, []corev1.Capability{"SETUID", "SETGID"}, capabilities(templates["metal3"], "metal3-httpd"))
+	assert.Equal(t, []corev1.Capability{"NET_ADMIN", "NET_RAW", "NET_BIND_SERVICE"}, capabilities(templates["dnsmasq"], "metal3-dnsmasq"))
+	assert.Equal(t, "restricted", GetPodSecurityNamespaceLabels(spec)["pod-security.kubernetes.io/audit"])
+}
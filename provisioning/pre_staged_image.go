@@ -0,0 +1,65 @@
+package provisioning
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const preStagedImageVolume = "metal3-pre-staged-image"
+
+// newPreStagedImageVolume returns the volume holding the pre-staged
+// provisioning OS image
+func newPreStagedImageVolume(claimName string) corev1.Volume {
+	return corev1.Volume{
+		Name: preStagedImageVolume,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: claimName,
+				ReadOnly:  true,
+			},
+		},
+	}
+}
+
+// newPreStagedImageMount mounts the pre-staged image in the directory the
+// machine-os-downloader would have cached it in, so that it is served
+// from the same URL
+func newPreStagedImageMount(config *metal3iov1alpha1.ProvisioningSpec) corev1.VolumeMount {
+	return corev1.VolumeMount{
+		Name:      preStagedImageVolume,
+		MountPath: path.Join(imageServerRoot, path.Dir(getCachedOSImageSubPath(config))),
+		ReadOnly:  true,
+	}
+}
+
+// validatePreStagedImage checks that the pre-staged image can be served
+// as it is
+func validatePreStagedImage(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if config.PreStagedImagePVC == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(config.PreStagedImagePVC); len(errs) > 0 {
+		return fmt.Errorf("invalid PreStagedImagePVC %q: %s", config.PreStagedImagePVC, strings.Join(errs, ", "))
+	}
+	// The name of the image is taken from the download URL
+	if getCachedOSImageSubPath(config) == "" {
+		return fmt.Errorf("PreStagedImagePVC requires a ProvisioningOSDownloadURL naming the image")
+	}
+	switch {
+	case config.ImageCache != nil:
+		return fmt.Errorf("PreStagedImagePVC cannot be combined with ImageCache")
+	case config.OSImageSignatureRef != nil:
+		return fmt.Errorf("PreStagedImagePVC cannot be combined with OSImageSignatureRef, the pre-staged image is never verified")
+	case config.ConvertOSImageToRaw:
+		return fmt.Errorf("PreStagedImagePVC cannot be combined with ConvertOSImageToRaw, the pre-staged image is mounted read-only")
+	case config.AutoUpdateOSImage:
+		return fmt.Errorf("PreStagedImagePVC cannot be combined with AutoUpdateOSImage, the pre-staged image is never replaced")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidatePreStagedImage(t *testing.T) {
+	tCases := []struct {
+		name        string
+		update      func(*metal3iov1alpha1.ProvisioningSpec)
+		expectedErr string
+	}{
+		{
+			name:   "NotSet",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) {},
+		},
+		{
+			name:   "Valid",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.PreStagedImagePVC = "rhcos" },
+		},
+		{
+			name:        "InvalidName",
+			update:      func(spec *metal3iov1alpha1.ProvisioningSpec) { spec.PreStagedImagePVC = "RHCOS_Image" },
+			expectedErr: `invalid PreStagedImagePVC "RHCOS_Image"`,
+		},
+		{
+			name: "NoImageName",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.PreStagedImagePVC = "rhcos"
+				spec.ProvisioningOSDownloadURL = ""
+			},
+			expectedErr: "PreStagedImagePVC requires a ProvisioningOSDownloadURL naming the image",
+		},
+		{
+			name: "ImageCache",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.PreStagedImagePVC = "rhcos"
+				spec.ImageCache = &metal3iov1alpha1.ImageCache{}
+			},
+			expectedErr: "PreStagedImagePVC cannot be combined with ImageCache",
+		},
+		{
+			name: "ConvertToRaw",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.PreStagedImagePVC = "rhcos"
+				spec.ConvertOSImageToRaw = true
+			},
+			expectedErr: "PreStagedImagePVC cannot be combined with ConvertOSImageToRaw, the pre-staged image is mounted read-only",
+		},
+		{
+			name: "AutoUpdate",
+			update: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.PreStagedImagePVC = "rhcos"
+				spec.AutoUpdateOSImage = true
+			},
+			expectedErr: "PreStagedImagePVC cannot be combined with AutoUpdateOSImage, the pre-staged image is never replaced",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			tc.update(spec)
+			err := validatePreStagedImage(spec)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestPreStagedImage(t *testing.T) {
+	spec := managedProvisioning()
+	spec.PreStagedImagePVC = "rhcos"
+	podSpec := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+
+	assert.Nil(t, findContainer(podSpec.InitContainers, "metal3-machine-os-downloader"))
+	assert.Nil(t, findContainer(podSpec.Containers, "metal3-machine-os-downloader"))
+	assert.NotNil(t, findContainer(podSpec.Containers, "metal3-ipa-downloader"))
+
+	volume := podSpec.Volumes[len(podSpec.Volumes)-1]
+	assert.Equal(t, preStagedImageVolume, volume.Name)
+	assert.Equal(t, &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "rhcos", ReadOnly: true}, volume.PersistentVolumeClaim)
+
+	httpd := findContainer(podSpec.Containers, "metal3-httpd")
+	assert.Contains(t, httpd.VolumeMounts, corev1.VolumeMount{
+		Name:      preStagedImageVolume,
+		MountPath: "/shared/html/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2",
+		ReadOnly:  true,
+	})
+	assert.Equal(t, "http://172.30.20.3:6180/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2",
+		GetImageServerStatus(spec).HTTP.OSImage)
+}
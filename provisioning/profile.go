@@ -0,0 +1,59 @@
+package provisioning
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const inspectorContainerName = "metal3-ironic-inspector"
+
+// minimalMemoryLimits are the memory limits of the containers run by the
+// Minimal profile, sized for managing a handful of hosts
+var minimalMemoryLimits = map[string]resource.Quantity{
+	"metal3-baremetal-operator": resource.MustParse("128Mi"),
+	"metal3-httpd":              resource.MustParse("128Mi"),
+	"metal3-ironic-conductor":   resource.MustParse("512Mi"),
+	"metal3-ironic-api":         resource.MustParse("256Mi"),
+	"metal3-static-ip-manager":  resource.MustParse("32Mi"),
+}
+
+// IsMinimalProfile returns true when only the essential metal3 services
+// must be run
+func IsMinimalProfile(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.Profile == metal3iov1alpha1.ProvisioningProfileMinimal
+}
+
+// applyMinimalProfile removes Ironic Inspector and MariaDB from the
+// metal3 containers, and limits the memory of the remaining ones.
+// Containers of optional services that were requested explicitly are
+// kept without limits.
+func applyMinimalProfile(containers []corev1.Container) []corev1.Container {
+	kept := []corev1.Container{}
+	for _, container := range useSQLiteDatabase(containers) {
+		if container.Name == inspectorContainerName {
+			continue
+		}
+		if limit, ok := minimalMemoryLimits[container.Name]; ok {
+			container.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: limit}
+		}
+		kept = append(kept, container)
+	}
+	return kept
+}
+
+func validateProfile(prov *metal3iov1alpha1.Provisioning) error {
+	if !IsMinimalProfile(&prov.Spec) {
+		return nil
+	}
+	// Without dnsmasq nothing would answer the DHCP requests of the hosts
+	if getProvisioningNetworkMode(prov) == metal3iov1alpha1.ProvisioningNetworkManaged {
+		return fmt.Errorf("Profile %s runs no DHCP server, and requires a ProvisioningNetwork of %s or %s",
+			metal3iov1alpha1.ProvisioningProfileMinimal,
+			metal3iov1alpha1.ProvisioningNetworkUnmanaged, metal3iov1alpha1.ProvisioningNetworkDisabled)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestMinimalProfile(t *testing.T) {
+	spec := managedProvisioning()
+	spec.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkUnmanaged
+	spec.ProvisioningDHCPRange = ""
+	spec.Profile = metal3iov1alpha1.ProvisioningProfileMinimal
+	assert.NoError(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec}))
+	assert.False(t, IsDnsmasqRequired(spec))
+
+	containers := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+	assert.Equal(t, []string{
+		"metal3-baremetal-operator",
+		"metal3-httpd",
+		"metal3-ironic-conductor",
+		"metal3-ironic-api",
+		"metal3-static-ip-manager",
+		"metal3-ipa-downloader",
+		"metal3-machine-os-downloader",
+	}, containerNames(containers))
+	assert.Equal(t, resource.MustParse("512Mi"), findContainer(containers, "metal3-ironic-conductor").Resources.Limits[corev1.ResourceMemory])
+	assert.Contains(t, findContainer(containers, "metal3-ironic-api").Env, corev1.EnvVar{Name: "IRONIC_USE_MARIADB", Value: "false"})
+
+	spec.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkManaged
+	assert.EqualError(t, ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec}),
+		"Profile Minimal runs no DHCP server, and requires a ProvisioningNetwork of Unmanaged or Disabled")
+}
+
+func TestDefaultProfile(t *testing.T) {
+	for _, profile := range []metal3iov1alpha1.ProvisioningProfile{"", metal3iov1alpha1.ProvisioningProfileDefault} {
+		spec := managedProvisioning()
+		spec.Profile = profile
+		containers := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers
+		assert.Contains(t, containerNames(containers), "metal3-mariadb")
+		assert.Contains(t, containerNames(containers), "metal3-ironic-inspector")
+		for _, container := range containers {
+			assert.Empty(t, container.Resources.Limits, "limits of %s", container.Name)
+		}
+	}
+}
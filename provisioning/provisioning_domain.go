@@ -0,0 +1,278 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ProvisioningDomainLabel holds the name of the Provisioning instance
+	// owning the pods of an additional metal3 stack
+	ProvisioningDomainLabel = "baremetal.openshift.io/provisioning-domain"
+	hostLabelSelectorEnvVar = "HOST_LABEL_SELECTOR"
+	hostnameTopologyKey     = "kubernetes.io/hostname"
+)
+
+// getHostLabelSelector returns the HostSelector in its string form, or nil
+// when all hosts are managed
+func getHostLabelSelector(config *metal3iov1alpha1.ProvisioningSpec) (*string, error) {
+	if config.HostSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(config.HostSelector)
+	if err != nil {
+		return nil, err
+	}
+	s := selector.String()
+	return &s, nil
+}
+
+// provisioningDomainName returns the name of the metal3 resources of the
+// given provisioning domain
+func provisioningDomainName(base string, domain string) string {
+	return base + "-" + domain
+}
+
+// relabelForProvisioningDomain gives the pods of a provisioning domain
+// their own controller label, so that the selectors of the main stack
+// never match them, and keeps them away from the nodes running another
+// metal3 stack since they all bind the same host ports.
+func relabelForProvisioningDomain(template *corev1.PodTemplateSpec, selector *metav1.LabelSelector, domain string) {
+	name := provisioningDomainName(metal3AppName, domain)
+	template.Labels["controller"] = name
+	template.Labels[ProvisioningDomainLabel] = domain
+	selector.MatchLabels = template.Labels
+
+	template.Spec.Affinity = &corev1.Affinity{
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"k8s-app": template.Labels["k8s-app"]},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{
+								Key:      "controller",
+								Operator: metav1.LabelSelectorOpNotIn,
+								Values:   []string{name},
+							},
+						},
+					},
+					TopologyKey: hostnameTopologyKey,
+				},
+			},
+		},
+	}
+}
+
+// NewProvisioningDomainDeployment returns the Deployment running the
+// metal3 pod of an additional Provisioning instance
+func NewProvisioningDomainDeployment(targetNamespace string, domain string, images *Images, config *metal3iov1alpha1.ProvisioningSpec) *appsv1.Deployment {
+	deployment := NewMetal3Deployment(targetNamespace, images, config)
+	deployment.Name = provisioningDomainName(baremetalDeploymentName, domain)
+	deployment.Labels[ProvisioningDomainLabel] = domain
+	relabelForProvisioningDomain(&deployment.Spec.Template, deployment.Spec.Selector, domain)
+	return deployment
+}
+
+// NewProvisioningDomainDaemonSet returns the DaemonSet running the DHCP
+// and TFTP server of an additional Provisioning instance
+func NewProvisioningDomainDaemonSet(targetNamespace string, domain string, images *Images, config *metal3iov1alpha1.ProvisioningSpec) *appsv1.DaemonSet {
+	daemonSet := NewDnsmasqDaemonSet(targetNamespace, images, config)
+	daemonSet.Name = provisioningDomainName(dnsmasqDaemonSetName, domain)
+	daemonSet.Labels[ProvisioningDomainLabel] = domain
+	// Every dnsmasq runs on each node, serving its own interface, so
+	// there is nothing to keep apart
+	template := &daemonSet.Spec.Template
+	template.Labels["controller"] = provisioningDomainName(metal3AppName, domain)
+	template.Labels[ProvisioningDomainLabel] = domain
+	daemonSet.Spec.Selector.MatchLabels = template.Labels
+	return daemonSet
+}
+
+// DeleteProvisioningDomainDaemonSet removes the dnsmasq DaemonSet of a
+// provisioning domain, if it exists
+func DeleteProvisioningDomainDaemonSet(client appsclientv1.DaemonSetsGetter, targetNamespace string, domain string) error {
+	err := client.DaemonSets(targetNamespace).Delete(context.Background(),
+		provisioningDomainName(dnsmasqDaemonSetName, domain), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// DeleteProvisioningDomain removes the metal3 stack of a provisioning
+// domain, if it exists
+func DeleteProvisioningDomain(client appsclientv1.AppsV1Interface, targetNamespace string, domain string) error {
+	err := client.Deployments(targetNamespace).Delete(context.Background(),
+		provisioningDomainName(baremetalDeploymentName, domain), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return DeleteProvisioningDomainDaemonSet(client, targetNamespace, domain)
+}
+
+// cidrsOverlap returns true when the two networks share addresses. Invalid
+// networks are reported by the validation of each instance.
+func cidrsOverlap(a string, b string) bool {
+	_, netA, errA := net.ParseCIDR(a)
+	_, netB, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return netA.Contains(netB.IP) || netB.Contains(netA.IP)
+}
+
+// selectorConstraint is what a label selector requires of one label
+type selectorConstraint struct {
+	// in holds the values the label may have, or is nil when any value
+	// is allowed
+	in        map[string]bool
+	notIn     map[string]bool
+	exists    bool
+	notExists bool
+}
+
+// getSelectorConstraints returns the constraints of a label selector on
+// each of the labels it reads
+func getSelectorConstraints(selector *metav1.LabelSelector) map[string]*selectorConstraint {
+	constraints := map[string]*selectorConstraint{}
+	constraint := func(key string) *selectorConstraint {
+		if constraints[key] == nil {
+			constraints[key] = &selectorConstraint{notIn: map[string]bool{}}
+		}
+		return constraints[key]
+	}
+	restrict := func(c *selectorConstraint, values []string) {
+		allowed := map[string]bool{}
+		for _, value := range values {
+			if c.in == nil || c.in[value] {
+				allowed[value] = true
+			}
+		}
+		c.in = allowed
+	}
+	for key, value := range selector.MatchLabels {
+		restrict(constraint(key), []string{value})
+	}
+	for _, requirement := range selector.MatchExpressions {
+		c := constraint(requirement.Key)
+		switch requirement.Operator {
+		case metav1.LabelSelectorOpIn:
+			restrict(c, requirement.Values)
+		case metav1.LabelSelectorOpNotIn:
+			for _, value := range requirement.Values {
+				c.notIn[value] = true
+			}
+		case metav1.LabelSelectorOpExists:
+			c.exists = true
+		case metav1.LabelSelectorOpDoesNotExist:
+			c.notExists = true
+		}
+	}
+	return constraints
+}
+
+// constraintsDisjoint returns true when no value of a label satisfies both
+// constraints
+func constraintsDisjoint(a, b *selectorConstraint) bool {
+	aExists := a.exists || a.in != nil
+	bExists := b.exists || b.in != nil
+	if (a.notExists && bExists) || (b.notExists && aExists) {
+		return true
+	}
+	excluded := func(in map[string]bool, notIn map[string]bool, other map[string]bool) bool {
+		for value := range in {
+			if !notIn[value] && (other == nil || other[value]) {
+				return false
+			}
+		}
+		return true
+	}
+	if a.in != nil && excluded(a.in, b.notIn, b.in) {
+		return true
+	}
+	return b.in != nil && excluded(b.in, a.notIn, a.in)
+}
+
+// selectorsDisjoint returns true when the two label selectors provably
+// never select the same object, through a label they constrain to
+// different values or one requires while the other excludes it. A nil
+// selector selects everything.
+func selectorsDisjoint(a, b *metav1.LabelSelector) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	constraintsA, constraintsB := getSelectorConstraints(a), getSelectorConstraints(b)
+	for key, constraint := range constraintsA {
+		if other, ok := constraintsB[key]; ok && constraintsDisjoint(constraint, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateProvisioningDomain checks the configuration of an additional
+// Provisioning instance against the main one, and against the other
+// additional instances created before it. The errors returned match
+// ErrInvalidSpec.
+func ValidateProvisioningDomain(domain *metal3iov1alpha1.Provisioning, main *metal3iov1alpha1.Provisioning, others []metal3iov1alpha1.Provisioning) error {
+	if err := validateBaremetalProvisioningConfig(domain); err != nil {
+		return NewInvalidSpecError(err)
+	}
+	return NewInvalidSpecError(validateProvisioningDomain(domain, main, others))
+}
+
+// createdBefore orders the Provisioning instances by creation, so that
+// the newest of two conflicting instances is the one rejected
+func createdBefore(a, b *metav1.ObjectMeta) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+func validateProvisioningDomain(domain *metal3iov1alpha1.Provisioning, main *metal3iov1alpha1.Provisioning, others []metal3iov1alpha1.Provisioning) error {
+	if domain.Spec.HostSelector == nil {
+		return fmt.Errorf("HostSelector is required on additional Provisioning instances")
+	}
+	// Each host has to be managed by a single metal3 stack, which is only
+	// certain when the selectors exclude each other
+	if !selectorsDisjoint(domain.Spec.HostSelector, main.Spec.HostSelector) {
+		return fmt.Errorf("HostSelector may select the same hosts as the HostSelector of Provisioning %s, which has to exclude the hosts of the additional instances",
+			main.Name)
+	}
+	for i := range others {
+		other := &others[i]
+		if other.Name == domain.Name || other.Name == main.Name || !createdBefore(&other.ObjectMeta, &domain.ObjectMeta) {
+			continue
+		}
+		if !selectorsDisjoint(domain.Spec.HostSelector, other.Spec.HostSelector) {
+			return fmt.Errorf("HostSelector may select the same hosts as the HostSelector of Provisioning %s", other.Name)
+		}
+	}
+	if IsAutoProvisioningNetwork(&domain.Spec) {
+		return fmt.Errorf("ProvisioningNetwork %s is not supported on additional Provisioning instances", metal3iov1alpha1.ProvisioningNetworkAuto)
+	}
+	if domain.Spec.ImageServerHTTPS || domain.Spec.IronicAPIExposure != nil || domain.Spec.HighAvailability != nil ||
+		domain.Spec.EnableIgnitionOverrides {
+		return fmt.Errorf("ImageServerHTTPS, IronicAPIExposure, HighAvailability and EnableIgnitionOverrides are not supported on additional Provisioning instances")
+	}
+	if domain.Spec.ProvisioningInterface != "" && domain.Spec.ProvisioningInterface == main.Spec.ProvisioningInterface {
+		return fmt.Errorf("ProvisioningInterface %s is already used by Provisioning %s",
+			domain.Spec.ProvisioningInterface, main.Name)
+	}
+	if cidrsOverlap(domain.Spec.ProvisioningNetworkCIDR, main.Spec.ProvisioningNetworkCIDR) {
+		return fmt.Errorf("ProvisioningNetworkCIDR %s overlaps with the network of Provisioning %s",
+			domain.Spec.ProvisioningNetworkCIDR, main.Name)
+	}
+	return nil
+}
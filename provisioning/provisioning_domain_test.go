@@ -0,0 +1,232 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func domainProvisioning() *metal3iov1alpha1.ProvisioningSpec {
+	config := managedProvisioning()
+	config.ProvisioningInterface = "eth1"
+	config.ProvisioningIP = "172.30.21.3"
+	config.ProvisioningNetworkCIDR = "172.30.21.0/24"
+	config.ProvisioningDHCPRange = "172.30.21.11, 172.30.21.101"
+	config.HostSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "1"}}
+	return config
+}
+
+func TestNewProvisioningDomainDeployment(t *testing.T) {
+	main := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+	domain := NewProvisioningDomainDeployment(testNamespace, "rack-1", &testImages, domainProvisioning())
+
+	assert.Equal(t, "metal3-rack-1", domain.Name)
+	assert.Equal(t, domain.Spec.Selector.MatchLabels, domain.Spec.Template.Labels)
+	assert.Equal(t, "rack-1", domain.Spec.Template.Labels[ProvisioningDomainLabel])
+
+	// Neither stack may select the pods of the other
+	mainSelector := labels.SelectorFromSet(main.Spec.Selector.MatchLabels)
+	domainSelector := labels.SelectorFromSet(domain.Spec.Selector.MatchLabels)
+	assert.False(t, mainSelector.Matches(labels.Set(domain.Spec.Template.Labels)))
+	assert.False(t, domainSelector.Matches(labels.Set(main.Spec.Template.Labels)))
+
+	// The domain pods avoid any node running another metal3 stack
+	terms := domain.Spec.Template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if assert.Len(t, terms, 1) {
+		antiAffinity, err := metav1.LabelSelectorAsSelector(terms[0].LabelSelector)
+		assert.NoError(t, err)
+		assert.True(t, antiAffinity.Matches(labels.Set(main.Spec.Template.Labels)))
+		assert.False(t, antiAffinity.Matches(labels.Set(domain.Spec.Template.Labels)))
+	}
+
+	bmo := findContainer(domain.Spec.Template.Spec.Containers, "metal3-baremetal-operator")
+	assert.Equal(t, "rack=1", envValue(bmo, hostLabelSelectorEnvVar))
+	assert.Equal(t, "", envValue(findContainer(main.Spec.Template.Spec.Containers, "metal3-baremetal-operator"), hostLabelSelectorEnvVar))
+}
+
+func TestNewProvisioningDomainDaemonSet(t *testing.T) {
+	main := NewDnsmasqDaemonSet(testNamespace, &testImages, managedProvisioning())
+	domain := NewProvisioningDomainDaemonSet(testNamespace, "rack-1", &testImages, domainProvisioning())
+
+	assert.Equal(t, "metal3-dnsmasq-rack-1", domain.Name)
+	assert.Equal(t, domain.Spec.Selector.MatchLabels, domain.Spec.Template.Labels)
+	assert.False(t, labels.SelectorFromSet(main.Spec.Selector.MatchLabels).Matches(labels.Set(domain.Spec.Template.Labels)))
+
+	dnsmasq := findContainer(domain.Spec.Template.Spec.Containers, "metal3-dnsmasq")
+	assert.Equal(t, "eth1", envValue(dnsmasq, provisioningInterface))
+}
+
+func TestDeleteProvisioningDomain(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset(
+		NewProvisioningDomainDeployment(testNamespace, "rack-1", &testImages, domainProvisioning()),
+		NewProvisioningDomainDaemonSet(testNamespace, "rack-1", &testImages, domainProvisioning()),
+		NewMetal3Deployment(testNamespace, &testImages, managedProvisioning()),
+	)
+
+	assert.NoError(t, DeleteProvisioningDomain(kubeClient.AppsV1(), testNamespace, "rack-1"))
+	assert.NoError(t, DeleteProvisioningDomain(kubeClient.AppsV1(), testNamespace, "rack-1"))
+
+	deployments, err := kubeClient.AppsV1().Deployments(testNamespace).List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, deployments.Items, 1) {
+		assert.Equal(t, baremetalDeploymentName, deployments.Items[0].Name)
+	}
+	daemonSets, err := kubeClient.AppsV1().DaemonSets(testNamespace).List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, daemonSets.Items)
+}
+
+func TestValidateProvisioningDomain(t *testing.T) {
+	main := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: "provisioning-configuration"},
+		Spec:       *managedProvisioning(),
+	}
+	main.Spec.HostSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "rack", Operator: metav1.LabelSelectorOpDoesNotExist},
+	}}
+	older := metav1.NewTime(time.Unix(1000, 0))
+	newer := metav1.NewTime(time.Unix(2000, 0))
+	otherDomain := func(name string, created metav1.Time, rack string) metal3iov1alpha1.Provisioning {
+		return metal3iov1alpha1.Provisioning{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created},
+			Spec: metal3iov1alpha1.ProvisioningSpec{
+				HostSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": rack}},
+			},
+		}
+	}
+
+	tCases := []struct {
+		name          string
+		modify        func(*metal3iov1alpha1.ProvisioningSpec)
+		mainSelector  *metav1.LabelSelector
+		others        []metal3iov1alpha1.Provisioning
+		expectedError bool
+	}{
+		{
+			name:   "Valid",
+			modify: func(*metal3iov1alpha1.ProvisioningSpec) {},
+		},
+		{
+			name:          "MissingHostSelector",
+			modify:        func(s *metal3iov1alpha1.ProvisioningSpec) { s.HostSelector = nil },
+			expectedError: true,
+		},
+		{
+			name:          "SameInterface",
+			modify:        func(s *metal3iov1alpha1.ProvisioningSpec) { s.ProvisioningInterface = "eth0" },
+			expectedError: true,
+		},
+		{
+			name: "OverlappingNetwork",
+			modify: func(s *metal3iov1alpha1.ProvisioningSpec) {
+				s.ProvisioningIP = "172.30.20.130"
+				s.ProvisioningNetworkCIDR = "172.30.20.128/25"
+				s.ProvisioningDHCPRange = "172.30.20.140, 172.30.20.150"
+			},
+			expectedError: true,
+		},
+		{
+			name:          "ImageServerHTTPS",
+			modify:        func(s *metal3iov1alpha1.ProvisioningSpec) { s.ImageServerHTTPS = true },
+			expectedError: true,
+		},
+		{
+			name: "InvalidHostSelector",
+			modify: func(s *metal3iov1alpha1.ProvisioningSpec) {
+				s.HostSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: "Bogus"},
+				}}
+			},
+			expectedError: true,
+		},
+		{
+			name:          "MainSelectsAllHosts",
+			modify:        func(*metal3iov1alpha1.ProvisioningSpec) {},
+			mainSelector:  &metav1.LabelSelector{},
+			expectedError: true,
+		},
+		{
+			name:   "MainExcludesRack",
+			modify: func(*metal3iov1alpha1.ProvisioningSpec) {},
+			mainSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "rack", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"1", "2"}},
+			}},
+		},
+		{
+			name:   "DisjointDomains",
+			modify: func(*metal3iov1alpha1.ProvisioningSpec) {},
+			others: []metal3iov1alpha1.Provisioning{*main, otherDomain("rack-2", older, "2")},
+		},
+		{
+			name:          "OverlappingOlderDomain",
+			modify:        func(*metal3iov1alpha1.ProvisioningSpec) {},
+			others:        []metal3iov1alpha1.Provisioning{otherDomain("rack-1-bis", older, "1")},
+			expectedError: true,
+		},
+		{
+			name:   "OverlappingNewerDomain",
+			modify: func(*metal3iov1alpha1.ProvisioningSpec) {},
+			others: []metal3iov1alpha1.Provisioning{otherDomain("rack-1-bis", newer, "1")},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			domain := &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: "rack-1", CreationTimestamp: metav1.NewTime(time.Unix(1500, 0))},
+				Spec:       *domainProvisioning(),
+			}
+			tc.modify(&domain.Spec)
+			mainCopy := main.DeepCopy()
+			if tc.mainSelector != nil {
+				mainCopy.Spec.HostSelector = tc.mainSelector
+			}
+			err := ValidateProvisioningDomain(domain, mainCopy, append([]metal3iov1alpha1.Provisioning{*domain}, tc.others...))
+			if tc.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSelectorsDisjoint(t *testing.T) {
+	rack := func(operator metav1.LabelSelectorOperator, values ...string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "rack", Operator: operator, Values: values},
+		}}
+	}
+	tCases := []struct {
+		name     string
+		a, b     *metav1.LabelSelector
+		disjoint bool
+	}{
+		{name: "Nil", a: nil, b: rack(metav1.LabelSelectorOpIn, "1")},
+		{name: "Empty", a: &metav1.LabelSelector{}, b: rack(metav1.LabelSelectorOpIn, "1")},
+		{name: "SameValue", a: rack(metav1.LabelSelectorOpIn, "1", "2"), b: rack(metav1.LabelSelectorOpIn, "2")},
+		{name: "OtherValue", a: rack(metav1.LabelSelectorOpIn, "1"), b: rack(metav1.LabelSelectorOpIn, "2", "3"), disjoint: true},
+		{name: "MatchLabels", a: &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "1"}}, b: rack(metav1.LabelSelectorOpIn, "2"), disjoint: true},
+		{name: "Excluded", a: rack(metav1.LabelSelectorOpNotIn, "1", "2"), b: rack(metav1.LabelSelectorOpIn, "2", "1"), disjoint: true},
+		{name: "PartlyExcluded", a: rack(metav1.LabelSelectorOpNotIn, "1"), b: rack(metav1.LabelSelectorOpIn, "1", "2")},
+		{name: "DoesNotExist", a: rack(metav1.LabelSelectorOpDoesNotExist), b: rack(metav1.LabelSelectorOpExists), disjoint: true},
+		{name: "NotInAndDoesNotExist", a: rack(metav1.LabelSelectorOpDoesNotExist), b: rack(metav1.LabelSelectorOpNotIn, "1")},
+		{
+			name: "OtherLabels",
+			a:    &metav1.LabelSelector{MatchLabels: map[string]string{"rack": "1"}},
+			b:    &metav1.LabelSelector{MatchLabels: map[string]string{"row": "1"}},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.disjoint, selectorsDisjoint(tc.a, tc.b))
+			assert.Equal(t, tc.disjoint, selectorsDisjoint(tc.b, tc.a))
+		})
+	}
+}
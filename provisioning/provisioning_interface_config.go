@@ -0,0 +1,114 @@
+package provisioning
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ProvisioningInterfaceMachineConfigName is the name of the
+	// MachineConfig configuring the provisioning interface. The 99 prefix
+	// orders it after the MachineConfigs of the installer.
+	ProvisioningInterfaceMachineConfigName = "99-master-metal3-provisioning-interface"
+	machineConfigRoleLabel                 = "machineconfiguration.openshift.io/role"
+	// provisioningConnectionPath is the NetworkManager keyfile of the
+	// provisioning interface
+	provisioningConnectionPath = "/etc/NetworkManager/system-connections/metal3-provisioning.nmconnection"
+	// NetworkManager ignores keyfiles readable by others than root
+	provisioningConnectionMode = 0600
+)
+
+// getProvisioningInterfaceConfig returns how the provisioning interface is
+// configured
+func getProvisioningInterfaceConfig(config *metal3iov1alpha1.ProvisioningSpec) metal3iov1alpha1.ProvisioningInterfaceConfig {
+	if config.ProvisioningInterfaceConfig == "" {
+		return metal3iov1alpha1.ProvisioningInterfaceConfigPod
+	}
+	return config.ProvisioningInterfaceConfig
+}
+
+// IsProvisioningInterfaceMachineConfig returns true when the provisioning
+// interface is configured through a MachineConfig
+func IsProvisioningInterfaceMachineConfig(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return getProvisioningInterfaceConfig(config) == metal3iov1alpha1.ProvisioningInterfaceConfigMachineConfig
+}
+
+// validateProvisioningInterfaceConfig checks that the MachineConfig
+// reaches the nodes the metal3 pods run on, and that there is an
+// interface to configure
+func validateProvisioningInterfaceConfig(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	if !IsProvisioningInterfaceMachineConfig(config) {
+		return nil
+	}
+	if mode == metal3iov1alpha1.ProvisioningNetworkDisabled {
+		return fmt.Errorf("ProvisioningInterfaceConfig MachineConfig cannot be used when the provisioning network is Disabled")
+	}
+	if !IsControlPlaneOnly(config) {
+		return fmt.Errorf("ProvisioningInterfaceConfig MachineConfig requires ControlPlaneOnly, the MachineConfig only applies to the control plane nodes")
+	}
+	return nil
+}
+
+// newProvisioningConnection returns the NetworkManager keyfile keeping the
+// provisioning interface up, without any address but the IPv6 link-local
+// one. The ProvisioningIP is still added by the metal3 pod, as it must
+// only be on the node the pod runs on.
+func newProvisioningConnection(config *metal3iov1alpha1.ProvisioningSpec) string {
+	return strings.Join([]string{
+		"[connection]",
+		"id=metal3-provisioning",
+		"type=ethernet",
+		"interface-name=" + config.ProvisioningInterface,
+		"autoconnect=true",
+		"autoconnect-priority=100",
+		"",
+		"[ipv4]",
+		"method=disabled",
+		"",
+		"[ipv6]",
+		"method=link-local",
+		"addr-gen-mode=eui64",
+		"",
+	}, "\n")
+}
+
+// NewProvisioningInterfaceMachineConfig returns the MachineConfig writing
+// the NetworkManager profile of the provisioning interface on the control
+// plane nodes. Changes to it are rolled out by the machine-config-operator,
+// which reboots the nodes one at a time.
+func NewProvisioningInterfaceMachineConfig(config *metal3iov1alpha1.ProvisioningSpec) *unstructured.Unstructured {
+	machineConfig := &unstructured.Unstructured{}
+	machineConfig.SetAPIVersion("machineconfiguration.openshift.io/v1")
+	machineConfig.SetKind("MachineConfig")
+	machineConfig.SetName(ProvisioningInterfaceMachineConfigName)
+	machineConfig.SetLabels(map[string]string{
+		machineConfigRoleLabel: "master",
+		"k8s-app":              metal3AppName,
+	})
+	contents := base64.StdEncoding.EncodeToString([]byte(newProvisioningConnection(config)))
+	machineConfig.Object["spec"] = map[string]interface{}{
+		"config": map[string]interface{}{
+			"ignition": map[string]interface{}{
+				"version": "3.2.0",
+			},
+			"storage": map[string]interface{}{
+				"files": []interface{}{
+					map[string]interface{}{
+						"path":      provisioningConnectionPath,
+						"mode":      int64(provisioningConnectionMode),
+						"overwrite": true,
+						"contents": map[string]interface{}{
+							"source": "data:text/plain;charset=utf-8;base64," + contents,
+						},
+					},
+				},
+			},
+		},
+	}
+	return machineConfig
+}
@@ -0,0 +1,93 @@
+package provisioning
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateProvisioningInterfaceConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        metal3iov1alpha1.ProvisioningInterfaceConfig
+		mode          metal3iov1alpha1.ProvisioningNetwork
+		workers       bool
+		expectedError string
+	}{
+		{
+			name: "Default",
+			mode: metal3iov1alpha1.ProvisioningNetworkDisabled,
+		},
+		{
+			name:   "MachineConfig",
+			config: metal3iov1alpha1.ProvisioningInterfaceConfigMachineConfig,
+			mode:   metal3iov1alpha1.ProvisioningNetworkManaged,
+		},
+		{
+			name:          "Disabled",
+			config:        metal3iov1alpha1.ProvisioningInterfaceConfigMachineConfig,
+			mode:          metal3iov1alpha1.ProvisioningNetworkDisabled,
+			expectedError: "ProvisioningInterfaceConfig MachineConfig cannot be used when the provisioning network is Disabled",
+		},
+		{
+			name:          "Workers",
+			config:        metal3iov1alpha1.ProvisioningInterfaceConfigMachineConfig,
+			mode:          metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			workers:       true,
+			expectedError: "ProvisioningInterfaceConfig MachineConfig requires ControlPlaneOnly, the MachineConfig only applies to the control plane nodes",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.ProvisioningInterfaceConfig = tc.config
+			if tc.workers {
+				spec.ControlPlaneOnly = pointer.BoolPtr(false)
+				spec.NodeSelector = map[string]string{"metal3": ""}
+			}
+			err := validateProvisioningInterfaceConfig(spec, tc.mode)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestNewProvisioningInterfaceMachineConfig(t *testing.T) {
+	spec := managedProvisioning()
+	assert.False(t, IsProvisioningInterfaceMachineConfig(spec))
+	spec.ProvisioningInterfaceConfig = metal3iov1alpha1.ProvisioningInterfaceConfigMachineConfig
+	assert.True(t, IsProvisioningInterfaceMachineConfig(spec))
+
+	machineConfig := NewProvisioningInterfaceMachineConfig(spec)
+	assert.Equal(t, "machineconfiguration.openshift.io/v1", machineConfig.GetAPIVersion())
+	assert.Equal(t, ProvisioningInterfaceMachineConfigName, machineConfig.GetName())
+	assert.Equal(t, "master", machineConfig.GetLabels()["machineconfiguration.openshift.io/role"])
+
+	files, _, _ := unstructured.NestedSlice(machineConfig.Object, "spec", "config", "storage", "files")
+	if !assert.Len(t, files, 1) {
+		return
+	}
+	file := files[0].(map[string]interface{})
+	assert.Equal(t, "/etc/NetworkManager/system-connections/metal3-provisioning.nmconnection", file["path"])
+	assert.Equal(t, int64(0600), file["mode"])
+	source, _, _ := unstructured.NestedString(file, "contents", "source")
+	prefix := "data:text/plain;charset=utf-8;base64,"
+	assert.True(t, strings.HasPrefix(source, prefix))
+	contents, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(source, prefix))
+	if assert.NoError(t, err) {
+		assert.Contains(t, string(contents), "interface-name=eth0\n")
+		assert.Contains(t, string(contents), "[ipv4]\nmethod=disabled\n")
+		assert.Contains(t, string(contents), "[ipv6]\nmethod=link-local\n")
+		// The ProvisioningIP follows the metal3 pod, it is not persisted
+		assert.NotContains(t, string(contents), "172.30.20.3")
+	}
+}
@@ -0,0 +1,34 @@
+package provisioning
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// normalizeProvisioningIP rewrites a ProvisioningIP given in CIDR
+// notation, such as 172.30.20.3/24, as the address alone. Its network
+// becomes the ProvisioningNetworkCIDR when that is not set, and must be
+// the ProvisioningNetworkCIDR otherwise. The address is then rendered with
+// the prefix length of the ProvisioningNetworkCIDR exactly as when it was
+// given alone.
+func normalizeProvisioningIP(config *metal3iov1alpha1.ProvisioningSpec) error {
+	if !strings.Contains(config.ProvisioningIP, "/") {
+		return nil
+	}
+	ip, network, err := net.ParseCIDR(config.ProvisioningIP)
+	if err != nil {
+		return fmt.Errorf("could not parse ProvisioningIP %q", config.ProvisioningIP)
+	}
+	if config.ProvisioningNetworkCIDR == "" {
+		config.ProvisioningNetworkCIDR = network.String()
+	} else if _, configured, err := net.ParseCIDR(config.ProvisioningNetworkCIDR); err != nil {
+		return fmt.Errorf("could not parse ProvisioningNetworkCIDR %q", config.ProvisioningNetworkCIDR)
+	} else if configured.String() != network.String() {
+		return fmt.Errorf("ProvisioningIP %q is not on the ProvisioningNetworkCIDR %q", config.ProvisioningIP, config.ProvisioningNetworkCIDR)
+	}
+	config.ProvisioningIP = ip.String()
+	return nil
+}
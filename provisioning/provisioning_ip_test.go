@@ -0,0 +1,89 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeProvisioningIP(t *testing.T) {
+	tests := []struct {
+		name          string
+		ip            string
+		cidr          string
+		expectedIP    string
+		expectedCIDR  string
+		expectedError string
+	}{
+		{
+			name:         "Address",
+			ip:           "172.30.20.3",
+			cidr:         "172.30.20.0/24",
+			expectedIP:   "172.30.20.3",
+			expectedCIDR: "172.30.20.0/24",
+		},
+		{
+			name:         "DerivedCIDR",
+			ip:           "172.30.20.3/24",
+			expectedIP:   "172.30.20.3",
+			expectedCIDR: "172.30.20.0/24",
+		},
+		{
+			name:         "MatchingCIDR",
+			ip:           "172.30.20.3/24",
+			cidr:         "172.30.20.0/24",
+			expectedIP:   "172.30.20.3",
+			expectedCIDR: "172.30.20.0/24",
+		},
+		{
+			name:         "IPv6",
+			ip:           "fd2e:6f44:5dd8:b856::3/64",
+			expectedIP:   "fd2e:6f44:5dd8:b856::3",
+			expectedCIDR: "fd2e:6f44:5dd8:b856::/64",
+		},
+		{
+			name:          "OtherPrefixLength",
+			ip:            "172.30.20.3/16",
+			cidr:          "172.30.20.0/24",
+			expectedError: `ProvisioningIP "172.30.20.3/16" is not on the ProvisioningNetworkCIDR "172.30.20.0/24"`,
+		},
+		{
+			name:          "OtherNetwork",
+			ip:            "172.30.21.3/24",
+			cidr:          "172.30.20.0/24",
+			expectedError: `ProvisioningIP "172.30.21.3/24" is not on the ProvisioningNetworkCIDR "172.30.20.0/24"`,
+		},
+		{
+			name:          "Invalid",
+			ip:            "172.30.20.3/33",
+			expectedError: `could not parse ProvisioningIP "172.30.20.3/33"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.ProvisioningIP = tc.ip
+			spec.ProvisioningNetworkCIDR = tc.cidr
+			err := normalizeProvisioningIP(spec)
+			if tc.expectedError != "" {
+				assert.EqualError(t, err, tc.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedIP, spec.ProvisioningIP)
+			assert.Equal(t, tc.expectedCIDR, spec.ProvisioningNetworkCIDR)
+		})
+	}
+}
+
+func TestNormalizedProvisioningIPConfig(t *testing.T) {
+	// The metal3 pod is rendered the same whichever way the address is given
+	spec := managedProvisioning()
+	spec.ProvisioningIP = "172.30.20.3/24"
+	spec.ProvisioningNetworkCIDR = ""
+	assert.NoError(t, normalizeProvisioningIP(spec))
+	for _, name := range []string{provisioningIP, ironicEndpoint, deployKernelUrl} {
+		assert.Equal(t, getMetal3DeploymentConfig(name, managedProvisioning()), getMetal3DeploymentConfig(name, spec), name)
+	}
+	assert.Equal(t, "172.30.20.3/24", *getMetal3DeploymentConfig(provisioningIP, spec))
+}
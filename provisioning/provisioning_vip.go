@@ -0,0 +1,269 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// ProvisioningVIPConfigMapName is the ConfigMap naming the node whose
+	// metal3 pod holds the ProvisioningIP
+	ProvisioningVIPConfigMapName = "metal3-provisioning-vip"
+
+	provisioningVIPVolume    = "metal3-provisioning-vip"
+	provisioningVIPMountPath = "/etc/metal3/provisioning-vip"
+	provisioningVIPNodeKey   = "node"
+
+	defaultGratuitousARPCount = 3
+
+	// provisioningVIPCheckInterval is how often the static IP managers
+	// check whether their node holds the ProvisioningIP
+	provisioningVIPCheckInterval = "5"
+)
+
+// provisioningVIPFunctions tell whether the node holds the ProvisioningIP
+// and announce it when it does. IPv6 has no gratuitous ARP, the
+// neighbours resolve the address again once their entry is stale.
+const provisioningVIPFunctions = `
+holds_provisioning_ip() {
+    local holder=""
+    if [ -f "$VIP_HOLDER_FILE" ]; then
+        read -r holder < "$VIP_HOLDER_FILE" || true
+    fi
+    [ "$holder" = "$NODE_NAME" ]
+}
+
+announce_provisioning_ip() {
+    case "$PROVISIONING_IP" in
+    *:*) ;;
+    *) arping -U -c "$GRATUITOUS_ARP_COUNT" -I "$PROVISIONING_INTERFACE" "${PROVISIONING_IP%/*}" || true ;;
+    esac
+}
+`
+
+// provisioningVIPSetScript only configures the ProvisioningIP on the node
+// holding it, and removes it from the other nodes when a previous metal3
+// pod configured it there
+const provisioningVIPSetScript = staticIPFunctions + provisioningVIPFunctions + `
+set -eu
+configure_node_ip
+if holds_provisioning_ip; then
+    record_address "$STATIC_IP_STATE_FILE" "$PROVISIONING_IP"
+    /set-static-ip
+    announce_provisioning_ip
+else
+    record_address "$STATIC_IP_STATE_FILE" ""
+fi
+`
+
+// provisioningVIPManagerScript follows the holder of the ProvisioningIP:
+// the node taking it over configures and announces it, then keeps its
+// lifetime refreshed, while the other nodes release it. It is released
+// as well when the pod stops, so that the next holder does not wait for
+// the address to expire.
+const provisioningVIPManagerScript = staticIPFunctions + provisioningVIPFunctions + `
+refresher=""
+release_provisioning_ip() {
+    if [ -n "$refresher" ]; then
+        kill "$refresher" || true
+        refresher=""
+    fi
+    record_address "$STATIC_IP_STATE_FILE" ""
+}
+trap 'release_provisioning_ip; exit 0' TERM
+
+while true; do
+    if holds_provisioning_ip; then
+        if ! ip -o address show dev "$PROVISIONING_INTERFACE" | grep -q " $PROVISIONING_IP "; then
+            echo "taking over provisioning IP $PROVISIONING_IP on $PROVISIONING_INTERFACE"
+            record_address "$STATIC_IP_STATE_FILE" "$PROVISIONING_IP"
+            /set-static-ip && announce_provisioning_ip || true
+        fi
+        if [ -z "$refresher" ] || ! kill -0 "$refresher" 2>/dev/null; then
+            /refresh-static-ip &
+            refresher=$!
+        fi
+    elif [ -n "$refresher" ] || [ -f "$STATIC_IP_STATE_FILE" ]; then
+        echo "releasing provisioning IP $PROVISIONING_IP, held by another node"
+        release_provisioning_ip
+    fi
+    configure_node_ip || true
+    sleep ${PROVISIONING_VIP_CHECK_INTERVAL} &
+    wait $!
+done
+`
+
+// provisioningVIPConfiguredCommand succeeds when the node does not hold
+// the ProvisioningIP, or has it configured on the provisioning interface
+var provisioningVIPConfiguredCommand = []string{"/bin/bash", "-c",
+	`[ "$(cat "$VIP_HOLDER_FILE" 2>/dev/null)" != "$NODE_NAME" ] || ip -o address show dev "$PROVISIONING_INTERFACE" | grep -q " $PROVISIONING_IP "`}
+
+func validateProvisioningVIP(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	if config.ProvisioningVIP == nil {
+		return nil
+	}
+	if config.HighAvailability == nil {
+		return fmt.Errorf("ProvisioningVIP requires HighAvailability")
+	}
+	if mode == metal3iov1alpha1.ProvisioningNetworkDisabled {
+		return fmt.Errorf("ProvisioningVIP cannot be used when the provisioning network is Disabled")
+	}
+	if config.ProvisioningIP == "" {
+		return fmt.Errorf("ProvisioningVIP requires a ProvisioningIP")
+	}
+	return nil
+}
+
+func getGratuitousARPCount(config *metal3iov1alpha1.ProvisioningSpec) int32 {
+	if config.ProvisioningVIP == nil || config.ProvisioningVIP.GratuitousARPCount == 0 {
+		return defaultGratuitousARPCount
+	}
+	return config.ProvisioningVIP.GratuitousARPCount
+}
+
+// SelectProvisioningVIPNode returns the node of the active metal3 pod,
+// which holds the ProvisioningIP. The current node is kept for as long as
+// a pod runs there, even failing or terminating, so that two pods never
+// run the metal3 services at once: another node is only elected once the
+// pod is gone, which on an unreachable node requires it to be fenced and
+// its pod force deleted. The oldest initialized pod then takes over, its
+// images being downloaded.
+func SelectProvisioningVIPNode(pods []corev1.Pod, current string) string {
+	candidates := []corev1.Pod{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		if pod.Spec.NodeName == current {
+			return current
+		}
+		if pod.DeletionTimestamp != nil || !isPodInitialized(&pod) {
+			continue
+		}
+		candidates = append(candidates, pod)
+	}
+	if len(candidates) == 0 {
+		return current
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		ti, tj := candidates[i].CreationTimestamp, candidates[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return candidates[0].Spec.NodeName
+}
+
+func isPodInitialized(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodInitialized {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// NewProvisioningVIPConfigMap returns the ConfigMap the containers of the
+// metal3 pods read the node of the active pod from
+func NewProvisioningVIPConfigMap(targetNamespace string, node string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ProvisioningVIPConfigMapName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: map[string]string{
+			provisioningVIPNodeKey: node,
+		},
+	}
+}
+
+// ApplyProvisioningVIPConfigMap creates or updates the ConfigMap of the
+// holder of the ProvisioningIP
+func ApplyProvisioningVIPConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	return applyConfigMap(client, configMap)
+}
+
+// GetActiveMetal3Node returns the node of the active metal3 pod, or an
+// empty string when none was elected
+func GetActiveMetal3Node(client coreclientv1.ConfigMapsGetter, targetNamespace string) (string, error) {
+	configMap, err := client.ConfigMaps(targetNamespace).Get(context.Background(), ProvisioningVIPConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", apiError(err)
+	}
+	return configMap.Data[provisioningVIPNodeKey], nil
+}
+
+// DeleteProvisioningVIPConfigMap removes the ConfigMap of the holder of
+// the ProvisioningIP, once HighAvailability is not set
+func DeleteProvisioningVIPConfigMap(client coreclientv1.ConfigMapsGetter, targetNamespace string) error {
+	err := client.ConfigMaps(targetNamespace).Delete(context.Background(), ProvisioningVIPConfigMapName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return apiError(err)
+}
+
+// newProvisioningVIPVolume returns the volume of the holder of the
+// ProvisioningIP. It is optional, so that the pods start before the
+// first election, none of them holding the address.
+func newProvisioningVIPVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: provisioningVIPVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: ProvisioningVIPConfigMapName},
+				Optional:             pointer.BoolPtr(true),
+			},
+		},
+	}
+}
+
+// addProvisioningVIP lets a static IP container find whether its node
+// holds the ProvisioningIP. As for the ProvisioningIPPool, the whole
+// ConfigMap is mounted so that a new election reaches the running pods.
+func addProvisioningVIP(container *corev1.Container, config *metal3iov1alpha1.ProvisioningSpec) {
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      provisioningVIPVolume,
+		MountPath: provisioningVIPMountPath,
+		ReadOnly:  true,
+	})
+	container.Env = append(container.Env,
+		corev1.EnvVar{
+			Name:  "VIP_HOLDER_FILE",
+			Value: provisioningVIPMountPath + "/" + provisioningVIPNodeKey,
+		},
+		corev1.EnvVar{
+			Name:  "GRATUITOUS_ARP_COUNT",
+			Value: strconv.Itoa(int(getGratuitousARPCount(config))),
+		},
+	)
+}
+
+// useProvisioningVIPManager makes the static IP manager follow the holder
+// of the ProvisioningIP. Its readiness no longer requires the address on
+// the nodes not holding it.
+func useProvisioningVIPManager(container *corev1.Container) {
+	container.Command = []string{"/bin/bash", "-c", provisioningVIPManagerScript}
+	container.ReadinessProbe.Handler.Exec.Command = provisioningVIPConfiguredCommand
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name:  "PROVISIONING_VIP_CHECK_INTERVAL",
+		Value: provisioningVIPCheckInterval,
+	})
+}
@@ -0,0 +1,181 @@
+package provisioning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateProvisioningVIP(t *testing.T) {
+	tests := []struct {
+		name          string
+		vip           *metal3iov1alpha1.ProvisioningVIP
+		replicas      int32
+		network       metal3iov1alpha1.ProvisioningNetwork
+		expectedError string
+	}{
+		{
+			name: "NoVIP",
+		},
+		{
+			name:     "Valid",
+			vip:      &metal3iov1alpha1.ProvisioningVIP{},
+			replicas: 2,
+			network:  metal3iov1alpha1.ProvisioningNetworkManaged,
+		},
+		{
+			name:          "WithoutHighAvailability",
+			vip:           &metal3iov1alpha1.ProvisioningVIP{},
+			network:       metal3iov1alpha1.ProvisioningNetworkManaged,
+			expectedError: "ProvisioningVIP requires HighAvailability",
+		},
+		{
+			name:          "Disabled",
+			vip:           &metal3iov1alpha1.ProvisioningVIP{},
+			replicas:      2,
+			network:       metal3iov1alpha1.ProvisioningNetworkDisabled,
+			expectedError: "ProvisioningVIP cannot be used when the provisioning network is Disabled",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.ProvisioningVIP = tc.vip
+			if tc.replicas > 0 {
+				spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: tc.replicas}
+			}
+			err := validateProvisioningVIP(spec, tc.network)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestSelectProvisioningVIPNode(t *testing.T) {
+	now := time.Now()
+	pod := func(name, node string, age time.Duration, initialized bool) corev1.Pod {
+		status := corev1.ConditionFalse
+		if initialized {
+			status = corev1.ConditionTrue
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))},
+			Spec:       corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{{Type: corev1.PodInitialized, Status: status}},
+			},
+		}
+	}
+	terminating := func(p corev1.Pod) corev1.Pod {
+		deleted := metav1.NewTime(now)
+		p.DeletionTimestamp = &deleted
+		return p
+	}
+	tests := []struct {
+		name     string
+		pods     []corev1.Pod
+		current  string
+		expected string
+	}{
+		{
+			name:     "OldestInitialized",
+			pods:     []corev1.Pod{pod("a", "master-0", time.Minute, true), pod("b", "master-1", time.Hour, true), pod("c", "master-2", 2*time.Hour, false)},
+			expected: "master-1",
+		},
+		{
+			name:     "KeepsCurrent",
+			pods:     []corev1.Pod{pod("a", "master-0", time.Minute, true), pod("b", "master-1", time.Hour, true)},
+			current:  "master-0",
+			expected: "master-0",
+		},
+		{
+			name:     "KeepsFailingCurrent",
+			pods:     []corev1.Pod{pod("a", "master-0", time.Minute, false), pod("b", "master-1", time.Hour, true)},
+			current:  "master-0",
+			expected: "master-0",
+		},
+		{
+			name:     "KeepsTerminatingCurrent",
+			pods:     []corev1.Pod{terminating(pod("a", "master-0", time.Minute, true)), pod("b", "master-1", time.Hour, true)},
+			current:  "master-0",
+			expected: "master-0",
+		},
+		{
+			name:     "FailsOverOnceGone",
+			pods:     []corev1.Pod{pod("b", "master-1", time.Hour, true), terminating(pod("c", "master-2", 2*time.Hour, true))},
+			current:  "master-0",
+			expected: "master-1",
+		},
+		{
+			name:     "NoInitializedPod",
+			pods:     []corev1.Pod{pod("b", "master-1", time.Minute, false)},
+			current:  "master-0",
+			expected: "master-0",
+		},
+		{
+			name: "NoPods",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, SelectProvisioningVIPNode(tc.pods, tc.current))
+		})
+	}
+}
+
+func TestMetal3ProvisioningVIP(t *testing.T) {
+	spec := managedProvisioning()
+	podSpec := NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.NotContains(t, podSpec.Volumes, newProvisioningVIPVolume())
+
+	// Only the active metal3 pod holds the ProvisioningIP, with or
+	// without a ProvisioningVIP
+	spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: 2}
+	podSpec = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.Contains(t, podSpec.Volumes, newProvisioningVIPVolume())
+	set := findContainer(podSpec.InitContainers, "metal3-static-ip-set")
+	if assert.NotNil(t, set) {
+		assert.Equal(t, []string{"/bin/bash", "-c", provisioningVIPSetScript}, set.Command)
+		assert.Equal(t, "3", envValue(set, "GRATUITOUS_ARP_COUNT"))
+	}
+
+	spec.ProvisioningVIP = &metal3iov1alpha1.ProvisioningVIP{GratuitousARPCount: 5}
+	podSpec = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	assert.Contains(t, podSpec.Volumes, newProvisioningVIPVolume())
+
+	set = findContainer(podSpec.InitContainers, "metal3-static-ip-set")
+	if assert.NotNil(t, set) {
+		assert.Equal(t, []string{"/bin/bash", "-c", provisioningVIPSetScript}, set.Command)
+		assert.Equal(t, provisioningVIPMountPath+"/node", envValue(set, "VIP_HOLDER_FILE"))
+		assert.Equal(t, "5", envValue(set, "GRATUITOUS_ARP_COUNT"))
+	}
+
+	manager := findContainer(podSpec.Containers, StaticIPManagerContainerName)
+	if assert.NotNil(t, manager) {
+		assert.Equal(t, []string{"/bin/bash", "-c", provisioningVIPManagerScript}, manager.Command)
+		assert.Equal(t, provisioningVIPConfiguredCommand, manager.ReadinessProbe.Exec.Command)
+		assert.Contains(t, manager.VolumeMounts, staticIPStateMount)
+		assert.Equal(t, provisioningVIPMountPath+"/node", envValue(manager, "VIP_HOLDER_FILE"))
+	}
+
+	// The pool and the VIP share the name of the node
+	spec.ProvisioningIPPool = []string{"172.30.20.4", "172.30.20.5"}
+	podSpec = NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec
+	manager = findContainer(podSpec.Containers, StaticIPManagerContainerName)
+	nodeNames := 0
+	for _, env := range manager.Env {
+		if env.Name == "NODE_NAME" {
+			nodeNames++
+		}
+	}
+	assert.Equal(t, 1, nodeNames)
+	assert.Equal(t, provisioningIPsMountPath+"/$(NODE_NAME)", envValue(manager, "NODE_IP_FILE"))
+}
@@ -0,0 +1,105 @@
+package provisioning
+
+import (
+	"fmt"
+	"sort"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// RAIDTemplateLabel names the DefaultRAIDConfig layout a BareMetalHost
+	// is deployed with
+	RAIDTemplateLabel = "baremetal.openshift.io/raid-template"
+
+	raidTemplateTraitPrefix = "CUSTOM_OCP_RAID_"
+	// raidDeployStepPriority runs the RAID configuration in the ramdisk,
+	// once deploy.deploy booted it at priority 100, and before the image
+	// is written by write_image at priority 80
+	raidDeployStepPriority = 90
+)
+
+// RAIDTemplateTrait returns the trait, and name, of the deploy template
+// of a RAID layout
+func RAIDTemplateTrait(name string) string {
+	return raidTemplateTraitPrefix + name
+}
+
+// validateDefaultRAIDConfig checks the RAID layouts Ironic would reject
+func validateDefaultRAIDConfig(config *metal3iov1alpha1.ProvisioningSpec) error {
+	names := map[string]bool{}
+	for _, template := range config.DefaultRAIDConfig {
+		if names[template.Name] {
+			return fmt.Errorf("DefaultRAIDConfig: duplicate template %q", template.Name)
+		}
+		names[template.Name] = true
+		rootVolumes := 0
+		for _, disk := range template.LogicalDisks {
+			if disk.IsRootVolume {
+				rootVolumes++
+			}
+			if len(disk.PhysicalDisks) > 0 && disk.Controller == "" {
+				return fmt.Errorf("DefaultRAIDConfig: template %q lists PhysicalDisks without a Controller", template.Name)
+			}
+		}
+		if rootVolumes > 1 {
+			return fmt.Errorf("DefaultRAIDConfig: template %q has more than one root volume", template.Name)
+		}
+	}
+	return nil
+}
+
+// newRAIDLogicalDisk returns a logical disk in the format of the Ironic
+// target RAID configuration
+func newRAIDLogicalDisk(disk metal3iov1alpha1.RAIDLogicalDisk) map[string]interface{} {
+	logicalDisk := map[string]interface{}{
+		"size_gb":    "MAX",
+		"raid_level": disk.RAIDLevel,
+	}
+	if disk.SizeGB != nil {
+		logicalDisk["size_gb"] = int(*disk.SizeGB)
+	}
+	if disk.IsRootVolume {
+		logicalDisk["is_root_volume"] = true
+	}
+	if disk.NumberOfPhysicalDisks != 0 {
+		logicalDisk["number_of_physical_disks"] = int(disk.NumberOfPhysicalDisks)
+	}
+	if disk.Controller != "" {
+		logicalDisk["controller"] = disk.Controller
+	}
+	if len(disk.PhysicalDisks) > 0 {
+		physicalDisks := make([]interface{}, len(disk.PhysicalDisks))
+		for i, name := range disk.PhysicalDisks {
+			physicalDisks[i] = name
+		}
+		logicalDisk["physical_disks"] = physicalDisks
+	}
+	return logicalDisk
+}
+
+// NewRAIDDeployTemplates returns the Ironic deploy templates applying the
+// DefaultRAIDConfig, sorted by name
+func NewRAIDDeployTemplates(config *metal3iov1alpha1.ProvisioningSpec) []IronicDeployTemplate {
+	templates := []IronicDeployTemplate{}
+	for _, raid := range config.DefaultRAIDConfig {
+		logicalDisks := make([]interface{}, len(raid.LogicalDisks))
+		for i, disk := range raid.LogicalDisks {
+			logicalDisks[i] = newRAIDLogicalDisk(disk)
+		}
+		templates = append(templates, IronicDeployTemplate{
+			Name: RAIDTemplateTrait(raid.Name),
+			Steps: []IronicDeployStep{{
+				Interface: "raid",
+				Step:      "apply_configuration",
+				Args: map[string]interface{}{
+					"raid_config":     map[string]interface{}{"logical_disks": logicalDisks},
+					"delete_existing": true,
+				},
+				Priority: raidDeployStepPriority,
+			}},
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
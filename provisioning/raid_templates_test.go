@@ -0,0 +1,112 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateDefaultRAIDConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		raid          []metal3iov1alpha1.RAIDTemplate
+		expectedError string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name: "Valid",
+			raid: []metal3iov1alpha1.RAIDTemplate{
+				{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "1", IsRootVolume: true}}},
+				{Name: "STRIPE", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "0", Controller: "RAID.Integrated.1-1", PhysicalDisks: []string{"Disk.0", "Disk.1"}}}},
+			},
+		},
+		{
+			name: "Duplicate",
+			raid: []metal3iov1alpha1.RAIDTemplate{
+				{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "1"}}},
+				{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "0"}}},
+			},
+			expectedError: `DefaultRAIDConfig: duplicate template "MIRROR"`,
+		},
+		{
+			name: "TwoRootVolumes",
+			raid: []metal3iov1alpha1.RAIDTemplate{
+				{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "1", IsRootVolume: true}, {RAIDLevel: "0", IsRootVolume: true}}},
+			},
+			expectedError: `DefaultRAIDConfig: template "MIRROR" has more than one root volume`,
+		},
+		{
+			name: "PhysicalDisksWithoutController",
+			raid: []metal3iov1alpha1.RAIDTemplate{
+				{Name: "STRIPE", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{{RAIDLevel: "0", PhysicalDisks: []string{"Disk.0"}}}},
+			},
+			expectedError: `DefaultRAIDConfig: template "STRIPE" lists PhysicalDisks without a Controller`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.DefaultRAIDConfig = tc.raid
+			err := validateDefaultRAIDConfig(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestNewRAIDDeployTemplates(t *testing.T) {
+	size := int32(100)
+	spec := managedProvisioning()
+	assert.Empty(t, NewRAIDDeployTemplates(spec))
+
+	spec.DefaultRAIDConfig = []metal3iov1alpha1.RAIDTemplate{
+		{Name: "STRIPE", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{
+			{RAIDLevel: "0", Controller: "RAID.Integrated.1-1", PhysicalDisks: []string{"Disk.0", "Disk.1"}},
+		}},
+		{Name: "MIRROR", LogicalDisks: []metal3iov1alpha1.RAIDLogicalDisk{
+			{RAIDLevel: "1", SizeGB: &size, IsRootVolume: true},
+			{RAIDLevel: "5", NumberOfPhysicalDisks: 3},
+		}},
+	}
+	templates := NewRAIDDeployTemplates(spec)
+	if !assert.Len(t, templates, 2) {
+		return
+	}
+	assert.Equal(t, "CUSTOM_OCP_RAID_MIRROR", templates[0].Name)
+	assert.Equal(t, "CUSTOM_OCP_RAID_STRIPE", templates[1].Name)
+
+	data, err := json.Marshal(templates[0])
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"name": "CUSTOM_OCP_RAID_MIRROR",
+		"steps": [{
+			"interface": "raid",
+			"step": "apply_configuration",
+			"priority": 90,
+			"args": {
+				"delete_existing": true,
+				"raid_config": {"logical_disks": [
+					{"size_gb": 100, "raid_level": "1", "is_root_volume": true},
+					{"size_gb": "MAX", "raid_level": "5", "number_of_physical_disks": 3}
+				]}
+			}
+		}]
+	}`, string(data))
+
+	data, err = json.Marshal(templates[1].Steps[0].Args)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"delete_existing": true,
+		"raid_config": {"logical_disks": [
+			{"size_gb": "MAX", "raid_level": "0", "controller": "RAID.Integrated.1-1", "physical_disks": ["Disk.0", "Disk.1"]}
+		]}
+	}`, string(data))
+}
@@ -0,0 +1,62 @@
+package provisioning
+
+import (
+	"sort"
+	"testing"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// The benchmarks render the operands of each of the golden configurations,
+// which the operator does on every reconcile. Run them with `make bench`.
+
+func benchmarkGoldenConfigs(b *testing.B, run func(b *testing.B, config *metal3iov1alpha1.ProvisioningSpec)) {
+	configs := goldenConfigs()
+	names := []string{}
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		config := configs[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				run(b, config)
+			}
+		})
+	}
+}
+
+func BenchmarkNewMetal3Deployment(b *testing.B) {
+	benchmarkGoldenConfigs(b, func(b *testing.B, config *metal3iov1alpha1.ProvisioningSpec) {
+		NewMetal3Deployment(goldenNamespace, &testImages, config)
+	})
+}
+
+func BenchmarkNewDnsmasqDaemonSet(b *testing.B) {
+	benchmarkGoldenConfigs(b, func(b *testing.B, config *metal3iov1alpha1.ProvisioningSpec) {
+		if IsDnsmasqRequired(config) {
+			NewDnsmasqDaemonSet(goldenNamespace, &testImages, config)
+		}
+	})
+}
+
+func BenchmarkValidateBaremetalProvisioningConfig(b *testing.B) {
+	benchmarkGoldenConfigs(b, func(b *testing.B, config *metal3iov1alpha1.ProvisioningSpec) {
+		if err := ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *config}); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
+
+// BenchmarkGetRolloutHash renders the metal3 Deployment and hashes it, as
+// done to decide whether the operands are rolled out again
+func BenchmarkGetRolloutHash(b *testing.B) {
+	benchmarkGoldenConfigs(b, func(b *testing.B, config *metal3iov1alpha1.ProvisioningSpec) {
+		deployment := NewMetal3Deployment(goldenNamespace, &testImages, config)
+		if _, err := GetRolloutHash(deployment.Spec); err != nil {
+			b.Fatal(err)
+		}
+	})
+}
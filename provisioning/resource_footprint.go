@@ -0,0 +1,72 @@
+package provisioning
+
+import (
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// The recommendations leave headroom over the peak usage sampled, more
+	// for the memory, as exceeding a memory limit kills the container
+	cpuHeadroomPercent    = 25
+	memoryHeadroomPercent = 50
+
+	cpuRecommendationStepMilli = 10
+	memoryRecommendationStep   = 16 * 1024 * 1024
+)
+
+// AddResourceUsageSample records the CPU and memory usage of the metal3
+// containers, keyed by container name, in the footprint
+func AddResourceUsageSample(footprint *metal3iov1alpha1.ResourceFootprint, usage map[string]corev1.ResourceList) {
+	footprint.Samples++
+	for name, used := range usage {
+		container := findContainerFootprint(footprint, name)
+		if container == nil {
+			footprint.Containers = append(footprint.Containers, metal3iov1alpha1.ContainerFootprint{Name: name})
+			container = &footprint.Containers[len(footprint.Containers)-1]
+		}
+		if cpu, ok := used[corev1.ResourceCPU]; ok && cpu.Cmp(container.PeakCPU) > 0 {
+			container.PeakCPU = cpu.DeepCopy()
+		}
+		if memory, ok := used[corev1.ResourceMemory]; ok && memory.Cmp(container.PeakMemory) > 0 {
+			container.PeakMemory = memory.DeepCopy()
+		}
+	}
+	sort.Slice(footprint.Containers, func(i, j int) bool {
+		return footprint.Containers[i].Name < footprint.Containers[j].Name
+	})
+}
+
+func findContainerFootprint(footprint *metal3iov1alpha1.ResourceFootprint, name string) *metal3iov1alpha1.ContainerFootprint {
+	for i := range footprint.Containers {
+		if footprint.Containers[i].Name == name {
+			return &footprint.Containers[i]
+		}
+	}
+	return nil
+}
+
+// SetResourceRecommendations sets the resources recommended for each
+// container from its peak usage
+func SetResourceRecommendations(footprint *metal3iov1alpha1.ResourceFootprint) {
+	for i := range footprint.Containers {
+		container := &footprint.Containers[i]
+		cpu := resource.NewMilliQuantity(roundUp(container.PeakCPU.MilliValue()*(100+cpuHeadroomPercent)/100,
+			cpuRecommendationStepMilli), resource.DecimalSI)
+		memory := resource.NewQuantity(roundUp(container.PeakMemory.Value()*(100+memoryHeadroomPercent)/100,
+			memoryRecommendationStep), resource.BinarySI)
+		container.RecommendedCPU, container.RecommendedMemory = cpu, memory
+	}
+}
+
+// roundUp rounds value up to a non-zero multiple of step
+func roundUp(value int64, step int64) int64 {
+	if value <= 0 {
+		return step
+	}
+	return (value + step - 1) / step * step
+}
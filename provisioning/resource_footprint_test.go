@@ -0,0 +1,61 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func usage(cpu string, memory string) corev1.ResourceList {
+	return corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+}
+
+func TestAddResourceUsageSample(t *testing.T) {
+	footprint := &metal3iov1alpha1.ResourceFootprint{}
+	AddResourceUsageSample(footprint, map[string]corev1.ResourceList{
+		"metal3-ironic-conductor": usage("120m", "300Mi"),
+		"metal3-httpd":            usage("2m", "20Mi"),
+	})
+	AddResourceUsageSample(footprint, map[string]corev1.ResourceList{
+		"metal3-ironic-conductor": usage("80m", "450Mi"),
+		"metal3-httpd":            usage("15m", "18Mi"),
+	})
+
+	assert.Equal(t, int32(2), footprint.Samples)
+	if assert.Len(t, footprint.Containers, 2) {
+		httpd, conductor := footprint.Containers[0], footprint.Containers[1]
+		assert.Equal(t, "metal3-httpd", httpd.Name)
+		assert.Equal(t, "15m", httpd.PeakCPU.String())
+		assert.Equal(t, "20Mi", httpd.PeakMemory.String())
+		assert.Equal(t, "metal3-ironic-conductor", conductor.Name)
+		assert.Equal(t, "120m", conductor.PeakCPU.String())
+		assert.Equal(t, "450Mi", conductor.PeakMemory.String())
+		assert.Nil(t, conductor.RecommendedCPU)
+	}
+}
+
+func TestSetResourceRecommendations(t *testing.T) {
+	footprint := &metal3iov1alpha1.ResourceFootprint{}
+	AddResourceUsageSample(footprint, map[string]corev1.ResourceList{
+		"metal3-ironic-conductor": usage("120m", "300Mi"),
+		"metal3-static-ip-manager": {
+			corev1.ResourceCPU:    resource.MustParse("0"),
+			corev1.ResourceMemory: resource.MustParse("1Mi"),
+		},
+	})
+	SetResourceRecommendations(footprint)
+
+	conductor, manager := footprint.Containers[0], footprint.Containers[1]
+	assert.Equal(t, "150m", conductor.RecommendedCPU.String())
+	assert.Equal(t, "464Mi", conductor.RecommendedMemory.String())
+	// Idle containers are still given some resources
+	assert.Equal(t, "10m", manager.RecommendedCPU.String())
+	assert.Equal(t, "16Mi", manager.RecommendedMemory.String())
+}
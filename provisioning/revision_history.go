@@ -0,0 +1,165 @@
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RevisionHistoryConfigMapName is the name of the ConfigMap holding the
+	// last rendered revisions of the operands
+	RevisionHistoryConfigMapName = "metal3-revision-history"
+	// RevisionHistoryLimit is the number of operand revisions kept
+	RevisionHistoryLimit = 5
+	// RollbackRevisionAnnotation, when set on the Provisioning CR to the
+	// rollout hash of a revision in the history, makes the operator apply
+	// that revision of the operands instead of the one rendered from the
+	// spec, until the annotation is removed
+	RollbackRevisionAnnotation = "baremetal.openshift.io/rollback-to-revision"
+
+	// revisionHistoryOrderKey lists the revisions, newest first
+	revisionHistoryOrderKey = "revisions"
+)
+
+// OperandRevision is a rendered revision of the operands, identified by
+// its rollout hash
+type OperandRevision struct {
+	RolloutHash string             `json:"rolloutHash"`
+	Applied     metav1.Time        `json:"applied"`
+	Deployment  *appsv1.Deployment `json:"deployment"`
+	// DaemonSet is the dnsmasq DaemonSet, when the revision deploys it
+	DaemonSet *appsv1.DaemonSet `json:"daemonSet,omitempty"`
+}
+
+// RevisionHistory holds the last operand revisions, newest first
+type RevisionHistory []OperandRevision
+
+// Get returns the revision with the given rollout hash, or nil when it is
+// not in the history
+func (h RevisionHistory) Get(rolloutHash string) *OperandRevision {
+	for i := range h {
+		if h[i].RolloutHash == rolloutHash {
+			return &h[i]
+		}
+	}
+	return nil
+}
+
+// Record adds the given revision as the newest one, dropping the oldest
+// revisions over RevisionHistoryLimit. A revision already in the history
+// is moved first. It returns true when the history changed.
+func (h *RevisionHistory) Record(revision OperandRevision) bool {
+	if len(*h) > 0 && (*h)[0].RolloutHash == revision.RolloutHash {
+		return false
+	}
+	history := RevisionHistory{revision}
+	for _, previous := range *h {
+		if previous.RolloutHash != revision.RolloutHash && len(history) < RevisionHistoryLimit {
+			history = append(history, previous)
+		}
+	}
+	*h = history
+	return true
+}
+
+// NewOperandRevision returns the revision of the given rendered operands
+func NewOperandRevision(rolloutHash string, deployment *appsv1.Deployment, daemonSet *appsv1.DaemonSet) OperandRevision {
+	revision := OperandRevision{
+		RolloutHash: rolloutHash,
+		Applied:     metav1.Now(),
+		Deployment:  trimDeployment(deployment),
+	}
+	if daemonSet != nil {
+		revision.DaemonSet = trimDaemonSet(daemonSet)
+	}
+	return revision
+}
+
+// trimDaemonSet only keeps what is needed to apply a rendered DaemonSet
+// again
+func trimDaemonSet(daemonSet *appsv1.DaemonSet) *appsv1.DaemonSet {
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        daemonSet.Name,
+			Namespace:   daemonSet.Namespace,
+			Labels:      daemonSet.Labels,
+			Annotations: daemonSet.Annotations,
+		},
+		Spec: daemonSet.Spec,
+	}
+}
+
+func revisionKey(rolloutHash string) string {
+	return rolloutHash + ".yaml"
+}
+
+// GetRevisionHistory reads the operand revision history, which is empty
+// before the first rollout
+func GetRevisionHistory(client coreclientv1.ConfigMapsGetter, targetNamespace string) (RevisionHistory, error) {
+	configMap, err := client.ConfigMaps(targetNamespace).Get(context.Background(), RevisionHistoryConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return RevisionHistory{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	history := RevisionHistory{}
+	for _, rolloutHash := range strings.Fields(configMap.Data[revisionHistoryOrderKey]) {
+		manifest, ok := configMap.Data[revisionKey(rolloutHash)]
+		if !ok {
+			return nil, fmt.Errorf("revision %s missing from %s ConfigMap", rolloutHash, RevisionHistoryConfigMapName)
+		}
+		revision := OperandRevision{}
+		if err := yaml.Unmarshal([]byte(manifest), &revision); err != nil {
+			return nil, fmt.Errorf("invalid revision %s in %s ConfigMap: %v", rolloutHash, RevisionHistoryConfigMapName, err)
+		}
+		history = append(history, revision)
+	}
+	return history, nil
+}
+
+// NewRevisionHistoryConfigMap returns the ConfigMap storing the operand
+// revision history
+func NewRevisionHistoryConfigMap(targetNamespace string, history RevisionHistory) (*corev1.ConfigMap, error) {
+	order := []string{}
+	data := map[string]string{}
+	for _, revision := range history {
+		// The owner references set when applying are not kept
+		revision.Deployment = trimDeployment(revision.Deployment)
+		if revision.DaemonSet != nil {
+			revision.DaemonSet = trimDaemonSet(revision.DaemonSet)
+		}
+		manifest, err := yaml.Marshal(revision)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, revision.RolloutHash)
+		data[revisionKey(revision.RolloutHash)] = string(manifest)
+	}
+	data[revisionHistoryOrderKey] = strings.Join(order, "\n")
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RevisionHistoryConfigMapName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+		},
+		Data: data,
+	}, nil
+}
+
+// ApplyRevisionHistoryConfigMap creates or updates the revision history
+// ConfigMap
+func ApplyRevisionHistoryConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	return ApplyBootArtifactsConfigMap(client, configMap)
+}
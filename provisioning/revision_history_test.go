@@ -0,0 +1,64 @@
+package provisioning
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func revisionHashes(history RevisionHistory) []string {
+	hashes := []string{}
+	for _, revision := range history {
+		hashes = append(hashes, revision.RolloutHash)
+	}
+	return hashes
+}
+
+func TestRevisionHistoryRecord(t *testing.T) {
+	deployment := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+	history := RevisionHistory{}
+	for i := 0; i < RevisionHistoryLimit+2; i++ {
+		assert.True(t, history.Record(NewOperandRevision(fmt.Sprintf("rev%d", i), deployment, nil)))
+	}
+	assert.Equal(t, []string{"rev6", "rev5", "rev4", "rev3", "rev2"}, revisionHashes(history))
+
+	assert.False(t, history.Record(NewOperandRevision("rev6", deployment, nil)))
+	assert.True(t, history.Record(NewOperandRevision("rev3", deployment, nil)))
+	assert.Equal(t, []string{"rev3", "rev6", "rev5", "rev4", "rev2"}, revisionHashes(history))
+
+	assert.NotNil(t, history.Get("rev4"))
+	assert.Nil(t, history.Get("rev1"))
+}
+
+func TestRevisionHistoryConfigMap(t *testing.T) {
+	client := fakekube.NewSimpleClientset()
+
+	history, err := GetRevisionHistory(client.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	config := managedProvisioning()
+	deployment := NewMetal3Deployment(testNamespace, &testImages, config)
+	deployment.OwnerReferences = []metav1.OwnerReference{{Name: "provisioning-configuration"}}
+	daemonSet := NewDnsmasqDaemonSet(testNamespace, &testImages, config)
+	history.Record(NewOperandRevision("rev0", deployment, nil))
+	history.Record(NewOperandRevision("rev1", deployment, daemonSet))
+
+	configMap, err := NewRevisionHistoryConfigMap(testNamespace, history)
+	assert.NoError(t, err)
+	assert.Equal(t, "rev1\nrev0", configMap.Data["revisions"])
+	assert.NoError(t, ApplyRevisionHistoryConfigMap(client.CoreV1(), configMap))
+
+	actual, err := GetRevisionHistory(client.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rev1", "rev0"}, revisionHashes(actual))
+	assert.Empty(t, actual[0].Deployment.OwnerReferences)
+	assert.Equal(t, GetPodTemplateImages(&deployment.Spec.Template), GetPodTemplateImages(&actual[0].Deployment.Spec.Template))
+	if assert.NotNil(t, actual[0].DaemonSet) {
+		assert.Equal(t, daemonSet.Name, actual[0].DaemonSet.Name)
+	}
+	assert.Nil(t, actual[1].DaemonSet)
+}
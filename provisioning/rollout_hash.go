@@ -0,0 +1,37 @@
+package provisioning
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RolloutHashAnnotation records on the operand resources the rollout hash
+// of the configuration they were rendered for
+const RolloutHashAnnotation = "baremetal.openshift.io/rollout-hash"
+
+// GetRolloutHash returns a hash of the given rendered operand specs, which
+// changes whenever the operator has to update any of them
+func GetRolloutHash(specs ...interface{}) (string, error) {
+	hash := sha256.New()
+	for _, spec := range specs {
+		data, err := json.Marshal(spec)
+		if err != nil {
+			return "", err
+		}
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16], nil
+}
+
+// SetRolloutHash records the rollout hash on an operand resource
+func SetRolloutHash(obj metav1.Object, rolloutHash string) {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[RolloutHashAnnotation] = rolloutHash
+	obj.SetAnnotations(annotations)
+}
@@ -0,0 +1,47 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetRolloutHash(t *testing.T) {
+	config := managedProvisioning()
+	deployment := NewMetal3Deployment(testNamespace, &testImages, config)
+	daemonSet := NewDnsmasqDaemonSet(testNamespace, &testImages, config)
+
+	hash, err := GetRolloutHash(deployment.Spec, daemonSet.Spec)
+	assert.NoError(t, err)
+	assert.Len(t, hash, 16)
+
+	again, err := GetRolloutHash(NewMetal3Deployment(testNamespace, &testImages, config).Spec, daemonSet.Spec)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, again, "hash should be stable")
+
+	config.ProvisioningInterface = "eth1"
+	changed, err := GetRolloutHash(NewMetal3Deployment(testNamespace, &testImages, config).Spec, daemonSet.Spec)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, changed, "hash should change with the rendered spec")
+}
+
+func TestApplyRolloutHash(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+	deployment := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+	SetRolloutHash(deployment, "first")
+	_, err := ApplyMetal3Deployment(kubeClient.AppsV1(), deployment.DeepCopy())
+	assert.NoError(t, err)
+
+	// Only the hash differs, the deployment is still updated
+	SetRolloutHash(deployment, "second")
+	updated, err := ApplyMetal3Deployment(kubeClient.AppsV1(), deployment.DeepCopy())
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	existing, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), deployment.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "second", existing.Annotations[RolloutHashAnnotation])
+}
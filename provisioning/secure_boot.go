@@ -0,0 +1,103 @@
+package provisioning
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	secureBootConfigEnvVar = "SECURE_BOOT_CONFIG"
+	// The UEFI client architectures of RFC 4578
+	clientArchEFIX64   = 7
+	clientArchEFIBC    = 9
+	clientArchEFIARM64 = 11
+	secureBootTagX64   = "secureboot-x64"
+	secureBootTagARM64 = "secureboot-aa64"
+	shimBootFileX64    = "shimx64.efi"
+	shimBootFileARM64  = "shimaa64.efi"
+	grubConfigTemplate = "$pybasedir/drivers/modules/pxe_grub_config.template"
+	// Ironic reads the options of its [pxe] section from the environment
+	ironicPXEEnvPrefix = "OS_PXE__"
+)
+
+// validateSecureBoot checks that the hosts boot from the network when the
+// Secure Boot chain is enabled
+func validateSecureBoot(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	if config.SecureBoot && mode == metal3iov1alpha1.ProvisioningNetworkDisabled {
+		return fmt.Errorf("SecureBoot is not supported when the ProvisioningNetwork is Disabled")
+	}
+	return nil
+}
+
+// uefiBootFile is a boot file handed out to the UEFI hosts of some client
+// architectures
+type uefiBootFile struct {
+	tag   string
+	file  string
+	archs []int
+}
+
+// getUEFIBootFileConfig returns the dnsmasq configuration handing out the
+// boot files to the UEFI hosts of their client architectures, over DHCPv6
+// when the provisioning network is IPv6
+func getUEFIBootFileConfig(config *metal3iov1alpha1.ProvisioningSpec, boots []uefiBootFile) string {
+	ipv6 := isIPv6Network(config)
+	lines := []string{}
+	for _, boot := range boots {
+		for _, arch := range boot.archs {
+			if ipv6 {
+				lines = append(lines, fmt.Sprintf("dhcp-match=set:%s,option6:61,%d", boot.tag, arch))
+			} else {
+				lines = append(lines, fmt.Sprintf("dhcp-match=set:%s,option:client-arch,%d", boot.tag, arch))
+			}
+		}
+		if ipv6 {
+			lines = append(lines, fmt.Sprintf("dhcp-option=tag:%s,option6:bootfile-url,tftp://[%s]/%s", boot.tag, config.ProvisioningIP, boot.file))
+		} else {
+			lines = append(lines, fmt.Sprintf("dhcp-boot=tag:%s,%s", boot.tag, boot.file))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getSecureBootConfig returns the dnsmasq configuration handing out the
+// shim boot files to the UEFI hosts. The 32-bit UEFI hosts are left to
+// iPXE, as no shim is built for them. The shim loads GRUB from the same
+// TFTP root, so a FilesConfigMap must hold shimx64.efi, grubx64.efi and
+// their aarch64 builds.
+func getSecureBootConfig(config *metal3iov1alpha1.ProvisioningSpec) string {
+	if !config.SecureBoot {
+		return ""
+	}
+	return getUEFIBootFileConfig(config, []uefiBootFile{
+		{tag: secureBootTagX64, file: shimBootFileX64, archs: []int{clientArchEFIX64, clientArchEFIBC}},
+		{tag: secureBootTagARM64, file: shimBootFileARM64, archs: []int{clientArchEFIARM64}},
+	})
+}
+
+// secureBootIronicEnv returns the configuration of the Ironic conductor
+// passed through the environment, so that the pxe boot interface
+// generates GRUB configurations and names the shim as UEFI boot file.
+func secureBootIronicEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	if !config.SecureBoot {
+		return nil
+	}
+	return []corev1.EnvVar{
+		{
+			Name:  ironicPXEEnvPrefix + "UEFI_PXE_BOOTFILE_NAME",
+			Value: shimBootFileX64,
+		},
+		{
+			Name:  ironicPXEEnvPrefix + "UEFI_PXE_CONFIG_TEMPLATE",
+			Value: grubConfigTemplate,
+		},
+		{
+			Name:  ironicPXEEnvPrefix + "PXE_BOOTFILE_NAME_BY_ARCH",
+			Value: "aarch64:" + shimBootFileARM64,
+		},
+	}
+}
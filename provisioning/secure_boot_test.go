@@ -0,0 +1,78 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateSecureBoot(t *testing.T) {
+	tCases := []struct {
+		name          string
+		mode          metal3iov1alpha1.ProvisioningNetwork
+		secureBoot    bool
+		expectedError bool
+	}{
+		{
+			name: "NotSet",
+			mode: metal3iov1alpha1.ProvisioningNetworkDisabled,
+		},
+		{
+			name:       "Managed",
+			mode:       metal3iov1alpha1.ProvisioningNetworkManaged,
+			secureBoot: true,
+		},
+		{
+			name:       "Unmanaged",
+			mode:       metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			secureBoot: true,
+		},
+		{
+			name:          "Disabled",
+			mode:          metal3iov1alpha1.ProvisioningNetworkDisabled,
+			secureBoot:    true,
+			expectedError: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			config.SecureBoot = tc.secureBoot
+			err := validateSecureBoot(config, tc.mode)
+			assert.Equal(t, tc.expectedError, err != nil, "%v", err)
+		})
+	}
+}
+
+func TestSecureBootConfig(t *testing.T) {
+	config := managedProvisioning()
+	assert.Equal(t, "", getSecureBootConfig(config))
+	assert.Empty(t, secureBootIronicEnv(config))
+
+	config.SecureBoot = true
+	assert.Equal(t, "dhcp-match=set:secureboot-x64,option:client-arch,7\n"+
+		"dhcp-match=set:secureboot-x64,option:client-arch,9\n"+
+		"dhcp-boot=tag:secureboot-x64,shimx64.efi\n"+
+		"dhcp-match=set:secureboot-aa64,option:client-arch,11\n"+
+		"dhcp-boot=tag:secureboot-aa64,shimaa64.efi", getSecureBootConfig(config))
+
+	dnsmasq := findContainer(newDnsmasqPodTemplateSpec(&testImages, config).Spec.Containers, "metal3-dnsmasq")
+	assert.Equal(t, getSecureBootConfig(config), envValue(dnsmasq, secureBootConfigEnvVar))
+	assert.Equal(t, dnsmasqServerScript+`mkdir -p /etc/dnsmasq.d && echo "${SECURE_BOOT_CONFIG}" > /etc/dnsmasq.d/secureboot.conf && exec /bin/rundnsmasq`,
+		dnsmasq.Command[2])
+
+	conductor := createContainerMetal3IronicConductor(&testImages, config)
+	assert.Equal(t, "shimx64.efi", envValue(&conductor, "OS_PXE__UEFI_PXE_BOOTFILE_NAME"))
+	assert.Equal(t, grubConfigTemplate, envValue(&conductor, "OS_PXE__UEFI_PXE_CONFIG_TEMPLATE"))
+	assert.Equal(t, "aarch64:shimaa64.efi", envValue(&conductor, "OS_PXE__PXE_BOOTFILE_NAME_BY_ARCH"))
+
+	config.ProvisioningIP = "fd00:1101::3"
+	config.ProvisioningNetworkCIDR = "fd00:1101::/64"
+	assert.Equal(t, "dhcp-match=set:secureboot-x64,option6:61,7\n"+
+		"dhcp-match=set:secureboot-x64,option6:61,9\n"+
+		"dhcp-option=tag:secureboot-x64,option6:bootfile-url,tftp://[fd00:1101::3]/shimx64.efi\n"+
+		"dhcp-match=set:secureboot-aa64,option6:61,11\n"+
+		"dhcp-option=tag:secureboot-aa64,option6:bootfile-url,tftp://[fd00:1101::3]/shimaa64.efi", getSecureBootConfig(config))
+}
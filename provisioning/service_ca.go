@@ -0,0 +1,152 @@
+package provisioning
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// ServiceCAConfigMapName is the name of the ConfigMap the service CA
+	// bundle is injected in, so that its rotation is noticed
+	ServiceCAConfigMapName = "metal3-service-ca"
+	// ServingCertsHashAnnotation records on the metal3 pod template the
+	// serving certificates issued by the service CA it was started with,
+	// so that the pod restarts when they are re-issued
+	ServingCertsHashAnnotation = "baremetal.openshift.io/serving-certs-hash"
+
+	serviceCAKey             = "service-ca.crt"
+	injectCABundleAnnotation = "service.beta.openshift.io/inject-cabundle"
+)
+
+// NewServiceCAConfigMap returns the ConfigMap the service CA bundle is
+// injected in
+func NewServiceCAConfigMap(targetNamespace string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ServiceCAConfigMapName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": metal3AppName,
+			},
+			Annotations: map[string]string{
+				injectCABundleAnnotation: "true",
+			},
+		},
+	}
+}
+
+// ApplyServiceCAConfigMap creates the service CA ConfigMap. Its data is
+// owned by the service CA operator, so an existing ConfigMap is only
+// updated when it lost the injection annotation.
+func ApplyServiceCAConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	existing, err := client.ConfigMaps(configMap.Namespace).Get(context.Background(), configMap.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.ConfigMaps(configMap.Namespace).Create(context.Background(), configMap, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Annotations[injectCABundleAnnotation] == "true" {
+		return nil
+	}
+	updated := existing.DeepCopy()
+	metav1.SetMetaDataAnnotation(&updated.ObjectMeta, injectCABundleAnnotation, "true")
+	updated.OwnerReferences = configMap.OwnerReferences
+	_, err = client.ConfigMaps(configMap.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	return err
+}
+
+// GetServiceCABundle returns the PEM encoded service CA bundle, or nil
+// until it has been injected
+func GetServiceCABundle(client coreclientv1.ConfigMapsGetter, targetNamespace string) ([]byte, error) {
+	configMap, err := client.ConfigMaps(targetNamespace).Get(context.Background(), ServiceCAConfigMapName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bundle := configMap.Data[serviceCAKey]; bundle != "" {
+		return []byte(bundle), nil
+	}
+	return nil, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return certs, nil
+}
+
+// IsSignedByCABundle returns true when the first certificate of the PEM
+// encoded chain is signed by one of the CAs of the bundle. During a
+// rotation the bundle holds both the previous and the new CA, so
+// certificates signed by either are accepted.
+func IsSignedByCABundle(certPEM []byte, bundle []byte) (bool, error) {
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return false, err
+	}
+	cas, err := parseCertificates(bundle)
+	if err != nil {
+		return false, fmt.Errorf("invalid CA bundle: %v", err)
+	}
+	for _, ca := range cas {
+		if certs[0].CheckSignatureFrom(ca) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetIronicAPIProxyTlsSecret returns the serving certificate of the Ironic
+// API proxy issued by the service CA, or nil until it has been issued
+func GetIronicAPIProxyTlsSecret(client coreclientv1.SecretsGetter, targetNamespace string) (*corev1.Secret, error) {
+	secret, err := client.Secrets(targetNamespace).Get(context.Background(), ironicAPIProxyTlsSecretName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// GetServingCertsHash returns a hash of the given serving certificates
+func GetServingCertsHash(secrets ...*corev1.Secret) string {
+	hash := sha256.New()
+	for _, secret := range secrets {
+		hash.Write(secret.Data[corev1.TLSCertKey])
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// SetServingCertsHash records the serving certificates hash on the pod
+// template of the metal3 Deployment
+func SetServingCertsHash(template *corev1.PodTemplateSpec, hash string) {
+	metav1.SetMetaDataAnnotation(&template.ObjectMeta, ServingCertsHashAnnotation, hash)
+}
@@ -0,0 +1,111 @@
+package provisioning
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+)
+
+// newTestCertificate returns a PEM encoded certificate signed by the given
+// CA, or a self-signed CA when none is given
+func newTestCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) ([]byte, *x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "metal3-ironic-api.openshift-machine-api.svc"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+	}
+	if ca == nil {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		ca, caKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), cert, key
+}
+
+func TestIsSignedByCABundle(t *testing.T) {
+	oldCAPEM, oldCA, oldKey := newTestCertificate(t, nil, nil)
+	newCAPEM, newCA, newKey := newTestCertificate(t, nil, nil)
+	oldCert, _, _ := newTestCertificate(t, oldCA, oldKey)
+	newCert, _, _ := newTestCertificate(t, newCA, newKey)
+
+	tCases := []struct {
+		name     string
+		cert     []byte
+		bundle   []byte
+		expected bool
+	}{
+		{
+			name:     "Signed",
+			cert:     newCert,
+			bundle:   newCAPEM,
+			expected: true,
+		},
+		{
+			name:     "SignedByPreviousCADuringRotation",
+			cert:     oldCert,
+			bundle:   append(append([]byte{}, newCAPEM...), oldCAPEM...),
+			expected: true,
+		},
+		{
+			name:   "CARotated",
+			cert:   oldCert,
+			bundle: newCAPEM,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			signed, err := IsSignedByCABundle(tc.cert, tc.bundle)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, signed)
+		})
+	}
+
+	_, err := IsSignedByCABundle([]byte("garbage"), newCAPEM)
+	assert.Error(t, err)
+}
+
+func TestServiceCAConfigMap(t *testing.T) {
+	client := fakekube.NewSimpleClientset()
+
+	assert.NoError(t, ApplyServiceCAConfigMap(client.CoreV1(), NewServiceCAConfigMap(testNamespace)))
+	bundle, err := GetServiceCABundle(client.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	assert.Nil(t, bundle)
+
+	// The injected bundle is kept
+	configMap := NewServiceCAConfigMap(testNamespace)
+	configMap.Data = map[string]string{serviceCAKey: "bundle"}
+	_, err = client.CoreV1().ConfigMaps(testNamespace).Update(context.Background(), configMap, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, ApplyServiceCAConfigMap(client.CoreV1(), NewServiceCAConfigMap(testNamespace)))
+	bundle, err = GetServiceCABundle(client.CoreV1(), testNamespace)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bundle"), bundle)
+}
+
+func TestGetServingCertsHash(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{corev1.TLSCertKey: []byte("cert")}}
+	rotated := &corev1.Secret{Data: map[string][]byte{corev1.TLSCertKey: []byte("rotated")}}
+	assert.Len(t, GetServingCertsHash(secret), 16)
+	assert.NotEqual(t, GetServingCertsHash(secret), GetServingCertsHash(rotated))
+}
@@ -0,0 +1,61 @@
+package provisioning
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// SingleReplicaTopologyMode is the control plane topology of single
+	// node OpenShift clusters, in the status of the Infrastructure
+	SingleReplicaTopologyMode = "SingleReplica"
+
+	mariadbContainerName = "metal3-mariadb"
+	useMariadbEnvVar     = "IRONIC_USE_MARIADB"
+)
+
+// GetTopologyProfile returns how the metal3 pods are deployed for a
+// control plane topology
+func GetTopologyProfile(controlPlaneTopology string) metal3iov1alpha1.TopologyProfile {
+	if controlPlaneTopology == SingleReplicaTopologyMode {
+		return metal3iov1alpha1.TopologyProfileSingleNode
+	}
+	return metal3iov1alpha1.TopologyProfileHighlyAvailable
+}
+
+// ApplyTopologyProfile adapts the pod spec of a metal3 workload to a
+// topology profile. On a single node the database of Ironic is kept in
+// SQLite on the shared volume, saving the MariaDB container, and the
+// pods are never evicted, as they have no other node to move to.
+func ApplyTopologyProfile(spec *corev1.PodSpec, profile metal3iov1alpha1.TopologyProfile) {
+	if profile != metal3iov1alpha1.TopologyProfileSingleNode {
+		return
+	}
+
+	spec.Containers = useSQLiteDatabase(spec.Containers)
+	applyIronicDBRecovery(spec)
+	for i := range spec.Tolerations {
+		if spec.Tolerations[i].Effect == corev1.TaintEffectNoExecute {
+			spec.Tolerations[i].TolerationSeconds = nil
+		}
+	}
+}
+
+// useSQLiteDatabase removes the MariaDB container, and has Ironic keep its
+// database in SQLite on the shared volume instead
+func useSQLiteDatabase(containers []corev1.Container) []corev1.Container {
+	kept := []corev1.Container{}
+	for _, container := range containers {
+		if container.Name == mariadbContainerName {
+			continue
+		}
+		for i, env := range container.Env {
+			if env.Name == mariadbPwdEnvVar {
+				container.Env[i] = corev1.EnvVar{Name: useMariadbEnvVar, Value: "false"}
+			}
+		}
+		kept = append(kept, container)
+	}
+	return kept
+}
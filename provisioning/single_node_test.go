@@ -0,0 +1,56 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestGetTopologyProfile(t *testing.T) {
+	tCases := []struct {
+		topology string
+		expected metal3iov1alpha1.TopologyProfile
+	}{
+		{topology: "", expected: metal3iov1alpha1.TopologyProfileHighlyAvailable},
+		{topology: "HighlyAvailable", expected: metal3iov1alpha1.TopologyProfileHighlyAvailable},
+		{topology: "SingleReplica", expected: metal3iov1alpha1.TopologyProfileSingleNode},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.topology, func(t *testing.T) {
+			assert.Equal(t, tc.expected, GetTopologyProfile(tc.topology))
+		})
+	}
+}
+
+func TestApplyTopologyProfile(t *testing.T) {
+	config := managedProvisioning()
+
+	t.Run("HighlyAvailable", func(t *testing.T) {
+		deployment := NewMetal3Deployment(testNamespace, &testImages, config)
+		expected := deployment.Spec.Template.Spec.DeepCopy()
+		ApplyTopologyProfile(&deployment.Spec.Template.Spec, metal3iov1alpha1.TopologyProfileHighlyAvailable)
+		assert.Equal(t, expected, &deployment.Spec.Template.Spec)
+	})
+
+	t.Run("SingleNode", func(t *testing.T) {
+		deployment := NewMetal3Deployment(testNamespace, &testImages, config)
+		spec := &deployment.Spec.Template.Spec
+		ApplyTopologyProfile(spec, metal3iov1alpha1.TopologyProfileSingleNode)
+
+		assert.NotContains(t, containerNames(spec.Containers), "metal3-mariadb")
+		for _, container := range spec.Containers {
+			for _, env := range container.Env {
+				assert.NotEqual(t, "MARIADB_PASSWORD", env.Name, "in container %s", container.Name)
+			}
+			if container.Name == "metal3-ironic-conductor" || container.Name == "metal3-ironic-api" {
+				assert.Contains(t, container.Env, corev1.EnvVar{Name: "IRONIC_USE_MARIADB", Value: "false"})
+			}
+		}
+		for _, toleration := range spec.Tolerations {
+			assert.Nil(t, toleration.TolerationSeconds, "toleration of %s", toleration.Key)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// SmokeTestJobName is the name of the Job running the smoke test
+	SmokeTestJobName = "metal3-smoke-test"
+	// smokeTestDeadline bounds the run of the smoke test, so that an
+	// unreachable service fails it rather than leaving it running
+	smokeTestDeadline = 300
+)
+
+// smokeTestScript runs the checks passed in SMOKE_TEST_CHECKS, and
+// reports their outcome as termination message. It fails when any of the
+// checks does.
+const smokeTestScript = `
+import base64
+import json
+import os
+import sys
+import urllib.request
+
+checks = json.loads(os.environ["SMOKE_TEST_CHECKS"])
+credentials = "%s:%s" % (os.environ.get("IRONIC_USERNAME", ""), os.environ.get("IRONIC_PASSWORD", ""))
+authorization = "Basic " + base64.b64encode(credentials.encode()).decode()
+
+results = []
+for check in checks:
+    request = urllib.request.Request(check["url"], method=check["method"])
+    if check.get("auth"):
+        request.add_header("Authorization", authorization)
+    try:
+        with urllib.request.urlopen(request, timeout=30):
+            pass
+        results.append({"name": check["name"], "passed": True})
+    except Exception as e:
+        results.append({"name": check["name"], "passed": False, "message": str(e)[:200]})
+    print("%s: %s" % (check["name"], "passed" if results[-1]["passed"] else results[-1]["message"]), flush=True)
+
+with open("/dev/termination-log", "w") as f:
+    json.dump({"checks": results}, f)
+sys.exit(0 if all(result["passed"] for result in results) else 1)
+`
+
+// smokeTestCheck is a request the smoke test expects to succeed
+type smokeTestCheck struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Auth   bool   `json:"auth,omitempty"`
+}
+
+// getSmokeTestChecks returns the requests exercising the services of the
+// metal3 pod with the given IP
+func getSmokeTestChecks(config *metal3iov1alpha1.ProvisioningSpec, podIP string) []smokeTestCheck {
+	imageServer := fmt.Sprintf("http://%s/", net.JoinHostPort(podIP, baremetalHttpPort))
+	checks := []smokeTestCheck{
+		{
+			Name:   "ironic-api",
+			Method: "GET",
+			URL:    fmt.Sprintf("http://%s/v1/nodes", net.JoinHostPort(podIP, getIronicAPIPort(config))),
+			Auth:   true,
+		},
+	}
+	// The Minimal profile runs no Ironic Inspector
+	if !IsMinimalProfile(config) {
+		checks = append(checks, smokeTestCheck{
+			Name:   "ironic-inspector-api",
+			Method: "GET",
+			URL:    fmt.Sprintf("http://%s/", net.JoinHostPort(podIP, baremetalIronicInspectorPort)),
+		})
+	}
+	checks = append(checks,
+		smokeTestCheck{Name: "deploy-kernel", Method: "HEAD", URL: imageServer + baremetalKernelUrlSubPath},
+		smokeTestCheck{Name: "deploy-ramdisk", Method: "HEAD", URL: imageServer + baremetalRamdiskUrlSubPath},
+	)
+	if subPath := getCachedOSImageSubPath(config); subPath != "" {
+		checks = append(checks, smokeTestCheck{Name: "os-image", Method: "HEAD", URL: imageServer + subPath})
+	}
+	return checks
+}
+
+// newIronicCredentialEnv passes a key of the Ironic credentials Secret to
+// the smoke test
+func newIronicCredentialEnv(name string, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: ironicSecretName,
+				},
+				Key: key,
+			},
+		},
+	}
+}
+
+// NewSmokeTestJob returns the Job running the smoke test against the
+// metal3 pod with the given IP, annotated with the rollout hash of the
+// operands it tests
+func NewSmokeTestJob(targetNamespace string, images *Images, config *metal3iov1alpha1.ProvisioningSpec, podIP string, rolloutHash string) (*batchv1.Job, error) {
+	checks, err := json.Marshal(getSmokeTestChecks(config, podIP))
+	if err != nil {
+		return nil, err
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SmokeTestJobName,
+			Namespace: targetNamespace,
+			Labels: map[string]string{
+				"k8s-app": SmokeTestJobName,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          pointer.Int32Ptr(0),
+			ActiveDeadlineSeconds: pointer.Int64Ptr(smokeTestDeadline),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"k8s-app": SmokeTestJobName,
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:            SmokeTestJobName,
+							Image:           images.BaremetalIronic,
+							Command:         []string{"python3", "-c", smokeTestScript},
+							ImagePullPolicy: "IfNotPresent",
+							SecurityContext: &corev1.SecurityContext{
+								Privileged: pointer.BoolPtr(false),
+							},
+							Env: []corev1.EnvVar{
+								{Name: "SMOKE_TEST_CHECKS", Value: string(checks)},
+								newIronicCredentialEnv("IRONIC_USERNAME", ironicUsernameKey),
+								newIronicCredentialEnv("IRONIC_PASSWORD", ironicPasswordKey),
+							},
+							TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+						},
+					},
+					ServiceAccountName: serviceAccountName,
+					NodeSelector:       GetMetal3NodeSelector(config),
+					Tolerations:        newMetal3Tolerations(config),
+				},
+			},
+		},
+	}
+	SetRolloutHash(job, rolloutHash)
+	return job, nil
+}
+
+// ParseSmokeTestReport returns the outcome of the checks reported by the
+// smoke test pod in its termination message
+func ParseSmokeTestReport(message string) ([]metal3iov1alpha1.SmokeTestCheck, error) {
+	report := struct {
+		Checks []metal3iov1alpha1.SmokeTestCheck `json:"checks"`
+	}{}
+	if err := json.Unmarshal([]byte(message), &report); err != nil {
+		return nil, fmt.Errorf("invalid smoke test report: %v", err)
+	}
+	if len(report.Checks) == 0 {
+		return nil, fmt.Errorf("smoke test reported no checks")
+	}
+	return report.Checks, nil
+}
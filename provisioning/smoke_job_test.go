@@ -0,0 +1,71 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestGetSmokeTestChecks(t *testing.T) {
+	spec := managedProvisioning()
+	assert.Equal(t, []smokeTestCheck{
+		{Name: "ironic-api", Method: "GET", URL: "http://10.0.0.5:6385/v1/nodes", Auth: true},
+		{Name: "ironic-inspector-api", Method: "GET", URL: "http://10.0.0.5:5050/"},
+		{Name: "deploy-kernel", Method: "HEAD", URL: "http://10.0.0.5:6180/images/ironic-python-agent.kernel"},
+		{Name: "deploy-ramdisk", Method: "HEAD", URL: "http://10.0.0.5:6180/images/ironic-python-agent.initramfs"},
+		{Name: "os-image", Method: "HEAD", URL: "http://10.0.0.5:6180/" + getCachedOSImageSubPath(spec)},
+	}, getSmokeTestChecks(spec, "10.0.0.5"))
+
+	// Ironic Inspector does not run with the Minimal profile
+	spec.Profile = metal3iov1alpha1.ProvisioningProfileMinimal
+	spec.ProvisioningOSDownloadURL = ""
+	names := []string{}
+	for _, check := range getSmokeTestChecks(spec, "fd00::5") {
+		names = append(names, check.Name)
+	}
+	assert.Equal(t, []string{"ironic-api", "deploy-kernel", "deploy-ramdisk"}, names)
+	assert.Equal(t, "http://[fd00::5]:6385/v1/nodes", getSmokeTestChecks(spec, "fd00::5")[0].URL)
+}
+
+func TestNewSmokeTestJob(t *testing.T) {
+	spec := managedProvisioning()
+	job, err := NewSmokeTestJob(testNamespace, &testImages, spec, "10.0.0.5", "0123456789abcdef")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, SmokeTestJobName, job.Name)
+	assert.Equal(t, "0123456789abcdef", job.Annotations[RolloutHashAnnotation])
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit)
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, expectedIronic, container.Image)
+	assert.Equal(t, corev1.TerminationMessageReadFile, container.TerminationMessagePolicy)
+	checks := []smokeTestCheck{}
+	assert.NoError(t, json.Unmarshal([]byte(envValue(&container, "SMOKE_TEST_CHECKS")), &checks))
+	assert.Equal(t, getSmokeTestChecks(spec, "10.0.0.5"), checks)
+	for _, env := range container.Env {
+		if env.Name == "IRONIC_PASSWORD" {
+			assert.Equal(t, ironicSecretName, env.ValueFrom.SecretKeyRef.Name)
+			assert.Equal(t, ironicPasswordKey, env.ValueFrom.SecretKeyRef.Key)
+		}
+	}
+}
+
+func TestParseSmokeTestReport(t *testing.T) {
+	checks, err := ParseSmokeTestReport(`{"checks": [{"name": "ironic-api", "passed": true}, {"name": "os-image", "passed": false, "message": "HTTP Error 404: Not Found"}]}`)
+	assert.NoError(t, err)
+	assert.Equal(t, []metal3iov1alpha1.SmokeTestCheck{
+		{Name: "ironic-api", Passed: true},
+		{Name: "os-image", Message: "HTTP Error 404: Not Found"},
+	}, checks)
+
+	_, err = ParseSmokeTestReport("")
+	assert.EqualError(t, err, "invalid smoke test report: unexpected end of JSON input")
+	_, err = ParseSmokeTestReport(`{"checks": []}`)
+	assert.EqualError(t, err, "smoke test reported no checks")
+}
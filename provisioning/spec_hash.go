@@ -0,0 +1,62 @@
+package provisioning
+
+import (
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// SpecHashAnnotation records on the operand resources the hash of the
+	// rendered object they were last updated from
+	SpecHashAnnotation = "baremetal.openshift.io/spec-hash"
+	// specGenerationAnnotation records the generation of the operand
+	// resources once updated from the rendered object. Any later change of
+	// their spec increments the generation past it.
+	specGenerationAnnotation = "baremetal.openshift.io/spec-generation"
+)
+
+// setSpecHash records on a rendered operand resource the hash of its spec
+// and of the metadata the operator sets
+func setSpecHash(obj metav1.Object, spec interface{}) error {
+	annotations := map[string]string{}
+	for key, value := range obj.GetAnnotations() {
+		if key != SpecHashAnnotation && key != specGenerationAnnotation {
+			annotations[key] = value
+		}
+	}
+	hash, err := GetRolloutHash(spec, obj.GetLabels(), annotations, obj.GetOwnerReferences())
+	if err != nil {
+		return err
+	}
+	annotations[SpecHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// isSpecApplied returns true when the existing operand resource was last
+// updated from a rendered object with the same hash as the desired one,
+// and its spec was not changed since. Comparing the hashes rather than the
+// objects avoids updates for differences the API server introduces, such
+// as defaulted fields.
+func isSpecApplied(existing metav1.Object, desired metav1.Object) bool {
+	annotations := existing.GetAnnotations()
+	return annotations[SpecHashAnnotation] == desired.GetAnnotations()[SpecHashAnnotation] &&
+		annotations[specGenerationAnnotation] == strconv.FormatInt(existing.GetGeneration(), 10)
+}
+
+// setSpecGeneration records the current generation of an operand resource
+// updated from a rendered object, and returns true when it changed
+func setSpecGeneration(obj metav1.Object) bool {
+	generation := strconv.FormatInt(obj.GetGeneration(), 10)
+	if obj.GetAnnotations()[specGenerationAnnotation] == generation {
+		return false
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[specGenerationAnnotation] = generation
+	obj.SetAnnotations(annotations)
+	return true
+}
@@ -0,0 +1,100 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+)
+
+// writeActions returns the verbs of the requests of the client that wrote
+// to the API server
+func writeActions(kubeClient *fakekube.Clientset) []string {
+	verbs := []string{}
+	for _, action := range kubeClient.Actions() {
+		if action.GetVerb() != "get" && action.GetVerb() != "list" && action.GetVerb() != "watch" {
+			verbs = append(verbs, action.GetVerb()+" "+action.GetResource().Resource)
+		}
+	}
+	return verbs
+}
+
+func TestSetSpecHash(t *testing.T) {
+	deployment := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning())
+	assert.NoError(t, setSpecHash(deployment, deployment.Spec))
+	hash := deployment.Annotations[SpecHashAnnotation]
+	assert.Len(t, hash, 16)
+
+	// The annotations of the operator are left out of the hash
+	deployment.Annotations[specGenerationAnnotation] = "3"
+	assert.NoError(t, setSpecHash(deployment, deployment.Spec))
+	assert.Equal(t, hash, deployment.Annotations[SpecHashAnnotation])
+
+	SetRolloutHash(deployment, "1234")
+	assert.NoError(t, setSpecHash(deployment, deployment.Spec))
+	assert.NotEqual(t, hash, deployment.Annotations[SpecHashAnnotation])
+}
+
+func TestApplyMetal3DeploymentWrites(t *testing.T) {
+	config := managedProvisioning()
+	kubeClient := fakekube.NewSimpleClientset()
+	_, err := ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"create deployments", "update deployments"}, writeActions(kubeClient))
+
+	kubeClient.ClearActions()
+	updated, err := ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.False(t, updated)
+	assert.Empty(t, writeActions(kubeClient), "an unchanged deployment should not be written")
+
+	// Fields defaulted by the API server are not rolled back
+	existing, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	existing.Spec.RevisionHistoryLimit = pointer.Int32Ptr(10)
+	existing.Spec.Template.Spec.SchedulerName = corev1.DefaultSchedulerName
+	existing.Spec.Template.Spec.Containers[0].TerminationMessagePath = corev1.TerminationMessagePathDefault
+	assert.NoError(t, kubeClient.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("deployments"), existing, testNamespace))
+	kubeClient.ClearActions()
+	updated, err = ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.False(t, updated)
+	assert.Empty(t, writeActions(kubeClient), "a defaulted deployment should not be written")
+
+	// A change of the spec by someone else is reverted
+	image := existing.Spec.Template.Spec.Containers[0].Image
+	existing.Spec.Template.Spec.Containers[0].Image = "example.com/ironic:modified"
+	existing.Generation++
+	assert.NoError(t, kubeClient.Tracker().Update(appsv1.SchemeGroupVersion.WithResource("deployments"), existing, testNamespace))
+	kubeClient.ClearActions()
+	updated, err = ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.True(t, updated)
+	reverted, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, image, reverted.Spec.Template.Spec.Containers[0].Image)
+
+	kubeClient.ClearActions()
+	updated, err = ApplyMetal3Deployment(kubeClient.AppsV1(), NewMetal3Deployment(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.False(t, updated)
+	assert.Empty(t, writeActions(kubeClient))
+}
+
+func TestApplyDnsmasqDaemonSetWrites(t *testing.T) {
+	config := managedProvisioning()
+	kubeClient := fakekube.NewSimpleClientset()
+	_, err := ApplyDnsmasqDaemonSet(kubeClient.AppsV1(), NewDnsmasqDaemonSet(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+
+	kubeClient.ClearActions()
+	updated, err := ApplyDnsmasqDaemonSet(kubeClient.AppsV1(), NewDnsmasqDaemonSet(testNamespace, &testImages, config))
+	assert.NoError(t, err)
+	assert.False(t, updated)
+	assert.Empty(t, writeActions(kubeClient), "an unchanged daemonset should not be written")
+}
@@ -0,0 +1,247 @@
+package provisioning
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// FieldError is a reason why a ProvisioningSpec is invalid
+type FieldError struct {
+	// Field is the path of the invalid field, such as
+	// spec.provisioningIP. Errors involving several fields report the
+	// one checked.
+	Field string
+	// Message describes the error, naming the fields involved
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// Warning is a setting of a valid ProvisioningSpec that is likely a
+// mistake, or not suitable for production
+type Warning struct {
+	// Field is the path of the field, such as spec.provisioningDHCPRange
+	Field string
+	// Message describes the issue
+	Message string
+}
+
+// specValidator checks a part of the spec. Its field is reported with the
+// errors that do not name one.
+type specValidator struct {
+	field    string
+	validate func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error
+}
+
+// specValidators run once the fields required by the provisioning
+// network mode are known to be set, in the order their errors are
+// reported
+var specValidators = []specValidator{
+	{"spec.provisioningInterface", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateInterfaceNames(&prov.Spec)
+	}},
+	{"spec.ironicAPIExposure", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateIronicAPIExposure(prov.Spec.IronicAPIExposure)
+	}},
+	{"spec.nodeSelector", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validatePlacement(&prov.Spec)
+	}},
+	{"spec.highAvailability", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateHighAvailability(&prov.Spec)
+	}},
+	{"spec.updateStrategy", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateUpdateStrategy(&prov.Spec)
+	}},
+	{"spec.ironicRPCTransport", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateIronicRPCTransport(&prov.Spec)
+	}},
+	{"spec.profile", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateProfile(prov)
+	}},
+	{"spec.conductorGroups", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateConductorGroups(&prov.Spec)
+	}},
+	{"spec.hardwareMetrics", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateHardwareMetrics(prov.Spec.HardwareMetrics)
+	}},
+	{"spec.inspectorRules", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateInspectorRulesConfig(&prov.Spec)
+	}},
+	{"spec.operandMetadata", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateOperandMetadata(prov.Spec.OperandMetadata)
+	}},
+	{"spec.imageCache", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateImageCache(prov.Spec.ImageCache)
+	}},
+	{"spec.osImageSignatureRef", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateOSImageSignatureRef(prov.Spec.OSImageSignatureRef)
+	}},
+	{"spec.imagePullSecretRef", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateImagePullSecretRef(prov.Spec.ImagePullSecretRef)
+	}},
+	{"spec.livePXEArtifacts", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateLivePXEArtifacts(prov.Spec.LivePXEArtifacts)
+	}},
+	{"spec.provisioningOSImage", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateProvisioningOSImage(&prov.Spec)
+	}},
+	{"spec.provisioningOSImage.mirrors", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateOSImageMirrors(&prov.Spec)
+	}},
+	{"spec.provisioningOSImage.delta", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateOSImageDelta(&prov.Spec)
+	}},
+	{"spec.imageServerMounts", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateImageServerMounts(prov.Spec.ImageServerMounts)
+	}},
+	{"spec.preStagedImagePVC", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validatePreStagedImage(&prov.Spec)
+	}},
+	{"spec.enableIgnitionOverrides", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateIgnitionOverrides(&prov.Spec)
+	}},
+	{"spec.ironicAPIAudit", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateIronicAPIAudit(&prov.Spec)
+	}},
+	{"spec.vendorExtensions", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateVendorExtensions(&prov.Spec)
+	}},
+	{"spec.firmwareUpdates", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateFirmwareUpdates(&prov.Spec)
+	}},
+	{"spec.defaultRAIDConfig", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateDefaultRAIDConfig(&prov.Spec)
+	}},
+	{"spec.biosProfiles", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateBIOSProfiles(&prov.Spec)
+	}},
+	{"spec.bmcProxy", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateBMCProxy(&prov.Spec)
+	}},
+	{"spec.bmcNetwork", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateBMCNetwork(&prov.Spec)
+	}},
+	{"spec.hostSelector", func(prov *metal3iov1alpha1.Provisioning, _ metal3iov1alpha1.ProvisioningNetwork) error {
+		if _, err := getHostLabelSelector(&prov.Spec); err != nil {
+			return fmt.Errorf("invalid HostSelector: %v", err)
+		}
+		return nil
+	}},
+	{"spec.provisioningInterfaceConfig", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateProvisioningInterfaceConfig(&prov.Spec, mode)
+	}},
+	{"spec.dhcpRelayRanges", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateDHCPRelayRanges(&prov.Spec, mode)
+	}},
+	{"spec.dhcpCircuitIDMappings", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateDHCPCircuitIDMappings(&prov.Spec, mode)
+	}},
+	{"spec.tftp", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateTFTPConfig(&prov.Spec, mode)
+	}},
+	{"spec.secureBoot", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateSecureBoot(&prov.Spec, mode)
+	}},
+	{"spec.uefiBootLoader", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateUEFIBootLoader(&prov.Spec, mode)
+	}},
+	{"spec.provisioningNetworkCIDR", validateProvisioningAddresses},
+	{"spec.provisioningIPPool", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateProvisioningIPPool(&prov.Spec, mode)
+	}},
+	{"spec.provisioningDHCPRange", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateDHCPRangeExclusions(&prov.Spec, mode)
+	}},
+	{"spec.provisioningVIP", func(prov *metal3iov1alpha1.Provisioning, mode metal3iov1alpha1.ProvisioningNetwork) error {
+		return validateProvisioningVIP(&prov.Spec, mode)
+	}},
+}
+
+// ValidateSpecDetailed runs the validation of the operator on a
+// ProvisioningSpec, for tools generating one to check it before it is
+// applied. It returns every error found instead of the first one, and
+// the warnings returned by the webhook, whether there are errors or not,
+// so that all the findings are reported at once. The spec is not
+// modified. The
+// checks depending on the cluster, such as its platform and feature set,
+// are not run.
+func ValidateSpecDetailed(spec *metal3iov1alpha1.ProvisioningSpec) ([]FieldError, []Warning) {
+	normalized := spec.DeepCopy()
+	if err := normalizeProvisioningIP(normalized); err != nil {
+		return []FieldError{newFieldError("spec.provisioningIP", err)}, getConfigWarnings(spec)
+	}
+	if err := normalizeProvisioningOSImage(normalized); err != nil {
+		return []FieldError{newFieldError("spec.provisioningOSImage", err)}, getConfigWarnings(spec)
+	}
+	errs := validateSpecFields(&metal3iov1alpha1.Provisioning{Spec: *normalized})
+	if len(errs) == 0 {
+		errs = nil
+	}
+	return errs, getConfigWarnings(normalized)
+}
+
+// validateSpecFields returns the errors of the spec, in the order they
+// are checked
+func validateSpecFields(prov *metal3iov1alpha1.Provisioning) []FieldError {
+	mode := getProvisioningNetworkMode(prov)
+	log.V(1).Info("provisioning network", "mode", mode)
+	var err error
+	switch mode {
+	case metal3iov1alpha1.ProvisioningNetworkManaged:
+		err = validateManagedConfig(prov)
+	case metal3iov1alpha1.ProvisioningNetworkUnmanaged:
+		err = validateUnmanagedConfig(prov)
+	case metal3iov1alpha1.ProvisioningNetworkDisabled:
+		err = validateDisabledConfig(prov)
+	case metal3iov1alpha1.ProvisioningNetworkAuto:
+		err = validateAutoConfig(prov)
+		// The rest is validated as the Managed configuration the network
+		// resolves to, whichever interface is detected
+		prov = &metal3iov1alpha1.Provisioning{
+			ObjectMeta: prov.ObjectMeta,
+			Spec:       *ResolveAutoProvisioningNetwork(&prov.Spec, prov.Spec.ProvisioningInterface),
+		}
+		mode = metal3iov1alpha1.ProvisioningNetworkManaged
+	}
+	if err != nil {
+		// The other checks assume the required fields are set
+		return []FieldError{newFieldError("spec.provisioningNetwork", err)}
+	}
+
+	errs := []FieldError{}
+	for _, validator := range specValidators {
+		if err := validator.validate(prov, mode); err != nil {
+			errs = append(errs, newFieldError(validator.field, err))
+		}
+	}
+	return errs
+}
+
+// newFieldError returns err as a FieldError, of the given field unless it
+// names its own
+func newFieldError(field string, err error) FieldError {
+	var fieldErr FieldError
+	if errors.As(err, &fieldErr) {
+		return fieldErr
+	}
+	return FieldError{Field: field, Message: err.Error()}
+}
+
+// fieldErrorf returns an error of the given spec field
+func fieldErrorf(field, format string, args ...interface{}) error {
+	return FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// requiredFieldError reports that the spec field of the given Go name is
+// required but not set
+func requiredFieldError(name string) error {
+	return FieldError{
+		Field:   "spec." + strings.ToLower(name[:1]) + name[1:],
+		Message: fmt.Sprintf("%s is required but is empty", name),
+	}
+}
@@ -0,0 +1,122 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateSpecDetailed(t *testing.T) {
+	tlsWarning := Warning{
+		Field: "spec.provisioningOSDownloadURL",
+		Message: "ProvisioningOSDownloadURL \"http://172.22.0.1/images/rhcos-44.81.202001171431.0-openstack.x86_64.qcow2.gz?" +
+			"sha256=e98f83a2b9d4043719664a2be75fe8134dc6ca1fdbde807996622f8cc7ecd234\" is not downloaded over TLS",
+	}
+	tCases := []struct {
+		name             string
+		spec             func() *metal3iov1alpha1.ProvisioningSpec
+		expectedErrors   []FieldError
+		expectedWarnings []Warning
+	}{
+		{
+			name:             "Valid",
+			spec:             managedProvisioning,
+			expectedWarnings: []Warning{tlsWarning},
+		},
+		{
+			name: "MissingRequiredField",
+			spec: func() *metal3iov1alpha1.ProvisioningSpec {
+				spec := managedProvisioning()
+				spec.ProvisioningNetworkCIDR = ""
+				return spec
+			},
+			expectedErrors: []FieldError{
+				{Field: "spec.provisioningNetworkCIDR", Message: "ProvisioningNetworkCIDR is required but is empty"},
+			},
+			expectedWarnings: []Warning{tlsWarning},
+		},
+		{
+			name: "SeveralErrors",
+			spec: func() *metal3iov1alpha1.ProvisioningSpec {
+				spec := managedProvisioning()
+				spec.ProvisioningDHCPRange = "172.30.20.101, 172.30.20.11"
+				spec.HostSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: "Near"},
+				}}
+				return spec
+			},
+			expectedErrors: []FieldError{
+				{Field: "spec.hostSelector", Message: "invalid HostSelector: \"Near\" is not a valid pod selector operator"},
+				{Field: "spec.provisioningDHCPRange", Message: "ProvisioningDHCPRange \"172.30.20.101, 172.30.20.11\" starts after its end"},
+			},
+			expectedWarnings: []Warning{tlsWarning},
+		},
+		{
+			name: "AddressOutsideNetwork",
+			spec: func() *metal3iov1alpha1.ProvisioningSpec {
+				spec := managedProvisioning()
+				spec.ProvisioningIP = "172.30.21.3"
+				return spec
+			},
+			expectedErrors: []FieldError{
+				{Field: "spec.provisioningIP", Message: "ProvisioningIP \"172.30.21.3\" is not in the ProvisioningNetworkCIDR \"172.30.20.0/24\""},
+			},
+			expectedWarnings: []Warning{tlsWarning},
+		},
+		{
+			name: "AutoModeAddressWithoutNetwork",
+			spec: func() *metal3iov1alpha1.ProvisioningSpec {
+				spec := managedProvisioning()
+				spec.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkAuto
+				spec.ProvisioningNetworkCIDR = ""
+				return spec
+			},
+			expectedErrors: []FieldError{
+				{Field: "spec.provisioningNetworkCIDR", Message: "ProvisioningIP and ProvisioningNetworkCIDR must be set together in Auto mode"},
+			},
+			expectedWarnings: []Warning{tlsWarning},
+		},
+		{
+			name: "ErrorsAndWarnings",
+			spec: func() *metal3iov1alpha1.ProvisioningSpec {
+				spec := managedProvisioning()
+				spec.ProvisioningDHCPRange = "172.30.20.11, 172.30.20.20"
+				spec.HostSelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "rack", Operator: "Near"},
+				}}
+				return spec
+			},
+			expectedErrors: []FieldError{
+				{Field: "spec.hostSelector", Message: "invalid HostSelector: \"Near\" is not a valid pod selector operator"},
+			},
+			expectedWarnings: []Warning{
+				{
+					Field:   "spec.provisioningDHCPRange",
+					Message: "ProvisioningDHCPRange \"172.30.20.11, 172.30.20.20\" only holds 10 addresses, hosts may fail to get a lease",
+				},
+				tlsWarning,
+			},
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := tc.spec()
+			original := spec.DeepCopy()
+			errs, warnings := ValidateSpecDetailed(spec)
+			assert.Equal(t, tc.expectedErrors, errs)
+			assert.Equal(t, tc.expectedWarnings, warnings)
+			assert.Equal(t, original, spec, "the spec must not be modified")
+
+			// The operator reports the first error
+			err := ValidateBaremetalProvisioningConfig(&metal3iov1alpha1.Provisioning{Spec: *spec})
+			if len(tc.expectedErrors) == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErrors[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package provisioning
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// downloadedMarkersDir holds the marker each downloader running
+	// alongside the metal3 services writes once its download has
+	// completed. It is on the shared volume, so that the services serving
+	// the images wait for them.
+	downloadedMarkersDir = "/shared/downloaded"
+
+	// StartupPhaseInit covers the init containers, from the start of the
+	// pod until it is initialized
+	StartupPhaseInit = "init"
+	// StartupPhaseContainers covers the downloads, the database migration
+	// and the startup of the metal3 services, from the end of the init
+	// containers until the pod is ready. The services not serving the
+	// images start along with the downloads, the others once they are
+	// done.
+	StartupPhaseContainers = "containers"
+	// StartupPhaseTotal covers the whole startup of the pod
+	StartupPhaseTotal = "total"
+)
+
+// StartupPhase is a step of the startup of a metal3 pod
+type StartupPhase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// needsOSImagePreparation returns true when the OS image has to be
+// verified or converted once downloaded. The download then has to
+// complete before the pod starts, as the steps run in order as init
+// containers.
+func needsOSImagePreparation(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.OSImageSignatureRef != nil || config.ConvertOSImageToRaw
+}
+
+// waitForDownloadsScript starts the service of a container once the
+// downloaders running alongside it have written their markers
+const waitForDownloadsScript = `for marker in $DOWNLOADED_MARKERS; do
+    until [ -f "$marker" ]; do
+        sleep 1
+    done
+done
+exec "$@"`
+
+// downloadWaiters are the containers serving the downloaded images, to
+// the hosts or to Ironic, which must not start before they are complete
+var downloadWaiters = map[string]bool{
+	"metal3-httpd":               true,
+	ironicConductorContainerName: true,
+}
+
+// getDownloadedMarker returns the marker written by a downloader once its
+// download has completed
+func getDownloadedMarker(container *corev1.Container) string {
+	return path.Join(downloadedMarkersDir, container.Name)
+}
+
+// runAlongside turns a downloader init container into a regular container,
+// so that the download runs in parallel with the other downloads and the
+// startup of the metal3 services that do not serve the images. Containers
+// of a pod cannot exit, so the downloader stays idle once done, and
+// reports ready from then on. A failed download restarts the container,
+// and shows as a crash loop. Shell scripts are run in a subshell, where
+// their exit ends the download only.
+func runAlongside(container corev1.Container) corev1.Container {
+	download := strings.Join(container.Command, " ")
+	if len(container.Command) == 3 && container.Command[0] == "/bin/sh" && container.Command[1] == "-c" {
+		download = "(" + container.Command[2] + ")"
+	}
+	marker := getDownloadedMarker(&container)
+	container.Command = []string{"/bin/sh", "-c",
+		"set -e; " + download + "; mkdir -p " + downloadedMarkersDir + "; touch " + marker + "; exec sleep infinity"}
+	container.ReadinessProbe = &corev1.Probe{
+		Handler: corev1.Handler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"test", "-f", marker},
+			},
+		},
+		PeriodSeconds: 5,
+	}
+	return container
+}
+
+// waitForDownloads delays the start of the containers serving the images
+// until the given downloaders, run alongside them, are done. The metal3
+// pods use the host network, so that the pod not being ready does not
+// keep the hosts from reaching Ironic and the image server before then.
+func waitForDownloads(containers []corev1.Container, downloaders []corev1.Container) {
+	markers := []string{}
+	for i := range downloaders {
+		markers = append(markers, getDownloadedMarker(&downloaders[i]))
+	}
+	for i := range containers {
+		container := &containers[i]
+		if !downloadWaiters[container.Name] || len(markers) == 0 {
+			continue
+		}
+		container.Command = append([]string{"/bin/sh", "-c", waitForDownloadsScript, "wait-for-downloads"}, container.Command...)
+		container.Env = append(container.Env, corev1.EnvVar{
+			Name:  "DOWNLOADED_MARKERS",
+			Value: strings.Join(markers, " "),
+		})
+	}
+}
+
+// downloadsOSImageAlongside returns true when the OS image is downloaded
+// while the metal3 services run
+func downloadsOSImageAlongside(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	// A pre-staged image is served as it is
+	return !needsOSImagePreparation(config) && config.PreStagedImagePVC == ""
+}
+
+// newMetal3DownloaderContainers returns the downloaders that do not have
+// to complete before the init containers are done. The containers serving
+// the images wait for them with waitForDownloads.
+func newMetal3DownloaderContainers(images *Images, config *metal3iov1alpha1.ProvisioningSpec) []corev1.Container {
+	containers := []corev1.Container{
+		runAlongside(createInitContainerIpaDownloader(images, config)),
+	}
+	if downloadsOSImageAlongside(config) {
+		containers = append(containers, runAlongside(createInitContainerMachineOsDownloader(images, config)))
+	}
+	if config.LivePXEArtifacts != nil {
+		containers = append(containers, runAlongside(createContainerMetal3LivePXEDownloader(images, config)))
+	}
+	return containers
+}
+
+// GetStartupPhases returns how long each init container, and each phase of
+// the startup of a pod, took. It returns false until the pod is ready.
+func GetStartupPhases(pod *corev1.Pod) ([]StartupPhase, bool) {
+	if pod.Status.StartTime == nil {
+		return nil, false
+	}
+	var initialized, ready *corev1.PodCondition
+	for i := range pod.Status.Conditions {
+		condition := &pod.Status.Conditions[i]
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case corev1.PodInitialized:
+			initialized = condition
+		case corev1.PodReady:
+			ready = condition
+		}
+	}
+	if initialized == nil || ready == nil {
+		return nil, false
+	}
+
+	phases := []StartupPhase{}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil {
+			phases = append(phases, StartupPhase{
+				Name:     status.Name,
+				Duration: terminated.FinishedAt.Sub(terminated.StartedAt.Time),
+			})
+		}
+	}
+	start := pod.Status.StartTime.Time
+	return append(phases,
+		StartupPhase{Name: StartupPhaseInit, Duration: initialized.LastTransitionTime.Sub(start)},
+		StartupPhase{Name: StartupPhaseContainers, Duration: ready.LastTransitionTime.Sub(initialized.LastTransitionTime.Time)},
+		StartupPhase{Name: StartupPhaseTotal, Duration: ready.LastTransitionTime.Sub(start)},
+	), true
+}
@@ -0,0 +1,141 @@
+package provisioning
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestNewMetal3DownloaderContainers(t *testing.T) {
+	signed := managedProvisioning()
+	signed.OSImageSignatureRef = &metal3iov1alpha1.OSImageSignatureRef{
+		Type:         metal3iov1alpha1.OSImageSignatureGPG,
+		SignatureURL: "https://mirror.example.com/rhcos.qcow2.sig",
+		KeyConfigMap: "rhcos-signing-key",
+	}
+
+	tCases := []struct {
+		name                string
+		config              *metal3iov1alpha1.ProvisioningSpec
+		expectedContainers  []string
+		expectedInitOSImage bool
+	}{
+		{
+			name:               "Parallel",
+			config:             managedProvisioning(),
+			expectedContainers: []string{"metal3-ipa-downloader", "metal3-machine-os-downloader"},
+		},
+		{
+			name:                "VerifiedOSImage",
+			config:              signed,
+			expectedContainers:  []string{"metal3-ipa-downloader"},
+			expectedInitOSImage: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			containers := newMetal3DownloaderContainers(&testImages, tc.config)
+			assert.Equal(t, tc.expectedContainers, containerNames(containers))
+			markers := []string{}
+			for _, container := range containers {
+				marker := "/shared/downloaded/" + container.Name
+				markers = append(markers, marker)
+				assert.Equal(t, []string{"/bin/sh", "-c",
+					"set -e; /usr/local/bin/get-resource.sh; mkdir -p /shared/downloaded; touch " + marker + "; exec sleep infinity"}, container.Command)
+				if assert.NotNil(t, container.ReadinessProbe) {
+					assert.Equal(t, []string{"test", "-f", marker}, container.ReadinessProbe.Exec.Command)
+				}
+			}
+			initOSImage := findContainer(newMetal3InitContainers(&testImages, tc.config), "metal3-machine-os-downloader")
+			assert.Equal(t, tc.expectedInitOSImage, initOSImage != nil)
+
+			// The images are only served once downloaded, the other
+			// services start alongside the downloads
+			metal3Containers := newMetal3Containers(&testImages, tc.config)
+			for _, name := range []string{"metal3-httpd", "metal3-ironic-conductor"} {
+				container := findContainer(metal3Containers, name)
+				if assert.NotNil(t, container, name) {
+					assert.Equal(t, waitForDownloadsScript, container.Command[2], name)
+					assert.Equal(t, strings.Join(markers, " "), envValue(container, "DOWNLOADED_MARKERS"), name)
+				}
+			}
+			assert.Equal(t, []string{"/baremetal-operator"}, findContainer(metal3Containers, "metal3-baremetal-operator").Command)
+		})
+	}
+}
+
+// waitingForDownloads returns the command of a container serving the
+// images once wrapped by waitForDownloads
+func waitingForDownloads(command ...string) []string {
+	return append([]string{"/bin/sh", "-c", waitForDownloadsScript, "wait-for-downloads"}, command...)
+}
+
+func TestGetStartupPhases(t *testing.T) {
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) metav1.Time {
+		return metav1.NewTime(start.Add(time.Duration(seconds) * time.Second))
+	}
+	startTime := at(0)
+	initStatuses := []corev1.ContainerStatus{
+		{
+			Name: "metal3-static-ip-set",
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{StartedAt: at(2), FinishedAt: at(5)},
+			},
+		},
+	}
+
+	tCases := []struct {
+		name           string
+		status         corev1.PodStatus
+		expectedPhases []StartupPhase
+		expectedReady  bool
+	}{
+		{
+			name:   "NotStarted",
+			status: corev1.PodStatus{},
+		},
+		{
+			name: "NotReady",
+			status: corev1.PodStatus{
+				StartTime:             &startTime,
+				InitContainerStatuses: initStatuses,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodInitialized, Status: corev1.ConditionTrue, LastTransitionTime: at(6)},
+					{Type: corev1.PodReady, Status: corev1.ConditionFalse, LastTransitionTime: at(6)},
+				},
+			},
+		},
+		{
+			name: "Ready",
+			status: corev1.PodStatus{
+				StartTime:             &startTime,
+				InitContainerStatuses: initStatuses,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodInitialized, Status: corev1.ConditionTrue, LastTransitionTime: at(6)},
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: at(96)},
+				},
+			},
+			expectedPhases: []StartupPhase{
+				{Name: "metal3-static-ip-set", Duration: 3 * time.Second},
+				{Name: StartupPhaseInit, Duration: 6 * time.Second},
+				{Name: StartupPhaseContainers, Duration: 90 * time.Second},
+				{Name: StartupPhaseTotal, Duration: 96 * time.Second},
+			},
+			expectedReady: true,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			phases, ready := GetStartupPhases(&corev1.Pod{Status: tc.status})
+			assert.Equal(t, tc.expectedReady, ready)
+			assert.Equal(t, tc.expectedPhases, phases)
+		})
+	}
+}
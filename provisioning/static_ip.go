@@ -0,0 +1,163 @@
+package provisioning
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	// StaticIPManagerContainerName is the container keeping the
+	// ProvisioningIP configured on the provisioning interface
+	StaticIPManagerContainerName = "metal3-static-ip-manager"
+
+	staticIPStateVolume   = "metal3-static-ip-state"
+	staticIPStateHostPath = "/var/lib/metal3/static-ip"
+	staticIPStateEnvVar   = "STATIC_IP_STATE_FILE"
+
+	// staticIPRepairInterval is how often the static IP manager checks
+	// that the ProvisioningIP is still configured
+	staticIPRepairInterval = "30"
+)
+
+var staticIPStateMount = corev1.VolumeMount{
+	Name:      staticIPStateVolume,
+	MountPath: staticIPStateHostPath,
+}
+
+// staticIPFunctions configure the addresses of the node. Each address is
+// recorded on the node, in a file of each metal3 Deployment so that the
+// provisioning domains do not remove each other's addresses, and the
+// address recorded by a previous metal3 pod is removed when it is not the
+// one to configure anymore.
+const staticIPFunctions = `
+record_address() {
+    local state_file=$1 address=$2 previous_ip="" previous_interface=""
+    if [ -f "$state_file" ]; then
+        read -r previous_ip previous_interface < "$state_file" || true
+    fi
+    if [ -n "$previous_ip" ] && [ "$previous_ip $previous_interface" != "$address $PROVISIONING_INTERFACE" ]; then
+        echo "removing stale provisioning IP $previous_ip from $previous_interface"
+        ip address del "$previous_ip" dev "$previous_interface" || true
+    fi
+    if [ -n "$address" ]; then
+        echo "$address $PROVISIONING_INTERFACE" > "$state_file"
+    else
+        rm -f "$state_file"
+    fi
+}
+
+configure_node_ip() {
+    local node_ip=""
+    if [ -n "${NODE_IP_FILE:-}" ] && [ -f "$NODE_IP_FILE" ]; then
+        read -r node_ip < "$NODE_IP_FILE" || true
+    fi
+    record_address "$STATIC_IP_STATE_FILE.node" "$node_ip"
+    if [ -n "$node_ip" ] && ! ip -o address show dev "$PROVISIONING_INTERFACE" | grep -q " $node_ip "; then
+        echo "configuring node provisioning IP $node_ip on $PROVISIONING_INTERFACE"
+        ip address add "$node_ip" dev "$PROVISIONING_INTERFACE"
+    fi
+}
+`
+
+// staticIPSetScript removes the addresses a previous metal3 pod
+// configured on the node, when the ProvisioningIP, interface or address
+// of the node changed since, before configuring the current ones
+const staticIPSetScript = staticIPFunctions + `
+set -eu
+record_address "$STATIC_IP_STATE_FILE" "$PROVISIONING_IP"
+configure_node_ip
+exec /set-static-ip
+`
+
+// staticIPManagerScript configures the ProvisioningIP again when it was
+// removed from the provisioning interface, as /refresh-static-ip only
+// extends the lifetime of an existing address. The address of the node,
+// when allocated from the ProvisioningIPPool, is kept configured as well
+// and follows the changes of the allocation. Nothing is configured
+// without a provisioning interface.
+const staticIPManagerScript = staticIPFunctions + `
+while [ -n "$PROVISIONING_INTERFACE" ] && sleep ${STATIC_IP_REPAIR_INTERVAL}; do
+    if ! ip -o address show dev "$PROVISIONING_INTERFACE" | grep -q " $PROVISIONING_IP "; then
+        echo "provisioning IP $PROVISIONING_IP is missing from $PROVISIONING_INTERFACE, configuring it again"
+        /set-static-ip || true
+    fi
+    if [ -n "${NODE_IP_FILE:-}" ]; then
+        configure_node_ip || true
+    fi
+done &
+exec /refresh-static-ip
+`
+
+// staticIPConfiguredCommand succeeds when the ProvisioningIP is
+// configured on the provisioning interface, or there is no provisioning
+// interface
+var staticIPConfiguredCommand = []string{"/bin/bash", "-c",
+	`[ -z "$PROVISIONING_INTERFACE" ] || ip -o address show dev "$PROVISIONING_INTERFACE" | grep -q " $PROVISIONING_IP "`}
+
+// newStaticIPStateVolume returns the node directory the addresses
+// configured by the metal3 pods are recorded in
+func newStaticIPStateVolume() corev1.Volume {
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	return corev1.Volume{
+		Name: staticIPStateVolume,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: staticIPStateHostPath,
+				Type: &hostPathType,
+			},
+		},
+	}
+}
+
+// staticIPStateEnv names the state file after the controller label of
+// the pod, which tells the metal3 Deployments apart
+func staticIPStateEnv() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name: "METAL3_CONTROLLER",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.labels['controller']",
+				},
+			},
+		},
+		{
+			Name:  staticIPStateEnvVar,
+			Value: staticIPStateHostPath + "/$(METAL3_CONTROLLER)",
+		},
+	}
+}
+
+// newStaticIPConfiguredProbe reports the static IP manager as not ready
+// while the ProvisioningIP is missing from the provisioning interface
+func newStaticIPConfiguredProbe() *corev1.Probe {
+	return &corev1.Probe{
+		Handler: corev1.Handler{
+			Exec: &corev1.ExecAction{Command: staticIPConfiguredCommand},
+		},
+		PeriodSeconds:    10,
+		FailureThreshold: 3,
+	}
+}
+
+// addNodeAddresses lets a static IP container find the addresses the
+// operator assigned to its node, from the ProvisioningIPPool and as node
+// of the active metal3 pod
+func addNodeAddresses(container *corev1.Container, config *metal3iov1alpha1.ProvisioningSpec) {
+	if !IsActivePassive(config) {
+		return
+	}
+	container.Env = append(container.Env, corev1.EnvVar{
+		Name: "NODE_NAME",
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{
+				FieldPath: "spec.nodeName",
+			},
+		},
+	})
+	if HasProvisioningIPPool(config) {
+		addProvisioningIPPool(container)
+	}
+	addProvisioningVIP(container, config)
+}
@@ -0,0 +1,38 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestStaticIPContainers(t *testing.T) {
+	podSpec := NewMetal3Deployment(testNamespace, &testImages, managedProvisioning()).Spec.Template.Spec
+
+	set := findContainer(podSpec.InitContainers, "metal3-static-ip-set")
+	if assert.NotNil(t, set) {
+		assert.Equal(t, []string{"/bin/bash", "-c", staticIPSetScript}, set.Command)
+		assert.Contains(t, set.VolumeMounts, staticIPStateMount)
+		assert.Equal(t, "/var/lib/metal3/static-ip/$(METAL3_CONTROLLER)", envValue(set, staticIPStateEnvVar))
+		assert.Equal(t, "172.30.20.3/24", envValue(set, provisioningIP))
+	}
+
+	manager := findContainer(podSpec.Containers, StaticIPManagerContainerName)
+	if assert.NotNil(t, manager) {
+		assert.Equal(t, []string{"/bin/bash", "-c", staticIPManagerScript}, manager.Command)
+		if assert.NotNil(t, manager.ReadinessProbe) {
+			assert.Equal(t, staticIPConfiguredCommand, manager.ReadinessProbe.Exec.Command)
+		}
+	}
+
+	var state *corev1.Volume
+	for i := range podSpec.Volumes {
+		if podSpec.Volumes[i].Name == staticIPStateVolume {
+			state = &podSpec.Volumes[i]
+		}
+	}
+	if assert.NotNil(t, state) && assert.NotNil(t, state.HostPath) {
+		assert.Equal(t, "/var/lib/metal3/static-ip", state.HostPath.Path)
+	}
+}
@@ -0,0 +1,161 @@
+package provisioning
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// SupportBundleConfigMapName is the ConfigMap the support bundle is
+	// written to, in its support-bundle.tar.gz key
+	SupportBundleConfigMapName = "metal3-support-bundle"
+	supportBundleKey           = "support-bundle.tar.gz"
+	// MaxSupportBundleSize keeps the compressed bundle below the size
+	// limit of a ConfigMap, with room for its metadata
+	MaxSupportBundleSize = 900 * 1024
+
+	redacted = "REDACTED"
+)
+
+var (
+	// urlCredentials matches the user information of URLs, such as
+	// credentials of a ProvisioningOSDownloadURL mirror
+	urlCredentials = regexp.MustCompile(`://[^/@\s:]+:[^/@\s]+@`)
+	// sensitiveEnvNames are the parts of the names of environment
+	// variables whose values are never added to the support bundle
+	sensitiveEnvNames = []string{"PASSWORD", "SECRET", "TOKEN", "HTPASSWD", "CREDENTIALS"}
+)
+
+// SupportBundleFile is a file of the support bundle
+type SupportBundleFile struct {
+	Name string
+	Data []byte
+}
+
+// RedactPodSpec removes from a pod spec the values of the environment
+// variables that may hold credentials. Values read from Secrets are only
+// references, and are kept.
+func RedactPodSpec(spec *corev1.PodSpec) {
+	for _, containers := range [][]corev1.Container{spec.InitContainers, spec.Containers} {
+		for i := range containers {
+			for j := range containers[i].Env {
+				env := &containers[i].Env[j]
+				if env.Value != "" && isSensitiveEnvName(env.Name) {
+					env.Value = redacted
+				}
+			}
+		}
+	}
+}
+
+func isSensitiveEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, sensitive := range sensitiveEnvNames {
+		if strings.Contains(upper, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeSupportBundleData removes the credentials embedded in URLs
+func sanitizeSupportBundleData(data []byte) []byte {
+	return urlCredentials.ReplaceAll(data, []byte("://"+redacted+"@"))
+}
+
+// NewSupportBundle returns the sanitized files as a gzipped tarball
+func NewSupportBundle(files []SupportBundleFile, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		data := sanitizeSupportBundleData(file.Data)
+		header := &tar.Header{
+			Name:    file.Name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ReadSupportBundle returns the files of a support bundle by name
+func ReadSupportBundle(bundle []byte) (map[string]string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = string(data)
+	}
+}
+
+// NewSupportBundleConfigMap returns the ConfigMap holding a support bundle
+func NewSupportBundleConfigMap(targetNamespace string, bundle []byte) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SupportBundleConfigMapName,
+			Namespace: targetNamespace,
+		},
+		BinaryData: map[string][]byte{
+			supportBundleKey: bundle,
+		},
+	}
+}
+
+// ApplySupportBundleConfigMap creates the support bundle ConfigMap, or
+// replaces the previous bundle
+func ApplySupportBundleConfigMap(client coreclientv1.ConfigMapsGetter, configMap *corev1.ConfigMap) error {
+	existing, err := client.ConfigMaps(configMap.Namespace).Get(context.Background(), configMap.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.ConfigMaps(configMap.Namespace).Create(context.Background(), configMap, metav1.CreateOptions{})
+		return apiError(err)
+	}
+	if err != nil {
+		return apiError(err)
+	}
+	updated := existing.DeepCopy()
+	updated.OwnerReferences = configMap.OwnerReferences
+	updated.Data = nil
+	updated.BinaryData = configMap.BinaryData
+	_, err = client.ConfigMaps(configMap.Namespace).Update(context.Background(), updated, metav1.UpdateOptions{})
+	return apiError(err)
+}
@@ -0,0 +1,29 @@
+package provisioning
+
+import (
+	"context"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	appsclientv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// IsProvisioningEnabled returns true unless the spec asks for the metal3
+// stack to be torn down
+func IsProvisioningEnabled(config *metal3iov1alpha1.ProvisioningSpec) bool {
+	return config.Enabled == nil || *config.Enabled
+}
+
+// DeleteMetal3Deployment removes the metal3 Deployment, if it exists. Its
+// pods go with it.
+func DeleteMetal3Deployment(client appsclientv1.DeploymentsGetter, targetNamespace string) error {
+	propagation := metav1.DeletePropagationBackground
+	err := client.Deployments(targetNamespace).Delete(context.Background(), baremetalDeploymentName,
+		metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return apiError(err)
+}
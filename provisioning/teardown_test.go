@@ -0,0 +1,31 @@
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestIsProvisioningEnabled(t *testing.T) {
+	assert.True(t, IsProvisioningEnabled(&metal3iov1alpha1.ProvisioningSpec{}))
+	assert.True(t, IsProvisioningEnabled(&metal3iov1alpha1.ProvisioningSpec{Enabled: pointer.BoolPtr(true)}))
+	assert.False(t, IsProvisioningEnabled(&metal3iov1alpha1.ProvisioningSpec{Enabled: pointer.BoolPtr(false)}))
+}
+
+func TestDeleteMetal3Deployment(t *testing.T) {
+	kubeClient := fakekube.NewSimpleClientset()
+	// Nothing to delete before the first deployment
+	assert.NoError(t, DeleteMetal3Deployment(kubeClient.AppsV1(), testNamespace))
+
+	kubeClient = fakekube.NewSimpleClientset(NewMetal3Deployment(testNamespace, &testImages, managedProvisioning()))
+	assert.NoError(t, DeleteMetal3Deployment(kubeClient.AppsV1(), testNamespace))
+	_, err := kubeClient.AppsV1().Deployments(testNamespace).Get(context.Background(), baremetalDeploymentName, metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
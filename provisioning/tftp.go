@@ -0,0 +1,83 @@
+package provisioning
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+const (
+	tftpConfigEnvVar    = "TFTP_CONFIG"
+	tftpFilesVolume     = "metal3-tftp-files"
+	tftpFilesMountPath  = "/etc/metal3-tftp"
+	tftpMinBlockSize    = 512
+	tftpMaxBlockSize    = 65464
+	tftpBlockSizeHeader = 32
+)
+
+// validateTFTPConfig checks the TFTP server settings
+func validateTFTPConfig(config *metal3iov1alpha1.ProvisioningSpec, mode metal3iov1alpha1.ProvisioningNetwork) error {
+	tftp := config.TFTP
+	if tftp == nil {
+		return nil
+	}
+	if mode != metal3iov1alpha1.ProvisioningNetworkManaged {
+		return fmt.Errorf("TFTP is only supported when the ProvisioningNetwork is Managed")
+	}
+	if tftp.FilesConfigMap != "" {
+		if errs := validation.IsDNS1123Subdomain(tftp.FilesConfigMap); len(errs) > 0 {
+			return fmt.Errorf("invalid TFTP.FilesConfigMap %q: %s", tftp.FilesConfigMap, strings.Join(errs, ", "))
+		}
+	}
+	if tftp.MaxBlockSize != 0 && (tftp.MaxBlockSize < tftpMinBlockSize || tftp.MaxBlockSize > tftpMaxBlockSize) {
+		return fmt.Errorf("TFTP.MaxBlockSize must be between %d and %d", tftpMinBlockSize, tftpMaxBlockSize)
+	}
+	if tftp.MaxBlockSize != 0 && tftp.DisableBlockSizeNegotiation {
+		return fmt.Errorf("TFTP.MaxBlockSize cannot be set when TFTP.DisableBlockSizeNegotiation is")
+	}
+	return nil
+}
+
+// getTFTPConfig returns the dnsmasq configuration of the TFTP server. The
+// files of the FilesConfigMap are served from their own root, which
+// dnsmasq prefers for the requests received on the provisioning
+// interface; the root populated by dnsmasq from the metal3 image is then
+// left unused. The block size is capped through the MTU dnsmasq assumes,
+// which includes the headers of the TFTP packets.
+func getTFTPConfig(config *metal3iov1alpha1.ProvisioningSpec) string {
+	tftp := config.TFTP
+	if tftp == nil {
+		return ""
+	}
+	lines := []string{}
+	if tftp.FilesConfigMap != "" {
+		lines = append(lines, fmt.Sprintf("tftp-root=%s,%s", tftpFilesMountPath, config.ProvisioningInterface))
+	}
+	if tftp.MaxBlockSize != 0 {
+		lines = append(lines, fmt.Sprintf("tftp-mtu=%d", tftp.MaxBlockSize+tftpBlockSizeHeader))
+	}
+	if tftp.DisableBlockSizeNegotiation {
+		lines = append(lines, "tftp-no-blocksize")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newTFTPFilesVolume returns the volume of the files served by the TFTP
+// server, or nil when the files of the metal3 image are served
+func newTFTPFilesVolume(config *metal3iov1alpha1.ProvisioningSpec) *corev1.Volume {
+	if config.TFTP == nil || config.TFTP.FilesConfigMap == "" {
+		return nil
+	}
+	return &corev1.Volume{
+		Name: tftpFilesVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: config.TFTP.FilesConfigMap},
+			},
+		},
+	}
+}
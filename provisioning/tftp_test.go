@@ -0,0 +1,102 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateTFTPConfig(t *testing.T) {
+	tCases := []struct {
+		name          string
+		mode          metal3iov1alpha1.ProvisioningNetwork
+		tftp          *metal3iov1alpha1.TFTPConfig
+		expectedError string
+	}{
+		{
+			name: "NotSet",
+			mode: metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+		},
+		{
+			name: "Valid",
+			mode: metal3iov1alpha1.ProvisioningNetworkManaged,
+			tftp: &metal3iov1alpha1.TFTPConfig{FilesConfigMap: "ipxe-builds", MaxBlockSize: 1400},
+		},
+		{
+			name:          "Unmanaged",
+			mode:          metal3iov1alpha1.ProvisioningNetworkUnmanaged,
+			tftp:          &metal3iov1alpha1.TFTPConfig{MaxBlockSize: 1400},
+			expectedError: "only supported",
+		},
+		{
+			name:          "InvalidConfigMap",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			tftp:          &metal3iov1alpha1.TFTPConfig{FilesConfigMap: "iPXE_builds"},
+			expectedError: "invalid TFTP.FilesConfigMap",
+		},
+		{
+			name:          "BlockSizeTooSmall",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			tftp:          &metal3iov1alpha1.TFTPConfig{MaxBlockSize: 8},
+			expectedError: "TFTP.MaxBlockSize must be between",
+		},
+		{
+			name:          "BlockSizeNotNegotiated",
+			mode:          metal3iov1alpha1.ProvisioningNetworkManaged,
+			tftp:          &metal3iov1alpha1.TFTPConfig{MaxBlockSize: 1400, DisableBlockSizeNegotiation: true},
+			expectedError: "cannot be set",
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := managedProvisioning()
+			config.TFTP = tc.tftp
+			err := validateTFTPConfig(config, tc.mode)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestTFTPConfig(t *testing.T) {
+	config := managedProvisioning()
+	template := newDnsmasqPodTemplateSpec(&testImages, config)
+	dnsmasq := findContainer(template.Spec.Containers, "metal3-dnsmasq")
+	assert.Equal(t, []string{"/bin/bash", "-c", dnsmasqServerScript + "exec /bin/rundnsmasq"}, dnsmasq.Command)
+	assert.Len(t, template.Spec.Volumes, 2)
+
+	config.TFTP = &metal3iov1alpha1.TFTPConfig{FilesConfigMap: "ipxe-builds", MaxBlockSize: 1400}
+	assert.Equal(t, "tftp-root=/etc/metal3-tftp,"+config.ProvisioningInterface+"\n"+
+		"tftp-mtu=1432", getTFTPConfig(config))
+
+	template = newDnsmasqPodTemplateSpec(&testImages, config)
+	dnsmasq = findContainer(template.Spec.Containers, "metal3-dnsmasq")
+	assert.Equal(t, getTFTPConfig(config), envValue(dnsmasq, tftpConfigEnvVar))
+	assert.Equal(t, dnsmasqServerScript+`mkdir -p /etc/dnsmasq.d && echo "${TFTP_CONFIG}" > /etc/dnsmasq.d/tftp.conf && exec /bin/rundnsmasq`,
+		dnsmasq.Command[2])
+	if assert.Len(t, template.Spec.Volumes, 3) {
+		assert.Equal(t, "ipxe-builds", template.Spec.Volumes[2].ConfigMap.Name)
+	}
+	mounted := false
+	for _, mount := range dnsmasq.VolumeMounts {
+		if mount.Name == tftpFilesVolume {
+			mounted = true
+			assert.Equal(t, tftpFilesMountPath, mount.MountPath)
+		}
+	}
+	assert.True(t, mounted)
+
+	config.TFTP = &metal3iov1alpha1.TFTPConfig{DisableBlockSizeNegotiation: true}
+	config.DHCPRelayRanges = []metal3iov1alpha1.DHCPRelayRange{
+		{NetworkCIDR: "192.168.10.0/24", DHCPRange: "192.168.10.10, 192.168.10.100", Router: "192.168.10.1"},
+	}
+	dnsmasq = &newDnsmasqPodTemplateSpec(&testImages, config).Spec.Containers[0]
+	assert.Equal(t, "tftp-no-blocksize", envValue(dnsmasq, tftpConfigEnvVar))
+	assert.Equal(t, dnsmasqServerScript+`mkdir -p /etc/dnsmasq.d && echo "${DHCP_RELAY_CONFIG}" > /etc/dnsmasq.d/relay.conf && `+
+		`echo "${TFTP_CONFIG}" > /etc/dnsmasq.d/tftp.conf && exec /bin/rundnsmasq`, dnsmasq.Command[2])
+}
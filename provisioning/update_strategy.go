@@ -0,0 +1,80 @@
+package provisioning
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+var (
+	defaultMetal3MaxSurge       = intstr.FromInt(1)
+	defaultMetal3MaxUnavailable = intstr.FromInt(0)
+)
+
+// getMetal3RollingUpdate returns the surge settings of the RollingUpdate
+// of the metal3 Deployment, defaulted
+func getMetal3RollingUpdate(strategy *metal3iov1alpha1.Metal3UpdateStrategy) (intstr.IntOrString, intstr.IntOrString) {
+	maxSurge, maxUnavailable := defaultMetal3MaxSurge, defaultMetal3MaxUnavailable
+	if strategy.MaxSurge != nil {
+		maxSurge = *strategy.MaxSurge
+	}
+	if strategy.MaxUnavailable != nil {
+		maxUnavailable = *strategy.MaxUnavailable
+	}
+	return maxSurge, maxUnavailable
+}
+
+// newMetal3DeploymentStrategy returns the update strategy of the metal3
+// Deployment, which recreates the pods unless a RollingUpdate is requested
+func newMetal3DeploymentStrategy(config *metal3iov1alpha1.ProvisioningSpec) appsv1.DeploymentStrategy {
+	strategy := config.UpdateStrategy
+	if strategy == nil || strategy.Type != metal3iov1alpha1.Metal3UpdateRollingUpdate {
+		return appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	}
+	maxSurge, maxUnavailable := getMetal3RollingUpdate(strategy)
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       &maxSurge,
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+}
+
+// validateUpdateStrategy checks that a RollingUpdate can make progress,
+// and that the pods it starts alongside the running ones are kept off
+// their nodes, as they would conflict on the host network ports and the
+// ProvisioningIP
+func validateUpdateStrategy(config *metal3iov1alpha1.ProvisioningSpec) error {
+	strategy := config.UpdateStrategy
+	if strategy == nil {
+		return nil
+	}
+	if strategy.Type != metal3iov1alpha1.Metal3UpdateRollingUpdate {
+		if strategy.MaxSurge != nil || strategy.MaxUnavailable != nil {
+			return fmt.Errorf("UpdateStrategy: MaxSurge and MaxUnavailable only apply to a RollingUpdate")
+		}
+		return nil
+	}
+
+	replicas := int(GetMetal3Replicas(config))
+	maxSurge, maxUnavailable := getMetal3RollingUpdate(strategy)
+	surge, err := intstr.GetValueFromIntOrPercent(&maxSurge, replicas, true)
+	if err != nil || surge < 0 {
+		return fmt.Errorf("UpdateStrategy: invalid MaxSurge %q", maxSurge.String())
+	}
+	unavailable, err := intstr.GetValueFromIntOrPercent(&maxUnavailable, replicas, false)
+	if err != nil || unavailable < 0 {
+		return fmt.Errorf("UpdateStrategy: invalid MaxUnavailable %q", maxUnavailable.String())
+	}
+	if surge == 0 && unavailable == 0 {
+		return fmt.Errorf("UpdateStrategy: MaxSurge and MaxUnavailable cannot both be 0, the rollout would never progress")
+	}
+	if surge > 0 && config.HighAvailability == nil {
+		return fmt.Errorf("UpdateStrategy: a RollingUpdate with a MaxSurge requires HighAvailability, the new metal3 pods would otherwise share the host network ports of the old ones on the same node")
+	}
+	return nil
+}
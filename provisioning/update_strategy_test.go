@@ -0,0 +1,128 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func intOrStringPtr(value intstr.IntOrString) *intstr.IntOrString {
+	return &value
+}
+
+func TestNewMetal3DeploymentStrategy(t *testing.T) {
+	spec := managedProvisioning()
+	deployment := NewMetal3Deployment(testNamespace, &testImages, spec)
+	assert.Equal(t, appsv1.RecreateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+	assert.Nil(t, deployment.Spec.Strategy.RollingUpdate)
+	assert.Nil(t, deployment.Spec.Template.Spec.TerminationGracePeriodSeconds)
+
+	spec.UpdateStrategy = &metal3iov1alpha1.Metal3UpdateStrategy{Type: metal3iov1alpha1.Metal3UpdateRollingUpdate}
+	spec.TerminationGracePeriodSeconds = pointer.Int64Ptr(120)
+	deployment = NewMetal3Deployment(testNamespace, &testImages, spec)
+	assert.Equal(t, appsv1.RollingUpdateDeploymentStrategyType, deployment.Spec.Strategy.Type)
+	if assert.NotNil(t, deployment.Spec.Strategy.RollingUpdate) {
+		assert.Equal(t, intstr.FromInt(1), *deployment.Spec.Strategy.RollingUpdate.MaxSurge)
+		assert.Equal(t, intstr.FromInt(0), *deployment.Spec.Strategy.RollingUpdate.MaxUnavailable)
+	}
+	assert.Equal(t, int64(120), *deployment.Spec.Template.Spec.TerminationGracePeriodSeconds)
+
+	spec.UpdateStrategy.MaxSurge = intOrStringPtr(intstr.FromString("50%"))
+	spec.UpdateStrategy.MaxUnavailable = intOrStringPtr(intstr.FromInt(1))
+	deployment = NewMetal3Deployment(testNamespace, &testImages, spec)
+	assert.Equal(t, intstr.FromString("50%"), *deployment.Spec.Strategy.RollingUpdate.MaxSurge)
+	assert.Equal(t, intstr.FromInt(1), *deployment.Spec.Strategy.RollingUpdate.MaxUnavailable)
+}
+
+func TestValidateUpdateStrategy(t *testing.T) {
+	withVIP := func(spec *metal3iov1alpha1.ProvisioningSpec) {
+		spec.HighAvailability = &metal3iov1alpha1.HighAvailability{Replicas: 2}
+		spec.ProvisioningVIP = &metal3iov1alpha1.ProvisioningVIP{}
+	}
+	tests := []struct {
+		name          string
+		strategy      *metal3iov1alpha1.Metal3UpdateStrategy
+		configure     func(*metal3iov1alpha1.ProvisioningSpec)
+		expectedError string
+	}{
+		{
+			name: "Default",
+		},
+		{
+			name:     "Recreate",
+			strategy: &metal3iov1alpha1.Metal3UpdateStrategy{Type: metal3iov1alpha1.Metal3UpdateRecreate},
+		},
+		{
+			name: "RecreateWithSurge",
+			strategy: &metal3iov1alpha1.Metal3UpdateStrategy{
+				Type:     metal3iov1alpha1.Metal3UpdateRecreate,
+				MaxSurge: intOrStringPtr(intstr.FromInt(1)),
+			},
+			expectedError: "only apply to a RollingUpdate",
+		},
+		{
+			name:      "SurgeWithVIP",
+			strategy:  &metal3iov1alpha1.Metal3UpdateStrategy{Type: metal3iov1alpha1.Metal3UpdateRollingUpdate},
+			configure: withVIP,
+		},
+		{
+			name:          "SurgeWithoutVIP",
+			strategy:      &metal3iov1alpha1.Metal3UpdateStrategy{Type: metal3iov1alpha1.Metal3UpdateRollingUpdate},
+			expectedError: "requires HighAvailability",
+		},
+		{
+			name:     "SurgeWithoutProvisioningIP",
+			strategy: &metal3iov1alpha1.Metal3UpdateStrategy{Type: metal3iov1alpha1.Metal3UpdateRollingUpdate},
+			configure: func(spec *metal3iov1alpha1.ProvisioningSpec) {
+				spec.ProvisioningNetwork = metal3iov1alpha1.ProvisioningNetworkDisabled
+				spec.ProvisioningIP = ""
+			},
+			expectedError: "requires HighAvailability",
+		},
+		{
+			name: "UnavailableWithoutVIP",
+			strategy: &metal3iov1alpha1.Metal3UpdateStrategy{
+				Type:           metal3iov1alpha1.Metal3UpdateRollingUpdate,
+				MaxSurge:       intOrStringPtr(intstr.FromInt(0)),
+				MaxUnavailable: intOrStringPtr(intstr.FromInt(1)),
+			},
+		},
+		{
+			name: "NoProgress",
+			strategy: &metal3iov1alpha1.Metal3UpdateStrategy{
+				Type:     metal3iov1alpha1.Metal3UpdateRollingUpdate,
+				MaxSurge: intOrStringPtr(intstr.FromString("0%")),
+			},
+			expectedError: "cannot both be 0",
+		},
+		{
+			name: "InvalidPercentage",
+			strategy: &metal3iov1alpha1.Metal3UpdateStrategy{
+				Type:     metal3iov1alpha1.Metal3UpdateRollingUpdate,
+				MaxSurge: intOrStringPtr(intstr.FromString("half")),
+			},
+			configure:     withVIP,
+			expectedError: "invalid MaxSurge",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.UpdateStrategy = tc.strategy
+			if tc.configure != nil {
+				tc.configure(spec)
+			}
+			err := validateUpdateStrategy(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+			} else if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
+			}
+		})
+	}
+}
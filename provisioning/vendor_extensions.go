@@ -0,0 +1,197 @@
+package provisioning
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+// driverInterfaces are the hardware types and interfaces enabled in
+// Ironic, keyed by the name of their enabled_* option
+type driverInterfaces map[string][]string
+
+// driverInterfaceOptions are the enabled_* options of Ironic, in the order
+// they are set
+var driverInterfaceOptions = []string{
+	"HARDWARE_TYPES",
+	"BIOS_INTERFACES",
+	"BOOT_INTERFACES",
+	"INSPECT_INTERFACES",
+	"MANAGEMENT_INTERFACES",
+	"POWER_INTERFACES",
+	"RAID_INTERFACES",
+	"VENDOR_INTERFACES",
+}
+
+// genericDriverInterfaces are always enabled, so that hosts without a
+// vendor driver keep being managed through IPMI or Redfish
+var genericDriverInterfaces = driverInterfaces{
+	"HARDWARE_TYPES":        {"ipmi", "redfish", "manual-management", "fake-hardware"},
+	"BIOS_INTERFACES":       {"no-bios", "redfish"},
+	"BOOT_INTERFACES":       {"ipxe", "pxe", "redfish-virtual-media", "fake"},
+	"INSPECT_INTERFACES":    {"inspector", "redfish", "no-inspect", "fake"},
+	"MANAGEMENT_INTERFACES": {"ipmitool", "redfish", "noop", "fake"},
+	"POWER_INTERFACES":      {"ipmitool", "redfish", "fake"},
+	"RAID_INTERFACES":       {"no-raid", "agent", "fake"},
+	"VENDOR_INTERFACES":     {"no-vendor", "ipmitool", "fake"},
+}
+
+func idracDriverInterfaces(extension *metal3iov1alpha1.IDRACExtension) driverInterfaces {
+	protocol := "idrac-redfish"
+	if extension.Protocol == metal3iov1alpha1.IDRACProtocolWSMAN {
+		protocol = "idrac-wsman"
+	}
+	return driverInterfaces{
+		"HARDWARE_TYPES":        {"idrac"},
+		"BIOS_INTERFACES":       {protocol},
+		"BOOT_INTERFACES":       {"idrac-redfish-virtual-media"},
+		"INSPECT_INTERFACES":    {protocol},
+		"MANAGEMENT_INTERFACES": {protocol},
+		"POWER_INTERFACES":      {protocol},
+		"RAID_INTERFACES":       {protocol},
+		"VENDOR_INTERFACES":     {protocol},
+	}
+}
+
+func iloDriverInterfaces(extension *metal3iov1alpha1.ILOExtension) driverInterfaces {
+	interfaces := driverInterfaces{
+		"HARDWARE_TYPES":        {"ilo"},
+		"BIOS_INTERFACES":       {"ilo"},
+		"BOOT_INTERFACES":       {"ilo-virtual-media", "ilo-pxe", "ilo-ipxe"},
+		"INSPECT_INTERFACES":    {"ilo"},
+		"MANAGEMENT_INTERFACES": {"ilo"},
+		"POWER_INTERFACES":      {"ilo"},
+		"VENDOR_INTERFACES":     {"ilo"},
+	}
+	if getILOGeneration(extension) == metal3iov1alpha1.ILOGeneration5 {
+		interfaces["HARDWARE_TYPES"] = append(interfaces["HARDWARE_TYPES"], "ilo5")
+		interfaces["MANAGEMENT_INTERFACES"] = append(interfaces["MANAGEMENT_INTERFACES"], "ilo5")
+		if extension.EnableRAID {
+			interfaces["RAID_INTERFACES"] = []string{"ilo5"}
+		}
+	}
+	return interfaces
+}
+
+var irmcDriverInterfaces = driverInterfaces{
+	"HARDWARE_TYPES":        {"irmc"},
+	"BIOS_INTERFACES":       {"irmc"},
+	"BOOT_INTERFACES":       {"irmc-pxe", "irmc-virtual-media"},
+	"INSPECT_INTERFACES":    {"irmc"},
+	"MANAGEMENT_INTERFACES": {"irmc"},
+	"POWER_INTERFACES":      {"irmc"},
+	"RAID_INTERFACES":       {"irmc"},
+}
+
+var xclarityDriverInterfaces = driverInterfaces{
+	"HARDWARE_TYPES":        {"xclarity"},
+	"MANAGEMENT_INTERFACES": {"xclarity"},
+	"POWER_INTERFACES":      {"xclarity"},
+}
+
+func getILOGeneration(extension *metal3iov1alpha1.ILOExtension) metal3iov1alpha1.ILOGeneration {
+	if extension.Generation == "" {
+		return metal3iov1alpha1.ILOGeneration5
+	}
+	return extension.Generation
+}
+
+func getIRMCSNMPVersion(extension *metal3iov1alpha1.IRMCExtension) metal3iov1alpha1.IRMCSNMPVersion {
+	if extension.SNMPVersion == "" {
+		return metal3iov1alpha1.IRMCSNMPv2c
+	}
+	return extension.SNMPVersion
+}
+
+// validateVendorExtensions checks the options of the vendor drivers that
+// exclude each other
+func validateVendorExtensions(config *metal3iov1alpha1.ProvisioningSpec) error {
+	extensions := config.VendorExtensions
+	if extensions == nil {
+		return nil
+	}
+	if ilo := extensions.ILO; ilo != nil {
+		if ilo.EnableRAID && getILOGeneration(ilo) != metal3iov1alpha1.ILOGeneration5 {
+			return fmt.Errorf("VendorExtensions.ILO.EnableRAID requires the ILO5 Generation")
+		}
+	}
+	if irmc := extensions.IRMC; irmc != nil {
+		if getIRMCSNMPVersion(irmc) == metal3iov1alpha1.IRMCSNMPv3 {
+			if irmc.SNMPSecurity == "" {
+				return fmt.Errorf("VendorExtensions.IRMC.SNMPSecurity is required with SNMP v3")
+			}
+			if irmc.SNMPCommunity != "" {
+				return fmt.Errorf("VendorExtensions.IRMC.SNMPCommunity cannot be used with SNMP v3")
+			}
+		} else if irmc.SNMPSecurity != "" {
+			return fmt.Errorf("VendorExtensions.IRMC.SNMPSecurity can only be used with SNMP v3")
+		}
+	}
+	return nil
+}
+
+// getEnabledDriverInterfaces returns the hardware types and interfaces
+// enabled for the VendorExtensions, or nil when all the drivers of the
+// image are kept
+func getEnabledDriverInterfaces(config *metal3iov1alpha1.ProvisioningSpec) driverInterfaces {
+	extensions := config.VendorExtensions
+	if extensions == nil {
+		return nil
+	}
+	vendors := []driverInterfaces{genericDriverInterfaces}
+	if extensions.IDRAC != nil {
+		vendors = append(vendors, idracDriverInterfaces(extensions.IDRAC))
+	}
+	if extensions.ILO != nil {
+		vendors = append(vendors, iloDriverInterfaces(extensions.ILO))
+	}
+	if extensions.IRMC != nil {
+		vendors = append(vendors, irmcDriverInterfaces)
+	}
+	if extensions.XClarity != nil {
+		vendors = append(vendors, xclarityDriverInterfaces)
+	}
+	enabled := driverInterfaces{}
+	for _, vendor := range vendors {
+		for option, names := range vendor {
+			enabled[option] = append(enabled[option], names...)
+		}
+	}
+	return enabled
+}
+
+// newVendorExtensionsEnv returns the environment of the Ironic conductor
+// enabling the vendor drivers, along with their own options
+func newVendorExtensionsEnv(config *metal3iov1alpha1.ProvisioningSpec) []corev1.EnvVar {
+	enabled := getEnabledDriverInterfaces(config)
+	if enabled == nil {
+		return nil
+	}
+	env := []corev1.EnvVar{}
+	for _, option := range driverInterfaceOptions {
+		env = append(env, corev1.EnvVar{
+			Name:  ironicDefaultEnvPrefix + "ENABLED_" + option,
+			Value: strings.Join(enabled[option], ","),
+		})
+	}
+	if ilo := config.VendorExtensions.ILO; ilo != nil && ilo.UseWebServerForImages {
+		env = append(env, corev1.EnvVar{Name: "OS_ILO__USE_WEB_SERVER_FOR_IMAGES", Value: "true"})
+	}
+	if irmc := config.VendorExtensions.IRMC; irmc != nil {
+		env = append(env, corev1.EnvVar{Name: "OS_IRMC__SNMP_VERSION", Value: string(getIRMCSNMPVersion(irmc))})
+		if irmc.SNMPPort != 0 {
+			env = append(env, corev1.EnvVar{Name: "OS_IRMC__SNMP_PORT", Value: strconv.Itoa(int(irmc.SNMPPort))})
+		}
+		if irmc.SNMPCommunity != "" {
+			env = append(env, corev1.EnvVar{Name: "OS_IRMC__SNMP_COMMUNITY", Value: irmc.SNMPCommunity})
+		}
+		if irmc.SNMPSecurity != "" {
+			env = append(env, corev1.EnvVar{Name: "OS_IRMC__SNMP_SECURITY", Value: irmc.SNMPSecurity})
+		}
+	}
+	return env
+}
@@ -0,0 +1,156 @@
+package provisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+)
+
+func TestValidateVendorExtensions(t *testing.T) {
+	tests := []struct {
+		name          string
+		extensions    *metal3iov1alpha1.VendorExtensions
+		expectedError string
+	}{
+		{
+			name: "None",
+		},
+		{
+			name: "AllVendors",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				IDRAC:    &metal3iov1alpha1.IDRACExtension{},
+				ILO:      &metal3iov1alpha1.ILOExtension{EnableRAID: true},
+				IRMC:     &metal3iov1alpha1.IRMCExtension{SNMPCommunity: "private"},
+				XClarity: &metal3iov1alpha1.XClarityExtension{},
+			},
+		},
+		{
+			name: "ILO4RAID",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				ILO: &metal3iov1alpha1.ILOExtension{Generation: metal3iov1alpha1.ILOGeneration4, EnableRAID: true},
+			},
+			expectedError: "VendorExtensions.ILO.EnableRAID requires the ILO5 Generation",
+		},
+		{
+			name: "SNMPv3",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				IRMC: &metal3iov1alpha1.IRMCExtension{SNMPVersion: metal3iov1alpha1.IRMCSNMPv3, SNMPSecurity: "admin"},
+			},
+		},
+		{
+			name: "SNMPv3WithoutSecurity",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				IRMC: &metal3iov1alpha1.IRMCExtension{SNMPVersion: metal3iov1alpha1.IRMCSNMPv3},
+			},
+			expectedError: "VendorExtensions.IRMC.SNMPSecurity is required with SNMP v3",
+		},
+		{
+			name: "SNMPv3WithCommunity",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				IRMC: &metal3iov1alpha1.IRMCExtension{SNMPVersion: metal3iov1alpha1.IRMCSNMPv3, SNMPSecurity: "admin", SNMPCommunity: "public"},
+			},
+			expectedError: "VendorExtensions.IRMC.SNMPCommunity cannot be used with SNMP v3",
+		},
+		{
+			name: "SecurityWithoutSNMPv3",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				IRMC: &metal3iov1alpha1.IRMCExtension{SNMPSecurity: "admin"},
+			},
+			expectedError: "VendorExtensions.IRMC.SNMPSecurity can only be used with SNMP v3",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.VendorExtensions = tc.extensions
+			err := validateVendorExtensions(spec)
+			if tc.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestVendorExtensionsEnv(t *testing.T) {
+	spec := managedProvisioning()
+	conductor := findContainer(NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, "metal3-ironic-conductor")
+	// Every driver of the image is kept by default
+	assert.Equal(t, "", envValue(conductor, "OS_DEFAULT__ENABLED_HARDWARE_TYPES"))
+
+	tests := []struct {
+		name       string
+		extensions *metal3iov1alpha1.VendorExtensions
+		expected   map[string]string
+	}{
+		{
+			name:       "GenericOnly",
+			extensions: &metal3iov1alpha1.VendorExtensions{},
+			expected: map[string]string{
+				"OS_DEFAULT__ENABLED_HARDWARE_TYPES":  "ipmi,redfish,manual-management,fake-hardware",
+				"OS_DEFAULT__ENABLED_RAID_INTERFACES": "no-raid,agent,fake",
+				"OS_ILO__USE_WEB_SERVER_FOR_IMAGES":   "",
+				"OS_IRMC__SNMP_VERSION":               "",
+			},
+		},
+		{
+			name:       "IDRACWSMAN",
+			extensions: &metal3iov1alpha1.VendorExtensions{IDRAC: &metal3iov1alpha1.IDRACExtension{Protocol: metal3iov1alpha1.IDRACProtocolWSMAN}},
+			expected: map[string]string{
+				"OS_DEFAULT__ENABLED_HARDWARE_TYPES":        "ipmi,redfish,manual-management,fake-hardware,idrac",
+				"OS_DEFAULT__ENABLED_MANAGEMENT_INTERFACES": "ipmitool,redfish,noop,fake,idrac-wsman",
+				"OS_DEFAULT__ENABLED_BOOT_INTERFACES":       "ipxe,pxe,redfish-virtual-media,fake,idrac-redfish-virtual-media",
+			},
+		},
+		{
+			name: "ILO5",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				ILO: &metal3iov1alpha1.ILOExtension{EnableRAID: true, UseWebServerForImages: true},
+			},
+			expected: map[string]string{
+				"OS_DEFAULT__ENABLED_HARDWARE_TYPES":  "ipmi,redfish,manual-management,fake-hardware,ilo,ilo5",
+				"OS_DEFAULT__ENABLED_RAID_INTERFACES": "no-raid,agent,fake,ilo5",
+				"OS_ILO__USE_WEB_SERVER_FOR_IMAGES":   "true",
+			},
+		},
+		{
+			name:       "ILO4",
+			extensions: &metal3iov1alpha1.VendorExtensions{ILO: &metal3iov1alpha1.ILOExtension{Generation: metal3iov1alpha1.ILOGeneration4}},
+			expected: map[string]string{
+				"OS_DEFAULT__ENABLED_HARDWARE_TYPES":  "ipmi,redfish,manual-management,fake-hardware,ilo",
+				"OS_DEFAULT__ENABLED_RAID_INTERFACES": "no-raid,agent,fake",
+			},
+		},
+		{
+			name: "IRMCAndXClarity",
+			extensions: &metal3iov1alpha1.VendorExtensions{
+				IRMC:     &metal3iov1alpha1.IRMCExtension{SNMPPort: 1161, SNMPCommunity: "private"},
+				XClarity: &metal3iov1alpha1.XClarityExtension{},
+			},
+			expected: map[string]string{
+				"OS_DEFAULT__ENABLED_HARDWARE_TYPES":   "ipmi,redfish,manual-management,fake-hardware,irmc,xclarity",
+				"OS_DEFAULT__ENABLED_POWER_INTERFACES": "ipmitool,redfish,fake,irmc,xclarity",
+				"OS_IRMC__SNMP_VERSION":                "v2c",
+				"OS_IRMC__SNMP_PORT":                   "1161",
+				"OS_IRMC__SNMP_COMMUNITY":              "private",
+				"OS_IRMC__SNMP_SECURITY":               "",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := managedProvisioning()
+			spec.VendorExtensions = tc.extensions
+			conductor := findContainer(NewMetal3Deployment(testNamespace, &testImages, spec).Spec.Template.Spec.Containers, "metal3-ironic-conductor")
+			if !assert.NotNil(t, conductor) {
+				return
+			}
+			for name, value := range tc.expected {
+				assert.Equal(t, value, envValue(conductor, name), name)
+			}
+		})
+	}
+}
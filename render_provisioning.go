@@ -0,0 +1,63 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	"github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+// renderProvisioningCommand is the subcommand writing an example
+// Provisioning manifest instead of running the operator
+const renderProvisioningCommand = "render-provisioning"
+
+// renderProvisioning writes the Provisioning manifest of the flags to
+// stdout, and returns the exit code of the command
+func renderProvisioning(args []string, stdout io.Writer, stderr io.Writer) int {
+	var mode string
+	options := provisioning.ExampleManifestOptions{}
+	flags := flag.NewFlagSet(renderProvisioningCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&mode, "mode", string(metal3iov1alpha1.ProvisioningNetworkManaged),
+		"The ProvisioningNetwork mode: Managed, Unmanaged, Disabled or Auto.")
+	flags.StringVar(&options.ProvisioningInterface, "interface", "",
+		"The NIC of the control plane nodes on the provisioning network.")
+	flags.StringVar(&options.ProvisioningNetworkCIDR, "cidr", "",
+		"The CIDR of the provisioning network.")
+	flags.StringVar(&options.ProvisioningIP, "provisioning-ip", "",
+		"The address the metal3 services are served on. Defaults to the third address of the provisioning network.")
+	flags.StringVar(&options.ProvisioningOSDownloadURL, "os-download-url", "",
+		"The URL of the image written to the disk of the hosts, with its sha256 checksum.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	options.Mode = metal3iov1alpha1.ProvisioningNetwork(mode)
+
+	manifest, err := provisioning.RenderExampleManifest(options)
+	if err != nil {
+		fmt.Fprintf(stderr, "unable to render the Provisioning manifest: %v\n", err)
+		return 1
+	}
+	if _, err := stdout.Write(manifest); err != nil {
+		fmt.Fprintf(stderr, "unable to write the Provisioning manifest: %v\n", err)
+		return 1
+	}
+	return 0
+}
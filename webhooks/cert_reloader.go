@@ -0,0 +1,192 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// defaultCertReloadInterval is how often the serving certificate files
+// are read again
+const defaultCertReloadInterval = 10 * time.Second
+
+var (
+	webhookCertReloadFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "webhook_serving_certificate_reload_failures_total",
+		Help: "Number of times the rotated serving certificate of the webhook server could not be loaded.",
+	})
+	webhookCertExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "webhook_serving_certificate_expiry_timestamp_seconds",
+		Help: "Expiry time of the serving certificate served by the webhook server, in seconds since the epoch.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(webhookCertReloadFailures, webhookCertExpiry)
+}
+
+// CertReloader serves the tls.crt and tls.key serving certificate of a
+// directory, loading it again once rotated. The service CA operator
+// rotates the certificate by updating its Secret, whose volume swaps a
+// symlink to a new directory rather than writing the files, so the files
+// are polled rather than watched. A certificate that cannot be loaded,
+// such as a key read before the matching certificate was written, leaves
+// the previous one served, and is retried at the next poll.
+type CertReloader struct {
+	CertDir string
+	// Interval is how often the files are read, defaultCertReloadInterval
+	// when zero
+	Interval time.Duration
+	Log      logr.Logger
+
+	lock    sync.RWMutex
+	cert    *tls.Certificate
+	leaf    *x509.Certificate
+	certPEM []byte
+	keyPEM  []byte
+	// lastErr is the error of the last reload, if it failed
+	lastErr error
+}
+
+// NewCertReloader returns a CertReloader serving the certificate of the
+// directory, which must load
+func NewCertReloader(certDir string, log logr.Logger) (*CertReloader, error) {
+	reloader := &CertReloader{CertDir: certDir, Log: log}
+	if _, err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload reads the certificate files, and serves them when they changed
+// and hold a valid pair. It returns true when a new certificate is
+// served.
+func (c *CertReloader) Reload() (bool, error) {
+	changed, err := c.reload()
+	c.lock.Lock()
+	c.lastErr = err
+	c.lock.Unlock()
+	if err != nil {
+		webhookCertReloadFailures.Inc()
+	}
+	return changed, err
+}
+
+func (c *CertReloader) reload() (bool, error) {
+	certPEM, err := ioutil.ReadFile(filepath.Join(c.CertDir, "tls.crt"))
+	if err != nil {
+		return false, fmt.Errorf("unable to read webhook certificate: %v", err)
+	}
+	keyPEM, err := ioutil.ReadFile(filepath.Join(c.CertDir, "tls.key"))
+	if err != nil {
+		return false, fmt.Errorf("unable to read webhook key: %v", err)
+	}
+
+	c.lock.RLock()
+	unchanged := bytes.Equal(certPEM, c.certPEM) && bytes.Equal(keyPEM, c.keyPEM)
+	c.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("invalid webhook certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false, fmt.Errorf("invalid webhook certificate: %v", err)
+	}
+	c.lock.RLock()
+	serving := c.leaf != nil
+	c.lock.RUnlock()
+	if serving && time.Now().After(leaf.NotAfter) {
+		// The previous certificate is at least as good
+		return false, fmt.Errorf("webhook certificate expired on %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	c.lock.Lock()
+	c.cert, c.leaf, c.certPEM, c.keyPEM = &cert, leaf, certPEM, keyPEM
+	c.lock.Unlock()
+	webhookCertExpiry.Set(float64(leaf.NotAfter.Unix()))
+	return true, nil
+}
+
+// GetCertificate returns the certificate to serve, for the GetCertificate
+// of a tls.Config
+func (c *CertReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.cert == nil {
+		return nil, fmt.Errorf("no webhook certificate loaded")
+	}
+	return c.cert, nil
+}
+
+// Check fails when the served certificate expired, which happens when its
+// rotation kept failing. A failed reload alone does not fail it, as the
+// previous certificate is still served.
+func (c *CertReloader) Check(_ *http.Request) error {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	if c.leaf == nil {
+		return fmt.Errorf("no webhook certificate loaded: %v", c.lastErr)
+	}
+	if time.Now().After(c.leaf.NotAfter) {
+		return fmt.Errorf("served webhook certificate expired on %s, last reload: %v", c.leaf.NotAfter.Format(time.RFC3339), c.lastErr)
+	}
+	return nil
+}
+
+// Start reloads the certificate periodically until stopped
+func (c *CertReloader) Start(stop <-chan struct{}) error {
+	interval := c.Interval
+	if interval == 0 {
+		interval = defaultCertReloadInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			changed, err := c.Reload()
+			if err != nil {
+				c.Log.Info("unable to reload the webhook certificate, serving the previous one", "error", err.Error())
+			} else if changed {
+				c.Log.Info("reloaded the rotated webhook certificate")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, as every replica serves the webhooks
+func (c *CertReloader) NeedLeaderElection() bool {
+	return false
+}
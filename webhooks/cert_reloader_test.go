@@ -0,0 +1,298 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// newTestServingCert returns a PEM encoded self-signed serving certificate
+// and key, valid until notAfter
+func newTestServingCert(t *testing.T, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "cluster-baremetal-webhook-service.openshift-machine-api.svc"},
+		DNSNames:     []string{"cluster-baremetal-webhook-service.openshift-machine-api.svc"},
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeSecretVolume writes the certificate the way the kubelet updates a
+// Secret volume: the files are written to a new directory, which the
+// ..data symlink is then swapped to
+func writeSecretVolume(t *testing.T, dir string, certPEM []byte, keyPEM []byte) {
+	data, err := ioutil.TempDir(dir, "..data-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(data, "tls.crt"), certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(data, "tls.key"), keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "..data")
+	previous, _ := os.Readlink(link)
+	if err := os.Symlink(filepath.Base(data), link+".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(link+".tmp", link); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		if _, err := os.Lstat(filepath.Join(dir, name)); os.IsNotExist(err) {
+			if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if previous != "" {
+		os.RemoveAll(filepath.Join(dir, previous))
+	}
+}
+
+func newTestCertDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "webhook-cert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func servedSerial(t *testing.T, certs *CertReloader) *big.Int {
+	cert, err := certs.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber
+}
+
+func serialOf(t *testing.T, certPEM []byte) *big.Int {
+	block, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.SerialNumber
+}
+
+func reloadFailures(t *testing.T) float64 {
+	metric := &dto.Metric{}
+	if err := webhookCertReloadFailures.Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetCounter().GetValue()
+}
+
+func TestCertReloaderRotation(t *testing.T) {
+	dir := newTestCertDir(t)
+	defer os.RemoveAll(dir)
+	firstCert, firstKey := newTestServingCert(t, time.Now().Add(time.Hour))
+	writeSecretVolume(t, dir, firstCert, firstKey)
+
+	certs, err := NewCertReloader(dir, ctrl.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, serialOf(t, firstCert), servedSerial(t, certs))
+	assert.NoError(t, certs.Check(nil))
+
+	changed, err := certs.Reload()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	secondCert, secondKey := newTestServingCert(t, time.Now().Add(2*time.Hour))
+	writeSecretVolume(t, dir, secondCert, secondKey)
+	changed, err = certs.Reload()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, serialOf(t, secondCert), servedSerial(t, certs))
+}
+
+func TestCertReloaderRotationFailures(t *testing.T) {
+	validCert, validKey := newTestServingCert(t, time.Now().Add(time.Hour))
+	otherCert, otherKey := newTestServingCert(t, time.Now().Add(time.Hour))
+	expiredCert, expiredKey := newTestServingCert(t, time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name   string
+		rotate func(t *testing.T, dir string)
+	}{
+		{
+			name: "KeyOfAnotherCertificate",
+			rotate: func(t *testing.T, dir string) {
+				writeSecretVolume(t, dir, otherCert, validKey)
+			},
+		},
+		{
+			name: "TruncatedCertificate",
+			rotate: func(t *testing.T, dir string) {
+				writeSecretVolume(t, dir, otherCert[:len(otherCert)/2], otherKey)
+			},
+		},
+		{
+			name: "NotPEM",
+			rotate: func(t *testing.T, dir string) {
+				writeSecretVolume(t, dir, []byte("not a certificate"), []byte("not a key"))
+			},
+		},
+		{
+			name: "Expired",
+			rotate: func(t *testing.T, dir string) {
+				writeSecretVolume(t, dir, expiredCert, expiredKey)
+			},
+		},
+		{
+			name: "SecretDeleted",
+			rotate: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, "..data")); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := newTestCertDir(t)
+			defer os.RemoveAll(dir)
+			writeSecretVolume(t, dir, validCert, validKey)
+			certs, err := NewCertReloader(dir, ctrl.Log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			failures := reloadFailures(t)
+
+			// The previous certificate is still served
+			tc.rotate(t, dir)
+			changed, err := certs.Reload()
+			assert.Error(t, err)
+			assert.False(t, changed)
+			assert.Equal(t, serialOf(t, validCert), servedSerial(t, certs))
+			assert.NoError(t, certs.Check(nil))
+			assert.Equal(t, failures+1, reloadFailures(t))
+
+			// and the next rotation is picked up
+			writeSecretVolume(t, dir, otherCert, otherKey)
+			changed, err = certs.Reload()
+			assert.NoError(t, err)
+			assert.True(t, changed)
+			assert.Equal(t, serialOf(t, otherCert), servedSerial(t, certs))
+		})
+	}
+}
+
+func TestCertReloaderCheck(t *testing.T) {
+	dir := newTestCertDir(t)
+	defer os.RemoveAll(dir)
+
+	_, err := NewCertReloader(dir, ctrl.Log)
+	assert.Error(t, err, "the certificate has not been issued yet")
+
+	expiredCert, expiredKey := newTestServingCert(t, time.Now().Add(-time.Hour))
+	writeSecretVolume(t, dir, expiredCert, expiredKey)
+	certs, err := NewCertReloader(dir, ctrl.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Error(t, certs.Check(nil))
+}
+
+func TestServerServesRotatedCertificate(t *testing.T) {
+	dir := newTestCertDir(t)
+	defer os.RemoveAll(dir)
+	firstCert, firstKey := newTestServingCert(t, time.Now().Add(time.Hour))
+	writeSecretVolume(t, dir, firstCert, firstKey)
+	certs, err := NewCertReloader(dir, ctrl.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs.Interval = 10 * time.Millisecond
+
+	listeners := make(chan net.Listener, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {})
+	server := &Server{Certs: certs, Mux: mux, Log: ctrl.Log,
+		listen: func(network, _ string, config *tls.Config) (net.Listener, error) {
+			listener, err := tls.Listen(network, "127.0.0.1:0", config)
+			listeners <- listener
+			return listener, err
+		},
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() { _ = server.Start(stop) }()
+	go func() { _ = certs.Start(stop) }()
+	address := (<-listeners).Addr().String()
+
+	handshake := func() *big.Int {
+		conn, err := tls.Dial("tcp", address, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber
+	}
+	assert.Equal(t, serialOf(t, firstCert), handshake())
+
+	secondCert, secondKey := newTestServingCert(t, time.Now().Add(2*time.Hour))
+	writeSecretVolume(t, dir, secondCert, secondKey)
+	expected := serialOf(t, secondCert)
+	for i := 0; handshake().Cmp(expected) != 0; i++ {
+		if i == 100 {
+			t.Fatal(fmt.Errorf("rotated certificate not served"))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhooks contains the admission webhooks for the metal3.io API
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+const (
+	provisioningValidatePath = "/validate-metal3-io-v1alpha1-provisioning"
+	provisioningMutatePath   = "/mutate-metal3-io-v1alpha1-provisioning"
+)
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-metal3-io-v1alpha1-provisioning,mutating=false,failurePolicy=fail,groups=metal3.io,resources=provisionings,versions=v1alpha1,name=vprovisioning.kb.io
+
+// provisioningValidator validates Provisioning resources, and warns about
+// settings that are accepted but likely mistakes. The Validator interface
+// of controller-runtime cannot return warnings, so the admission response
+// is built here instead. Tech preview settings are denied unless the
+// cluster FeatureGate enables them.
+type provisioningValidator struct {
+	client  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.DecoderInjector = &provisioningValidator{}
+
+// InjectDecoder injects the decoder
+func (v *provisioningValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle validates the Provisioning of the request
+func (v *provisioningValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	prov := &metal3iov1alpha1.Provisioning{}
+	if err := v.decoder.Decode(req, prov); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var err error
+	switch req.Operation {
+	case admissionv1beta1.Create:
+		err = prov.ValidateCreate()
+	case admissionv1beta1.Update:
+		old := &metal3iov1alpha1.Provisioning{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = prov.ValidateUpdate(old)
+	}
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	featureSet, err := v.getFeatureSet(ctx)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if err := provisioning.ValidateFeatureSet(prov, featureSet); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	resp := admission.Allowed("")
+	if warnings := provisioning.GetConfigWarnings(&prov.Spec); len(warnings) > 0 {
+		resp.Warnings = warnings
+	}
+	return resp
+}
+
+// getFeatureSet returns the feature set enabled on the cluster
+func (v *provisioningValidator) getFeatureSet(ctx context.Context) (osconfigv1.FeatureSet, error) {
+	featureGate := &osconfigv1.FeatureGate{}
+	err := v.client.Get(ctx, client.ObjectKey{Name: provisioning.ClusterFeatureGateName}, featureGate)
+	if apierrors.IsNotFound(err) {
+		return provisioning.GetFeatureSet(nil), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return provisioning.GetFeatureSet(featureGate), nil
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/mutate-metal3-io-v1alpha1-provisioning,mutating=true,failurePolicy=fail,groups=metal3.io,resources=provisionings,versions=v1alpha1,name=mprovisioning.kb.io
+
+// provisioningNormalizer rewrites the values of Provisioning resources
+// that are accepted in several notations into the one the operator
+// renders. It denies the values that cannot be rewritten consistently.
+type provisioningNormalizer struct {
+	decoder *admission.Decoder
+}
+
+var _ admission.DecoderInjector = &provisioningNormalizer{}
+
+// InjectDecoder injects the decoder
+func (n *provisioningNormalizer) InjectDecoder(d *admission.Decoder) error {
+	n.decoder = d
+	return nil
+}
+
+// Handle normalizes the Provisioning of the request
+func (n *provisioningNormalizer) Handle(ctx context.Context, req admission.Request) admission.Response {
+	prov := &metal3iov1alpha1.Provisioning{}
+	if err := n.decoder.Decode(req, prov); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := provisioning.NormalizeProvisioningSpec(&prov.Spec); err != nil {
+		return admission.Denied(err.Error())
+	}
+	marshalled, err := json.Marshal(prov)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshalled)
+}
+
+// SetupWithManager serves the Provisioning webhooks with the serving
+// certificate of certDir, which is reloaded when the service CA operator
+// rotates it
+func SetupWithManager(mgr ctrl.Manager, certDir string) error {
+	log := ctrl.Log.WithName("webhooks")
+	certs, err := NewCertReloader(certDir, log)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	hooks := map[string]*webhook.Admission{
+		provisioningMutatePath:   {Handler: &provisioningNormalizer{}},
+		provisioningValidatePath: {Handler: &provisioningValidator{client: mgr.GetClient()}},
+	}
+	for path, hook := range hooks {
+		// Injects the decoder of the handler
+		if err := mgr.SetFields(hook); err != nil {
+			return err
+		}
+		mux.Handle(path, hook)
+	}
+	if err := mgr.Add(certs); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("webhook-served-cert", certs.Check); err != nil {
+		return err
+	}
+	return mgr.Add(&Server{Port: DefaultPort, Certs: certs, Mux: mux, Log: log})
+}
@@ -0,0 +1,185 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	osconfigv1 "github.com/openshift/api/config/v1"
+	metal3iov1alpha1 "github.com/openshift/cluster-baremetal-operator/api/v1alpha1"
+	provisioning "github.com/openshift/cluster-baremetal-operator/provisioning"
+)
+
+func newTestValidator(t *testing.T, objs ...runtime.Object) *provisioningValidator {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, metal3iov1alpha1.AddToScheme(scheme))
+	assert.NoError(t, osconfigv1.Install(scheme))
+	decoder, err := admission.NewDecoder(scheme)
+	assert.NoError(t, err)
+	validator := &provisioningValidator{client: fakeclient.NewFakeClientWithScheme(scheme, objs...)}
+	assert.NoError(t, validator.InjectDecoder(decoder))
+	return validator
+}
+
+func newRequest(t *testing.T, operation admissionv1beta1.Operation, prov *metal3iov1alpha1.Provisioning) admission.Request {
+	prov.APIVersion = metal3iov1alpha1.GroupVersion.String()
+	prov.Kind = "Provisioning"
+	raw, err := json.Marshal(prov)
+	assert.NoError(t, err)
+	req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+	if operation == admissionv1beta1.Update {
+		req.OldObject = runtime.RawExtension{Raw: raw}
+	}
+	return req
+}
+
+func TestProvisioningValidator(t *testing.T) {
+	spec := metal3iov1alpha1.ProvisioningSpec{
+		ProvisioningInterface:     "eth0",
+		ProvisioningIP:            "172.30.20.3",
+		ProvisioningNetworkCIDR:   "172.30.20.0/24",
+		ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+		ProvisioningOSDownloadURL: "https://mirror.example.com/rhcos.qcow2.gz?sha256=1234",
+	}
+	suspicious := spec
+	suspicious.ProvisioningOSDownloadURL = "http://mirror.example.com/rhcos.qcow2.gz?sha256=1234"
+
+	tCases := []struct {
+		name             string
+		operation        admissionv1beta1.Operation
+		objName          string
+		spec             metal3iov1alpha1.ProvisioningSpec
+		expectedAllowed  bool
+		expectedWarnings int
+	}{
+		{
+			name:            "Create",
+			operation:       admissionv1beta1.Create,
+			objName:         metal3iov1alpha1.ProvisioningSingletonName,
+			spec:            spec,
+			expectedAllowed: true,
+		},
+		{
+			name:             "CreateWithWarnings",
+			operation:        admissionv1beta1.Create,
+			objName:          metal3iov1alpha1.ProvisioningSingletonName,
+			spec:             suspicious,
+			expectedAllowed:  true,
+			expectedWarnings: 1,
+		},
+		{
+			name:             "UpdateWithWarnings",
+			operation:        admissionv1beta1.Update,
+			objName:          metal3iov1alpha1.ProvisioningSingletonName,
+			spec:             suspicious,
+			expectedAllowed:  true,
+			expectedWarnings: 1,
+		},
+		{
+			name:      "CreateExtraInstance",
+			operation: admissionv1beta1.Create,
+			objName:   "extra",
+			spec:      spec,
+		},
+	}
+	for _, tc := range tCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prov := &metal3iov1alpha1.Provisioning{
+				ObjectMeta: metav1.ObjectMeta{Name: tc.objName},
+				Spec:       tc.spec,
+			}
+			resp := newTestValidator(t).Handle(context.Background(), newRequest(t, tc.operation, prov))
+			assert.Equal(t, tc.expectedAllowed, resp.Allowed)
+			assert.Len(t, resp.Warnings, tc.expectedWarnings)
+		})
+	}
+}
+
+func TestProvisioningValidatorFeatureSet(t *testing.T) {
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: metal3iov1alpha1.ProvisioningSingletonName},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface:     "eth0",
+			ProvisioningIP:            "172.30.20.3",
+			ProvisioningNetworkCIDR:   "172.30.20.0/24",
+			ProvisioningDHCPRange:     "172.30.20.11, 172.30.20.101",
+			ProvisioningOSDownloadURL: "https://mirror.example.com/rhcos.qcow2.gz?sha256=1234",
+			SecureBoot:                true,
+		},
+	}
+
+	resp := newTestValidator(t).Handle(context.Background(), newRequest(t, admissionv1beta1.Create, prov))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, "SecureBoot can only be used when the cluster FeatureGate enables the TechPreviewNoUpgrade feature set", string(resp.Result.Reason))
+
+	techPreview := &osconfigv1.FeatureGate{
+		ObjectMeta: metav1.ObjectMeta{Name: provisioning.ClusterFeatureGateName},
+		Spec: osconfigv1.FeatureGateSpec{
+			FeatureGateSelection: osconfigv1.FeatureGateSelection{FeatureSet: osconfigv1.TechPreviewNoUpgrade},
+		},
+	}
+	resp = newTestValidator(t, techPreview).Handle(context.Background(), newRequest(t, admissionv1beta1.Update, prov))
+	assert.True(t, resp.Allowed)
+}
+
+func TestProvisioningNormalizer(t *testing.T) {
+	validator := newTestValidator(t)
+	normalizer := &provisioningNormalizer{}
+	assert.NoError(t, normalizer.InjectDecoder(validator.decoder))
+
+	prov := &metal3iov1alpha1.Provisioning{
+		ObjectMeta: metav1.ObjectMeta{Name: metal3iov1alpha1.ProvisioningSingletonName},
+		Spec: metal3iov1alpha1.ProvisioningSpec{
+			ProvisioningInterface: "eth0",
+			ProvisioningIP:        "172.30.20.3",
+		},
+	}
+	cidrIP := prov.DeepCopy()
+	cidrIP.Spec.ProvisioningIP = "172.30.20.3/24"
+	mismatch := cidrIP.DeepCopy()
+	mismatch.Spec.ProvisioningNetworkCIDR = "172.30.0.0/16"
+
+	resp := normalizer.Handle(context.Background(), newRequest(t, admissionv1beta1.Create, prov))
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Patches)
+
+	resp = normalizer.Handle(context.Background(), newRequest(t, admissionv1beta1.Update, cidrIP))
+	assert.True(t, resp.Allowed)
+	patches := map[string]interface{}{}
+	for _, patch := range resp.Patches {
+		patches[patch.Path] = patch.Value
+	}
+	assert.Equal(t, map[string]interface{}{
+		"/spec/provisioningIP":          "172.30.20.3",
+		"/spec/provisioningNetworkCIDR": "172.30.20.0/24",
+	}, patches)
+
+	resp = normalizer.Handle(context.Background(), newRequest(t, admissionv1beta1.Create, mismatch))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, `ProvisioningIP "172.30.20.3/24" is not on the ProvisioningNetworkCIDR "172.30.0.0/16"`, string(resp.Result.Reason))
+}
@@ -0,0 +1,87 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultPort is the port the webhooks are served on
+const DefaultPort = 9443
+
+// shutdownTimeout bounds how long the requests being served delay the
+// shutdown of the server
+const shutdownTimeout = 10 * time.Second
+
+// Server serves the webhooks with the certificate of a CertReloader. The
+// webhook server of controller-runtime watches the certificate files, and
+// keeps serving an expired certificate when a rotation event is missed.
+type Server struct {
+	Port  int
+	Certs *CertReloader
+	Mux   *http.ServeMux
+	Log   logr.Logger
+
+	// listen replaces tls.Listen when set
+	listen func(network, address string, config *tls.Config) (net.Listener, error)
+}
+
+// Start serves the webhooks until stopped
+func (s *Server) Start(stop <-chan struct{}) error {
+	listen := tls.Listen
+	if s.listen != nil {
+		listen = s.listen
+	}
+	listener, err := listen("tcp", net.JoinHostPort("", strconv.Itoa(s.Port)), &tls.Config{
+		NextProtos:     []string{"h2"},
+		GetCertificate: s.Certs.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+	})
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: s.Mux}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			s.Log.Error(err, "unable to shut down the webhook server")
+		}
+	}()
+
+	s.Log.Info("serving webhooks", "port", s.Port)
+	if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	<-done
+	return nil
+}
+
+// NeedLeaderElection returns false, as every replica serves the webhooks
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}